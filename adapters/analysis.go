@@ -0,0 +1,688 @@
+package adapters
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ToolCall is a tool invocation normalized across sources.
+type ToolCall struct {
+	ID    string                 `json:"id,omitempty"`
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input,omitempty"`
+}
+
+// ExtractToolCalls returns the tool calls recorded on a message, whichever
+// source-specific shape they were stored in:
+//   - Metadata["tool_calls"]: copilot and mistral ({id, name, arguments})
+//   - Metadata["raw_content"]: claude and codex content blocks (tool_use / function_call)
+//   - NonTextParts: opencode parts of type "tool"
+func ExtractToolCalls(msg Message) []ToolCall {
+	var calls []ToolCall
+
+	if raw, ok := msg.Metadata["tool_calls"]; ok {
+		for _, item := range asObjectList(raw) {
+			calls = append(calls, ToolCall{
+				ID:    stringField(item, "id"),
+				Name:  stringField(item, "name"),
+				Input: asObject(item["arguments"]),
+			})
+		}
+	}
+
+	if raw, ok := msg.Metadata["raw_content"]; ok {
+		for _, block := range asObjectList(raw) {
+			switch stringField(block, "type") {
+			case "tool_use":
+				calls = append(calls, ToolCall{
+					ID:    stringField(block, "id"),
+					Name:  stringField(block, "name"),
+					Input: asObject(block["input"]),
+				})
+			case "function_call":
+				calls = append(calls, ToolCall{
+					ID:    stringField(block, "call_id"),
+					Name:  stringField(block, "name"),
+					Input: asObject(block["arguments"]),
+				})
+			}
+		}
+	}
+
+	for _, part := range msg.NonTextParts {
+		if stringField(part, "type") != "tool" {
+			continue
+		}
+		call := ToolCall{
+			ID:   stringField(part, "callID"),
+			Name: stringField(part, "tool"),
+		}
+		if state, ok := part["state"].(map[string]interface{}); ok {
+			call.Input = asObject(state["input"])
+		}
+		calls = append(calls, call)
+	}
+
+	return calls
+}
+
+// ToolResult is the output of a tool call, normalized across sources.
+type ToolResult struct {
+	CallID  string `json:"call_id,omitempty"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+	// DurationMS is how long the call ran, when the source recorded it
+	DurationMS int64 `json:"duration_ms,omitempty"`
+}
+
+// ExtractToolResults returns the tool results recorded on a message:
+//   - Metadata["tool_results"]: mistral ({tool_call_id, content, is_error})
+//   - Metadata["raw_content"]: tool_result content blocks
+//   - NonTextParts: opencode "tool" parts that have finished (state.output / state.error),
+//     timed by state.time
+func ExtractToolResults(msg Message) []ToolResult {
+	var results []ToolResult
+
+	if raw, ok := msg.Metadata["tool_results"]; ok {
+		for _, item := range asObjectList(raw) {
+			isError, _ := item["is_error"].(bool)
+			results = append(results, ToolResult{
+				CallID:  stringField(item, "tool_call_id"),
+				Content: textField(item["content"]),
+				IsError: isError,
+			})
+		}
+	}
+
+	if raw, ok := msg.Metadata["raw_content"]; ok {
+		for _, block := range asObjectList(raw) {
+			if stringField(block, "type") != "tool_result" {
+				continue
+			}
+			isError, _ := block["is_error"].(bool)
+			results = append(results, ToolResult{
+				CallID:  stringField(block, "tool_use_id"),
+				Content: textField(block["content"]),
+				IsError: isError,
+			})
+		}
+	}
+
+	for _, part := range msg.NonTextParts {
+		if stringField(part, "type") != "tool" {
+			continue
+		}
+		state, ok := part["state"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result := ToolResult{CallID: stringField(part, "callID")}
+		if times, ok := state["time"].(map[string]interface{}); ok {
+			if start, end := intField(times, "start"), intField(times, "end"); start > 0 && end >= start {
+				result.DurationMS = int64(end - start)
+			}
+		}
+		if output := stringField(state, "output"); output != "" {
+			result.Content = output
+		} else if errText := stringField(state, "error"); errText != "" {
+			result.Content, result.IsError = errText, true
+		} else {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// IsToolOutput reports whether a message only carries tool output: a "tool"
+// message (Copilot, Mistral, Gemini checkpoints), or one holding tool results
+// without text of its own or tool calls (Claude's tool_result lines).
+// Messages that pair calls with their results, as opencode records them, are
+// not tool output.
+func IsToolOutput(msg Message) bool {
+	if msg.Role == "tool" {
+		return true
+	}
+	if strings.TrimSpace(msg.Content) != "" || len(ExtractToolCalls(msg)) > 0 {
+		return false
+	}
+	return len(ExtractToolResults(msg)) > 0
+}
+
+// ToolInvocation is a tool call paired with its result, if the result was recorded.
+type ToolInvocation struct {
+	ToolCall
+	MessageIndex int        `json:"message_index"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"`
+	Result       *string    `json:"result,omitempty"`
+	Success      *bool      `json:"success,omitempty"` // nil when no result was recorded
+	// DurationMS is how long the call took: as recorded by the source (opencode,
+	// Copilot), or else the time between the call's message and its result's.
+	// nil when the timestamps don't allow it.
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+}
+
+// ExtractToolInvocations lists every tool call in a session in order, matched with
+// its result by call ID. Results may be recorded on the same message (opencode) or
+// on a later one (Claude tool_result blocks, Mistral and Copilot tool messages).
+func ExtractToolInvocations(messages []Message) []ToolInvocation {
+	var invocations []ToolInvocation
+	pending := make(map[string]int) // call ID -> index into invocations
+
+	for i, msg := range messages {
+		for _, call := range ExtractToolCalls(msg) {
+			invocation := ToolInvocation{ToolCall: call, MessageIndex: i}
+			if !msg.Timestamp.IsZero() {
+				ts := msg.Timestamp
+				invocation.Timestamp = &ts
+			}
+			if call.ID != "" {
+				pending[call.ID] = len(invocations)
+			}
+			invocations = append(invocations, invocation)
+		}
+
+		results := ExtractToolResults(msg)
+		if result, ok := copilotToolResult(msg); ok {
+			results = append(results, result)
+		}
+		for _, result := range results {
+			idx, ok := pending[result.CallID]
+			if !ok {
+				continue
+			}
+			content := result.Content
+			success := !result.IsError
+			invocations[idx].Result = &content
+			invocations[idx].Success = &success
+			if duration := result.DurationMS; duration > 0 {
+				invocations[idx].DurationMS = &duration
+			} else if called := invocations[idx].Timestamp; called != nil && invocations[idx].MessageIndex != i && !msg.Timestamp.IsZero() && !msg.Timestamp.Before(*called) {
+				duration := msg.Timestamp.Sub(*called).Milliseconds()
+				invocations[idx].DurationMS = &duration
+			}
+			delete(pending, result.CallID)
+		}
+	}
+
+	return invocations
+}
+
+// copilotToolResult returns the result carried by a Copilot tool message, whose
+// content is the tool's output. Renderers show that content as the message
+// itself, so it is left out of ExtractToolResults.
+func copilotToolResult(msg Message) (ToolResult, bool) {
+	callID := stringField(msg.Metadata, "tool_call_id")
+	if callID == "" {
+		return ToolResult{}, false
+	}
+	success, ok := msg.Metadata["success"].(bool)
+	duration, _ := msg.Metadata["duration_ms"].(int64)
+	return ToolResult{CallID: callID, Content: msg.Content, IsError: ok && !success, DurationMS: duration}, true
+}
+
+// TokenUsage sums token counts reported by a source.
+type TokenUsage struct {
+	Input      int `json:"input"`
+	Output     int `json:"output"`
+	Reasoning  int `json:"reasoning,omitempty"`
+	CacheRead  int `json:"cache_read,omitempty"`
+	CacheWrite int `json:"cache_write,omitempty"`
+	Total      int `json:"total"`
+}
+
+// add accumulates another usage into u.
+func (u *TokenUsage) add(other TokenUsage) {
+	u.Input += other.Input
+	u.Output += other.Output
+	u.Reasoning += other.Reasoning
+	u.CacheRead += other.CacheRead
+	u.CacheWrite += other.CacheWrite
+	u.Total += other.Total
+}
+
+// ExtractTokenUsage returns the token usage recorded on a message, if any.
+// It understands opencode's Metadata["tokens"] and Claude's Metadata["usage"].
+func ExtractTokenUsage(msg Message) (TokenUsage, bool) {
+	var usage TokenUsage
+	found := false
+
+	if tokens, ok := msg.Metadata["tokens"].(map[string]interface{}); ok {
+		usage.Input = intField(tokens, "input")
+		usage.Output = intField(tokens, "output")
+		usage.Reasoning = intField(tokens, "reasoning")
+		if cache, ok := tokens["cache"].(map[string]interface{}); ok {
+			usage.CacheRead = intField(cache, "read")
+			usage.CacheWrite = intField(cache, "write")
+		}
+		found = true
+	}
+
+	if raw, ok := msg.Metadata["usage"].(map[string]interface{}); ok {
+		usage.Input = intField(raw, "input_tokens")
+		usage.Output = intField(raw, "output_tokens")
+		usage.CacheRead = intField(raw, "cache_read_input_tokens")
+		usage.CacheWrite = intField(raw, "cache_creation_input_tokens")
+		found = true
+	}
+
+	usage.Total = usage.Input + usage.Output + usage.Reasoning + usage.CacheRead + usage.CacheWrite
+	return usage, found
+}
+
+// fileArgumentKeys are tool input keys that name a file the tool read or modified.
+var fileArgumentKeys = []string{"file_path", "filePath", "path", "notebook_path", "filename"}
+
+// patchFileMarkers start the lines naming files in an apply_patch patch.
+var patchFileMarkers = []string{"*** Add File: ", "*** Update File: ", "*** Delete File: "}
+
+// FilesFromToolCall returns file paths named in a tool call's input, including
+// the files changed by apply_patch-style patches.
+func FilesFromToolCall(call ToolCall) []string {
+	var files []string
+	for _, key := range fileArgumentKeys {
+		if value, ok := call.Input[key].(string); ok && strings.TrimSpace(value) != "" {
+			files = append(files, filepath.Clean(value))
+		}
+	}
+
+	for _, key := range []string{"patch", "input"} {
+		patch, ok := call.Input[key].(string)
+		if !ok || !strings.Contains(patch, "*** Begin Patch") {
+			continue
+		}
+		for _, line := range strings.Split(patch, "\n") {
+			for _, marker := range patchFileMarkers {
+				if file, found := strings.CutPrefix(line, marker); found && strings.TrimSpace(file) != "" {
+					files = append(files, filepath.Clean(strings.TrimSpace(file)))
+				}
+			}
+		}
+	}
+	return files
+}
+
+// File access kinds reported by FileAccessKind.
+const (
+	FileRead  = "read"
+	FileWrite = "write"
+	FileEdit  = "edit"
+	FileOther = "other"
+)
+
+// fileToolKinds maps lower-cased tool names across sources to how they access files.
+var fileToolKinds = map[string]string{
+	"read":            FileRead,
+	"read_file":       FileRead,
+	"read_many_files": FileRead,
+	"view":            FileRead,
+	"write":           FileWrite,
+	"write_file":      FileWrite,
+	"create_file":     FileWrite,
+	"notebookwrite":   FileWrite,
+	"edit":            FileEdit,
+	"multiedit":       FileEdit,
+	"edit_file":       FileEdit,
+	"replace":         FileEdit,
+	"search_replace":  FileEdit,
+	"str_replace":     FileEdit,
+	"notebookedit":    FileEdit,
+	"apply_patch":     FileEdit,
+	"patch":           FileEdit,
+}
+
+// FileAccessKind classifies a tool by how it accesses the files it names.
+func FileAccessKind(toolName string) string {
+	if kind, ok := fileToolKinds[strings.ToLower(toolName)]; ok {
+		return kind
+	}
+	return FileOther
+}
+
+// writtenTextFields are the tool input fields, across sources, holding text a
+// write or edit tool put into a file: whole-file contents, replacement
+// strings, and patches.
+var writtenTextFields = []string{"content", "file_text", "new_string", "newString", "new_str", "new_source", "patch", "input"}
+
+// WrittenText returns the text a write or edit tool call put into files, such
+// as a Write call's content, an Edit call's replacement, or an apply_patch
+// patch. It returns nil for other tools.
+func WrittenText(call ToolCall) []string {
+	if kind := FileAccessKind(call.Name); kind != FileWrite && kind != FileEdit {
+		return nil
+	}
+
+	var texts []string
+	collect := func(input map[string]interface{}) {
+		for _, field := range writtenTextFields {
+			if text := stringField(input, field); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	collect(call.Input)
+	for _, edit := range asObjectList(call.Input["edits"]) {
+		collect(edit)
+	}
+	return texts
+}
+
+// FileTouch counts how the tools in a session accessed one file.
+type FileTouch struct {
+	Path   string `json:"path"`
+	Reads  int    `json:"reads"`
+	Writes int    `json:"writes"`
+	Edits  int    `json:"edits"`
+	Other  int    `json:"other,omitempty"` // e.g. searched or listed
+}
+
+// ComputeFilesTouched returns every file named in a session's tool calls with
+// per-kind access counts, sorted by path.
+func ComputeFilesTouched(messages []Message) []FileTouch {
+	byPath := make(map[string]*FileTouch)
+	for _, msg := range messages {
+		for _, call := range ExtractToolCalls(msg) {
+			kind := FileAccessKind(call.Name)
+			for _, file := range FilesFromToolCall(call) {
+				touch, ok := byPath[file]
+				if !ok {
+					touch = &FileTouch{Path: file}
+					byPath[file] = touch
+				}
+				switch kind {
+				case FileRead:
+					touch.Reads++
+				case FileWrite:
+					touch.Writes++
+				case FileEdit:
+					touch.Edits++
+				default:
+					touch.Other++
+				}
+			}
+		}
+	}
+
+	files := make([]FileTouch, 0, len(byPath))
+	for _, touch := range byPath {
+		files = append(files, *touch)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// FileChange is a write or edit of one file by a tool call.
+type FileChange struct {
+	Path         string     `json:"path"`
+	Kind         string     `json:"kind"` // FileWrite or FileEdit
+	Tool         string     `json:"tool"`
+	MessageIndex int        `json:"message_index"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"`
+	Preview      string     `json:"preview,omitempty"` // start of the text written
+}
+
+// ExtractFileChanges lists, in order, every file a session's write and edit
+// tool calls changed. Calls whose result was recorded as an error changed
+// nothing and are left out.
+func ExtractFileChanges(messages []Message) []FileChange {
+	var changes []FileChange
+	for _, invocation := range ExtractToolInvocations(messages) {
+		kind := FileAccessKind(invocation.Name)
+		if kind != FileWrite && kind != FileEdit {
+			continue
+		}
+		if invocation.Success != nil && !*invocation.Success {
+			continue
+		}
+		preview := ""
+		if texts := WrittenText(invocation.ToolCall); len(texts) > 0 {
+			preview = TruncateText(texts[0], DefaultPreviewLength)
+		}
+		for _, file := range FilesFromToolCall(invocation.ToolCall) {
+			changes = append(changes, FileChange{
+				Path:         file,
+				Kind:         kind,
+				Tool:         invocation.Name,
+				MessageIndex: invocation.MessageIndex,
+				Timestamp:    invocation.Timestamp,
+				Preview:      preview,
+			})
+		}
+	}
+	return changes
+}
+
+// SessionStats aggregates a session's messages so clients don't need to page through it.
+type SessionStats struct {
+	MessageCount    int            `json:"message_count"`
+	MessagesByRole  map[string]int `json:"messages_by_role"`
+	ToolCallCount   int            `json:"tool_call_count"`
+	ToolCallsByName map[string]int `json:"tool_calls_by_name"`
+	Tokens          TokenUsage     `json:"tokens"`
+	Cost            float64        `json:"cost"`
+	StartTime       *time.Time     `json:"start_time,omitempty"`
+	EndTime         *time.Time     `json:"end_time,omitempty"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Models          []string       `json:"models"`
+	FilesTouched    []string       `json:"files_touched"`
+}
+
+// ComputeSessionStats aggregates counts, usage, cost, timing, models, and files
+// from a session's messages. Fields a source doesn't record are left at zero.
+func ComputeSessionStats(messages []Message) SessionStats {
+	stats := SessionStats{
+		MessagesByRole:  make(map[string]int),
+		ToolCallsByName: make(map[string]int),
+		Models:          []string{},
+		FilesTouched:    []string{},
+	}
+
+	models := make(map[string]bool)
+	files := make(map[string]bool)
+	countedUsage := make(map[string]bool)
+	var start, end time.Time
+
+	for _, msg := range messages {
+		stats.MessageCount++
+		stats.MessagesByRole[msg.Role]++
+
+		if !msg.Timestamp.IsZero() {
+			if start.IsZero() || msg.Timestamp.Before(start) {
+				start = msg.Timestamp
+			}
+			if msg.Timestamp.After(end) {
+				end = msg.Timestamp
+			}
+		}
+
+		for _, call := range ExtractToolCalls(msg) {
+			stats.ToolCallCount++
+			stats.ToolCallsByName[call.Name]++
+			for _, file := range FilesFromToolCall(call) {
+				files[file] = true
+			}
+		}
+
+		if usage, ok := ExtractTokenUsage(msg); ok {
+			// Claude repeats one response's usage on every line of that response
+			id, _ := msg.Metadata["message_id"].(string)
+			if id == "" || !countedUsage[id] {
+				stats.Tokens.add(usage)
+			}
+			if id != "" {
+				countedUsage[id] = true
+			}
+		}
+
+		if cost, ok := msg.Metadata["cost"].(float64); ok {
+			stats.Cost += cost
+		}
+		if model, ok := msg.Metadata["model"].(string); ok && model != "" {
+			models[model] = true
+		}
+	}
+
+	if !start.IsZero() {
+		stats.StartTime = &start
+		stats.EndTime = &end
+		stats.DurationSeconds = end.Sub(start).Seconds()
+	}
+	for model := range models {
+		stats.Models = append(stats.Models, model)
+	}
+	sort.Strings(stats.Models)
+	for file := range files {
+		stats.FilesTouched = append(stats.FilesTouched, file)
+	}
+	sort.Strings(stats.FilesTouched)
+
+	return stats
+}
+
+// AgentUsage aggregates the assistant work done under one agent or mode
+// (opencode's build, plan, or a custom agent) across sessions.
+type AgentUsage struct {
+	Agent           string     `json:"agent"`
+	SessionCount    int        `json:"session_count"`
+	MessageCount    int        `json:"message_count"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Cost            float64    `json:"cost"`
+	Tokens          TokenUsage `json:"tokens"`
+}
+
+// MessageAgent returns the agent or mode a message was generated under, if recorded.
+func MessageAgent(msg Message) string {
+	if agent, ok := msg.Metadata["agent"].(string); ok && agent != "" {
+		return agent
+	}
+	mode, _ := msg.Metadata["mode"].(string)
+	return mode
+}
+
+// AgentUsageTracker accumulates AgentUsage over any number of sessions.
+type AgentUsageTracker struct {
+	byAgent map[string]*AgentUsage
+}
+
+// NewAgentUsageTracker returns an empty tracker.
+func NewAgentUsageTracker() *AgentUsageTracker {
+	return &AgentUsageTracker{byAgent: make(map[string]*AgentUsage)}
+}
+
+// AddSession adds one session's messages. Messages without an agent are ignored.
+func (t *AgentUsageTracker) AddSession(messages []Message) {
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		agent := MessageAgent(msg)
+		if agent == "" {
+			continue
+		}
+
+		usage, ok := t.byAgent[agent]
+		if !ok {
+			usage = &AgentUsage{Agent: agent}
+			t.byAgent[agent] = usage
+		}
+		if !seen[agent] {
+			seen[agent] = true
+			usage.SessionCount++
+		}
+
+		usage.MessageCount++
+		usage.DurationSeconds += float64(intField(msg.Metadata, "duration_ms")) / 1000
+		if cost, ok := msg.Metadata["cost"].(float64); ok {
+			usage.Cost += cost
+		}
+		if tokens, ok := ExtractTokenUsage(msg); ok {
+			usage.Tokens.add(tokens)
+		}
+	}
+}
+
+// Usage returns the accumulated usage per agent, most time-consuming first.
+func (t *AgentUsageTracker) Usage() []AgentUsage {
+	usage := make([]AgentUsage, 0, len(t.byAgent))
+	for _, u := range t.byAgent {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].DurationSeconds != usage[j].DurationSeconds {
+			return usage[i].DurationSeconds > usage[j].DurationSeconds
+		}
+		return usage[i].Agent < usage[j].Agent
+	})
+	return usage
+}
+
+// asObjectList converts decoded JSON (or the typed slices adapters build) to a list of objects.
+func asObjectList(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		list := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				list = append(list, m)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// asObject converts tool arguments to an object, decoding JSON-encoded strings.
+func asObject(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case string:
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &decoded); err == nil {
+			return decoded
+		}
+	case json.RawMessage:
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(v, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return nil
+}
+
+// textField flattens tool output, which is either a string or a list of text blocks.
+func textField(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	var parts []string
+	for _, block := range asObjectList(value) {
+		if text := stringField(block, "text"); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// stringField returns m[key] if it is a string.
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// intField returns m[key] as an int when it is a JSON number.
+func intField(m map[string]interface{}, key string) int {
+	switch v := m[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,315 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractToolCallsAcrossSources(t *testing.T) {
+	claude := Message{Role: "assistant", Metadata: map[string]interface{}{
+		"raw_content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "Reading"},
+			map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Read", "input": map[string]interface{}{"file_path": "/src/main.go"}},
+		},
+	}}
+	mistral := Message{Role: "assistant", Metadata: map[string]interface{}{
+		"tool_calls": []map[string]interface{}{
+			{"id": "t2", "name": "write_file", "arguments": `{"path": "/src/util.go"}`},
+		},
+	}}
+	opencode := Message{Role: "assistant", NonTextParts: []map[string]interface{}{
+		{"type": "tool", "tool": "edit", "callID": "t3", "state": map[string]interface{}{"input": map[string]interface{}{"filePath": "/src/main.go"}}},
+		{"type": "reasoning", "text": "thinking"},
+	}}
+
+	for _, tc := range []struct {
+		msg  Message
+		name string
+		file string
+	}{
+		{claude, "Read", "/src/main.go"},
+		{mistral, "write_file", "/src/util.go"},
+		{opencode, "edit", "/src/main.go"},
+	} {
+		calls := ExtractToolCalls(tc.msg)
+		if len(calls) != 1 || calls[0].Name != tc.name {
+			t.Fatalf("expected one %s call, got %+v", tc.name, calls)
+		}
+		if files := FilesFromToolCall(calls[0]); len(files) != 1 || files[0] != tc.file {
+			t.Fatalf("expected file %s, got %v", tc.file, files)
+		}
+	}
+}
+
+func TestComputeSessionStats(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	usage := map[string]interface{}{"input_tokens": float64(100), "output_tokens": float64(20)}
+
+	messages := []Message{
+		{Role: "user", Content: "Fix it", Timestamp: start},
+		{Role: "assistant", Timestamp: start.Add(time.Minute), Metadata: map[string]interface{}{
+			"model": "claude-x", "usage": usage, "message_id": "m1",
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "name": "Edit", "input": map[string]interface{}{"file_path": "/a.go"}}},
+		}},
+		// Same API response continued on another line: usage must not be counted twice
+		{Role: "assistant", Timestamp: start.Add(2 * time.Minute), Metadata: map[string]interface{}{
+			"model": "claude-x", "usage": usage, "message_id": "m1",
+		}},
+		{Role: "assistant", Timestamp: start.Add(5 * time.Minute), Metadata: map[string]interface{}{
+			"model": "gpt-y", "cost": 0.25,
+			"tokens": map[string]interface{}{"input": float64(10), "output": float64(5), "cache": map[string]interface{}{"read": float64(3)}},
+		}},
+	}
+
+	stats := ComputeSessionStats(messages)
+	if stats.MessageCount != 4 || stats.MessagesByRole["assistant"] != 3 || stats.MessagesByRole["user"] != 1 {
+		t.Fatalf("unexpected message counts: %+v", stats)
+	}
+	if stats.ToolCallCount != 1 || stats.ToolCallsByName["Edit"] != 1 {
+		t.Fatalf("unexpected tool calls: %+v", stats.ToolCallsByName)
+	}
+	if stats.Tokens.Input != 110 || stats.Tokens.Output != 25 || stats.Tokens.CacheRead != 3 || stats.Tokens.Total != 138 {
+		t.Fatalf("unexpected tokens: %+v", stats.Tokens)
+	}
+	if stats.Cost != 0.25 {
+		t.Fatalf("unexpected cost: %v", stats.Cost)
+	}
+	if stats.DurationSeconds != 300 {
+		t.Fatalf("unexpected duration: %v", stats.DurationSeconds)
+	}
+	if strings.Join(stats.Models, ",") != "claude-x,gpt-y" {
+		t.Fatalf("unexpected models: %v", stats.Models)
+	}
+	if strings.Join(stats.FilesTouched, ",") != "/a.go" {
+		t.Fatalf("unexpected files: %v", stats.FilesTouched)
+	}
+}
+
+func TestExtractToolResultsAcrossSources(t *testing.T) {
+	claude := Message{Role: "user", Metadata: map[string]interface{}{
+		"raw_content": []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "package main"},
+			}},
+		},
+	}}
+	mistral := Message{Role: "tool", Metadata: map[string]interface{}{
+		"tool_results": []map[string]interface{}{
+			{"tool_call_id": "t2", "content": "permission denied", "is_error": true},
+		},
+	}}
+	opencode := Message{Role: "assistant", NonTextParts: []map[string]interface{}{
+		{"type": "tool", "tool": "bash", "callID": "t3", "state": map[string]interface{}{"output": "ok"}},
+	}}
+
+	for _, tc := range []struct {
+		msg     Message
+		want    ToolResult
+		comment string
+	}{
+		{claude, ToolResult{CallID: "t1", Content: "package main"}, "claude"},
+		{mistral, ToolResult{CallID: "t2", Content: "permission denied", IsError: true}, "mistral"},
+		{opencode, ToolResult{CallID: "t3", Content: "ok"}, "opencode"},
+	} {
+		results := ExtractToolResults(tc.msg)
+		if len(results) != 1 || results[0] != tc.want {
+			t.Fatalf("%s: expected %+v, got %+v", tc.comment, tc.want, results)
+		}
+	}
+}
+
+func TestIsToolOutput(t *testing.T) {
+	toolResult := map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"}
+	for _, tc := range []struct {
+		msg     Message
+		want    bool
+		comment string
+	}{
+		{Message{Role: "user", Metadata: map[string]interface{}{"raw_content": []interface{}{toolResult}}}, true, "claude tool_result line"},
+		{Message{Role: "tool", Content: "exit 0"}, true, "tool message"},
+		{Message{Role: "user", Content: "here is the log", Metadata: map[string]interface{}{"raw_content": []interface{}{toolResult}}}, false, "tool result with text"},
+		{Message{Role: "assistant", NonTextParts: []map[string]interface{}{
+			{"type": "tool", "tool": "bash", "callID": "t3", "state": map[string]interface{}{"output": "ok"}},
+		}}, false, "opencode call with its result"},
+		{Message{Role: "user", Content: "fix the parser"}, false, "prose"},
+	} {
+		if got := IsToolOutput(tc.msg); got != tc.want {
+			t.Errorf("%s: IsToolOutput = %v, want %v", tc.comment, got, tc.want)
+		}
+	}
+}
+
+func TestAgentUsageTracker(t *testing.T) {
+	tracker := NewAgentUsageTracker()
+	tracker.AddSession([]Message{
+		{Role: "user", Content: "Plan the refactor"},
+		{Role: "assistant", Metadata: map[string]interface{}{"mode": "plan", "duration_ms": int64(4000), "cost": 0.5}},
+		{Role: "assistant", Metadata: map[string]interface{}{"agent": "build", "mode": "plan", "duration_ms": int64(10000), "cost": 1.0,
+			"tokens": map[string]interface{}{"input": float64(100), "output": float64(50)}}},
+	})
+	tracker.AddSession([]Message{
+		{Role: "assistant", Metadata: map[string]interface{}{"agent": "build", "duration_ms": int64(2000)}},
+		{Role: "assistant", Metadata: map[string]interface{}{"agent": "build", "duration_ms": int64(1000)}},
+	})
+
+	usage := tracker.Usage()
+	if len(usage) != 2 || usage[0].Agent != "build" || usage[1].Agent != "plan" {
+		t.Fatalf("expected build then plan, got %+v", usage)
+	}
+	build := usage[0]
+	if build.SessionCount != 2 || build.MessageCount != 3 || build.DurationSeconds != 13 || build.Cost != 1.0 || build.Tokens.Total != 150 {
+		t.Fatalf("unexpected build usage: %+v", build)
+	}
+	if plan := usage[1]; plan.SessionCount != 1 || plan.MessageCount != 1 || plan.DurationSeconds != 4 {
+		t.Fatalf("unexpected plan usage: %+v", plan)
+	}
+}
+
+func TestExtractToolInvocationsPairsResults(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	messages := []Message{
+		{Role: "assistant", Timestamp: ts, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Bash", "input": map[string]interface{}{"command": "go test ./..."}},
+				map[string]interface{}{"type": "tool_use", "id": "t2", "name": "Read", "input": map[string]interface{}{"file_path": "/missing.go"}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"},
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t2", "content": "no such file", "is_error": true},
+			},
+		}},
+		{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t3", "name": "Bash", "input": map[string]interface{}{"command": "ls"}},
+			},
+		}},
+	}
+
+	invocations := ExtractToolInvocations(messages)
+	if len(invocations) != 3 {
+		t.Fatalf("expected 3 invocations, got %+v", invocations)
+	}
+	first := invocations[0]
+	if first.Name != "Bash" || first.Result == nil || *first.Result != "ok" || first.Success == nil || !*first.Success {
+		t.Fatalf("unexpected first invocation: %+v", first)
+	}
+	if first.Timestamp == nil || !first.Timestamp.Equal(ts) || first.MessageIndex != 0 {
+		t.Fatalf("expected timestamp and index from the calling message, got %+v", first)
+	}
+	if second := invocations[1]; second.Success == nil || *second.Success || *second.Result != "no such file" {
+		t.Fatalf("expected failed second invocation, got %+v", second)
+	}
+	if third := invocations[2]; third.Result != nil || third.Success != nil || third.MessageIndex != 2 {
+		t.Fatalf("expected third invocation without a result, got %+v", third)
+	}
+}
+
+func TestExtractToolInvocationsPairsCopilotResults(t *testing.T) {
+	messages := []Message{
+		{Role: "assistant", Metadata: map[string]interface{}{
+			"tool_calls": []map[string]interface{}{
+				{"id": "c1", "name": "bash", "arguments": map[string]interface{}{"command": "make"}},
+				{"id": "c2", "name": "view", "arguments": map[string]interface{}{"path": "/missing"}},
+			},
+		}},
+		{Role: "tool", Content: "built", Metadata: map[string]interface{}{"tool_call_id": "c1", "success": true}},
+		{Role: "tool", Content: "path does not exist", Metadata: map[string]interface{}{"tool_call_id": "c2", "success": false}},
+	}
+
+	invocations := ExtractToolInvocations(messages)
+	if len(invocations) != 2 {
+		t.Fatalf("expected 2 invocations, got %d", len(invocations))
+	}
+	if first := invocations[0]; first.Success == nil || !*first.Success || *first.Result != "built" {
+		t.Fatalf("expected successful first invocation, got %+v", first)
+	}
+	if second := invocations[1]; second.Success == nil || *second.Success || *second.Result != "path does not exist" {
+		t.Fatalf("expected failed second invocation, got %+v", second)
+	}
+	if results := ExtractToolResults(messages[2]); len(results) != 0 {
+		t.Fatalf("expected Copilot tool messages to be rendered as content, got results %+v", results)
+	}
+}
+
+func TestExtractFileChanges(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	toolUse := func(id, name string, input map[string]interface{}) Message {
+		return Message{Role: "assistant", Timestamp: ts, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "id": id, "name": name, "input": input}},
+		}}
+	}
+	messages := []Message{
+		toolUse("t1", "Read", map[string]interface{}{"file_path": "/src/auth.go"}),
+		toolUse("t2", "Edit", map[string]interface{}{"file_path": "/src/auth.go", "old_string": "a", "new_string": "return nil"}),
+		toolUse("t3", "Write", map[string]interface{}{"file_path": "/src/readonly.go", "content": "package src"}),
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": "t3", "content": "permission denied", "is_error": true}},
+		}},
+		toolUse("t4", "apply_patch", map[string]interface{}{"input": "*** Begin Patch\n*** Update File: a.go\n*** Add File: b.go\n*** End Patch"}),
+	}
+
+	changes := ExtractFileChanges(messages)
+	if len(changes) != 3 {
+		t.Fatalf("expected the edit and both patched files, got %+v", changes)
+	}
+	edit := changes[0]
+	if edit.Path != "/src/auth.go" || edit.Kind != FileEdit || edit.Tool != "Edit" || edit.MessageIndex != 1 || edit.Preview != "return nil" || !edit.Timestamp.Equal(ts) {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+	if changes[1].Path != "a.go" || changes[2].Path != "b.go" || changes[2].MessageIndex != 4 {
+		t.Fatalf("expected a change per patched file, got %+v", changes[1:])
+	}
+}
+
+func TestComputeFilesTouched(t *testing.T) {
+	call := func(name string, input map[string]interface{}) Message {
+		return Message{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "name": name, "input": input}},
+		}}
+	}
+	messages := []Message{
+		call("Read", map[string]interface{}{"file_path": "/src/main.go"}),
+		call("Edit", map[string]interface{}{"file_path": "/src/main.go"}),
+		call("Write", map[string]interface{}{"file_path": "/src/new.go"}),
+		call("Grep", map[string]interface{}{"path": "/src"}),
+		call("apply_patch", map[string]interface{}{"input": "*** Begin Patch\n*** Update File: /src/main.go\n@@\n-old\n+new\n*** End Patch"}),
+	}
+
+	files := ComputeFilesTouched(messages)
+	want := []FileTouch{
+		{Path: "/src", Other: 1},
+		{Path: "/src/main.go", Reads: 1, Edits: 2},
+		{Path: "/src/new.go", Writes: 1},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, files)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("expected %+v, got %+v", want, files)
+		}
+	}
+}
+
+func TestWrittenText(t *testing.T) {
+	calls := []ToolCall{
+		{Name: "Write", Input: map[string]interface{}{"file_path": "a.go", "content": "package a"}},
+		{Name: "MultiEdit", Input: map[string]interface{}{"file_path": "b.go", "edits": []interface{}{
+			map[string]interface{}{"old_string": "x", "new_string": "y := 1"},
+			map[string]interface{}{"old_string": "z", "new_string": "w := 2"},
+		}}},
+		{Name: "edit", Input: map[string]interface{}{"filePath": "c.ts", "oldString": "a", "newString": "const b = 2"}},
+		{Name: "Read", Input: map[string]interface{}{"file_path": "d.go", "content": "not written"}},
+	}
+	want := [][]string{{"package a"}, {"y := 1", "w := 2"}, {"const b = 2"}, nil}
+
+	for i, call := range calls {
+		got := WrittenText(call)
+		if strings.Join(got, "|") != strings.Join(want[i], "|") {
+			t.Errorf("%s: expected %q, got %q", call.Name, want[i], got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package adapters
+
+import (
+	"strings"
+)
+
+// Checklist evidence kinds.
+const (
+	EvidenceFileChange = "file_change"
+	EvidenceCommand    = "command"
+)
+
+// ChecklistEvidence is an action an agent took, cited as evidence that a
+// checklist item was worked on.
+type ChecklistEvidence struct {
+	MessageIndex int    `json:"message_index"`
+	Kind         string `json:"kind"` // EvidenceFileChange or EvidenceCommand
+	Description  string `json:"description"`
+	Success      *bool  `json:"success,omitempty"` // nil when no result was recorded
+}
+
+// ChecklistItem is one planned task and whether the session finished it.
+type ChecklistItem struct {
+	Text   string `json:"text"`
+	Status string `json:"status,omitempty"` // last status from a todo tool, e.g. "pending" or "completed"
+	Done   bool   `json:"done"`
+	Origin string `json:"origin"` // "todo_tool" or "text", as for Todo
+	// AddedAt and CompletedAt are the message indices where the item was
+	// first planned and marked completed
+	AddedAt     int                 `json:"added_at"`
+	CompletedAt *int                `json:"completed_at,omitempty"`
+	Evidence    []ChecklistEvidence `json:"evidence"`
+}
+
+// Checklist is a session's plan as a review checklist.
+type Checklist struct {
+	Items []ChecklistItem `json:"items"`
+	// Unplanned lists actions taken while no item was in progress
+	Unplanned []ChecklistEvidence `json:"unplanned"`
+}
+
+// checklistEvidence describes a tool call as evidence, if it changed files or
+// ran a command.
+func checklistEvidence(invocation ToolInvocation) (ChecklistEvidence, bool) {
+	evidence := ChecklistEvidence{MessageIndex: invocation.MessageIndex, Success: invocation.Success}
+	if command, ok := ShellCommand(invocation.ToolCall); ok {
+		evidence.Kind = EvidenceCommand
+		evidence.Description = TruncateText(command, DefaultPreviewLength)
+		return evidence, true
+	}
+	if kind := FileAccessKind(invocation.Name); kind == FileWrite || kind == FileEdit {
+		files := FilesFromToolCall(invocation.ToolCall)
+		if len(files) == 0 {
+			return ChecklistEvidence{}, false
+		}
+		evidence.Kind = EvidenceFileChange
+		evidence.Description = invocation.Name + " " + strings.Join(files, ", ")
+		return evidence, true
+	}
+	return ChecklistEvidence{}, false
+}
+
+// BuildChecklist turns the todo lists a session wrote with a todo tool into a
+// checklist, with the file changes and commands that did each item's work as
+// evidence. Actions are credited to the items in progress when they ran, or,
+// when none was, to the items the next todo list marks completed. Items keep
+// the last status any list gave them. Open TODO/FIXME notes found by
+// ExtractOpenTodos are added as items that aren't done.
+func BuildChecklist(messages []Message) Checklist {
+	checklist := Checklist{Items: []ChecklistItem{}, Unplanned: []ChecklistEvidence{}}
+	byText := make(map[string]int) // lower-cased text -> index into Items
+	var inProgress []int
+	var pending []ChecklistEvidence
+
+	invocations := ExtractToolInvocations(messages)
+	next := 0
+	for i, msg := range messages {
+		for ; next < len(invocations) && invocations[next].MessageIndex == i; next++ {
+			if evidence, ok := checklistEvidence(invocations[next]); ok {
+				pending = append(pending, evidence)
+			}
+		}
+
+		for _, call := range ExtractToolCalls(msg) {
+			field, ok := todoToolLists[strings.ToLower(call.Name)]
+			if !ok {
+				continue
+			}
+
+			var completed, nowInProgress []int
+			for _, entry := range asObjectList(call.Input[field]) {
+				text := stringField(entry, "content")
+				if text == "" {
+					text = stringField(entry, "step")
+				}
+				if text == "" {
+					continue
+				}
+				idx, ok := byText[strings.ToLower(text)]
+				if !ok {
+					idx = len(checklist.Items)
+					byText[strings.ToLower(text)] = idx
+					checklist.Items = append(checklist.Items, ChecklistItem{Text: text, Origin: "todo_tool", AddedAt: i, Evidence: []ChecklistEvidence{}})
+				}
+				item := &checklist.Items[idx]
+				status := stringField(entry, "status")
+				wasDone := item.Done
+				item.Status = status
+				item.Done = status == "completed"
+				switch {
+				case item.Done && !wasDone:
+					at := i
+					item.CompletedAt = &at
+					completed = append(completed, idx)
+				case !item.Done:
+					item.CompletedAt = nil
+				}
+				if status == "in_progress" {
+					nowInProgress = append(nowInProgress, idx)
+				}
+			}
+
+			credited := inProgress
+			if len(credited) == 0 {
+				credited = completed
+			}
+			checklist.creditEvidence(credited, pending)
+			pending = nil
+			inProgress = nowInProgress
+		}
+	}
+	for ; next < len(invocations); next++ {
+		if evidence, ok := checklistEvidence(invocations[next]); ok {
+			pending = append(pending, evidence)
+		}
+	}
+	checklist.creditEvidence(inProgress, pending)
+
+	for _, todo := range ExtractOpenTodos(messages) {
+		if todo.Origin != "text" {
+			continue
+		}
+		checklist.Items = append(checklist.Items, ChecklistItem{Text: todo.Text, Origin: todo.Origin, AddedAt: todo.MessageIndex, Evidence: []ChecklistEvidence{}})
+	}
+	return checklist
+}
+
+// creditEvidence adds evidence to each of the items, or to Unplanned when
+// there are none.
+func (c *Checklist) creditEvidence(items []int, evidence []ChecklistEvidence) {
+	if len(evidence) == 0 {
+		return
+	}
+	if len(items) == 0 {
+		c.Unplanned = append(c.Unplanned, evidence...)
+		return
+	}
+	for _, idx := range items {
+		c.Items[idx].Evidence = append(c.Items[idx].Evidence, evidence...)
+	}
+}
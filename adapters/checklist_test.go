@@ -0,0 +1,70 @@
+package adapters
+
+import "testing"
+
+func TestBuildChecklist(t *testing.T) {
+	todoWrite := func(items ...map[string]interface{}) Message {
+		todos := make([]interface{}, len(items))
+		for i, item := range items {
+			todos[i] = item
+		}
+		return Message{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "name": "TodoWrite", "input": map[string]interface{}{"todos": todos}}},
+		}}
+	}
+	item := func(content, status string) map[string]interface{} {
+		return map[string]interface{}{"content": content, "status": status}
+	}
+	toolUse := func(id, name string, input map[string]interface{}) Message {
+		return Message{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "id": id, "name": name, "input": input}},
+		}}
+	}
+
+	messages := []Message{
+		toolUse("t0", "Bash", map[string]interface{}{"command": "git status"}),
+		todoWrite(item("Add migration", "in_progress"), item("Write tests", "pending")),
+		toolUse("t1", "Write", map[string]interface{}{"file_path": "/app/migrate.sql", "content": "CREATE TABLE"}),
+		todoWrite(item("Add migration", "completed"), item("Write tests", "pending")),
+		toolUse("t2", "Bash", map[string]interface{}{"command": "go test ./..."}),
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": "t2", "content": "FAIL", "is_error": true}},
+		}},
+		todoWrite(item("Add migration", "completed"), item("Write tests", "in_progress")),
+		{Role: "assistant", Content: "TODO: cover the rollback path"},
+	}
+
+	checklist := BuildChecklist(messages)
+	if len(checklist.Items) != 3 {
+		t.Fatalf("expected two planned items and an open note, got %+v", checklist.Items)
+	}
+
+	migration := checklist.Items[0]
+	if !migration.Done || migration.AddedAt != 1 || migration.CompletedAt == nil || *migration.CompletedAt != 3 {
+		t.Fatalf("unexpected migration item %+v", migration)
+	}
+	if len(migration.Evidence) != 1 || migration.Evidence[0].Kind != EvidenceFileChange || migration.Evidence[0].Description != "Write /app/migrate.sql" {
+		t.Fatalf("expected the write as the migration's evidence, got %+v", migration.Evidence)
+	}
+
+	tests := checklist.Items[1]
+	if tests.Done || tests.Status != "in_progress" {
+		t.Fatalf("unexpected tests item %+v", tests)
+	}
+	// The failed test run happened while nothing was in progress and nothing
+	// was completed next, so it isn't credited to the tests item
+	if len(tests.Evidence) != 0 {
+		t.Fatalf("expected no evidence for the tests item, got %+v", tests.Evidence)
+	}
+
+	if note := checklist.Items[2]; note.Done || note.Origin != "text" || note.Text != "cover the rollback path" || note.AddedAt != 7 {
+		t.Fatalf("unexpected note item %+v", note)
+	}
+
+	if len(checklist.Unplanned) != 2 || checklist.Unplanned[0].Description != "git status" {
+		t.Fatalf("expected the commands outside any item to be unplanned, got %+v", checklist.Unplanned)
+	}
+	if failed := checklist.Unplanned[1]; failed.Kind != EvidenceCommand || failed.Success == nil || *failed.Success {
+		t.Fatalf("expected the failed test run, got %+v", failed)
+	}
+}
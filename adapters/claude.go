@@ -9,13 +9,23 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // ClaudeAdapter implements SessionAdapter for Claude Code CLI sessions.
 // Claude Code stores sessions as JSONL files in ~/.claude/projects/[PROJECT_DIR]/
-// where PROJECT_DIR is derived from the actual project path.
+// where PROJECT_DIR is derived from the actual project path. Sessions written
+// inside containers are read from configDirs, with their project paths
+// translated by pathMappings (see ConfigureContainers).
 type ClaudeAdapter struct {
-	homeDir string
+	homeDir      string
+	configDirs   []string
+	pathMappings []PathMapping
+	offsetCache  OffsetCache
+}
+
+func init() {
+	Register("claude", func() (SessionAdapter, error) { return NewClaudeAdapter() })
 }
 
 // NewClaudeAdapter creates a new Claude Code session adapter.
@@ -33,6 +43,17 @@ func (c *ClaudeAdapter) Name() string {
 	return "claude"
 }
 
+// WatchPaths returns the directories Claude Code writes session files to.
+func (c *ClaudeAdapter) WatchPaths() []string {
+	return c.projectsDirs()
+}
+
+// SetOffsetCache makes GetSession seek straight to the requested page of
+// session files it has read before.
+func (c *ClaudeAdapter) SetOffsetCache(cache OffsetCache) {
+	c.offsetCache = cache
+}
+
 // claudeMessage represents a single message entry in a Claude Code JSONL file.
 type claudeMessage struct {
 	Type        string                 `json:"type"`
@@ -41,15 +62,22 @@ type claudeMessage struct {
 	Content     interface{}            `json:"content,omitempty"`
 	Message     *claudeNestedMessage   `json:"message,omitempty"` // Nested message format
 	CWD         string                 `json:"cwd,omitempty"`
+	GitBranch   string                 `json:"gitBranch,omitempty"`
 	LeafUUID    string                 `json:"leafUuid,omitempty"`
 	IsSidechain bool                   `json:"isSidechain,omitempty"` // Skip sidechain messages
-	Metadata    map[string]interface{} `json:"-"`                     // Capture any extra fields
+	SessionID   string                 `json:"sessionId,omitempty"`   // Parent session for subagent transcripts
+	Timestamp   string                 `json:"timestamp,omitempty"`
+	CostUSD     float64                `json:"costUSD,omitempty"` // Recorded by older Claude Code versions
+	Metadata    map[string]interface{} `json:"-"`                 // Capture any extra fields
 }
 
 // claudeNestedMessage represents the nested message structure in newer Claude Code format
 type claudeNestedMessage struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+	Role    string                 `json:"role"`
+	Content interface{}            `json:"content"`
+	ID      string                 `json:"id,omitempty"`
+	Model   string                 `json:"model,omitempty"`
+	Usage   map[string]interface{} `json:"usage,omitempty"`
 }
 
 // projectDirName converts an absolute project path to Claude's directory naming format.
@@ -63,42 +91,65 @@ func projectDirName(projectPath string) string {
 // ListSessions returns all Claude Code sessions for the given project.
 // If projectPath is empty, returns sessions from ALL projects.
 func (c *ClaudeAdapter) ListSessions(projectPath string, limit int) ([]Session, error) {
-	claudeProjectsDir := filepath.Join(c.homeDir, ".claude", "projects")
-
 	// If no project path specified, list sessions from ALL projects
 	if projectPath == "" {
-		return c.listAllSessions(claudeProjectsDir, limit)
+		return c.listAllSessions(limit)
 	}
 
-	// Get absolute path
-	projectPath, err := filepath.Abs(projectPath)
+	filter, err := NewProjectFilter(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, err
 	}
-
-	// Convert to Claude's directory naming format
-	dirName := projectDirName(projectPath)
-	sessionsDir := filepath.Join(claudeProjectsDir, dirName)
-
-	// Check if directory exists
-	if _, err := os.Stat(sessionsDir); os.IsNotExist(err) {
-		return []Session{}, nil // No sessions for this project
+	// Directory names can't be globbed back into paths, so patterns are
+	// matched against every project's sessions
+	if filter.IsPattern() {
+		all, err := c.listAllSessions(0)
+		if err != nil {
+			return nil, err
+		}
+		return filter.Filter(all, limit), nil
 	}
+	projectPath = filter.Paths()[0]
 
-	// Read all .jsonl files
-	files, err := filepath.Glob(filepath.Join(sessionsDir, "*.jsonl"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to list session files: %w", err)
+	// Inside containers the project was recorded under its container path,
+	// and a symlinked project under whichever path it was opened through
+	var recordedPaths []string
+	for _, path := range filter.Paths() {
+		recordedPaths = append(recordedPaths, path)
+		recordedPaths = append(recordedPaths, c.containerPaths(path)...)
 	}
 
-	sessions := make([]Session, 0, len(files))
-	for _, filePath := range files {
-		session, err := c.parseSessionMetadata(filePath, projectPath)
-		if err != nil {
-			// Skip files we can't parse
-			continue
+	sessions := []Session{}
+	for _, claudeProjectsDir := range c.projectsDirs() {
+		for _, recordedPath := range recordedPaths {
+			// Convert to Claude's directory naming format
+			sessionsDir := filepath.Join(claudeProjectsDir, projectDirName(recordedPath))
+
+			// Check if directory exists
+			if _, err := os.Stat(sessionsDir); os.IsNotExist(err) {
+				continue // No sessions for this project here
+			}
+
+			// Read all .jsonl files
+			files, err := filepath.Glob(filepath.Join(sessionsDir, "*.jsonl"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list session files: %w", err)
+			}
+
+			files = append(files, claudeSubagentFiles(sessionsDir)...)
+
+			dirSessions := make([]Session, 0, len(files))
+			for _, filePath := range files {
+				session, err := c.parseSessionMetadata(filePath, projectPath)
+				if err != nil {
+					// Skip files we can't parse
+					continue
+				}
+				dirSessions = append(dirSessions, session)
+			}
+			linkClaudeSubagents(dirSessions)
+			sessions = append(sessions, dirSessions...)
 		}
-		sessions = append(sessions, session)
 	}
 
 	// Sort by timestamp (newest first)
@@ -115,10 +166,34 @@ func (c *ClaudeAdapter) ListSessions(projectPath string, limit int) ([]Session,
 }
 
 // listAllSessions lists sessions from all projects.
-func (c *ClaudeAdapter) listAllSessions(claudeProjectsDir string, limit int) ([]Session, error) {
+func (c *ClaudeAdapter) listAllSessions(limit int) ([]Session, error) {
+	allSessions := []Session{}
+	for _, claudeProjectsDir := range c.projectsDirs() {
+		sessions, err := c.listProjectsDir(claudeProjectsDir)
+		if err != nil {
+			return nil, err
+		}
+		allSessions = append(allSessions, sessions...)
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(allSessions, func(i, j int) bool {
+		return allSessions[i].Timestamp.After(allSessions[j].Timestamp)
+	})
+
+	// Apply limit
+	if limit > 0 && len(allSessions) > limit {
+		allSessions = allSessions[:limit]
+	}
+
+	return allSessions, nil
+}
+
+// listProjectsDir lists the sessions of every project in one projects directory.
+func (c *ClaudeAdapter) listProjectsDir(claudeProjectsDir string) ([]Session, error) {
 	// Check if projects directory exists
 	if _, err := os.Stat(claudeProjectsDir); os.IsNotExist(err) {
-		return []Session{}, nil
+		return nil, nil
 	}
 
 	// Read all project directories
@@ -139,25 +214,20 @@ func (c *ClaudeAdapter) listAllSessions(claudeProjectsDir string, limit int) ([]
 			continue
 		}
 
+		files = append(files, claudeSubagentFiles(projectDir)...)
+
 		projectPath := filepath.Join(claudeProjectsDir, dir.Name())
 
+		projectSessions := make([]Session, 0, len(files))
 		for _, filePath := range files {
 			session, err := c.parseSessionMetadata(filePath, projectPath)
 			if err != nil {
 				continue
 			}
-			allSessions = append(allSessions, session)
+			projectSessions = append(projectSessions, session)
 		}
-	}
-
-	// Sort by timestamp (newest first)
-	sort.Slice(allSessions, func(i, j int) bool {
-		return allSessions[i].Timestamp.After(allSessions[j].Timestamp)
-	})
-
-	// Apply limit
-	if limit > 0 && len(allSessions) > limit {
-		allSessions = allSessions[:limit]
+		linkClaudeSubagents(projectSessions)
+		allSessions = append(allSessions, projectSessions...)
 	}
 
 	return allSessions, nil
@@ -166,6 +236,9 @@ func (c *ClaudeAdapter) listAllSessions(claudeProjectsDir string, limit int) ([]
 // parseSessionMetadata extracts metadata from a Claude Code session file.
 // It reads the first few lines to get the summary and first user message.
 func (c *ClaudeAdapter) parseSessionMetadata(filePath, projectPath string) (Session, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return Session{}, err
+	}
 	// Performance optimization: Quick pre-scan using fast byte search
 	// to detect if there are any user messages before doing expensive JSON parsing.
 	// This allows us to skip files with no user messages entirely.
@@ -180,6 +253,12 @@ func (c *ClaudeAdapter) parseSessionMetadata(filePath, projectPath string) (Sess
 	session.ProjectPath = projectPath
 	session.FilePath = filePath
 
+	// Subagent transcripts are entirely sidechain messages, so keep them
+	isSubagent := isClaudeSubagentFile(filePath)
+	if isSubagent {
+		session.ParentSessionID = claudeParentFromPath(filePath)
+	}
+
 	// Get file modification time as a fallback timestamp
 	if stat, err := os.Stat(filePath); err == nil {
 		session.Timestamp = stat.ModTime()
@@ -192,6 +271,9 @@ func (c *ClaudeAdapter) parseSessionMetadata(filePath, projectPath string) (Sess
 	if !hasUserMessages {
 		session.FirstMessage = "(Empty session)"
 		session.UserMessageCount = 0
+		if isSubagent && session.ParentSessionID == "" {
+			session.ParentSessionID = claudeParentFromFile(filePath)
+		}
 		return session, nil
 	}
 
@@ -217,10 +299,19 @@ func (c *ClaudeAdapter) parseSessionMetadata(filePath, projectPath string) (Sess
 			projectPathFromLog = filepath.Clean(msg.CWD)
 		}
 
+		// Keep the last branch, which is where the session's work ended up
+		if msg.GitBranch != "" {
+			session.GitBranch = msg.GitBranch
+		}
+
+		if isSubagent && session.ParentSessionID == "" && msg.SessionID != "" {
+			session.ParentSessionID = msg.SessionID
+		}
+
 		// Capture first user message (skip system messages and sidechain messages)
 		if msg.Type == "user" {
 			// Skip sidechain messages (like "Warmup")
-			if msg.IsSidechain {
+			if msg.IsSidechain && !isSubagent {
 				continue
 			}
 
@@ -268,7 +359,7 @@ func (c *ClaudeAdapter) parseSessionMetadata(filePath, projectPath string) (Sess
 	}
 
 	if projectPathFromLog != "" {
-		session.ProjectPath = projectPathFromLog
+		session.ProjectPath = c.localPath(projectPathFromLog)
 	}
 
 	session.UserMessageCount = userMessageCount
@@ -336,11 +427,7 @@ func extractFirstLineFromClaudeContent(content interface{}) string {
 					continue
 				}
 
-				// Limit to 200 characters
-				if len(trimmed) > 200 {
-					return trimmed[:200] + "..."
-				}
-				return trimmed
+				return TruncateText(trimmed, MaxPreviewLength)
 			}
 		}
 	case []interface{}:
@@ -364,107 +451,133 @@ func extractFirstLineFromClaudeContent(content interface{}) string {
 func (c *ClaudeAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
 	// Find the session file
 	// We need to search all project directories since we only have the session ID
-	claudeDir := filepath.Join(c.homeDir, ".claude", "projects")
-	projectDirs, err := os.ReadDir(claudeDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read Claude projects directory: %w", err)
+	sessionFile := c.findSessionFile(sessionID)
+	if sessionFile == "" {
+		return nil, SessionNotFoundError(sessionID)
 	}
 
-	var sessionFile string
-	for _, dir := range projectDirs {
-		if !dir.IsDir() {
-			continue
-		}
-		candidate := filepath.Join(claudeDir, dir.Name(), sessionID+".jsonl")
-		if _, err := os.Stat(candidate); err == nil {
-			sessionFile = candidate
-			break
-		}
+	return readJSONLMessages(c.offsetCache, "claude", sessionFile, c.newDecoder(sessionFile), claudeMaxLine, page*pageSize, pageSize)
+}
+
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (c *ClaudeAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile := c.findSessionFile(sessionID)
+	if sessionFile == "" {
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
 	}
+	return readJSONLPage(c.offsetCache, "claude", sessionFile, c.newDecoder(sessionFile), claudeMaxLine, page, pageSize, fromEnd)
+}
 
+// StreamSessionPage reads the same page as GetSessionPage, passing each
+// message to yield as it is read instead of returning the page.
+func (c *ClaudeAdapter) StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(Message) error) (int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile := c.findSessionFile(sessionID)
 	if sessionFile == "" {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
+		return 0, page, false, SessionNotFoundError(sessionID)
 	}
+	return streamJSONLPage(c.offsetCache, "claude", sessionFile, c.newDecoder(sessionFile), claudeMaxLine, page, pageSize, fromEnd, yield)
+}
 
-	// Read all messages from the file
-	messages, err := c.readAllMessages(sessionFile)
-	if err != nil {
-		return nil, err
+// claudeMaxLine is the longest session file line Claude Code sessions are read with.
+const claudeMaxLine = 10 * 1024 * 1024
+
+// readAllMessages reads all messages from a Claude Code session file.
+func (c *ClaudeAdapter) readAllMessages(filePath string) ([]Message, error) {
+	return readJSONLMessages(nil, "claude", filePath, c.newDecoder(filePath), claudeMaxLine, 0, -1)
+}
+
+func (c *ClaudeAdapter) newDecoder(filePath string) func() jsonlDecoder {
+	return func() jsonlDecoder {
+		return &claudeDecoder{isSubagent: isClaudeSubagentFile(filePath)}
 	}
+}
+
+// claudeDecoder decodes Claude Code session lines. Each line stands alone, so
+// there is no state to carry between them.
+type claudeDecoder struct {
+	isSubagent bool
+}
 
-	// Apply pagination
-	start := page * pageSize
-	if start >= len(messages) {
-		return []Message{}, nil
+func (d *claudeDecoder) decode(line []byte, messages []Message) []Message {
+	var msg claudeMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return messages // Skip malformed lines
 	}
 
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
+	// Only process user and assistant messages
+	if msg.Type != "user" && msg.Type != "assistant" {
+		return messages
 	}
 
-	return messages[start:end], nil
-}
+	// Skip sidechain messages, unless this file is the sidechain transcript itself
+	if msg.IsSidechain && !d.isSubagent {
+		return messages
+	}
 
-// readAllMessages reads all messages from a Claude Code session file.
-func (c *ClaudeAdapter) readAllMessages(filePath string) ([]Message, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open session file: %w", err)
+	// Handle both old and new message formats
+	content := msg.Content
+	role := msg.Type
+	if msg.Message != nil {
+		content = msg.Message.Content
+		role = msg.Message.Role
 	}
-	defer file.Close()
 
-	var messages []Message
-	scanner := bufio.NewScanner(file)
+	message := Message{
+		Role:     role,
+		Content:  contentToString(content),
+		Metadata: make(map[string]interface{}),
+	}
 
-	// Increase buffer size for large messages
-	buf := make([]byte, 0, 1024*1024) // 1MB buffer
-	scanner.Buffer(buf, 10*1024*1024) // Max 10MB per line
+	if ts, err := time.Parse(time.RFC3339Nano, msg.Timestamp); err == nil {
+		message.Timestamp = ts
+	}
 
-	for scanner.Scan() {
-		var msg claudeMessage
-		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
-			continue // Skip malformed lines
-		}
+	// Tool output comes back as tool_result blocks on user lines, and pasted
+	// screenshots as image blocks
+	if role == "user" && (hasContentBlock(content, "tool_result") || hasContentBlock(content, "image")) {
+		message.Metadata["raw_content"] = content
+	}
 
-		// Only process user and assistant messages
-		if msg.Type != "user" && msg.Type != "assistant" {
-			continue
+	// Add any additional metadata
+	if role == "assistant" {
+		// Preserve structured content for tool calls, thinking blocks, etc.
+		message.Metadata["raw_content"] = content
+		if msg.Message != nil && msg.Message.Model != "" {
+			message.Metadata["model"] = msg.Message.Model
 		}
-
-		// Skip sidechain messages
-		if msg.IsSidechain {
-			continue
+		if msg.Message != nil && len(msg.Message.Usage) > 0 {
+			// One API response is split across lines that repeat the same usage;
+			// the message ID lets consumers count it once
+			message.Metadata["usage"] = msg.Message.Usage
+			message.Metadata["message_id"] = msg.Message.ID
 		}
-
-		// Handle both old and new message formats
-		content := msg.Content
-		role := msg.Type
-		if msg.Message != nil {
-			content = msg.Message.Content
-			role = msg.Message.Role
+		if msg.CostUSD > 0 {
+			message.Metadata["cost"] = msg.CostUSD
 		}
+	}
 
-		message := Message{
-			Role:     role,
-			Content:  contentToString(content),
-			Metadata: make(map[string]interface{}),
-		}
+	return append(messages, message)
+}
 
-		// Add any additional metadata
-		if role == "assistant" {
-			// Preserve structured content for tool calls, thinking blocks, etc.
-			message.Metadata["raw_content"] = content
-		}
+func (d *claudeDecoder) state() interface{} { return nil }
 
-		messages = append(messages, message)
-	}
+func (d *claudeDecoder) restore(json.RawMessage) error { return nil }
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading session file: %w", err)
+// hasContentBlock reports whether message content includes a block of the given type.
+func hasContentBlock(content interface{}, blockType string) bool {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return false
 	}
-
-	return messages, nil
+	for _, item := range blocks {
+		if m, ok := item.(map[string]interface{}); ok && m["type"] == blockType {
+			return true
+		}
+	}
+	return false
 }
 
 // contentToString converts various content formats to a plain string.
@@ -535,3 +648,69 @@ func (c *ClaudeAdapter) SearchSessions(projectPath, query string, limit int) ([]
 
 	return matches, nil
 }
+
+// claudeEntryTypes are the line types Claude Code is known to write. Only
+// user, assistant, and summary lines feed sessions; the rest are recognized
+// and ignored.
+var claudeEntryTypes = []string{"user", "assistant", "summary", "system", "file-history-snapshot", "queue-operation"}
+
+// claudeBlockTypes are the known content block types of user and assistant messages.
+var claudeBlockTypes = []string{"text", "thinking", "redacted_thinking", "tool_use", "tool_result", "image", "document"}
+
+// ValidateSession checks a session file strictly against the format this
+// adapter reads, reporting every line it would skip or misread.
+func (c *ClaudeAdapter) ValidateSession(session Session) ([]SchemaDeviation, error) {
+	return validateJSONLFile(session.FilePath, func(entry map[string]interface{}, d *deviations) {
+		entryType := d.str(entry, "", "type", true)
+		d.oneOf("type", "entry type", entryType, claudeEntryTypes...)
+
+		switch entryType {
+		case "summary":
+			d.str(entry, "", "summary", true)
+		case "user", "assistant":
+			d.timestamp(entry, "", "timestamp", true)
+			message := d.object(entry, "", "message", false)
+			if message == nil {
+				// Older versions put the content on the line itself
+				if _, ok := entry["content"]; !ok {
+					d.add("message", "missing, and no top-level content")
+					return
+				}
+				validateClaudeContent(d, "content", entry["content"])
+				return
+			}
+			if role := d.str(message, "message", "role", true); role != "" && role != entryType {
+				d.add("message.role", "role %q on a %s line", role, entryType)
+			}
+			validateClaudeContent(d, "message.content", message["content"])
+		}
+	})
+}
+
+// validateClaudeContent checks message content: a string or a list of blocks.
+func validateClaudeContent(d *deviations, path string, content interface{}) {
+	switch content := content.(type) {
+	case string:
+	case []interface{}:
+		d.objects(content, path, func(block map[string]interface{}, blockPath string) {
+			blockType := d.str(block, blockPath, "type", true)
+			d.oneOf(blockPath+".type", "content block type", blockType, claudeBlockTypes...)
+			switch blockType {
+			case "text":
+				d.str(block, blockPath, "text", true)
+			case "thinking":
+				d.str(block, blockPath, "thinking", true)
+			case "tool_use":
+				d.str(block, blockPath, "id", true)
+				d.str(block, blockPath, "name", true)
+				d.object(block, blockPath, "input", true)
+			case "tool_result":
+				d.str(block, blockPath, "tool_use_id", true)
+			}
+		})
+	case nil:
+		d.add(path, "missing")
+	default:
+		d.add(path, "expected string or array, got %s", jsonType(content))
+	}
+}
@@ -0,0 +1,347 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PathMapping translates a directory as seen inside a container to the same
+// directory on this machine, such as /workspaces/app to /Users/me/src/app.
+type PathMapping struct {
+	Container string `json:"container"`
+	Local     string `json:"local"`
+}
+
+// ClaudeContainerConfig describes Claude Code sessions written inside
+// containers, such as VS Code devcontainers. Claude Code there writes to the
+// container's ~/.claude and records container paths as each session's project.
+type ClaudeContainerConfig struct {
+	// ConfigDirs are local directories mounted as ~/.claude inside containers
+	ConfigDirs []string `json:"config_dirs,omitempty"`
+
+	// PathMappings translate container project paths to local ones
+	PathMappings []PathMapping `json:"path_mappings,omitempty"`
+
+	// Devcontainers are local project directories whose devcontainer.json is
+	// read for a bind-mounted ~/.claude and the workspace folder's container path
+	Devcontainers []string `json:"devcontainers,omitempty"`
+}
+
+// ConfigureContainers adds the container config directories and path mappings
+// in cfg, including those detected from each devcontainer project. A project
+// that can't be read is reported and skipped.
+func (c *ClaudeAdapter) ConfigureContainers(cfg ClaudeContainerConfig) []error {
+	var errs []error
+	for _, dir := range cfg.ConfigDirs {
+		c.addConfigDir(c.expandHome(dir))
+	}
+	for _, m := range cfg.PathMappings {
+		c.addPathMapping(PathMapping{Container: m.Container, Local: c.expandHome(m.Local)})
+	}
+	for _, project := range cfg.Devcontainers {
+		detected, err := DetectDevcontainer(c.expandHome(project))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, dir := range detected.ConfigDirs {
+			c.addConfigDir(dir)
+		}
+		for _, m := range detected.PathMappings {
+			c.addPathMapping(m)
+		}
+	}
+	return errs
+}
+
+// expandHome expands a leading "~" to the home directory.
+func (c *ClaudeAdapter) expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(c.homeDir, strings.TrimPrefix(path, "~"))
+	}
+	return path
+}
+
+// addConfigDir adds a container ~/.claude directory unless it is already read.
+func (c *ClaudeAdapter) addConfigDir(dir string) {
+	dir = filepath.Clean(dir)
+	for _, existing := range append([]string{filepath.Join(c.homeDir, ".claude")}, c.configDirs...) {
+		if existing == dir {
+			return
+		}
+	}
+	c.configDirs = append(c.configDirs, dir)
+}
+
+// addPathMapping adds a mapping, keeping the longest container paths first so
+// the most specific mapping wins.
+func (c *ClaudeAdapter) addPathMapping(m PathMapping) {
+	if m.Container == "" || m.Local == "" {
+		return
+	}
+	m.Container, m.Local = filepath.Clean(m.Container), filepath.Clean(m.Local)
+	for _, existing := range c.pathMappings {
+		if existing == m {
+			return
+		}
+	}
+	c.pathMappings = append(c.pathMappings, m)
+	sort.SliceStable(c.pathMappings, func(i, j int) bool {
+		return len(c.pathMappings[i].Container) > len(c.pathMappings[j].Container)
+	})
+}
+
+// projectsDirs returns the directories holding Claude Code project folders:
+// ~/.claude/projects, then each container config directory's.
+func (c *ClaudeAdapter) projectsDirs() []string {
+	dirs := []string{filepath.Join(c.homeDir, ".claude", "projects")}
+	for _, dir := range c.configDirs {
+		dirs = append(dirs, filepath.Join(dir, "projects"))
+	}
+	return dirs
+}
+
+// localPath translates a project path recorded inside a container to the
+// local path, or returns it unchanged if no mapping covers it.
+func (c *ClaudeAdapter) localPath(path string) string {
+	for _, m := range c.pathMappings {
+		if rest, ok := cutPathPrefix(path, m.Container); ok {
+			return filepath.Join(m.Local, rest)
+		}
+	}
+	return path
+}
+
+// containerPaths returns the paths a local project directory may have been
+// recorded under inside containers.
+func (c *ClaudeAdapter) containerPaths(path string) []string {
+	var paths []string
+	for _, m := range c.pathMappings {
+		if rest, ok := cutPathPrefix(path, m.Local); ok {
+			paths = append(paths, filepath.Join(m.Container, rest))
+		}
+	}
+	return paths
+}
+
+// cutPathPrefix reports whether path is dir or inside it, returning the rest of
+// the path relative to dir.
+func cutPathPrefix(path, dir string) (string, bool) {
+	if path == dir {
+		return "", true
+	}
+	if rest, ok := strings.CutPrefix(path, strings.TrimSuffix(dir, "/")+"/"); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+// devcontainerFiles are where a project's devcontainer.json may live, relative to the project.
+var devcontainerFiles = []string{
+	filepath.Join(".devcontainer", "devcontainer.json"),
+	".devcontainer.json",
+	filepath.Join(".devcontainer", "*", "devcontainer.json"),
+}
+
+// devcontainerConfig is the part of devcontainer.json that locates Claude
+// Code's files.
+type devcontainerConfig struct {
+	WorkspaceFolder string            `json:"workspaceFolder"`
+	WorkspaceMount  string            `json:"workspaceMount"`
+	Mounts          []json.RawMessage `json:"mounts"`
+	ContainerEnv    map[string]string `json:"containerEnv"`
+	RemoteEnv       map[string]string `json:"remoteEnv"`
+}
+
+// DetectDevcontainer reads a project's devcontainer.json files and returns the
+// container settings they imply. The workspace folder maps to the project, and
+// every bind mount maps its container target to its local source. A bind mount
+// at ~/.claude (or at CLAUDE_CONFIG_DIR) is a config directory. Volume mounts
+// live inside the container engine and can't be read from here, so they are
+// ignored.
+func DetectDevcontainer(projectDir string) (ClaudeContainerConfig, error) {
+	projectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return ClaudeContainerConfig{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	var files []string
+	for _, pattern := range devcontainerFiles {
+		matches, _ := filepath.Glob(filepath.Join(projectDir, pattern))
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return ClaudeContainerConfig{}, fmt.Errorf("no devcontainer.json found in %s", projectDir)
+	}
+
+	var detected ClaudeContainerConfig
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return ClaudeContainerConfig{}, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var cfg devcontainerConfig
+		if err := json.Unmarshal(stripJSONC(data), &cfg); err != nil {
+			return ClaudeContainerConfig{}, fmt.Errorf("invalid devcontainer config %s: %w", file, err)
+		}
+
+		vars := map[string]string{
+			"localWorkspaceFolder":         projectDir,
+			"localWorkspaceFolderBasename": filepath.Base(projectDir),
+		}
+		expand := func(s string) string { return expandDevcontainerVars(s, vars) }
+
+		workspace := expand(cfg.WorkspaceFolder)
+		if workspace == "" {
+			workspace = "/workspaces/" + filepath.Base(projectDir)
+		}
+		vars["containerWorkspaceFolder"] = workspace
+		detected.PathMappings = append(detected.PathMappings, PathMapping{Container: workspace, Local: projectDir})
+
+		var configTargets []string
+		for _, env := range []map[string]string{cfg.ContainerEnv, cfg.RemoteEnv} {
+			if dir := expand(env["CLAUDE_CONFIG_DIR"]); dir != "" {
+				configTargets = append(configTargets, filepath.Clean(dir))
+			}
+		}
+
+		mounts := make([]map[string]string, 0, len(cfg.Mounts)+1)
+		if cfg.WorkspaceMount != "" {
+			mounts = append(mounts, parseMountString(cfg.WorkspaceMount))
+		}
+		for _, raw := range cfg.Mounts {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				mounts = append(mounts, parseMountString(s))
+				continue
+			}
+			var m map[string]string
+			if json.Unmarshal(raw, &m) == nil {
+				mounts = append(mounts, m)
+			}
+		}
+
+		for _, mount := range mounts {
+			source, target := expand(mount["source"]), expand(mount["target"])
+			if mount["type"] != "bind" || source == "" || target == "" {
+				continue
+			}
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(projectDir, source)
+			}
+			source, target = filepath.Clean(source), filepath.Clean(target)
+
+			if filepath.Base(target) == ".claude" || slices.Contains(configTargets, target) {
+				detected.ConfigDirs = append(detected.ConfigDirs, source)
+				continue
+			}
+			detected.PathMappings = append(detected.PathMappings, PathMapping{Container: target, Local: source})
+		}
+	}
+	return detected, nil
+}
+
+// parseMountString parses a Docker --mount style string such as
+// "source=/a,target=/b,type=bind" into its fields. src and dst are accepted
+// as aliases, as Docker does.
+func parseMountString(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "src":
+			key = "source"
+		case "dst", "destination":
+			key = "target"
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// expandDevcontainerVars substitutes ${name} and ${localEnv:NAME} variables
+// in a devcontainer.json value. Unknown variables are left as they are.
+func expandDevcontainerVars(s string, vars map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			break
+		}
+		name := s[start+2 : start+end]
+		value, ok := vars[name]
+		if env, isEnv := strings.CutPrefix(name, "localEnv:"); isEnv {
+			env, fallback, _ := strings.Cut(env, ":")
+			if value, ok = os.LookupEnv(env); !ok || value == "" {
+				value, ok = fallback, true
+			}
+		}
+		b.WriteString(s[:start])
+		if ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(s[start : start+end+1])
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// stripJSONC removes the comments and trailing commas that devcontainer.json
+// allows, so it can be parsed as JSON.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case ch == '}' || ch == ']':
+			// Drop a comma that only has whitespace between it and the closing bracket
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package adapters
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// outputStyleSet matches Claude Code's confirmation that /output-style changed
+// the style, as recorded in the transcript.
+var outputStyleSet = regexp.MustCompile(`(?i)set output style to\s+\**([^*\n<]+?)\**\s*(?:</|$)`)
+
+// outputStyleCommand matches an /output-style command with the style as its argument.
+var outputStyleCommand = regexp.MustCompile(`(?s)<command-name>/output-style</command-name>.*?<command-args>\s*([^<\s][^<]*?)\s*</command-args>`)
+
+// ansiEscape matches terminal color codes in recorded command output.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// DetectPersonas returns the output styles and custom instructions a Claude
+// Code session ran with: styles switched to with /output-style during the
+// session, the style its settings select, and the project's CLAUDE.md files.
+func (c *ClaudeAdapter) DetectPersonas(sessionID string) ([]Persona, error) {
+	session, err := c.GetSessionInfo(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := c.readAllMessages(session.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracker personaTracker
+	for i, msg := range messages {
+		if msg.Role == "user" {
+			if style := claudeOutputStyle(msg.Content); style != "" {
+				tracker.activate(Persona{Kind: PersonaOutputStyle, Name: style}, i)
+			}
+		}
+		tracker.message()
+	}
+
+	personas := append([]Persona{}, tracker.personas...)
+	if style, file := c.configuredOutputStyle(session.ProjectPath); style != "" {
+		personas = append(personas, Persona{Kind: PersonaOutputStyle, Name: style, Evidence: EvidenceSettings, Excerpt: "set in " + file})
+	}
+	personas = append(personas, projectInstructions(session.ProjectPath, "CLAUDE.md", "CLAUDE.local.md", filepath.Join(".claude", "CLAUDE.md"))...)
+	return personas, nil
+}
+
+// claudeOutputStyle returns the output style a user message switched to, if
+// it is an /output-style command or its output.
+func claudeOutputStyle(content string) string {
+	if !strings.Contains(content, "output style") && !strings.Contains(content, "/output-style") {
+		return ""
+	}
+	if m := outputStyleSet.FindStringSubmatch(ansiEscape.ReplaceAllString(content, "")); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if m := outputStyleCommand.FindStringSubmatch(content); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// configuredOutputStyle returns the output style Claude Code's settings select
+// for a project and the settings file that selects it. Project settings
+// override user settings, and local settings override both.
+func (c *ClaudeAdapter) configuredOutputStyle(projectPath string) (style, file string) {
+	var candidates []string
+	if projectPath != "" {
+		candidates = append(candidates,
+			filepath.Join(projectPath, ".claude", "settings.local.json"),
+			filepath.Join(projectPath, ".claude", "settings.json"),
+		)
+	}
+	candidates = append(candidates, filepath.Join(c.homeDir, ".claude", "settings.json"))
+	for _, dir := range c.configDirs {
+		candidates = append(candidates, filepath.Join(dir, "settings.json"))
+	}
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var settings struct {
+			OutputStyle string `json:"outputStyle"`
+		}
+		if json.Unmarshal(data, &settings) == nil && settings.OutputStyle != "" {
+			return settings.OutputStyle, path
+		}
+	}
+	return "", ""
+}
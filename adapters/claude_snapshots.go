@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Claude Code backs up files before editing them, so a conversation can be
+// rewound. Each session's JSONL records a "file-history-snapshot" line before
+// every prompt, mapping each tracked file to the backup holding its content at
+// that point; later lines with isSnapshotUpdate add files to the same
+// snapshot. Backups live in [CONFIG_DIR]/file-history/<session-id>/.
+type claudeSnapshotLine struct {
+	Type             string `json:"type"`
+	MessageID        string `json:"messageId"`
+	IsSnapshotUpdate bool   `json:"isSnapshotUpdate"`
+	Snapshot         struct {
+		Timestamp          string                      `json:"timestamp"`
+		TrackedFileBackups map[string]claudeFileBackup `json:"trackedFileBackups"`
+	} `json:"snapshot"`
+}
+
+// claudeFileBackup is one tracked file in a snapshot. BackupFileName is null
+// for files that didn't exist yet.
+type claudeFileBackup struct {
+	BackupFileName *string `json:"backupFileName"`
+	Version        int     `json:"version"`
+}
+
+// claudeSnapshot is a parsed snapshot with where each file's backup is.
+type claudeSnapshot struct {
+	WorkspaceSnapshot
+	backups map[string]string // absolute path -> backup file name
+}
+
+// readClaudeSnapshots parses the file-history snapshots in a session file, in
+// order. Relative paths are made absolute against projectPath.
+func readClaudeSnapshots(sessionFile, sessionID, projectPath string) ([]claudeSnapshot, error) {
+	file, err := os.Open(sessionFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+
+	var snapshots []claudeSnapshot
+	byID := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	setScanBuffer(scanner, 1024*1024, 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !strings.Contains(string(line), `"file-history-snapshot"`) {
+			continue
+		}
+		var entry claudeSnapshotLine
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Type != "file-history-snapshot" || entry.MessageID == "" {
+			continue
+		}
+
+		idx, ok := byID[entry.MessageID]
+		if !ok {
+			idx = len(snapshots)
+			byID[entry.MessageID] = idx
+			snapshot := claudeSnapshot{
+				WorkspaceSnapshot: WorkspaceSnapshot{ID: entry.MessageID, SessionID: sessionID, Source: "claude", Description: "files before the prompt"},
+				backups:           make(map[string]string),
+			}
+			if ts, err := time.Parse(time.RFC3339, entry.Snapshot.Timestamp); err == nil {
+				snapshot.Timestamp = &ts
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		snapshot := &snapshots[idx]
+		for path, backup := range entry.Snapshot.TrackedFileBackups {
+			if projectPath != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(projectPath, path)
+			}
+			if backup.BackupFileName != nil {
+				snapshot.backups[path] = *backup.BackupFileName
+			} else {
+				delete(snapshot.backups, path)
+			}
+			found := false
+			for i := range snapshot.Files {
+				if snapshot.Files[i].Path == path {
+					snapshot.Files[i] = SnapshotFile{Path: path, Version: backup.Version, Existed: backup.BackupFileName != nil}
+					found = true
+				}
+			}
+			if !found {
+				snapshot.Files = append(snapshot.Files, SnapshotFile{Path: path, Version: backup.Version, Existed: backup.BackupFileName != nil})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	for i := range snapshots {
+		files := snapshots[i].Files
+		if files == nil {
+			snapshots[i].Files = []SnapshotFile{}
+		}
+		sort.Slice(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+	}
+	return snapshots, nil
+}
+
+// claudeConfigDirForFile returns the Claude config directory (such as
+// ~/.claude) a session file was found under.
+func (c *ClaudeAdapter) claudeConfigDirForFile(sessionFile string) string {
+	for _, projectsDir := range c.projectsDirs() {
+		if _, ok := cutPathPrefix(sessionFile, projectsDir); ok {
+			return filepath.Dir(projectsDir)
+		}
+	}
+	return filepath.Join(c.homeDir, ".claude")
+}
+
+// sessionSnapshots locates a session and parses its snapshots.
+func (c *ClaudeAdapter) sessionSnapshots(sessionID string) ([]claudeSnapshot, string, error) {
+	sessionFile := c.findSessionFile(sessionID)
+	if sessionFile == "" {
+		return nil, "", SessionNotFoundError(sessionID)
+	}
+	session, err := c.parseSessionMetadata(sessionFile, c.projectPathForFile(sessionFile))
+	if err != nil {
+		return nil, "", err
+	}
+	snapshots, err := readClaudeSnapshots(sessionFile, sessionID, session.ProjectPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return snapshots, filepath.Join(c.claudeConfigDirForFile(sessionFile), "file-history", sessionID), nil
+}
+
+// ListSnapshots returns the file-history snapshots Claude Code took during a
+// session, oldest first. Snapshots that track no files are left out.
+func (c *ClaudeAdapter) ListSnapshots(sessionID string) ([]WorkspaceSnapshot, error) {
+	snapshots, _, err := c.sessionSnapshots(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	result := []WorkspaceSnapshot{}
+	for _, snapshot := range snapshots {
+		if len(snapshot.Files) > 0 {
+			result = append(result, snapshot.WorkspaceSnapshot)
+		}
+	}
+	return result, nil
+}
+
+// ReadSnapshot calls read with the backed-up content of each file in a
+// snapshot. Files that didn't exist yet are skipped.
+func (c *ClaudeAdapter) ReadSnapshot(sessionID, snapshotID string, read SnapshotReader) error {
+	snapshots, backupDir, err := c.sessionSnapshots(sessionID)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ID != snapshotID {
+			continue
+		}
+		for _, f := range snapshot.Files {
+			backup, ok := snapshot.backups[f.Path]
+			if !ok {
+				continue
+			}
+			file, err := os.Open(filepath.Join(backupDir, backup))
+			if err != nil {
+				return fmt.Errorf("failed to open backup of %s: %w", f.Path, err)
+			}
+			err = read(f.Path, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("snapshot %s not found in session %s", snapshotID, sessionID)
+}
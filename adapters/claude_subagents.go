@@ -0,0 +1,179 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Claude Code runs Task/subagent work on a sidechain stored in its own JSONL file.
+// Older versions write agent-<id>.jsonl next to the parent session, with the parent's
+// ID in each line's sessionId field. Newer versions nest them under
+// [PROJECT_DIR]/<parent-session-id>/subagents/agent-<id>.jsonl.
+const claudeSubagentPrefix = "agent-"
+
+// isClaudeSubagentFile reports whether filePath is a subagent (sidechain) transcript.
+func isClaudeSubagentFile(filePath string) bool {
+	return strings.HasPrefix(filepath.Base(filePath), claudeSubagentPrefix) ||
+		filepath.Base(filepath.Dir(filePath)) == "subagents"
+}
+
+// claudeSubagentFiles returns the subagent transcripts nested under a project directory.
+// Top-level agent-*.jsonl files are already matched by the regular session glob.
+func claudeSubagentFiles(projectDir string) []string {
+	files, err := filepath.Glob(filepath.Join(projectDir, "*", "subagents", "*.jsonl"))
+	if err != nil {
+		return nil
+	}
+	return files
+}
+
+// claudeParentFromPath returns the parent session ID for nested subagent transcripts.
+func claudeParentFromPath(filePath string) string {
+	dir := filepath.Dir(filePath)
+	if filepath.Base(dir) != "subagents" {
+		return ""
+	}
+	return filepath.Base(filepath.Dir(dir))
+}
+
+// claudeParentFromFile reads the parent session ID recorded in a subagent transcript.
+func claudeParentFromFile(filePath string) string {
+	if parent := claudeParentFromPath(filePath); parent != "" {
+		return parent
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	setScanBuffer(scanner, 1024*1024, 10*1024*1024)
+	for scanner.Scan() {
+		var msg claudeMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.SessionID != "" {
+			return msg.SessionID
+		}
+	}
+	return ""
+}
+
+// linkClaudeSubagents fills ChildSessionIDs on parents from their children's ParentSessionID.
+func linkClaudeSubagents(sessions []Session) {
+	index := make(map[string]int, len(sessions))
+	for i, s := range sessions {
+		index[s.ID] = i
+	}
+	for _, s := range sessions {
+		if s.ParentSessionID == "" {
+			continue
+		}
+		if i, ok := index[s.ParentSessionID]; ok {
+			sessions[i].ChildSessionIDs = append(sessions[i].ChildSessionIDs, s.ID)
+		}
+	}
+	for i := range sessions {
+		sort.Strings(sessions[i].ChildSessionIDs)
+	}
+}
+
+// claudeChildSessionIDs finds the subagent transcripts spawned by a session.
+func claudeChildSessionIDs(sessionFile string) []string {
+	projectDir := filepath.Dir(sessionFile)
+	parentID := strings.TrimSuffix(filepath.Base(sessionFile), ".jsonl")
+
+	var children []string
+	nested, _ := filepath.Glob(filepath.Join(projectDir, parentID, "subagents", "*.jsonl"))
+	for _, file := range nested {
+		children = append(children, strings.TrimSuffix(filepath.Base(file), ".jsonl"))
+	}
+
+	topLevel, _ := filepath.Glob(filepath.Join(projectDir, claudeSubagentPrefix+"*.jsonl"))
+	for _, file := range topLevel {
+		if claudeParentFromFile(file) == parentID {
+			children = append(children, strings.TrimSuffix(filepath.Base(file), ".jsonl"))
+		}
+	}
+
+	sort.Strings(children)
+	return children
+}
+
+// findSessionFile locates a session or subagent transcript by ID across all
+// projects, including those written inside containers.
+func (c *ClaudeAdapter) findSessionFile(sessionID string) string {
+	for _, claudeDir := range c.projectsDirs() {
+		if file := findClaudeSessionFile(claudeDir, sessionID); file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+// findClaudeSessionFile locates a session or subagent transcript by ID in one
+// projects directory.
+func findClaudeSessionFile(claudeDir, sessionID string) string {
+	projectDirs, err := os.ReadDir(claudeDir)
+	if err != nil {
+		return ""
+	}
+
+	for _, dir := range projectDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		candidate := filepath.Join(claudeDir, dir.Name(), sessionID+".jsonl")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	if !strings.HasPrefix(sessionID, claudeSubagentPrefix) {
+		return ""
+	}
+	for _, dir := range projectDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(claudeDir, dir.Name(), "*", "subagents", sessionID+".jsonl"))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+	}
+	return ""
+}
+
+// GetSessionInfo returns metadata for a single session, including subagent links.
+func (c *ClaudeAdapter) GetSessionInfo(sessionID string) (Session, error) {
+	sessionFile := c.findSessionFile(sessionID)
+	if sessionFile == "" {
+		return Session{}, SessionNotFoundError(sessionID)
+	}
+
+	session, err := c.parseSessionMetadata(sessionFile, c.projectPathForFile(sessionFile))
+	if err != nil {
+		return Session{}, err
+	}
+	if session.ParentSessionID == "" {
+		session.ChildSessionIDs = claudeChildSessionIDs(sessionFile)
+	}
+	return session, nil
+}
+
+// projectPathForFile returns the fallback project path for a session file, used
+// when the transcript doesn't record its working directory.
+func (c *ClaudeAdapter) projectPathForFile(sessionFile string) string {
+	dir := filepath.Dir(sessionFile)
+	if filepath.Base(dir) == "subagents" {
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	return dir
+}
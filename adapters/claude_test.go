@@ -0,0 +1,202 @@
+package adapters
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeClaudeFile(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestClaudeSubagentLinking(t *testing.T) {
+	home := t.TempDir()
+	projectDir := filepath.Join(home, ".claude", "projects", "-work-app")
+
+	writeClaudeFile(t, filepath.Join(projectDir, "parent.jsonl"),
+		`{"type":"user","cwd":"/work/app","sessionId":"parent","message":{"role":"user","content":"Audit the code"}}`,
+		`{"type":"assistant","sessionId":"parent","message":{"role":"assistant","content":"Spawning agents"}}`)
+
+	// Older layout: sidechain file next to the parent, linked by sessionId
+	writeClaudeFile(t, filepath.Join(projectDir, "agent-old.jsonl"),
+		`{"type":"user","isSidechain":true,"cwd":"/work/app","sessionId":"parent","message":{"role":"user","content":"Check auth"}}`,
+		`{"type":"assistant","isSidechain":true,"sessionId":"parent","message":{"role":"assistant","content":"Auth ok"}}`)
+
+	// Newer layout: nested under the parent's directory
+	writeClaudeFile(t, filepath.Join(projectDir, "parent", "subagents", "agent-new.jsonl"),
+		`{"type":"user","isSidechain":true,"cwd":"/work/app","sessionId":"parent","message":{"role":"user","content":"Check db"}}`)
+
+	adapter := &ClaudeAdapter{homeDir: home}
+	sessions, err := adapter.ListSessions("", 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+
+	byID := make(map[string]Session)
+	for _, s := range sessions {
+		byID[s.ID] = s
+	}
+	if len(byID) != 3 {
+		t.Fatalf("expected parent and two subagent sessions, got %+v", sessions)
+	}
+	parent := byID["parent"]
+	if strings.Join(parent.ChildSessionIDs, ",") != "agent-new,agent-old" {
+		t.Fatalf("unexpected child IDs: %v", parent.ChildSessionIDs)
+	}
+	for _, id := range []string{"agent-old", "agent-new"} {
+		if byID[id].ParentSessionID != "parent" {
+			t.Fatalf("expected %s to link to parent, got %q", id, byID[id].ParentSessionID)
+		}
+	}
+	if byID["agent-old"].FirstMessage != "Check auth" {
+		t.Fatalf("expected subagent first message, got %q", byID["agent-old"].FirstMessage)
+	}
+
+	messages, err := adapter.GetSession("agent-new", 0, 10)
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "Check db" {
+		t.Fatalf("unexpected subagent messages: %+v", messages)
+	}
+
+	info, err := adapter.GetSessionInfo("parent")
+	if err != nil {
+		t.Fatalf("GetSessionInfo returned error: %v", err)
+	}
+	if info.ProjectPath != "/work/app" || strings.Join(info.ChildSessionIDs, ",") != "agent-new,agent-old" {
+		t.Fatalf("unexpected session info: %+v", info)
+	}
+}
+
+func TestClaudeDevcontainerSessions(t *testing.T) {
+	home := t.TempDir()
+	project := filepath.Join(t.TempDir(), "app")
+	containerClaude := filepath.Join(t.TempDir(), "claude-home")
+
+	writeClaudeFile(t, filepath.Join(project, ".devcontainer", "devcontainer.json"),
+		`{`,
+		`  // Claude Code keeps its config on the host so sessions survive rebuilds`,
+		`  "name": "app",`,
+		`  "mounts": [`,
+		`    "source=`+containerClaude+`,target=/home/node/.claude,type=bind",`,
+		`    "source=claude-history-${devcontainerId},target=/commandhistory,type=volume",`,
+		`    {"source": "${localWorkspaceFolder}/data", "target": "/data", "type": "bind"},`,
+		`  ],`,
+		`}`)
+	writeClaudeFile(t, filepath.Join(containerClaude, "projects", "-workspaces-app", "inside.jsonl"),
+		`{"type":"user","cwd":"/workspaces/app","sessionId":"inside","message":{"role":"user","content":"Run the migrations"}}`)
+	writeClaudeFile(t, filepath.Join(home, ".claude", "projects", projectDirName(project), "outside.jsonl"),
+		`{"type":"user","cwd":"`+project+`","sessionId":"outside","message":{"role":"user","content":"Review the diff"}}`)
+
+	detected, err := DetectDevcontainer(project)
+	if err != nil {
+		t.Fatalf("DetectDevcontainer returned error: %v", err)
+	}
+	if len(detected.ConfigDirs) != 1 || detected.ConfigDirs[0] != containerClaude {
+		t.Fatalf("expected the bind-mounted ~/.claude, got %+v", detected.ConfigDirs)
+	}
+	wantMappings := []PathMapping{{Container: "/workspaces/app", Local: project}, {Container: "/data", Local: filepath.Join(project, "data")}}
+	if len(detected.PathMappings) != len(wantMappings) || detected.PathMappings[0] != wantMappings[0] || detected.PathMappings[1] != wantMappings[1] {
+		t.Fatalf("expected mappings %+v, got %+v", wantMappings, detected.PathMappings)
+	}
+
+	adapter := &ClaudeAdapter{homeDir: home}
+	if errs := adapter.ConfigureContainers(ClaudeContainerConfig{Devcontainers: []string{project}}); len(errs) > 0 {
+		t.Fatalf("ConfigureContainers returned errors: %v", errs)
+	}
+
+	sessions, err := adapter.ListSessions(project, 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected the host and container sessions, got %+v", sessions)
+	}
+	for _, s := range sessions {
+		if s.ProjectPath != project {
+			t.Fatalf("expected %s to be attributed to %s, got %q", s.ID, project, s.ProjectPath)
+		}
+	}
+
+	messages, err := adapter.GetSession("inside", 0, 10)
+	if err != nil || len(messages) != 1 || messages[0].Content != "Run the migrations" {
+		t.Fatalf("expected to read the container session, got %+v (%v)", messages, err)
+	}
+}
+
+func TestClaudeSnapshots(t *testing.T) {
+	home := t.TempDir()
+	projectDir := filepath.Join(home, ".claude", "projects", "-work-app")
+	writeClaudeFile(t, filepath.Join(projectDir, "s1.jsonl"),
+		`{"type":"file-history-snapshot","messageId":"m0","isSnapshotUpdate":false,"snapshot":{"timestamp":"2025-03-01T10:00:00Z","trackedFileBackups":{}}}`,
+		`{"type":"user","cwd":"/work/app","sessionId":"s1","message":{"role":"user","content":"Refactor main"}}`,
+		`{"type":"file-history-snapshot","messageId":"m1","isSnapshotUpdate":false,"snapshot":{"timestamp":"2025-03-01T10:05:00Z","trackedFileBackups":{"/work/app/main.go":{"backupFileName":"abc@v1","version":1}}}}`,
+		`{"type":"file-history-snapshot","messageId":"m1","isSnapshotUpdate":true,"snapshot":{"timestamp":"2025-03-01T10:05:00Z","trackedFileBackups":{"new.go":{"backupFileName":null,"version":1}}}}`)
+	writeClaudeFile(t, filepath.Join(home, ".claude", "file-history", "s1", "abc@v1"), "package main")
+
+	adapter := &ClaudeAdapter{homeDir: home}
+	snapshots, err := adapter.ListSnapshots("s1")
+	if err != nil {
+		t.Fatalf("ListSnapshots returned error: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].ID != "m1" || snapshots[0].Timestamp == nil {
+		t.Fatalf("expected one snapshot with files, got %+v", snapshots)
+	}
+	files := snapshots[0].Files
+	if len(files) != 2 || files[0].Path != "/work/app/main.go" || !files[0].Existed || files[1].Path != "/work/app/new.go" || files[1].Existed {
+		t.Fatalf("unexpected snapshot files: %+v", files)
+	}
+
+	read := make(map[string]string)
+	err = adapter.ReadSnapshot("s1", "m1", func(path string, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		read[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshot returned error: %v", err)
+	}
+	if len(read) != 1 || read["/work/app/main.go"] != "package main\n" {
+		t.Fatalf("unexpected snapshot content: %v", read)
+	}
+
+	if err := adapter.ReadSnapshot("s1", "missing", func(string, io.Reader) error { return nil }); err == nil {
+		t.Fatal("expected an error for an unknown snapshot")
+	}
+}
+
+func TestSessionsRecordGitState(t *testing.T) {
+	home := t.TempDir()
+	writeClaudeFile(t, filepath.Join(home, ".claude", "projects", "-work-app", "s1.jsonl"),
+		`{"type":"user","cwd":"/work/app","gitBranch":"main","sessionId":"s1","message":{"role":"user","content":"Start a branch"}}`,
+		`{"type":"user","cwd":"/work/app","gitBranch":"feature","sessionId":"s1","message":{"role":"user","content":"Keep going"}}`)
+	writeClaudeFile(t, filepath.Join(home, ".codex", "sessions", "2025", "06", "01", "rollout-2025-06-01T10-00-00-c1.jsonl"),
+		`{"timestamp":"2025-06-01T10:00:00Z","type":"session_meta","payload":{"id":"c1","cwd":"/work/app","timestamp":"2025-06-01T10:00:00Z","git":{"branch":"main","commit_hash":"abc123"}}}`,
+		`{"timestamp":"2025-06-01T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Add a flag"}]}}`)
+
+	claude, err := (&ClaudeAdapter{homeDir: home}).GetSessionInfo("s1")
+	if err != nil {
+		t.Fatalf("GetSessionInfo returned error: %v", err)
+	}
+	if claude.GitBranch != "feature" {
+		t.Fatalf("expected the last recorded branch, got %q", claude.GitBranch)
+	}
+
+	codex, err := (&CodexAdapter{homeDir: home}).ListSessions("", 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+	if len(codex) != 1 || codex[0].GitBranch != "main" || codex[0].GitCommit != "abc123" {
+		t.Fatalf("expected the recorded git state, got %+v", codex)
+	}
+}
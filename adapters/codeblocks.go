@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in message text.
+type CodeBlock struct {
+	Language      string `json:"language,omitempty"`
+	Code          string `json:"code"`
+	ContextBefore string `json:"context_before,omitempty"` // prose line leading into the block
+	ContextAfter  string `json:"context_after,omitempty"`  // prose line following the block
+}
+
+// ExtractCodeBlocks returns the fenced (``` or ~~~) code blocks in Markdown text.
+// An unterminated block runs to the end of the text, as in CommonMark.
+func ExtractCodeBlocks(text string) []CodeBlock {
+	lines := strings.Split(text, "\n")
+	var blocks []CodeBlock
+
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		start := i + 1
+		end := len(lines)
+		for j := start; j < len(lines); j++ {
+			if closesFence(lines[j], fence) {
+				end = j
+				break
+			}
+		}
+
+		language := ""
+		if fields := strings.Fields(info); len(fields) > 0 {
+			language = strings.ToLower(fields[0])
+		}
+		blocks = append(blocks, CodeBlock{
+			Language:      language,
+			Code:          strings.Join(lines[start:end], "\n"),
+			ContextBefore: nearestProse(lines, i-1, -1),
+			ContextAfter:  nearestProse(lines, end+1, 1),
+		})
+		i = end
+	}
+
+	return blocks
+}
+
+// openingFence reports whether line opens a code block, returning the fence and info string.
+func openingFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", "", false
+	}
+	for _, char := range []string{"`", "~"} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == char[0] {
+			n++
+		}
+		if n >= 3 {
+			info = strings.TrimSpace(trimmed[n:])
+			// Backtick fences can't have backticks in the info string
+			if char == "`" && strings.Contains(info, "`") {
+				return "", "", false
+			}
+			return trimmed[:n], info, true
+		}
+	}
+	return "", "", false
+}
+
+// closesFence reports whether line closes a block opened with fence.
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 || len(trimmed) < len(fence) {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// nearestProse returns the first non-empty, non-fence line walking from start in
+// direction step, truncated for use as context.
+func nearestProse(lines []string, start, step int) string {
+	for i := start; i >= 0 && i < len(lines); i += step {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if _, _, isFence := openingFence(lines[i]); isFence {
+			return ""
+		}
+		return extractFirstLine(trimmed)
+	}
+	return ""
+}
+
+// StripCodeBlocks returns Markdown text with its fenced code blocks removed,
+// leaving the prose around them.
+func StripCodeBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	prose := make([]string, 0, len(lines))
+
+	for i := 0; i < len(lines); i++ {
+		fence, _, ok := openingFence(lines[i])
+		if !ok {
+			prose = append(prose, lines[i])
+			continue
+		}
+		i++
+		for i < len(lines) && !closesFence(lines[i], fence) {
+			i++
+		}
+	}
+
+	return strings.Join(prose, "\n")
+}
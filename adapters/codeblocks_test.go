@@ -0,0 +1,35 @@
+package adapters
+
+import "testing"
+
+func TestExtractCodeBlocks(t *testing.T) {
+	text := "Here is the handler:\n\n```Go title=main.go\nfunc main() {\n\t// ``` inside a string\n}\n```\n\nRun it with:\n~~~~bash\ngo run .\n~~~~\nThen check the output.\n\n```\nunterminated"
+
+	blocks := ExtractCodeBlocks(text)
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %+v", blocks)
+	}
+
+	if blocks[0].Language != "go" || blocks[0].Code != "func main() {\n\t// ``` inside a string\n}" {
+		t.Fatalf("unexpected first block: %+v", blocks[0])
+	}
+	if blocks[0].ContextBefore != "Here is the handler:" || blocks[0].ContextAfter != "Run it with:" {
+		t.Fatalf("unexpected context for first block: %+v", blocks[0])
+	}
+
+	if blocks[1].Language != "bash" || blocks[1].Code != "go run ." || blocks[1].ContextAfter != "Then check the output." {
+		t.Fatalf("unexpected second block: %+v", blocks[1])
+	}
+
+	if blocks[2].Language != "" || blocks[2].Code != "unterminated" || blocks[2].ContextAfter != "" {
+		t.Fatalf("unexpected unterminated block: %+v", blocks[2])
+	}
+}
+
+func TestStripCodeBlocks(t *testing.T) {
+	text := "Refresh tokens rotate on use.\n\n```go\nfunc refresh() {}\n```\nSo the old one is revoked.\n~~~\nunterminated"
+
+	if got, want := StripCodeBlocks(text), "Refresh tokens rotate on use.\n\nSo the old one is revoked."; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
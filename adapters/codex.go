@@ -16,7 +16,14 @@ import (
 // Codex stores sessions as JSONL files in ~/.codex/sessions and ~/.codex/archived_sessions
 // Files are named rollout-*.jsonl and contain structured log entries.
 type CodexAdapter struct {
-	homeDir string
+	homeDir       string
+	metadataCache MetadataCache
+	offsetCache   OffsetCache
+	pathCache     PathCache
+}
+
+func init() {
+	Register("codex", func() (SessionAdapter, error) { return NewCodexAdapter() })
 }
 
 // NewCodexAdapter creates a new Codex CLI session adapter.
@@ -33,6 +40,30 @@ func (c *CodexAdapter) Name() string {
 	return "codex"
 }
 
+// WatchPaths returns the directories Codex writes rollout files to.
+func (c *CodexAdapter) WatchPaths() []string {
+	codexHome := filepath.Join(c.homeDir, ".codex")
+	return []string{filepath.Join(codexHome, "sessions"), filepath.Join(codexHome, "archived_sessions")}
+}
+
+// SetMetadataCache makes ListSessions reuse metadata cached for unchanged
+// session files instead of parsing them again.
+func (c *CodexAdapter) SetMetadataCache(cache MetadataCache) {
+	c.metadataCache = cache
+}
+
+// SetOffsetCache makes GetSession seek straight to the requested page of
+// session files it has read before.
+func (c *CodexAdapter) SetOffsetCache(cache OffsetCache) {
+	c.offsetCache = cache
+}
+
+// SetPathCache makes GetSession look up which file holds a session instead of
+// reading every session file to find it.
+func (c *CodexAdapter) SetPathCache(cache PathCache) {
+	c.pathCache = cache
+}
+
 // codexEntry represents a single entry in a Codex rollout JSONL file.
 type codexEntry struct {
 	Type      string                 `json:"type"`
@@ -49,6 +80,8 @@ type sessionInfo struct {
 	SessionMetaTimestamp  string
 	FilePath              string
 	UserMessageCount      int
+	GitBranch             string
+	GitCommit             string
 }
 
 // parseCodexTimestamp parses timestamps produced by Codex rollout files.
@@ -85,14 +118,9 @@ func (c *CodexAdapter) ListSessions(projectPath string, limit int) ([]Session, e
 		return c.listAllSessions(sessionDirs, limit)
 	}
 
-	// Get absolute path and resolve symlinks
-	projectPath, err := filepath.Abs(projectPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
-	}
-	projectPath, err = filepath.EvalSymlinks(projectPath)
+	filter, err := NewProjectFilter(projectPath)
 	if err != nil {
-		projectPath, _ = filepath.Abs(projectPath) // Fallback if symlink resolution fails
+		return nil, err
 	}
 
 	// Find all rollout files
@@ -112,29 +140,11 @@ func (c *CodexAdapter) ListSessions(projectPath string, limit int) ([]Session, e
 	// Parse each file and filter by project path
 	var sessions []Session
 	for _, file := range allFiles {
-		info, err := c.scanRolloutFile(file, projectPath)
-		if err != nil || !info.CWDMatches(projectPath) {
+		session, err := c.rolloutSession(file)
+		if err != nil || !filter.Match(session.ProjectPath) {
 			continue
 		}
 
-		session := Session{
-			ID:               info.ID,
-			Source:           "codex",
-			ProjectPath:      projectPath,
-			FirstMessage:     info.FirstUserMessage,
-			UserMessageCount: info.UserMessageCount,
-			FilePath:         info.FilePath,
-		}
-
-		// Parse timestamp
-		tsStr := info.FirstMessageTimestamp
-		if tsStr == "" {
-			tsStr = info.SessionMetaTimestamp
-		}
-		if ts, err := parseCodexTimestamp(tsStr); err == nil {
-			session.Timestamp = ts
-		}
-
 		sessions = append(sessions, session)
 	}
 
@@ -168,11 +178,32 @@ func (c *CodexAdapter) listAllSessions(sessionDirs []string, limit int) ([]Sessi
 
 	var allSessions []Session
 	for _, file := range allFiles {
-		info, err := c.scanRolloutFile(file, "")
-		if err != nil || info.CWD == "" {
+		session, err := c.rolloutSession(file)
+		if err != nil || session.ProjectPath == "" {
 			continue
 		}
 
+		allSessions = append(allSessions, session)
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(allSessions, func(i, j int) bool {
+		return allSessions[i].Timestamp.After(allSessions[j].Timestamp)
+	})
+
+	return allSessions, nil
+}
+
+// rolloutSession returns the session stored in a rollout file, with its
+// working directory as the project path, using the metadata cache when the
+// file hasn't changed.
+func (c *CodexAdapter) rolloutSession(file string) (Session, error) {
+	return cachedMetadata(c.metadataCache, file, func() (Session, error) {
+		info, err := c.scanRolloutFile(file, "")
+		if err != nil {
+			return Session{}, err
+		}
+
 		session := Session{
 			ID:               info.ID,
 			Source:           "codex",
@@ -180,6 +211,8 @@ func (c *CodexAdapter) listAllSessions(sessionDirs []string, limit int) ([]Sessi
 			FirstMessage:     info.FirstUserMessage,
 			UserMessageCount: info.UserMessageCount,
 			FilePath:         info.FilePath,
+			GitBranch:        info.GitBranch,
+			GitCommit:        info.GitCommit,
 		}
 
 		// Parse timestamp
@@ -190,16 +223,8 @@ func (c *CodexAdapter) listAllSessions(sessionDirs []string, limit int) ([]Sessi
 		if ts, err := parseCodexTimestamp(tsStr); err == nil {
 			session.Timestamp = ts
 		}
-
-		allSessions = append(allSessions, session)
-	}
-
-	// Sort by timestamp (newest first)
-	sort.Slice(allSessions, func(i, j int) bool {
-		return allSessions[i].Timestamp.After(allSessions[j].Timestamp)
+		return session, nil
 	})
-
-	return allSessions, nil
 }
 
 // findRolloutFiles recursively finds all rollout-*.jsonl files in a directory.
@@ -225,6 +250,9 @@ func (c *CodexAdapter) findRolloutFiles(root string) ([]string, error) {
 // scanRolloutFile scans a Codex rollout file to extract session information.
 // It reads until it finds both the CWD and the first user message.
 func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return nil, err
+	}
 	// Performance optimization: Quick pre-scan using fast byte search
 	// to detect if there are any user messages before doing expensive JSON parsing.
 	fileData, err := os.ReadFile(filePath)
@@ -245,8 +273,7 @@ func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo
 	if !hasUserMessages {
 		// Quick scan for just CWD and session metadata
 		scanner := bufio.NewScanner(bytes.NewReader(fileData))
-		buf := make([]byte, 0, 1024*1024)
-		scanner.Buffer(buf, 10*1024*1024)
+		setScanBuffer(scanner, 1024*1024, 10*1024*1024)
 
 		for scanner.Scan() {
 			var entry codexEntry
@@ -269,6 +296,10 @@ func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo
 				if ts, ok := entry.Payload["timestamp"].(string); ok && info.SessionMetaTimestamp == "" {
 					info.SessionMetaTimestamp = ts
 				}
+				if git, ok := entry.Payload["git"].(map[string]interface{}); ok {
+					info.GitBranch, _ = git["branch"].(string)
+					info.GitCommit, _ = git["commit_hash"].(string)
+				}
 			case "turn_context":
 				if cwd, ok := entry.Payload["cwd"].(string); ok && info.CWD == "" {
 					if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
@@ -291,8 +322,7 @@ func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo
 
 	// File has user messages - do full JSON parse to get exact count and first message
 	scanner := bufio.NewScanner(bytes.NewReader(fileData))
-	buf := make([]byte, 0, 1024*1024) // 1MB buffer
-	scanner.Buffer(buf, 10*1024*1024) // Max 10MB per line
+	setScanBuffer(scanner, 1024*1024, 10*1024*1024) // Max 10MB per line
 
 	for scanner.Scan() {
 		var entry codexEntry
@@ -316,6 +346,10 @@ func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo
 			if ts, ok := entry.Payload["timestamp"].(string); ok && info.SessionMetaTimestamp == "" {
 				info.SessionMetaTimestamp = ts
 			}
+			if git, ok := entry.Payload["git"].(map[string]interface{}); ok {
+				info.GitBranch, _ = git["branch"].(string)
+				info.GitCommit, _ = git["commit_hash"].(string)
+			}
 
 		case "turn_context":
 			if cwd, ok := entry.Payload["cwd"].(string); ok && info.CWD == "" {
@@ -340,7 +374,7 @@ func (c *CodexAdapter) scanRolloutFile(filePath, targetCWD string) (*sessionInfo
 						info.UserMessageCount++
 
 						if info.FirstUserMessage == "" {
-							info.FirstUserMessage = c.extractFirstLine(text)
+							info.FirstUserMessage = extractFirstLine(text)
 							info.FirstMessageTimestamp = entry.Timestamp
 							if info.FirstMessageTimestamp == "" {
 								info.FirstMessageTimestamp = info.SessionMetaTimestamp
@@ -393,135 +427,181 @@ func (c *CodexAdapter) isSessionPrefix(text string) bool {
 		(strings.HasPrefix(lower, "<environment_context>") && strings.HasSuffix(lower, "</environment_context>"))
 }
 
-// extractFirstLine extracts the first non-empty line from text.
-func (c *CodexAdapter) extractFirstLine(text string) string {
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			if len(trimmed) > 200 {
-				return trimmed[:200] + "..."
-			}
-			return trimmed
-		}
+// GetSession retrieves the full content of a Codex session with pagination.
+func (c *CodexAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
+	sessionFile := c.findRolloutFile(sessionID)
+	if sessionFile == "" {
+		return nil, SessionNotFoundError(sessionID)
 	}
-	return ""
+
+	return readJSONLMessages(c.offsetCache, "codex", sessionFile, c.newDecoder, codexMaxLine, page*pageSize, pageSize)
 }
 
-// GetSession retrieves the full content of a Codex session with pagination.
-func (c *CodexAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
-	// Find the session file by scanning all rollout files
-	codexHome := filepath.Join(c.homeDir, ".codex")
-	sessionDirs := []string{
-		filepath.Join(codexHome, "sessions"),
-		filepath.Join(codexHome, "archived_sessions"),
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (c *CodexAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile := c.findRolloutFile(sessionID)
+	if sessionFile == "" {
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
 	}
+	return readJSONLPage(c.offsetCache, "codex", sessionFile, c.newDecoder, codexMaxLine, page, pageSize, fromEnd)
+}
 
-	var sessionFile string
-	for _, dir := range sessionDirs {
-		files, err := c.findRolloutFiles(dir)
-		if err != nil {
-			continue
+// StreamSessionPage reads the same page as GetSessionPage, passing each
+// message to yield as it is read instead of returning the page.
+func (c *CodexAdapter) StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(Message) error) (int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile := c.findRolloutFile(sessionID)
+	if sessionFile == "" {
+		return 0, page, false, SessionNotFoundError(sessionID)
+	}
+	return streamJSONLPage(c.offsetCache, "codex", sessionFile, c.newDecoder, codexMaxLine, page, pageSize, fromEnd, yield)
+}
+
+// findRolloutFile returns the rollout file of a session, or "" if there is none.
+func (c *CodexAdapter) findRolloutFile(sessionID string) string {
+	holds := func(file string) bool {
+		session, err := c.rolloutSession(file)
+		return err == nil && session.ID == sessionID
+	}
+	return findSessionPath(c.pathCache, "codex", sessionID, holds, func() string {
+		// Find the session file by scanning all rollout files
+		codexHome := filepath.Join(c.homeDir, ".codex")
+		sessionDirs := []string{
+			filepath.Join(codexHome, "sessions"),
+			filepath.Join(codexHome, "archived_sessions"),
 		}
 
-		for _, file := range files {
-			// Quick check: does this file contain the session ID?
-			if info, err := c.scanRolloutFile(file, ""); err == nil && info.ID == sessionID {
-				sessionFile = file
-				break
+		for _, dir := range sessionDirs {
+			files, err := c.findRolloutFiles(dir)
+			if err != nil {
+				continue
 			}
-		}
 
-		if sessionFile != "" {
-			break
+			for _, file := range files {
+				if holds(file) {
+					return file
+				}
+			}
 		}
-	}
+		return ""
+	})
+}
 
-	if sessionFile == "" {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
-	}
+// codexMaxLine is the longest rollout file line Codex sessions are read with.
+const codexMaxLine = 10 * 1024 * 1024
 
-	// Read all messages from the file
-	messages, err := c.readAllMessages(sessionFile)
-	if err != nil {
-		return nil, err
-	}
+// readAllMessages reads all messages from a Codex rollout file.
+func (c *CodexAdapter) readAllMessages(filePath string) ([]Message, error) {
+	return readJSONLMessages(nil, "codex", filePath, c.newDecoder, codexMaxLine, 0, -1)
+}
 
-	// Apply pagination
-	start := page * pageSize
-	if start >= len(messages) {
-		return []Message{}, nil
-	}
+func (c *CodexAdapter) newDecoder() jsonlDecoder {
+	return &codexDecoder{c: c, lastAssistant: -1}
+}
 
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
-	}
+// codexDecoder decodes Codex rollout lines. The model is only named by
+// turn_context lines, so it is carried to the messages that follow.
+type codexDecoder struct {
+	c             *CodexAdapter
+	model         string
+	lastAssistant int // index of the last assistant message, for token usage
+}
 
-	return messages[start:end], nil
+// codexReaderState is what codexDecoder carries between lines.
+type codexReaderState struct {
+	Model string `json:"model"`
 }
 
-// readAllMessages reads all messages from a Codex rollout file.
-func (c *CodexAdapter) readAllMessages(filePath string) ([]Message, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open rollout file: %w", err)
+func (d *codexDecoder) decode(line []byte, messages []Message) []Message {
+	var entry codexEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return messages
 	}
-	defer file.Close()
-
-	var messages []Message
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024)
 
-	for scanner.Scan() {
-		var entry codexEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
+	switch entry.Type {
+	case "turn_context":
+		if m, ok := entry.Payload["model"].(string); ok && m != "" {
+			d.model = m
 		}
-
-		if entry.Type != "response_item" {
-			continue
+		return messages
+	case "event_msg":
+		if usage, ok := codexTokenUsage(entry.Payload); ok && d.lastAssistant >= 0 {
+			addCodexTokens(messages[d.lastAssistant].Metadata, usage)
 		}
+		return messages
+	case "response_item":
+	default:
+		return messages
+	}
 
-		if riType, ok := entry.Payload["type"].(string); ok && riType == "message" {
-			if role, ok := entry.Payload["role"].(string); ok {
-				message := Message{
-					Role:     role,
-					Metadata: make(map[string]interface{}),
-				}
+	if riType, ok := entry.Payload["type"].(string); ok && riType == "message" {
+		if role, ok := entry.Payload["role"].(string); ok {
+			message := Message{
+				Role:     role,
+				Metadata: make(map[string]interface{}),
+			}
 
-				// Parse timestamp
-				if ts, err := parseCodexTimestamp(entry.Timestamp); err == nil {
-					message.Timestamp = ts
-				}
+			// Parse timestamp
+			if ts, err := parseCodexTimestamp(entry.Timestamp); err == nil {
+				message.Timestamp = ts
+			}
 
-				// Extract content
-				if content, ok := entry.Payload["content"].([]interface{}); ok {
-					if role == "user" {
-						message.Content = c.extractUserText(content)
-					} else {
-						// For assistant messages, extract all text parts
-						message.Content = c.extractAllText(content)
+			// Extract content
+			if content, ok := entry.Payload["content"].([]interface{}); ok {
+				if role == "user" {
+					message.Content = d.c.extractUserText(content)
+					if hasContentBlock(content, "input_image") {
 						message.Metadata["raw_content"] = content
 					}
+				} else {
+					// For assistant messages, extract all text parts
+					message.Content = d.c.extractAllText(content)
+					message.Metadata["raw_content"] = content
 				}
+			}
+			if role == "assistant" && d.model != "" {
+				message.Metadata["model"] = d.model
+			}
 
-				// Skip session prefix messages
-				if role == "user" && c.isSessionPrefix(strings.TrimSpace(message.Content)) {
-					continue
-				}
+			// Skip session prefix messages
+			if role == "user" && d.c.isSessionPrefix(strings.TrimSpace(message.Content)) {
+				return messages
+			}
 
-				messages = append(messages, message)
+			messages = append(messages, message)
+			if role == "assistant" {
+				d.lastAssistant = len(messages) - 1
 			}
 		}
 	}
+	return messages
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading rollout file: %w", err)
+// settled holds back the last assistant message, since token usage read
+// later is added to it.
+func (d *codexDecoder) settled(n int) int {
+	if d.lastAssistant >= 0 {
+		return min(n-1, d.lastAssistant)
+	}
+	return max(n-1, 0)
+}
+
+func (d *codexDecoder) state() interface{} {
+	if d.model == "" {
+		return nil
 	}
+	return codexReaderState{Model: d.model}
+}
 
-	return messages, nil
+func (d *codexDecoder) restore(state json.RawMessage) error {
+	var s codexReaderState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return err
+	}
+	d.model = s.Model
+	return nil
 }
 
 // extractAllText extracts all text from content blocks (for assistant messages).
@@ -576,3 +656,154 @@ func (c *CodexAdapter) SearchSessions(projectPath, query string, limit int) ([]S
 
 	return matches, nil
 }
+
+// codexTokenUsage reads the usage of the last model request from a
+// token_count event. Codex counts cached input within input_tokens and
+// reasoning within output_tokens, so both are split out.
+func codexTokenUsage(payload map[string]interface{}) (map[string]interface{}, bool) {
+	if payload["type"] != "token_count" {
+		return nil, false
+	}
+	info, _ := payload["info"].(map[string]interface{})
+	last, ok := info["last_token_usage"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	cached := intField(last, "cached_input_tokens")
+	reasoning := intField(last, "reasoning_output_tokens")
+	return map[string]interface{}{
+		"input":     float64(intField(last, "input_tokens") - cached),
+		"output":    float64(intField(last, "output_tokens") - reasoning),
+		"reasoning": float64(reasoning),
+		"cache":     map[string]interface{}{"read": float64(cached)},
+	}, true
+}
+
+// addCodexTokens adds usage to a message's "tokens" metadata. A reply that
+// took several model requests (one per tool call round trip) gets their sum.
+func addCodexTokens(metadata map[string]interface{}, usage map[string]interface{}) {
+	existing, ok := metadata["tokens"].(map[string]interface{})
+	if !ok {
+		metadata["tokens"] = usage
+		return
+	}
+	for _, key := range []string{"input", "output", "reasoning"} {
+		existing[key] = float64(intField(existing, key) + intField(usage, key))
+	}
+	cache, _ := existing["cache"].(map[string]interface{})
+	added, _ := usage["cache"].(map[string]interface{})
+	existing["cache"] = map[string]interface{}{"read": float64(intField(cache, "read") + intField(added, "read"))}
+}
+
+// codexEntryTypes are the rollout line types Codex is known to write.
+var codexEntryTypes = []string{"session_meta", "turn_context", "response_item", "event_msg", "compacted"}
+
+// codexResponseItemTypes are the known payload types of response_item lines.
+// Only messages feed sessions; the rest are recognized and ignored.
+var codexResponseItemTypes = []string{
+	"message", "reasoning", "function_call", "function_call_output",
+	"custom_tool_call", "custom_tool_call_output", "local_shell_call", "web_search_call",
+}
+
+// ValidateSession checks a rollout file strictly against the format this
+// adapter reads, reporting every line it would skip or misread.
+func (c *CodexAdapter) ValidateSession(session Session) ([]SchemaDeviation, error) {
+	return validateJSONLFile(session.FilePath, func(entry map[string]interface{}, d *deviations) {
+		entryType := d.str(entry, "", "type", true)
+		d.oneOf("type", "entry type", entryType, codexEntryTypes...)
+		d.timestamp(entry, "", "timestamp", true)
+		payload := d.object(entry, "", "payload", true)
+		if payload == nil {
+			return
+		}
+
+		switch entryType {
+		case "session_meta":
+			d.str(payload, "payload", "id", true)
+			d.str(payload, "payload", "cwd", true)
+			d.timestamp(payload, "payload", "timestamp", false)
+		case "turn_context":
+			d.str(payload, "payload", "cwd", false)
+			d.str(payload, "payload", "model", false)
+		case "event_msg":
+			if d.str(payload, "payload", "type", true) == "token_count" {
+				if info := d.object(payload, "payload", "info", false); info != nil {
+					d.object(info, "payload.info", "last_token_usage", true)
+				}
+			}
+		case "response_item":
+			itemType := d.str(payload, "payload", "type", true)
+			d.oneOf("payload.type", "response item type", itemType, codexResponseItemTypes...)
+			if itemType != "message" {
+				return
+			}
+			d.oneOf("payload.role", "role", d.str(payload, "payload", "role", true), "user", "assistant", "developer", "system")
+			content := d.array(payload, "payload", "content", true)
+			d.objects(content, "payload.content", func(block map[string]interface{}, path string) {
+				blockType := d.str(block, path, "type", true)
+				d.oneOf(path+".type", "content block type", blockType, "input_text", "output_text", "input_image")
+				if blockType == "input_text" || blockType == "output_text" {
+					d.str(block, path, "text", true)
+				}
+			})
+		}
+	})
+}
+
+// DetectPersonas returns the custom instructions a Codex session ran with.
+// Codex records them in the rollout file, either in the session metadata or
+// as a <user_instructions> prefix message, so they apply to every message.
+func (c *CodexAdapter) DetectPersonas(sessionID string) ([]Persona, error) {
+	sessionFile := c.findRolloutFile(sessionID)
+	if sessionFile == "" {
+		return nil, SessionNotFoundError(sessionID)
+	}
+	messages, err := c.readAllMessages(sessionFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(sessionFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rollout file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	setScanBuffer(scanner, 1024*1024, codexMaxLine)
+	var instructions string
+	for scanner.Scan() && instructions == "" {
+		var entry codexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		switch entry.Type {
+		case "session_meta":
+			instructions, _ = entry.Payload["instructions"].(string)
+		case "response_item":
+			if role, _ := entry.Payload["role"].(string); role != "user" {
+				continue
+			}
+			content, _ := entry.Payload["content"].([]interface{})
+			text := strings.TrimSpace(c.extractAllText(content))
+			if strings.HasPrefix(strings.ToLower(text), "<user_instructions>") {
+				instructions = strings.TrimSpace(text[len("<user_instructions>"):])
+				instructions = strings.TrimSuffix(instructions, "</user_instructions>")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rollout file: %w", err)
+	}
+
+	if strings.TrimSpace(instructions) == "" {
+		return []Persona{}, nil
+	}
+	return []Persona{{
+		Kind:     PersonaInstructions,
+		Name:     "user_instructions",
+		Evidence: EvidenceTranscript,
+		Excerpt:  instructionsExcerpt(instructions),
+		Messages: len(messages),
+	}}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -15,7 +16,13 @@ import (
 // CopilotAdapter implements SessionAdapter for GitHub Copilot CLI sessions.
 // Copilot CLI stores sessions as JSONL files in ~/.copilot/session-state/
 type CopilotAdapter struct {
-	homeDir string
+	homeDir       string
+	metadataCache MetadataCache
+	offsetCache   OffsetCache
+}
+
+func init() {
+	Register("copilot", func() (SessionAdapter, error) { return NewCopilotAdapter() })
 }
 
 // NewCopilotAdapter creates a new GitHub Copilot CLI session adapter.
@@ -34,6 +41,23 @@ func (c *CopilotAdapter) Name() string {
 	return "copilot"
 }
 
+// WatchPaths returns the directory Copilot CLI writes session files to.
+func (c *CopilotAdapter) WatchPaths() []string {
+	return []string{filepath.Join(c.homeDir, ".copilot", "session-state")}
+}
+
+// SetMetadataCache makes ListSessions reuse metadata cached for unchanged
+// session files instead of parsing them again.
+func (c *CopilotAdapter) SetMetadataCache(cache MetadataCache) {
+	c.metadataCache = cache
+}
+
+// SetOffsetCache makes GetSession seek straight to the requested page of
+// session files it has read before.
+func (c *CopilotAdapter) SetOffsetCache(cache OffsetCache) {
+	c.offsetCache = cache
+}
+
 // copilotEvent represents a single event line in a Copilot JSONL session file.
 type copilotEvent struct {
 	Type      string          `json:"type"`
@@ -103,13 +127,9 @@ func (c *CopilotAdapter) ListSessions(projectPath string, limit int) ([]Session,
 		return []Session{}, nil // No sessions
 	}
 
-	// Get absolute path if provided
-	if projectPath != "" {
-		var err error
-		projectPath, err = filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Read all *.jsonl files
@@ -120,14 +140,16 @@ func (c *CopilotAdapter) ListSessions(projectPath string, limit int) ([]Session,
 
 	sessions := make([]Session, 0, len(files))
 	for _, filePath := range files {
-		session, err := c.parseSessionMetadata(filePath)
+		session, err := cachedMetadata(c.metadataCache, filePath, func() (Session, error) {
+			return c.parseSessionMetadata(filePath)
+		})
 		if err != nil {
 			// Skip files we can't parse
 			continue
 		}
 
 		// Filter by project path if specified
-		if projectPath != "" && session.ProjectPath != projectPath {
+		if !filter.Match(session.ProjectPath) {
 			continue
 		}
 
@@ -149,6 +171,9 @@ func (c *CopilotAdapter) ListSessions(projectPath string, limit int) ([]Session,
 
 // parseSessionMetadata extracts metadata from a Copilot CLI session file.
 func (c *CopilotAdapter) parseSessionMetadata(filePath string) (Session, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return Session{}, err
+	}
 	file, err := os.Open(filePath)
 	if err != nil {
 		return Session{}, fmt.Errorf("failed to open session file: %w", err)
@@ -169,8 +194,7 @@ func (c *CopilotAdapter) parseSessionMetadata(filePath string) (Session, error)
 
 	scanner := bufio.NewScanner(file)
 	// Increase buffer size for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	setScanBuffer(scanner, 64*1024, 1024*1024)
 
 	for scanner.Scan() {
 		var event copilotEvent
@@ -271,151 +295,196 @@ func findCommonDirectory(paths []string) string {
 
 // GetSession retrieves the full content of a Copilot CLI session with pagination.
 func (c *CopilotAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
-	sessionsDir := filepath.Join(c.homeDir, ".copilot", "session-state")
-
-	// Try to find the session file directly by ID
-	sessionFile := filepath.Join(sessionsDir, sessionID+".jsonl")
-	if _, err := os.Stat(sessionFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
-	}
-
-	// Read all messages from the session
-	messages, err := c.readAllMessages(sessionFile)
+	sessionFile, err := c.sessionFile(sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply pagination
-	start := page * pageSize
-	if start >= len(messages) {
-		return []Message{}, nil
+	return readJSONLMessages(c.offsetCache, "copilot", sessionFile, newCopilotDecoder, copilotMaxLine, page*pageSize, pageSize)
+}
+
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (c *CopilotAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile, err := c.sessionFile(sessionID)
+	if err != nil {
+		return nil, 0, page, false, err
 	}
+	return readJSONLPage(c.offsetCache, "copilot", sessionFile, newCopilotDecoder, copilotMaxLine, page, pageSize, fromEnd)
+}
 
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
+// StreamSessionPage reads the same page as GetSessionPage, passing each
+// message to yield as it is read instead of returning the page.
+func (c *CopilotAdapter) StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(Message) error) (int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile, err := c.sessionFile(sessionID)
+	if err != nil {
+		return 0, page, false, err
 	}
+	return streamJSONLPage(c.offsetCache, "copilot", sessionFile, newCopilotDecoder, copilotMaxLine, page, pageSize, fromEnd, yield)
+}
 
-	return messages[start:end], nil
+// sessionFile returns the file holding a session, which is named by its ID.
+func (c *CopilotAdapter) sessionFile(sessionID string) (string, error) {
+	sessionFile := filepath.Join(c.homeDir, ".copilot", "session-state", sessionID+".jsonl")
+	if _, err := os.Stat(sessionFile); os.IsNotExist(err) {
+		return "", SessionNotFoundError(sessionID)
+	}
+	return sessionFile, nil
 }
 
+// copilotMaxLine is the longest session file line Copilot CLI sessions are read with.
+const copilotMaxLine = 1024 * 1024
+
 // readAllMessages reads all messages from a Copilot CLI session file.
 func (c *CopilotAdapter) readAllMessages(filePath string) ([]Message, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open session file: %w", err)
-	}
-	defer file.Close()
+	return readJSONLMessages(nil, "copilot", filePath, newCopilotDecoder, copilotMaxLine, 0, -1)
+}
 
-	var messages []Message
-	var currentModel string
+// copilotDecoder decodes Copilot CLI session events. The model is only named
+// when it changes, and tool durations need the start of each execution, so
+// both are carried between lines.
+type copilotDecoder struct {
+	model   string
+	started map[string]time.Time // tool call ID -> execution start, until it completes
+}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+// copilotReaderState is what copilotDecoder carries between lines.
+type copilotReaderState struct {
+	Model   string               `json:"model,omitempty"`
+	Started map[string]time.Time `json:"started,omitempty"`
+}
 
-	for scanner.Scan() {
-		var event copilotEvent
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			continue
+func newCopilotDecoder() jsonlDecoder {
+	return &copilotDecoder{started: make(map[string]time.Time)}
+}
+
+func (d *copilotDecoder) decode(line []byte, messages []Message) []Message {
+	var event copilotEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return messages
+	}
+
+	var timestamp time.Time
+	if event.Timestamp != "" {
+		if ts, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
+			timestamp = ts
+		} else if ts, err := time.Parse(time.RFC3339, event.Timestamp); err == nil {
+			timestamp = ts
 		}
+	}
 
-		var timestamp time.Time
-		if event.Timestamp != "" {
-			if ts, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
-				timestamp = ts
-			} else if ts, err := time.Parse(time.RFC3339, event.Timestamp); err == nil {
-				timestamp = ts
-			}
+	switch event.Type {
+	case "session.model_change":
+		var data copilotModelChange
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			d.model = data.NewModel
 		}
 
-		switch event.Type {
-		case "session.model_change":
-			var data copilotModelChange
-			if err := json.Unmarshal(event.Data, &data); err == nil {
-				currentModel = data.NewModel
+	case "user.message":
+		var data copilotUserMessage
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			msg := Message{
+				Role:      "user",
+				Content:   data.Content,
+				Timestamp: timestamp,
+				Metadata:  make(map[string]interface{}),
 			}
-
-		case "user.message":
-			var data copilotUserMessage
-			if err := json.Unmarshal(event.Data, &data); err == nil {
-				msg := Message{
-					Role:      "user",
-					Content:   data.Content,
-					Timestamp: timestamp,
-					Metadata:  make(map[string]interface{}),
-				}
-				if currentModel != "" {
-					msg.Metadata["model"] = currentModel
-				}
-				messages = append(messages, msg)
+			if d.model != "" {
+				msg.Metadata["model"] = d.model
 			}
+			messages = append(messages, msg)
+		}
 
-		case "assistant.message":
-			var data copilotAssistantMessage
-			if err := json.Unmarshal(event.Data, &data); err == nil {
-				msg := Message{
-					Role:      "assistant",
-					Content:   data.Content,
-					Timestamp: timestamp,
-					Metadata:  make(map[string]interface{}),
-				}
-				if currentModel != "" {
-					msg.Metadata["model"] = currentModel
-				}
-				// Add tool requests to metadata if present
-				if len(data.ToolRequests) > 0 {
-					toolCalls := make([]map[string]interface{}, len(data.ToolRequests))
-					for i, tr := range data.ToolRequests {
-						var args interface{}
-						if err := json.Unmarshal(tr.Arguments, &args); err != nil {
-							// Fallback to raw string if unmarshaling fails
-							args = string(tr.Arguments)
-						}
-						toolCalls[i] = map[string]interface{}{
-							"id":        tr.ToolCallID,
-							"name":      tr.Name,
-							"arguments": args,
-						}
+	case "assistant.message":
+		var data copilotAssistantMessage
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			msg := Message{
+				Role:      "assistant",
+				Content:   data.Content,
+				Timestamp: timestamp,
+				Metadata:  make(map[string]interface{}),
+			}
+			if d.model != "" {
+				msg.Metadata["model"] = d.model
+			}
+			// Add tool requests to metadata if present
+			if len(data.ToolRequests) > 0 {
+				toolCalls := make([]map[string]interface{}, len(data.ToolRequests))
+				for i, tr := range data.ToolRequests {
+					var args interface{}
+					if err := json.Unmarshal(tr.Arguments, &args); err != nil {
+						// Fallback to raw string if unmarshaling fails
+						args = string(tr.Arguments)
+					}
+					toolCalls[i] = map[string]interface{}{
+						"id":        tr.ToolCallID,
+						"name":      tr.Name,
+						"arguments": args,
 					}
-					msg.Metadata["tool_calls"] = toolCalls
 				}
-				messages = append(messages, msg)
+				msg.Metadata["tool_calls"] = toolCalls
 			}
+			messages = append(messages, msg)
+		}
 
-		case "tool.execution_complete":
-			var data copilotToolExecution
-			if err := json.Unmarshal(event.Data, &data); err == nil {
-				var result interface{}
-				json.Unmarshal(data.Result, &result)
-				msg := Message{
-					Role:      "tool",
-					Timestamp: timestamp,
-					Metadata: map[string]interface{}{
-						"tool_call_id": data.ToolCallID,
-						"tool_name":    data.ToolName,
-						"success":      data.Success,
-						"result":       result,
-					},
-				}
-				// Format tool result as content
-				if resultStr, ok := result.(string); ok {
-					msg.Content = resultStr
-				} else if result != nil {
-					if resultBytes, err := json.Marshal(result); err == nil {
-						msg.Content = string(resultBytes)
-					}
+	case "tool.execution_start":
+		var data copilotToolExecution
+		if err := json.Unmarshal(event.Data, &data); err == nil && !timestamp.IsZero() {
+			d.started[data.ToolCallID] = timestamp
+		}
+
+	case "tool.execution_complete":
+		var data copilotToolExecution
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			var result interface{}
+			json.Unmarshal(data.Result, &result)
+			msg := Message{
+				Role:      "tool",
+				Timestamp: timestamp,
+				Metadata: map[string]interface{}{
+					"tool_call_id": data.ToolCallID,
+					"tool_name":    data.ToolName,
+					"success":      data.Success,
+					"result":       result,
+				},
+			}
+			if start, ok := d.started[data.ToolCallID]; ok && !timestamp.Before(start) {
+				msg.Metadata["duration_ms"] = timestamp.Sub(start).Milliseconds()
+			}
+			delete(d.started, data.ToolCallID)
+			// Format tool result as content
+			if resultStr, ok := result.(string); ok {
+				msg.Content = resultStr
+			} else if result != nil {
+				if resultBytes, err := json.Marshal(result); err == nil {
+					msg.Content = string(resultBytes)
 				}
-				messages = append(messages, msg)
 			}
+			messages = append(messages, msg)
 		}
 	}
+	return messages
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading session file: %w", err)
+func (d *copilotDecoder) state() interface{} {
+	if d.model == "" && len(d.started) == 0 {
+		return nil
 	}
+	return copilotReaderState{Model: d.model, Started: maps.Clone(d.started)}
+}
 
-	return messages, nil
+func (d *copilotDecoder) restore(state json.RawMessage) error {
+	var s copilotReaderState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return err
+	}
+	d.model = s.Model
+	if s.Started != nil {
+		d.started = s.Started
+	}
+	return nil
 }
 
 // SearchSessions searches Copilot CLI sessions for the given query.
@@ -427,12 +496,9 @@ func (c *CopilotAdapter) SearchSessions(projectPath, query string, limit int) ([
 		return []Session{}, nil
 	}
 
-	if projectPath != "" {
-		var err error
-		projectPath, err = filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
 	}
 
 	files, err := filepath.Glob(filepath.Join(sessionsDir, "*.jsonl"))
@@ -451,7 +517,7 @@ func (c *CopilotAdapter) SearchSessions(projectPath, query string, limit int) ([
 		}
 
 		// Filter by project path if specified
-		if projectPath != "" && session.ProjectPath != projectPath {
+		if !filter.Match(session.ProjectPath) {
 			continue
 		}
 
@@ -483,6 +549,9 @@ func (c *CopilotAdapter) SearchSessions(projectPath, query string, limit int) ([
 // parseSessionWithContents reads a session file and returns metadata plus all message contents.
 // This avoids reading the file twice when both are needed for searching.
 func (c *CopilotAdapter) parseSessionWithContents(filePath string) (Session, []string, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return Session{}, nil, err
+	}
 	file, err := os.Open(filePath)
 	if err != nil {
 		return Session{}, nil, fmt.Errorf("failed to open session file: %w", err)
@@ -500,8 +569,7 @@ func (c *CopilotAdapter) parseSessionWithContents(filePath string) (Session, []s
 	userCount := 0
 
 	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	setScanBuffer(scanner, 64*1024, 1024*1024)
 
 	for scanner.Scan() {
 		var event copilotEvent
@@ -579,3 +647,67 @@ func (c *CopilotAdapter) parseSessionWithContents(filePath string) (Session, []s
 
 	return session, contents, nil
 }
+
+// copilotEventTypes are the event types this adapter reads.
+var copilotEventTypes = []string{
+	"session.start", "session.info", "session.model_change", "user.message",
+	"assistant.message", "tool.execution_start", "tool.execution_complete",
+}
+
+// ValidateSession checks a session file strictly against the format this
+// adapter reads, reporting every event it would skip or misread.
+func (c *CopilotAdapter) ValidateSession(session Session) ([]SchemaDeviation, error) {
+	return validateJSONLFile(session.FilePath, func(event map[string]interface{}, d *deviations) {
+		eventType := d.str(event, "", "type", true)
+		d.oneOf("type", "event type", eventType, copilotEventTypes...)
+		d.str(event, "", "id", true)
+		d.timestamp(event, "", "timestamp", true)
+		data := d.object(event, "", "data", true)
+		if data == nil {
+			return
+		}
+
+		switch eventType {
+		case "session.start":
+			d.str(data, "data", "sessionId", true)
+			d.timestamp(data, "data", "startTime", true)
+		case "session.model_change":
+			d.str(data, "data", "newModel", true)
+		case "user.message":
+			d.str(data, "data", "content", true)
+		case "assistant.message":
+			d.str(data, "data", "content", true)
+			d.objects(d.array(data, "data", "toolRequests", false), "data.toolRequests", func(req map[string]interface{}, path string) {
+				d.str(req, path, "toolCallId", true)
+				d.str(req, path, "name", true)
+			})
+		case "tool.execution_start", "tool.execution_complete":
+			d.str(data, "data", "toolCallId", true)
+			d.str(data, "data", "toolName", eventType == "tool.execution_start")
+		}
+	})
+}
+
+// DetectPersonas returns the custom instructions files in a Copilot CLI
+// session's project. Session logs don't record which instructions were
+// loaded, so these are the files as they are now.
+func (c *CopilotAdapter) DetectPersonas(sessionID string) ([]Persona, error) {
+	sessionFile := filepath.Join(c.homeDir, ".copilot", "session-state", sessionID+".jsonl")
+	if _, err := os.Stat(sessionFile); os.IsNotExist(err) {
+		return nil, SessionNotFoundError(sessionID)
+	}
+	session, err := c.parseSessionMetadata(sessionFile)
+	if err != nil {
+		return nil, err
+	}
+
+	personas := projectInstructions(session.ProjectPath,
+		filepath.Join(".github", "copilot-instructions.md"),
+		filepath.Join(".github", "instructions", "*.instructions.md"),
+		"AGENTS.md",
+	)
+	if personas == nil {
+		personas = []Persona{}
+	}
+	return personas, nil
+}
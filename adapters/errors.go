@@ -0,0 +1,32 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Failure kinds that callers can branch on with errors.Is. Errors returned by
+// adapters and tools wrap one of these when the failure is one of these kinds.
+var (
+	// ErrSessionNotFound means no session with the given ID exists in the source.
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSourceUnavailable means the source isn't known or has no data on this machine.
+	ErrSourceUnavailable = errors.New("unknown or unavailable source")
+
+	// ErrFormatUnsupported means a requested format, or the format of a file, isn't supported.
+	ErrFormatUnsupported = errors.New("unsupported format")
+
+	// ErrFileTooLarge means a session file is over the configured size limit.
+	ErrFileTooLarge = errors.New("session file too large")
+)
+
+// SessionNotFoundError returns an ErrSessionNotFound error naming the session.
+func SessionNotFoundError(sessionID string) error {
+	return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+}
+
+// SourceUnavailableError returns an ErrSourceUnavailable error naming the source.
+func SourceUnavailableError(source string) error {
+	return fmt.Errorf("%w: %s", ErrSourceUnavailable, source)
+}
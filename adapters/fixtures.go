@@ -0,0 +1,597 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FixtureOptions describes the synthetic sessions GenerateFixtures writes.
+// Zero fields take the defaults noted on each.
+type FixtureOptions struct {
+	// Sessions is the number of sessions to write (default 10)
+	Sessions int `json:"sessions,omitempty"`
+
+	// Turns is the number of user prompts per session (default 3). Each gets
+	// an assistant reply with a few tool calls.
+	Turns int `json:"turns,omitempty"`
+
+	// Projects is the number of projects sessions are spread over (default 3)
+	Projects int `json:"projects,omitempty"`
+
+	// ProjectRoot is the directory the fictional projects live in (default
+	// /home/dev/src). Nothing is written there.
+	ProjectRoot string `json:"project_root,omitempty"`
+
+	// Seed makes the output reproducible: the same options give the same files
+	Seed int64 `json:"seed,omitempty"`
+
+	// End is when the newest session ends (default now). Sessions are spread
+	// over the 90 days before it.
+	End time.Time `json:"end,omitempty"`
+}
+
+// FixtureSources lists the sources GenerateFixtures can write, sorted.
+func FixtureSources() []string {
+	return []string{"claude", "codex", "copilot", "gemini", "mistral", "opencode"}
+}
+
+// GenerateFixtures writes synthetic sessions for source under home, laid out
+// the way that agent stores them in a home directory (for example
+// home/.claude/projects/...). Point an adapter's home directory, or $HOME, at
+// it to read them back. The sessions read like real coding work (prompts,
+// replies, file reads, edits, and test runs) but are made up, so they can be
+// shared when reproducing issues. It returns the files written.
+func GenerateFixtures(home, source string, opts FixtureOptions) ([]string, error) {
+	write, ok := fixtureWriters[source]
+	if !ok {
+		return nil, fmt.Errorf("%w %q for fixtures (expected one of %s)", ErrSourceUnavailable, source, strings.Join(FixtureSources(), ", "))
+	}
+	if opts.Sessions <= 0 {
+		opts.Sessions = 10
+	}
+	if opts.Turns <= 0 {
+		opts.Turns = 3
+	}
+	if opts.Projects <= 0 {
+		opts.Projects = 3
+	}
+	if opts.ProjectRoot == "" {
+		opts.ProjectRoot = "/home/dev/src"
+	}
+	if opts.End.IsZero() {
+		opts.End = time.Now()
+	}
+
+	// Each source gets its own stream, so adding a source doesn't change the others
+	rng := rand.New(rand.NewSource(opts.Seed + int64(len(source))*7919 + int64(source[0])))
+	files := make([]string, 0, opts.Sessions)
+	for i := 0; i < opts.Sessions; i++ {
+		conv := newFixtureConversation(rng, opts)
+		path, err := write(home, conv)
+		if err != nil {
+			return files, err
+		}
+		if err := os.Chtimes(path, conv.end(), conv.end()); err != nil {
+			return files, fmt.Errorf("failed to set times on %s: %w", path, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// Building blocks for synthetic conversations.
+var (
+	fixtureProjects = []string{"billing-api", "web-dashboard", "cli-tools", "data-pipeline", "auth-service", "mobile-app", "docs-site", "infra"}
+	fixtureFiles    = []string{"main.go", "internal/server/handler.go", "internal/store/postgres.go", "pkg/retry/retry.go", "cmd/worker/main.go", "api/routes.go", "config/config.go", "README.md"}
+	fixtureTasks    = []string{
+		"Fix the flaky test in %s",
+		"Add pagination to the list endpoint in %s",
+		"Refactor %s to remove the duplicated error handling",
+		"Investigate the nil pointer panic in %s",
+		"Update the docs for the options in %s",
+		"Add retry with backoff to the client in %s",
+		"Why does %s time out under load?",
+		"Rename the config fields in %s to match the new schema",
+	}
+	fixtureFollowUps = []string{
+		"Looks good. Can you also add a test for the empty case?",
+		"The build fails on CI, can you check?",
+		"Please keep the public API unchanged.",
+		"Now run the whole test suite.",
+		"Can you explain why that fixes it?",
+	}
+	fixtureReplies = []string{
+		"I found the cause: the loop reuses the same variable, so every goroutine sees the last value. I changed it to copy the value per iteration and the tests pass now.",
+		"The handler returned before closing the response body, which leaked connections under load. I added a deferred close and a test that checks the pool is drained.",
+		"I added the option with a default that keeps the current behaviour, documented it, and covered both paths with tests.",
+		"The error was swallowed in the retry helper. It now wraps the last error so callers can inspect it with errors.Is.",
+		"I updated the code and the tests. Next steps:\n- [ ] Update the changelog\n- [ ] Check the staging dashboard after deploying",
+	}
+	fixtureModels = map[string][]string{
+		"claude":   {"claude-sonnet-4-5-20250929", "claude-opus-4-1-20250805"},
+		"codex":    {"gpt-5-codex", "gpt-5"},
+		"copilot":  {"claude-sonnet-4.5", "gpt-5"},
+		"gemini":   {"gemini-2.5-pro", "gemini-2.5-flash"},
+		"mistral":  {"devstral-medium", "mistral-large"},
+		"opencode": {"claude-sonnet-4-5", "gpt-5"},
+	}
+)
+
+// fixtureStep is one tool call in a synthetic reply.
+type fixtureStep struct {
+	kind    string // "read", "edit", or "run"
+	file    string // absolute path, for read and edit
+	command string // for run
+	output  string
+	ok      bool
+	at      time.Time
+	took    time.Duration
+}
+
+// fixtureTurn is a user prompt and the reply to it.
+type fixtureTurn struct {
+	prompt string
+	reply  string
+	steps  []fixtureStep
+	at     time.Time // when the prompt was sent
+	doneAt time.Time // when the reply finished
+	input  int       // tokens
+	output int
+}
+
+// fixtureConversation is a synthetic session, independent of any format.
+type fixtureConversation struct {
+	id      string
+	project string
+	model   string
+	start   time.Time
+	turns   []fixtureTurn
+	rng     *rand.Rand
+}
+
+func (c fixtureConversation) end() time.Time {
+	return c.turns[len(c.turns)-1].doneAt
+}
+
+// fixtureUUID returns a random version 4 UUID from rng.
+func fixtureUUID(rng *rand.Rand) string {
+	return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", rng.Uint32(), rng.Intn(1<<16), rng.Intn(1<<12), 0x8000|rng.Intn(1<<14), rng.Int63n(1<<48))
+}
+
+func newFixtureConversation(rng *rand.Rand, opts FixtureOptions) fixtureConversation {
+	project := filepath.Join(opts.ProjectRoot, fixtureProjects[rng.Intn(min(opts.Projects, len(fixtureProjects)))])
+	start := opts.End.Add(-time.Duration(rng.Int63n(int64(90 * 24 * time.Hour)))).Truncate(time.Second)
+	conv := fixtureConversation{id: fixtureUUID(rng), project: project, start: start, rng: rng}
+
+	at := start
+	for t := 0; t < opts.Turns; t++ {
+		file := filepath.Join(project, fixtureFiles[rng.Intn(len(fixtureFiles))])
+		rel, _ := filepath.Rel(project, file)
+		turn := fixtureTurn{at: at, input: 2000 + rng.Intn(30000), output: 200 + rng.Intn(2000)}
+		if t == 0 {
+			turn.prompt = fmt.Sprintf(fixtureTasks[rng.Intn(len(fixtureTasks))], rel)
+		} else {
+			turn.prompt = fixtureFollowUps[rng.Intn(len(fixtureFollowUps))]
+		}
+		turn.reply = fixtureReplies[rng.Intn(len(fixtureReplies))]
+
+		stepAt := at.Add(time.Duration(2+rng.Intn(10)) * time.Second)
+		addStep := func(step fixtureStep) {
+			step.at = stepAt
+			stepAt = stepAt.Add(step.took + time.Duration(1+rng.Intn(8))*time.Second)
+			turn.steps = append(turn.steps, step)
+		}
+		addStep(fixtureStep{kind: "read", file: file, ok: true, took: time.Duration(5+rng.Intn(50)) * time.Millisecond,
+			output: "package main\n\nfunc run() error {\n\treturn nil\n}\n"})
+		addStep(fixtureStep{kind: "edit", file: file, ok: true, took: time.Duration(10+rng.Intn(80)) * time.Millisecond,
+			output: "The file " + file + " has been updated."})
+		failed := rng.Intn(5) == 0
+		output := "ok  \texample.com/" + filepath.Base(project) + "\t0.41s"
+		if failed {
+			output = "--- FAIL: TestRun (0.00s)\n    main_test.go:12: unexpected error\nFAIL"
+		}
+		addStep(fixtureStep{kind: "run", command: "go test ./...", ok: !failed, output: output, took: time.Duration(800+rng.Intn(20000)) * time.Millisecond})
+		if failed {
+			addStep(fixtureStep{kind: "edit", file: file, ok: true, took: time.Duration(10+rng.Intn(80)) * time.Millisecond,
+				output: "The file " + file + " has been updated."})
+			addStep(fixtureStep{kind: "run", command: "go test ./...", ok: true, output: "ok  \texample.com/" + filepath.Base(project) + "\t0.38s", took: time.Duration(800+rng.Intn(20000)) * time.Millisecond})
+		}
+
+		turn.doneAt = stepAt
+		conv.turns = append(conv.turns, turn)
+		at = stepAt.Add(time.Duration(20+rng.Intn(600)) * time.Second)
+	}
+	return conv
+}
+
+// fixtureEdit returns a plausible old and new string for an edit step.
+func fixtureEdit() (string, string) {
+	return "\treturn nil", "\tif err := validate(); err != nil {\n\t\treturn fmt.Errorf(\"run: %w\", err)\n\t}\n\treturn nil"
+}
+
+// fixtureStamp formats t the way most agents write timestamps.
+func fixtureStamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// writeFixtureJSON writes v as JSON, creating parent directories.
+func writeFixtureJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return writeFixtureFile(path, data)
+}
+
+// writeFixtureJSONL writes one JSON line per entry, creating parent directories.
+func writeFixtureJSONL(path string, entries []interface{}) error {
+	var b strings.Builder
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return writeFixtureFile(path, []byte(b.String()))
+}
+
+func writeFixtureFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// fixtureWriters write a conversation in each source's format and return the
+// session file.
+var fixtureWriters = map[string]func(home string, conv fixtureConversation) (string, error){
+	"claude":   writeClaudeFixture,
+	"codex":    writeCodexFixture,
+	"copilot":  writeCopilotFixture,
+	"gemini":   writeGeminiFixture,
+	"mistral":  writeMistralFixture,
+	"opencode": writeOpencodeFixture,
+}
+
+func (c fixtureConversation) pickModel(source string) string {
+	models := fixtureModels[source]
+	return models[c.rng.Intn(len(models))]
+}
+
+func writeClaudeFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("claude")
+	var lines []interface{}
+	parent := ""
+	line := func(kind string, at time.Time, message map[string]interface{}) map[string]interface{} {
+		uuid := fixtureUUID(conv.rng)
+		entry := map[string]interface{}{
+			"type": kind, "uuid": uuid, "sessionId": conv.id, "cwd": conv.project,
+			"timestamp": fixtureStamp(at), "version": "2.0.14", "isSidechain": false, "userType": "external",
+			"message": message,
+		}
+		if parent != "" {
+			entry["parentUuid"] = parent
+		}
+		parent = uuid
+		return entry
+	}
+
+	for _, turn := range conv.turns {
+		lines = append(lines, line("user", turn.at, map[string]interface{}{"role": "user", "content": turn.prompt}))
+		usage := map[string]interface{}{"input_tokens": turn.input, "output_tokens": turn.output, "cache_read_input_tokens": turn.input / 2}
+		msgID := "msg_" + strings.ReplaceAll(fixtureUUID(conv.rng), "-", "")[:24]
+		for i, step := range turn.steps {
+			id := fmt.Sprintf("toolu_%s%02d", strings.ReplaceAll(conv.id, "-", "")[:16], i)
+			var name string
+			var input map[string]interface{}
+			switch step.kind {
+			case "read":
+				name, input = "Read", map[string]interface{}{"file_path": step.file}
+			case "edit":
+				oldString, newString := fixtureEdit()
+				name, input = "Edit", map[string]interface{}{"file_path": step.file, "old_string": oldString, "new_string": newString}
+			default:
+				name, input = "Bash", map[string]interface{}{"command": step.command, "description": "Run the tests"}
+			}
+			lines = append(lines, line("assistant", step.at, map[string]interface{}{
+				"id": msgID, "role": "assistant", "model": model, "usage": usage,
+				"content": []interface{}{map[string]interface{}{"type": "tool_use", "id": id, "name": name, "input": input}},
+			}))
+			lines = append(lines, line("user", step.at.Add(step.took), map[string]interface{}{
+				"role": "user",
+				"content": []interface{}{map[string]interface{}{
+					"type": "tool_result", "tool_use_id": id, "content": step.output, "is_error": !step.ok,
+				}},
+			}))
+		}
+		lines = append(lines, line("assistant", turn.doneAt, map[string]interface{}{
+			"id": msgID, "role": "assistant", "model": model, "usage": usage,
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": turn.reply}},
+		}))
+	}
+
+	path := filepath.Join(home, ".claude", "projects", projectDirName(conv.project), conv.id+".jsonl")
+	return path, writeFixtureJSONL(path, lines)
+}
+
+func writeCodexFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("codex")
+	lines := []interface{}{
+		map[string]interface{}{"timestamp": fixtureStamp(conv.start), "type": "session_meta", "payload": map[string]interface{}{
+			"id": conv.id, "timestamp": fixtureStamp(conv.start), "cwd": conv.project, "originator": "codex_cli_rs", "cli_version": "0.46.0",
+		}},
+		map[string]interface{}{"timestamp": fixtureStamp(conv.start), "type": "response_item", "payload": map[string]interface{}{
+			"type": "message", "role": "user", "content": []interface{}{map[string]interface{}{
+				"type": "input_text", "text": "<environment_context>\n  <cwd>" + conv.project + "</cwd>\n</environment_context>",
+			}},
+		}},
+	}
+	for t, turn := range conv.turns {
+		lines = append(lines,
+			map[string]interface{}{"timestamp": fixtureStamp(turn.at), "type": "turn_context", "payload": map[string]interface{}{"cwd": conv.project, "model": model, "approval_policy": "on-request"}},
+			map[string]interface{}{"timestamp": fixtureStamp(turn.at), "type": "response_item", "payload": map[string]interface{}{
+				"type": "message", "role": "user", "content": []interface{}{map[string]interface{}{"type": "input_text", "text": turn.prompt}},
+			}},
+		)
+		for i, step := range turn.steps {
+			callID := fmt.Sprintf("call_%d_%d", t, i)
+			command := step.command
+			switch step.kind {
+			case "read":
+				command = "sed -n '1,200p' " + step.file
+			case "edit":
+				oldString, newString := fixtureEdit()
+				rel, _ := filepath.Rel(conv.project, step.file)
+				command = "apply_patch <<'EOF'\n*** Begin Patch\n*** Update File: " + rel + "\n@@\n-" + oldString + "\n+" + strings.ReplaceAll(newString, "\n", "\n+") + "\n*** End Patch\nEOF"
+			}
+			args, _ := json.Marshal(map[string]interface{}{"command": []string{"bash", "-lc", command}, "workdir": conv.project})
+			exitCode := 0
+			if !step.ok {
+				exitCode = 1
+			}
+			output, _ := json.Marshal(map[string]interface{}{"output": step.output, "metadata": map[string]interface{}{"exit_code": exitCode, "duration_seconds": step.took.Seconds()}})
+			lines = append(lines,
+				map[string]interface{}{"timestamp": fixtureStamp(step.at), "type": "response_item", "payload": map[string]interface{}{
+					"type": "function_call", "name": "shell", "arguments": string(args), "call_id": callID,
+				}},
+				map[string]interface{}{"timestamp": fixtureStamp(step.at.Add(step.took)), "type": "response_item", "payload": map[string]interface{}{
+					"type": "function_call_output", "call_id": callID, "output": string(output),
+				}},
+			)
+		}
+		lines = append(lines,
+			map[string]interface{}{"timestamp": fixtureStamp(turn.doneAt), "type": "response_item", "payload": map[string]interface{}{
+				"type": "message", "role": "assistant", "content": []interface{}{map[string]interface{}{"type": "output_text", "text": turn.reply}},
+			}},
+			map[string]interface{}{"timestamp": fixtureStamp(turn.doneAt), "type": "event_msg", "payload": map[string]interface{}{
+				"type": "token_count", "info": map[string]interface{}{"last_token_usage": map[string]interface{}{
+					"input_tokens": turn.input, "cached_input_tokens": turn.input / 2, "output_tokens": turn.output, "reasoning_output_tokens": turn.output / 4,
+				}},
+			}},
+		)
+	}
+
+	start := conv.start.UTC()
+	name := fmt.Sprintf("rollout-%s-%s.jsonl", start.Format("2006-01-02T15-04-05"), conv.id)
+	path := filepath.Join(home, ".codex", "sessions", start.Format("2006"), start.Format("01"), start.Format("02"), name)
+	return path, writeFixtureJSONL(path, lines)
+}
+
+func writeCopilotFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("copilot")
+	var lines []interface{}
+	event := func(kind string, at time.Time, data map[string]interface{}) {
+		lines = append(lines, map[string]interface{}{"type": kind, "data": data, "id": fixtureUUID(conv.rng), "timestamp": fixtureStamp(at)})
+	}
+	event("session.start", conv.start, map[string]interface{}{"sessionId": conv.id, "version": 1, "producer": "copilot-agent", "copilotVersion": "0.0.339", "startTime": fixtureStamp(conv.start)})
+	event("session.info", conv.start, map[string]interface{}{"infoType": "folder_trust", "message": "Folder " + conv.project + " has been added to trusted folders."})
+	event("session.model_change", conv.start, map[string]interface{}{"newModel": model})
+
+	for t, turn := range conv.turns {
+		event("user.message", turn.at, map[string]interface{}{"content": turn.prompt, "attachments": []interface{}{}})
+		var requests []interface{}
+		for i, step := range turn.steps {
+			requests = append(requests, map[string]interface{}{"toolCallId": fmt.Sprintf("tooluse_%d_%d", t, i), "name": copilotFixtureTool(step), "arguments": copilotFixtureArgs(step)})
+		}
+		event("assistant.message", turn.at.Add(time.Second), map[string]interface{}{"messageId": fixtureUUID(conv.rng), "content": "", "toolRequests": requests})
+		for i, step := range turn.steps {
+			id := fmt.Sprintf("tooluse_%d_%d", t, i)
+			event("tool.execution_start", step.at, map[string]interface{}{"toolCallId": id, "toolName": copilotFixtureTool(step), "arguments": copilotFixtureArgs(step)})
+			event("tool.execution_complete", step.at.Add(step.took), map[string]interface{}{"toolCallId": id, "toolName": copilotFixtureTool(step), "success": step.ok, "result": map[string]interface{}{"content": step.output}})
+		}
+		event("assistant.message", turn.doneAt, map[string]interface{}{"messageId": fixtureUUID(conv.rng), "content": turn.reply})
+	}
+
+	path := filepath.Join(home, ".copilot", "session-state", conv.id+".jsonl")
+	return path, writeFixtureJSONL(path, lines)
+}
+
+func copilotFixtureTool(step fixtureStep) string {
+	switch step.kind {
+	case "read":
+		return "view"
+	case "edit":
+		return "str_replace_editor"
+	}
+	return "bash"
+}
+
+func copilotFixtureArgs(step fixtureStep) map[string]interface{} {
+	switch step.kind {
+	case "read":
+		return map[string]interface{}{"path": step.file}
+	case "edit":
+		oldString, newString := fixtureEdit()
+		return map[string]interface{}{"command": "str_replace", "path": step.file, "old_str": oldString, "new_str": newString}
+	}
+	return map[string]interface{}{"command": step.command, "description": "Run the tests"}
+}
+
+func writeGeminiFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("gemini")
+	var messages []interface{}
+	for _, turn := range conv.turns {
+		messages = append(messages, map[string]interface{}{"id": fixtureUUID(conv.rng), "timestamp": fixtureStamp(turn.at), "type": "user", "content": turn.prompt})
+		var calls []interface{}
+		for _, step := range turn.steps {
+			var name string
+			var args map[string]interface{}
+			switch step.kind {
+			case "read":
+				name, args = "read_file", map[string]interface{}{"absolute_path": step.file}
+			case "edit":
+				oldString, newString := fixtureEdit()
+				name, args = "replace", map[string]interface{}{"file_path": step.file, "old_string": oldString, "new_string": newString}
+			default:
+				name, args = "run_shell_command", map[string]interface{}{"command": step.command, "directory": conv.project}
+			}
+			status := "success"
+			if !step.ok {
+				status = "error"
+			}
+			calls = append(calls, map[string]interface{}{
+				"id": fmt.Sprintf("%s-%d", name, step.at.UnixMilli()), "name": name, "args": args, "status": status, "timestamp": fixtureStamp(step.at),
+				"result": []interface{}{map[string]interface{}{"functionResponse": map[string]interface{}{"name": name, "response": map[string]interface{}{"output": step.output}}}},
+			})
+		}
+		messages = append(messages, map[string]interface{}{
+			"id": fixtureUUID(conv.rng), "timestamp": fixtureStamp(turn.doneAt), "type": "gemini", "content": turn.reply, "model": model, "toolCalls": calls,
+			"tokens": map[string]interface{}{"input": turn.input, "output": turn.output, "cached": turn.input / 2, "total": turn.input + turn.output},
+		})
+	}
+
+	hash := hashProjectPath(conv.project)
+	session := map[string]interface{}{
+		"sessionId": conv.id, "projectHash": hash, "startTime": fixtureStamp(conv.start), "lastUpdated": fixtureStamp(conv.end()), "messages": messages,
+	}
+	name := fmt.Sprintf("session-%s-%s.json", conv.start.UTC().Format("2006-01-02T15-04"), conv.id[:8])
+	path := filepath.Join(home, ".gemini", "tmp", hash, "chats", name)
+	return path, writeFixtureJSON(path, session)
+}
+
+func writeMistralFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("mistral")
+	messages := []interface{}{map[string]interface{}{"role": "system", "content": "You are Vibe, a coding agent."}}
+	for t, turn := range conv.turns {
+		messages = append(messages, map[string]interface{}{"role": "user", "content": turn.prompt})
+		for i, step := range turn.steps {
+			id := fmt.Sprintf("call_%d_%d", t, i)
+			var name string
+			var args map[string]interface{}
+			switch step.kind {
+			case "read":
+				name, args = "read_file", map[string]interface{}{"path": step.file}
+			case "edit":
+				oldString, newString := fixtureEdit()
+				name, args = "search_replace", map[string]interface{}{"file_path": step.file, "content": "<<<<<<< SEARCH\n" + oldString + "\n=======\n" + newString + "\n>>>>>>> REPLACE"}
+			default:
+				name, args = "bash", map[string]interface{}{"command": step.command}
+			}
+			encoded, _ := json.Marshal(args)
+			messages = append(messages,
+				map[string]interface{}{"role": "assistant", "content": "", "tool_calls": []interface{}{map[string]interface{}{
+					"id": id, "type": "function", "function": map[string]interface{}{"name": name, "arguments": string(encoded)},
+				}}},
+				map[string]interface{}{"role": "tool", "content": "", "tool_call_results": []interface{}{map[string]interface{}{
+					"tool_call_id": id, "content": step.output, "is_error": !step.ok, "timestamp": fixtureStamp(step.at.Add(step.took)),
+				}}},
+			)
+		}
+		messages = append(messages, map[string]interface{}{"role": "assistant", "content": turn.reply})
+	}
+
+	session := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"session_id": conv.id,
+			"start_time": conv.start.UTC().Format("2006-01-02T15:04:05.000000"),
+			"end_time":   conv.end().UTC().Format("2006-01-02T15:04:05.000000"),
+			"environment": map[string]interface{}{
+				"working_directory": conv.project,
+			},
+			"agent_config": map[string]interface{}{"active_model": model},
+		},
+		"messages": messages,
+	}
+	name := fmt.Sprintf("session_%s_%s.json", conv.start.UTC().Format("20060102_150405"), conv.id[:8])
+	path := filepath.Join(home, ".vibe", "logs", "session", name)
+	return path, writeFixtureJSON(path, session)
+}
+
+// writeOpencodeFixture writes opencode's file storage layout, which the
+// adapter reads when there is no opencode.db.
+func writeOpencodeFixture(home string, conv fixtureConversation) (string, error) {
+	model := conv.pickModel("opencode")
+	storage := filepath.Join(home, ".local", "share", "opencode", "storage")
+	projectID := hashProjectPath(conv.project)[:40]
+	sessionID := "ses_" + strings.ReplaceAll(conv.id, "-", "")[:26]
+
+	project := map[string]interface{}{"id": projectID, "worktree": conv.project, "vcs": "git", "time": map[string]interface{}{"created": conv.start.UnixMilli()}}
+	if err := writeFixtureJSON(filepath.Join(storage, "project", projectID+".json"), project); err != nil {
+		return "", err
+	}
+
+	seq := 0
+	writeMessage := func(message map[string]interface{}) error {
+		seq++
+		id := fmt.Sprintf("msg_%s%04d", strings.ReplaceAll(conv.id, "-", "")[:20], seq)
+		message["id"] = id
+		message["sessionID"] = sessionID
+		return writeFixtureJSON(filepath.Join(storage, "message", sessionID, id+".json"), message)
+	}
+	for _, turn := range conv.turns {
+		if err := writeMessage(map[string]interface{}{"role": "user", "content": turn.prompt, "time": map[string]interface{}{"created": turn.at.UnixMilli()}}); err != nil {
+			return "", err
+		}
+		parts := []interface{}{}
+		for i, step := range turn.steps {
+			var tool string
+			var input map[string]interface{}
+			switch step.kind {
+			case "read":
+				tool, input = "read", map[string]interface{}{"filePath": step.file}
+			case "edit":
+				oldString, newString := fixtureEdit()
+				tool, input = "edit", map[string]interface{}{"filePath": step.file, "oldString": oldString, "newString": newString}
+			default:
+				tool, input = "bash", map[string]interface{}{"command": step.command, "description": "Run the tests"}
+			}
+			status := "completed"
+			if !step.ok {
+				status = "error"
+			}
+			parts = append(parts, map[string]interface{}{
+				"type": "tool", "tool": tool, "callID": fmt.Sprintf("call_%d", i),
+				"state": map[string]interface{}{
+					"status": status, "input": input, "output": step.output,
+					"time": map[string]interface{}{"start": step.at.UnixMilli(), "end": step.at.Add(step.took).UnixMilli()},
+				},
+			})
+		}
+		parts = append(parts, map[string]interface{}{"type": "text", "text": turn.reply})
+		if err := writeMessage(map[string]interface{}{
+			"role": "assistant", "content": parts, "modelID": model, "agent": "build",
+			"tokens": map[string]interface{}{"input": turn.input, "output": turn.output, "cache": map[string]interface{}{"read": turn.input / 2, "write": 0}},
+			"time":   map[string]interface{}{"created": turn.at.Add(time.Second).UnixMilli(), "completed": turn.doneAt.UnixMilli()},
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	session := map[string]interface{}{
+		"id": sessionID, "version": "0.15.0", "projectID": projectID, "directory": conv.project,
+		"title": conv.turns[0].prompt,
+		"time":  map[string]interface{}{"created": conv.start.UnixMilli(), "updated": conv.end().UnixMilli()},
+	}
+	path := filepath.Join(storage, "session", projectID, sessionID+".json")
+	return path, writeFixtureJSON(path, session)
+}
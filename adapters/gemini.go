@@ -18,6 +18,11 @@ import (
 type GeminiAdapter struct {
 	homeDir      string
 	projectCache map[string]string
+	pathCache    PathCache
+}
+
+func init() {
+	Register("gemini", func() (SessionAdapter, error) { return NewGeminiAdapter() })
 }
 
 // NewGeminiAdapter creates a new Gemini CLI session adapter.
@@ -37,6 +42,17 @@ func (g *GeminiAdapter) Name() string {
 	return "gemini"
 }
 
+// WatchPaths returns the directory Gemini CLI keeps per-project chats under.
+func (g *GeminiAdapter) WatchPaths() []string {
+	return []string{filepath.Join(g.homeDir, ".gemini", "tmp")}
+}
+
+// SetPathCache makes GetSession look up which file holds a session instead of
+// reading every session file to find it.
+func (g *GeminiAdapter) SetPathCache(cache PathCache) {
+	g.pathCache = cache
+}
+
 // geminiSession represents the structure of a Gemini session JSON file.
 type geminiSession struct {
 	SessionID string          `json:"sessionId"`
@@ -75,35 +91,56 @@ func (g *GeminiAdapter) ListSessions(projectPath string, limit int) ([]Session,
 		return g.listAllSessions(geminiTmpDir, limit)
 	}
 
-	// Get absolute path
-	projectPath, err := filepath.Abs(projectPath)
+	filter, err := NewProjectFilter(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, err
 	}
-
-	// Compute project hash
-	projectHash := hashProjectPath(projectPath)
-	chatsDir := filepath.Join(geminiTmpDir, projectHash, "chats")
-
-	// Check if directory exists
-	if _, err := os.Stat(chatsDir); os.IsNotExist(err) {
-		return []Session{}, nil // No sessions for this project
+	// Hashes can't be globbed, so patterns are matched against every
+	// project's sessions
+	if filter.IsPattern() {
+		all, err := g.listAllSessions(geminiTmpDir, 0)
+		if err != nil {
+			return nil, err
+		}
+		return filter.Filter(all, limit), nil
 	}
 
-	// Read all session-*.json files
-	files, err := filepath.Glob(filepath.Join(chatsDir, "session-*.json"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to list session files: %w", err)
-	}
+	// A symlinked project is hashed under whichever path it was opened through
+	sessions := []Session{}
+	for _, projectPath := range filter.Paths() {
+		// Compute project hash
+		projectHash := hashProjectPath(projectPath)
+		projectDir := filepath.Join(geminiTmpDir, projectHash)
+		chatsDir := filepath.Join(projectDir, "chats")
+
+		// Check if directory exists
+		if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+			continue // No sessions for this project
+		}
 
-	sessions := make([]Session, 0, len(files))
-	for _, filePath := range files {
-		session, err := g.parseSessionMetadata(filePath, projectPath)
+		// Read all session-*.json files
+		files, err := filepath.Glob(filepath.Join(chatsDir, "session-*.json"))
 		if err != nil {
-			// Skip files we can't parse
-			continue
+			return nil, fmt.Errorf("failed to list session files: %w", err)
+		}
+
+		for _, filePath := range files {
+			session, err := g.parseSessionMetadata(filePath, projectPath)
+			if err != nil {
+				// Skip files we can't parse
+				continue
+			}
+			sessions = append(sessions, session)
+		}
+
+		// Saved checkpoints (/chat save) are listed as separate sessions
+		for _, filePath := range checkpointFiles(projectDir) {
+			session, err := g.parseCheckpointMetadata(filePath, projectPath)
+			if err != nil {
+				continue
+			}
+			sessions = append(sessions, session)
 		}
-		sessions = append(sessions, session)
 	}
 
 	// Sort by timestamp (newest first)
@@ -152,6 +189,15 @@ func (g *GeminiAdapter) listAllSessions(geminiTmpDir string, limit int) ([]Sessi
 			}
 			allSessions = append(allSessions, session)
 		}
+
+		// Checkpoints come after chats so they can reuse any project path inferred from them
+		for _, filePath := range checkpointFiles(filepath.Join(geminiTmpDir, dir.Name())) {
+			session, err := g.parseCheckpointMetadata(filePath, "unknown-project-"+dir.Name())
+			if err != nil {
+				continue
+			}
+			allSessions = append(allSessions, session)
+		}
 	}
 
 	// Sort by timestamp (newest first)
@@ -169,6 +215,9 @@ func (g *GeminiAdapter) listAllSessions(geminiTmpDir string, limit int) ([]Sessi
 
 // parseSessionMetadata extracts metadata from a Gemini session file.
 func (g *GeminiAdapter) parseSessionMetadata(filePath, projectPath string) (Session, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return Session{}, err
+	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return Session{}, fmt.Errorf("failed to read session file: %w", err)
@@ -229,16 +278,7 @@ func (g *GeminiAdapter) parseSessionMetadata(filePath, projectPath string) (Sess
 func extractFirstLineFromContent(content interface{}) string {
 	switch v := content.(type) {
 	case string:
-		lines := strings.Split(v, "\n")
-		for _, line := range lines {
-			trimmed := strings.TrimSpace(line)
-			if trimmed != "" {
-				if len(trimmed) > 200 {
-					return trimmed[:200] + "..."
-				}
-				return trimmed
-			}
-		}
+		return extractFirstLine(v)
 	case []interface{}:
 		// Gemini may use structured content with text fields
 		for _, item := range v {
@@ -258,77 +298,94 @@ func extractFirstLineFromContent(content interface{}) string {
 
 // GetSession retrieves the full content of a Gemini session with pagination.
 func (g *GeminiAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
+	messages, _, _, _, err := g.GetSessionPage(sessionID, page, pageSize, false)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (g *GeminiAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile, err := g.findSessionFile(sessionID)
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+
+	// Read the session file
+	messages, err := g.readSessionFile(sessionFile)
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+
+	pageMessages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+	return pageMessages, totalMessages, resolvedPage, hasMore, nil
+}
+
+// findSessionFile locates a chat session or saved checkpoint by ID across all
+// project hash directories.
+func (g *GeminiAdapter) findSessionFile(sessionID string) (string, error) {
 	// We need to search for the session file since we don't know the project path
 	geminiTmpDir := filepath.Join(g.homeDir, ".gemini", "tmp")
 
 	// Read all project hash directories
 	projectDirs, err := os.ReadDir(geminiTmpDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Gemini tmp directory: %w", err)
+		return "", fmt.Errorf("failed to read Gemini tmp directory: %w", err)
 	}
 
-	var sessionFile string
-	for _, dir := range projectDirs {
-		if !dir.IsDir() {
-			continue
-		}
-
-		// Check for matching session file
-		chatsDir := filepath.Join(geminiTmpDir, dir.Name(), "chats")
-		files, err := filepath.Glob(filepath.Join(chatsDir, "session-*.json"))
-		if err != nil {
-			continue
+	if strings.HasPrefix(sessionID, geminiCheckpointPrefix) {
+		if sessionFile := g.findCheckpointFile(geminiTmpDir, sessionID); sessionFile != "" {
+			return sessionFile, nil
 		}
+	}
 
-		for _, file := range files {
-			// Read and check if this is the right session
-			data, err := os.ReadFile(file)
-			if err != nil {
+	holds := func(file string) bool {
+		// Read and check if this is the right session
+		var sess geminiSession
+		return decodeJSONFile(file, &sess) == nil && sess.SessionID == sessionID
+	}
+	sessionFile := findSessionPath(g.pathCache, "gemini", sessionID, holds, func() string {
+		for _, dir := range projectDirs {
+			if !dir.IsDir() {
 				continue
 			}
 
-			var sess geminiSession
-			if err := json.Unmarshal(data, &sess); err != nil {
+			// Check for matching session file
+			chatsDir := filepath.Join(geminiTmpDir, dir.Name(), "chats")
+			files, err := filepath.Glob(filepath.Join(chatsDir, "session-*.json"))
+			if err != nil {
 				continue
 			}
-
-			if sess.SessionID == sessionID {
-				sessionFile = file
-				break
+			for _, file := range files {
+				if holds(file) {
+					return file
+				}
 			}
 		}
-
-		if sessionFile != "" {
-			break
-		}
-	}
-
+		return ""
+	})
 	if sessionFile == "" {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
-	}
-
-	// Read the session file
-	messages, err := g.readAllMessages(sessionFile)
-	if err != nil {
-		return nil, err
-	}
-
-	// Apply pagination
-	start := page * pageSize
-	if start >= len(messages) {
-		return []Message{}, nil
+		return "", SessionNotFoundError(sessionID)
 	}
+	return sessionFile, nil
+}
 
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
+// readSessionFile reads all messages from either a chat session or a saved checkpoint.
+func (g *GeminiAdapter) readSessionFile(filePath string) ([]Message, error) {
+	if isCheckpointFile(filePath) {
+		return readCheckpointMessages(filePath)
 	}
-
-	return messages[start:end], nil
+	return g.readAllMessages(filePath)
 }
 
 // readAllMessages reads all messages from a Gemini session file.
 func (g *GeminiAdapter) readAllMessages(filePath string) ([]Message, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return nil, err
+	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read session file: %w", err)
@@ -341,6 +398,9 @@ func (g *GeminiAdapter) readAllMessages(filePath string) ([]Message, error) {
 
 	messages := make([]Message, 0, len(sess.Messages))
 	for _, msg := range sess.Messages {
+		if messageLimitReached(len(messages)) {
+			break
+		}
 		role := normalizeGeminiRole(msg)
 
 		message := Message{
@@ -551,14 +611,15 @@ func (g *GeminiAdapter) SearchSessions(projectPath, query string, limit int) ([]
 
 	// Search through each session
 	for _, session := range sessions {
-		// Check if query is in first message
-		if strings.Contains(strings.ToLower(session.FirstMessage), query) {
+		// Check if query is in first message or checkpoint tag
+		if strings.Contains(strings.ToLower(session.FirstMessage), query) ||
+			strings.Contains(strings.ToLower(session.Summary), query) {
 			matches = append(matches, session)
 			continue
 		}
 
 		// Search through full session content
-		messages, err := g.readAllMessages(session.FilePath)
+		messages, err := g.readSessionFile(session.FilePath)
 		if err != nil {
 			continue
 		}
@@ -578,3 +639,40 @@ func (g *GeminiAdapter) SearchSessions(projectPath, query string, limit int) ([]
 
 	return matches, nil
 }
+
+// geminiMessageTypes are the message types Gemini CLI is known to write.
+var geminiMessageTypes = []string{"user", "gemini", "model", "assistant", "system", "tool", "info", "error", "warning"}
+
+// ValidateSession checks a session file strictly against the format this
+// adapter reads, reporting every field it would skip or misread.
+func (g *GeminiAdapter) ValidateSession(session Session) ([]SchemaDeviation, error) {
+	return validateJSONFile(session.FilePath, func(doc map[string]interface{}, d *deviations) {
+		d.str(doc, "", "sessionId", true)
+		d.timestamp(doc, "", "startTime", false)
+
+		messages := d.array(doc, "", "messages", true)
+		d.objects(messages, "messages", func(msg map[string]interface{}, path string) {
+			role := d.str(msg, path, "type", false)
+			field := path + ".type"
+			if role == "" {
+				role = d.str(msg, path, "role", false)
+				field = path + ".role"
+			}
+			if role == "" {
+				d.add(path, "missing type and role")
+			}
+			d.oneOf(field, "message type", role, geminiMessageTypes...)
+			d.timestamp(msg, path, "timestamp", false)
+
+			switch content := msg["content"].(type) {
+			case string, []interface{}, map[string]interface{}:
+			default:
+				d.add(path+".content", "expected string, array, or object, got %s", jsonType(content))
+			}
+			d.objects(d.array(msg, path, "toolCalls", false), path+".toolCalls", func(call map[string]interface{}, callPath string) {
+				d.str(call, callPath, "name", true)
+				d.object(call, callPath, "args", false)
+			})
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Gemini CLI's `/chat save <tag>` writes the conversation history to
+// ~/.gemini/tmp/[PROJECT_HASH]/checkpoint-<tag>.json, next to the chats directory.
+// Each checkpoint is exposed as its own session, titled with its tag.
+const geminiCheckpointPrefix = "checkpoint-"
+
+// geminiContent is one entry of a saved checkpoint's history (the Gemini API Content shape).
+type geminiContent struct {
+	Role  string                   `json:"role"`
+	Parts []map[string]interface{} `json:"parts"`
+}
+
+// checkpointFiles returns the checkpoint files saved for one project hash directory.
+func checkpointFiles(projectDir string) []string {
+	files, err := filepath.Glob(filepath.Join(projectDir, geminiCheckpointPrefix+"*.json"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(files)
+	return files
+}
+
+// checkpointTag returns the tag a checkpoint was saved under.
+func checkpointTag(filePath string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), ".json")
+	return strings.TrimPrefix(name, geminiCheckpointPrefix)
+}
+
+// checkpointSessionID builds a stable session ID for a checkpoint. Tags are only
+// unique per project, so the project hash is included.
+func checkpointSessionID(filePath string) string {
+	hash := filepath.Base(filepath.Dir(filePath))
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return geminiCheckpointPrefix + hash + "-" + checkpointTag(filePath)
+}
+
+// isCheckpointFile reports whether filePath is a saved checkpoint rather than a chat session.
+func isCheckpointFile(filePath string) bool {
+	return strings.HasPrefix(filepath.Base(filePath), geminiCheckpointPrefix)
+}
+
+// readCheckpointHistory parses a checkpoint file. Older Gemini CLI versions store a bare
+// array of contents; newer ones wrap it in an object with a "history" field.
+func readCheckpointHistory(filePath string) ([]geminiContent, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	var history []geminiContent
+	if err := json.Unmarshal(data, &history); err == nil {
+		return history, nil
+	}
+
+	var wrapped struct {
+		History []geminiContent `json:"history"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint JSON: %w", err)
+	}
+	return wrapped.History, nil
+}
+
+// readCheckpointMessages converts a checkpoint's history into unified messages.
+func readCheckpointMessages(filePath string) ([]Message, error) {
+	history, err := readCheckpointHistory(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, 0, len(history))
+	for _, content := range history {
+		text, toolCalls := checkpointPartsToString(content.Parts)
+		if text == "" {
+			continue
+		}
+
+		role := normalizeGeminiRole(geminiMessage{Role: content.Role})
+		if toolCalls && role == "user" {
+			// Function responses are sent back with the user role
+			role = "tool"
+		}
+
+		messages = append(messages, Message{
+			Role:     role,
+			Content:  text,
+			Metadata: make(map[string]interface{}),
+		})
+	}
+
+	return messages, nil
+}
+
+// checkpointPartsToString joins text parts and summarizes function calls and responses.
+// The second return value reports whether the content was only tool traffic.
+func checkpointPartsToString(parts []map[string]interface{}) (string, bool) {
+	var (
+		texts    []string
+		toolOnly = len(parts) > 0
+	)
+	for _, part := range parts {
+		if text, ok := part["text"].(string); ok {
+			if strings.TrimSpace(text) != "" {
+				texts = append(texts, text)
+				toolOnly = false
+			}
+			continue
+		}
+		if call, ok := part["functionCall"].(map[string]interface{}); ok {
+			texts = append(texts, fmt.Sprintf("[tool call: %v]", call["name"]))
+			continue
+		}
+		if resp, ok := part["functionResponse"].(map[string]interface{}); ok {
+			texts = append(texts, fmt.Sprintf("[tool result: %v]", resp["name"]))
+			continue
+		}
+		toolOnly = false
+	}
+	return strings.Join(texts, "\n"), toolOnly
+}
+
+// parseCheckpointMetadata builds a session from a checkpoint file.
+func (g *GeminiAdapter) parseCheckpointMetadata(filePath, projectPath string) (Session, error) {
+	messages, err := readCheckpointMessages(filePath)
+	if err != nil {
+		return Session{}, err
+	}
+
+	hash := filepath.Base(filepath.Dir(filePath))
+	session := Session{
+		ID:          checkpointSessionID(filePath),
+		Source:      "gemini",
+		ProjectPath: g.resolveProjectPath(hash, projectPath, nil),
+		Summary:     checkpointTag(filePath),
+		FilePath:    filePath,
+	}
+
+	// Checkpoints carry no timestamps, so use when the checkpoint was saved
+	if stat, err := os.Stat(filePath); err == nil {
+		session.Timestamp = stat.ModTime()
+	}
+
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		session.UserMessageCount++
+		if session.FirstMessage == "" {
+			session.FirstMessage = extractFirstLine(msg.Content)
+		}
+	}
+
+	return session, nil
+}
+
+// findCheckpointFile locates the checkpoint file for a checkpoint session ID.
+func (g *GeminiAdapter) findCheckpointFile(geminiTmpDir, sessionID string) string {
+	projectDirs, err := os.ReadDir(geminiTmpDir)
+	if err != nil {
+		return ""
+	}
+	for _, dir := range projectDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		for _, file := range checkpointFiles(filepath.Join(geminiTmpDir, dir.Name())) {
+			if checkpointSessionID(file) == sessionID {
+				return file
+			}
+		}
+	}
+	return ""
+}
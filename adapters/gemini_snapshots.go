@@ -0,0 +1,163 @@
+package adapters
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// With checkpointing enabled, Gemini CLI commits the project to a shadow git
+// repository in ~/.gemini/history/[PROJECT_HASH] before each file-modifying
+// tool call, and writes a restore checkpoint naming the commit and the tool
+// call to ~/.gemini/tmp/[PROJECT_HASH]/checkpoints/. These are unrelated to
+// the conversations saved with /chat save (see gemini_checkpoints.go).
+type geminiRestoreCheckpoint struct {
+	ToolCall struct {
+		Name string                 `json:"name"`
+		Args map[string]interface{} `json:"args"`
+	} `json:"toolCall"`
+	CommitHash string `json:"commitHash"`
+	FilePath   string `json:"filePath"`
+}
+
+// geminiSnapshotSlack widens a session's time range when matching restore
+// checkpoints to it, since checkpoints aren't tied to a session ID.
+const geminiSnapshotSlack = time.Minute
+
+// sessionSnapshots returns the restore checkpoints written while a session was
+// active, oldest first, along with the session.
+func (g *GeminiAdapter) sessionSnapshots(sessionID string) ([]WorkspaceSnapshot, Session, error) {
+	sessionFile, err := g.findSessionFile(sessionID)
+	if err != nil {
+		return nil, Session{}, err
+	}
+	snapshots := []WorkspaceSnapshot{}
+	if isCheckpointFile(sessionFile) {
+		return snapshots, Session{}, nil
+	}
+	session, err := g.parseSessionMetadata(sessionFile, "")
+	if err != nil {
+		return nil, Session{}, err
+	}
+	info, err := os.Stat(sessionFile)
+	if err != nil {
+		return nil, Session{}, fmt.Errorf("failed to stat session file: %w", err)
+	}
+	start, end := session.Timestamp.Add(-geminiSnapshotSlack), info.ModTime().Add(geminiSnapshotSlack)
+
+	hashDir := filepath.Dir(filepath.Dir(sessionFile))
+	files, _ := filepath.Glob(filepath.Join(hashDir, "checkpoints", "*.json"))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.ModTime().Before(start) || info.ModTime().After(end) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var checkpoint geminiRestoreCheckpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil || checkpoint.CommitHash == "" {
+			continue
+		}
+
+		target := checkpoint.FilePath
+		if target == "" {
+			target = stringField(checkpoint.ToolCall.Args, "file_path")
+		}
+		ts := info.ModTime()
+		snapshot := WorkspaceSnapshot{
+			ID:          strings.TrimSuffix(filepath.Base(file), ".json"),
+			SessionID:   sessionID,
+			Source:      "gemini",
+			Timestamp:   &ts,
+			Description: "project before " + checkpoint.ToolCall.Name,
+			Files:       []SnapshotFile{},
+			Commit:      checkpoint.CommitHash,
+		}
+		if target != "" {
+			if session.ProjectPath != "" && !filepath.IsAbs(target) {
+				target = filepath.Join(session.ProjectPath, target)
+			}
+			snapshot.Description += " on " + target
+			snapshot.Files = append(snapshot.Files, SnapshotFile{Path: target, Existed: true})
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Timestamp.Before(*snapshots[j].Timestamp) })
+	return snapshots, session, nil
+}
+
+// ListSnapshots returns the restore checkpoints Gemini CLI wrote while a
+// session was active, oldest first. Each holds the whole project; Files names
+// the file the tool call was about to change.
+func (g *GeminiAdapter) ListSnapshots(sessionID string) ([]WorkspaceSnapshot, error) {
+	snapshots, _, err := g.sessionSnapshots(sessionID)
+	return snapshots, err
+}
+
+// ReadSnapshot calls read with every file in a restore checkpoint's commit,
+// read from the shadow repository with git archive. It needs git installed.
+func (g *GeminiAdapter) ReadSnapshot(sessionID, snapshotID string, read SnapshotReader) error {
+	snapshots, session, err := g.sessionSnapshots(sessionID)
+	if err != nil {
+		return err
+	}
+	var commit string
+	for _, snapshot := range snapshots {
+		if snapshot.ID == snapshotID {
+			commit = snapshot.Commit
+		}
+	}
+	if commit == "" {
+		return fmt.Errorf("snapshot %s not found in session %s", snapshotID, sessionID)
+	}
+
+	hash := extractHashFromPath(session.FilePath)
+	gitDir := filepath.Join(g.homeDir, ".gemini", "history", hash, ".git")
+	cmd := exec.Command("git", "--git-dir", gitDir, "archive", "--format=tar", commit)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git to read the snapshot: %w", err)
+	}
+
+	tr := tar.NewReader(stdout)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		path := filepath.FromSlash(header.Name)
+		if session.ProjectPath != "" {
+			path = filepath.Join(session.ProjectPath, path)
+		}
+		if err := read(path, tr); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return err
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -138,3 +138,71 @@ func TestNormalizeGeminiRole(t *testing.T) {
 		}
 	}
 }
+
+func TestGeminiCheckpointsListedAsSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectPath := "/abs/project"
+	projectDir := filepath.Join(tmpDir, ".gemini", "tmp", hashProjectPath(projectPath))
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+
+	// Older format: bare array of contents
+	bare := `[
+		{"role": "user", "parts": [{"text": "Refactor the parser\nplease"}]},
+		{"role": "model", "parts": [{"text": "Sure."}, {"functionCall": {"name": "read_file"}}]},
+		{"role": "user", "parts": [{"functionResponse": {"name": "read_file"}}]}
+	]`
+	if err := os.WriteFile(filepath.Join(projectDir, "checkpoint-parser-work.json"), []byte(bare), 0o600); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+
+	// Newer format: wrapped in an object with a history field
+	wrapped := `{"history": [{"role": "user", "parts": [{"text": "Fix flaky test"}]}]}`
+	if err := os.WriteFile(filepath.Join(projectDir, "checkpoint-flaky.json"), []byte(wrapped), 0o600); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+
+	adapter := &GeminiAdapter{homeDir: tmpDir, projectCache: make(map[string]string)}
+	sessions, err := adapter.ListSessions(projectPath, 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 checkpoint sessions, got %d", len(sessions))
+	}
+
+	byTag := make(map[string]Session)
+	for _, s := range sessions {
+		byTag[s.Summary] = s
+	}
+	parser, ok := byTag["parser-work"]
+	if !ok {
+		t.Fatalf("expected checkpoint tagged parser-work, got %+v", sessions)
+	}
+	if parser.FirstMessage != "Refactor the parser" || parser.UserMessageCount != 1 || parser.ProjectPath != projectPath {
+		t.Fatalf("unexpected checkpoint metadata: %+v", parser)
+	}
+
+	messages, err := adapter.GetSession(parser.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	if messages[1].Role != "assistant" || messages[1].Content != "Sure.\n[tool call: read_file]" {
+		t.Fatalf("unexpected assistant message: %+v", messages[1])
+	}
+	if messages[2].Role != "tool" {
+		t.Fatalf("expected function response to be a tool message, got %q", messages[2].Role)
+	}
+
+	all, err := adapter.ListSessions("", 0)
+	if err != nil {
+		t.Fatalf("ListSessions (all) returned error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected checkpoints when listing all projects, got %d", len(all))
+	}
+}
@@ -0,0 +1,370 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GenericJSONLConfig describes how to read sessions from an arbitrary JSONL-based agent CLI.
+// Each file matched by Glob is treated as one session, and each line as one message.
+// Field mappings are JSONPath-style expressions such as "$.message.role".
+type GenericJSONLConfig struct {
+	// Name is the source name exposed to clients (e.g. "mytool")
+	Name string `json:"name"`
+
+	// DisplayName is an optional friendly name for the source
+	DisplayName string `json:"display_name,omitempty"`
+
+	// Glob selects session files; a leading "~" expands to the home directory
+	Glob string `json:"glob"`
+
+	// SessionID is the path to the session ID; the file name is used when empty or missing
+	SessionID string `json:"session_id,omitempty"`
+
+	// Role is the path to the message role
+	Role string `json:"role"`
+
+	// Content is the path to the message content
+	Content string `json:"content"`
+
+	// Timestamp is the path to the message timestamp (RFC3339 or Unix epoch)
+	Timestamp string `json:"timestamp,omitempty"`
+
+	// ProjectPath is the path to the working directory recorded in the session
+	ProjectPath string `json:"project_path,omitempty"`
+
+	// RoleMap maps source-specific role names to user/assistant/system/tool
+	RoleMap map[string]string `json:"role_map,omitempty"`
+}
+
+// Validate checks that the config has the fields needed to read sessions.
+func (cfg GenericJSONLConfig) Validate() error {
+	if strings.TrimSpace(cfg.Name) == "" {
+		return fmt.Errorf("generic JSONL adapter requires a name")
+	}
+	if strings.TrimSpace(cfg.Glob) == "" {
+		return fmt.Errorf("generic JSONL adapter %q requires a glob", cfg.Name)
+	}
+	if strings.TrimSpace(cfg.Role) == "" || strings.TrimSpace(cfg.Content) == "" {
+		return fmt.Errorf("generic JSONL adapter %q requires role and content mappings", cfg.Name)
+	}
+	for field, path := range map[string]string{
+		"session_id":   cfg.SessionID,
+		"role":         cfg.Role,
+		"content":      cfg.Content,
+		"timestamp":    cfg.Timestamp,
+		"project_path": cfg.ProjectPath,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := parseJSONPath(path); err != nil {
+			return fmt.Errorf("generic JSONL adapter %q has invalid %s mapping: %w", cfg.Name, field, err)
+		}
+	}
+	return nil
+}
+
+// GenericJSONLAdapter implements SessionAdapter for JSONL session files described by a GenericJSONLConfig.
+type GenericJSONLAdapter struct {
+	config  GenericJSONLConfig
+	homeDir string
+}
+
+// NewGenericJSONLAdapter creates a config-driven JSONL session adapter.
+func NewGenericJSONLAdapter(cfg GenericJSONLConfig) (*GenericJSONLAdapter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &GenericJSONLAdapter{config: cfg, homeDir: homeDir}, nil
+}
+
+// Name returns the adapter name.
+func (g *GenericJSONLAdapter) Name() string {
+	return g.config.Name
+}
+
+// DisplayName returns the configured friendly name, falling back to the source name.
+func (g *GenericJSONLAdapter) DisplayName() string {
+	if g.config.DisplayName != "" {
+		return g.config.DisplayName
+	}
+	return g.config.Name
+}
+
+// sessionFiles returns all files matched by the configured glob.
+func (g *GenericJSONLAdapter) sessionFiles() ([]string, error) {
+	pattern := g.config.Glob
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		pattern = filepath.Join(g.homeDir, strings.TrimPrefix(pattern, "~"))
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", g.config.Glob, err)
+	}
+	return files, nil
+}
+
+// ListSessions returns all sessions matched by the configured glob.
+// If projectPath is empty, returns sessions from ALL projects.
+func (g *GenericJSONLAdapter) ListSessions(projectPath string, limit int) ([]Session, error) {
+	files, err := g.sessionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(files))
+	for _, filePath := range files {
+		session, _, err := g.parseSessionFile(filePath, false)
+		if err != nil {
+			// Skip files we can't parse
+			continue
+		}
+
+		// Filter by project path if specified and the source records one
+		if g.config.ProjectPath != "" && !filter.Match(session.ProjectPath) {
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Timestamp.After(sessions[j].Timestamp)
+	})
+
+	// Apply limit
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+
+	return sessions, nil
+}
+
+// parseSessionFile reads a session file, returning its metadata and, if requested, all messages.
+func (g *GenericJSONLAdapter) parseSessionFile(filePath string, withMessages bool) (Session, []Message, error) {
+	if err := checkFileSize(filePath); err != nil {
+		return Session{}, nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Session{}, nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+
+	session := Session{
+		Source:   g.config.Name,
+		FilePath: filePath,
+	}
+
+	var messages []Message
+	userCount := 0
+
+	scanner := bufio.NewScanner(file)
+	setScanBuffer(scanner, 1024*1024, 10*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue // Skip malformed lines
+		}
+
+		if session.ID == "" && g.config.SessionID != "" {
+			if v, ok := lookupJSONPath(entry, g.config.SessionID); ok {
+				session.ID = jsonValueToString(v)
+			}
+		}
+		if session.ProjectPath == "" && g.config.ProjectPath != "" {
+			if v, ok := lookupJSONPath(entry, g.config.ProjectPath); ok {
+				if path := jsonValueToString(v); path != "" {
+					session.ProjectPath = filepath.Clean(path)
+				}
+			}
+		}
+
+		roleValue, hasRole := lookupJSONPath(entry, g.config.Role)
+		contentValue, hasContent := lookupJSONPath(entry, g.config.Content)
+		if !hasRole || !hasContent {
+			continue // Not a message line
+		}
+
+		message := Message{
+			Role:     g.normalizeRole(jsonValueToString(roleValue)),
+			Content:  jsonValueToString(contentValue),
+			Metadata: make(map[string]interface{}),
+		}
+		if g.config.Timestamp != "" {
+			if v, ok := lookupJSONPath(entry, g.config.Timestamp); ok {
+				if ts, ok := parseFlexibleTimestamp(v); ok {
+					message.Timestamp = ts
+				}
+			}
+		}
+
+		if session.Timestamp.IsZero() && !message.Timestamp.IsZero() {
+			session.Timestamp = message.Timestamp
+		}
+		if message.Role == "user" && strings.TrimSpace(message.Content) != "" {
+			userCount++
+			if session.FirstMessage == "" {
+				session.FirstMessage = extractFirstLine(message.Content)
+			}
+		}
+
+		if withMessages && !messageLimitReached(len(messages)) {
+			messages = append(messages, message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return session, nil, fmt.Errorf("error reading session file: %w", err)
+	}
+
+	// Fall back to the file name when the session ID isn't recorded in the content
+	if session.ID == "" {
+		session.ID = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+
+	// If we still don't have a timestamp, use file modification time
+	if session.Timestamp.IsZero() {
+		if stat, err := os.Stat(filePath); err == nil {
+			session.Timestamp = stat.ModTime()
+		}
+	}
+
+	session.UserMessageCount = userCount
+
+	return session, messages, nil
+}
+
+// normalizeRole maps a raw role value onto the unified role names.
+func (g *GenericJSONLAdapter) normalizeRole(role string) string {
+	role = strings.TrimSpace(role)
+	if mapped, ok := g.config.RoleMap[role]; ok {
+		return mapped
+	}
+	role = strings.ToLower(role)
+	if mapped, ok := g.config.RoleMap[role]; ok {
+		return mapped
+	}
+	switch role {
+	case "human":
+		return "user"
+	case "ai", "model", "bot", "agent":
+		return "assistant"
+	default:
+		return role
+	}
+}
+
+// findSessionFile locates the file holding the given session ID.
+func (g *GenericJSONLAdapter) findSessionFile(sessionID string) (string, error) {
+	files, err := g.sessionFiles()
+	if err != nil {
+		return "", err
+	}
+
+	// Without a session ID mapping, the ID is the file name
+	if g.config.SessionID == "" {
+		for _, file := range files {
+			if strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) == sessionID {
+				return file, nil
+			}
+		}
+		return "", SessionNotFoundError(sessionID)
+	}
+
+	for _, file := range files {
+		session, _, err := g.parseSessionFile(file, false)
+		if err == nil && session.ID == sessionID {
+			return file, nil
+		}
+	}
+
+	return "", SessionNotFoundError(sessionID)
+}
+
+// GetSession retrieves the full content of a session with pagination.
+func (g *GenericJSONLAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
+	messages, _, _, _, err := g.GetSessionPage(sessionID, page, pageSize, false)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (g *GenericJSONLAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile, err := g.findSessionFile(sessionID)
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+
+	_, messages, err := g.parseSessionFile(sessionFile, true)
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+
+	pageMessages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+	return pageMessages, totalMessages, resolvedPage, hasMore, nil
+}
+
+// SearchSessions searches sessions for the given query.
+func (g *GenericJSONLAdapter) SearchSessions(projectPath, query string, limit int) ([]Session, error) {
+	sessions, err := g.ListSessions(projectPath, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	var matches []Session
+
+	for _, session := range sessions {
+		if strings.Contains(strings.ToLower(session.FirstMessage), query) {
+			matches = append(matches, session)
+			continue
+		}
+
+		_, messages, err := g.parseSessionFile(session.FilePath, true)
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			if strings.Contains(strings.ToLower(msg.Content), query) {
+				matches = append(matches, session)
+				break
+			}
+		}
+
+		// Apply limit if we've found enough
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"message": map[string]interface{}{
+			"content": []interface{}{
+				map[string]interface{}{"text": "first"},
+				map[string]interface{}{"text": "last"},
+			},
+		},
+		"odd key": "value",
+	}
+
+	table := []struct {
+		path string
+		want interface{}
+		ok   bool
+	}{
+		{"$.message.content[0].text", "first", true},
+		{"message.content[-1].text", "last", true},
+		{`$["odd key"]`, "value", true},
+		{"$.message.missing", nil, false},
+		{"$.message.content[5]", nil, false},
+	}
+
+	for _, tc := range table {
+		got, ok := lookupJSONPath(doc, tc.path)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Fatalf("lookupJSONPath(%q)=(%v,%v) want (%v,%v)", tc.path, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestGenericJSONLConfigValidate(t *testing.T) {
+	valid := GenericJSONLConfig{Name: "tool", Glob: "/tmp/*.jsonl", Role: "$.role", Content: "$.text"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate returned error for valid config: %v", err)
+	}
+
+	invalid := []GenericJSONLConfig{
+		{Glob: "/tmp/*.jsonl", Role: "$.role", Content: "$.text"},
+		{Name: "tool", Role: "$.role", Content: "$.text"},
+		{Name: "tool", Glob: "/tmp/*.jsonl", Content: "$.text"},
+		{Name: "tool", Glob: "/tmp/*.jsonl", Role: "$.role", Content: "$.items[x]"},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) expected error", cfg)
+		}
+	}
+}
+
+func TestGenericJSONLAdapterListAndGet(t *testing.T) {
+	tmpDir := t.TempDir()
+	lines := []string{
+		`{"sid":"abc-1","cwd":"/work/app","kind":"meta"}`,
+		`{"msg":{"from":"Human","body":"Fix the login bug\nmore detail"},"ts":"2024-05-01T10:00:00Z"}`,
+		`not json`,
+		`{"msg":{"from":"AI","body":[{"text":"Looking at auth.go"}]},"ts":1714557660}`,
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "one.jsonl"), []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatalf("failed to write session file: %v", err)
+	}
+
+	adapter := &GenericJSONLAdapter{
+		config: GenericJSONLConfig{
+			Name:        "mytool",
+			Glob:        filepath.Join(tmpDir, "*.jsonl"),
+			SessionID:   "$.sid",
+			Role:        "$.msg.from",
+			Content:     "$.msg.body",
+			Timestamp:   "$.ts",
+			ProjectPath: "$.cwd",
+		},
+	}
+
+	sessions, err := adapter.ListSessions("", 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+
+	session := sessions[0]
+	if session.ID != "abc-1" || session.Source != "mytool" || session.ProjectPath != "/work/app" {
+		t.Fatalf("unexpected session metadata: %+v", session)
+	}
+	if session.FirstMessage != "Fix the login bug" || session.UserMessageCount != 1 {
+		t.Fatalf("unexpected first message/count: %q %d", session.FirstMessage, session.UserMessageCount)
+	}
+
+	filtered, err := adapter.ListSessions("/other/project", 0)
+	if err != nil {
+		t.Fatalf("ListSessions with project filter returned error: %v", err)
+	}
+	if len(filtered) != 0 {
+		t.Fatalf("expected project filter to exclude session, got %d", len(filtered))
+	}
+
+	messages, err := adapter.GetSession("abc-1", 0, 10)
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].Role != "user" || messages[1].Role != "assistant" {
+		t.Fatalf("unexpected roles: %q %q", messages[0].Role, messages[1].Role)
+	}
+	if messages[1].Content != "Looking at auth.go" {
+		t.Fatalf("structured content not flattened: %q", messages[1].Content)
+	}
+	if messages[1].Timestamp.Unix() != 1714557660 {
+		t.Fatalf("epoch timestamp not parsed: %v", messages[1].Timestamp)
+	}
+
+	if _, err := adapter.GetSession("missing", 0, 10); err == nil {
+		t.Fatal("expected error for unknown session")
+	}
+}
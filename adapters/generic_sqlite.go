@@ -0,0 +1,360 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// GenericSQLiteConfig describes how to read sessions from an arbitrary SQLite-backed agent CLI.
+// Queries are matched to session/message fields by result column name, so they can alias
+// whatever the tool's schema uses (e.g. "SELECT conv_id AS id, cwd AS project_path ...").
+type GenericSQLiteConfig struct {
+	// Name is the source name exposed to clients (e.g. "mytool")
+	Name string `json:"name"`
+
+	// DisplayName is an optional friendly name for the source
+	DisplayName string `json:"display_name,omitempty"`
+
+	// DBPath is the SQLite database file; a leading "~" expands to the home directory
+	DBPath string `json:"db_path"`
+
+	// SessionsQuery lists sessions. Required column: id. Optional columns:
+	// project_path, title, first_message, timestamp, user_message_count.
+	SessionsQuery string `json:"sessions_query"`
+
+	// MessagesQuery lists a session's messages in order. It receives the session ID as its
+	// only parameter (?). Required columns: role, content. Optional column: timestamp.
+	MessagesQuery string `json:"messages_query"`
+
+	// RoleMap maps source-specific role names to user/assistant/system/tool
+	RoleMap map[string]string `json:"role_map,omitempty"`
+}
+
+// Validate checks that the config has the fields needed to read sessions.
+// Only read-only SELECT (or WITH ... SELECT) statements are accepted.
+func (cfg GenericSQLiteConfig) Validate() error {
+	if strings.TrimSpace(cfg.Name) == "" {
+		return fmt.Errorf("generic SQLite adapter requires a name")
+	}
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return fmt.Errorf("generic SQLite adapter %q requires a db_path", cfg.Name)
+	}
+	for field, query := range map[string]string{
+		"sessions_query": cfg.SessionsQuery,
+		"messages_query": cfg.MessagesQuery,
+	} {
+		if strings.TrimSpace(query) == "" {
+			return fmt.Errorf("generic SQLite adapter %q requires %s", cfg.Name, field)
+		}
+		if !isSelectStatement(query) {
+			return fmt.Errorf("generic SQLite adapter %q: %s must be a single SELECT statement", cfg.Name, field)
+		}
+	}
+	return nil
+}
+
+// isSelectStatement reports whether query looks like a single read-only SELECT.
+func isSelectStatement(query string) bool {
+	trimmed := strings.TrimSpace(query)
+	trimmed = strings.TrimSuffix(trimmed, ";")
+	if strings.Contains(trimmed, ";") {
+		return false
+	}
+	upper := strings.ToUpper(trimmed)
+	return strings.HasPrefix(upper, "SELECT") || strings.HasPrefix(upper, "WITH")
+}
+
+// GenericSQLiteAdapter implements SessionAdapter for SQLite databases described by a GenericSQLiteConfig.
+type GenericSQLiteAdapter struct {
+	config GenericSQLiteConfig
+	dbPath string
+}
+
+// NewGenericSQLiteAdapter creates a config-driven SQLite session adapter.
+func NewGenericSQLiteAdapter(cfg GenericSQLiteConfig) (*GenericSQLiteAdapter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	dbPath := cfg.DBPath
+	if dbPath == "~" || strings.HasPrefix(dbPath, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dbPath = filepath.Join(homeDir, strings.TrimPrefix(dbPath, "~"))
+	}
+	return &GenericSQLiteAdapter{config: cfg, dbPath: dbPath}, nil
+}
+
+// Name returns the adapter name.
+func (g *GenericSQLiteAdapter) Name() string {
+	return g.config.Name
+}
+
+// DisplayName returns the configured friendly name, falling back to the source name.
+func (g *GenericSQLiteAdapter) DisplayName() string {
+	if g.config.DisplayName != "" {
+		return g.config.DisplayName
+	}
+	return g.config.Name
+}
+
+// openDB opens the configured database read-only.
+func (g *GenericSQLiteAdapter) openDB() (*sql.DB, error) {
+	if _, err := os.Stat(g.dbPath); err != nil {
+		return nil, err
+	}
+
+	dsn := "file:" + (&url.URL{Path: g.dbPath}).EscapedPath() + "?mode=ro"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", g.config.Name, err)
+	}
+
+	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set sqlite busy_timeout: %w", err)
+	}
+
+	return db, nil
+}
+
+// queryRows runs a query and returns each row as a column-name keyed map.
+func queryRows(db *sql.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			row[strings.ToLower(col)] = values[i]
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// ListSessions returns all sessions produced by the configured sessions query.
+// If projectPath is empty, returns sessions from ALL projects.
+func (g *GenericSQLiteAdapter) ListSessions(projectPath string, limit int) ([]Session, error) {
+	db, err := g.openDB()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Session{}, nil
+		}
+		return nil, err
+	}
+	defer db.Close()
+
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := queryRows(db, g.config.SessionsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run sessions query: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		session := Session{
+			ID:          jsonValueToString(row["id"]),
+			Source:      g.config.Name,
+			ProjectPath: jsonValueToString(row["project_path"]),
+			Summary:     jsonValueToString(row["title"]),
+			FilePath:    g.dbPath,
+		}
+		if session.ID == "" {
+			continue
+		}
+
+		// Filter by project path if specified and the query provides one
+		if session.ProjectPath != "" && !filter.Match(session.ProjectPath) {
+			continue
+		}
+
+		if ts, ok := parseFlexibleTimestamp(row["timestamp"]); ok {
+			session.Timestamp = ts
+		}
+
+		session.FirstMessage = extractFirstLine(jsonValueToString(row["first_message"]))
+		if count, ok := row["user_message_count"].(int64); ok {
+			session.UserMessageCount = int(count)
+		}
+
+		// Derive missing preview fields from the messages themselves
+		if _, hasFirst := row["first_message"]; !hasFirst {
+			if messages, err := g.readMessages(db, session.ID); err == nil {
+				g.fillFromMessages(&session, messages, row)
+			}
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Timestamp.After(sessions[j].Timestamp)
+	})
+
+	// Apply limit
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+
+	return sessions, nil
+}
+
+// fillFromMessages sets first message, user count, and timestamp from message rows
+// when the sessions query doesn't provide them.
+func (g *GenericSQLiteAdapter) fillFromMessages(session *Session, messages []Message, row map[string]interface{}) {
+	userCount := 0
+	for _, msg := range messages {
+		if msg.Role != "user" || strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		userCount++
+		if session.FirstMessage == "" {
+			session.FirstMessage = extractFirstLine(msg.Content)
+		}
+	}
+	if _, hasCount := row["user_message_count"]; !hasCount {
+		session.UserMessageCount = userCount
+	}
+	if session.Timestamp.IsZero() && len(messages) > 0 {
+		session.Timestamp = messages[0].Timestamp
+	}
+}
+
+// readMessages runs the configured messages query for one session.
+func (g *GenericSQLiteAdapter) readMessages(db *sql.DB, sessionID string) ([]Message, error) {
+	rows, err := queryRows(db, g.config.MessagesQuery, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run messages query: %w", err)
+	}
+
+	messages := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		message := Message{
+			Role:     g.normalizeRole(jsonValueToString(row["role"])),
+			Content:  jsonValueToString(row["content"]),
+			Metadata: make(map[string]interface{}),
+		}
+		if ts, ok := parseFlexibleTimestamp(row["timestamp"]); ok {
+			message.Timestamp = ts
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// normalizeRole maps a raw role value onto the unified role names.
+func (g *GenericSQLiteAdapter) normalizeRole(role string) string {
+	role = strings.TrimSpace(role)
+	if mapped, ok := g.config.RoleMap[role]; ok {
+		return mapped
+	}
+	return strings.ToLower(role)
+}
+
+// GetSession retrieves the full content of a session with pagination.
+func (g *GenericSQLiteAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
+	messages, _, _, _, err := g.GetSessionPage(sessionID, page, pageSize, false)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (g *GenericSQLiteAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	db, err := g.openDB()
+	if err != nil {
+		return nil, 0, page, false, fmt.Errorf("failed to open %s database: %w", g.config.Name, err)
+	}
+	defer db.Close()
+
+	messages, err := g.readMessages(db, sessionID)
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+	if len(messages) == 0 {
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
+	}
+
+	pageMessages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+	return pageMessages, totalMessages, resolvedPage, hasMore, nil
+}
+
+// SearchSessions searches sessions for the given query.
+func (g *GenericSQLiteAdapter) SearchSessions(projectPath, query string, limit int) ([]Session, error) {
+	sessions, err := g.ListSessions(projectPath, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := g.openDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query = strings.ToLower(query)
+	var matches []Session
+
+	for _, session := range sessions {
+		if strings.Contains(strings.ToLower(session.Summary), query) ||
+			strings.Contains(strings.ToLower(session.FirstMessage), query) {
+			matches = append(matches, session)
+			continue
+		}
+
+		messages, err := g.readMessages(db, session.ID)
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			if strings.Contains(strings.ToLower(msg.Content), query) {
+				matches = append(matches, session)
+				break
+			}
+		}
+
+		// Apply limit if we've found enough
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,98 @@
+package adapters
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenericSQLiteConfigValidate(t *testing.T) {
+	valid := GenericSQLiteConfig{
+		Name:          "tool",
+		DBPath:        "/tmp/tool.db",
+		SessionsQuery: "SELECT id FROM conversations",
+		MessagesQuery: "WITH m AS (SELECT * FROM msgs) SELECT role, content FROM m WHERE conv = ?;",
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate returned error for valid config: %v", err)
+	}
+
+	invalid := valid
+	invalid.SessionsQuery = "DELETE FROM conversations"
+	if err := invalid.Validate(); err == nil {
+		t.Fatal("expected error for non-SELECT sessions query")
+	}
+
+	invalid = valid
+	invalid.MessagesQuery = "SELECT 1; DROP TABLE msgs"
+	if err := invalid.Validate(); err == nil {
+		t.Fatal("expected error for multiple statements")
+	}
+}
+
+func TestGenericSQLiteAdapterListAndGet(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "tool.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE conversations (conv_id TEXT, cwd TEXT, name TEXT, created INTEGER);
+		CREATE TABLE msgs (conv TEXT, seq INTEGER, who TEXT, body TEXT, at TEXT);
+		INSERT INTO conversations VALUES ('c1', '/work/app', 'Login fix', 1714557600000);
+		INSERT INTO conversations VALUES ('c2', '/work/other', 'Other', 1714557000000);
+		INSERT INTO msgs VALUES ('c1', 1, 'human', 'Why does login fail?', '2024-05-01T10:00:00Z');
+		INSERT INTO msgs VALUES ('c1', 2, 'bot', 'The token expired.', '2024-05-01T10:00:05Z');
+		INSERT INTO msgs VALUES ('c2', 1, 'human', 'Unrelated', '2024-05-01T09:50:00Z');
+	`); err != nil {
+		t.Fatalf("failed to seed sqlite db: %v", err)
+	}
+	db.Close()
+
+	adapter, err := NewGenericSQLiteAdapter(GenericSQLiteConfig{
+		Name:          "tool",
+		DBPath:        dbPath,
+		SessionsQuery: "SELECT conv_id AS id, cwd AS project_path, name AS title, created AS timestamp FROM conversations",
+		MessagesQuery: "SELECT who AS role, body AS content, at AS timestamp FROM msgs WHERE conv = ? ORDER BY seq",
+		RoleMap:       map[string]string{"human": "user", "bot": "assistant"},
+	})
+	if err != nil {
+		t.Fatalf("NewGenericSQLiteAdapter returned error: %v", err)
+	}
+
+	sessions, err := adapter.ListSessions("", 0)
+	if err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	first := sessions[0]
+	if first.ID != "c1" || first.Summary != "Login fix" || first.FirstMessage != "Why does login fail?" || first.UserMessageCount != 1 {
+		t.Fatalf("unexpected session: %+v", first)
+	}
+
+	filtered, err := adapter.ListSessions("/work/other", 0)
+	if err != nil {
+		t.Fatalf("ListSessions with project returned error: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "c2" {
+		t.Fatalf("project filter failed: %+v", filtered)
+	}
+
+	messages, err := adapter.GetSession("c1", 0, 10)
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if len(messages) != 2 || messages[1].Role != "assistant" || messages[1].Content != "The token expired." {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+
+	matches, err := adapter.SearchSessions("", "token expired", 0)
+	if err != nil {
+		t.Fatalf("SearchSessions returned error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "c1" {
+		t.Fatalf("unexpected search matches: %+v", matches)
+	}
+}
@@ -0,0 +1,29 @@
+package adapters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HashMessage returns a content address for a message: the hex SHA-256 of its role and content.
+// The hash does not depend on file location or page position, so it stays stable when
+// session files move or sources renumber pages.
+func HashMessage(msg Message) string {
+	h := sha256.New()
+	h.Write([]byte(strings.ToLower(msg.Role)))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.Content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashSession returns a content address for a session given its ordered message hashes.
+// Two sessions with the same messages in the same order share a hash.
+func HashSession(messageHashes []string) string {
+	h := sha256.New()
+	for _, mh := range messageHashes {
+		h.Write([]byte(mh))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -3,6 +3,7 @@ package adapters
 import (
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestProjectDirName(t *testing.T) {
@@ -20,14 +21,32 @@ func TestStripSystemXMLTags(t *testing.T) {
 }
 
 func TestExtractFirstLineString(t *testing.T) {
-	longLine := strings.Repeat("a", 210)
+	longLine := strings.Repeat("a", MaxPreviewLength+10)
 	text := "\n\n" + longLine + "\nnext line"
 	got := extractFirstLine(text)
-	if len(got) != 203 || !strings.HasSuffix(got, "...") {
+	if len(got) != MaxPreviewLength+3 || !strings.HasSuffix(got, "...") {
 		t.Fatalf("extractFirstLine should truncate long lines, got %q", got)
 	}
 }
 
+func TestTruncateTextKeepsGraphemesWhole(t *testing.T) {
+	// A family emoji is several runes joined by zero-width joiners, and "é"
+	// here is "e" plus a combining accent.
+	family := "👨‍👩‍👧"
+	text := strings.Repeat("é", 3) + family + "שלום"
+	if got := TruncateText(text, 4); got != "éé"+"é"+family+"..." {
+		t.Fatalf("TruncateText split a grapheme cluster: %q", got)
+	}
+	if got := TruncateText("שלום", 4); got != "שלום" {
+		t.Fatalf("TruncateText should not truncate text at the limit: %q", got)
+	}
+
+	got := extractFirstLine(strings.Repeat("日本語", 400))
+	if !utf8.ValidString(got) || got != strings.Repeat("日本語", 333)+"日..." {
+		t.Fatalf("extractFirstLine should cut after %d characters, got %q", MaxPreviewLength, got)
+	}
+}
+
 func TestExtractFirstLineFromClaudeContentStructured(t *testing.T) {
 	content := []interface{}{
 		map[string]interface{}{"text": "First meaningful line\nSecond line"},
@@ -106,9 +125,8 @@ func TestCodexIsSessionPrefix(t *testing.T) {
 }
 
 func TestCodexExtractFirstLine(t *testing.T) {
-	adapter := &CodexAdapter{}
 	text := "   line one\nline two"
-	if got := adapter.extractFirstLine(text); got != "line one" {
+	if got := extractFirstLine(text); got != "line one" {
 		t.Fatalf("extractFirstLine returned %q", got)
 	}
 }
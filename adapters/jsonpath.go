@@ -0,0 +1,157 @@
+package adapters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lookupJSONPath resolves a JSONPath-style expression against a decoded JSON value.
+// Supported syntax is intentionally small: an optional leading "$", dot-separated
+// object keys, and bracketed array indexes (e.g. "$.message.content[0].text").
+// Quoted bracket keys (e.g. `$["odd key"]`) are also accepted.
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	current := doc
+	for _, seg := range segments {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			if seg.isIndex {
+				return nil, false
+			}
+			next, ok := v[seg.key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			if !seg.isIndex {
+				return nil, false
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// jsonPathSegment is one step of a parsed JSONPath expression.
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath splits a JSONPath-style expression into segments.
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var segments []jsonPathSegment
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated bracket in JSONPath")
+			}
+			inner := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, jsonPathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid array index %q in JSONPath", inner)
+			}
+			segments = append(segments, jsonPathSegment{index: idx, isIndex: true})
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			segments = append(segments, jsonPathSegment{key: path[:end]})
+			path = path[end:]
+		}
+	}
+
+	return segments, nil
+}
+
+// jsonValueToString converts a looked-up JSON value to display text.
+// Structured content blocks are flattened by joining their "text" fields.
+func jsonValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		return string(v)
+	default:
+		return contentToString(v)
+	}
+}
+
+// parseFlexibleTimestamp parses a timestamp that may be an RFC3339 string or a
+// numeric Unix epoch in seconds or milliseconds.
+func parseFlexibleTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return time.Time{}, false
+		}
+		for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05.999999", "2006-01-02 15:04:05"} {
+			if ts, err := time.Parse(layout, v); err == nil {
+				return ts, true
+			}
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return epochToTime(n), true
+		}
+	case float64:
+		return epochToTime(v), true
+	case int64:
+		return epochToTime(float64(v)), true
+	case []byte:
+		return parseFlexibleTimestamp(string(v))
+	case time.Time:
+		return v, !v.IsZero()
+	}
+	return time.Time{}, false
+}
+
+// epochToTime converts a Unix epoch that may be in seconds or milliseconds.
+func epochToTime(n float64) time.Time {
+	// Anything past the year 33658 in seconds is almost certainly milliseconds.
+	if n > 1e12 {
+		return time.UnixMilli(int64(n))
+	}
+	return time.Unix(int64(n), 0)
+}
@@ -0,0 +1,70 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Limits bound how much of a session file adapters read, so one pathological
+// session can't exhaust the server's memory or hold up a tool call. A zero
+// field leaves that limit off.
+type Limits struct {
+	// MaxFileSize is the largest session file read, in bytes. Larger files are
+	// left out of listings and searches, and reading one fails with
+	// ErrFileTooLarge.
+	MaxFileSize int64 `json:"max_file_size,omitempty"`
+
+	// MaxLineLength caps the longest line read from JSONL session files, in
+	// bytes. Each format already has a limit of its own; this only lowers it.
+	MaxLineLength int `json:"max_line_length,omitempty"`
+
+	// MaxMessages is the most messages read from one session. Later messages
+	// are left out.
+	MaxMessages int `json:"max_messages,omitempty"`
+}
+
+var limits atomic.Pointer[Limits]
+
+// SetLimits sets the limits every adapter reads session files within.
+func SetLimits(l Limits) {
+	limits.Store(&l)
+}
+
+// currentLimits returns the limits set with SetLimits, if any.
+func currentLimits() Limits {
+	if l := limits.Load(); l != nil {
+		return *l
+	}
+	return Limits{}
+}
+
+// checkFileSize returns an ErrFileTooLarge error if the file at path is over
+// the size limit. Errors from stat are left for the read that follows to report.
+func checkFileSize(path string) error {
+	maxSize := currentLimits().MaxFileSize
+	if maxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() <= maxSize {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is %d bytes (limit %d)", ErrFileTooLarge, path, info.Size(), maxSize)
+}
+
+// lineLimit returns the longest line to read from a JSONL file whose format
+// allows formatMax bytes.
+func lineLimit(formatMax int) int {
+	if l := currentLimits().MaxLineLength; l > 0 && l < formatMax {
+		return l
+	}
+	return formatMax
+}
+
+// messageLimitReached reports whether a session already read up to n messages
+// has reached the message limit.
+func messageLimitReached(n int) bool {
+	l := currentLimits().MaxMessages
+	return l > 0 && n >= l
+}
@@ -0,0 +1,61 @@
+package adapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Cleanup(func() { SetLimits(Limits{}) })
+	opts := FixtureOptions{Sessions: 2, Turns: 3, Projects: 1, Seed: 7, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	for _, source := range FixtureSources() {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+	}
+	adaptersMap, _ := NewRegistered()
+	sessions := make(map[string][]Session)
+	for _, source := range FixtureSources() {
+		listed, err := adaptersMap[source].ListSessions("", 0)
+		if err != nil || len(listed) != opts.Sessions {
+			t.Fatalf("expected %d %s sessions, got %d (%v)", opts.Sessions, source, len(listed), err)
+		}
+		sessions[source] = listed
+	}
+
+	// Sessions are read up to the message limit, streamed or not
+	SetLimits(Limits{MaxMessages: 2})
+	for source, listed := range sessions {
+		messages, err := adaptersMap[source].GetSession(listed[0].ID, 0, 100)
+		if err != nil {
+			t.Fatalf("%s GetSession returned error: %v", source, err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("expected %s to stop at 2 messages, got %d", source, len(messages))
+		}
+	}
+
+	// Files over the size limit are left out of listings and fail to read
+	SetLimits(Limits{MaxFileSize: 10})
+	for _, source := range FixtureSources() {
+		listed, err := adaptersMap[source].ListSessions("", 0)
+		if err != nil {
+			t.Fatalf("%s ListSessions returned error: %v", source, err)
+		}
+		if len(listed) != 0 {
+			t.Fatalf("expected oversized %s sessions to be skipped, got %d", source, len(listed))
+		}
+	}
+	if _, err := adaptersMap["claude"].GetSession(sessions["claude"][0].ID, 0, 100); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("expected ErrFileTooLarge, got %v", err)
+	}
+
+	// A line limit below a line's length stops the read with an error
+	SetLimits(Limits{MaxLineLength: 16})
+	if _, err := adaptersMap["claude"].GetSession(sessions["claude"][0].ID, 0, 100); err == nil {
+		t.Fatal("expected an error reading lines over the line limit")
+	}
+}
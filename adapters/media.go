@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Image is an image attached to a message, such as a pasted screenshot or a
+// screenshot returned by a tool.
+type Image struct {
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"-"`
+}
+
+// ExtractImages returns the inline images recorded on a message:
+//   - Metadata["raw_content"]: Claude image blocks (also nested in tool_result content)
+//     and Codex input_image blocks with data URLs
+//   - NonTextParts: opencode "file" parts with an image data URL
+//
+// Images that are only referenced by path or remote URL are not included.
+func ExtractImages(msg Message) []Image {
+	var images []Image
+
+	if raw, ok := msg.Metadata["raw_content"]; ok {
+		images = appendBlockImages(images, raw)
+	}
+
+	for _, part := range msg.NonTextParts {
+		if stringField(part, "type") != "file" {
+			continue
+		}
+		if img, ok := imageFromDataURL(stringField(part, "url")); ok {
+			images = append(images, img)
+		}
+	}
+
+	return images
+}
+
+// appendBlockImages collects images from a list of content blocks, descending
+// into tool_result blocks whose content is itself a block list.
+func appendBlockImages(images []Image, blocks interface{}) []Image {
+	for _, block := range asObjectList(blocks) {
+		switch stringField(block, "type") {
+		case "image":
+			source := asObject(block["source"])
+			if stringField(source, "type") != "base64" {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(stringField(source, "data"))
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			images = append(images, Image{MediaType: stringField(source, "media_type"), Data: data})
+		case "input_image":
+			url := stringField(block, "image_url")
+			if url == "" {
+				url = stringField(asObject(block["image_url"]), "url")
+			}
+			if img, ok := imageFromDataURL(url); ok {
+				images = append(images, img)
+			}
+		case "tool_result":
+			images = appendBlockImages(images, block["content"])
+		}
+	}
+	return images
+}
+
+// imageFromDataURL decodes a base64 "data:image/...;base64,..." URL.
+func imageFromDataURL(url string) (Image, bool) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasPrefix(url, "data:image/") || !strings.HasSuffix(header, ";base64") {
+		return Image{}, false
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(data) == 0 {
+		return Image{}, false
+	}
+	return Image{MediaType: strings.TrimSuffix(header, ";base64"), Data: data}, true
+}
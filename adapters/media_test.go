@@ -0,0 +1,50 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestExtractImages(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("image bytes"))
+
+	claude := Message{Role: "user", Metadata: map[string]interface{}{
+		"raw_content": []interface{}{
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": data}},
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "url", "url": "https://example.com/a.png"}},
+			map[string]interface{}{"type": "tool_result", "content": []interface{}{
+				map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/jpeg", "data": data}},
+			}},
+		},
+	}}
+	codex := Message{Role: "user", Metadata: map[string]interface{}{
+		"raw_content": []interface{}{
+			map[string]interface{}{"type": "input_image", "image_url": "data:image/webp;base64," + data},
+		},
+	}}
+	opencode := Message{Role: "user", NonTextParts: []map[string]interface{}{
+		{"type": "file", "mime": "image/gif", "url": "data:image/gif;base64," + data},
+		{"type": "file", "mime": "text/plain", "url": "file:///notes.txt"},
+	}}
+
+	tests := []struct {
+		name string
+		msg  Message
+		want []string
+	}{
+		{"claude", claude, []string{"image/png", "image/jpeg"}},
+		{"codex", codex, []string{"image/webp"}},
+		{"opencode", opencode, []string{"image/gif"}},
+	}
+	for _, tt := range tests {
+		images := ExtractImages(tt.msg)
+		if len(images) != len(tt.want) {
+			t.Fatalf("%s: expected %d images, got %+v", tt.name, len(tt.want), images)
+		}
+		for i, img := range images {
+			if img.MediaType != tt.want[i] || string(img.Data) != "image bytes" {
+				t.Errorf("%s: unexpected image %d: %s %q", tt.name, i, img.MediaType, img.Data)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"os"
+	"time"
+)
+
+// MetadataCache persists the metadata adapters extract from session files, so
+// listing only parses files that changed since they were last listed. Entries
+// are keyed by file path and are valid only while the file's modification
+// time and size are unchanged.
+type MetadataCache interface {
+	// LoadSessionMetadata returns the session stored for path, if it was
+	// stored for a file with the same modification time and size.
+	LoadSessionMetadata(path string, modTime time.Time, size int64) (Session, bool)
+	// StoreSessionMetadata records the session extracted from path.
+	StoreSessionMetadata(path string, modTime time.Time, size int64, session Session)
+}
+
+// cachedMetadata returns the session metadata for the file at path, from cache
+// when the file is unchanged and by calling parse otherwise. Parsed metadata is
+// stored for next time. A nil cache always parses.
+func cachedMetadata(cache MetadataCache, path string, parse func() (Session, error)) (Session, error) {
+	if cache == nil {
+		return parse()
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return parse()
+	}
+	if session, ok := cache.LoadSessionMetadata(path, info.ModTime(), info.Size()); ok {
+		return session, nil
+	}
+	session, err := parse()
+	if err != nil {
+		return Session{}, err
+	}
+	cache.StoreSessionMetadata(path, info.ModTime(), info.Size(), session)
+	return session, nil
+}
@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mapMetadataCache is a MetadataCache kept in memory, counting hits and stores.
+type mapMetadataCache struct {
+	entries map[string]cachedEntry
+	hits    int
+	stores  int
+}
+
+type cachedEntry struct {
+	modTime time.Time
+	size    int64
+	session Session
+}
+
+func (m *mapMetadataCache) LoadSessionMetadata(path string, modTime time.Time, size int64) (Session, bool) {
+	entry, ok := m.entries[path]
+	if !ok || !entry.modTime.Equal(modTime) || entry.size != size {
+		return Session{}, false
+	}
+	m.hits++
+	return entry.session, true
+}
+
+func (m *mapMetadataCache) StoreSessionMetadata(path string, modTime time.Time, size int64, session Session) {
+	m.stores++
+	m.entries[path] = cachedEntry{modTime: modTime, size: size, session: session}
+}
+
+func TestListSessionsUsesMetadataCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := FixtureOptions{Sessions: 3, Turns: 1, Projects: 1, Seed: 7, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	for _, source := range []string{"codex", "copilot", "mistral"} {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+		adaptersMap, _ := NewRegistered()
+		adapter := adaptersMap[source]
+		uncached, err := adapter.ListSessions("", 0)
+		if err != nil {
+			t.Fatalf("%s ListSessions returned error: %v", source, err)
+		}
+
+		cache := &mapMetadataCache{entries: make(map[string]cachedEntry)}
+		adapter.(interface{ SetMetadataCache(MetadataCache) }).SetMetadataCache(cache)
+		first, err := adapter.ListSessions("", 0)
+		if err != nil {
+			t.Fatalf("%s ListSessions returned error: %v", source, err)
+		}
+		if !reflect.DeepEqual(first, uncached) {
+			t.Fatalf("%s: cached listing differs from an uncached one:\n%+v\n%+v", source, first, uncached)
+		}
+		if cache.stores != opts.Sessions || cache.hits != 0 {
+			t.Fatalf("%s: expected %d stores and no hits on the first listing, got %d and %d", source, opts.Sessions, cache.stores, cache.hits)
+		}
+
+		// Unchanged files come from the cache; a changed file is parsed again
+		changed := first[0]
+		if err := os.WriteFile(changed.FilePath, append(mustReadFile(t, changed.FilePath), '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		second, err := adapter.ListSessions(first[0].ProjectPath, 0)
+		if err != nil {
+			t.Fatalf("%s ListSessions returned error: %v", source, err)
+		}
+		if len(second) != len(first) {
+			t.Fatalf("%s: expected %d sessions for the project, got %d (%+v)", source, len(first), len(second), first)
+		}
+		if cache.hits != opts.Sessions-1 || cache.stores != opts.Sessions+1 {
+			t.Fatalf("%s: expected %d hits and one new store, got %d hits and %d stores", source, opts.Sessions-1, cache.hits, cache.stores)
+		}
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
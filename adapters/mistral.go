@@ -13,7 +13,13 @@ import (
 // MistralAdapter implements SessionAdapter for Mistral Vibe CLI sessions.
 // Mistral Vibe stores sessions as JSON files in ~/.vibe/logs/session/
 type MistralAdapter struct {
-	homeDir string
+	homeDir       string
+	metadataCache MetadataCache
+	pathCache     PathCache
+}
+
+func init() {
+	Register("mistral", func() (SessionAdapter, error) { return NewMistralAdapter() })
 }
 
 // NewMistralAdapter creates a new Mistral Vibe session adapter.
@@ -32,10 +38,21 @@ func (m *MistralAdapter) Name() string {
 	return "mistral"
 }
 
-// mistralSession represents the structure of a Mistral Vibe session JSON file.
-type mistralSession struct {
-	Metadata mistralMetadata  `json:"metadata"`
-	Messages []mistralMessage `json:"messages"`
+// WatchPaths returns the directory Mistral Vibe writes session logs to.
+func (m *MistralAdapter) WatchPaths() []string {
+	return []string{filepath.Join(m.homeDir, ".vibe", "logs", "session")}
+}
+
+// SetMetadataCache makes ListSessions reuse metadata cached for unchanged
+// session files instead of parsing them again.
+func (m *MistralAdapter) SetMetadataCache(cache MetadataCache) {
+	m.metadataCache = cache
+}
+
+// SetPathCache makes GetSession look up which file holds a session instead of
+// reading every session file to find it.
+func (m *MistralAdapter) SetPathCache(cache PathCache) {
+	m.pathCache = cache
 }
 
 // mistralMetadata represents the metadata section of a Mistral Vibe session.
@@ -90,13 +107,9 @@ func (m *MistralAdapter) ListSessions(projectPath string, limit int) ([]Session,
 		return []Session{}, nil // No sessions
 	}
 
-	// Get absolute path if provided
-	if projectPath != "" {
-		var err error
-		projectPath, err = filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Read all session-*.json files
@@ -107,14 +120,16 @@ func (m *MistralAdapter) ListSessions(projectPath string, limit int) ([]Session,
 
 	sessions := make([]Session, 0, len(files))
 	for _, filePath := range files {
-		session, err := m.parseSessionMetadata(filePath)
+		session, err := cachedMetadata(m.metadataCache, filePath, func() (Session, error) {
+			return m.parseSessionMetadata(filePath)
+		})
 		if err != nil {
 			// Skip files we can't parse
 			continue
 		}
 
 		// Filter by project path if specified
-		if projectPath != "" && session.ProjectPath != projectPath {
+		if !filter.Match(session.ProjectPath) {
 			continue
 		}
 
@@ -136,25 +151,42 @@ func (m *MistralAdapter) ListSessions(projectPath string, limit int) ([]Session,
 
 // parseSessionMetadata extracts metadata from a Mistral Vibe session file.
 func (m *MistralAdapter) parseSessionMetadata(filePath string) (Session, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return Session{}, fmt.Errorf("failed to read session file: %w", err)
-	}
+	return m.scanSession(filePath, nil)
+}
 
-	var mistralSess mistralSession
-	if err := json.Unmarshal(data, &mistralSess); err != nil {
-		return Session{}, fmt.Errorf("failed to parse session JSON: %w", err)
+// scanSession reads a session file's metadata, passing each message to
+// onMessage as well when it is set, so searching reads the file only once.
+func (m *MistralAdapter) scanSession(filePath string, onMessage func(mistralMessage)) (Session, error) {
+	// Extract first user message and count all user messages
+	var firstMessage string
+	userCount := 0
+	metadata, err := streamMistralSession(filePath, func(msg mistralMessage) bool {
+		if onMessage != nil {
+			onMessage(msg)
+		}
+		if msg.Role == "user" {
+			userCount++
+			if firstMessage == "" {
+				firstMessage = extractFirstLine(msg.Content)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return Session{}, err
 	}
 
 	session := Session{
-		ID:          mistralSess.Metadata.SessionID,
-		Source:      "mistral",
-		ProjectPath: mistralSess.Metadata.Environment.WorkingDirectory,
-		FilePath:    filePath,
+		ID:               metadata.SessionID,
+		Source:           "mistral",
+		ProjectPath:      metadata.Environment.WorkingDirectory,
+		FilePath:         filePath,
+		FirstMessage:     firstMessage,
+		UserMessageCount: userCount,
 	}
 
 	// Parse timestamp from start_time
-	if mistralSess.Metadata.StartTime != "" {
+	if metadata.StartTime != "" {
 		// Try multiple time formats
 		formats := []string{
 			"2006-01-02T15:04:05.999999",       // Python datetime format without timezone
@@ -163,7 +195,7 @@ func (m *MistralAdapter) parseSessionMetadata(filePath string) (Session, error)
 			time.RFC3339Nano,
 		}
 		for _, format := range formats {
-			if ts, err := time.Parse(format, mistralSess.Metadata.StartTime); err == nil {
+			if ts, err := time.Parse(format, metadata.StartTime); err == nil {
 				session.Timestamp = ts
 				break
 			}
@@ -177,92 +209,148 @@ func (m *MistralAdapter) parseSessionMetadata(filePath string) (Session, error)
 		}
 	}
 
-	// Extract first user message and count all user messages
-	userCount := 0
-	for _, msg := range mistralSess.Messages {
-		if msg.Role != "user" {
-			continue
-		}
-		userCount++
-		if session.FirstMessage == "" {
-			session.FirstMessage = extractFirstLine(msg.Content)
-		}
-	}
-
-	session.UserMessageCount = userCount
-
 	return session, nil
 }
 
-// GetSession retrieves the full content of a Mistral Vibe session with pagination.
-func (m *MistralAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
-	sessionsDir := filepath.Join(m.homeDir, ".vibe", "logs", "session")
-
-	// Find the session file by searching through all files
-	files, err := filepath.Glob(filepath.Join(sessionsDir, "session_*.json"))
+// streamMistralSession reads a Mistral Vibe session file, a JSON object with
+// metadata and a messages array, with a streaming decoder, passing its messages to onMessage one at a time, so a large session
+// is never held in memory whole. Messages after onMessage returns false are
+// skipped.
+func streamMistralSession(filePath string, onMessage func(mistralMessage) bool) (mistralMetadata, error) {
+	var metadata mistralMetadata
+	if err := checkFileSize(filePath); err != nil {
+		return metadata, err
+	}
+	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list session files: %w", err)
+		return metadata, fmt.Errorf("failed to read session file: %w", err)
 	}
+	defer file.Close()
 
-	var sessionFile string
-	for _, file := range files {
-		data, err := os.ReadFile(file)
+	dec := json.NewDecoder(file)
+	if err := expectDelim(dec, '{'); err != nil {
+		return metadata, fmt.Errorf("failed to parse session JSON: %w", err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
 		if err != nil {
-			continue
+			return metadata, fmt.Errorf("failed to parse session JSON: %w", err)
+		}
+		switch key {
+		case "metadata":
+			err = dec.Decode(&metadata)
+		case "messages":
+			err = decodeMistralMessages(dec, onMessage)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return metadata, fmt.Errorf("failed to parse session JSON: %w", err)
 		}
+	}
+	return metadata, nil
+}
 
-		var sess mistralSession
-		if err := json.Unmarshal(data, &sess); err != nil {
+// decodeMistralMessages decodes the messages array of a session one message
+// at a time, passing each to onMessage until it returns false.
+func decodeMistralMessages(dec *json.Decoder, onMessage func(mistralMessage) bool) error {
+	token, err := dec.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("messages is not an array")
+	}
+	reading := true
+	for dec.More() {
+		if !reading {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return err
+			}
 			continue
 		}
-
-		if sess.Metadata.SessionID == sessionID {
-			sessionFile = file
-			break
+		var msg mistralMessage
+		if err := dec.Decode(&msg); err != nil {
+			return err
 		}
+		reading = onMessage(msg)
 	}
+	_, err = dec.Token() // closing ]
+	return err
+}
 
-	if sessionFile == "" {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
+// expectDelim reads the next token from dec, failing unless it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
 	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
 
-	// Read the session file
-	messages, err := m.readAllMessages(sessionFile)
+// GetSession retrieves the full content of a Mistral Vibe session with pagination.
+func (m *MistralAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
+	messages, _, _, _, err := m.GetSessionPage(sessionID, page, pageSize, false)
 	if err != nil {
 		return nil, err
 	}
+	return messages, nil
+}
 
-	// Apply pagination
-	start := page * pageSize
-	if start >= len(messages) {
-		return []Message{}, nil
+// GetSessionPage retrieves one page of session messages plus pagination metadata.
+// If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
+func (m *MistralAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	sessionFile := m.findSessionFile(sessionID)
+	if sessionFile == "" {
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
 	}
 
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
+	// Read the session file
+	messages, err := m.readAllMessages(sessionFile)
+	if err != nil {
+		return nil, 0, page, false, err
 	}
 
-	return messages[start:end], nil
+	pageMessages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+	return pageMessages, totalMessages, resolvedPage, hasMore, nil
 }
 
-// readAllMessages reads all messages from a Mistral Vibe session file.
-func (m *MistralAdapter) readAllMessages(filePath string) ([]Message, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read session file: %w", err)
-	}
-
-	var sess mistralSession
-	if err := json.Unmarshal(data, &sess); err != nil {
-		return nil, fmt.Errorf("failed to parse session JSON: %w", err)
+// findSessionFile returns the file holding a session, or "" if there is none.
+// Files are named by date rather than session ID, so the ID is read from each.
+func (m *MistralAdapter) findSessionFile(sessionID string) string {
+	holds := func(file string) bool {
+		session, err := cachedMetadata(m.metadataCache, file, func() (Session, error) {
+			return m.parseSessionMetadata(file)
+		})
+		return err == nil && session.ID == sessionID
 	}
+	return findSessionPath(m.pathCache, "mistral", sessionID, holds, func() string {
+		files, err := filepath.Glob(filepath.Join(m.homeDir, ".vibe", "logs", "session", "session_*.json"))
+		if err != nil {
+			return ""
+		}
+		for _, file := range files {
+			if holds(file) {
+				return file
+			}
+		}
+		return ""
+	})
+}
 
-	messages := make([]Message, 0, len(sess.Messages))
-	for _, msg := range sess.Messages {
+// readAllMessages reads all messages from a Mistral Vibe session file.
+func (m *MistralAdapter) readAllMessages(filePath string) ([]Message, error) {
+	var messages []Message
+	_, err := streamMistralSession(filePath, func(msg mistralMessage) bool {
 		// Skip system messages
 		if msg.Role == "system" {
-			continue
+			return true
 		}
 
 		role := normalizeMistralRole(msg.Role)
@@ -300,6 +388,13 @@ func (m *MistralAdapter) readAllMessages(filePath string) ([]Message, error) {
 		}
 
 		messages = append(messages, message)
+		return !messageLimitReached(len(messages))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []Message{}
 	}
 
 	return messages, nil
@@ -319,12 +414,9 @@ func (m *MistralAdapter) SearchSessions(projectPath, query string, limit int) ([
 		return []Session{}, nil
 	}
 
-	if projectPath != "" {
-		var err error
-		projectPath, err = filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
+	filter, err := NewProjectFilter(projectPath)
+	if err != nil {
+		return nil, err
 	}
 
 	files, err := filepath.Glob(filepath.Join(sessionsDir, "session_*.json"))
@@ -337,25 +429,22 @@ func (m *MistralAdapter) SearchSessions(projectPath, query string, limit int) ([
 
 	// Read each file once and search in a single pass
 	for _, filePath := range files {
-		session, mistralSess, err := m.parseSessionFull(filePath)
+		// Search in all message content
+		found := false
+		session, err := m.scanSession(filePath, func(msg mistralMessage) {
+			if !found && strings.Contains(strings.ToLower(msg.Content), query) {
+				found = true
+			}
+		})
 		if err != nil {
 			continue
 		}
 
 		// Filter by project path if specified
-		if projectPath != "" && session.ProjectPath != projectPath {
+		if !filter.Match(session.ProjectPath) {
 			continue
 		}
 
-		// Search in all message content
-		found := false
-		for _, msg := range mistralSess.Messages {
-			if strings.Contains(strings.ToLower(msg.Content), query) {
-				found = true
-				break
-			}
-		}
-
 		if found {
 			matches = append(matches, session)
 			if limit > 0 && len(matches) >= limit {
@@ -372,60 +461,33 @@ func (m *MistralAdapter) SearchSessions(projectPath, query string, limit int) ([
 	return matches, nil
 }
 
-// parseSessionFull reads a session file and returns both metadata and raw session data.
-// This avoids reading the file twice when both are needed.
-func (m *MistralAdapter) parseSessionFull(filePath string) (Session, *mistralSession, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return Session{}, nil, fmt.Errorf("failed to read session file: %w", err)
-	}
-
-	var mistralSess mistralSession
-	if err := json.Unmarshal(data, &mistralSess); err != nil {
-		return Session{}, nil, fmt.Errorf("failed to parse session JSON: %w", err)
-	}
-
-	session := Session{
-		ID:          mistralSess.Metadata.SessionID,
-		Source:      "mistral",
-		ProjectPath: mistralSess.Metadata.Environment.WorkingDirectory,
-		FilePath:    filePath,
-	}
-
-	// Parse timestamp
-	if mistralSess.Metadata.StartTime != "" {
-		formats := []string{
-			"2006-01-02T15:04:05.999999",
-			"2006-01-02T15:04:05.999999Z07:00",
-			time.RFC3339,
-			time.RFC3339Nano,
-		}
-		for _, format := range formats {
-			if ts, err := time.Parse(format, mistralSess.Metadata.StartTime); err == nil {
-				session.Timestamp = ts
-				break
+// ValidateSession checks a session file strictly against the format this
+// adapter reads, reporting every field it would skip or misread.
+func (m *MistralAdapter) ValidateSession(session Session) ([]SchemaDeviation, error) {
+	return validateJSONFile(session.FilePath, func(doc map[string]interface{}, d *deviations) {
+		if metadata := d.object(doc, "", "metadata", true); metadata != nil {
+			d.str(metadata, "metadata", "session_id", true)
+			d.timestamp(metadata, "metadata", "start_time", true, "2006-01-02T15:04:05.999999", time.RFC3339Nano)
+			if env := d.object(metadata, "metadata", "environment", false); env != nil {
+				d.str(env, "metadata.environment", "working_directory", false)
 			}
 		}
-	}
-
-	if session.Timestamp.IsZero() {
-		if stat, err := os.Stat(filePath); err == nil {
-			session.Timestamp = stat.ModTime()
-		}
-	}
 
-	// Extract first user message and count
-	userCount := 0
-	for _, msg := range mistralSess.Messages {
-		if msg.Role != "user" {
-			continue
-		}
-		userCount++
-		if session.FirstMessage == "" {
-			session.FirstMessage = extractFirstLine(msg.Content)
-		}
-	}
-	session.UserMessageCount = userCount
-
-	return session, &mistralSess, nil
+		messages := d.array(doc, "", "messages", true)
+		d.objects(messages, "messages", func(msg map[string]interface{}, path string) {
+			d.oneOf(path+".role", "role", d.str(msg, path, "role", true), "system", "user", "assistant", "tool")
+			d.str(msg, path, "content", false)
+			d.objects(d.array(msg, path, "tool_calls", false), path+".tool_calls", func(call map[string]interface{}, callPath string) {
+				d.str(call, callPath, "id", true)
+				if fn := d.object(call, callPath, "function", true); fn != nil {
+					d.str(fn, callPath+".function", "name", true)
+					d.str(fn, callPath+".function", "arguments", false)
+				}
+			})
+			d.objects(d.array(msg, path, "tool_call_results", false), path+".tool_call_results", func(result map[string]interface{}, resultPath string) {
+				d.str(result, resultPath, "tool_call_id", true)
+				d.timestamp(result, resultPath, "timestamp", false)
+			})
+		})
+	})
 }
@@ -0,0 +1,324 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MessageOffset locates the line a message was read from in a JSONL session
+// file, along with the reader state needed to resume reading there.
+type MessageOffset struct {
+	Offset int64           `json:"o"`
+	State  json.RawMessage `json:"s,omitempty"`
+}
+
+// OffsetCache persists where each message starts in JSONL session files, so a
+// page deep into a large session is read by seeking to it instead of parsing
+// every line before it. Like MetadataCache, entries are valid only while the
+// file's modification time and size are unchanged.
+type OffsetCache interface {
+	// LoadMessageOffsets returns the offsets stored for path, if they were
+	// stored for a file with the same modification time and size.
+	LoadMessageOffsets(path string, modTime time.Time, size int64) ([]MessageOffset, bool)
+	// StoreMessageOffsets records the offsets of every message in path.
+	StoreMessageOffsets(source, path string, modTime time.Time, size int64, offsets []MessageOffset)
+}
+
+// jsonlDecoder turns the lines of a JSONL session file into messages, at most
+// one per line. Formats that carry state from line to line, such as the model
+// in effect, expose it so reading can resume mid-file.
+type jsonlDecoder interface {
+	// decode reads one line, appending the message it produces, if any. It may
+	// also update messages already read, such as adding token usage to the
+	// last assistant message.
+	decode(line []byte, messages []Message) []Message
+	// state returns what decode carries from one line to the next, or nil if
+	// there is nothing to carry.
+	state() interface{}
+	// restore resumes from a JSON-encoded value returned by state.
+	restore(state json.RawMessage) error
+}
+
+// settlingDecoder is a jsonlDecoder that may change messages before the last
+// one it read, such as codexDecoder adding token usage to the last assistant
+// message.
+type settlingDecoder interface {
+	// settled returns how many of the n messages read so far no later line
+	// can change.
+	settled(n int) int
+}
+
+// settledMessages returns how many of the n messages d has read no later line
+// can change. Unless d says otherwise, only the last one can.
+func settledMessages(d jsonlDecoder, n int) int {
+	if s, ok := d.(settlingDecoder); ok {
+		return s.settled(n)
+	}
+	return max(n-1, 0)
+}
+
+// readJSONLMessages reads count messages from a JSONL session file, starting
+// at message start; a negative count reads to the end. Lines longer than
+// maxLine stop reading with an error. With a cache, the first read of each
+// version of the file records where every message starts, and later reads
+// seek straight to start.
+func readJSONLMessages(cache OffsetCache, source, path string, newDecoder func() jsonlDecoder, maxLine, start, count int) ([]Message, error) {
+	offsets, messages, scanned, err := loadJSONL(cache, source, path, newDecoder, maxLine, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !scanned {
+		return readJSONLFrom(path, newDecoder(), maxLine, offsets, start, count)
+	}
+
+	// Apply pagination
+	if start >= len(messages) {
+		return []Message{}, nil
+	}
+	end := len(messages)
+	if count >= 0 && start+count < end {
+		end = start + count
+	}
+	return messages[start:end], nil
+}
+
+// readJSONLPage reads one page of a JSONL session file with the metadata
+// GetSessionPage reports.
+func readJSONLPage(cache OffsetCache, source, path string, newDecoder func() jsonlDecoder, maxLine, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
+	messages := []Message{}
+	totalMessages, resolvedPage, hasMore, err := streamJSONLPage(cache, source, path, newDecoder, maxLine, page, pageSize, fromEnd, func(msg Message) error {
+		messages = append(messages, msg)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, page, false, err
+	}
+	return messages, totalMessages, resolvedPage, hasMore, nil
+}
+
+// streamJSONLPage reads one page of a JSONL session file like readJSONLPage,
+// passing each of its messages to yield as soon as no later line can change
+// it rather than returning the page. With cached offsets the total is known
+// without reading the file, so a page counted from the end is read by
+// seeking to it. Otherwise the whole file is read to record the offsets; a
+// page counted from the start is still yielded as it is read, but one counted
+// from the end only once the total is known.
+func streamJSONLPage(cache OffsetCache, source, path string, newDecoder func() jsonlDecoder, maxLine, page, pageSize int, fromEnd bool, yield func(Message) error) (int, int, bool, error) {
+	var inPage func(int, Message) error
+	if !fromEnd {
+		start := page * pageSize
+		inPage = func(i int, msg Message) error {
+			if i >= start && i < start+pageSize {
+				return yield(msg)
+			}
+			return nil
+		}
+	}
+	offsets, messages, scanned, err := loadJSONL(cache, source, path, newDecoder, maxLine, inPage)
+	if err != nil {
+		return 0, page, false, err
+	}
+	if scanned {
+		messages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+		if fromEnd {
+			for _, msg := range messages {
+				if err := yield(msg); err != nil {
+					return 0, page, false, err
+				}
+			}
+		}
+		return totalMessages, resolvedPage, hasMore, nil
+	}
+
+	totalMessages := len(offsets)
+	resolvedPage := resolvePage(page, pageSize, totalMessages, fromEnd)
+	if resolvedPage < 0 {
+		return totalMessages, resolvedPage, false, nil
+	}
+	start := resolvedPage * pageSize
+	if err := streamJSONLFrom(path, newDecoder(), maxLine, offsets, start, pageSize, yield); err != nil {
+		return 0, page, false, err
+	}
+	return totalMessages, resolvedPage, start+pageSize < totalMessages, nil
+}
+
+// loadJSONL returns the offsets cached for the current version of a JSONL
+// session file. Without them, it reads every message instead, recording their
+// offsets in the cache, and reports scanned. Messages it reads are passed to
+// yield, if set, with their index, as they are read.
+func loadJSONL(cache OffsetCache, source, path string, newDecoder func() jsonlDecoder, maxLine int, yield func(int, Message) error) (offsets []MessageOffset, messages []Message, scanned bool, err error) {
+	if err := checkFileSize(path); err != nil {
+		return nil, nil, false, err
+	}
+	var modTime time.Time
+	var size int64
+	if cache != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to open session file: %w", err)
+		}
+		modTime, size = info.ModTime(), info.Size()
+		if offsets, ok := cache.LoadMessageOffsets(path, modTime, size); ok {
+			if l := currentLimits().MaxMessages; l > 0 && len(offsets) > l {
+				offsets = offsets[:l]
+			}
+			return offsets, nil, false, nil
+		}
+	}
+
+	messages, offsets, err = scanJSONL(path, newDecoder(), maxLine, cache != nil, yield)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	// Offsets cut short by the message limit would outlast a change to it
+	if cache != nil && !messageLimitReached(len(offsets)) {
+		cache.StoreMessageOffsets(source, path, modTime, size, offsets)
+	}
+	return offsets, messages, true, nil
+}
+
+// scanJSONL decodes every message in a JSONL session file, recording where
+// each one starts when record is set. Each message is passed to yield, if
+// set, with its index, once no later line can change it.
+func scanJSONL(path string, d jsonlDecoder, maxLine int, record bool, yield func(int, Message) error) ([]Message, []MessageOffset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+
+	var messages []Message
+	var offsets []MessageOffset
+	sent := 0
+	flush := func(n int) error {
+		for ; yield != nil && sent < n; sent++ {
+			if err := yield(sent, messages[sent]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := newOffsetScanner(file, maxLine)
+	for scanner.Scan() {
+		var before interface{}
+		if record {
+			before = d.state()
+		}
+		n := len(messages)
+		messages = d.decode(scanner.Bytes(), messages)
+		if record && len(messages) > n {
+			offset := MessageOffset{Offset: scanner.start}
+			if before != nil {
+				if offset.State, err = json.Marshal(before); err != nil {
+					return nil, nil, fmt.Errorf("failed to encode reader state: %w", err)
+				}
+			}
+			offsets = append(offsets, offset)
+		}
+		if messageLimitReached(len(messages)) {
+			messages = messages[:currentLimits().MaxMessages]
+			break
+		}
+		if err := flush(settledMessages(d, len(messages))); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading session file: %w", err)
+	}
+	if err := flush(len(messages)); err != nil {
+		return nil, nil, err
+	}
+	return messages, offsets, nil
+}
+
+// readJSONLFrom reads count messages from a JSONL session file by seeking to
+// message start.
+func readJSONLFrom(path string, d jsonlDecoder, maxLine int, offsets []MessageOffset, start, count int) ([]Message, error) {
+	messages := []Message{}
+	err := streamJSONLFrom(path, d, maxLine, offsets, start, count, func(msg Message) error {
+		messages = append(messages, msg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// streamJSONLFrom reads count messages from a JSONL session file by seeking
+// to message start, passing each to yield once no later line can change it.
+// Lines after the last message are read up to the next message, so updates
+// they make to it (like token usage) are kept. Messages are let go once
+// yielded, so a large page is never held whole.
+func streamJSONLFrom(path string, d jsonlDecoder, maxLine int, offsets []MessageOffset, start, count int, yield func(Message) error) error {
+	if start >= len(offsets) {
+		return nil
+	}
+	from := offsets[start]
+	if len(from.State) > 0 {
+		if err := d.restore(from.State); err != nil {
+			return fmt.Errorf("failed to restore reader state: %w", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(from.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek in session file: %w", err)
+	}
+
+	var messages []Message
+	sent := 0
+	flush := func(n int) error {
+		for ; sent < n; sent++ {
+			if err := yield(messages[sent]); err != nil {
+				return err
+			}
+			messages[sent] = Message{}
+		}
+		return nil
+	}
+	scanner := newOffsetScanner(file, maxLine)
+	for scanner.Scan() {
+		messages = d.decode(scanner.Bytes(), messages)
+		if count >= 0 && len(messages) > count {
+			return flush(count)
+		}
+		if err := flush(settledMessages(d, len(messages))); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading session file: %w", err)
+	}
+	return flush(len(messages))
+}
+
+// offsetScanner is a line scanner that knows where each line starts.
+type offsetScanner struct {
+	*bufio.Scanner
+	start int64 // offset of the line last returned by Scan, relative to where scanning began
+	next  int64
+}
+
+func newOffsetScanner(r io.Reader, maxLine int) *offsetScanner {
+	s := &offsetScanner{Scanner: bufio.NewScanner(r)}
+	setScanBuffer(s.Scanner, 64*1024, maxLine)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			s.start = s.next
+			s.next += int64(advance)
+		}
+		return advance, token, err
+	})
+	return s
+}
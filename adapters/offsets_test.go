@@ -0,0 +1,203 @@
+package adapters
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mapOffsetCache is an OffsetCache kept in memory, counting hits.
+type mapOffsetCache struct {
+	entries map[string]offsetEntry
+	hits    int
+}
+
+type offsetEntry struct {
+	modTime time.Time
+	size    int64
+	offsets []MessageOffset
+}
+
+func (m *mapOffsetCache) LoadMessageOffsets(path string, modTime time.Time, size int64) ([]MessageOffset, bool) {
+	entry, ok := m.entries[path]
+	if !ok || !entry.modTime.Equal(modTime) || entry.size != size {
+		return nil, false
+	}
+	m.hits++
+	return entry.offsets, true
+}
+
+func (m *mapOffsetCache) StoreMessageOffsets(source, path string, modTime time.Time, size int64, offsets []MessageOffset) {
+	m.entries[path] = offsetEntry{modTime: modTime, size: size, offsets: offsets}
+}
+
+func TestGetSessionSeeksWithOffsetCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := FixtureOptions{Sessions: 2, Turns: 3, Projects: 1, Seed: 11, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	for _, source := range []string{"claude", "codex", "copilot"} {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+		adaptersMap, _ := NewRegistered()
+		adapter := adaptersMap[source]
+		sessions, err := adapter.ListSessions("", 0)
+		if err != nil || len(sessions) == 0 {
+			t.Fatalf("%s ListSessions returned %d sessions (%v)", source, len(sessions), err)
+		}
+		session := sessions[0]
+		all, err := adapter.GetSession(session.ID, 0, 1000)
+		if err != nil {
+			t.Fatalf("%s GetSession returned error: %v", source, err)
+		}
+		if len(all) < 4 {
+			t.Fatalf("%s: expected a few messages to page through, got %d", source, len(all))
+		}
+
+		cache := &mapOffsetCache{entries: make(map[string]offsetEntry)}
+		adapter.(interface{ SetOffsetCache(OffsetCache) }).SetOffsetCache(cache)
+		// Every page, including those past the end, matches reading the whole session
+		for _, pageSize := range []int{1, 3} {
+			for page := 0; page*pageSize <= len(all); page++ {
+				got, err := adapter.GetSession(session.ID, page, pageSize)
+				if err != nil {
+					t.Fatalf("%s GetSession(page %d) returned error: %v", source, page, err)
+				}
+				end := min((page+1)*pageSize, len(all))
+				if want := all[page*pageSize : end]; !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s page %d of %d differs:\n got %+v\nwant %+v", source, page, pageSize, got, want)
+				}
+			}
+		}
+		if cache.hits == 0 {
+			t.Fatalf("%s: expected pages to be read using cached offsets", source)
+		}
+
+		// Changing the file invalidates the offsets
+		hits := cache.hits
+		data, err := os.ReadFile(session.FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(session.FilePath, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := adapter.GetSession(session.ID, 1, 1); err != nil {
+			t.Fatalf("%s GetSession returned error: %v", source, err)
+		}
+		if cache.hits != hits {
+			t.Fatalf("%s: expected offsets for the old file to be ignored", source)
+		}
+	}
+}
+
+func TestGetSessionPageFromEnd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := FixtureOptions{Sessions: 1, Turns: 4, Projects: 1, Seed: 5, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	type pager interface {
+		GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error)
+	}
+	for _, source := range FixtureSources() {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+		adaptersMap, _ := NewRegistered()
+		adapter := adaptersMap[source]
+		p, ok := adapter.(pager)
+		if !ok {
+			t.Fatalf("%s does not implement GetSessionPage", source)
+		}
+		sessions, err := adapter.ListSessions("", 0)
+		if err != nil || len(sessions) == 0 {
+			t.Fatalf("%s ListSessions returned %d sessions (%v)", source, len(sessions), err)
+		}
+		all, err := adapter.GetSession(sessions[0].ID, 0, 1000)
+		if err != nil {
+			t.Fatalf("%s GetSession returned error: %v", source, err)
+		}
+
+		// Reading the tail twice covers both a full read and, where offsets
+		// are cached, seeking to the last page
+		if c, ok := adapter.(interface{ SetOffsetCache(OffsetCache) }); ok {
+			c.SetOffsetCache(&mapOffsetCache{entries: make(map[string]offsetEntry)})
+		}
+		const pageSize = 3
+		lastPage := (len(all) - 1) / pageSize
+		for i := 0; i < 2; i++ {
+			got, total, resolved, hasMore, err := p.GetSessionPage(sessions[0].ID, 0, pageSize, true)
+			if err != nil {
+				t.Fatalf("%s GetSessionPage returned error: %v", source, err)
+			}
+			if total != len(all) || resolved != lastPage || hasMore {
+				t.Fatalf("%s: got total %d, page %d, has_more %v; want %d, %d, false", source, total, resolved, hasMore, len(all), lastPage)
+			}
+			if want := all[lastPage*pageSize:]; !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s last page differs:\n got %+v\nwant %+v", source, got, want)
+			}
+		}
+
+		if got, _, resolved, _, err := p.GetSessionPage(sessions[0].ID, lastPage+1, pageSize, true); err != nil || len(got) != 0 || resolved != -1 {
+			t.Fatalf("%s: expected no messages before the first page, got %d at page %d (%v)", source, len(got), resolved, err)
+		}
+	}
+}
+
+func TestStreamSessionPageYieldsMessagesAsRead(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := FixtureOptions{Sessions: 1, Turns: 4, Projects: 1, Seed: 7, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	type streamer interface {
+		GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error)
+		StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(Message) error) (int, int, bool, error)
+	}
+	stop := errors.New("stop")
+	for _, source := range []string{"claude", "codex", "copilot"} {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+		adaptersMap, _ := NewRegistered()
+		adapter := adaptersMap[source]
+		s := adapter.(streamer)
+		sessions, err := adapter.ListSessions("", 0)
+		if err != nil || len(sessions) == 0 {
+			t.Fatalf("%s ListSessions returned %d sessions (%v)", source, len(sessions), err)
+		}
+		id := sessions[0].ID
+
+		// Once without cached offsets, reading the whole file, and once seeking
+		adapter.(interface{ SetOffsetCache(OffsetCache) }).SetOffsetCache(&mapOffsetCache{entries: make(map[string]offsetEntry)})
+		for i := 0; i < 2; i++ {
+			for _, fromEnd := range []bool{false, true} {
+				want, wantTotal, wantPage, wantMore, err := s.GetSessionPage(id, 0, 3, fromEnd)
+				if err != nil {
+					t.Fatalf("%s GetSessionPage returned error: %v", source, err)
+				}
+				var got []Message
+				total, page, hasMore, err := s.StreamSessionPage(id, 0, 3, fromEnd, func(msg Message) error {
+					got = append(got, msg)
+					return nil
+				})
+				if err != nil || total != wantTotal || page != wantPage || hasMore != wantMore || !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s streamed page differs (from end %v):\n got %+v (%d, %d, %v, %v)\nwant %+v (%d, %d, %v)",
+						source, fromEnd, got, total, page, hasMore, err, want, wantTotal, wantPage, wantMore)
+				}
+			}
+
+			// A failing yield stops the read at the message it failed on
+			yielded := 0
+			_, _, _, err := s.StreamSessionPage(id, 0, 3, false, func(Message) error {
+				yielded++
+				return stop
+			})
+			if !errors.Is(err, stop) || yielded != 1 {
+				t.Fatalf("%s: expected reading to stop after 1 message, got %d (%v)", source, yielded, err)
+			}
+		}
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -25,6 +30,13 @@ import (
 type OpencodeAdapter struct {
 	storageDir string
 	dbPath     string
+
+	mu sync.Mutex
+	db *opencodeDB // opened on first use
+}
+
+func init() {
+	Register("opencode", func() (SessionAdapter, error) { return NewOpencodeAdapter() })
 }
 
 // NewOpencodeAdapter creates a new opencode session adapter.
@@ -46,22 +58,98 @@ func (o *OpencodeAdapter) Name() string {
 	return "opencode"
 }
 
-func (o *OpencodeAdapter) openDB() (*sql.DB, error) {
-	if _, err := os.Stat(o.dbPath); err != nil {
+// WatchPaths returns opencode's database and its legacy file storage.
+func (o *OpencodeAdapter) WatchPaths() []string {
+	return []string{o.dbPath, o.storageDir}
+}
+
+// opencodeDB is a connection pool on opencode.db that the adapter keeps for
+// its lifetime, with the statements every session read uses prepared once.
+type opencodeDB struct {
+	*sql.DB
+	file os.FileInfo // the database file the pool was opened on
+
+	sessionExists *sql.Stmt
+	countMessages *sql.Stmt
+	messagePage   *sql.Stmt
+}
+
+// database returns the adapter's pool on opencode.db, opening it on first
+// use. If opencode has replaced the database file since, the pool is
+// reopened on the new file.
+func (o *OpencodeAdapter) database() (*opencodeDB, error) {
+	info, err := os.Stat(o.dbPath)
+	if err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite", o.dbPath)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.db != nil {
+		if os.SameFile(o.db.file, info) {
+			return o.db, nil
+		}
+		o.db.Close()
+		o.db = nil
+	}
+
+	db, err := openOpencodeDB(o.dbPath, info)
+	if err != nil {
+		return nil, err
+	}
+	o.db = db
+	return db, nil
+}
+
+// warnOpencodeFallback logs why the database couldn't be used before falling
+// back to flat files. A missing database (on versions of opencode that predate
+// it) and sessions only stored in files are expected, and aren't logged.
+func warnOpencodeFallback(err error) {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrSessionNotFound) {
+		return
+	}
+	log.Printf("Warning: opencode database unavailable, using file fallback: %v", err)
+}
+
+func openOpencodeDB(path string, info os.FileInfo) (*opencodeDB, error) {
+	// busy_timeout is set in the DSN so every pooled connection gets it
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open opencode database: %w", err)
 	}
 
-	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to set sqlite busy_timeout: %w", err)
+	odb := &opencodeDB{DB: db, file: info}
+	for _, stmt := range []struct {
+		dest  **sql.Stmt
+		query string
+	}{
+		{&odb.sessionExists, "SELECT 1 FROM session WHERE id = ? LIMIT 1"},
+		{&odb.countMessages, "SELECT COUNT(*) FROM message WHERE session_id = ?"},
+		{&odb.messagePage, `
+			SELECT id, time_created, data
+			FROM message
+			WHERE session_id = ?
+			ORDER BY time_created ASC, id ASC
+			LIMIT ? OFFSET ?
+		`},
+	} {
+		if *stmt.dest, err = db.Prepare(stmt.query); err != nil {
+			odb.Close()
+			return nil, fmt.Errorf("failed to prepare opencode query: %w", err)
+		}
 	}
+	return odb, nil
+}
 
-	return db, nil
+// Close closes the prepared statements and the pool.
+func (db *opencodeDB) Close() error {
+	for _, stmt := range []*sql.Stmt{db.sessionExists, db.countMessages, db.messagePage} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return db.DB.Close()
 }
 
 // opencodeProject represents a project file in storage/project/
@@ -93,6 +181,7 @@ type opencodeMessage struct {
 	Role      string                 `json:"role"`
 	System    interface{}            `json:"system,omitempty"` // Can be string or array
 	Mode      string                 `json:"mode,omitempty"`
+	Agent     string                 `json:"agent,omitempty"` // replaces mode in newer opencode versions
 	Content   interface{}            `json:"content,omitempty"`
 	Cost      float64                `json:"cost,omitempty"`
 	Tokens    map[string]interface{} `json:"tokens,omitempty"`
@@ -115,6 +204,7 @@ func (o *OpencodeAdapter) ListSessions(projectPath string, limit int) ([]Session
 		return sessions, nil
 	}
 
+	warnOpencodeFallback(err)
 	fallbackSessions, fallbackErr := o.listSessionsFromFiles(projectPath, limit)
 	if fallbackErr == nil {
 		return fallbackSessions, nil
@@ -125,23 +215,13 @@ func (o *OpencodeAdapter) ListSessions(projectPath string, limit int) ([]Session
 
 // listSessionsFromSQLite lists sessions from opencode.db.
 func (o *OpencodeAdapter) listSessionsFromSQLite(projectPath string, limit int) ([]Session, error) {
-	db, err := o.openDB()
+	db, err := o.database()
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
-
-	return o.listSessionsFromSQLiteWithDB(db, projectPath, limit)
-}
 
-func (o *OpencodeAdapter) listSessionsFromSQLiteWithDB(db *sql.DB, projectPath string, limit int) ([]Session, error) {
-	var absPath string
-	if projectPath != "" {
-		resolvedPath, err := filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
-		absPath = resolvedPath
+	if _, err := NewProjectFilter(projectPath); err != nil {
+		return nil, err
 	}
 
 	query := `
@@ -151,9 +231,9 @@ func (o *OpencodeAdapter) listSessionsFromSQLiteWithDB(db *sql.DB, projectPath s
 	`
 	args := make([]interface{}, 0, 2)
 
-	if absPath != "" {
-		query += " WHERE p.worktree = ?"
-		args = append(args, absPath)
+	if projectPath != "" {
+		query += " WHERE project_matches(?, p.worktree)"
+		args = append(args, projectPath)
 	}
 
 	query += " ORDER BY s.time_created DESC"
@@ -167,6 +247,17 @@ func (o *OpencodeAdapter) listSessionsFromSQLiteWithDB(db *sql.DB, projectPath s
 	if err != nil {
 		return nil, fmt.Errorf("failed to query sessions from sqlite: %w", err)
 	}
+	sessions, err := o.scanSessionRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sqlite sessions: %w", err)
+	}
+	o.addUserMessageStats(db.DB, sessions)
+	return sessions, nil
+}
+
+// scanSessionRows reads sessions from rows of id, title, creation time, and
+// worktree, and closes rows.
+func (o *OpencodeAdapter) scanSessionRows(rows *sql.Rows) ([]Session, error) {
 	defer rows.Close()
 
 	sessions := make([]Session, 0)
@@ -179,73 +270,106 @@ func (o *OpencodeAdapter) listSessionsFromSQLiteWithDB(db *sql.DB, projectPath s
 		)
 
 		if err := rows.Scan(&sessionID, &title, &createdAt, &worktree); err != nil {
-			return nil, fmt.Errorf("failed to scan sqlite session row: %w", err)
-		}
-
-		firstMessage, userCount, firstErr := o.getFirstUserMessageAndCountFromSQLite(db, sessionID)
-		if firstErr != nil {
-			firstMessage = ""
-			userCount = 0
+			return nil, err
 		}
 
 		sessions = append(sessions, Session{
-			ID:               sessionID,
-			Source:           "opencode",
-			ProjectPath:      worktree,
-			FirstMessage:     firstMessage,
-			Summary:          title,
-			Timestamp:        time.UnixMilli(createdAt),
-			FilePath:         o.dbPath,
-			UserMessageCount: userCount,
+			ID:          sessionID,
+			Source:      "opencode",
+			ProjectPath: worktree,
+			Summary:     title,
+			Timestamp:   time.UnixMilli(createdAt),
+			FilePath:    o.dbPath,
 		})
 	}
+	return sessions, rows.Err()
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed while iterating sqlite sessions: %w", err)
+// addUserMessageStats fills in each session's first user message and user
+// message count. A session whose stats can't be read keeps them empty.
+func (o *OpencodeAdapter) addUserMessageStats(db *sql.DB, sessions []Session) {
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	stats, err := o.userMessageStats(db, ids)
+	if err != nil {
+		return
+	}
+	for i := range sessions {
+		if s, ok := stats[sessions[i].ID]; ok {
+			sessions[i].FirstMessage = s.firstMessage
+			sessions[i].UserMessageCount = s.count
+		}
 	}
+}
 
-	return sessions, nil
+// opencodeUserStats is what listings show about a session's user messages.
+type opencodeUserStats struct {
+	firstMessage string
+	count        int // user messages with text
 }
 
-func (o *OpencodeAdapter) getFirstUserMessageAndCountFromSQLite(db *sql.DB, sessionID string) (string, int, error) {
-	firstQuery := `
-		SELECT json_extract(p.data, '$.text')
-		FROM message m
-		JOIN part p ON p.message_id = m.id
-		WHERE m.session_id = ?
-		  AND json_extract(m.data, '$.role') = 'user'
-		  AND json_extract(p.data, '$.type') = 'text'
-		ORDER BY m.time_created ASC, p.time_created ASC
-		LIMIT 1
-	`
+// userMessageStats returns the first user message and user message count of
+// each session, with one query per chunk of sessions rather than two per
+// session.
+func (o *OpencodeAdapter) userMessageStats(db *sql.DB, sessionIDs []string) (map[string]opencodeUserStats, error) {
+	result := make(map[string]opencodeUserStats, len(sessionIDs))
 
-	var firstText sql.NullString
-	err := db.QueryRow(firstQuery, sessionID).Scan(&firstText)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return "", 0, fmt.Errorf("failed to query first user message: %w", err)
-	}
+	const chunkSize = 400
+	for start := 0; start < len(sessionIDs); start += chunkSize {
+		chunk := sessionIDs[start:min(start+chunkSize, len(sessionIDs))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
 
-	countQuery := `
-		SELECT COUNT(DISTINCT m.id)
-		FROM message m
-		JOIN part p ON p.message_id = m.id
-		WHERE m.session_id = ?
-		  AND json_extract(m.data, '$.role') = 'user'
-		  AND json_extract(p.data, '$.type') = 'text'
-		  AND trim(COALESCE(json_extract(p.data, '$.text'), '')) <> ''
-	`
+		query := fmt.Sprintf(`
+			SELECT session_id,
+			       MAX(CASE WHEN rn = 1 THEN text END),
+			       COUNT(DISTINCT CASE WHEN trim(COALESCE(text, '')) <> '' THEN message_id END)
+			FROM (
+				SELECT m.session_id, m.id AS message_id, json_extract(p.data, '$.text') AS text,
+				       ROW_NUMBER() OVER (PARTITION BY m.session_id ORDER BY m.time_created ASC, p.time_created ASC) AS rn
+				FROM message m
+				JOIN part p ON p.message_id = m.id
+				WHERE m.session_id IN (%s)
+				  AND json_extract(m.data, '$.role') = 'user'
+				  AND json_extract(p.data, '$.type') = 'text'
+			)
+			GROUP BY session_id
+		`, placeholders)
 
-	var userCount int
-	if err := db.QueryRow(countQuery, sessionID).Scan(&userCount); err != nil {
-		return "", 0, fmt.Errorf("failed to count user messages: %w", err)
-	}
+		args := make([]interface{}, 0, len(chunk))
+		for _, id := range chunk {
+			args = append(args, id)
+		}
 
-	firstMessage := ""
-	if firstText.Valid {
-		firstMessage = o.extractFirstLine(firstText.String)
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query user messages: %w", err)
+		}
+		for rows.Next() {
+			var (
+				sessionID string
+				firstText sql.NullString
+				count     int
+			)
+			if err := rows.Scan(&sessionID, &firstText, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan user messages: %w", err)
+			}
+			stats := opencodeUserStats{count: count}
+			if firstText.Valid {
+				stats.firstMessage = extractFirstLine(firstText.String)
+			}
+			result[sessionID] = stats
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed while iterating user messages: %w", err)
+		}
+		rows.Close()
 	}
 
-	return firstMessage, userCount, nil
+	return result, nil
 }
 
 // listSessionsFromFiles lists sessions from legacy flat-file storage.
@@ -257,19 +381,18 @@ func (o *OpencodeAdapter) listSessionsFromFiles(projectPath string, limit int) (
 		return []Session{}, nil
 	}
 
-	// If project path specified, find matching project ID
-	var targetProjectID string
+	// If project path specified, find the matching project IDs
+	var targetProjectIDs map[string]bool
 	if projectPath != "" {
-		absPath, err := filepath.Abs(projectPath)
+		filter, err := NewProjectFilter(projectPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+			return nil, err
 		}
 
-		projectID, err := o.findProjectIDByPath(storageDir, absPath)
-		if err != nil || projectID == "" {
+		targetProjectIDs, err = o.findProjectIDsByPath(storageDir, filter)
+		if err != nil || len(targetProjectIDs) == 0 {
 			return []Session{}, nil // No matching project
 		}
-		targetProjectID = projectID
 	}
 
 	// List all sessions
@@ -288,7 +411,7 @@ func (o *OpencodeAdapter) listSessionsFromFiles(projectPath string, limit int) (
 		projectID := projectDir.Name()
 
 		// Filter by project if specified
-		if targetProjectID != "" && projectID != targetProjectID {
+		if targetProjectIDs != nil && !targetProjectIDs[projectID] {
 			continue
 		}
 
@@ -320,44 +443,36 @@ func (o *OpencodeAdapter) listSessionsFromFiles(projectPath string, limit int) (
 	return allSessions, nil
 }
 
-// findProjectIDByPath finds a project ID by matching the worktree path
-func (o *OpencodeAdapter) findProjectIDByPath(storageDir, targetPath string) (string, error) {
+// findProjectIDsByPath finds the IDs of projects whose worktree path is
+// matched by filter
+func (o *OpencodeAdapter) findProjectIDsByPath(storageDir string, filter *ProjectFilter) (map[string]bool, error) {
 	projectDir := filepath.Join(storageDir, "project")
 	files, err := filepath.Glob(filepath.Join(projectDir, "*.json"))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	projectIDs := make(map[string]bool)
 	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-
 		var project opencodeProject
-		if err := json.Unmarshal(data, &project); err != nil {
+		if err := decodeJSONFile(file, &project); err != nil {
 			continue
 		}
 
-		if project.Worktree == targetPath {
-			return project.ID, nil
+		if filter.Match(project.Worktree) {
+			projectIDs[project.ID] = true
 		}
 	}
 
-	return "", nil
+	return projectIDs, nil
 }
 
 // loadProject loads project metadata
 func (o *OpencodeAdapter) loadProject(storageDir, projectID string) (*opencodeProject, error) {
 	projectFile := filepath.Join(storageDir, "project", projectID+".json")
-	data, err := os.ReadFile(projectFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read project file: %w", err)
-	}
-
 	var project opencodeProject
-	if err := json.Unmarshal(data, &project); err != nil {
-		return nil, fmt.Errorf("failed to parse project JSON: %w", err)
+	if err := decodeJSONFile(projectFile, &project); err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
 	}
 
 	return &project, nil
@@ -373,13 +488,8 @@ func (o *OpencodeAdapter) listProjectSessions(storageDir, projectID, worktree st
 
 	var sessions []Session
 	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-
 		var sess opencodeSession
-		if err := json.Unmarshal(data, &sess); err != nil {
+		if err := decodeJSONFile(file, &sess); err != nil {
 			continue
 		}
 
@@ -422,13 +532,8 @@ func (o *OpencodeAdapter) getFirstUserMessageAndCount(storageDir, sessionID stri
 	userCount := 0
 
 	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-
 		var msg opencodeMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
+		if err := decodeJSONFile(file, &msg); err != nil {
 			continue
 		}
 
@@ -438,7 +543,7 @@ func (o *OpencodeAdapter) getFirstUserMessageAndCount(storageDir, sessionID stri
 			if content != "" {
 				userCount++
 				if firstMessage == "" {
-					firstMessage = o.extractFirstLine(content)
+					firstMessage = extractFirstLine(content)
 				}
 			}
 		}
@@ -510,21 +615,6 @@ func (o *OpencodeAdapter) getPartType(part map[string]interface{}) string {
 	return "unknown"
 }
 
-// extractFirstLine extracts the first non-empty line from text
-func (o *OpencodeAdapter) extractFirstLine(text string) string {
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			if len(trimmed) > 200 {
-				return trimmed[:200] + "..."
-			}
-			return trimmed
-		}
-	}
-	return ""
-}
-
 // GetSession retrieves the full content of an opencode session with pagination.
 func (o *OpencodeAdapter) GetSession(sessionID string, page, pageSize int) ([]Message, error) {
 	messages, _, _, _, err := o.GetSessionPage(sessionID, page, pageSize, false)
@@ -537,18 +627,14 @@ func (o *OpencodeAdapter) GetSession(sessionID string, page, pageSize int) ([]Me
 // GetSessionPage retrieves one page of session messages plus pagination metadata.
 // If fromEnd is true, page=0 means last page, page=1 means second-to-last, etc.
 func (o *OpencodeAdapter) GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
-	if page < 0 {
-		page = 0
-	}
-	if pageSize <= 0 {
-		pageSize = 20
-	}
+	page, pageSize = normalizePage(page, pageSize)
 
 	messages, totalMessages, resolvedPage, hasMore, err := o.getSessionPageFromSQLite(sessionID, page, pageSize, fromEnd)
 	if err == nil {
 		return messages, totalMessages, resolvedPage, hasMore, nil
 	}
 
+	warnOpencodeFallback(err)
 	fallbackMessages, fallbackTotal, fallbackResolved, fallbackHasMore, fallbackErr := o.getSessionPageFromFiles(sessionID, page, pageSize, fromEnd)
 	if fallbackErr == nil {
 		return fallbackMessages, fallbackTotal, fallbackResolved, fallbackHasMore, nil
@@ -558,18 +644,17 @@ func (o *OpencodeAdapter) GetSessionPage(sessionID string, page, pageSize int, f
 }
 
 func (o *OpencodeAdapter) getSessionPageFromSQLite(sessionID string, page, pageSize int, fromEnd bool) ([]Message, int, int, bool, error) {
-	db, err := o.openDB()
+	db, err := o.database()
 	if err != nil {
 		return nil, 0, page, false, err
 	}
-	defer db.Close()
 
 	exists, err := o.sqliteSessionExists(db, sessionID)
 	if err != nil {
 		return nil, 0, page, false, err
 	}
 	if !exists {
-		return nil, 0, page, false, fmt.Errorf("session not found: %s", sessionID)
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
 	}
 
 	totalMessages, err := o.countSessionMessagesFromSQLite(db, sessionID)
@@ -587,13 +672,7 @@ func (o *OpencodeAdapter) getSessionPageFromSQLite(sessionID string, page, pageS
 		return []Message{}, totalMessages, resolvedPage, false, nil
 	}
 
-	rows, err := db.Query(`
-		SELECT id, time_created, data
-		FROM message
-		WHERE session_id = ?
-		ORDER BY time_created ASC, id ASC
-		LIMIT ? OFFSET ?
-	`, sessionID, pageSize, offset)
+	rows, err := db.messagePage.Query(sessionID, pageSize, offset)
 	if err != nil {
 		return nil, 0, page, false, fmt.Errorf("failed to query sqlite message page: %w", err)
 	}
@@ -621,7 +700,7 @@ func (o *OpencodeAdapter) getSessionPageFromSQLite(sessionID string, page, pageS
 		return nil, 0, page, false, fmt.Errorf("failed while iterating sqlite message page: %w", err)
 	}
 
-	partsByMessageID, err := o.getMessagePartsByMessageID(db, messageIDs)
+	partsByMessageID, err := o.getMessagePartsByMessageID(db.DB, messageIDs)
 	if err != nil {
 		return nil, 0, page, false, err
 	}
@@ -677,6 +756,12 @@ func (o *OpencodeAdapter) getSessionPageFromSQLite(sessionID string, page, pageS
 		if msg.Mode != "" {
 			message.Metadata["mode"] = msg.Mode
 		}
+		if msg.Agent != "" {
+			message.Metadata["agent"] = msg.Agent
+		}
+		if duration := o.extractMessageDuration(msg.Time); duration > 0 {
+			message.Metadata["duration_ms"] = duration
+		}
 		if msg.Cost > 0 {
 			message.Metadata["cost"] = msg.Cost
 		}
@@ -691,9 +776,9 @@ func (o *OpencodeAdapter) getSessionPageFromSQLite(sessionID string, page, pageS
 	return messages, totalMessages, resolvedPage, hasMore, nil
 }
 
-func (o *OpencodeAdapter) sqliteSessionExists(db *sql.DB, sessionID string) (bool, error) {
+func (o *OpencodeAdapter) sqliteSessionExists(db *opencodeDB, sessionID string) (bool, error) {
 	var exists int
-	err := db.QueryRow("SELECT 1 FROM session WHERE id = ? LIMIT 1", sessionID).Scan(&exists)
+	err := db.sessionExists.QueryRow(sessionID).Scan(&exists)
 	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
@@ -703,13 +788,9 @@ func (o *OpencodeAdapter) sqliteSessionExists(db *sql.DB, sessionID string) (boo
 	return true, nil
 }
 
-func (o *OpencodeAdapter) countSessionMessagesFromSQLite(db *sql.DB, sessionID string) (int, error) {
+func (o *OpencodeAdapter) countSessionMessagesFromSQLite(db *opencodeDB, sessionID string) (int, error) {
 	var total int
-	if err := db.QueryRow(`
-		SELECT COUNT(*)
-		FROM message
-		WHERE session_id = ?
-	`, sessionID).Scan(&total); err != nil {
+	if err := db.countMessages.QueryRow(sessionID).Scan(&total); err != nil {
 		return 0, fmt.Errorf("failed to count sqlite session messages: %w", err)
 	}
 	return total, nil
@@ -793,31 +874,29 @@ func (o *OpencodeAdapter) getMessagePartsByMessageID(db *sql.DB, messageIDs []st
 	return result, nil
 }
 
-func resolvePage(page, pageSize, totalMessages int, fromEnd bool) int {
-	if !fromEnd {
-		return page
-	}
+func (o *OpencodeAdapter) extractMessageCreatedAt(raw map[string]interface{}) int64 {
+	return opencodeTimeField(raw, "created")
+}
 
-	if totalMessages == 0 {
+// extractMessageDuration returns how long an assistant message took to generate,
+// from time.created to time.completed, in milliseconds.
+func (o *OpencodeAdapter) extractMessageDuration(raw map[string]interface{}) int64 {
+	created := opencodeTimeField(raw, "created")
+	completed := opencodeTimeField(raw, "completed")
+	if created <= 0 || completed <= created {
 		return 0
 	}
-
-	lastPage := (totalMessages - 1) / pageSize
-	resolvedPage := lastPage - page
-	if resolvedPage < 0 {
-		return -1
-	}
-
-	return resolvedPage
+	return completed - created
 }
 
-func (o *OpencodeAdapter) extractMessageCreatedAt(raw map[string]interface{}) int64 {
-	created, ok := raw["created"]
+// opencodeTimeField reads a millisecond timestamp from a message's time object.
+func opencodeTimeField(raw map[string]interface{}, key string) int64 {
+	value, ok := raw[key]
 	if !ok {
 		return 0
 	}
 
-	switch v := created.(type) {
+	switch v := value.(type) {
 	case float64:
 		return int64(v)
 	case int64:
@@ -836,7 +915,7 @@ func (o *OpencodeAdapter) getSessionPageFromFiles(sessionID string, page, pageSi
 
 	// Check if message directory exists
 	if _, err := os.Stat(messageDir); os.IsNotExist(err) {
-		return nil, 0, page, false, fmt.Errorf("session not found: %s", sessionID)
+		return nil, 0, page, false, SessionNotFoundError(sessionID)
 	}
 
 	// Read all messages
@@ -844,27 +923,8 @@ func (o *OpencodeAdapter) getSessionPageFromFiles(sessionID string, page, pageSi
 	if err != nil {
 		return nil, 0, page, false, err
 	}
-	totalMessages := len(messages)
-
-	resolvedPage := resolvePage(page, pageSize, totalMessages, fromEnd)
-	if resolvedPage < 0 {
-		return []Message{}, totalMessages, resolvedPage, false, nil
-	}
-
-	// Apply pagination
-	start := resolvedPage * pageSize
-	if start >= len(messages) {
-		return []Message{}, totalMessages, resolvedPage, false, nil
-	}
-
-	end := start + pageSize
-	if end > len(messages) {
-		end = len(messages)
-	}
-
-	hasMore := end < totalMessages
-
-	return messages[start:end], totalMessages, resolvedPage, hasMore, nil
+	pageMessages, totalMessages, resolvedPage, hasMore := PaginateMessages(messages, page, pageSize, fromEnd)
+	return pageMessages, totalMessages, resolvedPage, hasMore, nil
 }
 
 // readAllMessages reads all messages from a session directory
@@ -879,13 +939,11 @@ func (o *OpencodeAdapter) readAllMessages(messageDir string) ([]Message, error)
 
 	var messages []Message
 	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
+		if messageLimitReached(len(messages)) {
+			break
 		}
-
 		var msg opencodeMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
+		if err := decodeJSONFile(file, &msg); err != nil {
 			continue
 		}
 
@@ -919,6 +977,12 @@ func (o *OpencodeAdapter) readAllMessages(messageDir string) ([]Message, error)
 		if msg.Mode != "" {
 			message.Metadata["mode"] = msg.Mode
 		}
+		if msg.Agent != "" {
+			message.Metadata["agent"] = msg.Agent
+		}
+		if duration := o.extractMessageDuration(msg.Time); duration > 0 {
+			message.Metadata["duration_ms"] = duration
+		}
 		if msg.Cost > 0 {
 			message.Metadata["cost"] = msg.Cost
 		}
@@ -939,6 +1003,7 @@ func (o *OpencodeAdapter) SearchSessions(projectPath, query string, limit int) (
 		return matches, nil
 	}
 
+	warnOpencodeFallback(err)
 	fallbackMatches, fallbackErr := o.searchSessionsFromFiles(projectPath, query, limit)
 	if fallbackErr == nil {
 		return fallbackMatches, nil
@@ -948,19 +1013,13 @@ func (o *OpencodeAdapter) SearchSessions(projectPath, query string, limit int) (
 }
 
 func (o *OpencodeAdapter) searchSessionsFromSQLite(projectPath, query string, limit int) ([]Session, error) {
-	db, err := o.openDB()
+	db, err := o.database()
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
-	var absPath string
-	if projectPath != "" {
-		resolvedPath, err := filepath.Abs(projectPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path: %w", err)
-		}
-		absPath = resolvedPath
+	if _, err := NewProjectFilter(projectPath); err != nil {
+		return nil, err
 	}
 
 	lowerLikeQuery := "%" + strings.ToLower(query) + "%"
@@ -982,9 +1041,9 @@ func (o *OpencodeAdapter) searchSessionsFromSQLite(projectPath, query string, li
 	`
 
 	args := []interface{}{lowerLikeQuery, lowerLikeQuery}
-	if absPath != "" {
-		sqlQuery += " AND p.worktree = ?"
-		args = append(args, absPath)
+	if projectPath != "" {
+		sqlQuery += " AND project_matches(?, p.worktree)"
+		args = append(args, projectPath)
 	}
 
 	sqlQuery += " ORDER BY s.time_created DESC"
@@ -997,43 +1056,11 @@ func (o *OpencodeAdapter) searchSessionsFromSQLite(projectPath, query string, li
 	if err != nil {
 		return nil, fmt.Errorf("failed to search sqlite sessions: %w", err)
 	}
-	defer rows.Close()
-
-	matches := make([]Session, 0)
-	for rows.Next() {
-		var (
-			sessionID string
-			title     string
-			createdAt int64
-			worktree  string
-		)
-
-		if err := rows.Scan(&sessionID, &title, &createdAt, &worktree); err != nil {
-			return nil, fmt.Errorf("failed to scan sqlite search result: %w", err)
-		}
-
-		firstMessage, userCount, firstErr := o.getFirstUserMessageAndCountFromSQLite(db, sessionID)
-		if firstErr != nil {
-			firstMessage = ""
-			userCount = 0
-		}
-
-		matches = append(matches, Session{
-			ID:               sessionID,
-			Source:           "opencode",
-			ProjectPath:      worktree,
-			FirstMessage:     firstMessage,
-			Summary:          title,
-			Timestamp:        time.UnixMilli(createdAt),
-			FilePath:         o.dbPath,
-			UserMessageCount: userCount,
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed while iterating sqlite search results: %w", err)
+	matches, err := o.scanSessionRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sqlite search results: %w", err)
 	}
-
+	o.addUserMessageStats(db.DB, matches)
 	return matches, nil
 }
 
@@ -1079,3 +1106,36 @@ func (o *OpencodeAdapter) searchSessionsFromFiles(projectPath, query string, lim
 
 	return matches, nil
 }
+
+// DetectPersonas returns the agents an opencode session ran as, from the agent
+// (or, in older versions, mode) recorded on each message, and the project's
+// AGENTS.md.
+func (o *OpencodeAdapter) DetectPersonas(sessionID string) ([]Persona, error) {
+	messages, err := o.GetSession(sessionID, 0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracker personaTracker
+	for i, msg := range messages {
+		agent, _ := msg.Metadata["agent"].(string)
+		if agent == "" {
+			agent, _ = msg.Metadata["mode"].(string)
+		}
+		if agent != "" {
+			tracker.activate(Persona{Kind: PersonaAgent, Name: agent}, i)
+		}
+		tracker.message()
+	}
+
+	personas := append([]Persona{}, tracker.personas...)
+	if sessions, err := o.ListSessions("", 0); err == nil {
+		for _, session := range sessions {
+			if session.ID == sessionID {
+				personas = append(personas, projectInstructions(session.ProjectPath, "AGENTS.md")...)
+				break
+			}
+		}
+	}
+	return personas, nil
+}
@@ -102,7 +102,7 @@ func TestOpencodeAdapterSQLiteSessions(t *testing.T) {
 		INSERT INTO message (id, session_id, time_created, time_updated, data)
 		VALUES
 			('msg_user', 'ses_one', 2010, 2010, '{"role":"user","time":{"created":2010}}'),
-			('msg_assistant', 'ses_one', 2020, 2025, '{"role":"assistant","time":{"created":2020},"modelID":"gpt-5.3-codex","mode":"codex-5.3","tokens":{"input":10,"output":20}}'),
+			('msg_assistant', 'ses_one', 2020, 2025, '{"role":"assistant","time":{"created":2020,"completed":2520},"modelID":"gpt-5.3-codex","mode":"codex-5.3","agent":"build","tokens":{"input":10,"output":20}}'),
 			('msg_user_2', 'ses_two', 3010, 3010, '{"role":"user","time":{"created":3010}}');
 	`); err != nil {
 		t.Fatalf("failed to insert messages: %v", err)
@@ -136,6 +136,9 @@ func TestOpencodeAdapterSQLiteSessions(t *testing.T) {
 	if sessions[0].ID != "ses_two" {
 		t.Fatalf("expected latest session first, got %q", sessions[0].ID)
 	}
+	if sessions[0].FirstMessage != "Another session" || sessions[1].FirstMessage != "How do I fix this?" {
+		t.Fatalf("expected each session's own first message, got %q and %q", sessions[0].FirstMessage, sessions[1].FirstMessage)
+	}
 
 	filtered, err := adapter.ListSessions(projectOne, 10)
 	if err != nil {
@@ -193,6 +196,9 @@ func TestOpencodeAdapterSQLiteSessions(t *testing.T) {
 	if messages[1].Metadata["model"] != "gpt-5.3-codex" {
 		t.Fatalf("expected assistant model metadata, got %#v", messages[1].Metadata["model"])
 	}
+	if messages[1].Metadata["agent"] != "build" || messages[1].Metadata["duration_ms"] != int64(500) {
+		t.Fatalf("expected agent and duration metadata, got %#v", messages[1].Metadata)
+	}
 
 	pageOne, totalMessages, resolvedPage, hasMore, err := adapter.GetSessionPage("ses_one", 0, 1, false)
 	if err != nil {
@@ -236,4 +242,17 @@ func TestOpencodeAdapterSQLiteSessions(t *testing.T) {
 	if len(results) != 1 || results[0].ID != "ses_one" {
 		t.Fatalf("expected one search hit for ses_one, got %#v", results)
 	}
+	if results[0].FirstMessage != "How do I fix this?" || results[0].UserMessageCount != 1 {
+		t.Fatalf("expected search hits to include user message stats, got %#v", results[0])
+	}
+
+	// Every call shares one pool, with the busy timeout on each connection
+	pool := adapter.db
+	if _, err := adapter.ListSessions("", 10); err != nil || adapter.db != pool {
+		t.Fatalf("expected the database pool to be reused (%v)", err)
+	}
+	var timeout int
+	if err := pool.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != 5000 {
+		t.Fatalf("expected busy_timeout 5000, got %d (%v)", timeout, err)
+	}
 }
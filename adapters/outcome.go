@@ -0,0 +1,176 @@
+package adapters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Session outcomes, as guessed by ClassifyOutcome.
+const (
+	OutcomeCompleted = "completed"
+	OutcomeAbandoned = "abandoned"
+	OutcomeFailed    = "failed"
+)
+
+// OutcomeCaveat explains how far a classified outcome can be trusted. Tools
+// that return outcomes include it alongside them.
+const OutcomeCaveat = "Outcomes are guessed from heuristics: the last test run, phrases in the final assistant message, the tone of the user's last prompt, and whether the session ends without a reply. They miss work verified outside the session and misread sarcasm or terse replies, so treat low-confidence outcomes as hints and check the session before relying on one."
+
+// ValidateOutcome returns an error if outcome isn't one of the outcomes
+// ClassifyOutcome returns.
+func ValidateOutcome(outcome string) error {
+	switch outcome {
+	case OutcomeCompleted, OutcomeAbandoned, OutcomeFailed:
+		return nil
+	}
+	return fmt.Errorf("unknown outcome %q (expected %s, %s, or %s)", outcome, OutcomeCompleted, OutcomeAbandoned, OutcomeFailed)
+}
+
+// SessionOutcome is how a session probably ended, and why.
+type SessionOutcome struct {
+	Outcome    string   `json:"outcome"`
+	Confidence string   `json:"confidence"` // low, medium, or high
+	Signals    []string `json:"signals"`
+}
+
+// testCommand matches command lines that run a test suite.
+var testCommand = regexp.MustCompile(`(?i)\b(go test|pytest|(npm|yarn|pnpm|bun)( run)? test|cargo test|jest|vitest|mvn test|gradle\w* test|make test|rspec|phpunit|dotnet test|mix test|tox)\b`)
+
+// Phrases that suggest how the work went, matched case-insensitively.
+var (
+	failurePhrases = []string{
+		"i wasn't able to", "i was unable to", "i couldn't", "i could not",
+		"i'm unable to", "still failing", "still fails", "tests are failing",
+		"the error persists", "i'm stuck", "not able to fix",
+	}
+	completionPhrases = []string{
+		"all tests pass", "tests pass", "tests are passing", "successfully",
+		"is now working", "now works", "has been implemented", "i've implemented",
+		"i have implemented", "is complete", "are complete",
+	}
+	negativePrompts = []string{
+		"doesn't work", "does not work", "not working", "still broken",
+		"still failing", "that's wrong", "this is wrong", "never mind",
+		"nevermind", "forget it", "give up",
+	}
+	positivePrompts = []string{
+		"thanks", "thank you", "perfect", "great", "looks good", "lgtm",
+		"works now", "that works", "awesome", "nice",
+	}
+)
+
+// containsAny returns the first of phrases found in text, ignoring case.
+func containsAny(text string, phrases []string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, phrase := range phrases {
+		if strings.Contains(lower, phrase) {
+			return phrase, true
+		}
+	}
+	return "", false
+}
+
+// ClassifyOutcome guesses whether a session's work was completed, abandoned,
+// or failed. Each signal adds weight to one outcome:
+//   - the session ends on a user prompt, or an interruption, with no reply (abandoned)
+//   - the last test command failed or passed (failed or completed)
+//   - the final assistant message admits failure or reports success
+//   - the user's last prompt is negative or positive
+//
+// The outcome with the most weight wins, failed before abandoned before
+// completed on a tie. A session that ends with an assistant reply and gives no
+// other signal is taken as completed, with low confidence. See OutcomeCaveat.
+func ClassifyOutcome(messages []Message) SessionOutcome {
+	scores := map[string]int{}
+	var signals []string
+	add := func(outcome string, weight int, signal string) {
+		scores[outcome] += weight
+		signals = append(signals, signal)
+	}
+
+	var lastPrompt, finalReply string
+	lastPromptIndex, finalReplyIndex, interruptedIndex := -1, -1, -1
+	for i, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			continue
+		}
+		switch {
+		case msg.Role == "user" && strings.HasPrefix(content, "[Request interrupted"):
+			interruptedIndex = i
+		case msg.Role == "user" && len(ExtractToolResults(msg)) == 0:
+			lastPrompt, lastPromptIndex = content, i
+		case msg.Role == "assistant":
+			finalReply, finalReplyIndex = content, i
+		}
+	}
+
+	if lastPromptIndex < 0 && finalReplyIndex < 0 {
+		return SessionOutcome{Outcome: OutcomeAbandoned, Confidence: "low", Signals: []string{"session has no prompts or replies"}}
+	}
+	if interruptedIndex > finalReplyIndex {
+		add(OutcomeAbandoned, 2, "the user interrupted the last response")
+	} else if lastPromptIndex > finalReplyIndex {
+		add(OutcomeAbandoned, 2, "session ends on a user prompt with no reply")
+	}
+
+	var lastTest *ToolInvocation
+	invocations := ExtractToolInvocations(messages)
+	for i := range invocations {
+		if command, ok := ShellCommand(invocations[i].ToolCall); ok && testCommand.MatchString(command) && invocations[i].Success != nil {
+			lastTest = &invocations[i]
+		}
+	}
+	// Signals name the test run by message index rather than quoting the
+	// command, so outcomes in list results carry no session content
+	if lastTest != nil {
+		if *lastTest.Success {
+			add(OutcomeCompleted, 2, fmt.Sprintf("last test run passed (message %d)", lastTest.MessageIndex))
+		} else {
+			add(OutcomeFailed, 2, fmt.Sprintf("last test run failed (message %d)", lastTest.MessageIndex))
+		}
+	}
+
+	if finalReply != "" {
+		if phrase, ok := containsAny(finalReply, failurePhrases); ok {
+			add(OutcomeFailed, 1, fmt.Sprintf("final assistant message says %q", phrase))
+		} else if phrase, ok := containsAny(finalReply, completionPhrases); ok {
+			add(OutcomeCompleted, 1, fmt.Sprintf("final assistant message says %q", phrase))
+		}
+	}
+
+	if lastPrompt != "" {
+		if phrase, ok := containsAny(lastPrompt, negativePrompts); ok {
+			add(OutcomeFailed, 1, fmt.Sprintf("user's last prompt says %q", phrase))
+		} else if phrase, ok := containsAny(lastPrompt, positivePrompts); ok {
+			add(OutcomeCompleted, 1, fmt.Sprintf("user's last prompt says %q", phrase))
+		}
+	}
+
+	if len(signals) == 0 {
+		return SessionOutcome{Outcome: OutcomeCompleted, Confidence: "low", Signals: []string{"session ends with an assistant reply and gives no other signal"}}
+	}
+
+	best, runnerUp := "", 0
+	for _, outcome := range []string{OutcomeFailed, OutcomeAbandoned, OutcomeCompleted} {
+		switch score := scores[outcome]; {
+		case best == "" || score > scores[best]:
+			if best != "" {
+				runnerUp = scores[best]
+			}
+			best = outcome
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+
+	confidence := "low"
+	switch margin := scores[best] - runnerUp; {
+	case scores[best] >= 3 && margin >= 2:
+		confidence = "high"
+	case scores[best] >= 2 && margin >= 1:
+		confidence = "medium"
+	}
+	return SessionOutcome{Outcome: best, Confidence: confidence, Signals: signals}
+}
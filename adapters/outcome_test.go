@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"testing"
+)
+
+func TestClassifyOutcome(t *testing.T) {
+	testRun := func(id, command string) Message {
+		return Message{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": id, "name": "Bash", "input": map[string]interface{}{"command": command}},
+			},
+		}}
+	}
+	testResult := func(id string, failed bool) Message {
+		return Message{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": "output", "is_error": failed},
+			},
+		}}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		messages   []Message
+		outcome    string
+		confidence string
+	}{
+		{
+			name: "tests pass and the user is happy",
+			messages: []Message{
+				{Role: "user", Content: "Fix the flaky test"},
+				testRun("t1", "go test ./..."), testResult("t1", false),
+				{Role: "assistant", Content: "All tests pass now."},
+				{Role: "user", Content: "Perfect, thanks!"},
+				{Role: "assistant", Content: "Glad to help."},
+			},
+			outcome:    OutcomeCompleted,
+			confidence: "high",
+		},
+		{
+			name: "tests still fail",
+			messages: []Message{
+				{Role: "user", Content: "Fix the build"},
+				testRun("t1", "npm test"), testResult("t1", true),
+				{Role: "assistant", Content: "I wasn't able to get the last test to pass."},
+			},
+			outcome:    OutcomeFailed,
+			confidence: "high",
+		},
+		{
+			name: "last prompt never answered",
+			messages: []Message{
+				{Role: "user", Content: "Add a login page"},
+				{Role: "assistant", Content: "Here's a plan."},
+				{Role: "user", Content: "Go ahead"},
+			},
+			outcome:    OutcomeAbandoned,
+			confidence: "medium",
+		},
+		{
+			name: "interrupted",
+			messages: []Message{
+				{Role: "user", Content: "Refactor the parser"},
+				{Role: "assistant", Content: "Starting with the lexer."},
+				{Role: "user", Content: "[Request interrupted by user for tool use]"},
+			},
+			outcome:    OutcomeAbandoned,
+			confidence: "medium",
+		},
+		{
+			name: "no signals",
+			messages: []Message{
+				{Role: "user", Content: "What does this function do?"},
+				{Role: "assistant", Content: "It parses the config file."},
+			},
+			outcome:    OutcomeCompleted,
+			confidence: "low",
+		},
+		{
+			name: "user says it doesn't work",
+			messages: []Message{
+				{Role: "user", Content: "It still doesn't work"},
+				{Role: "assistant", Content: "Let me look again."},
+			},
+			outcome:    OutcomeFailed,
+			confidence: "low",
+		},
+		{
+			name:       "empty",
+			messages:   nil,
+			outcome:    OutcomeAbandoned,
+			confidence: "low",
+		},
+	} {
+		got := ClassifyOutcome(tc.messages)
+		if got.Outcome != tc.outcome || got.Confidence != tc.confidence || len(got.Signals) == 0 {
+			t.Errorf("%s: expected %s (%s confidence), got %+v", tc.name, tc.outcome, tc.confidence, got)
+		}
+	}
+
+	if err := ValidateOutcome("failed"); err != nil {
+		t.Errorf("expected failed to be valid, got %v", err)
+	}
+	if err := ValidateOutcome("succeeded"); err == nil {
+		t.Error("expected an error for an unknown outcome")
+	}
+}
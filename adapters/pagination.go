@@ -0,0 +1,55 @@
+package adapters
+
+// resolvePage turns a page counted from the end of a session into one counted
+// from the start. It returns -1 for a page before the first.
+func resolvePage(page, pageSize, totalMessages int, fromEnd bool) int {
+	if !fromEnd {
+		return page
+	}
+
+	if totalMessages == 0 {
+		return 0
+	}
+
+	lastPage := (totalMessages - 1) / pageSize
+	resolvedPage := lastPage - page
+	if resolvedPage < 0 {
+		return -1
+	}
+
+	return resolvedPage
+}
+
+// PaginateMessages returns one page of a session's messages along with the
+// pagination metadata GetSessionPage reports: the total number of messages,
+// the page counted from the start, and whether later pages follow.
+func PaginateMessages(messages []Message, page, pageSize int, fromEnd bool) ([]Message, int, int, bool) {
+	totalMessages := len(messages)
+	resolvedPage := resolvePage(page, pageSize, totalMessages, fromEnd)
+	if resolvedPage < 0 {
+		return []Message{}, totalMessages, resolvedPage, false
+	}
+
+	start := resolvedPage * pageSize
+	if start >= totalMessages {
+		return []Message{}, totalMessages, resolvedPage, false
+	}
+
+	end := start + pageSize
+	if end > totalMessages {
+		end = totalMessages
+	}
+
+	return messages[start:end], totalMessages, resolvedPage, end < totalMessages
+}
+
+// normalizePage applies GetSessionPage's defaults to a requested page.
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 0 {
+		page = 0
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
@@ -0,0 +1,47 @@
+package adapters
+
+import (
+	"os"
+	"time"
+)
+
+// PathCache persists which file holds each session, for adapters whose files
+// aren't named after the session ID, so finding a session doesn't mean
+// reading every session file.
+type PathCache interface {
+	// LookupSessionPath returns the file recorded for a session, and the
+	// file's modification time when it was recorded.
+	LookupSessionPath(source, sessionID string) (path string, modTime time.Time, ok bool)
+	// StoreSessionPath records the file that holds a session.
+	StoreSessionPath(source, sessionID, path string, modTime time.Time)
+}
+
+// findSessionPath returns the file holding a session, or "" if there is none.
+// A recorded path is used while the file's modification time is unchanged;
+// once it changes, holds must confirm the file still holds the session.
+// Otherwise find searches for it, and what it finds is recorded. A nil cache
+// always searches.
+func findSessionPath(cache PathCache, source, sessionID string, holds func(path string) bool, find func() string) string {
+	if cache == nil {
+		return find()
+	}
+	if path, modTime, ok := cache.LookupSessionPath(source, sessionID); ok {
+		if info, err := os.Stat(path); err == nil {
+			if info.ModTime().Equal(modTime) {
+				return path
+			}
+			if holds(path) {
+				cache.StoreSessionPath(source, sessionID, path, info.ModTime())
+				return path
+			}
+		}
+	}
+
+	path := find()
+	if path != "" {
+		if info, err := os.Stat(path); err == nil {
+			cache.StoreSessionPath(source, sessionID, path, info.ModTime())
+		}
+	}
+	return path
+}
@@ -0,0 +1,98 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mapPathCache is a PathCache kept in memory.
+type mapPathCache map[string]pathEntry
+
+type pathEntry struct {
+	path    string
+	modTime time.Time
+}
+
+func (m mapPathCache) LookupSessionPath(source, sessionID string) (string, time.Time, bool) {
+	entry, ok := m[source+"/"+sessionID]
+	return entry.path, entry.modTime, ok
+}
+
+func (m mapPathCache) StoreSessionPath(source, sessionID, path string, modTime time.Time) {
+	m[source+"/"+sessionID] = pathEntry{path: path, modTime: modTime}
+}
+
+func TestFindSessionPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.json")
+	if err := os.WriteFile(file, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cache := mapPathCache{}
+	var finds, checks int
+	holdsSession := true
+	holds := func(string) bool { checks++; return holdsSession }
+	find := func() string { finds++; return file }
+
+	for i := 0; i < 2; i++ {
+		if got := findSessionPath(cache, "mistral", "s1", holds, find); got != file {
+			t.Fatalf("findSessionPath = %q, want %q", got, file)
+		}
+	}
+	if finds != 1 || checks != 0 {
+		t.Fatalf("expected one search and no checks, got %d searches and %d checks", finds, checks)
+	}
+
+	// A modified file is checked rather than searched for
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	findSessionPath(cache, "mistral", "s1", holds, find)
+	if finds != 1 || checks != 1 {
+		t.Fatalf("expected the changed file to be checked, got %d searches and %d checks", finds, checks)
+	}
+
+	// A file that no longer holds the session is searched for again
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	holdsSession = false
+	findSessionPath(cache, "mistral", "s1", holds, find)
+	if finds != 2 {
+		t.Fatalf("expected a search after the check failed, got %d searches", finds)
+	}
+}
+
+func TestGetSessionRecordsSessionPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := FixtureOptions{Sessions: 2, Turns: 1, Projects: 1, Seed: 5, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	for _, source := range []string{"codex", "gemini", "mistral"} {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s) returned error: %v", source, err)
+		}
+		adaptersMap, _ := NewRegistered()
+		adapter := adaptersMap[source]
+		sessions, err := adapter.ListSessions("", 0)
+		if err != nil || len(sessions) == 0 {
+			t.Fatalf("%s ListSessions returned %d sessions (%v)", source, len(sessions), err)
+		}
+
+		cache := mapPathCache{}
+		adapter.(interface{ SetPathCache(PathCache) }).SetPathCache(cache)
+		for i := 0; i < 2; i++ {
+			messages, err := adapter.GetSession(sessions[0].ID, 0, 100)
+			if err != nil || len(messages) == 0 {
+				t.Fatalf("%s GetSession returned %d messages (%v)", source, len(messages), err)
+			}
+		}
+		path, _, ok := cache.LookupSessionPath(source, sessions[0].ID)
+		if !ok || path != sessions[0].FilePath {
+			t.Fatalf("%s: expected %s to be recorded, got %q (%v)", source, sessions[0].FilePath, path, ok)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Persona kinds, as returned by DetectPersonas.
+const (
+	PersonaOutputStyle  = "output_style" // a Claude Code output style
+	PersonaAgent        = "agent"        // an opencode agent, or mode in older versions
+	PersonaInstructions = "instructions" // custom instructions such as AGENTS.md or CLAUDE.md
+)
+
+// Where a persona was found.
+const (
+	EvidenceTranscript = "transcript" // the session records it
+	EvidenceSettings   = "settings"   // the agent's settings name it now
+	EvidenceProject    = "project"    // the project holds it now
+)
+
+// PersonaCaveat explains how far detected personas can be trusted. Tools that
+// return personas include it alongside them.
+const PersonaCaveat = "Personas with transcript evidence were recorded during the session. Those found in settings or the project reflect the configuration as it is now, which may have changed since the session ran; agents also read instructions from places not checked here, such as parent directories and user-level files."
+
+// Persona is configuration that shaped how the agent behaved in a session: an
+// output style, an agent, or custom instructions.
+type Persona struct {
+	Kind string `json:"kind"`
+	// Name identifies the style or agent, or the instructions file
+	Name     string `json:"name"`
+	Evidence string `json:"evidence"`
+	// Excerpt is the first line of custom instructions
+	Excerpt string `json:"excerpt,omitempty"`
+	// FirstMessage and Messages are the message index a transcript persona
+	// took effect at and how many messages it was active for
+	FirstMessage int `json:"first_message"`
+	Messages     int `json:"messages,omitempty"`
+}
+
+// personaTracker follows which persona of each kind is active as a session's
+// messages are read, so each is reported with the messages it covered.
+type personaTracker struct {
+	personas []Persona
+	active   map[string]int // kind -> index in personas
+}
+
+// activate makes p the active persona of its kind from message index on. A
+// persona that is already active, by name in any case, carries on.
+func (t *personaTracker) activate(p Persona, index int) {
+	if t.active == nil {
+		t.active = make(map[string]int)
+	}
+	if i, ok := t.active[p.Kind]; ok && strings.EqualFold(t.personas[i].Name, p.Name) {
+		return
+	}
+	p.Evidence = EvidenceTranscript
+	p.FirstMessage = index
+	t.personas = append(t.personas, p)
+	t.active[p.Kind] = len(t.personas) - 1
+}
+
+// message counts one message for every active persona.
+func (t *personaTracker) message() {
+	for _, i := range t.active {
+		t.personas[i].Messages++
+	}
+}
+
+// projectInstructions returns a persona for each custom instructions file in
+// the project matching one of patterns, which are globs relative to the
+// project directory.
+func projectInstructions(projectPath string, patterns ...string) []Persona {
+	if projectPath == "" {
+		return nil
+	}
+	var personas []Persona
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(projectPath, pattern))
+		sort.Strings(matches)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			name, _ := filepath.Rel(projectPath, path)
+			personas = append(personas, Persona{
+				Kind:     PersonaInstructions,
+				Name:     filepath.ToSlash(name),
+				Evidence: EvidenceProject,
+				Excerpt:  firstFileLine(path),
+			})
+		}
+	}
+	return personas
+}
+
+// firstFileLine returns the first non-empty line of a text file, without
+// Markdown heading markers.
+func firstFileLine(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := instructionsExcerpt(scanner.Text()); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// instructionsExcerpt returns the first non-empty line of instructions text,
+// without Markdown heading markers, truncated to a readable length.
+func instructionsExcerpt(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line != "" {
+			return TruncateText(line, DefaultPreviewLength)
+		}
+	}
+	return ""
+}
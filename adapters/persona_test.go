@@ -0,0 +1,100 @@
+package adapters
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestClaudeDetectPersonas(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	writeClaudeFile(t, filepath.Join(project, "CLAUDE.md"), "# Billing API", "Run go test before committing.")
+	writeClaudeFile(t, filepath.Join(project, ".claude", "settings.json"), `{"outputStyle": "Learning"}`)
+
+	cmd, _ := json.Marshal("<command-name>/output-style</command-name>\n<command-message>output-style</command-message>\n<command-args>explanatory</command-args>")
+	out, _ := json.Marshal("<local-command-stdout>Set output style to \x1b[1mExplanatory\x1b[22m</local-command-stdout>")
+	writeClaudeFile(t, filepath.Join(home, ".claude", "projects", "-billing", "s1.jsonl"),
+		`{"type":"user","cwd":"`+project+`","sessionId":"s1","message":{"role":"user","content":"Fix the invoice bug"}}`,
+		`{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":"Fixed"}}`,
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":`+string(cmd)+`}}`,
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":`+string(out)+`}}`,
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":"Why did that work?"}}`,
+		`{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":"Because"}}`)
+
+	adapter := &ClaudeAdapter{homeDir: home}
+	personas, err := adapter.DetectPersonas("s1")
+	if err != nil {
+		t.Fatalf("DetectPersonas returned error: %v", err)
+	}
+	if len(personas) != 3 {
+		t.Fatalf("expected a transcript style, a settings style, and CLAUDE.md, got %+v", personas)
+	}
+	if p := personas[0]; p.Kind != PersonaOutputStyle || p.Name != "explanatory" || p.Evidence != EvidenceTranscript || p.FirstMessage != 2 || p.Messages != 4 {
+		t.Fatalf("unexpected transcript persona: %+v", p)
+	}
+	if p := personas[1]; p.Name != "Learning" || p.Evidence != EvidenceSettings {
+		t.Fatalf("unexpected settings persona: %+v", p)
+	}
+	if p := personas[2]; p.Kind != PersonaInstructions || p.Name != "CLAUDE.md" || p.Excerpt != "Billing API" || p.Evidence != EvidenceProject {
+		t.Fatalf("unexpected instructions persona: %+v", p)
+	}
+}
+
+func TestClaudeOutputStyle(t *testing.T) {
+	cases := map[string]string{
+		"<local-command-stdout>Set output style to **Explanatory**</local-command-stdout>": "Explanatory",
+		"<command-name>/output-style</command-name><command-args>Learning</command-args>":  "Learning",
+		"<command-name>/output-style</command-name><command-args></command-args>":          "",
+		"What output style should I use?":                                                  "",
+	}
+	for content, want := range cases {
+		if got := claudeOutputStyle(content); got != want {
+			t.Errorf("claudeOutputStyle(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestCodexDetectPersonas(t *testing.T) {
+	home := t.TempDir()
+	writeClaudeFile(t, filepath.Join(home, ".codex", "sessions", "2025", "06", "01", "rollout-2025-06-01T10-00-00-c1.jsonl"),
+		`{"timestamp":"2025-06-01T10:00:00Z","type":"session_meta","payload":{"id":"c1","cwd":"/work/app","timestamp":"2025-06-01T10:00:00Z"}}`,
+		`{"timestamp":"2025-06-01T10:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<user_instructions>\n\n# Repository Guidelines\nUse tabs.\n\n</user_instructions>"}]}}`,
+		`{"timestamp":"2025-06-01T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Add a flag"}]}}`,
+		`{"timestamp":"2025-06-01T10:00:02Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Added"}]}}`)
+
+	adapter := &CodexAdapter{homeDir: home}
+	personas, err := adapter.DetectPersonas("c1")
+	if err != nil {
+		t.Fatalf("DetectPersonas returned error: %v", err)
+	}
+	if len(personas) != 1 {
+		t.Fatalf("expected the session's instructions, got %+v", personas)
+	}
+	if p := personas[0]; p.Kind != PersonaInstructions || p.Excerpt != "Repository Guidelines" || p.Evidence != EvidenceTranscript || p.Messages != 2 {
+		t.Fatalf("unexpected persona: %+v", p)
+	}
+}
+
+func TestPersonaTracker(t *testing.T) {
+	var tracker personaTracker
+	for i, agent := range []string{"", "build", "build", "plan", "build"} {
+		if agent != "" {
+			tracker.activate(Persona{Kind: PersonaAgent, Name: agent}, i)
+		}
+		tracker.message()
+	}
+	want := []Persona{
+		{Kind: PersonaAgent, Name: "build", Evidence: EvidenceTranscript, FirstMessage: 1, Messages: 2},
+		{Kind: PersonaAgent, Name: "plan", Evidence: EvidenceTranscript, FirstMessage: 3, Messages: 1},
+		{Kind: PersonaAgent, Name: "build", Evidence: EvidenceTranscript, FirstMessage: 4, Messages: 1},
+	}
+	if len(tracker.personas) != len(want) {
+		t.Fatalf("expected %d personas, got %+v", len(want), tracker.personas)
+	}
+	for i := range want {
+		if tracker.personas[i] != want[i] {
+			t.Fatalf("persona %d = %+v, want %+v", i, tracker.personas[i], want[i])
+		}
+	}
+}
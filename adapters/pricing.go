@@ -0,0 +1,265 @@
+package adapters
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelPrice is a model's list price in USD per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// Cost returns the estimated cost of usage at this price.
+func (p ModelPrice) Cost(u TokenUsage) float64 {
+	return (float64(u.Input)*p.Input +
+		float64(u.Output+u.Reasoning)*p.Output +
+		float64(u.CacheWrite)*p.CacheWrite +
+		float64(u.CacheRead)*p.CacheRead) / 1e6
+}
+
+// modelPrices maps model name fragments to list prices. The first fragment
+// contained in a model name wins, so more specific fragments come first.
+var modelPrices = []struct {
+	fragment string
+	price    ModelPrice
+}{
+	{"opus-4-5", ModelPrice{Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50}},
+	{"opus", ModelPrice{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50}},
+	{"sonnet", ModelPrice{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}},
+	{"haiku-4", ModelPrice{Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10}},
+	{"3-5-haiku", ModelPrice{Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08}},
+	{"haiku", ModelPrice{Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03}},
+}
+
+// PriceForModel returns the list price for a model, if known.
+func PriceForModel(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	for _, entry := range modelPrices {
+		if strings.Contains(model, entry.fragment) {
+			return entry.price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// CostReport summarizes token usage and cost over a set of sessions.
+type CostReport struct {
+	SessionCount int        `json:"session_count"`
+	Tokens       TokenUsage `json:"tokens"`
+	CostUSD      float64    `json:"cost_usd"`
+	// CacheHitRatio is the share of input tokens served from the prompt cache
+	CacheHitRatio  float64            `json:"cache_hit_ratio"`
+	CostByModel    map[string]float64 `json:"cost_by_model"`
+	UnpricedModels []string           `json:"unpriced_models,omitempty"`
+}
+
+// CostTracker accumulates a CostReport over any number of sessions.
+type CostTracker struct {
+	report   CostReport
+	unpriced map[string]bool
+}
+
+// NewCostTracker returns an empty tracker.
+func NewCostTracker() *CostTracker {
+	return &CostTracker{
+		report:   CostReport{CostByModel: make(map[string]float64)},
+		unpriced: make(map[string]bool),
+	}
+}
+
+// CostEntry is the token usage and cost of one model response.
+type CostEntry struct {
+	Timestamp time.Time
+	Model     string
+	Tokens    TokenUsage
+	CostUSD   float64
+	// Unpriced is set when the cost wasn't recorded and the model's price is unknown
+	Unpriced bool
+}
+
+// CostEntries returns the usage and cost of each model response in a session.
+// Recorded costs (opencode, older Claude Code) are used as-is; otherwise cost is
+// estimated from token usage at list price.
+func CostEntries(messages []Message) []CostEntry {
+	var entries []CostEntry
+	counted := make(map[string]bool)
+
+	for _, msg := range messages {
+		usage, hasUsage := ExtractTokenUsage(msg)
+		recorded, hasCost := msg.Metadata["cost"].(float64)
+		if !hasUsage && !hasCost {
+			continue
+		}
+
+		// Claude repeats one response's usage on every line of that response
+		if id, _ := msg.Metadata["message_id"].(string); id != "" {
+			if counted[id] {
+				continue
+			}
+			counted[id] = true
+		}
+
+		entry := CostEntry{Timestamp: msg.Timestamp, Tokens: usage, CostUSD: recorded}
+		entry.Model, _ = msg.Metadata["model"].(string)
+		if !hasCost {
+			if price, ok := PriceForModel(entry.Model); ok {
+				entry.CostUSD = price.Cost(usage)
+			} else {
+				entry.Unpriced = true
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// AddSession adds one session's messages.
+func (t *CostTracker) AddSession(messages []Message) {
+	t.AddEntries(CostEntries(messages))
+}
+
+// AddEntries adds one session's cost entries, such as those within a date range.
+func (t *CostTracker) AddEntries(entries []CostEntry) {
+	t.report.SessionCount++
+	for _, entry := range entries {
+		if entry.Unpriced && entry.Model != "" {
+			t.unpriced[entry.Model] = true
+		}
+		t.report.Tokens.add(entry.Tokens)
+		t.report.CostUSD += entry.CostUSD
+		if entry.Model != "" {
+			t.report.CostByModel[entry.Model] += entry.CostUSD
+		}
+	}
+}
+
+// Report returns the accumulated report.
+func (t *CostTracker) Report() CostReport {
+	report := t.report
+	report.CostByModel = make(map[string]float64, len(t.report.CostByModel))
+	for model, cost := range t.report.CostByModel {
+		report.CostByModel[model] = cost
+	}
+
+	inputs := report.Tokens.Input + report.Tokens.CacheRead + report.Tokens.CacheWrite
+	if inputs > 0 {
+		report.CacheHitRatio = float64(report.Tokens.CacheRead) / float64(inputs)
+	}
+
+	report.UnpricedModels = nil
+	for model := range t.unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	sort.Strings(report.UnpricedModels)
+	return report
+}
+
+// ModelUsage aggregates the responses one model produced across sessions.
+type ModelUsage struct {
+	Model         string     `json:"model"`
+	Sources       []string   `json:"sources"`
+	SessionCount  int        `json:"session_count"`
+	ResponseCount int        `json:"response_count"`
+	Tokens        TokenUsage `json:"tokens"`
+	CostUSD       float64    `json:"cost_usd"`
+	// Unpriced is set when some cost wasn't recorded and the model's price is unknown
+	Unpriced  bool       `json:"unpriced,omitempty"`
+	FirstUsed *time.Time `json:"first_used,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// ModelUsageTracker accumulates ModelUsage over any number of sessions.
+type ModelUsageTracker struct {
+	byModel map[string]*ModelUsage
+	sources map[string]map[string]bool
+}
+
+// NewModelUsageTracker returns an empty tracker.
+func NewModelUsageTracker() *ModelUsageTracker {
+	return &ModelUsageTracker{byModel: make(map[string]*ModelUsage), sources: make(map[string]map[string]bool)}
+}
+
+// usage returns the entry for a model, creating it on first use.
+func (t *ModelUsageTracker) usage(model string) *ModelUsage {
+	usage, ok := t.byModel[model]
+	if !ok {
+		usage = &ModelUsage{Model: model}
+		t.byModel[model] = usage
+		t.sources[model] = make(map[string]bool)
+	}
+	return usage
+}
+
+// AddSession adds one session's messages from source. Messages without a
+// model are ignored.
+func (t *ModelUsageTracker) AddSession(source string, messages []Message) {
+	seen := make(map[string]bool)
+	counted := make(map[string]bool)
+	for _, msg := range messages {
+		model, _ := msg.Metadata["model"].(string)
+		if model == "" {
+			continue
+		}
+		// Claude repeats one response's metadata on every line of that response
+		if id, _ := msg.Metadata["message_id"].(string); id != "" {
+			if counted[id] {
+				continue
+			}
+			counted[id] = true
+		}
+
+		usage := t.usage(model)
+		if !seen[model] {
+			seen[model] = true
+			usage.SessionCount++
+			t.sources[model][source] = true
+		}
+		usage.ResponseCount++
+		if ts := msg.Timestamp; !ts.IsZero() {
+			if usage.FirstUsed == nil || ts.Before(*usage.FirstUsed) {
+				usage.FirstUsed = &ts
+			}
+			if usage.LastUsed == nil || ts.After(*usage.LastUsed) {
+				usage.LastUsed = &ts
+			}
+		}
+	}
+
+	for _, entry := range CostEntries(messages) {
+		if entry.Model == "" {
+			continue
+		}
+		usage := t.usage(entry.Model)
+		usage.Tokens.add(entry.Tokens)
+		usage.CostUSD += entry.CostUSD
+		if entry.Unpriced {
+			usage.Unpriced = true
+		}
+	}
+}
+
+// Usage returns the accumulated usage per model, most responses first.
+func (t *ModelUsageTracker) Usage() []ModelUsage {
+	usage := make([]ModelUsage, 0, len(t.byModel))
+	for model, u := range t.byModel {
+		entry := *u
+		entry.Sources = make([]string, 0, len(t.sources[model]))
+		for source := range t.sources[model] {
+			entry.Sources = append(entry.Sources, source)
+		}
+		sort.Strings(entry.Sources)
+		usage = append(usage, entry)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].ResponseCount != usage[j].ResponseCount {
+			return usage[i].ResponseCount > usage[j].ResponseCount
+		}
+		return usage[i].Model < usage[j].Model
+	})
+	return usage
+}
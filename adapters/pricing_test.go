@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCostTracker(t *testing.T) {
+	usage := map[string]interface{}{
+		"input_tokens":                float64(1000),
+		"output_tokens":               float64(500),
+		"cache_read_input_tokens":     float64(8000),
+		"cache_creation_input_tokens": float64(1000),
+	}
+
+	tracker := NewCostTracker()
+	tracker.AddSession([]Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "usage": usage, "message_id": "m1"}},
+		// Continuation line of the same response must not be counted twice
+		{Role: "assistant", Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "usage": usage, "message_id": "m1"}},
+		{Role: "assistant", Metadata: map[string]interface{}{"model": "mystery-model", "usage": usage, "message_id": "m2"}},
+	})
+	tracker.AddSession([]Message{
+		{Role: "assistant", Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "cost": 0.5}},
+	})
+
+	report := tracker.Report()
+	if report.SessionCount != 2 {
+		t.Fatalf("expected 2 sessions, got %d", report.SessionCount)
+	}
+	// 1000*3 + 500*15 + 1000*3.75 + 8000*0.30 per million, plus the recorded 0.5
+	wantCost := (3000+7500+3750+2400)/1e6 + 0.5
+	if math.Abs(report.CostUSD-wantCost) > 1e-9 {
+		t.Fatalf("expected cost %v, got %v", wantCost, report.CostUSD)
+	}
+	if report.Tokens.CacheRead != 16000 {
+		t.Fatalf("expected usage from both priced and unpriced models, got %+v", report.Tokens)
+	}
+	if math.Abs(report.CacheHitRatio-0.8) > 1e-9 {
+		t.Fatalf("expected cache hit ratio 0.8, got %v", report.CacheHitRatio)
+	}
+	if len(report.UnpricedModels) != 1 || report.UnpricedModels[0] != "mystery-model" {
+		t.Fatalf("expected mystery-model to be unpriced, got %v", report.UnpricedModels)
+	}
+}
+
+func TestPriceForModel(t *testing.T) {
+	for model, input := range map[string]float64{
+		"claude-opus-4-5-20251101":   5,
+		"claude-opus-4-1-20250805":   15,
+		"claude-3-5-haiku-20241022":  0.80,
+		"claude-haiku-4-5-20251001":  1,
+		"claude-sonnet-4-5-20250929": 3,
+	} {
+		price, ok := PriceForModel(model)
+		if !ok || price.Input != input {
+			t.Errorf("PriceForModel(%s) = %+v, %v; want input price %v", model, price, ok, input)
+		}
+	}
+	if _, ok := PriceForModel("gpt-5"); ok {
+		t.Error("expected no price for a non-Claude model")
+	}
+}
+
+func TestModelUsageTracker(t *testing.T) {
+	first := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	last := first.Add(48 * time.Hour)
+	usage := map[string]interface{}{"input_tokens": float64(1000000), "output_tokens": float64(0)}
+
+	tracker := NewModelUsageTracker()
+	tracker.AddSession("claude", []Message{
+		{Role: "user", Content: "Refactor the parser"},
+		// Claude repeats one response's metadata on every line of it
+		{Role: "assistant", Timestamp: first, Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "message_id": "m1", "usage": usage}},
+		{Role: "assistant", Timestamp: first, Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "message_id": "m1", "usage": usage}},
+		{Role: "assistant", Timestamp: first, Metadata: map[string]interface{}{"model": "claude-haiku-4-5", "message_id": "m2"}},
+	})
+	tracker.AddSession("opencode", []Message{
+		{Role: "assistant", Timestamp: last, Metadata: map[string]interface{}{"model": "claude-sonnet-4-5", "cost": 0.25}},
+		{Role: "assistant", Timestamp: last, Metadata: map[string]interface{}{"model": "mystery-model",
+			"tokens": map[string]interface{}{"input": float64(10), "output": float64(5)}}},
+	})
+
+	models := tracker.Usage()
+	if len(models) != 3 || models[0].Model != "claude-sonnet-4-5" {
+		t.Fatalf("expected sonnet first of three models, got %+v", models)
+	}
+	sonnet := models[0]
+	if sonnet.SessionCount != 2 || sonnet.ResponseCount != 2 || len(sonnet.Sources) != 2 || sonnet.Sources[0] != "claude" {
+		t.Fatalf("unexpected sonnet usage: %+v", sonnet)
+	}
+	if sonnet.Tokens.Input != 1000000 || math.Abs(sonnet.CostUSD-3.25) > 1e-9 {
+		t.Fatalf("expected one counted response plus the recorded cost, got %+v", sonnet)
+	}
+	if !sonnet.FirstUsed.Equal(first) || !sonnet.LastUsed.Equal(last) {
+		t.Fatalf("unexpected first/last used: %v, %v", sonnet.FirstUsed, sonnet.LastUsed)
+	}
+	for _, model := range models[1:] {
+		if model.ResponseCount != 1 {
+			t.Errorf("expected one response from %s, got %+v", model.Model, model)
+		}
+		if model.Model == "mystery-model" && (!model.Unpriced || model.Tokens.Total != 15) {
+			t.Errorf("expected mystery-model's tokens without a price, got %+v", model)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package adapters
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"modernc.org/sqlite"
+)
+
+// ProjectFilter matches session project paths against the project_path a
+// caller asked for. A plain path matches that directory only. A path with
+// glob segments matches many: "*", "?" and "[...]" match within a segment,
+// and "**" matches any number of segments, so "~/work/monorepo/**" covers
+// the monorepo and every subdirectory or worktree under it. Both sides are
+// compared with symlinks resolved, so a project opened through a symlink
+// matches its real location.
+type ProjectFilter struct {
+	paths    []string   // a plain path, absolute and cleaned, and its resolved form
+	patterns [][]string // the segments of a glob, as given and with its literal prefix resolved
+}
+
+// maxMemoized caps how many symlink resolutions and parsed filters are
+// remembered before each memo starts over.
+const maxMemoized = 4096
+
+var (
+	memoMu        sync.Mutex
+	resolvedPaths = make(map[string]string)
+	sqlFilters    = make(map[string]*ProjectFilter)
+)
+
+func init() {
+	// Lets queries filter rows the way ProjectFilter filters sessions, for
+	// opencode's database and the search cache. It isn't deterministic, since
+	// symlinks can change between calls.
+	sqlite.MustRegisterScalarFunction("project_matches", 2, projectMatchesSQL)
+}
+
+// NewProjectFilter parses a project path or glob. A leading "~" is expanded
+// to the home directory and relative paths are made absolute. An empty
+// projectPath returns a nil filter, which matches every project.
+func NewProjectFilter(projectPath string) (*ProjectFilter, error) {
+	if projectPath == "" {
+		return nil, nil
+	}
+	if projectPath == "~" || strings.HasPrefix(projectPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand ~: %w", err)
+		}
+		projectPath = filepath.Join(home, projectPath[1:])
+	}
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	if !isGlob(absPath) {
+		filter := &ProjectFilter{paths: []string{absPath}}
+		if resolved := resolveSymlinks(absPath); resolved != absPath {
+			filter.paths = append(filter.paths, resolved)
+		}
+		return filter, nil
+	}
+
+	segments := strings.Split(filepath.ToSlash(absPath), "/")
+	literal := 0
+	for literal < len(segments) && !isGlob(segments[literal]) {
+		literal++
+	}
+	for _, segment := range segments[literal:] {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid project path pattern %q: %w", projectPath, err)
+		}
+	}
+
+	filter := &ProjectFilter{patterns: [][]string{segments}}
+	prefix := filepath.FromSlash(strings.Join(segments[:literal], "/"))
+	if prefix != "" {
+		if resolved := resolveSymlinks(prefix); resolved != prefix {
+			resolvedSegments := append(strings.Split(filepath.ToSlash(resolved), "/"), segments[literal:]...)
+			filter.patterns = append(filter.patterns, resolvedSegments)
+		}
+	}
+	return filter, nil
+}
+
+// IsPattern reports whether the filter is a glob rather than a single
+// directory. Adapters that store sessions by project directory look plain
+// paths up directly and scan every project for patterns.
+func (f *ProjectFilter) IsPattern() bool {
+	return f != nil && len(f.patterns) > 0
+}
+
+// Paths returns the directory a plain-path filter matches, followed by its
+// symlink-resolved form when that differs. It is empty for patterns.
+func (f *ProjectFilter) Paths() []string {
+	if f == nil {
+		return nil
+	}
+	return f.paths
+}
+
+// Match reports whether a session's project path is matched. A nil filter
+// matches everything; otherwise sessions without a project path never match.
+func (f *ProjectFilter) Match(projectPath string) bool {
+	if f == nil {
+		return true
+	}
+	if projectPath == "" {
+		return false
+	}
+	cleaned := filepath.Clean(projectPath)
+	candidates := []string{cleaned}
+	if resolved := resolveSymlinks(cleaned); resolved != cleaned {
+		candidates = append(candidates, resolved)
+	}
+
+	for _, candidate := range candidates {
+		for _, p := range f.paths {
+			if candidate == p {
+				return true
+			}
+		}
+		if len(f.patterns) == 0 {
+			continue
+		}
+		segments := strings.Split(filepath.ToSlash(candidate), "/")
+		for _, pattern := range f.patterns {
+			if matchSegments(pattern, segments) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the sessions whose project path is matched, keeping their
+// order, up to limit (0 means no limit).
+func (f *ProjectFilter) Filter(sessions []Session, limit int) []Session {
+	matched := []Session{}
+	for _, session := range sessions {
+		if limit > 0 && len(matched) == limit {
+			break
+		}
+		if f.Match(session.ProjectPath) {
+			matched = append(matched, session)
+		}
+	}
+	return matched
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches zero or more whole segments.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// isGlob reports whether s contains glob metacharacters.
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// resolveSymlinks returns p with symlinks resolved. When p no longer exists,
+// such as a deleted worktree, its deepest existing ancestor is resolved
+// instead. Results are memoized, since the same few project paths are
+// compared against every session.
+func resolveSymlinks(p string) string {
+	memoMu.Lock()
+	resolved, ok := resolvedPaths[p]
+	memoMu.Unlock()
+	if ok {
+		return resolved
+	}
+
+	resolved = p
+	for dir, rest := p, ""; ; {
+		if r, err := filepath.EvalSymlinks(dir); err == nil {
+			resolved = filepath.Join(r, rest)
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+
+	memoMu.Lock()
+	if len(resolvedPaths) >= maxMemoized {
+		resolvedPaths = make(map[string]string)
+	}
+	resolvedPaths[p] = resolved
+	memoMu.Unlock()
+	return resolved
+}
+
+// projectMatchesSQL implements project_matches(pattern, path) for SQLite,
+// true when path is matched by the project path or glob pattern. The filter
+// is parsed once per pattern rather than once per row.
+func projectMatchesSQL(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	pattern, _ := args[0].(string)
+	projectPath, _ := args[1].(string)
+
+	memoMu.Lock()
+	filter, ok := sqlFilters[pattern]
+	memoMu.Unlock()
+	if !ok {
+		var err error
+		if filter, err = NewProjectFilter(pattern); err != nil {
+			return nil, err
+		}
+		memoMu.Lock()
+		if len(sqlFilters) >= maxMemoized {
+			sqlFilters = make(map[string]*ProjectFilter)
+		}
+		sqlFilters[pattern] = filter
+		memoMu.Unlock()
+	}
+	if filter.Match(projectPath) {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
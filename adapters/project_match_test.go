@@ -0,0 +1,115 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProjectFilterMatch(t *testing.T) {
+	root := t.TempDir()
+	real := filepath.Join(root, "real", "monorepo")
+	if err := os.MkdirAll(filepath.Join(real, "services", "api"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(filepath.Join(root, "real"), link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tests := []struct {
+		filter string
+		path   string
+		want   bool
+	}{
+		{filter: real, path: real, want: true},
+		{filter: real, path: filepath.Join(real, "services"), want: false},
+		{filter: real + "/", path: real, want: true},
+		{filter: real + "/**", path: real, want: true},
+		{filter: real + "/**", path: filepath.Join(real, "services", "api"), want: true},
+		{filter: real + "/**", path: filepath.Join(root, "real", "other"), want: false},
+		{filter: real + "/*", path: filepath.Join(real, "services"), want: true},
+		{filter: real + "/*", path: filepath.Join(real, "services", "api"), want: false},
+		{filter: root + "/**/api", path: filepath.Join(real, "services", "api"), want: true},
+		{filter: root + "/real/mono?epo", path: real, want: true},
+		// Symlinks resolve on either side
+		{filter: filepath.Join(link, "monorepo"), path: real, want: true},
+		{filter: real, path: filepath.Join(link, "monorepo"), want: true},
+		{filter: link + "/monorepo/**", path: filepath.Join(real, "services"), want: true},
+		{filter: real + "/**", path: filepath.Join(link, "monorepo", "services", "api"), want: true},
+		{filter: real, path: "", want: false},
+	}
+	for _, tt := range tests {
+		filter, err := NewProjectFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("NewProjectFilter(%q): %v", tt.filter, err)
+		}
+		if got := filter.Match(tt.path); got != tt.want {
+			t.Errorf("NewProjectFilter(%q).Match(%q) = %v, want %v", tt.filter, tt.path, got, tt.want)
+		}
+	}
+
+	if filter, _ := NewProjectFilter(""); filter != nil || !filter.Match("/anything") {
+		t.Fatal("expected an empty project path to match everything")
+	}
+	if _, err := NewProjectFilter(root + "/[monorepo"); err == nil {
+		t.Fatal("expected an invalid pattern to be rejected")
+	}
+
+	home, _ := os.UserHomeDir()
+	filter, err := NewProjectFilter("~/work/**")
+	if err != nil || !filter.Match(filepath.Join(home, "work", "monorepo")) {
+		t.Fatalf("expected ~ to expand to the home directory (%v)", err)
+	}
+}
+
+func TestListSessionsByProjectPattern(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projectRoot := filepath.Join(home, "code")
+	link := filepath.Join(home, "linked")
+	if err := os.MkdirAll(projectRoot, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Symlink(projectRoot, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	opts := FixtureOptions{Sessions: 6, Turns: 1, Projects: 3, ProjectRoot: projectRoot, Seed: 7, End: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	for _, source := range FixtureSources() {
+		if _, err := GenerateFixtures(home, source, opts); err != nil {
+			t.Fatalf("GenerateFixtures(%s): %v", source, err)
+		}
+	}
+
+	adaptersMap, _ := NewRegistered()
+	for _, source := range FixtureSources() {
+		adapter := adaptersMap[source]
+		all, err := adapter.ListSessions("", 0)
+		if err != nil {
+			t.Fatalf("%s ListSessions: %v", source, err)
+		}
+
+		// A glob covers every project under the root, including through a symlink
+		for _, pattern := range []string{projectRoot + "/**", link + "/*"} {
+			matched, err := adapter.ListSessions(pattern, 0)
+			if err != nil {
+				t.Fatalf("%s ListSessions(%q): %v", source, pattern, err)
+			}
+			if len(matched) != len(all) {
+				t.Errorf("%s ListSessions(%q) returned %d sessions, want %d", source, pattern, len(matched), len(all))
+			}
+		}
+
+		// An exact path opened through the symlink finds the same sessions
+		project := all[0].ProjectPath
+		direct, err := adapter.ListSessions(project, 0)
+		if err != nil || len(direct) == 0 {
+			t.Fatalf("%s ListSessions(%q) found nothing (%v)", source, project, err)
+		}
+		viaLink, err := adapter.ListSessions(filepath.Join(link, filepath.Base(project)), 0)
+		if err != nil || len(viaLink) != len(direct) {
+			t.Errorf("%s found %d sessions through the symlink, want %d (%v)", source, len(viaLink), len(direct), err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a session adapter. Returning an error means the source is
+// unavailable on this machine; it is skipped rather than treated as fatal.
+type Factory func() (SessionAdapter, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes an adapter available under the given source name.
+// It is intended to be called from init functions, so built-in adapters, forks,
+// and files behind build tags can add sources without touching main.
+// Register panics if name is empty, factory is nil, or name is already registered.
+func Register(name string, factory func() (SessionAdapter, error)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("adapters: Register called with empty name")
+	}
+	if factory == nil {
+		panic("adapters: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("adapters: Register called twice for %q", name))
+	}
+	registry[name] = factory
+}
+
+// Registered returns the sorted names of all registered adapters.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegistered creates every registered adapter, keyed by source name.
+// Adapters whose factory fails are left out and reported in the returned errors.
+func NewRegistered() (map[string]SessionAdapter, []error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	adapters := make(map[string]SessionAdapter, len(registry))
+	var errs []error
+	for name, factory := range registry {
+		adapter, err := factory()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		adapters[name] = adapter
+	}
+	return adapters, errs
+}
@@ -0,0 +1,49 @@
+package adapters
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuiltinAdaptersRegistered(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, name := range Registered() {
+		registered[name] = true
+	}
+	for _, name := range []string{"claude", "codex", "copilot", "gemini", "mistral", "opencode"} {
+		if !registered[name] {
+			t.Errorf("expected built-in adapter %q to be registered", name)
+		}
+	}
+}
+
+func TestRegisterAndNewRegistered(t *testing.T) {
+	Register("test-ok", func() (SessionAdapter, error) {
+		return NewGenericJSONLAdapter(GenericJSONLConfig{Name: "test-ok", Glob: "*.jsonl", Role: "$.role", Content: "$.content"})
+	})
+	Register("test-fail", func() (SessionAdapter, error) { return nil, errors.New("not installed") })
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "test-ok")
+		delete(registry, "test-fail")
+		registryMu.Unlock()
+	})
+
+	adapters, errs := NewRegistered()
+	if _, ok := adapters["test-ok"]; !ok {
+		t.Errorf("expected test-ok adapter to be created")
+	}
+	if _, ok := adapters["test-fail"]; ok {
+		t.Errorf("expected failing factory to be skipped")
+	}
+	if len(errs) == 0 {
+		t.Errorf("expected factory error to be reported")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected duplicate registration to panic")
+		}
+	}()
+	Register("test-ok", func() (SessionAdapter, error) { return nil, nil })
+}
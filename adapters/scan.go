@@ -0,0 +1,54 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+)
+
+// lowPowerScanBuffer is the initial size of the buffers session files are
+// scanned with in low-power mode. Buffers grow up to each format's line limit
+// as needed, so starting small only costs reallocations when long lines turn up.
+const lowPowerScanBuffer = 16 * 1024
+
+var lowPower atomic.Bool
+
+// SetLowPower makes adapters scan session files with small initial buffers,
+// so each read holds less memory on machines where that matters more than
+// speed.
+func SetLowPower(on bool) {
+	lowPower.Store(on)
+}
+
+// newScanBuffer returns the initial buffer for scanning a session file: size
+// bytes, or less in low-power mode.
+func newScanBuffer(size int) []byte {
+	if lowPower.Load() {
+		size = min(size, lowPowerScanBuffer)
+	}
+	return make([]byte, 0, size)
+}
+
+// setScanBuffer sets scanner up to read a session file whose format allows
+// lines of up to formatMax bytes, starting with a buffer of size bytes. The
+// configured line limit lowers formatMax when it is smaller.
+func setScanBuffer(scanner *bufio.Scanner, size, formatMax int) {
+	maxLine := lineLimit(formatMax)
+	scanner.Buffer(newScanBuffer(min(size, maxLine)), maxLine)
+}
+
+// decodeJSONFile decodes the JSON file at path into v, streaming it from disk
+// rather than reading it into memory first. Files over the size limit fail
+// with ErrFileTooLarge.
+func decodeJSONFile(path string, v interface{}) error {
+	if err := checkFileSize(path); err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(v)
+}
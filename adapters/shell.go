@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// shellToolNames are the tool names sources use to run shell commands.
+var shellToolNames = map[string]bool{
+	"bash":              true, // Claude Code, opencode
+	"shell":             true, // Codex
+	"local_shell":       true, // Codex
+	"exec_command":      true, // Codex
+	"run_shell_command": true, // Gemini CLI
+	"run_terminal_cmd":  true,
+	"execute_command":   true,
+}
+
+// ShellCommand returns the command line a tool call ran, if it is a shell tool call.
+// Commands given as an argv list are joined, and "bash -lc <script>" wrappers are
+// unwrapped to the script itself.
+func ShellCommand(call ToolCall) (string, bool) {
+	if !shellToolNames[strings.ToLower(call.Name)] {
+		return "", false
+	}
+
+	for _, key := range []string{"command", "cmd"} {
+		switch v := call.Input[key].(type) {
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return strings.TrimSpace(v), true
+			}
+		case []interface{}:
+			argv := make([]string, 0, len(v))
+			for _, arg := range v {
+				if s, ok := arg.(string); ok {
+					argv = append(argv, s)
+				}
+			}
+			if len(argv) == 3 && (argv[1] == "-lc" || argv[1] == "-c") && strings.HasSuffix(argv[0], "sh") {
+				return strings.TrimSpace(argv[2]), true
+			}
+			if len(argv) > 0 {
+				return strings.Join(argv, " "), true
+			}
+		}
+	}
+	return "", false
+}
+
+// ShellCommandEntry is one distinct command from a reconstructed shell history.
+type ShellCommandEntry struct {
+	Command    string     `json:"command"`
+	Count      int        `json:"count"`
+	Failures   int        `json:"failures,omitempty"`
+	FirstRun   *time.Time `json:"first_run,omitempty"`
+	LastRun    *time.Time `json:"last_run,omitempty"`
+	SessionIDs []string   `json:"session_ids"`
+	order      int
+}
+
+// ShellCommandTracker reconstructs a deduplicated shell history over any number of sessions.
+type ShellCommandTracker struct {
+	byCommand map[string]*ShellCommandEntry
+}
+
+// NewShellCommandTracker returns an empty tracker.
+func NewShellCommandTracker() *ShellCommandTracker {
+	return &ShellCommandTracker{byCommand: make(map[string]*ShellCommandEntry)}
+}
+
+// AddSession adds the shell commands run in one session.
+func (t *ShellCommandTracker) AddSession(sessionID string, messages []Message) {
+	for _, invocation := range ExtractToolInvocations(messages) {
+		command, ok := ShellCommand(invocation.ToolCall)
+		if !ok {
+			continue
+		}
+
+		entry, ok := t.byCommand[command]
+		if !ok {
+			entry = &ShellCommandEntry{Command: command, SessionIDs: []string{}, order: len(t.byCommand)}
+			t.byCommand[command] = entry
+		}
+		entry.Count++
+		if invocation.Success != nil && !*invocation.Success {
+			entry.Failures++
+		}
+		if ts := invocation.Timestamp; ts != nil {
+			if entry.FirstRun == nil || ts.Before(*entry.FirstRun) {
+				entry.FirstRun = ts
+			}
+			if entry.LastRun == nil || ts.After(*entry.LastRun) {
+				entry.LastRun = ts
+			}
+		}
+		if n := len(entry.SessionIDs); n == 0 || entry.SessionIDs[n-1] != sessionID {
+			entry.SessionIDs = append(entry.SessionIDs, sessionID)
+		}
+	}
+}
+
+// Commands returns the distinct commands in the order they were first run.
+// Commands without timestamps keep the order they were added in.
+func (t *ShellCommandTracker) Commands() []ShellCommandEntry {
+	commands := make([]ShellCommandEntry, 0, len(t.byCommand))
+	for _, entry := range t.byCommand {
+		commands = append(commands, *entry)
+	}
+	sort.SliceStable(commands, func(i, j int) bool {
+		a, b := commands[i], commands[j]
+		if a.FirstRun != nil && b.FirstRun != nil && !a.FirstRun.Equal(*b.FirstRun) {
+			return a.FirstRun.Before(*b.FirstRun)
+		}
+		return a.order < b.order
+	})
+	return commands
+}
@@ -0,0 +1,64 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShellCommand(t *testing.T) {
+	for _, tc := range []struct {
+		call ToolCall
+		want string
+		ok   bool
+	}{
+		{ToolCall{Name: "Bash", Input: map[string]interface{}{"command": "go test ./..."}}, "go test ./...", true},
+		{ToolCall{Name: "shell", Input: map[string]interface{}{"command": []interface{}{"bash", "-lc", "ls -la"}}}, "ls -la", true},
+		{ToolCall{Name: "shell", Input: map[string]interface{}{"command": []interface{}{"git", "status"}}}, "git status", true},
+		{ToolCall{Name: "Read", Input: map[string]interface{}{"command": "not a shell"}}, "", false},
+	} {
+		got, ok := ShellCommand(tc.call)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ShellCommand(%+v) = %q, %v; want %q, %v", tc.call, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestShellCommandTracker(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	bash := func(id, command string, at time.Time) Message {
+		return Message{Role: "assistant", Timestamp: at, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": id, "name": "Bash", "input": map[string]interface{}{"command": command}},
+			},
+		}}
+	}
+	failed := func(id string) Message {
+		return Message{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": "exit 1", "is_error": true},
+			},
+		}}
+	}
+
+	tracker := NewShellCommandTracker()
+	tracker.AddSession("s1", []Message{
+		bash("t1", "go build ./...", start),
+		bash("t2", "go test ./...", start.Add(time.Minute)),
+		failed("t2"),
+	})
+	tracker.AddSession("s2", []Message{
+		bash("t3", "go test ./...", start.Add(time.Hour)),
+	})
+
+	commands := tracker.Commands()
+	if len(commands) != 2 || commands[0].Command != "go build ./..." || commands[1].Command != "go test ./..." {
+		t.Fatalf("unexpected commands: %+v", commands)
+	}
+	test := commands[1]
+	if test.Count != 2 || test.Failures != 1 || len(test.SessionIDs) != 2 {
+		t.Fatalf("unexpected go test entry: %+v", test)
+	}
+	if !test.FirstRun.Equal(start.Add(time.Minute)) || !test.LastRun.Equal(start.Add(time.Hour)) {
+		t.Fatalf("unexpected run times: %v - %v", test.FirstRun, test.LastRun)
+	}
+}
@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"io"
+	"time"
+)
+
+// WorkspaceSnapshot is a copy of workspace files an agent saved so its edits
+// could be undone: a Claude Code file-history snapshot taken before a prompt,
+// or a Gemini CLI checkpoint taken before a file-modifying tool call.
+type WorkspaceSnapshot struct {
+	ID          string         `json:"id"`
+	SessionID   string         `json:"session_id"`
+	Source      string         `json:"source"`
+	Timestamp   *time.Time     `json:"timestamp,omitempty"`
+	Description string         `json:"description"`
+	Files       []SnapshotFile `json:"files"`
+	// Commit is the shadow git commit holding a Gemini checkpoint's files
+	Commit string `json:"commit,omitempty"`
+}
+
+// SnapshotFile is one file a snapshot tracks.
+type SnapshotFile struct {
+	Path    string `json:"path"`
+	Version int    `json:"version,omitempty"`
+	// Existed is false when the file didn't exist yet when the snapshot was
+	// taken, so the snapshot has no content for it
+	Existed bool `json:"existed"`
+}
+
+// SnapshotReader is called with each file a snapshot holds: its path, as
+// recorded and made absolute against the project where possible, and its
+// content.
+type SnapshotReader func(path string, content io.Reader) error
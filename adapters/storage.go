@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiskUsage returns the size in bytes of a file, or of every file under a
+// directory. Missing paths and unreadable entries count as zero.
+func DiskUsage(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	if !info.IsDir() {
+		return info.Size()
+	}
+
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// SessionStorage returns the transcript and the directory Claude Code keeps
+// next to it for the session's subagents and large tool results.
+func (c *ClaudeAdapter) SessionStorage(session Session) []string {
+	paths := []string{session.FilePath}
+	dir := strings.TrimSuffix(session.FilePath, filepath.Ext(session.FilePath))
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		paths = append(paths, dir)
+	}
+	return paths
+}
+
+// SessionStorage returns the files holding a session. Sessions in the SQLite
+// database all share it; sessions in file storage also have a directory of
+// messages and, in newer layouts, a directory of parts per message.
+func (o *OpencodeAdapter) SessionStorage(session Session) []string {
+	if session.FilePath == o.dbPath {
+		return []string{o.dbPath}
+	}
+
+	paths := []string{session.FilePath}
+	messageDir := filepath.Join(o.storageDir, "message", session.ID)
+	if _, err := os.Stat(messageDir); err != nil {
+		return paths
+	}
+	paths = append(paths, messageDir)
+	files, _ := filepath.Glob(filepath.Join(messageDir, "msg_*.json"))
+	for _, file := range files {
+		partDir := filepath.Join(o.storageDir, "part", strings.TrimSuffix(filepath.Base(file), ".json"))
+		if _, err := os.Stat(partDir); err == nil {
+			paths = append(paths, partDir)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,30 @@
+package adapters
+
+import "github.com/rivo/uniseg"
+
+// Preview lengths, in characters. Adapters keep session previews (the first
+// message) up to MaxPreviewLength, and tools shorten previews to the length a
+// caller asks for, DefaultPreviewLength unless set.
+const (
+	DefaultPreviewLength = 200
+	MaxPreviewLength     = 1000
+)
+
+// TruncateText shortens text to its first maxLen characters and appends "..."
+// if anything was cut. Characters are grapheme clusters, so emoji sequences,
+// combining marks, and conjuncts in scripts like Devanagari are never split.
+func TruncateText(text string, maxLen int) string {
+	end, count := 0, 0
+	state := -1
+	rest := text
+	for len(rest) > 0 {
+		if count == maxLen {
+			return text[:end] + "..."
+		}
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		end += len(cluster)
+		count++
+	}
+	return text
+}
@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"sort"
+	"time"
+)
+
+// ToolTiming aggregates how long one tool's calls took.
+type ToolTiming struct {
+	Tool  string `json:"tool"`
+	Calls int    `json:"calls"`
+	// TimedCalls counts the calls whose duration is known; the durations below
+	// are over those calls only
+	TimedCalls int   `json:"timed_calls"`
+	TotalMS    int64 `json:"total_ms"`
+	AvgMS      int64 `json:"avg_ms"`
+	MedianMS   int64 `json:"median_ms"`
+	MaxMS      int64 `json:"max_ms"`
+}
+
+// TimedCall is one tool call and how long it took.
+type TimedCall struct {
+	SessionID    string     `json:"session_id"`
+	Source       string     `json:"source"`
+	Tool         string     `json:"tool"`
+	MessageIndex int        `json:"message_index"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"`
+	DurationMS   int64      `json:"duration_ms"`
+	Success      *bool      `json:"success,omitempty"`
+	// File is the first file the call worked on; commands and other inputs are
+	// left out so timings stay free of session content
+	File string `json:"file,omitempty"`
+}
+
+// ToolTimingTracker accumulates tool call durations over any number of
+// sessions, keeping the slowest calls.
+type ToolTimingTracker struct {
+	maxSlowest int
+	byTool     map[string]*ToolTiming
+	durations  map[string][]int64
+	slowest    []TimedCall
+}
+
+// NewToolTimingTracker returns an empty tracker that keeps the maxSlowest
+// slowest calls.
+func NewToolTimingTracker(maxSlowest int) *ToolTimingTracker {
+	return &ToolTimingTracker{maxSlowest: maxSlowest, byTool: make(map[string]*ToolTiming), durations: make(map[string][]int64)}
+}
+
+// AddSession adds the tool calls in one session's messages. Calls whose
+// duration is unknown are counted but not timed.
+func (t *ToolTimingTracker) AddSession(sessionID, source string, messages []Message) {
+	for _, invocation := range ExtractToolInvocations(messages) {
+		timing, ok := t.byTool[invocation.Name]
+		if !ok {
+			timing = &ToolTiming{Tool: invocation.Name}
+			t.byTool[invocation.Name] = timing
+		}
+		timing.Calls++
+		if invocation.DurationMS == nil {
+			continue
+		}
+		duration := *invocation.DurationMS
+		timing.TimedCalls++
+		timing.TotalMS += duration
+		if duration > timing.MaxMS {
+			timing.MaxMS = duration
+		}
+		t.durations[invocation.Name] = append(t.durations[invocation.Name], duration)
+		call := TimedCall{
+			SessionID:    sessionID,
+			Source:       source,
+			Tool:         invocation.Name,
+			MessageIndex: invocation.MessageIndex,
+			Timestamp:    invocation.Timestamp,
+			DurationMS:   duration,
+			Success:      invocation.Success,
+		}
+		if files := FilesFromToolCall(invocation.ToolCall); len(files) > 0 {
+			call.File = files[0]
+		}
+		t.addSlow(call)
+	}
+}
+
+// addSlow records call if it is among the slowest seen so far.
+func (t *ToolTimingTracker) addSlow(call TimedCall) {
+	if t.maxSlowest <= 0 {
+		return
+	}
+	if len(t.slowest) == t.maxSlowest && call.DurationMS <= t.slowest[len(t.slowest)-1].DurationMS {
+		return
+	}
+	at := sort.Search(len(t.slowest), func(i int) bool { return t.slowest[i].DurationMS < call.DurationMS })
+	t.slowest = append(t.slowest, TimedCall{})
+	copy(t.slowest[at+1:], t.slowest[at:])
+	t.slowest[at] = call
+	if len(t.slowest) > t.maxSlowest {
+		t.slowest = t.slowest[:t.maxSlowest]
+	}
+}
+
+// Timings returns each tool's timing, most total time first, then most calls.
+func (t *ToolTimingTracker) Timings() []ToolTiming {
+	timings := make([]ToolTiming, 0, len(t.byTool))
+	for name, timing := range t.byTool {
+		entry := *timing
+		if durations := t.durations[name]; len(durations) > 0 {
+			sorted := append([]int64(nil), durations...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			entry.AvgMS = entry.TotalMS / int64(len(sorted))
+			entry.MedianMS = sorted[len(sorted)/2]
+			if len(sorted)%2 == 0 {
+				entry.MedianMS = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+			}
+		}
+		timings = append(timings, entry)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].TotalMS != timings[j].TotalMS {
+			return timings[i].TotalMS > timings[j].TotalMS
+		}
+		if timings[i].Calls != timings[j].Calls {
+			return timings[i].Calls > timings[j].Calls
+		}
+		return timings[i].Tool < timings[j].Tool
+	})
+	return timings
+}
+
+// Slowest returns the slowest calls seen, slowest first.
+func (t *ToolTimingTracker) Slowest() []TimedCall {
+	return append([]TimedCall{}, t.slowest...)
+}
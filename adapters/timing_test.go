@@ -0,0 +1,88 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolTimingTracker(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	claude := []Message{
+		{Role: "assistant", Timestamp: ts, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Bash", "input": map[string]interface{}{"command": "go test ./..."}},
+			},
+		}},
+		{Role: "user", Timestamp: ts.Add(90 * time.Second), Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"},
+			},
+		}},
+		// No result timestamp: counted but not timed
+		{Role: "assistant", Timestamp: ts.Add(2 * time.Minute), Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t2", "name": "Bash", "input": map[string]interface{}{"command": "ls"}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t2", "content": "main.go"},
+			},
+		}},
+	}
+	copilot := []Message{
+		{Role: "assistant", Metadata: map[string]interface{}{
+			"tool_calls": []map[string]interface{}{
+				{"id": "c1", "name": "Bash", "arguments": map[string]interface{}{"command": "make"}},
+			},
+		}},
+		{Role: "tool", Content: "built", Metadata: map[string]interface{}{"tool_call_id": "c1", "success": true, "duration_ms": int64(30000)}},
+	}
+	opencode := []Message{
+		{Role: "assistant", NonTextParts: []map[string]interface{}{
+			{"type": "tool", "tool": "read", "callID": "o1", "state": map[string]interface{}{
+				"input":  map[string]interface{}{"filePath": "/src/main.go"},
+				"output": "package main",
+				"time":   map[string]interface{}{"start": float64(1000), "end": float64(1250)},
+			}},
+		}},
+	}
+
+	tracker := NewToolTimingTracker(3)
+	tracker.AddSession("s1", "claude", claude)
+	tracker.AddSession("s2", "copilot", copilot)
+	tracker.AddSession("s3", "opencode", opencode)
+
+	timings := tracker.Timings()
+	if len(timings) != 2 || timings[0].Tool != "Bash" || timings[1].Tool != "read" {
+		t.Fatalf("expected Bash then read, got %+v", timings)
+	}
+	bash := timings[0]
+	if bash.Calls != 3 || bash.TimedCalls != 2 || bash.TotalMS != 120000 || bash.AvgMS != 60000 || bash.MedianMS != 60000 || bash.MaxMS != 90000 {
+		t.Fatalf("unexpected Bash timing: %+v", bash)
+	}
+	if read := timings[1]; read.Calls != 1 || read.TimedCalls != 1 || read.TotalMS != 250 || read.MedianMS != 250 {
+		t.Fatalf("unexpected read timing: %+v", read)
+	}
+
+	slowest := tracker.Slowest()
+	if len(slowest) != 3 {
+		t.Fatalf("expected the 3 timed calls, got %+v", slowest)
+	}
+	if first := slowest[0]; first.SessionID != "s1" || first.DurationMS != 90000 || first.MessageIndex != 0 {
+		t.Fatalf("unexpected slowest call: %+v", first)
+	}
+	if second := slowest[1]; second.SessionID != "s2" || second.DurationMS != 30000 || second.Tool != "Bash" {
+		t.Fatalf("unexpected second slowest call: %+v", second)
+	}
+	if third := slowest[2]; third.SessionID != "s3" || third.DurationMS != 250 || third.File != "/src/main.go" {
+		t.Fatalf("unexpected third slowest call: %+v", third)
+	}
+
+	limited := NewToolTimingTracker(1)
+	limited.AddSession("s3", "opencode", opencode)
+	limited.AddSession("s1", "claude", claude)
+	if slowest := limited.Slowest(); len(slowest) != 1 || slowest[0].DurationMS != 90000 {
+		t.Fatalf("expected only the slowest call to be kept, got %+v", slowest)
+	}
+}
@@ -0,0 +1,154 @@
+package adapters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Todo is an open task found in a session.
+type Todo struct {
+	Text         string `json:"text"`
+	Status       string `json:"status,omitempty"` // from a todo tool, e.g. "pending" or "in_progress"
+	MessageIndex int    `json:"message_index"`
+	Origin       string `json:"origin"` // "todo_tool" or "text"
+}
+
+// todoToolLists maps lower-cased todo tool names to the input field holding
+// their list: Claude TodoWrite, opencode todowrite, and Codex update_plan.
+var todoToolLists = map[string]string{
+	"todowrite":   "todos",
+	"update_plan": "plan",
+}
+
+// todoMarker matches a "TODO:" or "FIXME:" note, optionally in a list item or
+// comment, or an unchecked Markdown checkbox.
+var todoMarker = regexp.MustCompile(`^(?:[-*]\s+\[ \]\s+|(?:[-*]\s+|//\s*|#\s*)?(?:TODO|FIXME)(?:\([^)]*\))?:\s*)(.+)$`)
+
+// ExtractOpenTodos returns the tasks a session left open. The last todo list
+// written with a todo tool is authoritative: its items that aren't completed
+// are returned. TODO/FIXME notes and unchecked checkboxes in message text after
+// that list are added, since the list doesn't cover them.
+func ExtractOpenTodos(messages []Message) []Todo {
+	var todos []Todo
+	listIndex := -1
+
+	for i, msg := range messages {
+		for _, call := range ExtractToolCalls(msg) {
+			field, ok := todoToolLists[strings.ToLower(call.Name)]
+			if !ok {
+				continue
+			}
+			listIndex = i
+			todos = todos[:0]
+			for _, item := range asObjectList(call.Input[field]) {
+				status := stringField(item, "status")
+				if status == "completed" || status == "cancelled" {
+					continue
+				}
+				text := stringField(item, "content")
+				if text == "" {
+					text = stringField(item, "step")
+				}
+				if text != "" {
+					todos = append(todos, Todo{Text: text, Status: status, MessageIndex: i, Origin: "todo_tool"})
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(todos))
+	for _, todo := range todos {
+		seen[strings.ToLower(todo.Text)] = true
+	}
+	for i := listIndex + 1; i < len(messages); i++ {
+		inFence := false
+		for _, line := range strings.Split(messages[i].Content, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "```") {
+				inFence = !inFence
+				continue
+			}
+			if inFence {
+				continue
+			}
+			match := todoMarker.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			text := strings.TrimSpace(match[1])
+			if text == "" || seen[strings.ToLower(text)] {
+				continue
+			}
+			seen[strings.ToLower(text)] = true
+			todos = append(todos, Todo{Text: text, MessageIndex: i, Origin: "text"})
+		}
+	}
+
+	return todos
+}
+
+// nextStepsHeading matches a line introducing a list of follow-up work, such
+// as "Next steps:" or "## Remaining work", with any text after the colon.
+// Sentences that merely start with those words don't match.
+var nextStepsHeading = regexp.MustCompile(`(?i)^(?:#+\s*|\*\*)?(?:suggested |recommended )?(?:next steps?|follow[- ]ups?|remaining (?:work|tasks|items)|still to do|future work|left to do)(?:\*\*)?\s*(?::|$)\s*(?:\*\*)?\s*(.*)$`)
+
+// listItem matches a bulleted or numbered list item.
+var listItem = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[ \]\s+)?(.+)$`)
+
+// DetectTodos returns the follow-up work assistant messages wrote down:
+// TODO/FIXME notes, unchecked checkboxes, and the items under a "Next steps"
+// style heading (Origin "next_steps"). Code blocks are skipped, as are
+// repeats of the same text within the session.
+func DetectTodos(messages []Message) []Todo {
+	var todos []Todo
+	seen := make(map[string]bool)
+	add := func(text string, index int, origin string) {
+		text = strings.TrimSpace(strings.Trim(strings.TrimSpace(text), "*"))
+		key := strings.ToLower(text)
+		if text == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		todos = append(todos, Todo{Text: text, MessageIndex: index, Origin: origin})
+	}
+
+	for i, msg := range messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		inFence, inNextSteps := false, false
+		for _, line := range strings.Split(msg.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "```") {
+				inFence = !inFence
+				inNextSteps = false
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			if match := todoMarker.FindStringSubmatch(line); match != nil {
+				add(match[1], i, "text")
+				continue
+			}
+			if match := nextStepsHeading.FindStringSubmatch(line); match != nil {
+				inNextSteps = true
+				if rest := match[1]; rest != "" {
+					add(rest, i, "next_steps")
+				}
+				continue
+			}
+			if !inNextSteps {
+				continue
+			}
+			if match := listItem.FindStringSubmatch(line); match != nil {
+				add(match[1], i, "next_steps")
+			} else if line != "" {
+				// The list ended
+				inNextSteps = false
+			}
+		}
+	}
+	return todos
+}
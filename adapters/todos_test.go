@@ -0,0 +1,62 @@
+package adapters
+
+import "testing"
+
+func TestExtractOpenTodos(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "TODO: this note predates the todo list"},
+		{Role: "assistant", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "TodoWrite", "input": map[string]interface{}{
+					"todos": []interface{}{
+						map[string]interface{}{"content": "Add migration", "status": "completed"},
+						map[string]interface{}{"content": "Write tests", "status": "in_progress"},
+						map[string]interface{}{"content": "Update docs", "status": "pending"},
+					},
+				}},
+			},
+		}},
+		{Role: "assistant", Content: "Remaining:\n- [ ] Bump the version\n- [x] Done already\n```go\n// TODO: inside code\n```\nFIXME: flaky retry test\nThe todo list is updated."},
+	}
+
+	todos := ExtractOpenTodos(messages)
+	want := []Todo{
+		{Text: "Write tests", Status: "in_progress", MessageIndex: 1, Origin: "todo_tool"},
+		{Text: "Update docs", Status: "pending", MessageIndex: 1, Origin: "todo_tool"},
+		{Text: "Bump the version", MessageIndex: 2, Origin: "text"},
+		{Text: "flaky retry test", MessageIndex: 2, Origin: "text"},
+	}
+	if len(todos) != len(want) {
+		t.Fatalf("expected %d todos, got %+v", len(want), todos)
+	}
+	for i := range want {
+		if todos[i] != want[i] {
+			t.Errorf("todo %d: expected %+v, got %+v", i, want[i], todos[i])
+		}
+	}
+}
+
+func TestDetectTodos(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "TODO: notes from the user are not the assistant's"},
+		{Role: "assistant", Content: "Done.\n\n## Next steps\n1. Add an index on user_id\n2. **Backfill old rows**\n\nLet me know.\n- not a next step"},
+		{Role: "assistant", Content: "```go\n// TODO: inside code\n```\nFIXME: flaky retry test\nNext steps: ship it\nRemaining tasks look fine to me.\n- [ ] Update the changelog\n\n**Follow-ups:**\n- add an index on user_id"},
+	}
+
+	todos := DetectTodos(messages)
+	want := []Todo{
+		{Text: "Add an index on user_id", MessageIndex: 1, Origin: "next_steps"},
+		{Text: "Backfill old rows", MessageIndex: 1, Origin: "next_steps"},
+		{Text: "flaky retry test", MessageIndex: 2, Origin: "text"},
+		{Text: "ship it", MessageIndex: 2, Origin: "next_steps"},
+		{Text: "Update the changelog", MessageIndex: 2, Origin: "text"},
+	}
+	if len(todos) != len(want) {
+		t.Fatalf("expected %d todos, got %+v", len(want), todos)
+	}
+	for i := range want {
+		if todos[i] != want[i] {
+			t.Errorf("todo %d = %+v, want %+v", i, todos[i], want[i])
+		}
+	}
+}
@@ -33,6 +33,17 @@ type Session struct {
 
 	// Summary is an optional high-level summary of the session (if available)
 	Summary string `json:"summary,omitempty"`
+
+	// ParentSessionID is set on subagent sessions spawned by another session (e.g. Claude Code Task runs)
+	ParentSessionID string `json:"parent_session_id,omitempty"`
+
+	// ChildSessionIDs lists subagent sessions spawned by this session, when known
+	ChildSessionIDs []string `json:"child_session_ids,omitempty"`
+
+	// GitBranch and GitCommit are the repository state the agent recorded
+	// for the session, when it records one
+	GitBranch string `json:"git_branch,omitempty"`
+	GitCommit string `json:"git_commit,omitempty"`
 }
 
 // Message represents a single message within a session.
@@ -59,19 +70,19 @@ type Message struct {
 
 	// NonTextParts contains structured non-text parts when available for the source.
 	NonTextParts []map[string]interface{} `json:"non_text_parts,omitempty"`
+
+	// ContentHash is the content address of this message (see HashMessage).
+	ContentHash string `json:"content_hash,omitempty"`
 }
 
-// extractFirstLine extracts the first non-empty line from text, truncating if needed.
+// extractFirstLine extracts the first non-empty line from text, truncated to MaxPreviewLength.
 // This is a shared helper used by multiple adapters for extracting message previews.
 func extractFirstLine(text string) string {
 	lines := strings.Split(text, "\n")
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		if trimmed != "" {
-			if len(trimmed) > 200 {
-				return trimmed[:200] + "..."
-			}
-			return trimmed
+			return TruncateText(trimmed, MaxPreviewLength)
 		}
 	}
 	return ""
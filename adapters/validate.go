@@ -0,0 +1,205 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SchemaDeviation is a place where a session file departs from the format its
+// adapter knows. Normal reads skip what they don't recognize; strict
+// validation reports it, so upstream format changes get noticed.
+type SchemaDeviation struct {
+	// Line is the 1-based line in a JSONL file; zero for single-document files
+	Line int `json:"line,omitempty"`
+	// Field is the path to the offending value, such as "message.content[1].type"
+	Field   string `json:"field,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// deviations collects the problems found in one file.
+type deviations struct {
+	list []SchemaDeviation
+	line int // the JSONL line being checked
+}
+
+func (d *deviations) add(field, format string, args ...interface{}) {
+	d.list = append(d.list, SchemaDeviation{Line: d.line, Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// str checks that obj[key] is a string and returns it. A missing key is only
+// a deviation when required.
+func (d *deviations) str(obj map[string]interface{}, path, key string, required bool) string {
+	value, ok := obj[key]
+	if !ok || value == nil {
+		if required {
+			d.add(fieldPath(path, key), "missing")
+		}
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		d.add(fieldPath(path, key), "expected string, got %s", jsonType(value))
+	}
+	return s
+}
+
+// object checks that obj[key] is a JSON object and returns it.
+func (d *deviations) object(obj map[string]interface{}, path, key string, required bool) map[string]interface{} {
+	value, ok := obj[key]
+	if !ok || value == nil {
+		if required {
+			d.add(fieldPath(path, key), "missing")
+		}
+		return nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		d.add(fieldPath(path, key), "expected object, got %s", jsonType(value))
+	}
+	return m
+}
+
+// array checks that obj[key] is a JSON array and returns it.
+func (d *deviations) array(obj map[string]interface{}, path, key string, required bool) []interface{} {
+	value, ok := obj[key]
+	if !ok || value == nil {
+		if required {
+			d.add(fieldPath(path, key), "missing")
+		}
+		return nil
+	}
+	a, ok := value.([]interface{})
+	if !ok {
+		d.add(fieldPath(path, key), "expected array, got %s", jsonType(value))
+	}
+	return a
+}
+
+// timestamp checks that obj[key] is a timestamp in one of layouts, or in
+// RFC 3339 when none are given.
+func (d *deviations) timestamp(obj map[string]interface{}, path, key string, required bool, layouts ...string) {
+	s := d.str(obj, path, key, required)
+	if s == "" {
+		return
+	}
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339Nano}
+	}
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	d.add(fieldPath(path, key), "unparseable timestamp %q", s)
+}
+
+// objects checks that every item of an array is an object, calling check on each.
+func (d *deviations) objects(items []interface{}, path string, check func(item map[string]interface{}, path string)) {
+	for i, item := range items {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			d.add(itemPath, "expected object, got %s", jsonType(item))
+			continue
+		}
+		check(m, itemPath)
+	}
+}
+
+// oneOf reports value unless it is one of known.
+func (d *deviations) oneOf(field, kind, value string, known ...string) {
+	if value == "" {
+		return
+	}
+	for _, k := range known {
+		if value == k {
+			return
+		}
+	}
+	d.add(field, "unknown %s %q", kind, value)
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// validateJSONLFile checks each line of a JSONL session file with check,
+// reporting lines that aren't JSON objects and lines too long to read.
+func validateJSONLFile(path string, check func(entry map[string]interface{}, d *deviations)) ([]SchemaDeviation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+
+	d := &deviations{}
+	scanner := bufio.NewScanner(file)
+	setScanBuffer(scanner, 1024*1024, 10*1024*1024) // the limit adapters read lines with
+
+	for scanner.Scan() {
+		d.line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			d.add("", "not a JSON object: %v", err)
+			continue
+		}
+		check(entry, d)
+	}
+	if err := scanner.Err(); err != nil {
+		if !errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("failed to read session file: %w", err)
+		}
+		d.line++
+		d.add("", "line longer than 10MB; it and the rest of the file are not read")
+	}
+	return d.list, nil
+}
+
+// validateJSONFile checks a single-document JSON session file with check.
+func validateJSONFile(path string, check func(doc map[string]interface{}, d *deviations)) ([]SchemaDeviation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	d := &deviations{}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		d.add("", "not a JSON object: %v", err)
+		return d.list, nil
+	}
+	check(doc, d)
+	return d.list, nil
+}
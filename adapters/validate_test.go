@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClaudeValidateSession(t *testing.T) {
+	lines := []string{
+		`{"type":"user","timestamp":"2025-06-01T10:00:00Z","message":{"role":"user","content":"hello"}}`,
+		`{"type":"assistant","timestamp":"2025-06-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"hi"},{"type":"hologram"}]}}`,
+		`not json`,
+		``,
+		`{"type":"checkpoint"}`,
+		`{"type":"user","timestamp":"yesterday","message":{"role":"assistant","content":42}}`,
+		`{"type":"summary"}`,
+	}
+	file := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("failed to write session file: %v", err)
+	}
+
+	got, err := (&ClaudeAdapter{}).ValidateSession(Session{FilePath: file})
+	if err != nil {
+		t.Fatalf("ValidateSession returned error: %v", err)
+	}
+	var summary []string
+	for _, d := range got {
+		summary = append(summary, fmt.Sprintf("%d %s %s", d.Line, d.Field, strings.SplitN(d.Problem, ":", 2)[0]))
+	}
+	want := []string{
+		`2 message.content[1].type unknown content block type "hologram"`,
+		`3  not a JSON object`,
+		`5 type unknown entry type "checkpoint"`,
+		`6 timestamp unparseable timestamp "yesterday"`,
+		`6 message.role role "assistant" on a user line`,
+		`6 message.content expected string or array, got number`,
+		`7 summary missing`,
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Fatalf("unexpected deviations:\n%s\nwant:\n%s", strings.Join(summary, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestGeminiValidateSession(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.json")
+	doc := `{"sessionId":"s1","messages":[{"type":"user","content":"hi"},{"type":"thought","content":"hmm"},{"content":7,"toolCalls":[{}]}]}`
+	if err := os.WriteFile(file, []byte(doc), 0o644); err != nil {
+		t.Fatalf("failed to write session file: %v", err)
+	}
+
+	got, err := (&GeminiAdapter{}).ValidateSession(Session{FilePath: file})
+	if err != nil {
+		t.Fatalf("ValidateSession returned error: %v", err)
+	}
+	want := []SchemaDeviation{
+		{Field: "messages[1].type", Problem: `unknown message type "thought"`},
+		{Field: "messages[2]", Problem: "missing type and role"},
+		{Field: "messages[2].content", Problem: "expected string, array, or object, got number"},
+		{Field: "messages[2].toolCalls[0].name", Problem: "missing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected deviations: %+v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// bookmarkPreview summarizes a bookmarked message on one line: the start of its
+// text, or its first tool call if it has no text.
+func bookmarkPreview(msg adapters.Message) string {
+	if text := strings.Join(strings.Fields(msg.Content), " "); text != "" {
+		return adapters.TruncateText(text, adapters.DefaultPreviewLength)
+	}
+	if calls := adapters.ExtractToolCalls(msg); len(calls) > 0 {
+		return strings.TrimSpace(calls[0].Name + " " + toolCallPreview(calls[0]))
+	}
+	return ""
+}
+
+// Tool 32: bookmark_message
+type bookmarkMessageArgs struct {
+	SessionID    string `json:"session_id" jsonschema:"The session containing the message"`
+	Source       string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	MessageIndex int    `json:"message_index" jsonschema:"The message to bookmark, counting from 0 as in search_in_session and get_messages"`
+	Label        string `json:"label,omitempty" jsonschema:"A short label for the bookmark, such as \"schema decision\""`
+}
+
+func addBookmarkMessageTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "bookmark_message",
+		Description: "Bookmark a message so the exchange can be found again later with list_bookmarks, without searching. Bookmarking a message again replaces its label.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args bookmarkMessageArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		session, found := findSession(adapter, args.SessionID)
+		if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+			return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if args.MessageIndex < 0 || args.MessageIndex >= len(messages) {
+			return nil, nil, fmt.Errorf("message_index %d is out of range (session has %d messages)", args.MessageIndex, len(messages))
+		}
+
+		bookmark, err := searchCache.AddBookmark(search.Bookmark{
+			SessionID:    args.SessionID,
+			Source:       args.Source,
+			ProjectPath:  session.ProjectPath,
+			MessageIndex: args.MessageIndex,
+			Label:        strings.TrimSpace(args.Label),
+			Preview:      bookmarkPreview(messages[args.MessageIndex]),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(map[string]interface{}{"bookmark": bookmark}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 33: list_bookmarks
+type listBookmarksArgs struct {
+	SessionID   string `json:"session_id,omitempty" jsonschema:"Only list bookmarks in this session"`
+	Source      string `json:"source,omitempty" jsonschema:"Only list bookmarks in sessions from this source"`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only list bookmarks in sessions from this project directory"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of bookmarks to return (default: 50)"`
+}
+
+func addListBookmarksTool(server *mcp.Server, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "list_bookmarks",
+		Description: "List bookmarked messages, newest first, with their labels and a preview of each message. Read the exchange around a bookmark with get_messages.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listBookmarksArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+
+		bookmarks, err := searchCache.Bookmarks(args.SessionID, args.Source, args.ProjectPath, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		allowed := make([]search.Bookmark, 0, len(bookmarks))
+		var withheld []string
+		for _, b := range bookmarks {
+			if !consent.allowed(ctx, req.Session, b.ProjectPath) {
+				withheld = appendUnique(withheld, b.ProjectPath)
+				continue
+			}
+			if len(allowed) < args.Limit {
+				allowed = append(allowed, b)
+			}
+		}
+
+		result := map[string]interface{}{
+			"bookmarks": allowed,
+			"count":     len(allowed),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
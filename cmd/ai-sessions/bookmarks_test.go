@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestBookmarkMessageAndList(t *testing.T) {
+	sessions := []adapters.Session{{ID: "sess-1", Source: "stub", ProjectPath: "/app"}}
+	messages := map[string][]adapters.Message{
+		"sess-1": {
+			{Role: "user", Content: "Should we shard the table?"},
+			{Role: "assistant", Content: "No:\n  partition it by month instead."},
+			{Role: "assistant", Metadata: map[string]interface{}{
+				"raw_content": []interface{}{
+					map[string]interface{}{"type": "tool_use", "name": "Bash", "input": map[string]interface{}{"command": "make migrate"}},
+				},
+			}},
+		},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, messages)}
+	cache := newTestCache(t)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addBookmarkMessageTool(server, adaptersMap, cache, nil)
+	addListBookmarksTool(server, cache, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	call := func(name string, args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected error from %s: %v", name, result.Content[0].(*mcp.TextContent).Text)
+		}
+		return result
+	}
+
+	call("bookmark_message", map[string]interface{}{"session_id": "sess-1", "source": "stub", "message_index": 1, "label": "sharding decision"})
+	call("bookmark_message", map[string]interface{}{"session_id": "sess-1", "source": "stub", "message_index": 2})
+
+	result := call("list_bookmarks", map[string]interface{}{"project_path": "/app"})
+	var got struct {
+		Bookmarks []search.Bookmark `json:"bookmarks"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if len(got.Bookmarks) != 2 {
+		t.Fatalf("expected 2 bookmarks, got %+v", got.Bookmarks)
+	}
+	previews := map[int]string{}
+	for _, b := range got.Bookmarks {
+		previews[b.MessageIndex] = b.Preview
+	}
+	if previews[1] != "No: partition it by month instead." || previews[2] != "Bash make migrate" {
+		t.Fatalf("unexpected previews: %+v", previews)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// Kinds of change reported by changes_since.
+const (
+	changeNew      = "new"      // started in the window
+	changeResumed  = "resumed"  // started earlier, and the user prompted again in the window
+	changeModified = "modified" // started earlier and written to in the window without a new prompt
+)
+
+// defaultChangeWindow is how far back changes_since looks the first time a
+// client asks without giving a time.
+const defaultChangeWindow = 24 * time.Hour
+
+// sessionChange is a session that changed in a changes_since window.
+type sessionChange struct {
+	Session      adapters.Session `json:"session"`
+	Change       string           `json:"change"`
+	LastActivity time.Time        `json:"last_activity"`
+	ResumedAt    *time.Time       `json:"resumed_at,omitempty"`   // first prompt in the window
+	NewMessages  int              `json:"new_messages,omitempty"` // messages timestamped in the window, when recorded
+}
+
+// parseSince parses a changes_since time: RFC 3339, a date, or a duration
+// back from now such as "24h".
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (expected an RFC 3339 time, a date like 2025-03-01, a duration like 24h, or \"last\")", value)
+}
+
+// messagesSince counts the messages timestamped after since and returns the
+// time of the first user prompt among them, if any.
+func messagesSince(messages []adapters.Message, since time.Time) (count int, resumedAt *time.Time) {
+	for _, msg := range messages {
+		if !msg.Timestamp.After(since) {
+			continue
+		}
+		count++
+		if resumedAt == nil && isPrompt(msg) {
+			ts := msg.Timestamp
+			resumedAt = &ts
+		}
+	}
+	return count, resumedAt
+}
+
+// collectChanges finds sessions that started or were written to after since.
+// A session's own file modification time tells whether it changed. Sessions
+// stored together in one database share a modification time, so for those the
+// message timestamps decide. Messages are only read for sessions that started
+// before since and might have been resumed.
+func collectChanges(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, since time.Time) ([]sessionChange, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	var changes []sessionChange
+	for _, adapter := range adaptersToQuery {
+		sessions, err := adapter.ListSessions(projectPath, 0) // Get all sessions
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", adapter.Name(), err)
+			continue
+		}
+
+		sessionsPerFile := make(map[string]int)
+		for _, s := range sessions {
+			sessionsPerFile[s.FilePath]++
+		}
+
+		for _, session := range sessions {
+			modTime := session.Timestamp
+			if info, err := os.Stat(session.FilePath); err == nil {
+				modTime = info.ModTime()
+			}
+			if !modTime.After(since) && !session.Timestamp.After(since) {
+				continue
+			}
+			sharedFile := sessionsPerFile[session.FilePath] > 1
+
+			if session.Timestamp.After(since) {
+				changes = append(changes, sessionChange{Session: session, Change: changeNew, LastActivity: modTime})
+				continue
+			}
+
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error reading session %s: %v", session.ID, err)
+				continue
+			}
+			count, resumedAt := messagesSince(messages, since)
+			change := sessionChange{Session: session, LastActivity: modTime, ResumedAt: resumedAt, NewMessages: count}
+			if sharedFile && count > 0 {
+				change.LastActivity = messages[len(messages)-1].Timestamp
+			}
+			switch {
+			case resumedAt != nil:
+				change.Change = changeResumed
+			case count > 0 || !sharedFile:
+				change.Change = changeModified
+			default:
+				continue // another session in the same database changed
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].LastActivity.After(changes[j].LastActivity)
+	})
+	return changes, nil
+}
+
+// clientName identifies the MCP client of a session, or "unknown".
+func clientName(session *mcp.ServerSession) string {
+	if session != nil {
+		if params := session.InitializeParams(); params != nil && params.ClientInfo != nil && params.ClientInfo.Name != "" {
+			return params.ClientInfo.Name
+		}
+	}
+	return "unknown"
+}
+
+// Tool 30: changes_since
+type changesSinceArgs struct {
+	Since         string `json:"since,omitempty" jsonschema:"Report changes after this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (24h). Leave empty or use \"last\" for changes since this client last called changes_since."`
+	Source        string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath   string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for all projects."`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum number of sessions to return per kind of change (default: 20)"`
+	PreviewLength int    `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+}
+
+func addChangesSinceTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "changes_since",
+		Description: "Report what changed in session history since a time, or since this client last asked: new sessions, resumed sessions (the user prompted again), and sessions modified without a new prompt, across all sources. Use it for an incremental view instead of re-listing everything.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args changesSinceArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 20
+		}
+
+		now := time.Now()
+		client := clientName(req.Session)
+		var since time.Time
+		firstCheck := false
+		if args.Since == "" || strings.EqualFold(args.Since, "last") {
+			checkpoint, ok, err := searchCache.ChangeCheckpoint(client)
+			if err != nil {
+				return nil, nil, err
+			}
+			since, firstCheck = checkpoint, !ok
+			if firstCheck {
+				since = now.Add(-defaultChangeWindow)
+			}
+		} else {
+			var err error
+			if since, err = parseSince(args.Since, now); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		changes, err := collectChanges(adaptersMap, args.Source, args.ProjectPath, since)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Every call counts as asking, so the next "last" starts here
+		if err := searchCache.SetChangeCheckpoint(client, now); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		grouped := map[string][]sessionChange{changeNew: {}, changeResumed: {}, changeModified: {}}
+		counts := map[string]int{}
+		var withheld []string
+		for _, change := range changes {
+			if !consent.allowed(ctx, req.Session, change.Session.ProjectPath) {
+				withheld = appendUnique(withheld, change.Session.ProjectPath)
+				continue
+			}
+			counts[change.Change]++
+			if len(grouped[change.Change]) < args.Limit {
+				change.Session = previewSession(change.Session, args.PreviewLength)
+				grouped[change.Change] = append(grouped[change.Change], change)
+			}
+		}
+
+		result := map[string]interface{}{
+			"since":    since,
+			"until":    now,
+			"new":      grouped[changeNew],
+			"resumed":  grouped[changeResumed],
+			"modified": grouped[changeModified],
+			"counts":   counts,
+		}
+		if firstCheck {
+			result["first_check"] = true
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectChanges(t *testing.T) {
+	dir := t.TempDir()
+	since := time.Now().Add(-time.Hour)
+	before, after := since.Add(-time.Hour), since.Add(30*time.Minute)
+
+	touch := func(name string, modTime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	shared := touch("shared.db", after)
+
+	sessions := []adapters.Session{
+		{ID: "fresh", Source: "stub", Timestamp: after, FilePath: touch("fresh.jsonl", after)},
+		{ID: "resumed", Source: "stub", Timestamp: before, FilePath: touch("resumed.jsonl", after)},
+		{ID: "appended", Source: "stub", Timestamp: before, FilePath: touch("appended.jsonl", after)},
+		{ID: "old", Source: "stub", Timestamp: before, FilePath: touch("old.jsonl", before)},
+		{ID: "db-active", Source: "stub", Timestamp: before, FilePath: shared},
+		{ID: "db-idle", Source: "stub", Timestamp: before, FilePath: shared},
+	}
+	messages := map[string][]adapters.Message{
+		"resumed": {
+			{Role: "user", Content: "start", Timestamp: before},
+			{Role: "user", Content: "keep going", Timestamp: after},
+			{Role: "assistant", Content: "ok", Timestamp: after},
+		},
+		"appended": {
+			{Role: "user", Content: "start", Timestamp: before},
+			{Role: "assistant", Content: "late reply", Timestamp: after},
+		},
+		"db-active": {
+			{Role: "user", Content: "start", Timestamp: before},
+			{Role: "user", Content: "again", Timestamp: after},
+		},
+		"db-idle": {
+			{Role: "user", Content: "start", Timestamp: before},
+		},
+	}
+	adapter := newStubAdapter(sessions, messages)
+
+	changes, err := collectChanges(map[string]adapters.SessionAdapter{"stub": adapter}, "", "", since)
+	if err != nil {
+		t.Fatalf("collectChanges failed: %v", err)
+	}
+
+	got := make(map[string]sessionChange)
+	for _, c := range changes {
+		got[c.Session.ID] = c
+	}
+	want := map[string]string{"fresh": changeNew, "resumed": changeResumed, "appended": changeModified, "db-active": changeResumed}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for id, kind := range want {
+		if got[id].Change != kind {
+			t.Errorf("expected %s to be %s, got %q", id, kind, got[id].Change)
+		}
+	}
+	if c := got["resumed"]; c.NewMessages != 2 || c.ResumedAt == nil || !c.ResumedAt.Equal(after) {
+		t.Errorf("expected resumed at %v with 2 new messages, got %+v", after, c)
+	}
+	if adapter.getCalls["fresh"] != 0 {
+		t.Error("expected new sessions not to be read")
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	if got, err := parseSince("6h", now); err != nil || !got.Equal(now.Add(-6*time.Hour)) {
+		t.Errorf("duration: got %v, %v", got, err)
+	}
+	if got, err := parseSince("2025-03-01T08:00:00Z", now); err != nil || !got.Equal(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339: got %v, %v", got, err)
+	}
+	if got, err := parseSince("2025-03-01", now); err != nil || got.Day() != 1 {
+		t.Errorf("date: got %v, %v", got, err)
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("expected an error for an unparseable time")
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// Checklist formats, besides formatMarkdown
+const (
+	formatJSON  = "json"
+	formatJUnit = "junit"
+)
+
+// checklistFormat resolves the requested checklist format, defaulting to JUnit
+// XML when the output file ends in .xml, Markdown when it ends in .md, and
+// JSON otherwise.
+func checklistFormat(format, outputPath string) (string, error) {
+	switch strings.ToLower(format) {
+	case "":
+		switch strings.ToLower(filepath.Ext(outputPath)) {
+		case ".xml":
+			return formatJUnit, nil
+		case ".md", ".markdown":
+			return formatMarkdown, nil
+		}
+		return formatJSON, nil
+	case "json":
+		return formatJSON, nil
+	case "markdown", "md":
+		return formatMarkdown, nil
+	case "junit", "xml":
+		return formatJUnit, nil
+	}
+	return "", fmt.Errorf("%w %q for checklist (expected json, markdown, or junit)", adapters.ErrFormatUnsupported, format)
+}
+
+// checklistCounts returns how many items a checklist has and how many are done.
+func checklistCounts(checklist adapters.Checklist) (total, done int) {
+	for _, item := range checklist.Items {
+		if item.Done {
+			done++
+		}
+	}
+	return len(checklist.Items), done
+}
+
+// evidenceLine describes one piece of evidence on a single line.
+func evidenceLine(evidence adapters.ChecklistEvidence) string {
+	line := fmt.Sprintf("message %d: %s", evidence.MessageIndex, evidence.Description)
+	if evidence.Success != nil && !*evidence.Success {
+		line += " (failed)"
+	}
+	return line
+}
+
+// renderChecklistMarkdown renders a checklist as Markdown task list items,
+// with each item's evidence nested under it.
+func renderChecklistMarkdown(session adapters.Session, checklist adapters.Checklist) string {
+	var b strings.Builder
+	total, done := checklistCounts(checklist)
+	fmt.Fprintf(&b, "# Review checklist: %s\n\n", exportTitle(session))
+	fmt.Fprintf(&b, "- **Source:** %s\n", getAgentDisplayName(session.Source))
+	fmt.Fprintf(&b, "- **Session ID:** `%s`\n", session.ID)
+	if session.ProjectPath != "" {
+		fmt.Fprintf(&b, "- **Project:** `%s`\n", session.ProjectPath)
+	}
+	fmt.Fprintf(&b, "- **Done:** %d of %d\n\n", done, total)
+
+	for _, item := range checklist.Items {
+		mark := " "
+		if item.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&b, "- [%s] %s", mark, item.Text)
+		if item.Status != "" && !item.Done {
+			fmt.Fprintf(&b, " *(%s)*", item.Status)
+		}
+		b.WriteString("\n")
+		for _, evidence := range item.Evidence {
+			fmt.Fprintf(&b, "  - %s\n", evidenceLine(evidence))
+		}
+	}
+
+	if len(checklist.Unplanned) > 0 {
+		b.WriteString("\n## Unplanned actions\n\n")
+		for _, evidence := range checklist.Unplanned {
+			fmt.Fprintf(&b, "- %s\n", evidenceLine(evidence))
+		}
+	}
+	return b.String()
+}
+
+// JUnit XML elements, in the subset CI systems and test report viewers read.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// renderChecklistJUnit renders a checklist as a JUnit XML report with a test
+// case per item, failing the items that aren't done, so it can be reviewed in
+// tools that display test results. Evidence goes in each case's system-out,
+// and unplanned actions in the suite's.
+func renderChecklistJUnit(session adapters.Session, checklist adapters.Checklist) (string, error) {
+	total, done := checklistCounts(checklist)
+	suite := junitTestSuite{
+		Name:      exportTitle(session),
+		Tests:     total,
+		Failures:  total - done,
+		TestCases: []junitTestCase{},
+	}
+	className := session.Source + "." + session.ID
+	for _, item := range checklist.Items {
+		testCase := junitTestCase{ClassName: className, Name: item.Text}
+		if !item.Done {
+			status := item.Status
+			if status == "" {
+				status = "open"
+			}
+			testCase.Failure = &junitFailure{Message: "not done: " + status}
+		}
+		lines := make([]string, 0, len(item.Evidence))
+		for _, evidence := range item.Evidence {
+			lines = append(lines, evidenceLine(evidence))
+		}
+		testCase.SystemOut = strings.Join(lines, "\n")
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	lines := make([]string, 0, len(checklist.Unplanned))
+	for _, evidence := range checklist.Unplanned {
+		lines = append(lines, "unplanned "+evidenceLine(evidence))
+	}
+	suite.SystemOut = strings.Join(lines, "\n")
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to render JUnit report: %w", err)
+	}
+	return xml.Header + string(out) + "\n", nil
+}
+
+// Tool 41: export_review_checklist
+type exportReviewChecklistArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to build a checklist for"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: json, markdown, or junit (JUnit XML). Default: junit when output_path ends in .xml, markdown when it ends in .md, json otherwise."`
+}
+
+// exportReviewChecklistToFileArgs are export_review_checklist's arguments on
+// servers started with --allow-write, which may write the checklist to a file.
+type exportReviewChecklistToFileArgs struct {
+	exportReviewChecklistArgs
+	OutputPath string `json:"output_path,omitempty" jsonschema:"Absolute file path to write the checklist to. If omitted, the checklist is returned."`
+}
+
+// addExportReviewChecklistTool registers export_review_checklist. Only with
+// allowWrite (--allow-write) can clients have the checklist written to a file.
+func addExportReviewChecklistTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, allowWrite bool) {
+	tool := &mcp.Tool{
+		Name:        "export_review_checklist",
+		Description: "Turn a session's plan (todo tool lists and open TODO notes) into a review checklist: each item marked done or not done, with the file changes and commands that did its work as evidence linked by message index, plus actions taken outside any planned item. Returns JSON, Markdown, or a JUnit XML report with a failing test case per unfinished item.",
+	}
+	if !allowWrite {
+		addTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args exportReviewChecklistArgs) (*mcp.CallToolResult, any, error) {
+			return exportReviewChecklist(ctx, req, adaptersMap, searchCache, consent, args, "")
+		})
+		return
+	}
+	tool.Description += " Can write it to output_path instead."
+	addTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args exportReviewChecklistToFileArgs) (*mcp.CallToolResult, any, error) {
+		return exportReviewChecklist(ctx, req, adaptersMap, searchCache, consent, args.exportReviewChecklistArgs, args.OutputPath)
+	})
+}
+
+// exportReviewChecklist builds a session's checklist for
+// export_review_checklist, writing it to outputPath if set.
+func exportReviewChecklist(ctx context.Context, req *mcp.CallToolRequest, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, args exportReviewChecklistArgs, outputPath string) (*mcp.CallToolResult, any, error) {
+	if args.SessionID == "" {
+		return nil, nil, fmt.Errorf("session_id is required")
+	}
+	if args.Source == "" {
+		return nil, nil, fmt.Errorf("source is required")
+	}
+	if outputPath != "" && !filepath.IsAbs(outputPath) {
+		return nil, nil, fmt.Errorf("output_path must be absolute")
+	}
+	format, err := checklistFormat(args.Format, outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	adapter, ok := adaptersMap[args.Source]
+	if !ok {
+		return nil, nil, adapters.SourceUnavailableError(args.Source)
+	}
+
+	session, found := findSession(adapter, args.SessionID)
+	if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+		return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+	}
+
+	messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	recordAccess(searchCache, req.Session, search.AccessEntry{
+		SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+	})
+	checklist := adapters.BuildChecklist(messages)
+	total, done := checklistCounts(checklist)
+
+	var content string
+	switch format {
+	case formatMarkdown:
+		content = renderChecklistMarkdown(session, checklist)
+	case formatJUnit:
+		if content, err = renderChecklistJUnit(session, checklist); err != nil {
+			return nil, nil, err
+		}
+	default:
+		out, err := json.MarshalIndent(map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"items":      checklist.Items,
+			"unplanned":  checklist.Unplanned,
+			"total":      total,
+			"done":       done,
+		}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+		content = string(out)
+	}
+
+	if outputPath == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: content},
+			},
+		}, nil, nil
+	}
+
+	if err := writeExport(outputPath, content, nil); err != nil {
+		return nil, nil, err
+	}
+
+	resultJSON, err := json.MarshalIndent(map[string]interface{}{
+		"session_id":  args.SessionID,
+		"source":      args.Source,
+		"output_path": outputPath,
+		"format":      format,
+		"total":       total,
+		"done":        done,
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func testChecklist() (adapters.Session, adapters.Checklist) {
+	completedAt := 3
+	failed := false
+	session := adapters.Session{ID: "sess-1", Source: "claude", ProjectPath: "/app", FirstMessage: "Add the migration"}
+	checklist := adapters.Checklist{
+		Items: []adapters.ChecklistItem{
+			{Text: "Add migration", Status: "completed", Done: true, Origin: "todo_tool", AddedAt: 1, CompletedAt: &completedAt,
+				Evidence: []adapters.ChecklistEvidence{{MessageIndex: 2, Kind: adapters.EvidenceFileChange, Description: "Write /app/migrate.sql"}}},
+			{Text: "Write tests", Status: "pending", Origin: "todo_tool", AddedAt: 1, Evidence: []adapters.ChecklistEvidence{}},
+		},
+		Unplanned: []adapters.ChecklistEvidence{{MessageIndex: 4, Kind: adapters.EvidenceCommand, Description: "go test ./...", Success: &failed}},
+	}
+	return session, checklist
+}
+
+func TestRenderChecklistMarkdown(t *testing.T) {
+	out := renderChecklistMarkdown(testChecklist())
+	for _, want := range []string{
+		"- **Done:** 1 of 2",
+		"- [x] Add migration\n  - message 2: Write /app/migrate.sql\n",
+		"- [ ] Write tests *(pending)*\n",
+		"## Unplanned actions\n\n- message 4: go test ./... (failed)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderChecklistJUnit(t *testing.T) {
+	out, err := renderChecklistJUnit(testChecklist())
+	if err != nil {
+		t.Fatalf("renderChecklistJUnit failed: %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("expected valid XML, got %v:\n%s", err, out)
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("expected one suite, got %+v", report.Suites)
+	}
+	suite := report.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.TestCases) != 2 {
+		t.Fatalf("unexpected suite %+v", suite)
+	}
+	if done := suite.TestCases[0]; done.Failure != nil || done.ClassName != "claude.sess-1" || done.SystemOut != "message 2: Write /app/migrate.sql" {
+		t.Fatalf("unexpected done case %+v", done)
+	}
+	if open := suite.TestCases[1]; open.Failure == nil || open.Failure.Message != "not done: pending" {
+		t.Fatalf("expected the open item to fail, got %+v", open)
+	}
+	if !strings.Contains(suite.SystemOut, "unplanned message 4: go test ./... (failed)") {
+		t.Fatalf("expected unplanned actions in the suite output, got %q", suite.SystemOut)
+	}
+}
+
+func TestChecklistFormat(t *testing.T) {
+	for _, tt := range []struct {
+		format, outputPath, want string
+	}{
+		{"", "", formatJSON},
+		{"", "/tmp/review.xml", formatJUnit},
+		{"", "/tmp/review.md", formatMarkdown},
+		{"junit", "/tmp/review.txt", formatJUnit},
+	} {
+		if got, err := checklistFormat(tt.format, tt.outputPath); err != nil || got != tt.want {
+			t.Errorf("checklistFormat(%q, %q) = %q, %v; want %q", tt.format, tt.outputPath, got, err, tt.want)
+		}
+	}
+	if _, err := checklistFormat("pdf", ""); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
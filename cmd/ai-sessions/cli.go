@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/manifoldco/promptui"
+	"github.com/rivo/uniseg"
 	"github.com/yoavf/ai-sessions-mcp/adapters"
 	"golang.org/x/term"
 )
@@ -107,6 +108,22 @@ func handleCLI() {
 		handleLogin(apiURL)
 	case "upload":
 		handleUploadCommand()
+	case "export":
+		handleExportCommand()
+	case "show":
+		handleShowCommand()
+	case "search":
+		handleSearchCommand()
+	case "cache":
+		handleCacheCommand()
+	case "storage":
+		handleStorageCommand()
+	case "forget":
+		handleForgetCommand()
+	case "clients":
+		handleClientsCommand()
+	case "fixtures":
+		handleFixturesCommand()
 	case "version", "-v", "--version":
 		fmt.Println("aisessions version 2.0.0")
 	case "help", "-h", "--help":
@@ -128,17 +145,75 @@ Usage:
 Commands:
   login              Configure authentication token
   upload <file>      Upload a transcript file
+  export <id>        Export a session as Markdown or HTML (requires --source)
+  show <id>          Read a session in the terminal (requires --source)
+  search <query>     Search sessions and open a result in the show view
+  cache export <file>
+                     Save the search index to a single snapshot file
+  cache import <file>
+                     Replace the search index with a snapshot
+  cache vacuum       Evict sessions beyond the retention caps and reclaim the
+                     index's unused space
+  storage            Show disk usage per source, project, and month, and the
+                     largest sessions
+  forget <id>        Remove a session from the search index and keep it out
+                     (requires --source; --undo lets it back in)
+  forget --list      List forgotten sessions
+  clients            List clients registered for remote (HTTP) access
+  clients add <name> --scope list|search|read|write
+                     Register a remote client and print its token
+  clients remove <name>
+                     Revoke a remote client's access
+  fixtures generate --output <dir>
+                     Write synthetic session stores for testing, laid out as
+                     a home directory (all sources unless --source is given)
   version            Show version information
   help               Show this help message
 
 Options:
   --title <title>    Set the title for the uploaded transcript (upload only)
   --url <url>        Override API URL (default: https://aisessions.dev)
+  --source <source>  Source that created the session (export, show, and forget), or
+                     only include this source (search and storage), or
+                     generate this source, repeatable (fixtures)
+  --project <path>   Only include sessions from this project (search and storage)
+  --scope <scope>    all; prose to match only the assistant's explanations; or
+                     code to match only code blocks and file edits
+                     (search only, default: all)
+  --limit <n>        Max search results (search, default: 50) or largest
+                     sessions listed (storage, default: 10)
+  --output <file>    Write the export to a file instead of stdout (export), or
+                     the directory to write fixtures to (fixtures)
+  --format <format>  markdown or html; defaults to html for .html output (export only)
+  --messages <ranges>
+                     Only export these message indices, e.g. 10-19,25 (export only)
+  --expand           Show tool call inputs and results in full (show and search)
+  --page <n>         Show one page of messages, counting from 0 (show only)
+  --page-size <n>    Messages per page (show and search, default: 20)
+  --no-pager         Print directly instead of through $PAGER (show and search)
+  --no-color         Disable colors; also honored via NO_COLOR (show and search)
+  --sessions <n>     Sessions per source (fixtures, default: 10)
+  --turns <n>        User prompts per session (fixtures, default: 3)
+  --projects <n>     Projects sessions are spread over (fixtures, default: 3)
+  --seed <n>         Seed for reproducible fixtures (fixtures, default: 0)
+  --spec <file>      JSON file declaring fixture options per source (fixtures)
+
+Server options (run without a command to start the MCP server):
+  --no-cache         Keep the search index in memory instead of ~/.cache
+                     (alias: --in-memory)
+  --allow-write      Enable export_snapshot, which writes snapshot files to disk
+  --http <addr>      Serve over streamable HTTP on addr (such as :8080)
+                     instead of stdio
 
 Examples:
   aisessions login
   aisessions upload session.jsonl
   aisessions upload session.jsonl --title "Bug Fix Session"
+  aisessions export 4f2c9e1a --source claude --output session.md
+  aisessions export 4f2c9e1a --source claude --output session.html
+  aisessions show 4f2c9e1a --source claude --expand
+  aisessions search "oauth refresh" --source codex
+  aisessions fixtures generate --source claude --sessions 100 --output /tmp/fixtures
 
   # Development mode (use local server)
   aisessions login --url http://localhost:3000
@@ -373,13 +448,7 @@ func getAgentDisplayName(source string) string {
 
 // cleanFirstMessage trims and truncates the first message
 func cleanFirstMessage(msg string, maxLen int) string {
-	msg = strings.TrimSpace(msg)
-
-	// Truncate with ellipsis if needed
-	if len(msg) > maxLen {
-		return msg[:maxLen-3] + "..."
-	}
-	return msg
+	return truncateString(strings.TrimSpace(msg), maxLen)
 }
 
 // getTerminalWidth returns the terminal width, defaulting to 80 if unable to determine
@@ -391,26 +460,77 @@ func getTerminalWidth() int {
 	return width
 }
 
-// truncateString truncates a string to maxLen with ellipsis at the end
+// truncateString truncates a string to maxLen terminal columns with ellipsis at the end.
+// It cuts between grapheme clusters, so emoji and combining marks stay whole, and
+// counts wide characters such as CJK as two columns.
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if uniseg.StringWidth(s) <= maxLen {
 		return s
 	}
+	clusters := graphemeClusters(s)
+	ellipsis := "..."
 	if maxLen < 3 {
-		return s[:maxLen]
+		ellipsis = ""
+	}
+	var b strings.Builder
+	width := 0
+	for _, c := range clusters {
+		if width+c.width > maxLen-len(ellipsis) {
+			break
+		}
+		b.WriteString(c.text)
+		width += c.width
 	}
-	return s[:maxLen-3] + "..."
+	return b.String() + ellipsis
 }
 
-// truncateStringStart truncates a string to maxLen with ellipsis at the start
+// truncateStringStart truncates a string to maxLen terminal columns with ellipsis at the start
 func truncateStringStart(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if uniseg.StringWidth(s) <= maxLen {
 		return s
 	}
+	clusters := graphemeClusters(s)
+	ellipsis := "..."
 	if maxLen < 3 {
-		return s[len(s)-maxLen:]
+		ellipsis = ""
+	}
+	start, width := len(clusters), 0
+	for start > 0 && width+clusters[start-1].width <= maxLen-len(ellipsis) {
+		start--
+		width += clusters[start].width
+	}
+	var b strings.Builder
+	b.WriteString(ellipsis)
+	for _, c := range clusters[start:] {
+		b.WriteString(c.text)
+	}
+	return b.String()
+}
+
+// graphemeCluster is one user-perceived character and its width in terminal columns.
+type graphemeCluster struct {
+	text  string
+	width int
+}
+
+func graphemeClusters(s string) []graphemeCluster {
+	var clusters []graphemeCluster
+	state := -1
+	for len(s) > 0 {
+		var c graphemeCluster
+		c.text, s, c.width, state = uniseg.FirstGraphemeClusterInString(s, state)
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// padRight pads s with spaces to width terminal columns, so columns of text
+// with wide characters still line up.
+func padRight(s string, width int) string {
+	if w := uniseg.StringWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
 	}
-	return "..." + s[len(s)-(maxLen-3):]
+	return s
 }
 
 // formatSessionRow formats a session as a table row
@@ -436,7 +556,7 @@ func formatSessionRow(s adapters.Session, width int) string {
 	// For project names, truncate from the start (show the end with ellipsis at the start)
 	projectCol := truncateStringStart(project, 28)
 
-	return fmt.Sprintf("  %-12s  %-12s  %5s  %-28s  %s", timeCol, agentCol, userMsgCol, projectCol, message)
+	return fmt.Sprintf("  %s  %s  %5s  %s  %s", padRight(timeCol, 12), padRight(agentCol, 12), userMsgCol, padRight(projectCol, 28), message)
 }
 
 // formatTableHeader formats the table header row
@@ -580,7 +700,7 @@ func selectSessionInteractively() (string, error) {
 	fmt.Print("\033[H\033[2J") // Clear screen
 	fmt.Println()
 	fmt.Println("Selected session:")
-	fmt.Printf("  %s\n", selectedSession.FirstMessage)
+	fmt.Printf("  %s\n", adapters.TruncateText(selectedSession.FirstMessage, adapters.DefaultPreviewLength))
 	fmt.Printf("  Project: %s\n", getProjectName(selectedSession.ProjectPath))
 	fmt.Printf("  Agent: %s\n", getAgentDisplayName(selectedSession.Source))
 	fmt.Printf("  User messages: %d\n", selectedSession.UserMessageCount)
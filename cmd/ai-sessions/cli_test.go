@@ -134,6 +134,20 @@ func TestTruncateStringHelpers(t *testing.T) {
 	if got := truncateStringStart("small", 10); got != "small" {
 		t.Fatalf("truncateStringStart no-op failed: %q", got)
 	}
+
+	// Wide characters take two columns, and emoji sequences are never split
+	if got := truncateString("日本語のテキスト", 9); got != "日本語..." {
+		t.Fatalf("truncateString wide characters failed: %q", got)
+	}
+	if got := truncateString("ok 👍🏽👍🏽👍🏽", 8); got != "ok 👍🏽..." {
+		t.Fatalf("truncateString emoji failed: %q", got)
+	}
+	if got := truncateStringStart("~/プロジェクト", 10); got != "...ェクト" {
+		t.Fatalf("truncateStringStart wide characters failed: %q", got)
+	}
+	if got := padRight("日本", 6); got != "日本  " {
+		t.Fatalf("padRight failed: %q", got)
+	}
 }
 
 func TestFormatSessionRow(t *testing.T) {
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const clientsFile = "clients.json"
+
+// Client scopes, from least to most access. Each scope includes the tools of the ones before it.
+const (
+	scopeList   = "list"   // sources, projects, and session metadata
+	scopeSearch = "search" // plus search snippets and aggregates
+	scopeRead   = "read"   // plus full session content; every tool that changes nothing
+	scopeWrite  = "write"  // every tool, including those that change the index, notes, or files
+)
+
+// clientScopes are the client scopes, from least to most access.
+var clientScopes = []string{scopeList, scopeSearch, scopeRead, scopeWrite}
+
+// scopeTools lists the tools each scope adds on top of the previous one.
+// scopeRead allows every tool not listed here, so it needs no entry.
+var scopeTools = map[string][]string{
+	scopeList: {
+		"list_available_sources",
+		"list_projects",
+		"list_sessions",
+		"changes_since",
+		"get_search_syntax",
+		"group_by_task",
+		"get_diagnostics",
+		"index_status",
+	},
+	scopeSearch: {
+		"search_sessions",
+		"search_in_session",
+		"find_sessions_by_file",
+		"file_history",
+		"compare_sessions",
+		"find_related_sessions",
+		"lookup_content_hash",
+		"get_session_stats",
+		"get_session_timeline",
+		"list_files_touched",
+		"list_snapshots",
+		"get_session_personas",
+		"get_agent_usage",
+		"get_model_usage",
+		"get_tool_timings",
+		"get_cost_report",
+		"cost_report",
+		"usage_stats",
+		"storage_report",
+		"diagnose_sources",
+		"detect_todos",
+		"list_bookmarks",
+	},
+	scopeWrite: {
+		"forget_session",
+		"rebuild_index",
+		"reindex_sessions",
+		"cancel_operation",
+		"set_power_mode",
+		"annotate_session",
+		"bookmark_message",
+		"export_snapshot",
+	},
+}
+
+// validScope reports whether scope is one of the known client scopes.
+func validScope(scope string) bool {
+	return slices.Contains(clientScopes, scope)
+}
+
+// scopeAllows reports whether a client with the given scope may call tool.
+func scopeAllows(scope, tool string) bool {
+	rank := slices.Index(clientScopes, scope)
+	if rank < 0 {
+		return false
+	}
+	for i, s := range clientScopes {
+		if slices.Contains(scopeTools[s], tool) {
+			return rank >= i
+		}
+	}
+	return rank >= slices.Index(clientScopes, scopeRead)
+}
+
+// applyClientScope removes the tools a client's scope doesn't allow from the
+// server instance that serves it.
+func applyClientScope(server *mcp.Server, scope string, tools []string) {
+	var denied []string
+	for _, tool := range tools {
+		if !scopeAllows(scope, tool) {
+			denied = append(denied, tool)
+		}
+	}
+	server.RemoveTools(denied...)
+	if !scopeAllows(scope, "get_session") {
+		server.RemoveResourceTemplates(sessionResourceTemplate)
+	}
+}
+
+// registeredClient is a remote MCP client allowed to connect over HTTP.
+// Only a hash of its bearer token is stored.
+type registeredClient struct {
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	TokenHash string     `json:"token_hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+}
+
+// clientRegistry is the set of registered clients in ~/.aisessions/clients.json.
+// Stdio clients are local and need no registration; clients connecting from
+// beyond localhost must present a registered token and are limited to its scope.
+// The file is read again whenever it changes, so a running server sees clients
+// added and removed with "aisessions clients", and every write to it is made
+// under a lock from what is on disk.
+type clientRegistry struct {
+	mu      sync.Mutex
+	path    string
+	clients []registeredClient
+	modTime time.Time // of the file when clients was read
+	size    int64
+}
+
+// lastSeenInterval is how stale a client's last_seen may get before
+// authenticating records it again, so requests don't each rewrite the file.
+const lastSeenInterval = time.Minute
+
+// clientsLockTimeout bounds how long a write waits for the registry lock. A
+// lock older than this is assumed left behind by a crashed process.
+const clientsLockTimeout = 5 * time.Second
+
+// getClientsPath returns the path to the client registry file
+func getClientsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, configDir, clientsFile), nil
+}
+
+// loadClientRegistry reads the registry at path. A missing file means no clients.
+func loadClientRegistry(path string) (*clientRegistry, error) {
+	registry := &clientRegistry{path: path}
+	if err := registry.reload(); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// reload reads the registry file. Callers must hold r.mu.
+func (r *clientRegistry) reload() error {
+	info, err := os.Stat(r.path)
+	if os.IsNotExist(err) {
+		r.clients, r.modTime, r.size = nil, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read clients file: %w", err)
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read clients file: %w", err)
+	}
+
+	var clients []registeredClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return fmt.Errorf("invalid clients file %s: %w", r.path, err)
+	}
+	r.clients, r.modTime, r.size = clients, info.ModTime(), info.Size()
+	return nil
+}
+
+// refresh reloads the registry if the file changed since it was read.
+// Callers must hold r.mu.
+func (r *clientRegistry) refresh() error {
+	info, err := os.Stat(r.path)
+	if os.IsNotExist(err) {
+		if r.clients == nil {
+			return nil
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read clients file: %w", err)
+	} else if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return nil
+	}
+	return r.reload()
+}
+
+// update changes the registry under the file lock, starting from what is on
+// disk, and writes the result. Callers must hold r.mu.
+func (r *clientRegistry) update(change func(clients []registeredClient) ([]registeredClient, error)) error {
+	unlock, err := lockFile(r.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.reload(); err != nil {
+		return err
+	}
+	clients, err := change(slices.Clone(r.clients))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(clients, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal clients: %w", err)
+	}
+	// Replace the file whole, so a server reading it never sees half a write
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), clientsFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write clients file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write clients file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write clients file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to write clients file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to write clients file: %w", err)
+	}
+	return r.reload()
+}
+
+// lockFile takes an exclusive lock by creating path, waiting for another
+// process holding it for up to clientsLockTimeout. It returns a function that
+// releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	deadline := time.Now().Add(clientsLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > clientsLockTimeout {
+			os.Remove(path) // left behind by a process that didn't finish
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// list returns the registered clients sorted by name.
+func (r *clientRegistry) list() []registeredClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.refresh() // a bad file keeps the last good list
+
+	clients := append([]registeredClient(nil), r.clients...)
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+	return clients
+}
+
+// add registers a client and returns its bearer token, which is not stored and
+// cannot be shown again.
+func (r *clientRegistry) add(name, scope string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("client name is required")
+	}
+	if !validScope(scope) {
+		return "", fmt.Errorf("invalid scope %q (expected one of %s)", scope, strings.Join(clientScopes, ", "))
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := "ais_" + hex.EncodeToString(raw)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.update(func(clients []registeredClient) ([]registeredClient, error) {
+		for _, c := range clients {
+			if c.Name == name {
+				return nil, fmt.Errorf("client already registered: %s", name)
+			}
+		}
+		return append(clients, registeredClient{
+			Name:      name,
+			Scope:     scope,
+			TokenHash: hashClientToken(token),
+			CreatedAt: time.Now().UTC(),
+		}), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// remove unregisters a client, revoking its token.
+func (r *clientRegistry) remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(func(clients []registeredClient) ([]registeredClient, error) {
+		for i, c := range clients {
+			if c.Name == name {
+				return slices.Delete(clients, i, i+1), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown client: %s", name)
+	})
+}
+
+// authenticate returns the client a bearer token belongs to, as registered
+// now, and records when it was last seen at most once per lastSeenInterval.
+func (r *clientRegistry) authenticate(token string) (registeredClient, bool) {
+	if token == "" {
+		return registeredClient{}, false
+	}
+	hash := hashClientToken(token)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.refresh(); err != nil {
+		return registeredClient{}, false // fail closed on an unreadable file
+	}
+
+	for _, client := range r.clients {
+		if subtle.ConstantTimeCompare([]byte(client.TokenHash), []byte(hash)) != 1 {
+			continue
+		}
+		now := time.Now().UTC()
+		if client.LastSeen == nil || now.Sub(*client.LastSeen) >= lastSeenInterval {
+			// Last-seen is informational; failing to persist it must not lock the client out
+			_ = r.update(func(clients []registeredClient) ([]registeredClient, error) {
+				for i := range clients {
+					if clients[i].Name == client.Name {
+						clients[i].LastSeen = &now
+					}
+				}
+				return clients, nil
+			})
+			client.LastSeen = &now
+		}
+		return client, true
+	}
+	return registeredClient{}, false
+}
+
+// hashClientToken returns the stored form of a client token.
+func hashClientToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleClientsCommand processes: aisessions clients [add <name> --scope <scope> | remove <name>]
+func handleClientsCommand() {
+	path, err := getClientsPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	registry, err := loadClientRegistry(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	args := os.Args[2:]
+	if len(args) == 0 || args[0] == "list" {
+		printClients(registry.list())
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		scope := scopeSearch
+		var name string
+		for i := 1; i < len(args); i++ {
+			if args[i] == "--scope" {
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "Error: --scope requires a value\n")
+					os.Exit(1)
+				}
+				scope = args[i+1]
+				i++
+			} else if name == "" {
+				name = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Unknown argument: %s\n", args[i])
+				os.Exit(1)
+			}
+		}
+
+		token, err := registry.add(name, scope)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Registered client %q with scope %q.\n", name, scope)
+		fmt.Println("Bearer token (shown once, store it in the client's configuration):")
+		fmt.Println()
+		fmt.Printf("  %s\n", token)
+	case "remove":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: aisessions clients remove <name>\n")
+			os.Exit(1)
+		}
+		if err := registry.remove(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed client %q; its token no longer works.\n", args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: aisessions clients [list | add <name> --scope list|search|read|write | remove <name>]\n")
+		os.Exit(1)
+	}
+}
+
+// printClients shows registered clients and when each last connected.
+func printClients(clients []registeredClient) {
+	if len(clients) == 0 {
+		fmt.Println("No registered clients. Add one with: aisessions clients add <name> --scope list|search|read|write")
+		return
+	}
+
+	fmt.Printf("%-24s %-8s %-20s %s\n", "NAME", "SCOPE", "REGISTERED", "LAST SEEN")
+	for _, c := range clients {
+		lastSeen := "never"
+		if c.LastSeen != nil {
+			lastSeen = formatRelativeTime(*c.LastSeen)
+		}
+		fmt.Printf("%-24s %-8s %-20s %s\n", truncateString(c.Name, 24), c.Scope, c.CreatedAt.Local().Format("2006-01-02 15:04"), lastSeen)
+	}
+}
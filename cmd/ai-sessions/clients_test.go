@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestClientRegistryLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	registry, err := loadClientRegistry(path)
+	if err != nil {
+		t.Fatalf("loadClientRegistry failed: %v", err)
+	}
+
+	token, err := registry.add("laptop", scopeSearch)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if _, err := registry.add("laptop", scopeRead); err == nil {
+		t.Fatal("expected duplicate client name to be rejected")
+	}
+	if _, err := registry.add("tablet", "admin"); err == nil {
+		t.Fatal("expected unknown scope to be rejected")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read clients file: %v", err)
+	}
+	if strings.Contains(string(data), token) || !strings.Contains(string(data), hashClientToken(token)) {
+		t.Fatal("expected clients file to store only a token hash")
+	}
+
+	reloaded, err := loadClientRegistry(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	client, ok := reloaded.authenticate(token)
+	if !ok || client.Name != "laptop" || client.Scope != scopeSearch || client.LastSeen == nil {
+		t.Fatalf("expected token to authenticate laptop, got %+v, %v", client, ok)
+	}
+	if _, ok := reloaded.authenticate("ais_wrong"); ok {
+		t.Fatal("expected unknown token to be rejected")
+	}
+
+	if err := reloaded.remove("laptop"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, ok := reloaded.authenticate(token); ok {
+		t.Fatal("expected removed client's token to be rejected")
+	}
+}
+
+func TestClientRegistrySharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	server, err := loadClientRegistry(path)
+	if err != nil {
+		t.Fatalf("loadClientRegistry failed: %v", err)
+	}
+	cli, err := loadClientRegistry(path)
+	if err != nil {
+		t.Fatalf("loadClientRegistry failed: %v", err)
+	}
+
+	// A client added from the command line works on the running server
+	token, err := cli.add("laptop", scopeSearch)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if _, ok := server.authenticate(token); !ok {
+		t.Fatal("expected a client added elsewhere to authenticate")
+	}
+	seen, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read clients file: %v", err)
+	}
+	if _, ok := server.authenticate(token); !ok {
+		t.Fatal("expected the client to authenticate again")
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(seen) {
+		t.Fatal("expected last_seen to be recorded at most once a minute")
+	}
+
+	// Removing it revokes the token at once, and the server doesn't write it back
+	if err := cli.remove("laptop"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, ok := server.authenticate(token); ok {
+		t.Fatal("expected a client removed elsewhere to be rejected")
+	}
+	if clients := cli.list(); len(clients) != 0 {
+		t.Fatalf("expected the removed client to stay removed, got %+v", clients)
+	}
+}
+
+func TestApplyClientScope(t *testing.T) {
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, nil)}
+	tools := []string{"list_sessions", "search_in_session", "get_session", "forget_session"}
+
+	for scope, want := range map[string][]bool{
+		scopeList:   {true, false, false, false},
+		scopeSearch: {true, true, false, false},
+		scopeRead:   {true, true, true, false},
+		scopeWrite:  {true, true, true, true},
+	} {
+		server := mcp.NewServer(&mcp.Implementation{Name: "ai-sessions", Version: "test"}, nil)
+		addListSessionsTool(server, adaptersMap, nil, nil)
+		addSearchInSessionTool(server, adaptersMap, nil)
+		addGetSessionTool(server, adaptersMap, nil, nil)
+		addForgetSessionTool(server, adaptersMap, nil, nil)
+		applyClientScope(server, scope, tools)
+
+		available := listServerTools(t, server)
+		for i, tool := range tools {
+			if available[tool] != want[i] {
+				t.Errorf("scope %s: %s available = %v, want %v", scope, tool, available[tool], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// comparedSession summarizes one side of a session comparison.
+type comparedSession struct {
+	SessionID       string     `json:"session_id"`
+	Source          string     `json:"source"`
+	ProjectPath     string     `json:"project_path,omitempty"`
+	FirstMessage    string     `json:"first_message,omitempty"`
+	MessageCount    int        `json:"message_count"`
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Models          []string   `json:"models"`
+	FileCount       int        `json:"file_count"`
+
+	files   []string
+	content string
+}
+
+// sessionComparison is the result of compare_sessions.
+type sessionComparison struct {
+	A           comparedSession  `json:"a"`
+	B           comparedSession  `json:"b"`
+	SameProject bool             `json:"same_project"`
+	Files       fileComparison   `json:"files"`
+	Keywords    []search.Keyword `json:"shared_keywords"`
+	Similarity  float64          `json:"keyword_similarity"` // Jaccard similarity of the two sessions' terms
+	Models      []string         `json:"shared_models"`
+	Time        timeComparison   `json:"time"`
+}
+
+type fileComparison struct {
+	Shared []string `json:"shared"`
+	OnlyA  []string `json:"only_a"`
+	OnlyB  []string `json:"only_b"`
+}
+
+type timeComparison struct {
+	Order          string  `json:"order,omitempty"`           // "a_first", "b_first", or empty when unknown
+	Overlapping    bool    `json:"overlapping"`               // the sessions were active at the same time
+	GapSeconds     float64 `json:"gap_seconds,omitempty"`     // from the end of the first to the start of the second
+	OverlapSeconds float64 `json:"overlap_seconds,omitempty"` // how long both were active
+}
+
+// loadComparedSession reads a whole session and summarizes it for comparison.
+func loadComparedSession(adapter adapters.SessionAdapter, sessionID string) (comparedSession, error) {
+	messages, err := adapter.GetSession(sessionID, 0, 100000) // Get all messages
+	if err != nil {
+		return comparedSession{}, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+
+	session := lookupSession(adapter, sessionID)
+	stats := adapters.ComputeSessionStats(messages)
+
+	var files []string
+	for _, f := range resolveFilePaths(adapters.ComputeFilesTouched(messages), session.ProjectPath) {
+		files = append(files, f.Path)
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	contentParts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Content != "" {
+			contentParts = append(contentParts, msg.Content)
+		}
+	}
+
+	return comparedSession{
+		SessionID:       sessionID,
+		Source:          adapter.Name(),
+		ProjectPath:     session.ProjectPath,
+		FirstMessage:    adapters.TruncateText(session.FirstMessage, adapters.DefaultPreviewLength),
+		MessageCount:    stats.MessageCount,
+		StartTime:       stats.StartTime,
+		EndTime:         stats.EndTime,
+		DurationSeconds: stats.DurationSeconds,
+		Models:          stats.Models,
+		FileCount:       len(files),
+		files:           files,
+		content:         strings.Join(contentParts, " "),
+	}, nil
+}
+
+// compareSessions compares two summarized sessions. Shared keywords are weighted
+// against the search index, so terms common to most sessions rank low.
+func compareSessions(cache search.Store, a, b comparedSession) (sessionComparison, error) {
+	keywords, err := cache.SharedKeywords(a.content, b.content, 25)
+	if err != nil {
+		return sessionComparison{}, err
+	}
+
+	comparison := sessionComparison{
+		A:           a,
+		B:           b,
+		SameProject: a.ProjectPath != "" && a.ProjectPath == b.ProjectPath,
+		Files:       fileComparison{Shared: []string{}, OnlyA: []string{}, OnlyB: []string{}},
+		Keywords:    keywords,
+		Similarity:  math.Round(search.Jaccard(search.Tokenize(a.content), search.Tokenize(b.content))*1000) / 1000,
+		Models:      []string{},
+		Time:        compareTimes(a, b),
+	}
+
+	for _, path := range a.files {
+		if _, found := slices.BinarySearch(b.files, path); found {
+			comparison.Files.Shared = append(comparison.Files.Shared, path)
+		} else {
+			comparison.Files.OnlyA = append(comparison.Files.OnlyA, path)
+		}
+	}
+	for _, path := range b.files {
+		if _, found := slices.BinarySearch(a.files, path); !found {
+			comparison.Files.OnlyB = append(comparison.Files.OnlyB, path)
+		}
+	}
+
+	for _, model := range a.Models {
+		if slices.Contains(b.Models, model) {
+			comparison.Models = append(comparison.Models, model)
+		}
+	}
+
+	return comparison, nil
+}
+
+// compareTimes orders two sessions and measures the gap or overlap between them.
+func compareTimes(a, b comparedSession) timeComparison {
+	if a.StartTime == nil || a.EndTime == nil || b.StartTime == nil || b.EndTime == nil {
+		return timeComparison{}
+	}
+
+	var t timeComparison
+	first, second := a, b
+	t.Order = "a_first"
+	if b.StartTime.Before(*a.StartTime) {
+		first, second = b, a
+		t.Order = "b_first"
+	}
+
+	if second.StartTime.Before(*first.EndTime) {
+		t.Overlapping = true
+		end := *first.EndTime
+		if second.EndTime.Before(end) {
+			end = *second.EndTime
+		}
+		t.OverlapSeconds = end.Sub(*second.StartTime).Seconds()
+	} else {
+		t.GapSeconds = second.StartTime.Sub(*first.EndTime).Seconds()
+	}
+	return t
+}
+
+// Tool 24: compare_sessions
+type compareSessionsArgs struct {
+	SessionA string `json:"session_a" jsonschema:"The first session ID"`
+	SourceA  string `json:"source_a" jsonschema:"The source that created the first session (claude, gemini, codex, opencode, mistral, copilot)"`
+	SessionB string `json:"session_b" jsonschema:"The second session ID"`
+	SourceB  string `json:"source_b" jsonschema:"The source that created the second session"`
+}
+
+func addCompareSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "compare_sessions",
+		Description: "Compare two sessions, possibly from different sources: overlapping and distinct files, shared keywords, time ranges, and models used. Useful when resuming work started in another tool.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args compareSessionsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionA == "" || args.SessionB == "" {
+			return nil, nil, fmt.Errorf("session_a and session_b are required")
+		}
+		if args.SourceA == "" || args.SourceB == "" {
+			return nil, nil, fmt.Errorf("source_a and source_b are required")
+		}
+
+		var sides [2]comparedSession
+		for i, ref := range [2][2]string{{args.SessionA, args.SourceA}, {args.SessionB, args.SourceB}} {
+			adapter, ok := adaptersMap[ref[1]]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(ref[1])
+			}
+			if consent != nil {
+				if projectPath, found := findSessionProject(adapter, ref[0]); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+					return nil, nil, sessionConsentError(ref[0], projectPath, found)
+				}
+			}
+			side, err := loadComparedSession(adapter, ref[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			sides[i] = side
+		}
+
+		// Keyword weights come from the index, so make sure it's populated
+		if err := indexSessions(adaptersMap, searchCache, "", ""); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+
+		comparison, err := compareSessions(searchCache, sides[0], sides[1])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestCompareSessions(t *testing.T) {
+	cache, err := search.NewMemoryCache()
+	if err != nil {
+		t.Fatalf("NewMemoryCache failed: %v", err)
+	}
+	defer cache.Close()
+
+	at := func(hour int) *time.Time {
+		ts := time.Date(2025, 3, 1, hour, 0, 0, 0, time.UTC)
+		return &ts
+	}
+	a := comparedSession{
+		SessionID: "a", Source: "claude", ProjectPath: "/src/app",
+		StartTime: at(9), EndTime: at(11), Models: []string{"claude-sonnet-4", "gpt-5"},
+		files:   []string{"/src/app/auth.go", "/src/app/main.go"},
+		content: "refactor the oauth middleware and token refresh",
+	}
+	b := comparedSession{
+		SessionID: "b", Source: "codex", ProjectPath: "/src/app",
+		StartTime: at(13), EndTime: at(14), Models: []string{"gpt-5"},
+		files:   []string{"/src/app/auth.go", "/src/app/auth_test.go"},
+		content: "finish the oauth token refresh tests",
+	}
+
+	comparison, err := compareSessions(cache, a, b)
+	if err != nil {
+		t.Fatalf("compareSessions failed: %v", err)
+	}
+
+	if !comparison.SameProject {
+		t.Error("expected sessions in the same project")
+	}
+	if !slices.Equal(comparison.Files.Shared, []string{"/src/app/auth.go"}) ||
+		!slices.Equal(comparison.Files.OnlyA, []string{"/src/app/main.go"}) ||
+		!slices.Equal(comparison.Files.OnlyB, []string{"/src/app/auth_test.go"}) {
+		t.Errorf("unexpected file comparison: %+v", comparison.Files)
+	}
+	if !slices.Equal(comparison.Models, []string{"gpt-5"}) {
+		t.Errorf("expected shared model gpt-5, got %v", comparison.Models)
+	}
+
+	var terms []string
+	for _, k := range comparison.Keywords {
+		terms = append(terms, k.Term)
+	}
+	for _, want := range []string{"oauth", "token", "refresh"} {
+		if !slices.Contains(terms, want) {
+			t.Errorf("expected shared keyword %q, got %v", want, terms)
+		}
+	}
+	if comparison.Similarity <= 0 || comparison.Similarity >= 1 {
+		t.Errorf("expected partial keyword similarity, got %v", comparison.Similarity)
+	}
+
+	if comparison.Time.Order != "a_first" || comparison.Time.Overlapping || comparison.Time.GapSeconds != 2*3600 {
+		t.Errorf("unexpected time comparison: %+v", comparison.Time)
+	}
+	b.StartTime = at(10)
+	if got := compareTimes(a, b); !got.Overlapping || got.OverlapSeconds != 3600 {
+		t.Errorf("expected a one hour overlap, got %+v", got)
+	}
+}
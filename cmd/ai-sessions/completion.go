@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// MCP completion applies to prompt and resource template arguments, not to
+// tool arguments, so sessions are also exposed as a resource template whose
+// source and session_id arguments clients can complete, with the same values
+// get_session takes.
+const sessionResourceTemplate = "aisessions://session/{source}/{session_id}"
+
+const (
+	// maxCompletions is the most values a completion may hold, per the protocol.
+	maxCompletions = 100
+	// completionSessionsPerSource caps how many recent sessions each source
+	// lists when completing a session ID, so completion stays quick.
+	completionSessionsPerSource = 200
+	// completionPreviewLength is how much of a session's first message is
+	// shown beside its completed ID.
+	completionPreviewLength = 80
+)
+
+// addSessionResource registers the session resource template, which reads
+// the first page of a session like get_session.
+func addSessionResource(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "session",
+		Title:       "AI session",
+		Description: "The first page of a session's messages, as get_session returns it. The source and session_id arguments support completion.",
+		MIMEType:    "application/json",
+		URITemplate: sessionResourceTemplate,
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		source, sessionID, ok := parseSessionURI(req.Params.URI)
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		result, err := readSessionPage(ctx, req.Session, adaptersMap, searchCache, consent, getSessionArgs{SessionID: sessionID, Source: sourceFilter{source}}, nil)
+		if err != nil {
+			return nil, err
+		}
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+			{URI: req.Params.URI, MIMEType: "application/json", Text: string(resultJSON)},
+		}}, nil
+	})
+}
+
+// parseSessionURI splits a session resource URI into its source and session ID.
+func parseSessionURI(uri string) (source, sessionID string, ok bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "aisessions" || u.Host != "session" {
+		return "", "", false
+	}
+	source, sessionID, ok = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	return source, sessionID, ok && source != "" && sessionID != ""
+}
+
+// completeArguments answers completion requests for source and session_id
+// arguments, whichever prompt or resource template they belong to. Sources
+// complete by name prefix. Session IDs complete with the most recent sessions
+// whose ID starts with, or whose first message contains, what was typed, from
+// the source already chosen if there is one. Each ID's first-message preview
+// is in the result's _meta under "previews", for clients that can show it.
+func completeArguments(adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) func(context.Context, *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	return func(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+		argument := req.Params.Argument
+		var values []string
+		result := &mcp.CompleteResult{}
+		switch argument.Name {
+		case "source":
+			values = completeSources(adaptersMap, argument.Value)
+		case "session_id":
+			var source string
+			if req.Params.Context != nil {
+				source = req.Params.Context.Arguments["source"]
+			}
+			sessions, err := completeSessions(ctx, adaptersMap, consent, source, argument.Value)
+			if err != nil {
+				return nil, err
+			}
+			previews := make(map[string]string)
+			for _, session := range sessions {
+				values = append(values, session.ID)
+				if len(previews) < maxCompletions {
+					previews[session.ID] = adapters.TruncateText(session.FirstMessage, completionPreviewLength)
+				}
+			}
+			result.Meta = mcp.Meta{"previews": previews}
+		}
+
+		result.Completion = mcp.CompletionResultDetails{Values: values, Total: len(values)}
+		if len(values) > maxCompletions {
+			result.Completion.Values = values[:maxCompletions]
+			result.Completion.HasMore = true
+		}
+		if result.Completion.Values == nil {
+			result.Completion.Values = []string{}
+		}
+		return result, nil
+	}
+}
+
+// completeSources returns the available sources whose name starts with prefix.
+func completeSources(adaptersMap map[string]adapters.SessionAdapter, prefix string) []string {
+	var values []string
+	for name := range adaptersMap {
+		if strings.HasPrefix(name, strings.ToLower(prefix)) {
+			values = append(values, name)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// completeSessions returns recent sessions, newest first, whose ID starts
+// with typed or whose first message contains it. Sessions from projects the
+// client hasn't been approved for are left out rather than prompted for.
+func completeSessions(ctx context.Context, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent, source, typed string) ([]adapters.Session, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, nil
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	listed, err := listSessionsConcurrently(ctx, adaptersToQuery, "", completionSessionsPerSource)
+	if err != nil {
+		return nil, err
+	}
+	sessions := flattenSessions(listed)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Timestamp.After(sessions[j].Timestamp)
+	})
+
+	typed = strings.ToLower(typed)
+	var matches []adapters.Session
+	for _, session := range sessions {
+		if !consent.approved(session.ProjectPath) {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(session.ID), typed) || strings.Contains(strings.ToLower(session.FirstMessage), typed) {
+			matches = append(matches, session)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCompleteSessionArguments(t *testing.T) {
+	now := time.Now()
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(
+		[]adapters.Session{
+			{ID: "abc-older", Source: "stub", FirstMessage: "fix the parser", Timestamp: now.Add(-time.Hour)},
+			{ID: "abc-newer", Source: "stub", FirstMessage: "add a flag", Timestamp: now},
+		},
+		map[string][]adapters.Message{"abc-newer": {{Role: "user", Content: "add a flag"}}},
+	)}
+	cache := newTestCache(t)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ServerOptions{
+		CompletionHandler: completeArguments(adaptersMap, nil),
+	})
+	addSessionResource(server, adaptersMap, cache, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	// complete returns the values suggested for an argument of the session resource
+	complete := func(name, value string, known map[string]string) *mcp.CompleteResult {
+		t.Helper()
+		params := &mcp.CompleteParams{
+			Ref:      &mcp.CompleteReference{Type: "ref/resource", URI: sessionResourceTemplate},
+			Argument: mcp.CompleteParamsArgument{Name: name, Value: value},
+		}
+		if known != nil {
+			params.Context = &mcp.CompleteContext{Arguments: known}
+		}
+		result, err := clientSession.Complete(ctx, params)
+		if err != nil {
+			t.Fatalf("Complete(%s=%q): %v", name, value, err)
+		}
+		return result
+	}
+
+	if got := complete("source", "st", nil).Completion.Values; !reflect.DeepEqual(got, []string{"stub"}) {
+		t.Fatalf("expected source completion [stub], got %v", got)
+	}
+	if got := complete("source", "x", nil).Completion.Values; len(got) != 0 {
+		t.Fatalf("expected no source completions, got %v", got)
+	}
+
+	// Session IDs complete newest first, with previews of their first messages
+	result := complete("session_id", "abc", map[string]string{"source": "stub"})
+	if got := result.Completion.Values; !reflect.DeepEqual(got, []string{"abc-newer", "abc-older"}) {
+		t.Fatalf("expected newest session first, got %v", got)
+	}
+	previews, _ := result.Meta["previews"].(map[string]any)
+	if previews["abc-older"] != "fix the parser" {
+		t.Fatalf("expected a first-message preview, got %v", result.Meta)
+	}
+
+	// What was typed also matches first messages
+	if got := complete("session_id", "parser", nil).Completion.Values; !reflect.DeepEqual(got, []string{"abc-older"}) {
+		t.Fatalf("expected a first-message match, got %v", got)
+	}
+	if got := complete("session_id", "abc", map[string]string{"source": "missing"}).Completion.Values; len(got) != 0 {
+		t.Fatalf("expected no completions for an unknown source, got %v", got)
+	}
+
+	// A completed session reads through the resource
+	read, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "aisessions://session/stub/abc-newer"})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, "add a flag") {
+		t.Fatalf("unexpected session resource contents: %+v", read.Contents)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+	"golang.org/x/sync/singleflight"
+)
+
+// Served over HTTP, several clients can ask for the same indexing at once.
+// Identical runs share one pass through indexFlights, and sessionLocks keeps
+// overlapping runs (a search, the background indexer, a filesystem watcher)
+// from reading and indexing the same changed session twice.
+var (
+	indexFlights singleflight.Group
+	sessionLocks keyedMutex
+)
+
+// indexSessionsShared indexes sessions like indexSessionsContext, joining an
+// identical run that is already in progress instead of starting another. The
+// run isn't stopped when ctx is cancelled, since other callers may be waiting
+// on it; the caller just stops waiting.
+func indexSessionsShared(ctx context.Context, adaptersMap map[string]adapters.SessionAdapter, cache search.Store, source string, projectPath string) error {
+	key := fmt.Sprintf("%p\x00%p\x00%s\x00%s", adaptersMap, cache, source, projectPath)
+	done := indexFlights.DoChan(key, func() (interface{}, error) {
+		return nil, indexSessionsContext(context.WithoutCancel(ctx), adaptersMap, cache, source, projectPath, indexOptions{})
+	})
+	select {
+	case result := <-done:
+		return result.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// keyedMutex is a set of mutexes, one per key, created as they are needed and
+// dropped once nothing holds or waits for them.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int // holders and waiters
+}
+
+// lock locks key, waiting while another goroutine holds it, and returns the
+// function that unlocks it.
+func (k *keyedMutex) lock(key string) (unlock func()) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
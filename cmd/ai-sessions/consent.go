@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+const consentFile = "consent.json"
+
+// consentDecisions is the on-disk record of per-project consent answers.
+type consentDecisions struct {
+	Allowed []string `json:"allowed,omitempty"`
+	Denied  []string `json:"denied,omitempty"`
+}
+
+// projectConsent gates the first exposure of each project's sessions to a client.
+// Decisions come from allowed_projects in the server config, from answers saved in
+// ~/.aisessions/consent.json, or from an elicitation prompt when the client supports it.
+type projectConsent struct {
+	mu        sync.Mutex
+	path      string
+	decisions map[string]bool
+}
+
+// newProjectConsent returns a consent gate, or nil if consent is not required.
+func newProjectConsent(config *ServerConfig) (*projectConsent, error) {
+	if !config.RequireProjectConsent {
+		return nil, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	consent := &projectConsent{
+		path:      filepath.Join(homeDir, configDir, consentFile),
+		decisions: make(map[string]bool),
+	}
+	if err := consent.load(); err != nil {
+		return nil, err
+	}
+	for _, project := range config.AllowedProjects {
+		consent.decisions[filepath.Clean(project)] = true
+	}
+
+	return consent, nil
+}
+
+// load reads saved decisions. A missing file means no decisions yet.
+func (c *projectConsent) load() error {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read consent file: %w", err)
+	}
+
+	var saved consentDecisions
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid consent file %s: %w", c.path, err)
+	}
+	for _, project := range saved.Allowed {
+		c.decisions[project] = true
+	}
+	for _, project := range saved.Denied {
+		c.decisions[project] = false
+	}
+	return nil
+}
+
+// save writes all decisions to disk. Callers must hold c.mu.
+func (c *projectConsent) save() error {
+	var saved consentDecisions
+	for project, allowed := range c.decisions {
+		if allowed {
+			saved.Allowed = append(saved.Allowed, project)
+		} else {
+			saved.Denied = append(saved.Denied, project)
+		}
+	}
+	sort.Strings(saved.Allowed)
+	sort.Strings(saved.Denied)
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write consent file: %w", err)
+	}
+	return nil
+}
+
+// allowed reports whether sessions from projectPath may be returned, asking the
+// user through the client if no decision exists yet. Sessions without a project
+// path are always allowed since there is no repository to protect.
+func (c *projectConsent) allowed(ctx context.Context, session *mcp.ServerSession, projectPath string) bool {
+	if c == nil || projectPath == "" {
+		return true
+	}
+	projectPath = filepath.Clean(projectPath)
+
+	c.mu.Lock()
+	allowed, decided := c.decisions[projectPath]
+	c.mu.Unlock()
+	if decided {
+		return allowed
+	}
+	if !supportsElicitation(session) {
+		return false
+	}
+
+	// The prompt waits on the user, so it runs without c.mu to keep other
+	// requests from blocking behind it
+	result, err := session.Elicit(ctx, &mcp.ElicitParams{
+		Message:         fmt.Sprintf("Allow this client to read AI session history from %s?", projectPath),
+		RequestedSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+	})
+	if err != nil {
+		log.Printf("Warning: consent prompt failed for %s: %v", projectPath, err)
+		return false
+	}
+
+	switch result.Action {
+	case "accept":
+		allowed = true
+	case "decline":
+		allowed = false
+	default:
+		// Dismissed without a choice: withhold for now and ask again next time
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.decisions[projectPath] = allowed
+	if err := c.save(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return allowed
+}
+
+// allowedSession is allowed for a session looked up by ID. found is false when the
+// session couldn't be found; its project is then unknown rather than empty, so the
+// session is withheld instead of being treated as belonging to no project.
+func (c *projectConsent) allowedSession(ctx context.Context, session *mcp.ServerSession, projectPath string, found bool) bool {
+	if c == nil {
+		return true
+	}
+	return found && c.allowed(ctx, session, projectPath)
+}
+
+// approved reports whether sessions from projectPath may be returned without
+// asking, for requests such as completions that can't stop to prompt the user.
+func (c *projectConsent) approved(projectPath string) bool {
+	if c == nil || projectPath == "" {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.decisions[filepath.Clean(projectPath)]
+}
+
+// filterSessions drops sessions from projects without consent and returns the
+// withheld project paths so callers can tell the client what was hidden.
+func (c *projectConsent) filterSessions(ctx context.Context, session *mcp.ServerSession, sessions []adapters.Session) ([]adapters.Session, []string) {
+	return c.takeSessions(ctx, session, sessions, 0)
+}
+
+// takeSessions is filterSessions for the first limit allowed sessions: it
+// walks sessions in order and stops once limit are kept, so projects past
+// that point are never prompted for. A limit of 0 keeps every allowed session.
+func (c *projectConsent) takeSessions(ctx context.Context, session *mcp.ServerSession, sessions []adapters.Session, limit int) ([]adapters.Session, []string) {
+	if c == nil {
+		if limit > 0 && len(sessions) > limit {
+			sessions = sessions[:limit]
+		}
+		return sessions, nil
+	}
+
+	verdicts := make(map[string]bool)
+	var withheld []string
+	filtered := make([]adapters.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if limit > 0 && len(filtered) == limit {
+			break
+		}
+		allowed, seen := verdicts[s.ProjectPath]
+		if !seen {
+			allowed = c.allowed(ctx, session, s.ProjectPath)
+			verdicts[s.ProjectPath] = allowed
+			if !allowed {
+				withheld = append(withheld, s.ProjectPath)
+			}
+		}
+		if allowed {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, withheld
+}
+
+// supportsElicitation reports whether the connected client can answer prompts.
+func supportsElicitation(session *mcp.ServerSession) bool {
+	if session == nil {
+		return false
+	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+// findSessionProject returns the project path recorded for a session. found is
+// false when the session can't be found, in which case its project is unknown.
+func findSessionProject(adapter adapters.SessionAdapter, sessionID string) (string, bool) {
+	session, found := findSession(adapter, sessionID)
+	return session.ProjectPath, found
+}
+
+// sessionConsentError explains why allowedSession withheld a session.
+func sessionConsentError(sessionID, projectPath string, found bool) error {
+	if !found {
+		return fmt.Errorf("session %s was not found, so its project can't be checked for approval", sessionID)
+	}
+	return fmt.Errorf("sessions from project %s have not been approved for this client", projectPath)
+}
+
+// appendUnique appends value to values unless it is already present.
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
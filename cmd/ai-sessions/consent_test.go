@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// callJSONTool calls a tool that returns JSON text and decodes it into out.
+func callJSONTool(t *testing.T, clientSession *mcp.ClientSession, name string, args map[string]interface{}, out interface{}) {
+	t.Helper()
+	result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s): %v", name, err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error from %s: %v", name, result.Content[0].(*mcp.TextContent).Text)
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), out); err != nil {
+		t.Fatalf("unmarshal %s result: %v", name, err)
+	}
+}
+
+func TestProjectConsentDisabled(t *testing.T) {
+	consent, err := newProjectConsent(&ServerConfig{})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+	if consent != nil {
+		t.Fatalf("expected nil consent gate when consent is not required")
+	}
+
+	sessions := []adapters.Session{{ID: "a", ProjectPath: "/private"}}
+	filtered, withheld := consent.filterSessions(context.Background(), nil, sessions)
+	if len(filtered) != 1 || len(withheld) != 0 {
+		t.Fatalf("disabled gate should pass everything through, got %v withheld %v", filtered, withheld)
+	}
+}
+
+func TestProjectConsentFiltersUndecidedProjects(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	saved := `{"allowed": ["/saved"], "denied": ["/denied"]}`
+	if err := os.MkdirAll(filepath.Join(home, configDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, configDir, consentFile), []byte(saved), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/configured/"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	sessions := []adapters.Session{
+		{ID: "1", ProjectPath: "/configured"},
+		{ID: "2", ProjectPath: "/saved"},
+		{ID: "3", ProjectPath: "/denied"},
+		{ID: "4", ProjectPath: "/unknown"},
+		{ID: "5", ProjectPath: "/unknown"},
+		{ID: "6"},
+	}
+
+	// Without a client session there is no way to ask, so undecided projects are withheld
+	filtered, withheld := consent.filterSessions(context.Background(), nil, sessions)
+
+	var ids []string
+	for _, s := range filtered {
+		ids = append(ids, s.ID)
+	}
+	if len(ids) != 3 || ids[0] != "1" || ids[1] != "2" || ids[2] != "6" {
+		t.Fatalf("unexpected sessions returned: %v", ids)
+	}
+	if len(withheld) != 2 || withheld[0] != "/denied" || withheld[1] != "/unknown" {
+		t.Fatalf("unexpected withheld projects: %v", withheld)
+	}
+}
+
+func TestProjectConsentDeniesUnresolvedSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/configured"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	adapter := newStubAdapter([]adapters.Session{
+		{ID: "approved", ProjectPath: "/configured"},
+		{ID: "no-project"},
+	}, nil)
+	failing := newStubAdapter(nil, nil)
+	failing.listErr = fmt.Errorf("listing failed")
+
+	tests := []struct {
+		name      string
+		adapter   adapters.SessionAdapter
+		sessionID string
+		want      bool
+	}{
+		{"approved project", adapter, "approved", true},
+		{"session without a project", adapter, "no-project", true},
+		{"unknown session", adapter, "missing", false},
+		{"listing fails", failing, "approved", false},
+	}
+	for _, tt := range tests {
+		projectPath, found := findSessionProject(tt.adapter, tt.sessionID)
+		if got := consent.allowedSession(context.Background(), nil, projectPath, found); got != tt.want {
+			t.Errorf("%s: allowedSession = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListSessionsAppliesConsentBeforeLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/approved"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	now := time.Now()
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter([]adapters.Session{
+		{ID: "newest", Source: "stub", ProjectPath: "/private", Timestamp: now},
+		{ID: "older", Source: "stub", ProjectPath: "/approved", Timestamp: now.Add(-time.Hour)},
+		{ID: "oldest", Source: "stub", ProjectPath: "/later", Timestamp: now.Add(-2 * time.Hour)},
+	}, nil)}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListSessionsTool(server, adaptersMap, newTestCache(t), consent)
+	clientSession := newTestClient(t, server)
+
+	// The withheld newest session must not use up the only slot
+	var listed listSessionsResult
+	callTypedTool(t, clientSession, "list_sessions", map[string]interface{}{"source": "stub", "limit": 1}, &listed)
+	if len(listed.Sessions) != 1 || listed.Sessions[0].ID != "older" {
+		t.Fatalf("expected the approved session to fill the limit, got %+v", listed.Sessions)
+	}
+	// Projects past the limit are never reached, so they aren't asked about
+	if len(listed.WithheldProjects) != 1 || listed.WithheldProjects[0] != "/private" {
+		t.Fatalf("expected only /private to be withheld, got %v", listed.WithheldProjects)
+	}
+}
+
+func TestSearchSessionsAppliesConsentBeforeCounting(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/approved"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	now := time.Now()
+	cache := newTestCache(t)
+	sessions := []adapters.Session{
+		{ID: "private", Source: "stub", ProjectPath: "/private", Timestamp: now, FilePath: sessionFile},
+		{ID: "approved-1", Source: "stub", ProjectPath: "/approved", Timestamp: now, FilePath: sessionFile},
+		{ID: "approved-2", Source: "stub", ProjectPath: "/approved", Timestamp: now, FilePath: sessionFile},
+	}
+	for i, session := range sessions {
+		content := "deploy the api" + strings.Repeat(" filler", i)
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+		if err := cache.IndexMessages(session.ID, []adapters.Message{{Role: "user", Content: content}}); err != nil {
+			t.Fatalf("IndexMessages: %v", err)
+		}
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, nil)}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addSearchSessionsTool(server, adaptersMap, cache, consent, nil, newBackgroundIndexer(adaptersMap, cache))
+	clientSession := newTestClient(t, server)
+
+	var found searchSessionsResult
+	callTypedTool(t, clientSession, "search_sessions", map[string]interface{}{"query": "deploy", "limit": 1}, &found)
+	if found.TotalMatches != 2 || found.Count != 1 || found.Matches[0].Session.ProjectPath != "/approved" {
+		t.Fatalf("expected one approved session out of 2, got %+v", found)
+	}
+	if found.NextCursor == "" || len(found.WithheldProjects) != 1 || found.WithheldProjects[0] != "/private" {
+		t.Fatalf("expected a cursor and the private project withheld, got %+v", found)
+	}
+}
+
+func TestListProjectsWithholdsUnapprovedProjects(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/approved"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter([]adapters.Session{
+		{ID: "a", Source: "stub", ProjectPath: "/approved", Timestamp: time.Now()},
+		{ID: "b", Source: "stub", ProjectPath: "/private", Timestamp: time.Now()},
+	}, nil)}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListProjectsTool(server, adaptersMap, consent)
+	clientSession := newTestClient(t, server)
+
+	var listed struct {
+		Projects []struct {
+			ProjectPath string `json:"project_path"`
+		} `json:"projects"`
+		WithheldProjects []string `json:"withheld_projects"`
+	}
+	callJSONTool(t, clientSession, "list_projects", map[string]interface{}{}, &listed)
+	if len(listed.Projects) != 1 || listed.Projects[0].ProjectPath != "/approved" {
+		t.Fatalf("expected only the approved project, got %+v", listed.Projects)
+	}
+	if len(listed.WithheldProjects) != 1 || listed.WithheldProjects[0] != "/private" {
+		t.Fatalf("expected /private to be withheld, got %v", listed.WithheldProjects)
+	}
+}
+
+func TestLookupContentHashWithholdsUnapprovedProjects(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	consent, err := newProjectConsent(&ServerConfig{
+		RequireProjectConsent: true,
+		AllowedProjects:       []string{"/approved"},
+	})
+	if err != nil {
+		t.Fatalf("newProjectConsent: %v", err)
+	}
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	cache := newTestCache(t)
+	message := adapters.Message{Role: "user", Content: "rotate the signing keys"}
+	message.ContentHash = adapters.HashMessage(message)
+	for _, session := range []adapters.Session{
+		{ID: "a", Source: "stub", ProjectPath: "/approved", FilePath: sessionFile},
+		{ID: "b", Source: "stub", ProjectPath: "/private", FilePath: sessionFile},
+	} {
+		if err := cache.IndexSession(session, message.Content); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+		if err := cache.IndexMessageHashes(session.ID, []adapters.Message{message}); err != nil {
+			t.Fatalf("IndexMessageHashes: %v", err)
+		}
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addLookupContentHashTool(server, cache, consent)
+	clientSession := newTestClient(t, server)
+
+	var found struct {
+		Matches          []search.ContentRef `json:"matches"`
+		WithheldProjects []string            `json:"withheld_projects"`
+	}
+	callJSONTool(t, clientSession, "lookup_content_hash", map[string]interface{}{"hash": message.ContentHash}, &found)
+	if len(found.Matches) != 1 || found.Matches[0].SessionID != "a" {
+		t.Fatalf("expected only the approved session's message, got %+v", found.Matches)
+	}
+	if len(found.WithheldProjects) != 1 || found.WithheldProjects[0] != "/private" {
+		t.Fatalf("expected /private to be withheld, got %v", found.WithheldProjects)
+	}
+}
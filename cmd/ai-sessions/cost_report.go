@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// defaultCostWindow is how far back cost_report looks when since is omitted.
+const defaultCostWindow = 30 * 24 * time.Hour
+
+// costGroup is the cost of one day, project, source, or model.
+type costGroup struct {
+	Name string `json:"name"`
+	adapters.CostReport
+}
+
+// sessionCost is one session's cost within the reported range.
+type sessionCost struct {
+	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
+	ProjectPath  string    `json:"project_path,omitempty"`
+	FirstMessage string    `json:"first_message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	adapters.CostReport
+}
+
+// costBreakdown is a cost report over a date range, with the totals grouped
+// several ways and the sessions it covers.
+type costBreakdown struct {
+	Total     adapters.CostReport `json:"total"`
+	ByDay     []costGroup         `json:"by_day"`
+	BySource  []costGroup         `json:"by_source"`
+	ByProject []costGroup         `json:"by_project"`
+	ByModel   []costGroup         `json:"by_model"`
+	Sessions  []sessionCost       `json:"sessions"`
+}
+
+// costGroups accumulates a tracker per group name.
+type costGroups map[string]*adapters.CostTracker
+
+// add adds one session's entries to the named group.
+func (g costGroups) add(name string, entries []adapters.CostEntry) {
+	tracker, ok := g[name]
+	if !ok {
+		tracker = adapters.NewCostTracker()
+		g[name] = tracker
+	}
+	tracker.AddEntries(entries)
+}
+
+// reports returns each group's report, most expensive first, or in name
+// order when byName is set.
+func (g costGroups) reports(byName bool) []costGroup {
+	groups := make([]costGroup, 0, len(g))
+	for name, tracker := range g {
+		groups = append(groups, costGroup{Name: name, CostReport: tracker.Report()})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if !byName && groups[i].CostUSD != groups[j].CostUSD {
+			return groups[i].CostUSD > groups[j].CostUSD
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// collectCosts reports the cost of model responses timestamped from since up
+// to until. filter decides which listed sessions may be read and returns the
+// projects it withheld. Sessions whose file wasn't written since the range
+// started are skipped without being read. Days are in local time.
+func collectCosts(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, since, until time.Time, filter func([]adapters.Session) ([]adapters.Session, []string)) (costBreakdown, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return costBreakdown{}, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	total := adapters.NewCostTracker()
+	byDay, bySource, byProject, byModel := costGroups{}, costGroups{}, costGroups{}, costGroups{}
+	sessions := []sessionCost{}
+	var withheld []string
+
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, 0) // Get all sessions
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		for _, session := range listed {
+			if session.Timestamp.After(until) {
+				continue
+			}
+			if info, err := os.Stat(session.FilePath); err == nil && info.ModTime().Before(since) && session.Timestamp.Before(since) {
+				continue
+			}
+
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+
+			var inRange []adapters.CostEntry
+			days := make(map[string][]adapters.CostEntry)
+			models := make(map[string][]adapters.CostEntry)
+			for _, entry := range adapters.CostEntries(messages) {
+				if entry.Timestamp.IsZero() {
+					entry.Timestamp = session.Timestamp
+				}
+				if entry.Timestamp.Before(since) || entry.Timestamp.After(until) {
+					continue
+				}
+				inRange = append(inRange, entry)
+				day := entry.Timestamp.Local().Format("2006-01-02")
+				days[day] = append(days[day], entry)
+				model := entry.Model
+				if model == "" {
+					model = "unknown"
+				}
+				models[model] = append(models[model], entry)
+			}
+			if len(inRange) == 0 {
+				continue
+			}
+
+			total.AddEntries(inRange)
+			bySource.add(session.Source, inRange)
+			byProject.add(session.ProjectPath, inRange)
+			for day, entries := range days {
+				byDay.add(day, entries)
+			}
+			for model, entries := range models {
+				byModel.add(model, entries)
+			}
+
+			tracker := adapters.NewCostTracker()
+			tracker.AddEntries(inRange)
+			sessions = append(sessions, sessionCost{
+				SessionID:    session.ID,
+				Source:       session.Source,
+				ProjectPath:  session.ProjectPath,
+				FirstMessage: truncateString(session.FirstMessage, 200),
+				Timestamp:    session.Timestamp,
+				CostReport:   tracker.Report(),
+			})
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CostUSD != sessions[j].CostUSD {
+			return sessions[i].CostUSD > sessions[j].CostUSD
+		}
+		return sessions[i].Timestamp.After(sessions[j].Timestamp)
+	})
+	sort.Strings(withheld)
+
+	return costBreakdown{
+		Total:     total.Report(),
+		ByDay:     byDay.reports(true),
+		BySource:  bySource.reports(false),
+		ByProject: byProject.reports(false),
+		ByModel:   byModel.reports(false),
+		Sessions:  sessions,
+	}, withheld, nil
+}
+
+// Tool 34: cost_report
+type costReportArgs struct {
+	Since       string `json:"since,omitempty" jsonschema:"Start of the range: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h). Default: 30 days ago."`
+	Until       string `json:"until,omitempty" jsonschema:"End of the range: an RFC 3339 time or a date, which includes the whole day. Default: now."`
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for all projects."`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of sessions in the per-session breakdown, most expensive first (default: 50)"`
+}
+
+func addCostReportTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "cost_report",
+		Description: "Report token usage and cost over a date range across all sources, with totals by day, source, project, and model and a per-session breakdown. Only responses within the range count, so a long session is split across the days it ran. Costs not recorded by the source are estimated from token usage at list price.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args costReportArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+
+		now := time.Now()
+		since, until := now.Add(-defaultCostWindow), now
+		var err error
+		if args.Since != "" {
+			if since, err = parseSince(args.Since, now); err != nil {
+				return nil, nil, err
+			}
+		}
+		if args.Until != "" {
+			if until, err = parseSince(args.Until, now); err != nil {
+				return nil, nil, fmt.Errorf("invalid until %q (expected an RFC 3339 time or a date like 2025-03-31)", args.Until)
+			}
+			if _, err := time.Parse("2006-01-02", args.Until); err == nil {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		}
+		if until.Before(since) {
+			return nil, nil, fmt.Errorf("until must not be before since")
+		}
+
+		breakdown, withheld, err := collectCosts(adaptersMap, args.Source, args.ProjectPath, since, until, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sessionCount := len(breakdown.Sessions)
+		if len(breakdown.Sessions) > args.Limit {
+			breakdown.Sessions = breakdown.Sessions[:args.Limit]
+		}
+
+		result := map[string]interface{}{
+			"since":         since.Format(time.RFC3339),
+			"until":         until.Format(time.RFC3339),
+			"total":         breakdown.Total,
+			"by_day":        breakdown.ByDay,
+			"by_source":     breakdown.BySource,
+			"by_project":    breakdown.ByProject,
+			"by_model":      breakdown.ByModel,
+			"sessions":      breakdown.Sessions,
+			"session_count": sessionCount,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
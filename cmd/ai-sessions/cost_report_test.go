@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectCosts(t *testing.T) {
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	outside := day1.AddDate(0, 0, -10)
+
+	reply := func(ts time.Time, model string, cost float64) adapters.Message {
+		return adapters.Message{Role: "assistant", Timestamp: ts, Metadata: map[string]interface{}{"model": model, "cost": cost}}
+	}
+	sessions := []adapters.Session{
+		{ID: "long", Source: "stub", ProjectPath: "/src/app", Timestamp: outside},
+		{ID: "short", Source: "stub", ProjectPath: "/src/lib", Timestamp: day2},
+		{ID: "secret", Source: "stub", ProjectPath: "/src/secret", Timestamp: day1},
+	}
+	messages := map[string][]adapters.Message{
+		"long": {
+			reply(outside, "model-a", 5),
+			reply(day1, "model-a", 1),
+			reply(day2, "model-b", 2),
+		},
+		"short":  {reply(day2, "model-a", 0.5)},
+		"secret": {reply(day1, "model-a", 100)},
+	}
+	adapter := newStubAdapter(sessions, messages)
+
+	filter := func(listed []adapters.Session) ([]adapters.Session, []string) {
+		var kept []adapters.Session
+		for _, s := range listed {
+			if s.ProjectPath != "/src/secret" {
+				kept = append(kept, s)
+			}
+		}
+		return kept, []string{"/src/secret"}
+	}
+	since, until := day1.Add(-time.Hour), day2.Add(time.Hour)
+	breakdown, withheld, err := collectCosts(map[string]adapters.SessionAdapter{"stub": adapter}, "", "", since, until, filter)
+	if err != nil {
+		t.Fatalf("collectCosts failed: %v", err)
+	}
+
+	if math.Abs(breakdown.Total.CostUSD-3.5) > 1e-9 || breakdown.Total.SessionCount != 2 {
+		t.Fatalf("expected $3.50 over 2 sessions, got %+v", breakdown.Total)
+	}
+	if len(withheld) != 1 || withheld[0] != "/src/secret" {
+		t.Fatalf("expected the secret project to be withheld, got %v", withheld)
+	}
+
+	if len(breakdown.ByDay) != 2 || breakdown.ByDay[0].Name != "2025-03-01" || breakdown.ByDay[1].Name != "2025-03-02" {
+		t.Fatalf("expected two days in order, got %+v", breakdown.ByDay)
+	}
+	if math.Abs(breakdown.ByDay[1].CostUSD-2.5) > 1e-9 || breakdown.ByDay[1].SessionCount != 2 {
+		t.Fatalf("expected $2.50 over 2 sessions on the second day, got %+v", breakdown.ByDay[1])
+	}
+
+	if len(breakdown.ByModel) != 2 || breakdown.ByModel[0].Name != "model-b" || math.Abs(breakdown.ByModel[1].CostUSD-1.5) > 1e-9 {
+		t.Fatalf("expected model-b first and $1.50 for model-a, got %+v", breakdown.ByModel)
+	}
+	if len(breakdown.ByProject) != 2 || breakdown.ByProject[0].Name != "/src/app" {
+		t.Fatalf("expected /src/app to be the most expensive project, got %+v", breakdown.ByProject)
+	}
+	if len(breakdown.BySource) != 1 || breakdown.BySource[0].Name != "stub" {
+		t.Fatalf("expected one source, got %+v", breakdown.BySource)
+	}
+
+	if len(breakdown.Sessions) != 2 || breakdown.Sessions[0].SessionID != "long" || math.Abs(breakdown.Sessions[0].CostUSD-3) > 1e-9 {
+		t.Fatalf("expected the long session's in-range cost first, got %+v", breakdown.Sessions)
+	}
+	if adapter.getCalls["secret"] != 0 {
+		t.Fatal("expected the withheld session not to be read")
+	}
+}
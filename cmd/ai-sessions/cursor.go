@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// searchCursor encodes where the next page of search_sessions results starts.
+// It is tied to the search it came from, so a cursor can't silently page
+// through a different query's results.
+func searchCursor(args searchSessionsArgs, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset) + ":" + searchFingerprint(args)))
+}
+
+// parseSearchCursor returns the offset a cursor from searchCursor encodes,
+// checking that it was made for the same search.
+func parseSearchCursor(cursor string, args searchSessionsArgs) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offsetText, fingerprint, ok := strings.Cut(string(decoded), ":")
+	offset, err := strconv.Atoi(offsetText)
+	if !ok || err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	if fingerprint != searchFingerprint(args) {
+		return 0, fmt.Errorf("cursor is from a different search; repeat the query, filters, and ranker it came from")
+	}
+	return offset, nil
+}
+
+// searchFingerprint identifies the arguments that decide which results a
+// search returns and in what order.
+func searchFingerprint(args searchSessionsArgs) string {
+	parts := []string{args.Query, strings.Join(args.Source, ","), args.ProjectPath, args.Ranker, args.Scope, args.Outcome, strconv.FormatBool(args.IncludeToolOutput)}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
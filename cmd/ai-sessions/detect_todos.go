@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// detectedTodo is a piece of follow-up work an assistant wrote down, from the
+// most recent session that mentioned it.
+type detectedTodo struct {
+	Text         string    `json:"text"`
+	Origin       string    `json:"origin"` // "text" for TODO/FIXME notes and checkboxes, "next_steps" for next-steps lists
+	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
+	ProjectPath  string    `json:"project_path,omitempty"`
+	MessageIndex int       `json:"message_index"`
+	Timestamp    time.Time `json:"timestamp"`
+	// Mentions counts the sessions that wrote down the same text
+	Mentions int `json:"mentions"`
+}
+
+// collectTodos detects follow-up work in each source's most recent sessions,
+// up to limit per source, skipping sessions last active before since when it
+// is set. The same text from several sessions is reported once, from the most
+// recent, and results are most recent first. filter decides which listed
+// sessions may be read and returns the projects it withheld.
+func collectTodos(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, since time.Time, limit int, filter func([]adapters.Session) ([]adapters.Session, []string)) ([]detectedTodo, int, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, 0, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	byText := make(map[string]*detectedTodo)
+	sessionCount := 0
+	var withheld []string
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, limit)
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		for _, session := range listed {
+			if !since.IsZero() && session.Timestamp.Before(since) {
+				continue
+			}
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+			sessionCount++
+
+			for _, todo := range adapters.DetectTodos(messages) {
+				ts := messages[todo.MessageIndex].Timestamp
+				if ts.IsZero() {
+					ts = session.Timestamp
+				}
+				key := strings.ToLower(todo.Text)
+				mentions := 1
+				if existing, ok := byText[key]; ok {
+					existing.Mentions++
+					if !ts.After(existing.Timestamp) {
+						continue
+					}
+					mentions = existing.Mentions
+				}
+				byText[key] = &detectedTodo{
+					Text:         todo.Text,
+					Origin:       todo.Origin,
+					SessionID:    session.ID,
+					Source:       session.Source,
+					ProjectPath:  session.ProjectPath,
+					MessageIndex: todo.MessageIndex,
+					Timestamp:    ts,
+					Mentions:     mentions,
+				}
+			}
+		}
+	}
+
+	todos := make([]detectedTodo, 0, len(byText))
+	for _, todo := range byText {
+		todos = append(todos, *todo)
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].Timestamp.Equal(todos[j].Timestamp) {
+			return todos[i].Timestamp.After(todos[j].Timestamp)
+		}
+		if todos[i].SessionID != todos[j].SessionID {
+			return todos[i].SessionID < todos[j].SessionID
+		}
+		return todos[i].MessageIndex < todos[j].MessageIndex
+	})
+	sort.Strings(withheld)
+	return todos, sessionCount, withheld, nil
+}
+
+// Tool 42: detect_todos
+type detectTodosArgs struct {
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include sessions from this project. Leave empty for all projects."`
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	Since       string `json:"since,omitempty" jsonschema:"Only include sessions active since this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h)"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions to scan per source (default: 20)"`
+	MaxResults  int    `json:"max_results,omitempty" jsonschema:"Maximum number of items to return, most recent first (default: 100)"`
+}
+
+func addDetectTodosTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "detect_todos",
+		Description: "Collect the follow-up work assistants wrote down in recent sessions (TODO/FIXME notes, unchecked checkboxes, and items under headings like \"Next steps\" or \"Remaining work\") into a backlog, most recent first, each linked to the session and message it came from",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args detectTodosArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 20
+		}
+		if args.MaxResults == 0 {
+			args.MaxResults = 100
+		}
+
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = parseSince(args.Since, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		todos, sessionCount, withheld, err := collectTodos(adaptersMap, args.Source, args.ProjectPath, since, args.Limit, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		total := len(todos)
+		if len(todos) > args.MaxResults {
+			todos = todos[:args.MaxResults]
+		}
+
+		result := map[string]interface{}{
+			"todos":         todos,
+			"count":         total,
+			"session_count": sessionCount,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectTodos(t *testing.T) {
+	now := time.Now()
+	sessions := []adapters.Session{
+		{ID: "old", Source: "stub", ProjectPath: "/app", Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "new", Source: "stub", ProjectPath: "/app", Timestamp: now},
+	}
+	messages := map[string][]adapters.Message{
+		"old": {{Role: "assistant", Timestamp: now.Add(-2 * time.Hour), Content: "Next steps:\n- Add rate limiting\n- Write docs"}},
+		"new": {{Role: "assistant", Timestamp: now, Content: "TODO: add rate limiting"}},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, messages)}
+	allowAll := func(s []adapters.Session) ([]adapters.Session, []string) { return s, nil }
+
+	todos, sessionCount, _, err := collectTodos(adaptersMap, "", "", time.Time{}, 20, allowAll)
+	if err != nil {
+		t.Fatalf("collectTodos failed: %v", err)
+	}
+	if sessionCount != 2 || len(todos) != 2 {
+		t.Fatalf("expected two distinct todos from two sessions, got %d: %+v", sessionCount, todos)
+	}
+	if first := todos[0]; first.SessionID != "new" || first.Text != "add rate limiting" || first.Origin != "text" || first.Mentions != 2 {
+		t.Fatalf("expected the most recent mention first, counting both sessions, got %+v", first)
+	}
+	if second := todos[1]; second.SessionID != "old" || second.Text != "Write docs" || second.Mentions != 1 {
+		t.Fatalf("unexpected second todo %+v", second)
+	}
+
+	todos, _, _, err = collectTodos(adaptersMap, "", "", now.Add(-time.Hour), 20, allowAll)
+	if err != nil || len(todos) != 1 || todos[0].SessionID != "new" {
+		t.Fatalf("expected since to skip the old session, got %+v (%v)", todos, err)
+	}
+}
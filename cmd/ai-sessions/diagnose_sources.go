@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// validatingAdapter is implemented by adapters that can check a session file
+// strictly against the format they read.
+type validatingAdapter interface {
+	ValidateSession(session adapters.Session) ([]adapters.SchemaDeviation, error)
+}
+
+// sourceDiagnosis is the health of one source's session files.
+type sourceDiagnosis struct {
+	Source    string `json:"source"`
+	Sessions  int    `json:"sessions"`
+	ListError string `json:"list_error,omitempty"`
+	// StrictSupported is false for sources whose adapter has no validator
+	StrictSupported bool            `json:"strict_supported"`
+	Validated       int             `json:"validated,omitempty"`
+	Deviations      int             `json:"deviations,omitempty"`
+	Files           []fileDiagnosis `json:"files,omitempty"`
+}
+
+// fileDiagnosis lists what strict validation found in one session file.
+type fileDiagnosis struct {
+	SessionID  string                     `json:"session_id"`
+	File       string                     `json:"file"`
+	Error      string                     `json:"error,omitempty"`
+	Count      int                        `json:"count,omitempty"`
+	Deviations []adapters.SchemaDeviation `json:"deviations,omitempty"`
+}
+
+// diagnoseOptions controls how much diagnoseSources checks.
+type diagnoseOptions struct {
+	strict        bool
+	sessions      int // most recent sessions validated per source
+	maxDeviations int // deviations listed per file; 0 lists all
+}
+
+// diagnoseSources lists each source's sessions and, in strict mode, validates
+// the most recent ones. filter decides which sessions may be reported and
+// returns the projects it withheld.
+func diagnoseSources(adaptersMap map[string]adapters.SessionAdapter, source string, opts diagnoseOptions, filter func([]adapters.Session) ([]adapters.Session, []string)) ([]sourceDiagnosis, []string, error) {
+	names := make([]string, 0, len(adaptersMap))
+	if source != "" {
+		if _, ok := adaptersMap[source]; !ok {
+			return nil, nil, adapters.SourceUnavailableError(source)
+		}
+		names = append(names, source)
+	} else {
+		for name := range adaptersMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	diagnoses := make([]sourceDiagnosis, 0, len(names))
+	var withheld []string
+	for _, name := range names {
+		adapter := adaptersMap[name]
+		diagnosis := sourceDiagnosis{Source: name}
+		validator, ok := adapter.(validatingAdapter)
+		diagnosis.StrictSupported = ok
+
+		sessions, err := adapter.ListSessions("", 0)
+		if err != nil {
+			diagnosis.ListError = err.Error()
+			diagnoses = append(diagnoses, diagnosis)
+			continue
+		}
+		diagnosis.Sessions = len(sessions)
+		if !opts.strict || !ok {
+			diagnoses = append(diagnoses, diagnosis)
+			continue
+		}
+
+		sessions, skipped := filter(sessions)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+		sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Timestamp.After(sessions[j].Timestamp) })
+		if opts.sessions > 0 && len(sessions) > opts.sessions {
+			sessions = sessions[:opts.sessions]
+		}
+
+		for _, session := range sessions {
+			diagnosis.Validated++
+			found, err := validator.ValidateSession(session)
+			file := fileDiagnosis{SessionID: session.ID, File: session.FilePath, Count: len(found)}
+			if err != nil {
+				file.Error = err.Error()
+			} else if len(found) == 0 {
+				continue
+			}
+			diagnosis.Deviations += len(found)
+			if opts.maxDeviations > 0 && len(found) > opts.maxDeviations {
+				found = found[:opts.maxDeviations]
+			}
+			file.Deviations = found
+			diagnosis.Files = append(diagnosis.Files, file)
+		}
+		diagnoses = append(diagnoses, diagnosis)
+	}
+	sort.Strings(withheld)
+	return diagnoses, withheld, nil
+}
+
+// Tool 50: diagnose_sources
+type diagnoseSourcesArgs struct {
+	Source        string `json:"source,omitempty" jsonschema:"Only diagnose this source (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	Strict        bool   `json:"strict,omitempty" jsonschema:"Validate session files strictly against each source's known format and report every deviation, such as unknown entry types, missing fields, and malformed lines, that normal reads silently skip"`
+	Sessions      int    `json:"sessions,omitempty" jsonschema:"In strict mode, the number of most recent sessions validated per source (default: 20, -1 for all)"`
+	MaxDeviations int    `json:"max_deviations,omitempty" jsonschema:"In strict mode, the maximum deviations listed per file (default: 50, -1 for all). Every deviation is still counted."`
+}
+
+func addDiagnoseSourcesTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "diagnose_sources",
+		Description: "Check each source's session files: how many sessions it lists and whether listing fails. With strict, also validate recent session files against the format each adapter knows and report every deviation. Useful for noticing when an agent CLI changes its session format.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args diagnoseSourcesArgs) (*mcp.CallToolResult, any, error) {
+		opts := diagnoseOptions{strict: args.Strict, sessions: args.Sessions, maxDeviations: args.MaxDeviations}
+		if opts.sessions == 0 {
+			opts.sessions = 20
+		}
+		if opts.maxDeviations == 0 {
+			opts.maxDeviations = 50
+		}
+
+		diagnoses, withheld, err := diagnoseSources(adaptersMap, args.Source, opts, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := map[string]interface{}{
+			"strict":  args.Strict,
+			"sources": diagnoses,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// validatingStubAdapter is a stubAdapter that reports canned deviations per session.
+type validatingStubAdapter struct {
+	*stubAdapter
+	deviations map[string][]adapters.SchemaDeviation
+}
+
+func (v *validatingStubAdapter) ValidateSession(session adapters.Session) ([]adapters.SchemaDeviation, error) {
+	return v.deviations[session.ID], nil
+}
+
+func TestDiagnoseSources(t *testing.T) {
+	now := time.Now()
+	validating := &validatingStubAdapter{
+		stubAdapter: newStubAdapter([]adapters.Session{
+			{ID: "old", Source: "strict", FilePath: "/old.jsonl", Timestamp: now.Add(-time.Hour)},
+			{ID: "new", Source: "strict", FilePath: "/new.jsonl", Timestamp: now},
+			{ID: "hidden", Source: "strict", FilePath: "/hidden.jsonl", ProjectPath: "/secret", Timestamp: now},
+		}, nil),
+		deviations: map[string][]adapters.SchemaDeviation{
+			"old":    {{Line: 1, Field: "type", Problem: "missing"}},
+			"new":    {{Line: 2, Field: "a", Problem: "x"}, {Line: 3, Field: "b", Problem: "y"}},
+			"hidden": {{Line: 1, Field: "type", Problem: "missing"}},
+		},
+	}
+	broken := newStubAdapter(nil, nil)
+	broken.listErr = errors.New("permission denied")
+	adaptersMap := map[string]adapters.SessionAdapter{"strict": validating, "broken": broken, "plain": newStubAdapter(nil, nil)}
+	filter := func(sessions []adapters.Session) ([]adapters.Session, []string) {
+		var kept []adapters.Session
+		for _, s := range sessions {
+			if s.ProjectPath != "/secret" {
+				kept = append(kept, s)
+			}
+		}
+		return kept, []string{"/secret"}
+	}
+
+	// Without strict, sources are only listed
+	diagnoses, _, err := diagnoseSources(adaptersMap, "", diagnoseOptions{}, filter)
+	if err != nil {
+		t.Fatalf("diagnoseSources returned error: %v", err)
+	}
+	if len(diagnoses) != 3 || diagnoses[0].Source != "broken" || diagnoses[0].ListError != "permission denied" {
+		t.Fatalf("unexpected diagnoses: %+v", diagnoses)
+	}
+	if strict := diagnoses[2]; strict.Sessions != 3 || !strict.StrictSupported || strict.Validated != 0 || diagnoses[1].StrictSupported {
+		t.Fatalf("unexpected non-strict diagnoses: %+v", diagnoses)
+	}
+
+	// Strict validates the most recent allowed sessions and caps listed deviations
+	diagnoses, withheld, err := diagnoseSources(adaptersMap, "strict", diagnoseOptions{strict: true, sessions: 1, maxDeviations: 1}, filter)
+	if err != nil {
+		t.Fatalf("diagnoseSources returned error: %v", err)
+	}
+	if len(withheld) != 1 || len(diagnoses) != 1 {
+		t.Fatalf("unexpected result: %+v (withheld %v)", diagnoses, withheld)
+	}
+	strict := diagnoses[0]
+	if strict.Validated != 1 || strict.Deviations != 2 || len(strict.Files) != 1 {
+		t.Fatalf("unexpected strict diagnosis: %+v", strict)
+	}
+	if file := strict.Files[0]; file.SessionID != "new" || file.Count != 2 || len(file.Deviations) != 1 {
+		t.Fatalf("unexpected file diagnosis: %+v", file)
+	}
+
+	if _, _, err := diagnoseSources(adaptersMap, "missing", diagnoseOptions{}, filter); !errors.Is(err, adapters.ErrSourceUnavailable) {
+		t.Fatalf("expected an unavailable source error, got %v", err)
+	}
+}
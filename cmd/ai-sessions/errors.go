@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// CLI exit codes. Usage and other errors exit with 1; the failure kinds that
+// wrappers commonly need to handle get their own codes.
+const (
+	exitError             = 1
+	exitSessionNotFound   = 3
+	exitSourceUnavailable = 4
+	exitFormatUnsupported = 5
+	exitQuerySyntax       = 6
+)
+
+// Error codes reported in the structured content of failed tool calls.
+const (
+	codeError             = "error"
+	codeSessionNotFound   = "session_not_found"
+	codeSourceUnavailable = "source_unavailable"
+	codeFormatUnsupported = "format_unsupported"
+	codeQuerySyntax       = "query_syntax_error"
+)
+
+// errorKinds maps each failure kind to its tool error code and CLI exit code.
+var errorKinds = []struct {
+	err  error
+	code string
+	exit int
+}{
+	{adapters.ErrSessionNotFound, codeSessionNotFound, exitSessionNotFound},
+	{adapters.ErrSourceUnavailable, codeSourceUnavailable, exitSourceUnavailable},
+	{adapters.ErrFormatUnsupported, codeFormatUnsupported, exitFormatUnsupported},
+	{search.ErrQuerySyntax, codeQuerySyntax, exitQuerySyntax},
+}
+
+// errorCode returns the machine-readable code for err.
+func errorCode(err error) string {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.code
+		}
+	}
+	return codeError
+}
+
+// exitCode returns the CLI exit code for err.
+func exitCode(err error) int {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.exit
+		}
+	}
+	return exitError
+}
+
+// exitWithError prints err and exits with the code for its kind.
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitCode(err))
+}
+
+// addTool registers a tool whose errors are reported with a machine-readable
+// code: the result's text is the error message, as before, and its structured
+// content is {"error": message, "error_code": code}. Tools that declare an
+// output schema, which an error couldn't conform to, carry the code in the
+// result's _meta instead. The input schema is
+// inferred from In, with argTypeSchemas for arguments like source that take
+// more than one JSON form.
+func addTool[In any](server *mcp.Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, any]) {
+	if tool.InputSchema == nil && reflect.TypeFor[In]() != reflect.TypeFor[any]() {
+		schema, err := jsonschema.For[In](&jsonschema.ForOptions{TypeSchemas: argTypeSchemas})
+		if err != nil {
+			panic(fmt.Sprintf("input schema for %s: %v", tool.Name, err))
+		}
+		tool.InputSchema = schema
+	}
+	mcp.AddTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		result, out, err := handler(ctx, req, args)
+		if err != nil {
+			return toolErrorResult(tool, err), nil, nil
+		}
+		return result, out, nil
+	})
+}
+
+// toolErrorResult reports a failure of tool to the client.
+func toolErrorResult(tool *mcp.Tool, err error) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: err.Error()},
+		},
+	}
+	if tool.OutputSchema != nil {
+		// Structured content must match the output schema, even on error
+		result.Meta = mcp.Meta{"error_code": errorCode(err)}
+		return result
+	}
+	result.StructuredContent = map[string]interface{}{
+		"error":      err.Error(),
+		"error_code": errorCode(err),
+	}
+	return result
+}
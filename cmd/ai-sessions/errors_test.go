@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+		exit int
+	}{
+		{fmt.Errorf("failed to get session: %w", adapters.SessionNotFoundError("abc")), codeSessionNotFound, exitSessionNotFound},
+		{adapters.SourceUnavailableError("cursor"), codeSourceUnavailable, exitSourceUnavailable},
+		{func() error { _, err := exportFormat("pdf", ""); return err }(), codeFormatUnsupported, exitFormatUnsupported},
+		{fmt.Errorf("search failed: %w", fmt.Errorf("%w: unclosed quote", search.ErrQuerySyntax)), codeQuerySyntax, exitQuerySyntax},
+		{fmt.Errorf("session_id is required"), codeError, exitError},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.code {
+			t.Errorf("errorCode(%v) = %q, want %q", tt.err, got, tt.code)
+		}
+		if got := exitCode(tt.err); got != tt.exit {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.exit)
+		}
+	}
+}
+
+func TestToolErrorsIncludeCode(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListFilesTouchedTool(server, map[string]adapters.SessionAdapter{}, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_files_touched",
+		Arguments: map[string]interface{}{"session_id": "abc", "source": "nope"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected an error result")
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; text != "unknown or unavailable source: nope" {
+		t.Errorf("unexpected error text: %q", text)
+	}
+
+	raw, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("marshal structured content: %v", err)
+	}
+	var structured map[string]string
+	if err := json.Unmarshal(raw, &structured); err != nil {
+		t.Fatalf("unmarshal structured content: %v", err)
+	}
+	if structured["error_code"] != codeSourceUnavailable {
+		t.Errorf("expected error_code %q, got %v", codeSourceUnavailable, structured)
+	}
+}
+
+func TestTypedToolErrorsKeepToOutputSchema(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetSessionTool(server, map[string]adapters.SessionAdapter{}, nil, nil)
+	clientSession := newTestClient(t, server)
+
+	result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "get_session",
+		Arguments: map[string]interface{}{"session_id": "abc", "source": "nope"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError || result.StructuredContent != nil {
+		t.Fatalf("expected an error without structured content, got %+v", result)
+	}
+	if code := result.Meta["error_code"]; code != codeSourceUnavailable {
+		t.Errorf("expected error_code %q in _meta, got %v", codeSourceUnavailable, result.Meta)
+	}
+}
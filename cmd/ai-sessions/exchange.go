@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// exchangeMessage is one message picked out of a session, with its position.
+type exchangeMessage struct {
+	MessageIndex int        `json:"message_index"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"`
+	Content      string     `json:"content"`
+}
+
+// sessionBookends is what a session was asked and how it ended.
+type sessionBookends struct {
+	SessionID    string           `json:"session_id"`
+	Source       string           `json:"source"`
+	FirstPrompt  *exchangeMessage `json:"first_user_message,omitempty"`
+	LastPrompt   *exchangeMessage `json:"last_user_message,omitempty"`
+	FinalReply   *exchangeMessage `json:"final_assistant_message,omitempty"`
+	MessageCount int              `json:"message_count"`
+	PromptCount  int              `json:"user_message_count"`
+}
+
+// findBookends picks out a session's first and last user prompts, which are
+// the same message when there was only one, and its final assistant message
+// with text, truncating each to maxLength characters unless
+// it is negative. User messages that only carry tool results aren't prompts.
+func findBookends(messages []adapters.Message, maxLength int) sessionBookends {
+	pick := func(i int) *exchangeMessage {
+		picked := &exchangeMessage{MessageIndex: i, Content: strings.TrimSpace(messages[i].Content)}
+		if maxLength >= 0 {
+			picked.Content = truncateString(picked.Content, maxLength)
+		}
+		if ts := messages[i].Timestamp; !ts.IsZero() {
+			picked.Timestamp = &ts
+		}
+		return picked
+	}
+
+	bookends := sessionBookends{MessageCount: len(messages)}
+	first, last, reply := -1, -1, -1
+	for i, msg := range messages {
+		switch {
+		case isPrompt(msg):
+			bookends.PromptCount++
+			if first < 0 {
+				first = i
+			}
+			last = i
+		case msg.Role == "assistant" && strings.TrimSpace(msg.Content) != "":
+			reply = i
+		}
+	}
+	if first >= 0 {
+		bookends.FirstPrompt = pick(first)
+		bookends.LastPrompt = pick(last)
+	}
+	if reply >= 0 {
+		bookends.FinalReply = pick(reply)
+	}
+	return bookends
+}
+
+// Tool 43: get_first_and_last_exchange
+type getFirstAndLastExchangeArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to summarize"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	MaxLength int    `json:"max_length,omitempty" jsonschema:"Truncate each message to this many characters (default: 2000, -1 for no limit)"`
+}
+
+func addGetFirstAndLastExchangeTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_first_and_last_exchange",
+		Description: "Return only a session's first user message, last user message, and final assistant message, for quick triage of what was asked and how it ended without paging through the session",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getFirstAndLastExchangeArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.MaxLength == 0 {
+			args.MaxLength = 2000
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		recordAccess(searchCache, req.Session, search.AccessEntry{
+			SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+		})
+
+		result := findBookends(messages, args.MaxLength)
+		result.SessionID, result.Source = args.SessionID, args.Source
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestFindBookends(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	messages := []adapters.Message{
+		{Role: "user", Content: "Add a login page", Timestamp: ts},
+		{Role: "assistant", Content: "Starting with the form."},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"}},
+		}},
+		{Role: "user", Content: "Now make the tests pass, please"},
+		{Role: "assistant", Content: "All tests pass."},
+		{Role: "assistant", Metadata: map[string]interface{}{"model": "claude-sonnet-4-5"}},
+	}
+
+	bookends := findBookends(messages, 16)
+	if bookends.MessageCount != 6 || bookends.PromptCount != 2 {
+		t.Fatalf("unexpected counts %+v", bookends)
+	}
+	if first := bookends.FirstPrompt; first == nil || first.MessageIndex != 0 || first.Content != "Add a login page" || !first.Timestamp.Equal(ts) {
+		t.Fatalf("unexpected first user message %+v", first)
+	}
+	if last := bookends.LastPrompt; last == nil || last.MessageIndex != 3 || last.Content != "Now make the ..." || last.Timestamp != nil {
+		t.Fatalf("unexpected last user message %+v", last)
+	}
+	if reply := bookends.FinalReply; reply == nil || reply.MessageIndex != 4 || reply.Content != "All tests pass." {
+		t.Fatalf("expected the last assistant message with text, got %+v", reply)
+	}
+
+	single := findBookends(messages[:2], -1)
+	if single.FirstPrompt == nil || single.LastPrompt == nil || single.FirstPrompt.MessageIndex != 0 || single.LastPrompt.MessageIndex != 0 {
+		t.Fatalf("expected one prompt to be both first and last, got %+v", single)
+	}
+	if empty := findBookends(nil, -1); empty.FirstPrompt != nil || empty.FinalReply != nil {
+		t.Fatalf("expected nothing from an empty session, got %+v", empty)
+	}
+}
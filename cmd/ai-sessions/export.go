@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// lookupSession returns metadata for a session, falling back to just its ID and
+// source when the adapter can't find it in its listing.
+func lookupSession(adapter adapters.SessionAdapter, sessionID string) adapters.Session {
+	session, _ := findSession(adapter, sessionID)
+	return session
+}
+
+// findSession is lookupSession, also reporting whether the adapter found the session.
+func findSession(adapter adapters.SessionAdapter, sessionID string) (adapters.Session, bool) {
+	if infoAdapter, ok := adapter.(sessionInfoCapableAdapter); ok {
+		if session, err := infoAdapter.GetSessionInfo(sessionID); err == nil {
+			return session, true
+		}
+	}
+	if sessions, err := adapter.ListSessions("", 0); err == nil {
+		for _, s := range sessions {
+			if s.ID == sessionID {
+				return s, true
+			}
+		}
+	}
+	return adapters.Session{ID: sessionID, Source: adapter.Name()}, false
+}
+
+// messageRange is an inclusive range of message indices, counting from 0.
+type messageRange struct {
+	start, end int
+}
+
+// parseMessageRanges parses a selection like "10-19,25" into sorted, merged ranges.
+// An open-ended range ("390-") runs to the last message.
+func parseMessageRanges(spec string, total int) ([]messageRange, error) {
+	var ranges []messageRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startText, endText, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(startText))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid message range %q", part)
+		}
+		end := start
+		if isRange {
+			if strings.TrimSpace(endText) == "" {
+				end = total - 1
+			} else if end, err = strconv.Atoi(strings.TrimSpace(endText)); err != nil || end < start {
+				return nil, fmt.Errorf("invalid message range %q", part)
+			}
+		}
+		if start >= total {
+			return nil, fmt.Errorf("message range %q is past the end of the session (%d messages)", part, total)
+		}
+		ranges = append(ranges, messageRange{start: start, end: min(end, total-1)})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no message ranges given")
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.end+1 {
+			last.end = max(last.end, r.end)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged, nil
+}
+
+// Export formats
+const (
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+)
+
+// exportFormat resolves the requested format, defaulting to HTML when the
+// output file ends in .html and to Markdown otherwise.
+func exportFormat(format, outputPath string) (string, error) {
+	switch strings.ToLower(format) {
+	case "":
+		ext := strings.ToLower(filepath.Ext(outputPath))
+		if ext == ".html" || ext == ".htm" {
+			return formatHTML, nil
+		}
+		return formatMarkdown, nil
+	case "markdown", "md":
+		return formatMarkdown, nil
+	case "html":
+		return formatHTML, nil
+	}
+	return "", fmt.Errorf("%w %q for export (expected markdown or html)", adapters.ErrFormatUnsupported, format)
+}
+
+// renderExport renders a session in the given format. Markdown exports written
+// to outputPath link their images as files collected in the returned assets;
+// HTML exports embed them.
+func renderExport(format, outputPath string, session adapters.Session, messages []adapters.Message, ranges []messageRange) (string, *exportAssets) {
+	if format == formatHTML {
+		return renderHTML(session, messages, ranges), nil
+	}
+	var assets *exportAssets
+	if outputPath != "" {
+		assets = newExportAssets(outputPath)
+	}
+	return renderMarkdown(session, messages, ranges, assets), assets
+}
+
+// exportAssets collects the images of a Markdown export so they can be written
+// to a directory next to it, named after the export file.
+type exportAssets struct {
+	dir   string // directory name, relative to the export file
+	files []exportAsset
+}
+
+type exportAsset struct {
+	name string
+	data []byte
+}
+
+func newExportAssets(outputPath string) *exportAssets {
+	base := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+	return &exportAssets{dir: base + "_files"}
+}
+
+// add stores an image and returns the path to link it by.
+func (a *exportAssets) add(img adapters.Image) string {
+	name := fmt.Sprintf("image-%d%s", len(a.files)+1, imageExtension(img.MediaType))
+	a.files = append(a.files, exportAsset{name: name, data: img.Data})
+	return a.dir + "/" + name
+}
+
+// imageExtension returns a file extension for an image media type.
+func imageExtension(mediaType string) string {
+	switch mediaType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	}
+	return ".img"
+}
+
+// exportTitle returns the first line of the session's summary, first message, or ID.
+func exportTitle(session adapters.Session) string {
+	title := session.Summary
+	if title == "" {
+		title = session.FirstMessage
+	}
+	if title == "" {
+		title = session.ID
+	}
+	return adapters.TruncateText(strings.TrimSpace(strings.SplitN(title, "\n", 2)[0]), adapters.DefaultPreviewLength)
+}
+
+// renderMarkdown renders a session transcript as Markdown: a metadata header,
+// a section per message, tool calls as fenced JSON, and tool results collapsed
+// in <details> blocks. If ranges is non-empty only those messages are rendered,
+// with a note where messages were left out. Images are linked as files added to
+// assets, or replaced by a placeholder when assets is nil.
+func renderMarkdown(session adapters.Session, messages []adapters.Message, ranges []messageRange, assets *exportAssets) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", exportTitle(session))
+	fmt.Fprintf(&b, "- **Source:** %s\n", getAgentDisplayName(session.Source))
+	fmt.Fprintf(&b, "- **Session ID:** `%s`\n", session.ID)
+	if session.ProjectPath != "" {
+		fmt.Fprintf(&b, "- **Project:** `%s`\n", session.ProjectPath)
+	}
+	if !session.Timestamp.IsZero() {
+		fmt.Fprintf(&b, "- **Started:** %s\n", session.Timestamp.UTC().Format(time.RFC3339))
+	}
+	if len(ranges) == 0 {
+		ranges = []messageRange{{start: 0, end: len(messages) - 1}}
+	} else {
+		fmt.Fprintf(&b, "- **Excerpt:** %s of %d messages\n", describeRanges(ranges), len(messages))
+	}
+
+	next := 0
+	for _, r := range ranges {
+		if r.start > next {
+			writeSeparator(&b)
+			fmt.Fprintf(&b, "*%d messages omitted*\n\n", r.start-next)
+		}
+		renderMessages(&b, messages[r.start:r.end+1], assets)
+		next = r.end + 1
+	}
+	if next < len(messages) && next > 0 {
+		writeSeparator(&b)
+		fmt.Fprintf(&b, "*%d messages omitted*\n\n", len(messages)-next)
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// describeRanges formats ranges for display, e.g. "messages 10-19, 25".
+func describeRanges(ranges []messageRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.start == r.end {
+			parts = append(parts, strconv.Itoa(r.start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", r.start, r.end))
+		}
+	}
+	return "messages " + strings.Join(parts, ", ")
+}
+
+// renderMessages writes a section per message, skipping messages with nothing to show.
+func renderMessages(b *strings.Builder, messages []adapters.Message, assets *exportAssets) {
+	for _, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		calls := adapters.ExtractToolCalls(msg)
+		results := adapters.ExtractToolResults(msg)
+		images := adapters.ExtractImages(msg)
+		if content == "" && len(calls) == 0 && len(results) == 0 && len(images) == 0 {
+			continue
+		}
+
+		writeSeparator(b)
+		b.WriteString("## " + roleHeading(msg.Role))
+		if !msg.Timestamp.IsZero() {
+			b.WriteString(" · " + msg.Timestamp.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("\n\n")
+
+		if content != "" {
+			b.WriteString(content + "\n\n")
+		}
+
+		for _, img := range images {
+			if assets == nil {
+				fmt.Fprintf(b, "*[%s image, %.1f KB, export to a file to keep it]*\n\n", img.MediaType, float64(len(img.Data))/1024)
+			} else {
+				fmt.Fprintf(b, "![image](%s)\n\n", assets.add(img))
+			}
+		}
+
+		for _, call := range calls {
+			fmt.Fprintf(b, "**Tool call:** `%s`\n\n", call.Name)
+			if len(call.Input) > 0 {
+				input, err := json.MarshalIndent(call.Input, "", "  ")
+				if err == nil {
+					writeFenced(b, string(input), "json")
+				}
+			}
+		}
+
+		for _, result := range results {
+			summary := "Tool result"
+			if result.IsError {
+				summary = "Tool error"
+			}
+			fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n", summary)
+			writeFenced(b, result.Content, "")
+			b.WriteString("</details>\n\n")
+		}
+	}
+}
+
+// writeSeparator starts a new section with a horizontal rule after a blank line.
+func writeSeparator(b *strings.Builder) {
+	if !strings.HasSuffix(b.String(), "\n\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("---\n\n")
+}
+
+// roleHeading capitalizes a message role for use as a section heading.
+func roleHeading(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// writeFenced writes text as a fenced code block, using a fence longer than
+// any backtick run in the text so embedded code blocks can't close it early.
+func writeFenced(b *strings.Builder, text, lang string) {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+// writeExport writes a rendered export to path, creating parent directories,
+// and its images (if any) to the assets directory next to it. Transcripts can
+// contain secrets, so the files are only readable by the owner.
+func writeExport(path, content string, assets *exportAssets) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	if assets == nil || len(assets.files) == 0 {
+		return nil
+	}
+	dir := filepath.Join(filepath.Dir(path), assets.dir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+	for _, file := range assets.files {
+		if err := os.WriteFile(filepath.Join(dir, file.name), file.data, 0o600); err != nil {
+			return fmt.Errorf("failed to write image: %w", err)
+		}
+	}
+	return nil
+}
+
+// Tool 13: export_session
+type exportSessionArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to export"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Format    string `json:"format,omitempty" jsonschema:"Export format: 'markdown' or 'html'. Defaults to html when output_path ends in .html, otherwise markdown."`
+	Messages  string `json:"messages,omitempty" jsonschema:"Only export these message indices, e.g. '120-129' or '10-19,25' (indices as returned by search_in_session). Leave empty for the whole session."`
+}
+
+// exportSessionToFileArgs are export_session's arguments on servers started
+// with --allow-write, which may write the export to a file.
+type exportSessionToFileArgs struct {
+	exportSessionArgs
+	OutputPath string `json:"output_path,omitempty" jsonschema:"Optional absolute path to write the export to. If omitted, the export is returned and images are left out."`
+}
+
+// addExportSessionTool registers export_session. Only with allowWrite
+// (--allow-write) can clients have the export written to a file.
+func addExportSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, allowWrite bool) {
+	tool := &mcp.Tool{
+		Name:        "export_session",
+		Description: "Export a full session as Markdown or a self-contained HTML page, with role headers, fenced code blocks, collapsed tool results, and images. Returns the export.",
+	}
+	if !allowWrite {
+		addTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args exportSessionArgs) (*mcp.CallToolResult, any, error) {
+			return exportSession(ctx, req, adaptersMap, searchCache, consent, args, "")
+		})
+		return
+	}
+	tool.Description = strings.TrimSuffix(tool.Description, ".") + ", or writes it to output_path."
+	addTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args exportSessionToFileArgs) (*mcp.CallToolResult, any, error) {
+		return exportSession(ctx, req, adaptersMap, searchCache, consent, args.exportSessionArgs, args.OutputPath)
+	})
+}
+
+// exportSession exports a session for export_session, writing it to
+// outputPath if set.
+func exportSession(ctx context.Context, req *mcp.CallToolRequest, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, args exportSessionArgs, outputPath string) (*mcp.CallToolResult, any, error) {
+	if args.SessionID == "" {
+		return nil, nil, fmt.Errorf("session_id is required")
+	}
+	if args.Source == "" {
+		return nil, nil, fmt.Errorf("source is required")
+	}
+	if outputPath != "" && !filepath.IsAbs(outputPath) {
+		return nil, nil, fmt.Errorf("output_path must be absolute")
+	}
+	format, err := exportFormat(args.Format, outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	adapter, ok := adaptersMap[args.Source]
+	if !ok {
+		return nil, nil, adapters.SourceUnavailableError(args.Source)
+	}
+
+	session, found := findSession(adapter, args.SessionID)
+	if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+		return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+	}
+
+	messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	recordAccess(searchCache, req.Session, search.AccessEntry{
+		SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+	})
+	var ranges []messageRange
+	if args.Messages != "" {
+		if ranges, err = parseMessageRanges(args.Messages, len(messages)); err != nil {
+			return nil, nil, err
+		}
+	}
+	content, assets := renderExport(format, outputPath, session, messages, ranges)
+
+	if outputPath == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: content},
+			},
+		}, nil, nil
+	}
+
+	if err := writeExport(outputPath, content, assets); err != nil {
+		return nil, nil, err
+	}
+
+	result := map[string]interface{}{
+		"session_id":    args.SessionID,
+		"source":        args.Source,
+		"output_path":   outputPath,
+		"format":        format,
+		"bytes":         len(content),
+		"message_count": len(messages),
+	}
+	if assets != nil && len(assets.files) > 0 {
+		result["images_dir"] = filepath.Join(filepath.Dir(outputPath), assets.dir)
+		result["image_count"] = len(assets.files)
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// handleExportCommand processes: aisessions export <session-id> --source <source> [--output <file>] [--format markdown|html] [--messages <ranges>]
+func handleExportCommand() {
+	var sessionID, source, output, formatFlag, selection string
+
+	if len(os.Args) >= 3 && !strings.HasPrefix(os.Args[2], "--") {
+		sessionID = os.Args[2]
+	}
+
+	startIdx := 3
+	if sessionID == "" {
+		startIdx = 2
+	}
+	for i := startIdx; i < len(os.Args); i++ {
+		switch os.Args[i] {
+		case "--source", "--output", "--format", "--messages":
+			if i+1 >= len(os.Args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", os.Args[i])
+				os.Exit(1)
+			}
+			switch os.Args[i] {
+			case "--source":
+				source = os.Args[i+1]
+			case "--output":
+				output = os.Args[i+1]
+			case "--format":
+				formatFlag = os.Args[i+1]
+			default:
+				selection = os.Args[i+1]
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", os.Args[i])
+			os.Exit(1)
+		}
+	}
+
+	if sessionID == "" || source == "" {
+		fmt.Fprintf(os.Stderr, "Usage: aisessions export <session-id> --source <source> [--output <file>] [--format markdown|html] [--messages <ranges>]\n")
+		os.Exit(1)
+	}
+	format, err := exportFormat(formatFlag, output)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	adaptersMap, _ := adapters.NewRegistered()
+	if serverConfig, err := loadServerConfig(); err == nil {
+		addConfiguredAdapters(adaptersMap, serverConfig)
+	}
+
+	adapter, ok := adaptersMap[source]
+	if !ok {
+		exitWithError(adapters.SourceUnavailableError(source))
+	}
+
+	messages, err := adapter.GetSession(sessionID, 0, 100000)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to get session: %w", err))
+	}
+	var ranges []messageRange
+	if selection != "" {
+		if ranges, err = parseMessageRanges(selection, len(messages)); err != nil {
+			exitWithError(err)
+		}
+	}
+	content, assets := renderExport(format, output, lookupSession(adapter, sessionID), messages, ranges)
+
+	if output == "" {
+		fmt.Print(content)
+		return
+	}
+	if err := writeExport(output, content, assets); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Exported %s to %s\n", sessionID, output)
+	if assets != nil && len(assets.files) > 0 {
+		fmt.Printf("Saved %d images to %s\n", len(assets.files), filepath.Join(filepath.Dir(output), assets.dir))
+	}
+}
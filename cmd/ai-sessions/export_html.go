@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// htmlExportStyle keeps HTML exports readable without any external assets.
+const htmlExportStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;max-width:900px;margin:2em auto;padding:0 1em;line-height:1.5;color:#1f2328}
+header ul{list-style:none;padding:0;color:#59636e}
+section{border-top:1px solid #d1d9e0;padding:1em 0}
+h2{font-size:1em;margin:0 0 .5em}
+h2 time{font-weight:normal;color:#59636e}
+section.user h2{color:#0969da}
+section.assistant h2{color:#8250df}
+.text{white-space:pre-wrap;overflow-wrap:anywhere}
+pre{background:#f6f8fa;padding:.75em;overflow-x:auto;border-radius:6px}
+img{max-width:100%;border:1px solid #d1d9e0;border-radius:6px;margin:.5em 0}
+.omitted{color:#59636e;font-style:italic}`
+
+// renderHTML renders a session transcript as a self-contained HTML page, with
+// the same layout as renderMarkdown. Images are embedded as data URLs so the
+// page can be shared as a single file. Message text sets dir="auto" so
+// right-to-left messages are laid out by their own direction.
+func renderHTML(session adapters.Session, messages []adapters.Message, ranges []messageRange) string {
+	var b strings.Builder
+
+	title := html.EscapeString(exportTitle(session))
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", title, htmlExportStyle)
+
+	fmt.Fprintf(&b, "<header>\n<h1 dir=\"auto\">%s</h1>\n<ul>\n", title)
+	fmt.Fprintf(&b, "<li><strong>Source:</strong> %s</li>\n", html.EscapeString(getAgentDisplayName(session.Source)))
+	fmt.Fprintf(&b, "<li><strong>Session ID:</strong> <code>%s</code></li>\n", html.EscapeString(session.ID))
+	if session.ProjectPath != "" {
+		fmt.Fprintf(&b, "<li><strong>Project:</strong> <code>%s</code></li>\n", html.EscapeString(session.ProjectPath))
+	}
+	if !session.Timestamp.IsZero() {
+		fmt.Fprintf(&b, "<li><strong>Started:</strong> %s</li>\n", session.Timestamp.UTC().Format(time.RFC3339))
+	}
+	if len(ranges) == 0 {
+		ranges = []messageRange{{start: 0, end: len(messages) - 1}}
+	} else {
+		fmt.Fprintf(&b, "<li><strong>Excerpt:</strong> %s of %d messages</li>\n", describeRanges(ranges), len(messages))
+	}
+	b.WriteString("</ul>\n</header>\n")
+
+	next := 0
+	for _, r := range ranges {
+		if r.start > next {
+			fmt.Fprintf(&b, "<section class=\"omitted\">%d messages omitted</section>\n", r.start-next)
+		}
+		renderHTMLMessages(&b, messages[r.start:r.end+1])
+		next = r.end + 1
+	}
+	if next < len(messages) && next > 0 {
+		fmt.Fprintf(&b, "<section class=\"omitted\">%d messages omitted</section>\n", len(messages)-next)
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// renderHTMLMessages writes a section per message, skipping messages with nothing to show.
+func renderHTMLMessages(b *strings.Builder, messages []adapters.Message) {
+	for _, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		calls := adapters.ExtractToolCalls(msg)
+		results := adapters.ExtractToolResults(msg)
+		images := adapters.ExtractImages(msg)
+		if content == "" && len(calls) == 0 && len(results) == 0 && len(images) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "<section class=\"%s\">\n<h2>%s", html.EscapeString(msg.Role), html.EscapeString(roleHeading(msg.Role)))
+		if !msg.Timestamp.IsZero() {
+			fmt.Fprintf(b, " <time>· %s</time>", msg.Timestamp.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("</h2>\n")
+
+		if content != "" {
+			fmt.Fprintf(b, "<div class=\"text\" dir=\"auto\">%s</div>\n", html.EscapeString(content))
+		}
+
+		for _, img := range images {
+			fmt.Fprintf(b, "<img src=\"data:%s;base64,%s\" alt=\"image\">\n", html.EscapeString(img.MediaType), base64.StdEncoding.EncodeToString(img.Data))
+		}
+
+		for _, call := range calls {
+			fmt.Fprintf(b, "<p><strong>Tool call:</strong> <code>%s</code></p>\n", html.EscapeString(call.Name))
+			if len(call.Input) > 0 {
+				input, err := json.MarshalIndent(call.Input, "", "  ")
+				if err == nil {
+					fmt.Fprintf(b, "<pre><code>%s</code></pre>\n", html.EscapeString(string(input)))
+				}
+			}
+		}
+
+		for _, result := range results {
+			summary := "Tool result"
+			if result.IsError {
+				summary = "Tool error"
+			}
+			fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n<pre><code>%s</code></pre>\n</details>\n", summary, html.EscapeString(strings.TrimRight(result.Content, "\n")))
+		}
+
+		b.WriteString("</section>\n")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/src/app", FirstMessage: "Fix the build", Timestamp: start}
+	messages := []adapters.Message{
+		{Role: "user", Content: "Fix the build", Timestamp: start},
+		{Role: "assistant", Content: "Here is the fix:\n```go\nfunc main() {}\n```", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Read", "input": map[string]interface{}{"file_path": "/src/app/main.go"}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "```\nnested fence\n```"},
+			},
+		}},
+		{Role: "user"}, // nothing to render
+	}
+
+	markdown := renderMarkdown(session, messages, nil, nil)
+
+	for _, want := range []string{
+		"# Fix the build\n",
+		"- **Source:** Claude Code\n",
+		"- **Project:** `/src/app`\n",
+		"## User · 2025-03-01T10:00:00Z\n",
+		"## Assistant\n",
+		"```go\nfunc main() {}\n```",
+		"**Tool call:** `Read`",
+		"\"file_path\": \"/src/app/main.go\"",
+		"<details>\n<summary>Tool result</summary>\n\n````\n```\nnested fence\n```\n````\n\n</details>",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+	if got := strings.Count(markdown, "## User"); got != 2 {
+		t.Errorf("expected empty messages to be skipped, got %d user sections", got)
+	}
+}
+
+func TestWriteExportCreatesPrivateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exports", "session.md")
+	if err := writeExport(path, "# Session\n", nil); err != nil {
+		t.Fatalf("writeExport failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+}
+
+func TestParseMessageRanges(t *testing.T) {
+	ranges, err := parseMessageRanges("25, 10-19,18-20,390-", 400)
+	if err != nil {
+		t.Fatalf("parseMessageRanges failed: %v", err)
+	}
+	want := []messageRange{{10, 20}, {25, 25}, {390, 399}}
+	if len(ranges) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ranges)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ranges)
+		}
+	}
+
+	for _, spec := range []string{"", "abc", "5-2", "400", "-3"} {
+		if _, err := parseMessageRanges(spec, 400); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestRenderMarkdownExcerpt(t *testing.T) {
+	var messages []adapters.Message
+	for i := 0; i < 10; i++ {
+		messages = append(messages, adapters.Message{Role: "user", Content: fmt.Sprintf("message %d", i)})
+	}
+
+	markdown := renderMarkdown(adapters.Session{ID: "s1", Source: "claude"}, messages, []messageRange{{2, 3}, {6, 6}}, nil)
+
+	for _, want := range []string{
+		"- **Excerpt:** messages 2-3, 6 of 10 messages\n",
+		"*2 messages omitted*\n\n---\n\n## User\n\nmessage 2",
+		"message 3\n\n---\n\n*2 messages omitted*",
+		"message 6\n\n---\n\n*3 messages omitted*\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected excerpt to contain %q, got:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "message 5") || strings.Contains(markdown, "message 0") {
+		t.Errorf("expected unselected messages to be left out, got:\n%s", markdown)
+	}
+}
+
+func TestExportImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	session := adapters.Session{ID: "s1", Source: "claude"}
+	messages := []adapters.Message{
+		{Role: "user", Content: "What's wrong here?", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "What's wrong here?"},
+				map[string]interface{}{"type": "image", "source": map[string]interface{}{
+					"type": "base64", "media_type": "image/png", "data": base64.StdEncoding.EncodeToString(png),
+				}},
+			},
+		}},
+	}
+
+	if markdown := renderMarkdown(session, messages, nil, nil); !strings.Contains(markdown, "*[image/png image,") {
+		t.Errorf("expected a placeholder when images can't be saved, got:\n%s", markdown)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.md")
+	markdown, assets := renderExport(formatMarkdown, path, session, messages, nil)
+	if !strings.Contains(markdown, "![image](session_files/image-1.png)") {
+		t.Errorf("expected image link, got:\n%s", markdown)
+	}
+	if err := writeExport(path, markdown, assets); err != nil {
+		t.Fatalf("writeExport failed: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(filepath.Dir(path), "session_files", "image-1.png"))
+	if err != nil || string(saved) != string(png) {
+		t.Fatalf("expected image file next to the export, got %q (%v)", saved, err)
+	}
+
+	page, assets := renderExport(formatHTML, "", session, messages, nil)
+	if assets != nil {
+		t.Errorf("expected HTML export to embed images, got assets %+v", assets)
+	}
+	if want := `<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `"`; !strings.Contains(page, want) {
+		t.Errorf("expected embedded image, got:\n%s", page)
+	}
+	if !strings.Contains(page, "What&#39;s wrong here?") {
+		t.Errorf("expected escaped message text, got:\n%s", page)
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		format, output, want string
+	}{
+		{"", "", formatMarkdown},
+		{"", "/tmp/session.md", formatMarkdown},
+		{"", "/tmp/session.HTML", formatHTML},
+		{"html", "", formatHTML},
+		{"md", "/tmp/session.html", formatMarkdown},
+	}
+	for _, tt := range tests {
+		if got, err := exportFormat(tt.format, tt.output); err != nil || got != tt.want {
+			t.Errorf("exportFormat(%q, %q) = %q, %v; want %q", tt.format, tt.output, got, err, tt.want)
+		}
+	}
+	if _, err := exportFormat("pdf", ""); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestOutputPathNeedsAllowWrite(t *testing.T) {
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, nil)}
+	for _, allowWrite := range []bool{false, true} {
+		server := mcp.NewServer(&mcp.Implementation{Name: "ai-sessions", Version: "test"}, nil)
+		addExportSessionTool(server, adaptersMap, nil, nil, allowWrite)
+		addExportReviewChecklistTool(server, adaptersMap, nil, nil, allowWrite)
+
+		ctx := context.Background()
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		serverSession, err := server.Connect(ctx, serverTransport, nil)
+		if err != nil {
+			t.Fatalf("server connect: %v", err)
+		}
+		client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		clientSession, err := client.Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatalf("client connect: %v", err)
+		}
+		for tool, err := range clientSession.Tools(ctx, nil) {
+			if err != nil {
+				t.Fatalf("listing tools: %v", err)
+			}
+			data, _ := json.Marshal(tool.InputSchema)
+			var schema struct {
+				Properties map[string]any `json:"properties"`
+			}
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("decode input schema: %v", err)
+			}
+			if _, got := schema.Properties["output_path"]; got != allowWrite {
+				t.Errorf("allowWrite %v: %s offers output_path = %v", allowWrite, tool.Name, got)
+			}
+		}
+		clientSession.Close()
+		serverSession.Close()
+	}
+}
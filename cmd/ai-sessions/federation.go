@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultPeerTimeout bounds how long search_sessions waits for a peer.
+const defaultPeerTimeout = 10 * time.Second
+
+// PeerConfig is another MCP memory server that search_sessions can forward
+// queries to, so session history can be searched alongside other memories.
+type PeerConfig struct {
+	Name string `json:"name"`
+
+	// Command starts the peer as a subprocess speaking MCP over stdio;
+	// URL connects to it over streamable HTTP instead. Set exactly one.
+	Command []string `json:"command,omitempty"`
+	URL     string   `json:"url,omitempty"`
+
+	// Token, if set, is sent as a bearer token to a URL peer
+	Token string `json:"token,omitempty"`
+
+	// Tool is the peer's search tool, called with the query in QueryArgument
+	// (default "query") plus any fixed Arguments
+	Tool          string                 `json:"tool"`
+	QueryArgument string                 `json:"query_argument,omitempty"`
+	Arguments     map[string]interface{} `json:"arguments,omitempty"`
+
+	// Timeout is a Go duration such as "5s" (default 10s)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// peer is a validated PeerConfig.
+type peer struct {
+	name      string
+	tool      string
+	queryArg  string
+	arguments map[string]interface{}
+	timeout   time.Duration
+	transport func() mcp.Transport
+}
+
+// federation forwards search queries to the configured peers.
+type federation struct {
+	peers []peer
+}
+
+// peerMatch is one result from a peer, in the peer's own shape.
+type peerMatch struct {
+	Peer   string      `json:"peer"`
+	Rank   int         `json:"rank"` // position in the peer's results, from 1
+	Result interface{} `json:"result"`
+}
+
+// peerError records a peer that couldn't be searched.
+type peerError struct {
+	Peer  string `json:"peer"`
+	Error string `json:"error"`
+}
+
+// bearerTransport adds an Authorization header to every request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// newFederation validates the configured peers, skipping invalid ones. It
+// returns nil when no peer is usable.
+func newFederation(configs []PeerConfig) (*federation, []error) {
+	var errs []error
+	f := &federation{}
+	seen := make(map[string]bool)
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			errs = append(errs, fmt.Errorf("federation peer is missing a name"))
+			continue
+		}
+		if seen[cfg.Name] {
+			errs = append(errs, fmt.Errorf("federation peer %q is configured twice", cfg.Name))
+			continue
+		}
+		if (len(cfg.Command) == 0) == (cfg.URL == "") {
+			errs = append(errs, fmt.Errorf("federation peer %q needs exactly one of command or url", cfg.Name))
+			continue
+		}
+		if cfg.Tool == "" {
+			errs = append(errs, fmt.Errorf("federation peer %q is missing a tool", cfg.Name))
+			continue
+		}
+		timeout := defaultPeerTimeout
+		if cfg.Timeout != "" {
+			d, err := time.ParseDuration(cfg.Timeout)
+			if err != nil || d <= 0 {
+				errs = append(errs, fmt.Errorf("federation peer %q has an invalid timeout %q", cfg.Name, cfg.Timeout))
+				continue
+			}
+			timeout = d
+		}
+		queryArg := cfg.QueryArgument
+		if queryArg == "" {
+			queryArg = "query"
+		}
+
+		p := peer{name: cfg.Name, tool: cfg.Tool, queryArg: queryArg, arguments: cfg.Arguments, timeout: timeout}
+		if len(cfg.Command) > 0 {
+			command := cfg.Command
+			p.transport = func() mcp.Transport {
+				return &mcp.CommandTransport{Command: exec.Command(command[0], command[1:]...)}
+			}
+		} else {
+			transport := &mcp.StreamableClientTransport{Endpoint: cfg.URL, MaxRetries: -1}
+			if cfg.Token != "" {
+				transport.HTTPClient = &http.Client{Transport: bearerTransport{token: cfg.Token, base: http.DefaultTransport}}
+			}
+			p.transport = func() mcp.Transport { return transport }
+		}
+		seen[cfg.Name] = true
+		f.peers = append(f.peers, p)
+	}
+	if len(f.peers) == 0 {
+		return nil, errs
+	}
+	return f, errs
+}
+
+// search sends query to every peer at once and merges their results,
+// taking each peer's first result in configured order, then each one's
+// second, and so on, keeping up to limit results per peer. Peers that fail or
+// time out are reported in the errors and don't hold up the rest.
+func (f *federation) search(ctx context.Context, query string, limit int) ([]peerMatch, []peerError) {
+	results := make([][]interface{}, len(f.peers))
+	errs := make([]error, len(f.peers))
+	var wg sync.WaitGroup
+	for i, p := range f.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = p.search(ctx, query)
+		}()
+	}
+	wg.Wait()
+
+	matches := []peerMatch{}
+	peerErrors := []peerError{}
+	for i, err := range errs {
+		if err != nil {
+			peerErrors = append(peerErrors, peerError{Peer: f.peers[i].name, Error: err.Error()})
+		}
+	}
+	for rank := 0; rank < limit; rank++ {
+		added := false
+		for i, items := range results {
+			if rank < len(items) {
+				matches = append(matches, peerMatch{Peer: f.peers[i].name, Rank: rank + 1, Result: items[rank]})
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return matches, peerErrors
+}
+
+// search calls the peer's search tool on a fresh connection.
+func (p peer) search(ctx context.Context, query string) ([]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "ai-sessions", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, p.transport(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer session.Close()
+
+	args := make(map[string]interface{}, len(p.arguments)+1)
+	for k, v := range p.arguments {
+		args[k] = v
+	}
+	args[p.queryArg] = query
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: p.tool, Arguments: args})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", p.tool, err)
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("%s failed: %s", p.tool, peerResultText(result))
+	}
+	if result.StructuredContent != nil {
+		data, err := json.Marshal(result.StructuredContent)
+		if err == nil {
+			var decoded interface{}
+			if json.Unmarshal(data, &decoded) == nil {
+				return peerResultItems(decoded), nil
+			}
+		}
+	}
+	var items []interface{}
+	for _, content := range result.Content {
+		text, ok := content.(*mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(text.Text), &decoded); err != nil {
+			items = append(items, text.Text)
+			continue
+		}
+		items = append(items, peerResultItems(decoded)...)
+	}
+	return items, nil
+}
+
+// peerResultListFields are the fields memory servers commonly return their
+// results under.
+var peerResultListFields = []string{"matches", "results", "memories", "entities", "items"}
+
+// peerResultItems splits a peer's decoded result into individual results: the
+// elements of a top-level array or of a known list field, or else the whole
+// result as one.
+func peerResultItems(decoded interface{}) []interface{} {
+	switch v := decoded.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		for _, field := range peerResultListFields {
+			if list, ok := v[field].([]interface{}); ok {
+				return list
+			}
+		}
+	}
+	return []interface{}{decoded}
+}
+
+// peerResultText joins a result's text content, for error messages.
+func peerResultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type peerSearchArgs struct {
+	Text string `json:"text"`
+}
+
+// inMemoryPeer returns a peer backed by an MCP server whose "recall" tool
+// returns results as JSON text.
+func inMemoryPeer(t *testing.T, name, body string) peer {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: name, Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "recall"}, func(ctx context.Context, req *mcp.CallToolRequest, args peerSearchArgs) (*mcp.CallToolResult, any, error) {
+		if args.Text == "" {
+			return nil, nil, fmt.Errorf("text is required")
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: body}}}, nil, nil
+	})
+	return peer{
+		name:     name,
+		tool:     "recall",
+		queryArg: "text",
+		timeout:  5 * time.Second,
+		transport: func() mcp.Transport {
+			serverTransport, clientTransport := mcp.NewInMemoryTransports()
+			if _, err := server.Connect(context.Background(), serverTransport, nil); err != nil {
+				t.Errorf("server connect: %v", err)
+			}
+			return clientTransport
+		},
+	}
+}
+
+func TestFederationSearch(t *testing.T) {
+	notes := inMemoryPeer(t, "notes", `{"results":[{"id":"n1"},{"id":"n2"},{"id":"n3"}]}`)
+	graph := inMemoryPeer(t, "graph", `[{"entity":"retry policy"}]`)
+	broken := inMemoryPeer(t, "broken", `{}`)
+	broken.tool = "missing"
+
+	f := &federation{peers: []peer{notes, graph, broken}}
+	matches, errs := f.search(context.Background(), "retry", 2)
+
+	var got []string
+	for _, m := range matches {
+		got = append(got, fmt.Sprintf("%s#%d", m.Peer, m.Rank))
+	}
+	if fmt.Sprint(got) != "[notes#1 graph#1 notes#2]" {
+		t.Fatalf("unexpected merge order: %v", got)
+	}
+	if len(errs) != 1 || errs[0].Peer != "broken" {
+		t.Fatalf("expected the broken peer to be reported, got %+v", errs)
+	}
+}
+
+func TestNewFederation(t *testing.T) {
+	f, errs := newFederation([]PeerConfig{
+		{Name: "notes", Command: []string{"notes-mcp"}, Tool: "search"},
+		{Name: "notes", URL: "http://localhost:9000/mcp", Tool: "search"},
+		{Name: "both", Command: []string{"x"}, URL: "http://localhost:9000/mcp", Tool: "search"},
+		{Name: "notool", URL: "http://localhost:9000/mcp"},
+		{Name: "slow", URL: "http://localhost:9000/mcp", Tool: "search", Timeout: "soon"},
+	})
+	if len(errs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", errs)
+	}
+	if f == nil || len(f.peers) != 1 || f.peers[0].queryArg != "query" || f.peers[0].timeout != defaultPeerTimeout {
+		t.Fatalf("unexpected federation: %+v", f)
+	}
+
+	if f, _ := newFederation(nil); f != nil {
+		t.Fatal("expected no federation without peers")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// Tool 40: file_history
+type fileHistoryArgs struct {
+	Path        string `json:"path" jsonschema:"The file to show the history of: an absolute path, or a path relative to project_path"`
+	Prefix      bool   `json:"prefix,omitempty" jsonschema:"If true, include every file whose path starts with path (e.g. a directory)"`
+	Source      string `json:"source,omitempty" jsonschema:"Optional source filter"`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include changes made in sessions from this project"`
+	Since       string `json:"since,omitempty" jsonschema:"Only include changes since this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h)"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of changes to return, most recent first (default: 100)"`
+}
+
+func addFileHistoryTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "file_history",
+		Description: "Show every write and edit agents made to a file (or a directory, with prefix), across all sources, most recent first. Each change links to its session and message, with a preview of the text written. The history is kept in the index, so it includes sessions whose files have since been deleted.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args fileHistoryArgs) (*mcp.CallToolResult, any, error) {
+		args.Path = strings.TrimSpace(args.Path)
+		if args.Path == "" {
+			return nil, nil, fmt.Errorf("path is required")
+		}
+		if !filepath.IsAbs(args.Path) {
+			if args.ProjectPath == "" {
+				return nil, nil, fmt.Errorf("a relative path needs project_path")
+			}
+			if filter, err := adapters.NewProjectFilter(args.ProjectPath); err != nil || filter.IsPattern() {
+				return nil, nil, fmt.Errorf("a relative path needs project_path to be a directory, not a pattern")
+			}
+			args.Path = filepath.Join(args.ProjectPath, args.Path)
+		}
+		if args.Limit == 0 {
+			args.Limit = 100
+		}
+
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = parseSince(args.Since, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Lazy indexing: record changes from sessions that need it
+		if err := indexSessions(adaptersMap, searchCache, args.Source, args.ProjectPath); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+
+		found, err := searchCache.FileHistory(args.Path, args.Prefix, args.Source, args.ProjectPath, since, args.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		changes := make([]search.FileHistoryEntry, 0, len(found))
+		sessions := make(map[string]bool)
+		var withheld []string
+		for _, change := range found {
+			if !consent.allowed(ctx, req.Session, change.ProjectPath) {
+				withheld = appendUnique(withheld, change.ProjectPath)
+				continue
+			}
+			change.FirstMessage = adapters.TruncateText(change.FirstMessage, adapters.DefaultPreviewLength)
+			changes = append(changes, change)
+			sessions[change.Source+"/"+change.SessionID] = true
+		}
+
+		result := map[string]interface{}{
+			"path":          args.Path,
+			"prefix":        args.Prefix,
+			"changes":       changes,
+			"count":         len(changes),
+			"session_count": len(sessions),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
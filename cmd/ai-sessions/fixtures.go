@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// fixtureSpec declares the fixtures to generate: defaults shared by every
+// source, overridden per source.
+type fixtureSpec struct {
+	adapters.FixtureOptions
+	Sources map[string]adapters.FixtureOptions `json:"sources"`
+}
+
+// loadFixtureSpec reads a fixture spec file.
+func loadFixtureSpec(path string) (fixtureSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixtureSpec{}, fmt.Errorf("failed to read fixture spec: %w", err)
+	}
+	var spec fixtureSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return fixtureSpec{}, fmt.Errorf("invalid fixture spec %s: %w", path, err)
+	}
+	if len(spec.Sources) == 0 {
+		return fixtureSpec{}, fmt.Errorf("fixture spec %s lists no sources", path)
+	}
+	return spec, nil
+}
+
+// resolve returns the options for each source, filling fields a source leaves
+// unset from the spec's defaults.
+func (s fixtureSpec) resolve() map[string]adapters.FixtureOptions {
+	plan := make(map[string]adapters.FixtureOptions, len(s.Sources))
+	for source, opts := range s.Sources {
+		if opts.Sessions == 0 {
+			opts.Sessions = s.Sessions
+		}
+		if opts.Turns == 0 {
+			opts.Turns = s.Turns
+		}
+		if opts.Projects == 0 {
+			opts.Projects = s.Projects
+		}
+		if opts.ProjectRoot == "" {
+			opts.ProjectRoot = s.ProjectRoot
+		}
+		if opts.Seed == 0 {
+			opts.Seed = s.Seed
+		}
+		if opts.End.IsZero() {
+			opts.End = s.End
+		}
+		plan[source] = opts
+	}
+	return plan
+}
+
+// parseFixturesArgs parses the arguments to "fixtures generate" into the
+// output directory and the options for each source. Flags override the spec's
+// defaults; --source limits the spec to those sources, or picks them when
+// there is no spec. Without either, every source is generated.
+func parseFixturesArgs(args []string) (string, map[string]adapters.FixtureOptions, error) {
+	var output, specPath string
+	var sources []string
+	var flags adapters.FixtureOptions
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--output", "--spec", "--source", "--sessions", "--turns", "--projects", "--seed":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("%s requires a value", args[i])
+			}
+			value := args[i+1]
+			i++
+			switch args[i-1] {
+			case "--output":
+				output = value
+			case "--spec":
+				specPath = value
+			case "--source":
+				sources = append(sources, value)
+			case "--seed":
+				seed, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid --seed: %s", value)
+				}
+				flags.Seed = seed
+			default:
+				n, err := strconv.Atoi(value)
+				if err != nil || n <= 0 {
+					return "", nil, fmt.Errorf("invalid %s: %s", args[i-1], value)
+				}
+				switch args[i-1] {
+				case "--sessions":
+					flags.Sessions = n
+				case "--turns":
+					flags.Turns = n
+				default:
+					flags.Projects = n
+				}
+			}
+		default:
+			return "", nil, fmt.Errorf("unexpected argument: %s", args[i])
+		}
+	}
+	if output == "" {
+		return "", nil, fmt.Errorf("--output is required")
+	}
+
+	spec := fixtureSpec{Sources: make(map[string]adapters.FixtureOptions)}
+	if specPath != "" {
+		var err error
+		if spec, err = loadFixtureSpec(specPath); err != nil {
+			return "", nil, err
+		}
+	}
+	if len(sources) > 0 {
+		selected := make(map[string]adapters.FixtureOptions, len(sources))
+		for _, source := range sources {
+			selected[source] = spec.Sources[source]
+		}
+		spec.Sources = selected
+	}
+	if len(spec.Sources) == 0 {
+		for _, source := range adapters.FixtureSources() {
+			spec.Sources[source] = adapters.FixtureOptions{}
+		}
+	}
+
+	// Flags apply to every source, over the spec
+	plan := spec.resolve()
+	for source, opts := range plan {
+		if flags.Sessions > 0 {
+			opts.Sessions = flags.Sessions
+		}
+		if flags.Turns > 0 {
+			opts.Turns = flags.Turns
+		}
+		if flags.Projects > 0 {
+			opts.Projects = flags.Projects
+		}
+		if flags.Seed != 0 {
+			opts.Seed = flags.Seed
+		}
+		plan[source] = opts
+	}
+	return output, plan, nil
+}
+
+// handleFixturesCommand generates synthetic session stores.
+func handleFixturesCommand() {
+	const usage = "Usage: aisessions fixtures generate --output <dir> [--source <source>]... [--sessions <n>] [--turns <n>] [--projects <n>] [--seed <n>] [--spec <file>]\n"
+	if len(os.Args) < 3 || os.Args[2] != "generate" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	output, plan, err := parseFixturesArgs(os.Args[3:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	output, err = filepath.Abs(output)
+	if err != nil {
+		exitWithError(err)
+	}
+	// Fixtures are laid out like a home directory; never mix them into the real one
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) == output {
+		exitWithError(fmt.Errorf("--output must not be your home directory"))
+	}
+
+	sources := make([]string, 0, len(plan))
+	for source := range plan {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		files, err := adapters.GenerateFixtures(output, source, plan[source])
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Wrote %d %s sessions\n", len(files), source)
+	}
+	fmt.Printf("\nFixtures are in %s, laid out as a home directory. To use them:\n", output)
+	fmt.Printf("  HOME=%s aisessions search \"retry\"\n", output)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFixturesArgs(t *testing.T) {
+	output, plan, err := parseFixturesArgs([]string{"--source", "claude", "--sessions", "100", "--output", "/tmp/fx"})
+	if err != nil {
+		t.Fatalf("parseFixturesArgs returned error: %v", err)
+	}
+	if output != "/tmp/fx" || len(plan) != 1 || plan["claude"].Sessions != 100 {
+		t.Fatalf("unexpected plan: %s %+v", output, plan)
+	}
+
+	if _, plan, err = parseFixturesArgs([]string{"--output", "/tmp/fx"}); err != nil || len(plan) != 6 {
+		t.Fatalf("expected every source without --source or --spec, got %+v (%v)", plan, err)
+	}
+
+	spec := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(spec, []byte(`{"sessions": 5, "seed": 3, "sources": {"codex": {"turns": 8}, "gemini": {"sessions": 50}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	_, plan, err = parseFixturesArgs([]string{"--spec", spec, "--projects", "2", "--output", "/tmp/fx"})
+	if err != nil {
+		t.Fatalf("parseFixturesArgs returned error: %v", err)
+	}
+	codex, gemini := plan["codex"], plan["gemini"]
+	if len(plan) != 2 || codex.Sessions != 5 || codex.Turns != 8 || codex.Seed != 3 || gemini.Sessions != 50 || gemini.Projects != 2 {
+		t.Fatalf("unexpected plan from spec: %+v", plan)
+	}
+
+	for _, args := range [][]string{
+		{"--source", "claude"},
+		{"--output", "/tmp/fx", "--sessions", "0"},
+		{"--output", "/tmp/fx", "extra"},
+	} {
+		if _, _, err := parseFixturesArgs(args); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// Tool 45: forget_session
+type forgetSessionArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session to forget"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+}
+
+func addForgetSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "forget_session",
+		Description: "Remove a session from the search index, for example because it contains secrets, and keep indexing from adding it back. The session file itself is not deleted. Undo with `aisessions forget <id> --source <source> --undo`.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args forgetSessionArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		removed, err := searchCache.ForgetSession(args.SessionID, args.Source, time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(map[string]interface{}{
+			"session_id":         args.SessionID,
+			"source":             args.Source,
+			"removed_from_index": removed,
+			"forgotten":          true,
+		}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// handleForgetCommand processes:
+//
+//	aisessions forget <session-id> --source <source> [--undo]
+//	aisessions forget --list
+func handleForgetCommand() {
+	const usage = "Usage: aisessions forget <session-id> --source <source> [--undo]\n       aisessions forget --list\n"
+	var sessionID, source string
+	var undo, list bool
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--source":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --source requires a value\n")
+				os.Exit(1)
+			}
+			source = args[i+1]
+			i++
+		case "--undo":
+			undo = true
+		case "--list":
+			list = true
+		default:
+			if sessionID != "" {
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			sessionID = args[i]
+		}
+	}
+	if list != (sessionID == "") || (!list && source == "") || (list && undo) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	cache, err := openConfiguredCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open search cache: %v\n", err)
+		os.Exit(1)
+	}
+	defer cache.Close()
+
+	switch {
+	case list:
+		forgotten, err := cache.ForgottenSessions()
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(forgotten) == 0 {
+			fmt.Println("No forgotten sessions")
+			return
+		}
+		for _, s := range forgotten {
+			fmt.Printf("%s  %-10s %s\n", s.ForgottenAt.Local().Format("2006-01-02 15:04"), s.Source, s.SessionID)
+		}
+	case undo:
+		restored, err := cache.UnforgetSession(sessionID, source)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !restored {
+			fmt.Printf("Session %s (%s) was not forgotten\n", sessionID, source)
+			return
+		}
+		fmt.Printf("Session %s (%s) will be indexed again on the next search\n", sessionID, source)
+	default:
+		if _, err := cache.ForgetSession(sessionID, source, time.Now()); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Forgot session %s (%s): it is out of the search index and won't be indexed again\n", sessionID, source)
+	}
+}
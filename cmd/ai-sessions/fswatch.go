@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// watchDebounce is how long a source must be quiet before its changed files
+// are indexed. Agents append to a session file once per message, so this
+// turns a burst of writes into a single reindex.
+const watchDebounce = 500 * time.Millisecond
+
+// watchableAdapter is implemented by adapters that can say where their
+// session files live, so they can be watched for changes.
+type watchableAdapter interface {
+	// WatchPaths returns the paths session files are written under.
+	// Directories are watched along with everything below them; a file is
+	// watched on its own, along with siblings that share its name as a
+	// prefix (such as SQLite -wal and -shm files).
+	WatchPaths() []string
+}
+
+// watchRoot is one watched path and the source it belongs to.
+type watchRoot struct {
+	source string
+	path   string
+	file   bool
+}
+
+// sessionWatcher indexes new and modified session files as they appear,
+// using filesystem notifications instead of listing and statting every
+// session on each search.
+type sessionWatcher struct {
+	adaptersMap map[string]adapters.SessionAdapter
+	cache       search.Store
+	watcher     *fsnotify.Watcher
+	roots       []watchRoot
+}
+
+// newSessionWatcher starts watching the storage of every watchable adapter.
+// Paths that don't exist yet are skipped; the sources they belong to are
+// left to the background indexer's passes. It returns the watcher and the
+// sources it covers.
+func newSessionWatcher(adaptersMap map[string]adapters.SessionAdapter, cache search.Store) (*sessionWatcher, []string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &sessionWatcher{adaptersMap: adaptersMap, cache: cache, watcher: watcher}
+
+	watched := make(map[string]bool)
+	for source, adapter := range adaptersMap {
+		watchable, ok := adapter.(watchableAdapter)
+		if !ok {
+			continue
+		}
+		for _, path := range watchable.WatchPaths() {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			root := watchRoot{source: source, path: filepath.Clean(path), file: !info.IsDir()}
+			if root.file {
+				err = watcher.Add(filepath.Dir(root.path))
+			} else {
+				err = w.addTree(root.path)
+			}
+			if err != nil {
+				log.Printf("Warning: cannot watch %s for %s: %v", path, source, err)
+				continue
+			}
+			w.roots = append(w.roots, root)
+			watched[source] = true
+		}
+	}
+
+	sources := make([]string, 0, len(watched))
+	for source := range watched {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return w, sources, nil
+}
+
+// addTree watches dir and every directory below it.
+func (w *sessionWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path != dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+// sourceFor returns the source whose storage path changed, if any.
+func (w *sessionWatcher) sourceFor(path string) (watchRoot, bool) {
+	for _, root := range w.roots {
+		if root.file {
+			if strings.HasPrefix(path, root.path) && filepath.Dir(path) == filepath.Dir(root.path) {
+				return root, true
+			}
+			continue
+		}
+		if path == root.path || strings.HasPrefix(path, root.path+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return watchRoot{}, false
+}
+
+// run indexes changed sessions until ctx is cancelled, then closes the watcher.
+func (w *sessionWatcher) run(ctx context.Context) {
+	defer w.watcher.Close()
+
+	pending := make(map[string]map[string]bool) // source -> changed paths
+	var flush <-chan time.Time
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: session watcher error: %v", err)
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			root, ok := w.sourceFor(event.Name)
+			if !ok {
+				continue
+			}
+			if event.Has(fsnotify.Create) && !root.file {
+				// Watch new directories, such as Codex's per-day folders
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						log.Printf("Warning: cannot watch %s: %v", event.Name, err)
+					}
+				}
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			path := event.Name
+			if root.file {
+				path = root.path // a -wal or -shm write is a database change
+			}
+			if pending[root.source] == nil {
+				pending[root.source] = make(map[string]bool)
+			}
+			pending[root.source][path] = true
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			flush = timer.C
+		case <-flush:
+			flush = nil
+			if lowPower.Load() {
+				// Searches index changed sessions themselves in low-power mode
+				pending = make(map[string]map[string]bool)
+				continue
+			}
+			for source, paths := range pending {
+				if err := ctx.Err(); err != nil {
+					return
+				}
+				indexChangedFiles(w.cache, w.adaptersMap[source], paths)
+			}
+			pending = make(map[string]map[string]bool)
+		}
+	}
+}
+
+// indexChangedFiles indexes the adapter's sessions stored in the changed
+// paths: sessions whose file changed, and sessions stored as a directory
+// named after their ID (like opencode's message folders) that had a file
+// change inside. Unchanged sessions are skipped as usual.
+func indexChangedFiles(cache search.Store, adapter adapters.SessionAdapter, paths map[string]bool) {
+	sessions, err := adapter.ListSessions("", 0)
+	if err != nil {
+		log.Printf("Error listing sessions for %s: %v", adapter.Name(), err)
+		return
+	}
+
+	dirs := make(map[string]bool)
+	for path := range paths {
+		for _, part := range strings.Split(filepath.Dir(path), string(filepath.Separator)) {
+			dirs[part] = true
+		}
+	}
+	for _, session := range sessions {
+		if paths[session.FilePath] || dirs[session.ID] {
+			indexSession(cache, adapter, session, false)
+		}
+	}
+}
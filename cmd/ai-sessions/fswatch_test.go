@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// watchedStubAdapter is a stubAdapter whose sessions live under paths.
+type watchedStubAdapter struct {
+	*stubAdapter
+	paths []string
+}
+
+func (w *watchedStubAdapter) WatchPaths() []string {
+	return w.paths
+}
+
+func TestSessionWatcherIndexesNewFiles(t *testing.T) {
+	cache := newTestCache(t)
+	dir := t.TempDir()
+	sessionFile := filepath.Join(dir, "2026", "10", "sess-1.jsonl")
+	adapter := &watchedStubAdapter{
+		stubAdapter: newStubAdapter(
+			[]adapters.Session{{ID: "sess-1", Source: "stub", FilePath: sessionFile, Timestamp: time.Now()}},
+			map[string][]adapters.Message{"sess-1": {{Role: "user", Content: "watched keyword"}}},
+		),
+		paths: []string{dir, filepath.Join(dir, "missing")},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": adapter, "other": newStubAdapter(nil, nil)}
+
+	watcher, sources, err := newSessionWatcher(adaptersMap, cache)
+	if err != nil {
+		t.Fatalf("newSessionWatcher returned error: %v", err)
+	}
+	if !reflect.DeepEqual(sources, []string{"stub"}) {
+		t.Fatalf("expected only the stub source to be watched, got %v", sources)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.run(ctx)
+
+	// The session is written into directories created after watching began
+	if err := os.MkdirAll(filepath.Dir(sessionFile), 0o755); err != nil {
+		t.Fatalf("failed to create session dir: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to write session file: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		results, err := cache.Search("watched keyword", "", "", 10)
+		if err == nil && len(results) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the new session to be indexed, got %v (%v)", results, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestIndexChangedFiles(t *testing.T) {
+	cache := newTestCache(t)
+	dir := t.TempDir()
+	var sessions []adapters.Session
+	messages := make(map[string][]adapters.Message)
+	for _, id := range []string{"changed", "folder", "untouched"} {
+		file := filepath.Join(dir, id+".json")
+		if err := os.WriteFile(file, []byte("dummy"), 0o644); err != nil {
+			t.Fatalf("failed to write session file: %v", err)
+		}
+		sessions = append(sessions, adapters.Session{ID: id, Source: "stub", FilePath: file, Timestamp: time.Now()})
+		messages[id] = []adapters.Message{{Role: "user", Content: "content of " + id}}
+	}
+	adapter := newStubAdapter(sessions, messages)
+
+	indexChangedFiles(cache, adapter, map[string]bool{
+		filepath.Join(dir, "changed.json"):                true,
+		filepath.Join(dir, "message", "folder", "m.json"): true,
+	})
+
+	for id, want := range map[string]int{"changed": 1, "folder": 1, "untouched": 0} {
+		if got := adapter.getCalls[id]; got != want {
+			t.Errorf("expected %s to be read %d times, got %d", id, want, got)
+		}
+	}
+}
+
+func TestBackgroundIndexerSkipsWatchedSourcesOnRefresh(t *testing.T) {
+	cache := newTestCache(t)
+	watched := newStubAdapter(nil, nil)
+	polled := newStubAdapter(nil, nil)
+	indexer := newBackgroundIndexer(map[string]adapters.SessionAdapter{"watched": watched, "polled": polled}, cache)
+	indexer.setWatched([]string{"watched"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.run(ctx, 0)
+	if err := indexer.awaitWarm(ctx); err != nil {
+		t.Fatalf("awaitWarm returned error: %v", err)
+	}
+	indexer.requestRefresh()
+	deadline := time.Now().Add(time.Second)
+	for indexer.currentStatus().Passes < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if watched.listCalls != 1 {
+		t.Errorf("expected the watched source to be listed only at startup, got %d", watched.listCalls)
+	}
+	if polled.listCalls < 2 {
+		t.Errorf("expected the unwatched source to be listed on refresh, got %d", polled.listCalls)
+	}
+	if status := indexer.currentStatus(); !reflect.DeepEqual(status.WatchedSources, []string{"watched"}) {
+		t.Errorf("unexpected watched sources: %v", status.WatchedSources)
+	}
+}
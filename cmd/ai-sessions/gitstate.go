@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// gitStateCaveat explains what the git comparison of a session can and can't
+// tell, returned alongside it.
+const gitStateCaveat = "workspace_git is the project's repository as it is now. commits_since counts commits on the current HEAD after the session's recorded commit or, when none was recorded, after the session started; a session whose advice touched files changed since, or that ran on another branch, may no longer apply."
+
+// gitTimeout bounds each git command, so a slow or locked repository doesn't
+// hold up the listing.
+const gitTimeout = 5 * time.Second
+
+// workspaceGitState is the current state of a project's git repository.
+type workspaceGitState struct {
+	Branch string `json:"branch,omitempty"` // empty on a detached HEAD
+	Head   string `json:"head"`
+	Dirty  bool   `json:"dirty"`
+	// ChangedFiles counts modified, staged, and untracked files
+	ChangedFiles int `json:"changed_files"`
+}
+
+// sessionGitComparison relates the repository state a session recorded to the
+// project's current state.
+type sessionGitComparison struct {
+	RecordedBranch string `json:"recorded_branch,omitempty"`
+	RecordedCommit string `json:"recorded_commit,omitempty"`
+	// SameBranch is set when the session recorded its branch
+	SameBranch *bool `json:"same_branch,omitempty"`
+	// CommitsSince is omitted when it couldn't be counted, such as when the
+	// recorded commit isn't in the repository
+	CommitsSince *int `json:"commits_since,omitempty"`
+	// Since says what CommitsSince counts from: recorded_commit or session_start
+	Since string `json:"since,omitempty"`
+}
+
+// gitStateForSessions returns the current git state of each project that
+// sessions belong to, keyed by project path, and how each session's recorded
+// state compares, keyed by session ID. Projects that aren't git repositories
+// are left out, along with their sessions.
+func gitStateForSessions(ctx context.Context, sessions []adapters.Session) (map[string]workspaceGitState, map[string]sessionGitComparison) {
+	states := make(map[string]workspaceGitState)
+	checked := make(map[string]bool)
+	comparisons := make(map[string]sessionGitComparison)
+	for _, session := range sessions {
+		project := session.ProjectPath
+		if project == "" {
+			continue
+		}
+		if !checked[project] {
+			checked[project] = true
+			if state, ok := currentGitState(ctx, project); ok {
+				states[project] = state
+			}
+		}
+		state, ok := states[project]
+		if !ok {
+			continue
+		}
+		comparisons[session.ID] = compareGitState(ctx, project, state, session)
+	}
+	return states, comparisons
+}
+
+// currentGitState reads the HEAD, branch, and working tree status of the
+// repository at dir. It reports false if dir isn't in a git repository or
+// git isn't installed.
+func currentGitState(ctx context.Context, dir string) (workspaceGitState, bool) {
+	head, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return workspaceGitState{}, false
+	}
+	state := workspaceGitState{Head: head}
+	if branch, err := runGit(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		state.Branch = branch
+	}
+	if status, err := runGit(ctx, dir, "status", "--porcelain"); err == nil && status != "" {
+		state.Dirty = true
+		state.ChangedFiles = len(strings.Split(status, "\n"))
+	}
+	return state, true
+}
+
+// compareGitState compares the state session recorded with the current state
+// of its project's repository at dir.
+func compareGitState(ctx context.Context, dir string, state workspaceGitState, session adapters.Session) sessionGitComparison {
+	comparison := sessionGitComparison{
+		RecordedBranch: session.GitBranch,
+		RecordedCommit: session.GitCommit,
+	}
+	if session.GitBranch != "" && state.Branch != "" {
+		same := session.GitBranch == state.Branch
+		comparison.SameBranch = &same
+	}
+
+	var count string
+	var err error
+	switch {
+	case session.GitCommit != "":
+		count, err = runGit(ctx, dir, "rev-list", "--count", session.GitCommit+"..HEAD")
+		comparison.Since = "recorded_commit"
+	case !session.Timestamp.IsZero():
+		count, err = runGit(ctx, dir, "rev-list", "--count", "--since="+session.Timestamp.Format(time.RFC3339), "HEAD")
+		comparison.Since = "session_start"
+	default:
+		return comparison
+	}
+	if err != nil {
+		comparison.Since = ""
+		return comparison
+	}
+	if n, err := strconv.Atoi(count); err == nil {
+		comparison.CommitsSince = &n
+	} else {
+		comparison.Since = ""
+	}
+	return comparison
+}
+
+// runGit runs a git command in dir and returns its output without the
+// trailing newline.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// gitStateResult is the git state of sessions' projects and each session's
+// comparison with it, as added to tool results on request.
+type gitStateResult struct {
+	WorkspaceGit  map[string]workspaceGitState    `json:"workspace_git,omitempty"`
+	GitComparison map[string]sessionGitComparison `json:"git_comparison,omitempty"`
+	GitCaveat     string                          `json:"git_caveat,omitempty"`
+}
+
+// gitState returns the git state of sessions' projects, which is empty when
+// none is a repository.
+func gitState(ctx context.Context, sessions []adapters.Session) gitStateResult {
+	states, comparisons := gitStateForSessions(ctx, sessions)
+	if len(states) == 0 {
+		return gitStateResult{}
+	}
+	return gitStateResult{WorkspaceGit: states, GitComparison: comparisons, GitCaveat: gitStateCaveat}
+}
+
+// addGitState adds the git state of sessions' projects and each session's
+// comparison with it to a tool result, when any project is a repository.
+func addGitState(ctx context.Context, result map[string]interface{}, sessions []adapters.Session) {
+	state := gitState(ctx, sessions)
+	if state.WorkspaceGit == nil {
+		return
+	}
+	result["workspace_git"] = state.WorkspaceGit
+	result["git_comparison"] = state.GitComparison
+	result["git_caveat"] = state.GitCaveat
+}
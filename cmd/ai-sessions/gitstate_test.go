@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestGitStateForSessions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	commit := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(name), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		git("add", name)
+		git("commit", "-q", "-m", name)
+	}
+
+	git("init", "-q", "-b", "main")
+	commit("a.txt")
+	first, err := runGit(context.Background(), repo, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("rev-parse failed: %v", err)
+	}
+	commit("b.txt")
+	commit("c.txt")
+	if err := os.WriteFile(filepath.Join(repo, "untracked.txt"), nil, 0o600); err != nil {
+		t.Fatalf("failed to write untracked file: %v", err)
+	}
+
+	sessions := []adapters.Session{
+		{ID: "recorded", ProjectPath: repo, GitBranch: "feature", GitCommit: first},
+		{ID: "timed", ProjectPath: repo, Timestamp: time.Now().Add(time.Hour)},
+		{ID: "elsewhere", ProjectPath: t.TempDir()},
+	}
+	states, comparisons := gitStateForSessions(context.Background(), sessions)
+
+	state, ok := states[repo]
+	if !ok || len(states) != 1 {
+		t.Fatalf("expected only the repository's state, got %+v", states)
+	}
+	if state.Branch != "main" || !state.Dirty || state.ChangedFiles != 1 || len(state.Head) != 40 {
+		t.Fatalf("unexpected workspace state: %+v", state)
+	}
+
+	recorded := comparisons["recorded"]
+	if recorded.SameBranch == nil || *recorded.SameBranch || recorded.CommitsSince == nil || *recorded.CommitsSince != 2 || recorded.Since != "recorded_commit" {
+		t.Fatalf("unexpected comparison for recorded commit: %+v", recorded)
+	}
+	timed := comparisons["timed"]
+	if timed.SameBranch != nil || timed.CommitsSince == nil || *timed.CommitsSince != 0 || timed.Since != "session_start" {
+		t.Fatalf("unexpected comparison for session start: %+v", timed)
+	}
+	if _, ok := comparisons["elsewhere"]; ok {
+		t.Fatal("expected no comparison for a project outside git")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// httpShutdownTimeout bounds how long in-flight requests get to finish when
+// the HTTP server stops.
+const httpShutdownTimeout = 5 * time.Second
+
+// clientScopeKey is the request context key holding the scope a request was
+// admitted with.
+type clientScopeKey struct{}
+
+// serveHTTP serves the tools over streamable HTTP on addr until ctx is
+// cancelled or the process is interrupted, then lets in-flight requests finish.
+func serveHTTP(ctx context.Context, deps serverDeps, addr string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	path, err := getClientsPath()
+	if err != nil {
+		return err
+	}
+	registry, err := loadClientRegistry(path)
+	if err != nil {
+		return err
+	}
+	handler, err := httpHandler(deps, registry)
+	if err != nil {
+		return err
+	}
+	if len(registry.list()) == 0 && !loopbackAddr(addr) {
+		log.Printf("Warning: no clients are registered, so only local clients can connect; register remote ones with 'aisessions clients add'")
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving MCP over streamable HTTP on %s", addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// httpHandler serves the tools over the streamable HTTP transport, at any
+// path. Each client scope gets its own server instance without the tools the
+// scope doesn't allow, and each session is served by the instance for the
+// scope it was admitted with.
+func httpHandler(deps serverDeps, registry *clientRegistry) (http.Handler, error) {
+	tools, err := serverToolNames(newServer(deps))
+	if err != nil {
+		return nil, err
+	}
+	servers := make(map[string]*mcp.Server)
+	for _, scope := range clientScopes {
+		scopeDeps := deps
+		if scope != scopeWrite {
+			// Only clients that may write get tools that write files
+			scopeDeps.flags.allowWrite = false
+		}
+		server := newServer(scopeDeps)
+		applyClientScope(server, scope, tools)
+		if deps.logger != nil {
+			deps.logger.attach(server)
+		}
+		servers[scope] = server
+	}
+
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		scope, _ := r.Context().Value(clientScopeKey{}).(string)
+		return servers[scope]
+	}, nil)
+	return requireClient(registry, handler), nil
+}
+
+// requireClient admits requests that carry a registered client's bearer token,
+// with that client's scope. Requests from this machine may leave the token
+// out, and are admitted with full access like stdio clients, as long as they
+// are addressed to a loopback name and don't come from a web page elsewhere.
+// That keeps a page in the user's browser from reaching the server through
+// DNS rebinding.
+func requireClient(registry *clientRegistry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := scopeWrite
+		header := r.Header.Get("Authorization")
+		if header == "" && loopbackAddr(r.RemoteAddr) && !localRequest(r) {
+			http.Error(w, "requests from other sites need a registered client token", http.StatusForbidden)
+			return
+		}
+		if header != "" || !loopbackAddr(r.RemoteAddr) {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			client, found := registry.authenticate(strings.TrimSpace(token))
+			if !ok || !found {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ai-sessions"`)
+				http.Error(w, "a registered client token is required", http.StatusUnauthorized)
+				return
+			}
+			scope = client.Scope
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientScopeKey{}, scope)))
+	})
+}
+
+// localRequest reports whether a request is addressed to a loopback name and,
+// if it comes from a web page, whether the page is served from one too.
+func localRequest(r *http.Request) bool {
+	if !loopbackHost(r.Host) {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && loopbackHost(u.Host)
+}
+
+// serverToolNames returns the names of the tools registered on server, by
+// listing them over an in-memory connection.
+func serverToolNames(server *mcp.Server) ([]string, error) {
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "ai-sessions", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	defer clientSession.Close()
+
+	var names []string
+	for tool, err := range clientSession.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		names = append(names, tool.Name)
+	}
+	return names, nil
+}
+
+// loopbackAddr reports whether addr, a host and port, is on the loopback
+// interface. An address without a host, such as ":8080", is not.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return loopbackName(host)
+}
+
+// loopbackHost reports whether host, as in a Host header, with or without a
+// port, names the loopback interface.
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return loopbackName(strings.Trim(host, "[]"))
+}
+
+// loopbackName reports whether host is "localhost" or a loopback IP.
+func loopbackName(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
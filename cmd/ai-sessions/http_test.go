@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	registry, err := loadClientRegistry(filepath.Join(t.TempDir(), "clients.json"))
+	if err != nil {
+		t.Fatalf("loadClientRegistry failed: %v", err)
+	}
+	token, err := registry.add("laptop", scopeList)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, nil)}
+	cache := newTestCache(t)
+	handler, err := httpHandler(serverDeps{
+		config:      &ServerConfig{},
+		adaptersMap: adaptersMap,
+		searchCache: cache,
+		indexer:     newBackgroundIndexer(adaptersMap, cache),
+		operations:  newOperationManager(),
+	}, registry)
+	if err != nil {
+		t.Fatalf("httpHandler failed: %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	// connect returns the tools a client sees, sending token if set
+	connect := func(token string) map[string]bool {
+		t.Helper()
+		transport := &mcp.StreamableClientTransport{Endpoint: httpServer.URL + "/mcp", MaxRetries: -1}
+		if token != "" {
+			transport.HTTPClient = &http.Client{Transport: bearerTransport{token: token, base: http.DefaultTransport}}
+		}
+		client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), transport, nil)
+		if err != nil {
+			t.Fatalf("client connect: %v", err)
+		}
+		defer session.Close()
+		tools := make(map[string]bool)
+		for tool, err := range session.Tools(context.Background(), nil) {
+			if err != nil {
+				t.Fatalf("listing tools: %v", err)
+			}
+			tools[tool.Name] = true
+		}
+		return tools
+	}
+
+	// Local clients need no token and get every tool...
+	if tools := connect(""); !tools["get_session"] || !tools["list_sessions"] {
+		t.Fatalf("expected a local client to see every tool, got %v", tools)
+	}
+	// ...while a registered client is limited to its scope
+	if tools := connect(token); tools["get_session"] || tools["search_sessions"] || !tools["list_sessions"] {
+		t.Fatalf("expected a list-scoped client to see only list tools, got %v", tools)
+	}
+}
+
+func TestRequireClient(t *testing.T) {
+	registry, err := loadClientRegistry(filepath.Join(t.TempDir(), "clients.json"))
+	if err != nil {
+		t.Fatalf("loadClientRegistry failed: %v", err)
+	}
+	token, err := registry.add("laptop", scopeSearch)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	var admitted string
+	handler := requireClient(registry, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admitted, _ = r.Context().Value(clientScopeKey{}).(string)
+	}))
+
+	tests := []struct {
+		remote, authorization string
+		host, origin          string
+		status                int
+		scope                 string
+	}{
+		{remote: "192.0.2.1:4000", status: http.StatusUnauthorized},
+		{remote: "192.0.2.1:4000", authorization: "Bearer ais_wrong", status: http.StatusUnauthorized},
+		{remote: "192.0.2.1:4000", authorization: "Bearer " + token, status: http.StatusOK, scope: scopeSearch},
+		{remote: "127.0.0.1:4000", status: http.StatusOK, scope: scopeWrite},
+		{remote: "127.0.0.1:4000", authorization: "Bearer ais_wrong", status: http.StatusUnauthorized},
+		{remote: "127.0.0.1:4000", host: "localhost:8080", origin: "http://localhost:3000", status: http.StatusOK, scope: scopeWrite},
+		{remote: "[::1]:4000", host: "[::1]:8080", status: http.StatusOK, scope: scopeWrite},
+		// A page elsewhere can't use the local exemption, through DNS rebinding or otherwise
+		{remote: "127.0.0.1:4000", host: "attacker.example:8080", status: http.StatusForbidden},
+		{remote: "127.0.0.1:4000", host: "localhost:8080", origin: "http://attacker.example", status: http.StatusForbidden},
+		{remote: "127.0.0.1:4000", host: "attacker.example:8080", authorization: "Bearer " + token, status: http.StatusOK, scope: scopeSearch},
+	}
+	for _, tt := range tests {
+		admitted = ""
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.RemoteAddr = tt.remote
+		req.Host = "127.0.0.1:8080"
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status || admitted != tt.scope {
+			t.Errorf("request from %s to %s with %q: got %d admitted as %q, want %d as %q", tt.remote, req.Host, tt.authorization, rec.Code, admitted, tt.status, tt.scope)
+		}
+	}
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+	} {
+		if got := loopbackAddr(addr); got != want {
+			t.Errorf("loopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
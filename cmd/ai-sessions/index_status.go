@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// Tool 46: index_status
+type indexStatusArgs struct{}
+
+func addIndexStatusTool(server *mcp.Server, searchCache search.Store) {
+	addTool(server, &mcp.Tool{
+		Name:        "index_status",
+		Description: "Inspect the search index: where it is stored, its size on disk, the number of indexed sessions, quarantined and forgotten sessions, and per source how many sessions are indexed and when one was last indexed",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args indexStatusArgs) (*mcp.CallToolResult, any, error) {
+		status, err := searchCache.Status()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 47: rebuild_index
+type rebuildIndexArgs struct {
+	Source string `json:"source,omitempty" jsonschema:"Only rebuild sessions from this source. Leave empty to rebuild the whole index."`
+}
+
+func addRebuildIndexTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, operations *operationManager) {
+	addTool(server, &mcp.Tool{
+		Name:        "rebuild_index",
+		Description: "Reset the search index and rebuild it from the session files in the background, for when it is corrupt or out of date. Notes, bookmarks, and forgotten sessions are kept. Returns an operation_id to poll with get_operation_status; searches return fewer results until it finishes.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args rebuildIndexArgs) (*mcp.CallToolResult, any, error) {
+		if args.Source != "" {
+			if _, ok := adaptersMap[args.Source]; !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+		}
+
+		removed, err := searchCache.ResetIndex(args.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		status := operations.start("rebuild_index", func(ctx context.Context, progress func(done, total int)) (interface{}, error) {
+			var total int
+			err := indexSessionsContext(ctx, adaptersMap, searchCache, args.Source, "", indexOptions{
+				force: true,
+				progress: func(done, n int) {
+					total = n
+					progress(done, n)
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]int{"removed": removed, "sessions": total}, nil
+		})
+
+		return operationResult(status)
+	})
+}
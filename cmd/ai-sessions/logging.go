@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// clientLogLevels are the MCP logging levels, least severe first.
+var clientLogLevels = []mcp.LoggingLevel{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// defaultClientLogLevel is the least severe level forwarded to clients when
+// the server config doesn't set one. Clients narrow it further with
+// logging/setLevel, and hear nothing until they call it.
+const defaultClientLogLevel = "info"
+
+// pendingClientLogs caps how many log lines wait to be forwarded. Lines
+// logged while the queue is full are only written to stderr.
+const pendingClientLogs = 64
+
+// clientLogLevel parses the configured client_log_level, returning "" when
+// forwarding is off.
+func (c *ServerConfig) clientLogLevel() (mcp.LoggingLevel, error) {
+	switch c.ClientLogLevel {
+	case "":
+		return defaultClientLogLevel, nil
+	case "off":
+		return "", nil
+	}
+	level := mcp.LoggingLevel(c.ClientLogLevel)
+	if !slices.Contains(clientLogLevels, level) {
+		return defaultClientLogLevel, fmt.Errorf("invalid client_log_level %q", c.ClientLogLevel)
+	}
+	return level, nil
+}
+
+// clientLogger is the standard logger's output while serving. It writes each
+// line to stderr as before and forwards it to connected clients as an MCP
+// logging notification, so hosts can tell the user when a source degrades,
+// such as opencode falling back from its database to slower flat files.
+// Lines are forwarded from a goroutine, so logging never waits on a client.
+// Every connected client receives every line, including ones caused by another
+// client's request, so forwarded lines have their identifiers redacted.
+type clientLogger struct {
+	out     io.Writer
+	floor   mcp.LoggingLevel // least severe level forwarded; "" forwards nothing
+	pending chan *mcp.LoggingMessageParams
+
+	mu      sync.Mutex
+	servers []*mcp.Server
+}
+
+// newClientLogger creates a logger writing to out and forwarding lines at
+// floor or above, and starts forwarding.
+func newClientLogger(out io.Writer, floor mcp.LoggingLevel) *clientLogger {
+	l := &clientLogger{
+		out:     out,
+		floor:   floor,
+		pending: make(chan *mcp.LoggingMessageParams, pendingClientLogs),
+	}
+	go l.forward()
+	return l
+}
+
+// install makes l the standard logger's output. l adds the timestamp itself,
+// so the line it forwards is just the message.
+func (l *clientLogger) install() {
+	log.SetFlags(0)
+	log.SetOutput(l)
+}
+
+// attach forwards log lines to the sessions of servers.
+func (l *clientLogger) attach(servers ...*mcp.Server) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.servers = append(l.servers, servers...)
+}
+
+func (l *clientLogger) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(l.out, "%s %s", time.Now().Format("2006/01/02 15:04:05"), p); err != nil {
+		return 0, err
+	}
+
+	message := strings.TrimSuffix(string(p), "\n")
+	level := logLineLevel(message)
+	if l.floor == "" || slices.Index(clientLogLevels, level) < slices.Index(clientLogLevels, l.floor) {
+		return len(p), nil
+	}
+	select {
+	case l.pending <- &mcp.LoggingMessageParams{Level: level, Logger: "ai-sessions", Data: redactLogLine(message)}:
+	default: // clients are behind; stderr still has the line
+	}
+	return len(p), nil
+}
+
+// forward sends queued lines to every session of the attached servers. The
+// SDK drops lines below the level each client set.
+func (l *clientLogger) forward() {
+	for params := range l.pending {
+		l.mu.Lock()
+		servers := slices.Clone(l.servers)
+		l.mu.Unlock()
+		for _, server := range servers {
+			for session := range server.Sessions() {
+				// Failures aren't logged, which would only queue more lines
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_ = session.Log(ctx, params)
+				cancel()
+			}
+		}
+	}
+}
+
+var (
+	// logPathPattern matches absolute and home-relative paths, which name
+	// projects and session files.
+	logPathPattern = regexp.MustCompile(`(^|[\s"'(=])(?:~?/|[A-Za-z]:\\)[^\s"',:;()]*`)
+	// logQuotedPattern matches quoted text, such as a query in an error.
+	logQuotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	// logIDPattern matches session IDs, hashes, and other tokens of eight or
+	// more characters that mix letters and digits.
+	logIDPattern = regexp.MustCompile(`[0-9A-Za-z_-]{8,}`)
+)
+
+// redactLogLine strips paths, quoted text, and identifiers from a log line
+// before it is forwarded, so one client can't learn another's projects,
+// sessions, or queries from it. What happened, such as a source falling
+// back or failing to list, is still readable.
+func redactLogLine(line string) string {
+	line = logPathPattern.ReplaceAllString(line, "${1}<path>")
+	line = logQuotedPattern.ReplaceAllString(line, "<text>")
+	return logIDPattern.ReplaceAllStringFunc(line, func(token string) string {
+		if strings.ContainsAny(token, "0123456789") && strings.ContainsFunc(token, unicode.IsLetter) {
+			return "<id>"
+		}
+		return token
+	})
+}
+
+// logLineLevel infers a log line's level from how the server words it:
+// "Warning: ..." for warnings, and "Error ..." or "Failed ..." for errors.
+func logLineLevel(line string) mcp.LoggingLevel {
+	switch {
+	case strings.HasPrefix(line, "Warning"):
+		return "warning"
+	case strings.HasPrefix(line, "Error"), strings.HasPrefix(line, "Failed"):
+		return "error"
+	}
+	return "info"
+}
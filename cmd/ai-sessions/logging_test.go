@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestClientLoggerForwardsLogLines(t *testing.T) {
+	var stderr bytes.Buffer
+	logger := newClientLogger(&stderr, "info")
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	logger.attach(server)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	received := make(chan *mcp.LoggingMessageParams, 10)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ClientOptions{
+		LoggingMessageHandler: func(_ context.Context, req *mcp.LoggingMessageRequest) {
+			received <- req.Params
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+	if err := clientSession.SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: "warning"}); err != nil {
+		t.Fatalf("SetLoggingLevel: %v", err)
+	}
+
+	// Lines below the level the client set reach stderr only
+	std := log.New(logger, "", 0)
+	std.Printf("Indexed 3 sessions")
+	std.Printf("Warning: opencode database unavailable, using file fallback: database is locked")
+
+	select {
+	case params := <-received:
+		if params.Level != "warning" || params.Data != "Warning: opencode database unavailable, using file fallback: database is locked" {
+			t.Fatalf("unexpected log notification: %+v", params)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the warning to reach the client")
+	}
+	select {
+	case params := <-received:
+		t.Fatalf("expected only the warning to be forwarded, also got %+v", params)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !strings.Contains(stderr.String(), "Indexed 3 sessions") || !strings.Contains(stderr.String(), "database is locked") {
+		t.Fatalf("expected every line on stderr, got %q", stderr.String())
+	}
+}
+
+func TestClientLogLevel(t *testing.T) {
+	tests := []struct {
+		configured string
+		want       mcp.LoggingLevel
+		wantErr    bool
+	}{
+		{configured: "", want: "info"},
+		{configured: "warning", want: "warning"},
+		{configured: "off", want: ""},
+		{configured: "loud", want: "info", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := (&ServerConfig{ClientLogLevel: tt.configured}).clientLogLevel()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("clientLogLevel(%q) = %q, %v; want %q (error: %v)", tt.configured, got, err, tt.want, tt.wantErr)
+		}
+	}
+	for line, want := range map[string]mcp.LoggingLevel{
+		"Warning: skipping federation peer": "warning",
+		"Error indexing session s1: boom":   "error",
+		"Failed to list sessions":           "error",
+		"Serving MCP over streamable HTTP":  "info",
+	} {
+		if got := logLineLevel(line); got != want {
+			t.Errorf("logLineLevel(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestRedactLogLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			line: "Error indexing session 3f2b9c1e-7a4d-4e0b-9c55-0d1e2f3a4b5c: open /home/ana/.claude/projects/-home-ana-secret/3f2b9c1e.jsonl: permission denied",
+			want: "Error indexing session <id>: open <path>: permission denied",
+		},
+		{
+			line: "Warning: consent prompt failed for /home/ana/secret: context canceled",
+			want: "Warning: consent prompt failed for <path>: context canceled",
+		},
+		{
+			line: `Warning: cannot watch C:\Users\ana\secret for claude: too many open files`,
+			want: "Warning: cannot watch <path> for claude: too many open files",
+		},
+		{
+			line: `Error reading session ses_01HZX4: query "deploy credentials": fts5 syntax error`,
+			want: "Error reading session <id>: query <text>: fts5 syntax error",
+		},
+		{
+			line: "Warning: opencode database unavailable, using file fallback: database is locked",
+			want: "Warning: opencode database unavailable, using file fallback: database is locked",
+		},
+	}
+	for _, tt := range tests {
+		if got := redactLogLine(tt.line); got != tt.want {
+			t.Errorf("redactLogLine(%q)\n got %q\nwant %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/yoavf/ai-sessions-mcp/adapters"
@@ -24,74 +26,309 @@ type paginationCapableAdapter interface {
 	GetSessionPage(sessionID string, page, pageSize int, fromEnd bool) ([]adapters.Message, int, int, bool, error)
 }
 
+// pageStreamingAdapter is implemented by adapters that can hand over a page's
+// messages one at a time as they are parsed.
+type pageStreamingAdapter interface {
+	StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(adapters.Message) error) (int, int, bool, error)
+}
+
+type sessionInfoCapableAdapter interface {
+	GetSessionInfo(sessionID string) (adapters.Session, error)
+}
+
+// serverFlags are options accepted when running as an MCP server.
+type serverFlags struct {
+	noCache    bool   // keep the search index in memory instead of ~/.cache
+	allowWrite bool   // register tools that write files outside the cache
+	httpAddr   string // serve over streamable HTTP on this address instead of stdio
+}
+
+// parseServerFlags parses server options. It reports false if args contain
+// anything else, in which case they are treated as a CLI command.
+func parseServerFlags(args []string) (serverFlags, bool) {
+	var flags serverFlags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--no-cache", arg == "--in-memory":
+			flags.noCache = true
+		case arg == "--allow-write":
+			flags.allowWrite = true
+		case arg == "--http" && i+1 < len(args):
+			i++
+			flags.httpAddr = args[i]
+		case strings.HasPrefix(arg, "--http="):
+			flags.httpAddr = strings.TrimPrefix(arg, "--http=")
+		default:
+			return serverFlags{}, false
+		}
+	}
+	return flags, true
+}
+
 func main() {
 	// Check if running in CLI mode (has command arguments)
-	if len(os.Args) > 1 {
+	flags, isServer := parseServerFlags(os.Args[1:])
+	if !isServer {
 		handleCLI()
 		return
 	}
 
 	// Otherwise, run as MCP server
-	// Create the MCP server with metadata
-	opts := &mcp.ServerOptions{
-		Instructions: "This server provides access to AI assistant CLI sessions from Claude Code, Gemini CLI, OpenAI Codex, opencode, Mistral Vibe, and GitHub Copilot CLI. Use the tools to search, list, and read previous coding sessions.",
-	}
-
-	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "ai-sessions",
-		Version: "1.0.0",
-	}, opts)
+	// Initialize adapters registered by the adapters package (and any build-tagged extensions)
+	adaptersMap, _ := adapters.NewRegistered()
 
-	// Initialize adapters
-	adaptersMap := make(map[string]adapters.SessionAdapter)
-	if claudeAdapter, err := adapters.NewClaudeAdapter(); err == nil {
-		adaptersMap["claude"] = claudeAdapter
-	}
-	if geminiAdapter, err := adapters.NewGeminiAdapter(); err == nil {
-		adaptersMap["gemini"] = geminiAdapter
+	// Load optional server config and add config-driven adapters
+	serverConfig, err := loadServerConfig()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		serverConfig = &ServerConfig{}
 	}
-	if codexAdapter, err := adapters.NewCodexAdapter(); err == nil {
-		adaptersMap["codex"] = codexAdapter
+	// Forward log lines to clients as well as stderr
+	logLevel, err := serverConfig.clientLogLevel()
+	logger := newClientLogger(os.Stderr, logLevel)
+	logger.install()
+	if err != nil {
+		log.Printf("Warning: %v", err)
 	}
-	if opencodeAdapter, err := adapters.NewOpencodeAdapter(); err == nil {
-		adaptersMap["opencode"] = opencodeAdapter
+	for _, err := range addConfiguredAdapters(adaptersMap, serverConfig) {
+		log.Printf("Warning: skipping configured adapter: %v", err)
 	}
-	if mistralAdapter, err := adapters.NewMistralAdapter(); err == nil {
-		adaptersMap["mistral"] = mistralAdapter
+	peers, errs := newFederation(serverConfig.FederationPeers)
+	for _, err := range errs {
+		log.Printf("Warning: skipping federation peer: %v", err)
 	}
-	if copilotAdapter, err := adapters.NewCopilotAdapter(); err == nil {
-		adaptersMap["copilot"] = copilotAdapter
+	consent, err := newProjectConsent(serverConfig)
+	if err != nil {
+		log.Fatalf("Failed to load project consent: %v", err)
 	}
 
 	// Initialize search cache
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatalf("Failed to get home directory: %v", err)
-	}
-	cachePath := filepath.Join(homeDir, ".cache", "ai-sessions", "search.db")
-	searchCache, err := search.NewCache(cachePath)
+	searchCache, err := openSearchCache(flags, serverConfig.Cache)
 	if err != nil {
 		log.Fatalf("Failed to initialize search cache: %v", err)
 	}
 	defer searchCache.Close()
+	useFileCaches(adaptersMap, searchCache)
+	if serverConfig.DefaultRanker != "" {
+		if err := searchCache.SetDefaultRanker(serverConfig.DefaultRanker); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
 
-	// Add tools with strongly-typed argument structures
-	addListAvailableSourcesTool(server, adaptersMap)
-	addListSessionsTool(server, adaptersMap)
-	addSearchSessionsTool(server, adaptersMap, searchCache)
-	addGetSessionTool(server, adaptersMap)
+	adapters.SetLimits(serverConfig.Limits)
+	if serverConfig.LowPower {
+		setPowerMode(powerLow, nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Index in the background from startup, so searches don't wait for it
+	interval, err := serverConfig.indexPollInterval()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	indexer := newBackgroundIndexer(adaptersMap, searchCache)
+	if serverConfig.WatchSessions {
+		if watcher, sources, err := newSessionWatcher(adaptersMap, searchCache); err != nil {
+			log.Printf("Warning: cannot watch session directories: %v", err)
+		} else {
+			indexer.setWatched(sources)
+			go watcher.run(ctx)
+		}
+	}
+	go indexer.run(ctx, interval)
+	var maint *maintainer
+	if schedule, ok, err := serverConfig.maintenanceSchedule(); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if ok {
+		maint = &maintainer{schedule: schedule, retention: serverConfig.Cache.Retention}
+		go maint.run(ctx, adaptersMap, searchCache)
+	}
+
+	deps := serverDeps{
+		flags:       flags,
+		config:      serverConfig,
+		adaptersMap: adaptersMap,
+		searchCache: searchCache,
+		consent:     consent,
+		peers:       peers,
+		indexer:     indexer,
+		maint:       maint,
+		operations:  newOperationManager(),
+		logger:      logger,
+	}
 
-	// Run the server over stdio
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+	// Serve over streamable HTTP when asked, or over stdio
+	if flags.httpAddr != "" {
+		if err := serveHTTP(ctx, deps, flags.httpAddr); err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+		return
+	}
+	server := newServer(deps)
+	logger.attach(server)
+	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// serverDeps is what the server's tools share, however many server instances
+// serve them.
+type serverDeps struct {
+	flags       serverFlags
+	config      *ServerConfig
+	adaptersMap map[string]adapters.SessionAdapter
+	searchCache search.Store
+	consent     *projectConsent
+	peers       *federation
+	indexer     *backgroundIndexer
+	maint       *maintainer
+	operations  *operationManager
+	logger      *clientLogger // forwards log lines to clients; nil in tests
+}
+
+// newServer creates an MCP server with every tool registered.
+func newServer(deps serverDeps) *mcp.Server {
+	opts := &mcp.ServerOptions{
+		CompletionHandler: completeArguments(deps.adaptersMap, deps.consent),
+		Instructions:      "This server provides access to AI assistant CLI sessions from Claude Code, Gemini CLI, OpenAI Codex, opencode, Mistral Vibe, and GitHub Copilot CLI. Use the tools to search, list, and read previous coding sessions.",
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "ai-sessions",
+		Version: "1.0.0",
+	}, opts)
+
+	// Add tools with strongly-typed argument structures
+	addListAvailableSourcesTool(server, deps.adaptersMap)
+	addListSessionsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addChangesSinceTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addListProjectsTool(server, deps.adaptersMap, deps.consent)
+	addGetDiagnosticsTool(server, deps.adaptersMap, deps.searchCache, deps.config, deps.maint, deps.indexer)
+	addIndexStatusTool(server, deps.searchCache)
+	addGroupByTaskTool(server, deps.adaptersMap, deps.consent)
+	addSearchSessionsTool(server, deps.adaptersMap, deps.searchCache, deps.consent, deps.peers, deps.indexer)
+	addFindSessionsByFileTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addFileHistoryTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addCompareSessionsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addFindRelatedSessionsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addGetSessionTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addGetFirstAndLastExchangeTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addGetLastSessionTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addGetMessagesTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addAnnotateSessionTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addForgetSessionTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addBookmarkMessageTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addListBookmarksTool(server, deps.searchCache, deps.consent)
+	addLookupContentHashTool(server, deps.searchCache, deps.consent)
+	addGetAccessLogTool(server, deps.searchCache)
+	addGetSessionTreeTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addGetSearchSyntaxTool(server, deps.adaptersMap, deps.searchCache)
+	addGetSessionStatsTool(server, deps.adaptersMap, deps.consent)
+	addGetSessionTimelineTool(server, deps.adaptersMap, deps.consent)
+	addListFilesTouchedTool(server, deps.adaptersMap, deps.consent)
+	addGetAgentUsageTool(server, deps.adaptersMap, deps.consent)
+	addGetModelUsageTool(server, deps.adaptersMap, deps.consent)
+	addGetCostReportTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addCostReportTool(server, deps.adaptersMap, deps.consent)
+	addUsageStatsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addStorageReportTool(server, deps.adaptersMap, deps.consent)
+	addDiagnoseSourcesTool(server, deps.adaptersMap, deps.consent)
+	addGetToolCallsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addListToolFailuresTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addDetectTodosTool(server, deps.adaptersMap, deps.consent)
+	addGetToolTimingsTool(server, deps.adaptersMap, deps.consent)
+	addExtractCodeBlocksTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addExtractShellCommandsTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addExportSessionTool(server, deps.adaptersMap, deps.searchCache, deps.consent, deps.flags.allowWrite)
+	addExportReviewChecklistTool(server, deps.adaptersMap, deps.searchCache, deps.consent, deps.flags.allowWrite)
+	addGenerateResumeContextTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	addSearchInSessionTool(server, deps.adaptersMap, deps.consent)
+	addListSnapshotsTool(server, deps.adaptersMap, deps.consent)
+	addGetSessionPersonasTool(server, deps.adaptersMap, deps.consent)
+	addSetPowerModeTool(server, deps.indexer)
+	addSessionResource(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	if deps.flags.allowWrite {
+		addExportSnapshotTool(server, deps.adaptersMap, deps.searchCache, deps.consent)
+	}
+
+	// Long-running operations report progress through get_operation_status
+	addReindexSessionsTool(server, deps.adaptersMap, deps.searchCache, deps.operations)
+	addRebuildIndexTool(server, deps.adaptersMap, deps.searchCache, deps.operations)
+	addOperationTools(server, deps.operations)
+	applyAggregatesOnly(server, deps.config)
+	return server
+}
+
+// openSearchCache opens the search index with the configured backend, or an
+// in-memory one with --no-cache. Either way the index is built lazily the
+// first time a search needs it.
+func openSearchCache(flags serverFlags, config CacheConfig) (search.Store, error) {
+	if flags.noCache {
+		config.Backend, config.DSN = search.BackendMemory, ""
+	}
+	return config.open()
+}
+
+// openConfiguredCache opens the search index the server config selects, for
+// CLI commands that share the server's index.
+func openConfiguredCache() (search.Store, error) {
+	config, err := loadServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.Cache.open()
+}
+
+// metadataCachingAdapter is implemented by adapters that can cache the
+// metadata they parse out of session files, so listing skips unchanged files.
+type metadataCachingAdapter interface {
+	SetMetadataCache(cache adapters.MetadataCache)
+}
+
+// offsetCachingAdapter is implemented by adapters that can cache where each
+// message starts in their session files, so reading a page seeks to it.
+type offsetCachingAdapter interface {
+	SetOffsetCache(cache adapters.OffsetCache)
+}
+
+// pathCachingAdapter is implemented by adapters that can record which file
+// holds each session, so finding one by ID doesn't read every file.
+type pathCachingAdapter interface {
+	SetPathCache(cache adapters.PathCache)
+}
+
+// useFileCaches gives every adapter that can cache what it reads from session
+// files the index to keep it in.
+func useFileCaches(adaptersMap map[string]adapters.SessionAdapter, cache search.Store) {
+	for _, adapter := range adaptersMap {
+		if caching, ok := adapter.(metadataCachingAdapter); ok {
+			caching.SetMetadataCache(cache)
+		}
+		if caching, ok := adapter.(offsetCachingAdapter); ok {
+			caching.SetOffsetCache(cache)
+		}
+		if caching, ok := adapter.(pathCachingAdapter); ok {
+			caching.SetPathCache(cache)
+		}
+	}
+}
+
+// defaultCachePath returns the location of the on-disk search cache.
+func defaultCachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".cache", "ai-sessions", "search.db"), nil
+}
+
 // Tool 1: list_available_sources
 type listAvailableSourcesArgs struct{}
 
 func addListAvailableSourcesTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter) {
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "list_available_sources",
 		Description: "List which AI CLI sources have sessions available (e.g., claude, gemini, codex, opencode)",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args listAvailableSourcesArgs) (*mcp.CallToolResult, any, error) {
@@ -123,137 +360,319 @@ func addListAvailableSourcesTool(server *mcp.Server, adaptersMap map[string]adap
 
 // Tool 2: list_sessions
 type listSessionsArgs struct {
-	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
-	ProjectPath string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for current directory."`
-	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of sessions to return"`
+	Source        sourceFilter `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot), or an array of them such as [\"claude\", \"codex\"]. Leave empty for all sources."`
+	ProjectPath   string       `json:"project_path,omitempty" jsonschema:"Filter by project directory path, or a glob such as ~/work/monorepo/** to include its subdirectories and worktrees. Leave empty for current directory."`
+	Limit         int          `json:"limit,omitempty" jsonschema:"Maximum number of sessions to return"`
+	PreviewLength int          `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+	Outcome       string       `json:"outcome,omitempty" jsonschema:"Only include sessions whose guessed outcome is completed, abandoned, or failed. Outcomes are heuristic; see outcome_caveat in the result."`
+	IncludeGit    bool         `json:"include_git_state,omitempty" jsonschema:"Include each project's current git HEAD, branch, and dirty state, and how far each session's recorded branch and commit are behind it"`
+	// Substantive-session filters apply before the limit
+	MinUserMessages int  `json:"min_user_messages,omitempty" jsonschema:"Only include sessions with at least this many user messages, to skip one-off and aborted sessions"`
+	ExcludeEmpty    bool `json:"exclude_empty,omitempty" jsonschema:"Leave out sessions without a single user message, such as ones opened and closed without a prompt"`
 }
 
-func addListSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter) {
-	mcp.AddTool(server, &mcp.Tool{
+func addListSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTypedTool(server, &mcp.Tool{
 		Name:        "list_sessions",
-		Description: "List recent AI assistant sessions with optional filtering by source and project",
-	}, func(ctx context.Context, req *mcp.CallToolRequest, args listSessionsArgs) (*mcp.CallToolResult, any, error) {
+		Description: "List recent AI assistant sessions with optional filtering by source, project, and guessed outcome",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listSessionsArgs) (listSessionsResult, error) {
 		if args.Limit == 0 {
 			args.Limit = 10
 		}
+		listLimit := args.Limit
+		if args.Outcome != "" {
+			if err := adapters.ValidateOutcome(args.Outcome); err != nil {
+				return listSessionsResult{}, err
+			}
+			// Outcomes are classified when sessions are indexed, so bring the
+			// index up to date and filter every session before applying the limit
+			if err := indexSources(adaptersMap, searchCache, args.Source, args.ProjectPath); err != nil {
+				log.Printf("Warning: indexing error: %v", err)
+			}
+			listLimit = 0
+		}
+		if args.MinUserMessages < 0 {
+			return listSessionsResult{}, fmt.Errorf("min_user_messages must not be negative")
+		}
+		minUserMessages := args.MinUserMessages
+		if args.ExcludeEmpty {
+			minUserMessages = max(minUserMessages, 1)
+		}
+		if minUserMessages > 0 || consent != nil {
+			// Filter every session before applying the limit
+			listLimit = 0
+		}
 
 		var allSessions []adapters.Session
 
 		// Determine which adapters to query
-		adaptersToQuery := make(map[string]adapters.SessionAdapter)
-		if args.Source != "" {
-			if adapter, ok := adaptersMap[args.Source]; ok {
-				adaptersToQuery[args.Source] = adapter
-			} else {
-				return nil, nil, fmt.Errorf("unknown source: %s", args.Source)
-			}
-		} else {
-			adaptersToQuery = adaptersMap
+		adaptersToQuery, err := args.Source.adapters(adaptersMap)
+		if err != nil {
+			return listSessionsResult{}, err
 		}
 
-		// Query each adapter
-		for _, adapter := range adaptersToQuery {
-			sessions, err := adapter.ListSessions(args.ProjectPath, args.Limit)
-			if err != nil {
-				// Log error but continue with other adapters
-				log.Printf("Error listing sessions for %s: %v", adapter.Name(), err)
-				continue
-			}
-			allSessions = append(allSessions, sessions...)
+		// Query every adapter at once; one that fails is logged and skipped
+		bySource, err := listSessionsConcurrently(ctx, adaptersToQuery, args.ProjectPath, listLimit)
+		if err != nil {
+			return listSessionsResult{}, err
 		}
+		allSessions = flattenSessions(bySource)
 
 		// Sort by timestamp (newest first)
 		sort.Slice(allSessions, func(i, j int) bool {
 			return allSessions[i].Timestamp.After(allSessions[j].Timestamp)
 		})
 
-		// Apply limit
-		if args.Limit > 0 && len(allSessions) > args.Limit {
-			allSessions = allSessions[:args.Limit]
+		outcomes := outcomesForSessions(searchCache, allSessions)
+		if args.Outcome != "" {
+			allSessions = filterByOutcome(allSessions, outcomes, args.Outcome)
 		}
-
-		result := map[string]interface{}{
-			"sessions": allSessions,
-			"count":    len(allSessions),
+		if minUserMessages > 0 {
+			allSessions = filterByUserMessages(allSessions, minUserMessages)
 		}
 
-		resultJSON, err := json.MarshalIndent(result, "", "  ")
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		// Withheld projects don't take up the limit's slots. Sessions are
+		// checked newest first, so only projects reached before the limit is
+		// filled are prompted for.
+		allSessions, withheld := consent.takeSessions(ctx, req.Session, allSessions, args.Limit)
+
+		result := listSessionsResult{
+			Sessions:     previewSessions(allSessions, args.PreviewLength),
+			Count:        len(allSessions),
+			SourceCounts: sourceCounts(allSessions),
+			Notes:        notesForSessions(searchCache, allSessions),
+		}
+		listed := make(map[string]adapters.SessionOutcome, len(allSessions))
+		for _, session := range allSessions {
+			if outcome, ok := outcomes[session.ID]; ok {
+				listed[session.ID] = outcome
+			}
+		}
+		if len(listed) > 0 {
+			result.Outcomes = listed
+			result.OutcomeCaveat = adapters.OutcomeCaveat
+		}
+		if args.IncludeGit {
+			result.gitStateResult = gitState(ctx, allSessions)
 		}
+		result.WithheldProjects = withheld
 
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: string(resultJSON)},
-			},
-		}, nil, nil
+		return result, nil
 	})
 }
 
 // Tool 3: search_sessions
 type searchSessionsArgs struct {
-	Query       string `json:"query" jsonschema:"Search query to find in session content"`
-	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
-	ProjectPath string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for current directory."`
-	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of matching sessions to return"`
+	Query         string       `json:"query" jsonschema:"Search query to find in session content. Supports phrases, AND/OR/NOT, and qualifiers such as source:claude or file:main.go; see get_search_syntax"`
+	Source        sourceFilter `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot), or an array of them such as [\"claude\", \"codex\"]. Leave empty for all sources."`
+	ProjectPath   string       `json:"project_path,omitempty" jsonschema:"Filter by project directory path, or a glob such as ~/work/monorepo/** to include its subdirectories and worktrees. Leave empty for current directory."`
+	Limit         int          `json:"limit,omitempty" jsonschema:"Maximum number of matching sessions to return"`
+	Ranker        string       `json:"ranker,omitempty" jsonschema:"Ranking strategy (bm25, bm25_recency, vector, hybrid). Leave empty for the server default."`
+	Scope         string       `json:"scope,omitempty" jsonschema:"What to match: all (default), prose (only the assistant's explanations, without code blocks or tool output), or code (only code blocks and text written to files by edit tools)"`
+	PreviewLength int          `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+	Outcome       string       `json:"outcome,omitempty" jsonschema:"Only include sessions whose guessed outcome is completed, abandoned, or failed. Outcomes are heuristic; see outcome_caveat in the result."`
+	IncludePeers  bool         `json:"include_peers,omitempty" jsonschema:"Also send the query to the peer memory servers configured for federation and return their results in peer_matches"`
+	PageSize      int          `json:"page_size,omitempty" jsonschema:"Page size you'll use with get_session, for the page of each match's best message (default: 20)"`
+	Offset        int          `json:"offset,omitempty" jsonschema:"Number of matches to skip, for paging through results"`
+	Cursor        string       `json:"cursor,omitempty" jsonschema:"next_cursor from a previous call with the same query and filters, to get the next page of matches. Use instead of offset."`
+	// Tool output is indexed apart from the conversation, so it can be left out
+	IncludeToolOutput bool `json:"include_tool_output,omitempty" jsonschema:"Also match tool output, such as file contents and command output returned to the agent. It is left out by default so matches favor prompts and replies."`
 }
 
-func addSearchSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache *search.Cache) {
-	mcp.AddTool(server, &mcp.Tool{
+// addSearchSessionsTool registers search_sessions. peers is nil when no
+// federation peers are configured. The indexer keeps the index current;
+// searches only wait for it while the index is cold.
+func addSearchSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, peers *federation, indexer *backgroundIndexer) {
+	addTypedTool(server, &mcp.Tool{
 		Name:        "search_sessions",
 		Description: "Search through session content using BM25 ranking for relevance",
-	}, func(ctx context.Context, req *mcp.CallToolRequest, args searchSessionsArgs) (*mcp.CallToolResult, any, error) {
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args searchSessionsArgs) (searchSessionsResult, error) {
 		if args.Query == "" {
-			return nil, nil, fmt.Errorf("query is required")
+			return searchSessionsResult{}, fmt.Errorf("query is required")
+		}
+		if err := search.ValidateScope(args.Scope); err != nil {
+			return searchSessionsResult{}, err
+		}
+		if args.Outcome != "" {
+			if err := adapters.ValidateOutcome(args.Outcome); err != nil {
+				return searchSessionsResult{}, err
+			}
+		}
+
+		if args.IncludePeers && peers == nil {
+			return searchSessionsResult{}, fmt.Errorf("include_peers is set but no federation peers are configured")
+		}
+		if args.Offset < 0 {
+			return searchSessionsResult{}, fmt.Errorf("offset must not be negative")
+		}
+		if args.Limit < 0 {
+			return searchSessionsResult{}, fmt.Errorf("limit must not be negative")
+		}
+		if args.Cursor != "" {
+			if args.Offset != 0 {
+				return searchSessionsResult{}, fmt.Errorf("use either offset or cursor, not both")
+			}
+			offset, err := parseSearchCursor(args.Cursor, args)
+			if err != nil {
+				return searchSessionsResult{}, err
+			}
+			args.Offset = offset
 		}
 
 		if args.Limit == 0 {
 			args.Limit = 10
 		}
+		args.Limit = powerLimit(args.Limit, lowPowerSearchResults)
+		if args.PageSize <= 0 {
+			args.PageSize = 20
+		}
 
-		// Lazy indexing: index sessions that need it
-		if err := indexSessions(adaptersMap, searchCache, args.Source, args.ProjectPath); err != nil {
-			log.Printf("Warning: indexing error: %v", err)
-			// Continue with search anyway - we may have some indexed data
+		// Peers are searched while the local index is brought up to date.
+		// They page their own results, so only the first page includes them.
+		var peerMatches []peerMatch
+		var peerErrors []peerError
+		peersDone := make(chan struct{})
+		if args.IncludePeers && args.Offset == 0 {
+			go func() {
+				defer close(peersDone)
+				peerMatches, peerErrors = peers.search(ctx, args.Query, args.Limit)
+			}()
+		} else {
+			close(peersDone)
+		}
+
+		// The background indexer keeps the index current; wait only when cold
+		if err := indexer.awaitWarm(ctx); err != nil {
+			return searchSessionsResult{}, err
 		}
 
-		// Perform BM25 search (snippets are extracted from cached content)
-		results, err := searchCache.Search(args.Query, args.Source, args.ProjectPath, args.Limit)
+		// Perform BM25 search (snippets are extracted from cached content).
+		// Every match is ranked and filtered, so the total is known, and then
+		// the page is cut from them.
+		scope := args.Scope
+		if !args.IncludeToolOutput {
+			scope = search.WithoutToolOutput(scope)
+		}
+		results, err := searchCache.SearchInScope(args.Query, scope, args.Source.single(), args.ProjectPath, 0, args.Ranker)
 		if err != nil {
-			return nil, nil, fmt.Errorf("search failed: %w", err)
+			return searchSessionsResult{}, fmt.Errorf("search failed: %w", err)
+		}
+		if len(args.Source) > 1 {
+			kept := results[:0]
+			for _, result := range results {
+				if args.Source.includes(result.Session.Source) {
+					kept = append(kept, result)
+				}
+			}
+			results = kept
 		}
 
-		// Convert to session list with scores and snippets
-		matches := make([]map[string]interface{}, len(results))
-		for i, result := range results {
-			matches[i] = map[string]interface{}{
-				"session": result.Session,
-				"score":   result.Score,
-				"snippet": result.Snippet,
+		sessionsOf := func(results []search.SearchResult) []adapters.Session {
+			sessions := make([]adapters.Session, len(results))
+			for i, result := range results {
+				sessions[i] = result.Session
+			}
+			return sessions
+		}
+		var outcomes map[string]adapters.SessionOutcome
+		if args.Outcome != "" {
+			outcomes = outcomesForSessions(searchCache, sessionsOf(results))
+			kept := results[:0]
+			for _, result := range results {
+				if outcome, ok := outcomes[result.Session.ID]; ok && outcome.Outcome == args.Outcome {
+					kept = append(kept, result)
+				}
 			}
+			results = kept
 		}
 
-		result := map[string]interface{}{
-			"query":   args.Query,
-			"matches": matches,
-			"count":   len(matches),
+		// Withheld projects are dropped before counting, so totals and
+		// cursors only describe matches the client may see
+		var withheldProjects []string
+		if consent != nil {
+			kept := results[:0]
+			for _, result := range results {
+				if consent.allowed(ctx, req.Session, result.Session.ProjectPath) {
+					kept = append(kept, result)
+				} else {
+					withheldProjects = appendUnique(withheldProjects, result.Session.ProjectPath)
+				}
+			}
+			results = kept
 		}
 
-		resultJSON, err := json.MarshalIndent(result, "", "  ")
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		totalMatches := len(results)
+		// Clamp before adding, so a huge offset or limit can't overflow
+		start := min(args.Offset, totalMatches)
+		results = results[start : start+min(args.Limit, totalMatches-start)]
+		sessions := sessionsOf(results)
+		if outcomes == nil {
+			outcomes = outcomesForSessions(searchCache, sessions)
 		}
+		notes := notesForSessions(searchCache, sessions)
 
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: string(resultJSON)},
-			},
-		}, nil, nil
+		// Convert to session list with scores and snippets
+		result := searchSessionsResult{Query: args.Query, Matches: make([]sessionHit, 0, len(results)), TotalMatches: totalMatches, Offset: args.Offset, WithheldProjects: withheldProjects}
+		if next := args.Offset + len(results); next < totalMatches {
+			result.NextCursor = searchCursor(args, next)
+		}
+		for _, match := range results {
+			hit := sessionHit{
+				Session:     previewSession(match.Session, args.PreviewLength),
+				Score:       match.Score,
+				Snippet:     match.Snippet,
+				ContentHash: match.ContentHash,
+				Notes:       notes[match.Session.ID],
+				Fields:      match.Fields,
+			}
+			hit.KeywordScore, hit.SemanticScore = match.KeywordScore, match.SemanticScore
+			if match.MessageIndex != nil {
+				page := *match.MessageIndex / args.PageSize
+				hit.MessageIndex, hit.Page = match.MessageIndex, &page
+			}
+			if outcome, ok := outcomes[match.Session.ID]; ok {
+				hit.Outcome = &outcome
+				result.OutcomeCaveat = adapters.OutcomeCaveat
+			}
+			result.Matches = append(result.Matches, hit)
+		}
+		result.Count = len(result.Matches)
+		matched := make([]adapters.Session, len(result.Matches))
+		for i, hit := range result.Matches {
+			matched[i] = hit.Session
+		}
+		result.SourceCounts = sourceCounts(matched)
+
+		<-peersDone
+		if args.IncludePeers {
+			result.PeerMatches = peerMatches
+			result.PeerErrors = peerErrors
+		}
+
+		return result, nil
 	})
 }
 
-// indexSessions lazily indexes sessions that need updating
-func indexSessions(adaptersMap map[string]adapters.SessionAdapter, cache *search.Cache, source string, projectPath string) error {
+// indexOptions controls an indexing run.
+type indexOptions struct {
+	// force reindexes every session, even if its file hasn't changed
+	force bool
+
+	// progress, if set, is called after each session is considered
+	progress func(done, total int)
+
+	// since, if set, limits indexing to sessions started or written to after it
+	since time.Time
+}
+
+// indexSessions lazily indexes sessions that need updating, sharing the run
+// with identical calls already in progress
+func indexSessions(adaptersMap map[string]adapters.SessionAdapter, cache search.Store, source string, projectPath string) error {
+	return indexSessionsShared(context.Background(), adaptersMap, cache, source, projectPath)
+}
+
+// indexSessionsContext indexes sessions, stopping early with ctx.Err() if ctx is cancelled.
+func indexSessionsContext(ctx context.Context, adaptersMap map[string]adapters.SessionAdapter, cache search.Store, source string, projectPath string, opts indexOptions) error {
 	// Determine which adapters to index
 	adaptersToQuery := make(map[string]adapters.SessionAdapter)
 	if source != "" {
@@ -264,148 +683,1949 @@ func indexSessions(adaptersMap map[string]adapters.SessionAdapter, cache *search
 		adaptersToQuery = adaptersMap
 	}
 
-	// Index sessions from each adapter
-	for _, adapter := range adaptersToQuery {
-		sessions, err := adapter.ListSessions(projectPath, 0) // Get all sessions
-		if err != nil {
-			log.Printf("Error listing sessions for %s: %v", adapter.Name(), err)
-			continue
-		}
-
-		for _, session := range sessions {
-			// Check if session needs reindexing
-			needsReindex, err := cache.NeedsReindex(session.ID, session.FilePath)
-			if err != nil {
-				log.Printf("Error checking if session needs reindex: %v", err)
-				continue
-			}
-
-			if !needsReindex {
-				continue
-			}
-
-			// Get full session content for indexing
-			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
-			if err != nil {
-				log.Printf("Error getting session %s: %v", session.ID, err)
+	// Collect sessions from each adapter up front so progress has a total.
+	// Sources are listed concurrently; indexing stays sequential since every
+	// session is written to the same SQLite database.
+	listed, err := listSessionsConcurrently(ctx, adaptersToQuery, projectPath, 0) // Get all sessions
+	if err != nil {
+		return err
+	}
+	type pendingSession struct {
+		adapter adapters.SessionAdapter
+		session adapters.Session
+	}
+	var pending []pendingSession
+	for _, l := range listed {
+		for _, session := range l.sessions {
+			if !opts.since.IsZero() && !activeSince(session, opts.since) {
 				continue
 			}
+			pending = append(pending, pendingSession{adapter: l.adapter, session: session})
+		}
+	}
 
-			// Combine all message content
-			contentParts := make([]string, 0, len(messages)+2)
-			if session.FirstMessage != "" {
-				contentParts = append(contentParts, session.FirstMessage)
-			}
-			if session.Summary != "" {
-				contentParts = append(contentParts, session.Summary)
-			}
-			for _, msg := range messages {
-				if msg.Content != "" {
-					contentParts = append(contentParts, msg.Content)
-				}
-			}
-			content := strings.Join(contentParts, " ")
-
-			// Index the session
-			if err := cache.IndexSession(session, content); err != nil {
-				log.Printf("Error indexing session %s: %v", session.ID, err)
-				continue
-			}
+	for i, p := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		indexSession(cache, p.adapter, p.session, opts.force)
+		if opts.progress != nil {
+			opts.progress(i+1, len(pending))
 		}
 	}
 
 	return nil
 }
 
-// Tool 4: get_session
-type getSessionArgs struct {
-	SessionID string `json:"session_id" jsonschema:"The session ID to retrieve"`
-	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
-	Page      int    `json:"page,omitempty" jsonschema:"Page number for pagination (0-indexed)"`
-	PageSize  int    `json:"page_size,omitempty" jsonschema:"Number of messages per page"`
-	FromEnd   bool   `json:"from_end,omitempty" jsonschema:"If true, page 0 means the last page, page 1 means the second-to-last page (currently supported by opencode)."`
+// activeSince reports whether session started or its file was written to
+// after since.
+func activeSince(session adapters.Session, since time.Time) bool {
+	if session.Timestamp.After(since) {
+		return true
+	}
+	info, err := os.Stat(session.FilePath)
+	return err == nil && info.ModTime().After(since)
 }
 
-func addGetSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter) {
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "get_session",
-		Description: "Get the full content of a session with pagination support",
-	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionArgs) (*mcp.CallToolResult, any, error) {
-		if args.SessionID == "" {
-			return nil, nil, fmt.Errorf("session_id is required")
-		}
-		if args.Source == "" {
-			return nil, nil, fmt.Errorf("source is required")
-		}
+// indexSession indexes one session if it changed (or always, when force is set).
+// Errors are logged so one bad session doesn't stop the run.
+func indexSession(cache search.Store, adapter adapters.SessionAdapter, session adapters.Session, force bool) {
+	// Wait out anyone indexing the same session, so a change is read once
+	unlock := sessionLocks.lock(session.Source + "\x00" + session.ID)
+	defer unlock()
 
-		adapter, ok := adaptersMap[args.Source]
-		if !ok {
-			return nil, nil, fmt.Errorf("unknown source: %s", args.Source)
+	// Sessions the user asked to forget stay out of the index
+	forgotten, err := cache.IsForgotten(session.ID, session.Source)
+	if err != nil {
+		log.Printf("Error checking if session %s was forgotten: %v", session.ID, err)
+		return
+	}
+	if forgotten {
+		return
+	}
+
+	if !force {
+		// Check if session needs reindexing
+		needsReindex, err := cache.NeedsReindex(session.ID, session.FilePath)
+		if err != nil {
+			log.Printf("Error checking if session needs reindex: %v", err)
+			return
 		}
 
-		if args.PageSize == 0 {
-			args.PageSize = 20
+		if !needsReindex {
+			return
 		}
-		if args.Page < 0 {
-			args.Page = 0
+	}
+
+	// Get full session content for indexing
+	messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+	if err != nil {
+		log.Printf("Error getting session %s: %v", session.ID, err)
+		return
+	}
+
+	// Combine all message content
+	contentParts := make([]string, 0, len(messages)+2)
+	if session.FirstMessage != "" {
+		contentParts = append(contentParts, session.FirstMessage)
+	}
+	if session.Summary != "" {
+		contentParts = append(contentParts, session.Summary)
+	}
+	for _, msg := range messages {
+		if msg.Content != "" {
+			contentParts = append(contentParts, msg.Content)
 		}
+	}
+	content := strings.Join(contentParts, " ")
 
-		var (
-			messages      []adapters.Message
-			totalMessages int
-			resolvedPage  = args.Page
-			hasMore       bool
-			err           error
-		)
+	// Index the session
+	if err := cache.IndexSession(session, content); err != nil {
+		log.Printf("Error indexing session %s: %v", session.ID, err)
+		return
+	}
 
-		if paginator, ok := adapter.(paginationCapableAdapter); ok {
-			messages, totalMessages, resolvedPage, hasMore, err = paginator.GetSessionPage(args.SessionID, args.Page, args.PageSize, args.FromEnd)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to get session: %w", err)
-			}
-		} else {
-			if args.FromEnd {
-				return nil, nil, fmt.Errorf("from_end is not supported for source: %s", args.Source)
-			}
+	// Index the text of each search scope, such as assistant prose
+	if err := cache.IndexScopes(session.ID, messages); err != nil {
+		log.Printf("Error indexing search scopes for session %s: %v", session.ID, err)
+	}
 
-			fetched, err := adapter.GetSession(args.SessionID, args.Page, args.PageSize+1)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to get session: %w", err)
-			}
+	// Index each message on its own, so matches rank by their best message
+	if err := cache.IndexMessages(session.ID, messages); err != nil {
+		log.Printf("Error indexing messages for session %s: %v", session.ID, err)
+	}
 
-			hasMore = len(fetched) > args.PageSize
-			messages = fetched
-			if hasMore {
-				messages = fetched[:args.PageSize]
-			}
-		}
+	// Record content addresses for the session and its messages
+	if err := cache.IndexMessageHashes(session.ID, messages); err != nil {
+		log.Printf("Error hashing session %s: %v", session.ID, err)
+	}
 
-		for i := range messages {
-			if messages[i].PartTypes == nil {
-				messages[i].PartTypes = map[string]int{}
-			}
-		}
+	// Record the files its tool calls referenced, for find_sessions_by_file
+	files := resolveFilePaths(adapters.ComputeFilesTouched(messages), session.ProjectPath)
+	if err := cache.IndexSessionFiles(session.ID, files); err != nil {
+		log.Printf("Error indexing files for session %s: %v", session.ID, err)
+	}
 
-		totalPages := 0
-		if totalMessages > 0 {
-			totalPages = (totalMessages + args.PageSize - 1) / args.PageSize
+	// Record its file writes and edits in the ledger, for file_history
+	changes := adapters.ExtractFileChanges(messages)
+	for i := range changes {
+		if session.ProjectPath != "" && !filepath.IsAbs(changes[i].Path) {
+			changes[i].Path = filepath.Join(session.ProjectPath, changes[i].Path)
 		}
+	}
+	if err := cache.IndexFileChanges(session, changes); err != nil {
+		log.Printf("Error indexing file changes for session %s: %v", session.ID, err)
+	}
 
-		result := map[string]interface{}{
-			"session_id":    args.SessionID,
-			"source":        args.Source,
-			"page":          args.Page,
-			"resolved_page": resolvedPage,
-			"page_size":     args.PageSize,
-			"from_end":      args.FromEnd,
-			"has_more":      hasMore,
-			"messages":      messages,
-			"count":         len(messages),
-		}
+	// Record its activity per hour, for usage_stats
+	if err := cache.IndexSessionActivity(session, messages); err != nil {
+		log.Printf("Error indexing activity for session %s: %v", session.ID, err)
+	}
 
-		if _, ok := adapter.(paginationCapableAdapter); ok {
-			result["total_messages"] = totalMessages
-			result["total_pages"] = totalPages
+	// Record how it probably ended, for filtering by outcome
+	if err := cache.IndexSessionOutcome(session.ID, adapters.ClassifyOutcome(messages)); err != nil {
+		log.Printf("Error classifying session %s: %v", session.ID, err)
+	}
+
+	// Record the models it used, for model: qualifiers in search queries
+	if err := cache.IndexSessionModels(session.ID, adapters.ComputeSessionStats(messages).Models); err != nil {
+		log.Printf("Error indexing models for session %s: %v", session.ID, err)
+	}
+
+	// Record its usage and cost per model, for get_cost_report
+	if err := cache.IndexSessionCosts(session.ID, adapters.CostEntries(messages)); err != nil {
+		log.Printf("Error indexing costs for session %s: %v", session.ID, err)
+	}
+}
+
+// resolveFilePaths makes relative tool call paths absolute against the session's
+// project, so the same file is indexed under one path whichever way it was named.
+func resolveFilePaths(files []adapters.FileTouch, projectPath string) []adapters.FileTouch {
+	if projectPath == "" {
+		return files
+	}
+	for i := range files {
+		if !filepath.IsAbs(files[i].Path) {
+			files[i].Path = filepath.Join(projectPath, files[i].Path)
+		}
+	}
+	return files
+}
+
+// filterByUserMessages keeps the sessions with at least minCount user messages.
+func filterByUserMessages(sessions []adapters.Session, minCount int) []adapters.Session {
+	kept := make([]adapters.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.UserMessageCount >= minCount {
+			kept = append(kept, session)
+		}
+	}
+	return kept
+}
+
+// previewSession shortens a session's first message and summary to n
+// characters, or adapters.DefaultPreviewLength if n isn't positive.
+func previewSession(session adapters.Session, n int) adapters.Session {
+	if n <= 0 {
+		n = adapters.DefaultPreviewLength
+	}
+	session.FirstMessage = adapters.TruncateText(session.FirstMessage, n)
+	session.Summary = adapters.TruncateText(session.Summary, n)
+	return session
+}
+
+// previewSessions applies previewSession to each session in place.
+func previewSessions(sessions []adapters.Session, n int) []adapters.Session {
+	for i := range sessions {
+		sessions[i] = previewSession(sessions[i], n)
+	}
+	return sessions
+}
+
+// Tool 4: get_session
+type getSessionArgs struct {
+	SessionID string       `json:"session_id" jsonschema:"The session ID to retrieve"`
+	Source    sourceFilter `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot). An array of sources, such as [\"claude\", \"codex\"], looks the session up in each in turn."`
+	Page      int          `json:"page,omitempty" jsonschema:"Page number for pagination (0-indexed)"`
+	PageSize  int          `json:"page_size,omitempty" jsonschema:"Number of messages per page"`
+	FromEnd   bool         `json:"from_end,omitempty" jsonschema:"If true, page 0 means the last page, page 1 means the second-to-last page."`
+	Stream    bool         `json:"stream,omitempty" jsonschema:"If true, send the page's messages in chunks of 50 as progress notifications (in each notification's _meta, with the offset of its first message) and leave them out of the result. Useful for large pages. Requires a progress token in the request."`
+	// Filters apply before pagination, so pages hold only matching messages
+	Roles             []string `json:"roles,omitempty" jsonschema:"Only return messages with these roles (user, assistant, system, tool), such as [\"user\", \"assistant\"] for the conversation alone. Applied before pagination."`
+	IncludeToolOutput *bool    `json:"include_tool_output,omitempty" jsonschema:"If false, leave out messages that only carry tool output, such as Claude's tool_result lines. Messages with tool calls are kept. Applied before pagination. Default: true"`
+}
+
+func addGetSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTypedTool(server, &mcp.Tool{
+		Name:        "get_session",
+		Description: "Get the full content of a session with pagination support",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionArgs) (getSessionResult, error) {
+		progressToken := req.Params.GetProgressToken()
+		if args.Stream && progressToken == nil {
+			return getSessionResult{}, fmt.Errorf("stream requires a progress token in the request")
+		}
+		return readSessionPage(ctx, req.Session, adaptersMap, searchCache, consent, args, progressToken)
+	})
+}
+
+// readSessionPage reads a page of a session for get_session and the session
+// resource, streaming it to progressToken when args.Stream is set.
+func readSessionPage(ctx context.Context, session *mcp.ServerSession, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent, args getSessionArgs, progressToken any) (getSessionResult, error) {
+	if args.SessionID == "" {
+		return getSessionResult{}, fmt.Errorf("session_id is required")
+	}
+	source, err := resolveSessionSource(adaptersMap, args.Source, args.SessionID)
+	if err != nil {
+		return getSessionResult{}, err
+	}
+	adapter := adaptersMap[source]
+
+	if consent != nil {
+		if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, session, projectPath, found) {
+			return getSessionResult{}, sessionConsentError(args.SessionID, projectPath, found)
+		}
+	}
+
+	if args.PageSize == 0 {
+		args.PageSize = 20
+	}
+	if args.Page < 0 {
+		args.Page = 0
+	}
+
+	keep, err := messageFilter(args.Roles, args.IncludeToolOutput)
+	if err != nil {
+		return getSessionResult{}, err
+	}
+
+	var (
+		messages      []adapters.Message
+		totalMessages int
+		counted       bool // whether totalMessages is known
+		filteredOut   int
+		resolvedPage  = args.Page
+		hasMore       bool
+		stream        *messageStream
+	)
+	if args.Stream {
+		stream = newMessageStream(ctx, session, progressToken)
+	}
+
+	if keep != nil {
+		// Filtering needs every message before a page can be cut
+		all, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return getSessionResult{}, fmt.Errorf("failed to get session: %w", err)
+		}
+		var kept []adapters.Message
+		for _, msg := range all {
+			if keep(msg) {
+				kept = append(kept, msg)
+			}
+		}
+		filteredOut = len(all) - len(kept)
+		messages, totalMessages, resolvedPage, hasMore = adapters.PaginateMessages(kept, args.Page, args.PageSize, args.FromEnd)
+		counted = true
+	} else if streamer, ok := adapter.(pageStreamingAdapter); ok && stream != nil {
+		totalMessages, resolvedPage, hasMore, err = streamer.StreamSessionPage(args.SessionID, args.Page, args.PageSize, args.FromEnd, stream.add)
+		if err != nil {
+			return getSessionResult{}, fmt.Errorf("failed to get session: %w", err)
+		}
+		counted = true
+	} else if paginator, ok := adapter.(paginationCapableAdapter); ok {
+		messages, totalMessages, resolvedPage, hasMore, err = paginator.GetSessionPage(args.SessionID, args.Page, args.PageSize, args.FromEnd)
+		if err != nil {
+			return getSessionResult{}, fmt.Errorf("failed to get session: %w", err)
+		}
+		counted = true
+	} else {
+		if args.FromEnd {
+			return getSessionResult{}, fmt.Errorf("from_end is not supported for source: %s", source)
+		}
+
+		fetched, err := adapter.GetSession(args.SessionID, args.Page, args.PageSize+1)
+		if err != nil {
+			return getSessionResult{}, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		hasMore = len(fetched) > args.PageSize
+		messages = fetched
+		if hasMore {
+			messages = fetched[:args.PageSize]
+		}
+	}
+
+	count := len(messages)
+	if stream != nil {
+		// Pages that weren't streamed from the adapter go out once read
+		for i := range messages {
+			if err := stream.add(messages[i]); err != nil {
+				return getSessionResult{}, err
+			}
+			messages[i] = adapters.Message{}
+		}
+		if err := stream.flush(); err != nil {
+			return getSessionResult{}, err
+		}
+		count = stream.sent
+	} else {
+		for i := range messages {
+			prepareMessage(&messages[i])
+		}
+	}
+
+	recordAccess(searchCache, session, search.AccessEntry{
+		SessionID: args.SessionID, Source: source, Page: resolvedPage, PageSize: args.PageSize, MessageCount: count,
+	})
+
+	result := getSessionResult{
+		SessionID:    args.SessionID,
+		Source:       source,
+		Page:         args.Page,
+		ResolvedPage: resolvedPage,
+		PageSize:     args.PageSize,
+		FromEnd:      args.FromEnd,
+		HasMore:      hasMore,
+		Count:        count,
+		Streamed:     args.Stream,
+		FilteredOut:  filteredOut,
+		Notes:        notesForSession(searchCache, args.SessionID, source),
+	}
+	if !args.Stream {
+		result.Messages = &messages
+	}
+	if counted {
+		totalPages := 0
+		if totalMessages > 0 {
+			totalPages = (totalMessages + args.PageSize - 1) / args.PageSize
+		}
+		result.TotalMessages = &totalMessages
+		result.TotalPages = &totalPages
+	}
+
+	return result, nil
+}
+
+// messageRoles are the roles get_session can filter messages by.
+var messageRoles = []string{"user", "assistant", "system", "tool"}
+
+// messageFilter returns which messages get_session keeps for the given
+// filters, or nil when every message is kept.
+func messageFilter(roles []string, includeToolOutput *bool) (func(adapters.Message) bool, error) {
+	for _, role := range roles {
+		if !slices.Contains(messageRoles, role) {
+			return nil, fmt.Errorf("invalid role %q: must be one of %s", role, strings.Join(messageRoles, ", "))
+		}
+	}
+	dropToolOutput := includeToolOutput != nil && !*includeToolOutput
+	if len(roles) == 0 && !dropToolOutput {
+		return nil, nil
+	}
+	return func(msg adapters.Message) bool {
+		if len(roles) > 0 && !slices.Contains(roles, msg.Role) {
+			return false
+		}
+		return !dropToolOutput || !adapters.IsToolOutput(msg)
+	}, nil
+}
+
+// maxMessageRange caps how many messages get_messages returns in one call.
+const maxMessageRange = 200
+
+// Tool 29: get_messages
+type getMessagesArgs struct {
+	SessionID  string `json:"session_id" jsonschema:"The session ID to read"`
+	Source     string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	StartIndex int    `json:"start_index" jsonschema:"Index of the first message to return (0-based, as in search_in_session and get_session_timeline)"`
+	EndIndex   *int   `json:"end_index,omitempty" jsonschema:"Index of the last message to return, inclusive (default: start_index + 19). At most 200 messages are returned per call."`
+}
+
+func addGetMessagesTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_messages",
+		Description: "Get an arbitrary range of messages from a session by index, such as the messages around a search_in_session match or a get_session_timeline event",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getMessagesArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.StartIndex < 0 {
+			return nil, nil, fmt.Errorf("start_index must not be negative")
+		}
+		endIndex := args.StartIndex + 19
+		if args.EndIndex != nil {
+			endIndex = *args.EndIndex
+		}
+		if endIndex < args.StartIndex {
+			return nil, nil, fmt.Errorf("end_index must not be before start_index")
+		}
+		if endIndex-args.StartIndex+1 > maxMessageRange {
+			return nil, nil, fmt.Errorf("at most %d messages can be read at once", maxMessageRange)
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		all, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if args.StartIndex >= len(all) {
+			return nil, nil, fmt.Errorf("start_index %d is past the end of the session (%d messages)", args.StartIndex, len(all))
+		}
+		endIndex = min(endIndex, len(all)-1)
+
+		messages := all[args.StartIndex : endIndex+1]
+		for i := range messages {
+			if messages[i].PartTypes == nil {
+				messages[i].PartTypes = map[string]int{}
+			}
+			messages[i].ContentHash = adapters.HashMessage(messages[i])
+		}
+
+		recordAccess(searchCache, req.Session, search.AccessEntry{
+			SessionID: args.SessionID, Source: args.Source, StartIndex: args.StartIndex, MessageCount: len(messages),
+		})
+
+		result := map[string]interface{}{
+			"session_id":     args.SessionID,
+			"source":         args.Source,
+			"start_index":    args.StartIndex,
+			"end_index":      endIndex,
+			"total_messages": len(all),
+			"has_more":       endIndex < len(all)-1,
+			"messages":       messages,
+			"count":          len(messages),
+		}
+		if notes := notesForSession(searchCache, args.SessionID, args.Source); len(notes) > 0 {
+			result["notes"] = notes
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// lastPage returns the last page of a session, numbered like get_session pages
+// so clients can keep paging backwards from it.
+func lastPage(adapter adapters.SessionAdapter, sessionID string, pageSize int) (messages []adapters.Message, totalMessages, page int, err error) {
+	if paginator, ok := adapter.(paginationCapableAdapter); ok {
+		messages, totalMessages, page, _, err = paginator.GetSessionPage(sessionID, 0, pageSize, true)
+		return messages, totalMessages, page, err
+	}
+
+	all, err := adapter.GetSession(sessionID, 0, 100000) // Get all messages
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(all) > 0 {
+		page = (len(all) - 1) / pageSize
+	}
+	return all[page*pageSize:], len(all), page, nil
+}
+
+// Tool 27: get_last_session
+type getLastSessionArgs struct {
+	Source        string `json:"source,omitempty" jsonschema:"Only consider sessions from this source (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath   string `json:"project_path,omitempty" jsonschema:"The project directory. Leave empty for the server's current directory."`
+	PageSize      int    `json:"page_size,omitempty" jsonschema:"Number of messages to return from the end of the session (default: 20)"`
+	PreviewLength int    `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+	IncludeGit    bool   `json:"include_git_state,omitempty" jsonschema:"Include the project's current git HEAD, branch, and dirty state, and how far the session's recorded branch and commit are behind it"`
+}
+
+func addGetLastSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_last_session",
+		Description: "Get the most recent session for a project (the current directory by default) together with its last page of messages, in one call. Use it to answer \"what was I just doing?\"",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getLastSessionArgs) (*mcp.CallToolResult, any, error) {
+		if args.PageSize == 0 {
+			args.PageSize = 20
+		}
+		if args.PageSize < 0 {
+			return nil, nil, fmt.Errorf("page_size must be positive")
+		}
+		if args.ProjectPath == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to determine current directory: %w", err)
+			}
+			args.ProjectPath = cwd
+		}
+
+		adaptersToQuery := adaptersMap
+		if args.Source != "" {
+			adapter, ok := adaptersMap[args.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+			adaptersToQuery = map[string]adapters.SessionAdapter{args.Source: adapter}
+		}
+
+		listed, err := listSessionsConcurrently(ctx, adaptersToQuery, args.ProjectPath, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates := flattenSessions(listed)
+		candidates, withheld := consent.filterSessions(ctx, req.Session, candidates)
+
+		result := map[string]interface{}{
+			"project_path": args.ProjectPath,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		if len(candidates) == 0 {
+			result["session"] = nil
+		} else {
+			latest := candidates[0]
+			for _, s := range candidates[1:] {
+				if s.Timestamp.After(latest.Timestamp) {
+					latest = s
+				}
+			}
+
+			adapter, ok := adaptersToQuery[latest.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(latest.Source)
+			}
+			messages, totalMessages, page, err := lastPage(adapter, latest.ID, args.PageSize)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get session: %w", err)
+			}
+			for i := range messages {
+				if messages[i].PartTypes == nil {
+					messages[i].PartTypes = map[string]int{}
+				}
+				messages[i].ContentHash = adapters.HashMessage(messages[i])
+			}
+
+			recordAccess(searchCache, req.Session, search.AccessEntry{
+				SessionID: latest.ID, Source: latest.Source, Page: page, PageSize: args.PageSize, MessageCount: len(messages),
+			})
+
+			result["session"] = previewSession(latest, args.PreviewLength)
+			if args.IncludeGit {
+				addGitState(ctx, result, []adapters.Session{latest})
+			}
+			result["page"] = page
+			result["page_size"] = args.PageSize
+			result["has_earlier"] = page > 0
+			result["total_messages"] = totalMessages
+			result["messages"] = messages
+			result["count"] = len(messages)
+			if notes := notesForSession(searchCache, latest.ID, latest.Source); len(notes) > 0 {
+				result["notes"] = notes
+			}
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 5: lookup_content_hash
+type lookupContentHashArgs struct {
+	Hash string `json:"hash" jsonschema:"A content_hash previously returned for a message or session"`
+}
+
+func addLookupContentHashTool(server *mcp.Server, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "lookup_content_hash",
+		Description: "Resolve a message or session content_hash to the indexed sessions and message indices that contain it",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args lookupContentHashArgs) (*mcp.CallToolResult, any, error) {
+		if args.Hash == "" {
+			return nil, nil, fmt.Errorf("hash is required")
+		}
+
+		refs, err := searchCache.LookupContentHash(strings.ToLower(args.Hash))
+		if err != nil {
+			return nil, nil, fmt.Errorf("lookup failed: %w", err)
+		}
+		var withheld []string
+		allowed := make([]search.ContentRef, 0, len(refs))
+		for _, ref := range refs {
+			if consent.allowed(ctx, req.Session, ref.ProjectPath) {
+				allowed = append(allowed, ref)
+			} else {
+				withheld = appendUnique(withheld, ref.ProjectPath)
+			}
+		}
+
+		result := map[string]interface{}{
+			"hash":    args.Hash,
+			"matches": allowed,
+			"count":   len(allowed),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// recordAccess logs a read of a session page or message range, filling in the
+// client from the MCP session. Failures are logged, not returned, so the access
+// log never blocks reading a session.
+func recordAccess(searchCache search.Store, session *mcp.ServerSession, entry search.AccessEntry) {
+	if searchCache == nil {
+		return
+	}
+
+	entry.ClientName = clientName(session)
+	if session != nil {
+		if params := session.InitializeParams(); params != nil && params.ClientInfo != nil {
+			entry.ClientVersion = params.ClientInfo.Version
+		}
+	}
+
+	if err := searchCache.RecordAccess(entry); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// Tool 6: get_access_log
+type getAccessLogArgs struct {
+	SessionID string `json:"session_id,omitempty" jsonschema:"Only return reads of this session. Leave empty for all sessions."`
+	Source    string `json:"source,omitempty" jsonschema:"Only return reads of sessions from this source"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of entries to return"`
+}
+
+func addGetAccessLogTool(server *mcp.Server, searchCache search.Store) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_access_log",
+		Description: "Show which clients read which sessions and pages, and when (newest first)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getAccessLogArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+
+		entries, err := searchCache.AccessLog(args.SessionID, args.Source, args.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if entries == nil {
+			entries = []search.AccessEntry{}
+		}
+
+		result := map[string]interface{}{
+			"entries": entries,
+			"count":   len(entries),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 7: get_session_tree
+type getSessionTreeArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The parent session ID (a subagent session ID resolves to its parent)"`
+	Source    string `json:"source" jsonschema:"The source that created this session (currently supported by claude)"`
+	PageSize  int    `json:"page_size,omitempty" jsonschema:"Maximum number of messages to return per transcript"`
+}
+
+// sessionTranscript is one session in a get_session_tree result.
+type sessionTranscript struct {
+	Session  adapters.Session   `json:"session"`
+	Messages []adapters.Message `json:"messages"`
+	HasMore  bool               `json:"has_more"`
+}
+
+func addGetSessionTreeTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_session_tree",
+		Description: "Get a session together with the subagent (Task) transcripts it spawned",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionTreeArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+		infoAdapter, ok := adapter.(sessionInfoCapableAdapter)
+		if !ok {
+			return nil, nil, fmt.Errorf("session trees are not supported for source: %s", args.Source)
+		}
+
+		if args.PageSize == 0 {
+			args.PageSize = 20
+		}
+
+		parent, err := infoAdapter.GetSessionInfo(args.SessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if parent.ParentSessionID != "" {
+			if parent, err = infoAdapter.GetSessionInfo(parent.ParentSessionID); err != nil {
+				return nil, nil, fmt.Errorf("failed to get parent session: %w", err)
+			}
+		}
+
+		if !consent.allowed(ctx, req.Session, parent.ProjectPath) {
+			return nil, nil, fmt.Errorf("sessions from project %s have not been approved for this client", parent.ProjectPath)
+		}
+
+		loadTranscript := func(session adapters.Session) (sessionTranscript, error) {
+			fetched, err := adapter.GetSession(session.ID, 0, args.PageSize+1)
+			if err != nil {
+				return sessionTranscript{}, err
+			}
+			transcript := sessionTranscript{Session: previewSession(session, 0), Messages: fetched}
+			if len(fetched) > args.PageSize {
+				transcript.Messages = fetched[:args.PageSize]
+				transcript.HasMore = true
+			}
+			for i := range transcript.Messages {
+				transcript.Messages[i].ContentHash = adapters.HashMessage(transcript.Messages[i])
+			}
+			recordAccess(searchCache, req.Session, search.AccessEntry{
+				SessionID: session.ID, Source: args.Source, PageSize: args.PageSize, MessageCount: len(transcript.Messages),
+			})
+			return transcript, nil
+		}
+
+		root, err := loadTranscript(parent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		children := make([]sessionTranscript, 0, len(parent.ChildSessionIDs))
+		for _, childID := range parent.ChildSessionIDs {
+			child, err := infoAdapter.GetSessionInfo(childID)
+			if err != nil {
+				log.Printf("Error getting subagent session %s: %v", childID, err)
+				continue
+			}
+			transcript, err := loadTranscript(child)
+			if err != nil {
+				log.Printf("Error getting subagent session %s: %v", childID, err)
+				continue
+			}
+			children = append(children, transcript)
+		}
+
+		result := map[string]interface{}{
+			"session":  root.Session,
+			"messages": root.Messages,
+			"has_more": root.HasMore,
+			"children": children,
+			"count":    len(children),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 8: get_search_syntax
+type getSearchSyntaxArgs struct{}
+
+// searchFilter describes one search_sessions argument besides the query itself.
+type searchFilter struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Values      []string `json:"values,omitempty"`
+}
+
+// searchFilters describes every searchSessionsArgs field besides the query,
+// for get_search_syntax. Keep it in step with searchSessionsArgs.
+func searchFilters(sources, rankers []string) []searchFilter {
+	return []searchFilter{
+		{Name: "source", Description: "Only search sessions from this source, or from any of an array of sources such as [\"claude\", \"codex\"]", Values: sources},
+		{Name: "project_path", Description: "Only search sessions from this project directory, or from projects matching a glob such as ~/work/monorepo/**"},
+		{Name: "limit", Description: "Maximum number of matches to return (default 10)"},
+		{Name: "ranker", Description: "Ranking strategy for this query", Values: rankers},
+		{Name: "scope", Description: "Which text to match: all of it, only the assistant's prose without code blocks or tool output, or only code blocks and text written to files by edit tools", Values: search.ScopeNames()},
+		{Name: "preview_length", Description: "Truncate each match's first_message and summary to this many characters (default 200, max 1000)"},
+		{Name: "outcome", Description: "Only include sessions whose guessed outcome is this one", Values: []string{adapters.OutcomeCompleted, adapters.OutcomeAbandoned, adapters.OutcomeFailed}},
+		{Name: "include_peers", Description: "Also search the peer memory servers configured for federation; their results are in peer_matches"},
+		{Name: "page_size", Description: "Page size you'll use with get_session, for the page of each match's best message (default 20)"},
+		{Name: "offset", Description: "Number of matches to skip, for paging through results"},
+		{Name: "cursor", Description: "next_cursor from a previous call with the same query and filters, to get the next page; use instead of offset"},
+		{Name: "include_tool_output", Description: "Also match tool output, such as file contents and command output; left out by default"},
+	}
+}
+
+func addGetSearchSyntaxTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_search_syntax",
+		Description: "Describe the query syntax, filters, and rankers supported by search_sessions",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSearchSyntaxArgs) (*mcp.CallToolResult, any, error) {
+		sources := make([]string, 0, len(adaptersMap))
+		for name := range adaptersMap {
+			sources = append(sources, name)
+		}
+		sort.Strings(sources)
+
+		syntax := search.DescribeSyntax()
+		syntax.Default = searchCache.DefaultRanker()
+
+		result := map[string]interface{}{
+			"query":   syntax,
+			"filters": searchFilters(sources, syntax.Rankers),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 9: reindex_sessions
+type reindexSessionsArgs struct {
+	Source      string `json:"source,omitempty" jsonschema:"Only reindex sessions from this source. Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only reindex sessions from this project directory"`
+	Since       string `json:"since,omitempty" jsonschema:"Only reindex sessions started or written to after this time: an RFC 3339 time, a date like 2025-03-01, or a duration back from now like 24h"`
+	Force       bool   `json:"force,omitempty" jsonschema:"Reindex every session in scope, even ones that haven't changed"`
+}
+
+func addReindexSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, operations *operationManager) {
+	addTool(server, &mcp.Tool{
+		Name:        "reindex_sessions",
+		Description: "Start a background reindex of the search cache, optionally limited to one source, project, or sessions active since a time, for refreshing just what changed (for example after syncing sessions from another machine). Returns an operation_id to poll with get_operation_status.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args reindexSessionsArgs) (*mcp.CallToolResult, any, error) {
+		if args.Source != "" {
+			if _, ok := adaptersMap[args.Source]; !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+		}
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = parseSince(args.Since, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		status := operations.start("reindex", func(ctx context.Context, progress func(done, total int)) (interface{}, error) {
+			var total int
+			err := indexSessionsContext(ctx, adaptersMap, searchCache, args.Source, args.ProjectPath, indexOptions{
+				force: args.Force,
+				since: since,
+				progress: func(done, n int) {
+					total = n
+					progress(done, n)
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]int{"sessions": total}, nil
+		})
+
+		return operationResult(status)
+	})
+}
+
+// Tools 10-11: get_operation_status and cancel_operation
+type operationArgs struct {
+	OperationID string `json:"operation_id" jsonschema:"The operation_id returned when the operation was started"`
+}
+
+func addOperationTools(server *mcp.Server, operations *operationManager) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_operation_status",
+		Description: "Get the status, progress, and result of a background operation",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args operationArgs) (*mcp.CallToolResult, any, error) {
+		if args.OperationID == "" {
+			return nil, nil, fmt.Errorf("operation_id is required")
+		}
+		status, err := operations.get(args.OperationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return operationResult(status)
+	})
+
+	addTool(server, &mcp.Tool{
+		Name:        "cancel_operation",
+		Description: "Cancel a running background operation",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args operationArgs) (*mcp.CallToolResult, any, error) {
+		if args.OperationID == "" {
+			return nil, nil, fmt.Errorf("operation_id is required")
+		}
+		status, err := operations.cancel(args.OperationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return operationResult(status)
+	})
+}
+
+// operationResult renders an operation status as a tool result.
+func operationResult(status operationStatus) (*mcp.CallToolResult, any, error) {
+	resultJSON, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// Tool 12: get_session_stats
+type getSessionStatsArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to summarize"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+}
+
+func addGetSessionStatsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_session_stats",
+		Description: "Get per-session aggregates: message counts by role, tool calls by name, tokens, cost, duration, models used, files touched, and the session's guessed outcome (completed, abandoned, or failed) with the signals behind it",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionStatsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"session_id":     args.SessionID,
+			"source":         args.Source,
+			"stats":          adapters.ComputeSessionStats(messages),
+			"outcome":        adapters.ClassifyOutcome(messages),
+			"outcome_caveat": adapters.OutcomeCaveat,
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 14: search_in_session
+type searchInSessionArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to search"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Query     string `json:"query" jsonschema:"Keywords to find within the session"`
+	Scope     string `json:"scope,omitempty" jsonschema:"What to match: all (default), prose (only the assistant's explanations, without code blocks or tool output), or code (only code blocks and text written to files by edit tools)"`
+	PageSize  int    `json:"page_size,omitempty" jsonschema:"Page size used to compute each match's get_session page (default: 20)"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of matches to return (default: 50)"`
+}
+
+// pagedMatch is a search_in_session match with the get_session page it appears on.
+type pagedMatch struct {
+	search.MessageMatch
+	Page int `json:"page"`
+}
+
+func addSearchInSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTypedTool(server, &mcp.Tool{
+		Name:        "search_in_session",
+		Description: "Find the messages in one session that match a query. Returns message indices, snippets, and the get_session page each match is on.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args searchInSessionArgs) (searchInSessionResult, error) {
+		if args.SessionID == "" {
+			return searchInSessionResult{}, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return searchInSessionResult{}, fmt.Errorf("source is required")
+		}
+		if args.Query == "" {
+			return searchInSessionResult{}, fmt.Errorf("query is required")
+		}
+		if err := search.ValidateScope(args.Scope); err != nil {
+			return searchInSessionResult{}, err
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return searchInSessionResult{}, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return searchInSessionResult{}, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		if args.PageSize <= 0 {
+			args.PageSize = 20
+		}
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+		args.Limit = powerLimit(args.Limit, lowPowerInSessionMatches)
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return searchInSessionResult{}, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		matches := search.SearchMessagesInScope(messages, args.Query, args.Scope, 200)
+		totalMatches := len(matches)
+		if len(matches) > args.Limit {
+			matches = matches[:args.Limit]
+		}
+
+		results := make([]pagedMatch, 0, len(matches))
+		for _, match := range matches {
+			results = append(results, pagedMatch{MessageMatch: match, Page: match.Index / args.PageSize})
+		}
+
+		return searchInSessionResult{
+			SessionID:     args.SessionID,
+			Source:        args.Source,
+			Query:         args.Query,
+			PageSize:      args.PageSize,
+			TotalMessages: len(messages),
+			TotalMatches:  totalMatches,
+			Matches:       results,
+			Count:         len(results),
+		}, nil
+	})
+}
+
+// Tool 15: list_projects
+type listProjectsArgs struct {
+	Source string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Maximum number of projects to return (default: 50)"`
+}
+
+// projectSummary aggregates the sessions recorded for one project directory.
+type projectSummary struct {
+	ProjectPath   string         `json:"project_path"`
+	SessionCount  int            `json:"session_count"`
+	Sources       map[string]int `json:"sources"`
+	FirstActivity time.Time      `json:"first_activity"`
+	LastActivity  time.Time      `json:"last_activity"`
+}
+
+// summarizeProjects groups sessions by project, most recently active first.
+// Subagent sessions are folded into their parent and not counted separately.
+func summarizeProjects(sessions []adapters.Session) (projects []projectSummary, unknown int) {
+	byPath := make(map[string]*projectSummary)
+	for _, s := range sessions {
+		if s.ParentSessionID != "" {
+			continue
+		}
+		if s.ProjectPath == "" {
+			unknown++
+			continue
+		}
+
+		p, ok := byPath[s.ProjectPath]
+		if !ok {
+			p = &projectSummary{ProjectPath: s.ProjectPath, Sources: make(map[string]int)}
+			byPath[s.ProjectPath] = p
+		}
+		p.SessionCount++
+		p.Sources[s.Source]++
+		if !s.Timestamp.IsZero() && (p.FirstActivity.IsZero() || s.Timestamp.Before(p.FirstActivity)) {
+			p.FirstActivity = s.Timestamp
+		}
+		if s.Timestamp.After(p.LastActivity) {
+			p.LastActivity = s.Timestamp
+		}
+	}
+
+	projects = make([]projectSummary, 0, len(byPath))
+	for _, p := range byPath {
+		projects = append(projects, *p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if !projects[i].LastActivity.Equal(projects[j].LastActivity) {
+			return projects[i].LastActivity.After(projects[j].LastActivity)
+		}
+		return projects[i].ProjectPath < projects[j].ProjectPath
+	})
+	return projects, unknown
+}
+
+func addListProjectsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "list_projects",
+		Description: "List the project directories that have AI assistant sessions, with session counts per source and first/last activity",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listProjectsArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+
+		adaptersToQuery := adaptersMap
+		if args.Source != "" {
+			adapter, ok := adaptersMap[args.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+			adaptersToQuery = map[string]adapters.SessionAdapter{args.Source: adapter}
+		}
+
+		listed, err := listSessionsConcurrently(ctx, adaptersToQuery, "", 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		allSessions, withheld := consent.filterSessions(ctx, req.Session, flattenSessions(listed))
+
+		projects, unknown := summarizeProjects(allSessions)
+		totalProjects := len(projects)
+		if args.Limit > 0 && len(projects) > args.Limit {
+			projects = projects[:args.Limit]
+		}
+
+		result := map[string]interface{}{
+			"projects":       projects,
+			"count":          len(projects),
+			"total_projects": totalProjects,
+		}
+		if unknown > 0 {
+			result["sessions_without_project"] = unknown
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 16: get_agent_usage
+type getAgentUsageArgs struct {
+	Source      string `json:"source,omitempty" jsonschema:"Source to aggregate (default: opencode, which records an agent/mode per message)"`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include sessions from this project. Leave empty for all projects."`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions to include (default: 100)"`
+}
+
+func addGetAgentUsageTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_agent_usage",
+		Description: "Aggregate time, cost, tokens, and message counts per agent/mode (e.g. opencode build, plan, or custom agents) across recent sessions",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getAgentUsageArgs) (*mcp.CallToolResult, any, error) {
+		if args.Source == "" {
+			args.Source = "opencode"
+		}
+		if args.Limit == 0 {
+			args.Limit = 100
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		sessions, err := adapter.ListSessions(args.ProjectPath, args.Limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sessions, withheld := consent.filterSessions(ctx, req.Session, sessions)
+
+		tracker := adapters.NewAgentUsageTracker()
+		for _, session := range sessions {
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+			tracker.AddSession(messages)
+		}
+
+		result := map[string]interface{}{
+			"source":        args.Source,
+			"session_count": len(sessions),
+			"agents":        tracker.Usage(),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 17: get_tool_calls
+type getToolCallsArgs struct {
+	SessionID       string `json:"session_id" jsonschema:"The session ID to audit"`
+	Source          string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Name            string `json:"name,omitempty" jsonschema:"Only return calls to this tool (case-insensitive)"`
+	MaxResultLength int    `json:"max_result_length,omitempty" jsonschema:"Truncate each tool result to this many characters (default: 2000, -1 for no limit)"`
+}
+
+func addGetToolCallsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_tool_calls",
+		Description: "List only the tool invocations in a session (name, arguments, result, success, timestamp), normalized across sources. Useful for auditing what an agent actually executed.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getToolCallsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.MaxResultLength == 0 {
+			args.MaxResultLength = 2000
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		recordAccess(searchCache, req.Session, search.AccessEntry{
+			SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+		})
+
+		invocations := []adapters.ToolInvocation{}
+		for _, invocation := range adapters.ExtractToolInvocations(messages) {
+			if args.Name != "" && !strings.EqualFold(invocation.Name, args.Name) {
+				continue
+			}
+			if invocation.Result != nil && args.MaxResultLength > 0 && len(*invocation.Result) > args.MaxResultLength {
+				truncated := (*invocation.Result)[:args.MaxResultLength] + "..."
+				invocation.Result = &truncated
+			}
+			invocations = append(invocations, invocation)
+		}
+
+		result := map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"tool_calls": invocations,
+			"count":      len(invocations),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 18: get_cost_report
+type getCostReportArgs struct {
+	Source      string `json:"source,omitempty" jsonschema:"Source to report on (default: claude)"`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include this project. Leave empty for all projects."`
+	Days        int    `json:"days,omitempty" jsonschema:"Only include sessions started in the last N days (default: all)"`
+}
+
+// projectCost is the cost report for one project.
+type projectCost struct {
+	ProjectPath string `json:"project_path"`
+	adapters.CostReport
+}
+
+func addGetCostReportTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_cost_report",
+		Description: "Report token usage, cost, and prompt cache-hit ratio per project across past sessions. Costs not recorded by the source are estimated from token usage at list price.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getCostReportArgs) (*mcp.CallToolResult, any, error) {
+		if args.Source == "" {
+			args.Source = "claude"
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		sessions, err := adapter.ListSessions(args.ProjectPath, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		if args.Days > 0 {
+			cutoff := time.Now().AddDate(0, 0, -args.Days)
+			recent := sessions[:0]
+			for _, s := range sessions {
+				if s.Timestamp.After(cutoff) {
+					recent = append(recent, s)
+				}
+			}
+			sessions = recent
+		}
+		sessions, withheld := consent.filterSessions(ctx, req.Session, sessions)
+
+		// Usage is recorded per session when it is indexed, so bring the
+		// index up to date and only read sessions it couldn't index
+		if err := indexSources(adaptersMap, searchCache, sourceFilter{args.Source}, args.ProjectPath); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+		indexed, err := searchCache.SessionCosts(sessions)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			indexed = nil
+		}
+
+		total := adapters.NewCostTracker()
+		byProject := make(map[string]*adapters.CostTracker)
+		for _, session := range sessions {
+			entries, ok := indexed[session.ID]
+			if !ok {
+				messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+				if err != nil {
+					log.Printf("Error getting session %s: %v", session.ID, err)
+					continue
+				}
+				entries = adapters.CostEntries(messages)
+			}
+			tracker, ok := byProject[session.ProjectPath]
+			if !ok {
+				tracker = adapters.NewCostTracker()
+				byProject[session.ProjectPath] = tracker
+			}
+			tracker.AddEntries(entries)
+			total.AddEntries(entries)
+		}
+
+		projects := make([]projectCost, 0, len(byProject))
+		for path, tracker := range byProject {
+			projects = append(projects, projectCost{ProjectPath: path, CostReport: tracker.Report()})
+		}
+		sort.Slice(projects, func(i, j int) bool {
+			if projects[i].CostUSD != projects[j].CostUSD {
+				return projects[i].CostUSD > projects[j].CostUSD
+			}
+			return projects[i].ProjectPath < projects[j].ProjectPath
+		})
+
+		result := map[string]interface{}{
+			"source":   args.Source,
+			"total":    total.Report(),
+			"projects": projects,
+			"count":    len(projects),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 19: extract_code_blocks
+type extractCodeBlocksArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to scan"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Language  string `json:"language,omitempty" jsonschema:"Only return blocks tagged with this language (e.g. go, python, bash)"`
+}
+
+// sessionCodeBlock is a code block with the assistant message it came from.
+type sessionCodeBlock struct {
+	MessageIndex int        `json:"message_index"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"`
+	adapters.CodeBlock
+}
+
+func addExtractCodeBlocksTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "extract_code_blocks",
+		Description: "Extract the fenced code blocks from a session's assistant messages, with language tags and the surrounding lines of context, to recover code without reading the whole transcript",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args extractCodeBlocksArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		recordAccess(searchCache, req.Session, search.AccessEntry{
+			SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+		})
+
+		blocks := []sessionCodeBlock{}
+		for i, msg := range messages {
+			if msg.Role != "assistant" {
+				continue
+			}
+			for _, block := range adapters.ExtractCodeBlocks(msg.Content) {
+				if args.Language != "" && !strings.EqualFold(block.Language, args.Language) {
+					continue
+				}
+				found := sessionCodeBlock{MessageIndex: i, CodeBlock: block}
+				if !msg.Timestamp.IsZero() {
+					ts := msg.Timestamp
+					found.Timestamp = &ts
+				}
+				blocks = append(blocks, found)
+			}
+		}
+
+		result := map[string]interface{}{
+			"session_id":  args.SessionID,
+			"source":      args.Source,
+			"code_blocks": blocks,
+			"count":       len(blocks),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 20: group_by_task
+type groupByTaskArgs struct {
+	Source        string  `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty to compare across all sources."`
+	ProjectPath   string  `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for all projects."`
+	Limit         int     `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions per source to compare (default: 200)"`
+	Threshold     float64 `json:"threshold,omitempty" jsonschema:"Minimum similarity (0-1) of first messages to treat sessions as the same task (default: 0.6)"`
+	PreviewLength int     `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+}
+
+// taskGroup is a set of sessions attempting the same task.
+type taskGroup struct {
+	Task     string             `json:"task"`
+	Attempts []adapters.Session `json:"attempts"`
+	// LikelySuccessful is the attempt the user stopped retrying after: the latest one
+	LikelySuccessful string   `json:"likely_successful"`
+	Sources          []string `json:"sources"`
+}
+
+func addGroupByTaskTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "group_by_task",
+		Description: "Find tasks that were retried across sessions (near-duplicate first messages, e.g. the same prompt tried with different tools or models) and report which attempt likely succeeded",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args groupByTaskArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 200
+		}
+
+		adaptersToQuery := adaptersMap
+		if args.Source != "" {
+			adapter, ok := adaptersMap[args.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+			adaptersToQuery = map[string]adapters.SessionAdapter{args.Source: adapter}
+		}
+
+		listed, err := listSessionsConcurrently(ctx, adaptersToQuery, args.ProjectPath, args.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		var allSessions []adapters.Session
+		for _, s := range flattenSessions(listed) {
+			// Subagent prompts are written by the parent agent, not retried by the user
+			if s.ParentSessionID == "" {
+				allSessions = append(allSessions, s)
+			}
+		}
+		allSessions, withheld := consent.filterSessions(ctx, req.Session, allSessions)
+
+		groups := []taskGroup{}
+		for _, attempts := range search.GroupByTask(allSessions, args.Threshold) {
+			group := taskGroup{
+				Task:             previewSession(attempts[0], args.PreviewLength).FirstMessage,
+				Attempts:         previewSessions(attempts, args.PreviewLength),
+				LikelySuccessful: attempts[len(attempts)-1].ID,
+			}
+			for _, attempt := range attempts {
+				group.Sources = appendUnique(group.Sources, attempt.Source)
+			}
+			groups = append(groups, group)
+		}
+
+		result := map[string]interface{}{
+			"groups":            groups,
+			"count":             len(groups),
+			"sessions_compared": len(allSessions),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 21: extract_shell_commands
+type extractShellCommandsArgs struct {
+	SessionID   string `json:"session_id,omitempty" jsonschema:"Session to reconstruct the shell history of (requires source)"`
+	Source      string `json:"source,omitempty" jsonschema:"The source that created the session(s) (claude, gemini, codex, opencode, mistral, copilot). Leave empty with project_path to include all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Reconstruct the shell history across this project's sessions instead of a single session"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions per source to include with project_path (default: 50)"`
+}
+
+func addExtractShellCommandsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "extract_shell_commands",
+		Description: "Reconstruct the shell history of an agent: every command run through bash/exec-style tools in a session or across a project's sessions, deduplicated, with run counts, failures, and first/last run times",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args extractShellCommandsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" && args.ProjectPath == "" {
+			return nil, nil, fmt.Errorf("session_id or project_path is required")
+		}
+		if args.SessionID != "" && args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required with session_id")
+		}
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+
+		adaptersToQuery := adaptersMap
+		if args.Source != "" {
+			adapter, ok := adaptersMap[args.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+			adaptersToQuery = map[string]adapters.SessionAdapter{args.Source: adapter}
+		}
+
+		tracker := adapters.NewShellCommandTracker()
+		sessionCount := 0
+		var withheld []string
+
+		if args.SessionID != "" {
+			adapter := adaptersToQuery[args.Source]
+			if consent != nil {
+				if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+					return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+				}
+			}
+			messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get session: %w", err)
+			}
+			recordAccess(searchCache, req.Session, search.AccessEntry{
+				SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+			})
+			tracker.AddSession(args.SessionID, messages)
+			sessionCount = 1
+		} else {
+			listed, err := listSessionsConcurrently(ctx, adaptersToQuery, args.ProjectPath, args.Limit)
+			if err != nil {
+				return nil, nil, err
+			}
+			sessions := flattenSessions(listed)
+			sessionAdapters := make(map[string]adapters.SessionAdapter)
+			for _, l := range listed {
+				for _, session := range l.sessions {
+					sessionAdapters[session.Source+"/"+session.ID] = l.adapter
+				}
+			}
+			sessions, withheld = consent.filterSessions(ctx, req.Session, sessions)
+
+			for _, session := range sessions {
+				messages, err := sessionAdapters[session.Source+"/"+session.ID].GetSession(session.ID, 0, 100000)
+				if err != nil {
+					log.Printf("Error getting session %s: %v", session.ID, err)
+					continue
+				}
+				recordAccess(searchCache, req.Session, search.AccessEntry{
+					SessionID: session.ID, Source: session.Source, MessageCount: len(messages),
+				})
+				tracker.AddSession(session.ID, messages)
+				sessionCount++
+			}
+		}
+
+		commands := tracker.Commands()
+		result := map[string]interface{}{
+			"commands":      commands,
+			"count":         len(commands),
+			"session_count": sessionCount,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 22: list_files_touched
+type listFilesTouchedArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to inspect"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+}
+
+func addListFilesTouchedTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "list_files_touched",
+		Description: "List the files a session's tools read, wrote, or edited, with per-file counts, derived from tool arguments across sources",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listFilesTouchedArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		files := adapters.ComputeFilesTouched(messages)
+		result := map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"files":      files,
+			"count":      len(files),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 23: find_sessions_by_file
+type findSessionsByFileArgs struct {
+	Path          string `json:"path" jsonschema:"The file path to look for. Relative paths in sessions are resolved against their project, so pass an absolute path."`
+	Prefix        bool   `json:"prefix,omitempty" jsonschema:"If true, match every file whose path starts with path (e.g. a directory)"`
+	Source        string `json:"source,omitempty" jsonschema:"Optional source filter"`
+	ProjectPath   string `json:"project_path,omitempty" jsonschema:"Optional project path filter"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum number of sessions to return (default: 20)"`
+	PreviewLength int    `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+}
+
+func addFindSessionsByFileTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "find_sessions_by_file",
+		Description: "Find sessions, across all sources, whose tool calls read, wrote, or edited a file, by exact path or path prefix. Most recent first.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args findSessionsByFileArgs) (*mcp.CallToolResult, any, error) {
+		args.Path = strings.TrimSpace(args.Path)
+		if args.Path == "" {
+			return nil, nil, fmt.Errorf("path is required")
+		}
+
+		if args.Limit == 0 {
+			args.Limit = 20
+		}
+
+		// Lazy indexing: index sessions that need it
+		if err := indexSessions(adaptersMap, searchCache, args.Source, args.ProjectPath); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+
+		found, err := searchCache.FindSessionsByFile(args.Path, args.Prefix, args.Source, args.ProjectPath, args.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		matches := make([]map[string]interface{}, 0, len(found))
+		var withheld []string
+		for _, match := range found {
+			if !consent.allowed(ctx, req.Session, match.Session.ProjectPath) {
+				withheld = appendUnique(withheld, match.Session.ProjectPath)
+				continue
+			}
+			matches = append(matches, map[string]interface{}{
+				"session": previewSession(match.Session, args.PreviewLength),
+				"files":   match.Files,
+			})
+		}
+
+		result := map[string]interface{}{
+			"path":    args.Path,
+			"prefix":  args.Prefix,
+			"matches": matches,
+			"count":   len(matches),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 25: find_related_sessions
+type findRelatedSessionsArgs struct {
+	SessionID     string `json:"session_id" jsonschema:"The session to find related sessions for"`
+	Source        string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum number of related sessions to return (default: 10)"`
+	PreviewLength int    `json:"preview_length,omitempty" jsonschema:"Truncate each session's first_message and summary to this many characters (default: 200, max: 1000)"`
+}
+
+func addFindRelatedSessionsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "find_related_sessions",
+		Description: "Find sessions related to a given session, across all sources, ranked by similar text (BM25), overlapping files, and a shared project. Useful for reconstructing work that spanned several sessions.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args findRelatedSessionsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if _, ok := adaptersMap[args.Source]; !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if args.Limit == 0 {
+			args.Limit = 10
+		}
+
+		// Related sessions can come from any source, so index them all
+		if err := indexSessions(adaptersMap, searchCache, "", ""); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+
+		related, err := searchCache.RelatedSessions(args.SessionID, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		matches := make([]map[string]interface{}, 0, args.Limit)
+		var withheld []string
+		for _, r := range related {
+			if len(matches) == args.Limit {
+				break
+			}
+			if !consent.allowed(ctx, req.Session, r.Session.ProjectPath) {
+				withheld = appendUnique(withheld, r.Session.ProjectPath)
+				continue
+			}
+			match := map[string]interface{}{
+				"session":      previewSession(r.Session, args.PreviewLength),
+				"score":        r.Score,
+				"text_score":   r.TextScore,
+				"same_project": r.SameProject,
+			}
+			if len(r.SharedFiles) > 0 {
+				match["shared_files"] = r.SharedFiles
+			}
+			matches = append(matches, match)
+		}
+
+		result := map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"related":    matches,
+			"count":      len(matches),
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
 		}
 
 		resultJSON, err := json.MarshalIndent(result, "", "  ")
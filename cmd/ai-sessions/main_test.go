@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/yoavf/ai-sessions-mcp/adapters"
 	"github.com/yoavf/ai-sessions-mcp/search"
 )
 
 type stubAdapter struct {
+	mu        sync.Mutex // guards the call counts, as tools may run concurrently
 	sessions  []adapters.Session
 	messages  map[string][]adapters.Message
 	listErr   error
@@ -35,7 +41,9 @@ func (s *stubAdapter) Name() string {
 }
 
 func (s *stubAdapter) ListSessions(projectPath string, limit int) ([]adapters.Session, error) {
+	s.mu.Lock()
 	s.listCalls++
+	s.mu.Unlock()
 	if s.listErr != nil {
 		return nil, s.listErr
 	}
@@ -43,7 +51,9 @@ func (s *stubAdapter) ListSessions(projectPath string, limit int) ([]adapters.Se
 }
 
 func (s *stubAdapter) GetSession(sessionID string, page, pageSize int) ([]adapters.Message, error) {
+	s.mu.Lock()
 	s.getCalls[sessionID]++
+	s.mu.Unlock()
 	if msgs, ok := s.messages[sessionID]; ok {
 		return msgs, nil
 	}
@@ -152,3 +162,454 @@ func TestIndexSessionsSkipsUnknownSource(t *testing.T) {
 		t.Fatalf("expected GetSession not to be called, got %d calls", len(adapter.getCalls))
 	}
 }
+
+func TestIndexSessionsSkipsForgottenSessions(t *testing.T) {
+	cache := newTestCache(t)
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+	session := adapters.Session{ID: "sess-1", Source: "stub", Timestamp: time.Now(), FilePath: sessionFile}
+	adapter := newStubAdapter([]adapters.Session{session}, map[string][]adapters.Message{
+		"sess-1": {{Role: "user", Content: "leaked secret token"}},
+	})
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": adapter}
+
+	if err := indexSessions(adaptersMap, cache, "", ""); err != nil {
+		t.Fatalf("indexSessions returned error: %v", err)
+	}
+	if _, err := cache.ForgetSession("sess-1", "stub", time.Now()); err != nil {
+		t.Fatalf("ForgetSession failed: %v", err)
+	}
+
+	// Neither a lazy nor a forced reindex brings it back
+	if err := indexSessions(adaptersMap, cache, "", ""); err != nil {
+		t.Fatalf("indexSessions returned error: %v", err)
+	}
+	if err := indexSessionsContext(context.Background(), adaptersMap, cache, "", "", indexOptions{force: true}); err != nil {
+		t.Fatalf("forced indexSessions returned error: %v", err)
+	}
+	if got := adapter.getCalls["sess-1"]; got != 1 {
+		t.Fatalf("expected a forgotten session not to be read again, got %d GetSession calls", got)
+	}
+	if results, err := cache.Search("secret token", "", "", 10); err != nil || len(results) != 0 {
+		t.Fatalf("expected a forgotten session to stay out of search, got %d results (%v)", len(results), err)
+	}
+
+	if _, err := cache.UnforgetSession("sess-1", "stub"); err != nil {
+		t.Fatalf("UnforgetSession failed: %v", err)
+	}
+	if err := indexSessions(adaptersMap, cache, "", ""); err != nil {
+		t.Fatalf("indexSessions returned error: %v", err)
+	}
+	if results, err := cache.Search("secret token", "", "", 10); err != nil || len(results) != 1 {
+		t.Fatalf("expected an unforgotten session to be indexed again, got %d results (%v)", len(results), err)
+	}
+}
+
+func TestConcurrentIndexing(t *testing.T) {
+	cache := newTestCache(t)
+	dir := t.TempDir()
+
+	var sessions []adapters.Session
+	messages := make(map[string][]adapters.Message)
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("sess-%d", i)
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte("dummy"), 0o644); err != nil {
+			t.Fatalf("failed to create session file: %v", err)
+		}
+		sessions = append(sessions, adapters.Session{ID: id, Source: "stub", Timestamp: time.Now(), FilePath: path})
+		messages[id] = []adapters.Message{{Role: "user", Content: "concurrent " + id}}
+	}
+	adapter := newStubAdapter(sessions, messages)
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": adapter}
+
+	// Simultaneous searches and a filesystem watcher all index at once
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- indexSessions(adaptersMap, cache, "", "")
+		}()
+	}
+	for _, session := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			indexSession(cache, adapter, session, false)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("indexSessions returned error: %v", err)
+		}
+	}
+
+	// Each session was read once, however many runs overlapped
+	for _, session := range sessions {
+		if got := adapter.getCalls[session.ID]; got != 1 {
+			t.Errorf("expected %s to be read once, got %d GetSession calls", session.ID, got)
+		}
+	}
+	if results, err := cache.Search("concurrent", "", "", 10); err != nil || len(results) != len(sessions) {
+		t.Fatalf("expected every session to be indexed, got %d results (%v)", len(results), err)
+	}
+}
+
+func TestIndexSessionsSince(t *testing.T) {
+	cache := newTestCache(t)
+	dir := t.TempDir()
+	week := time.Now().Add(-7 * 24 * time.Hour)
+
+	var sessions []adapters.Session
+	messages := make(map[string][]adapters.Message)
+	for _, id := range []string{"old", "resumed", "new"} {
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte("dummy"), 0o644); err != nil {
+			t.Fatalf("failed to create session file: %v", err)
+		}
+		session := adapters.Session{ID: id, Source: "stub", Timestamp: week, FilePath: path}
+		switch id {
+		case "new":
+			session.Timestamp = time.Now()
+		case "old":
+			if err := os.Chtimes(path, week, week); err != nil {
+				t.Fatalf("failed to update file mtime: %v", err)
+			}
+		}
+		sessions = append(sessions, session)
+		messages[id] = []adapters.Message{{Role: "user", Content: "hello"}}
+	}
+	adapter := newStubAdapter(sessions, messages)
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": adapter}
+
+	var total int
+	err := indexSessionsContext(context.Background(), adaptersMap, cache, "", "", indexOptions{
+		since:    time.Now().Add(-time.Hour),
+		progress: func(done, n int) { total = n },
+	})
+	if err != nil {
+		t.Fatalf("indexSessionsContext returned error: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("expected 2 sessions active in the last hour, got %d", total)
+	}
+	if adapter.getCalls["old"] != 0 || adapter.getCalls["resumed"] != 1 || adapter.getCalls["new"] != 1 {
+		t.Fatalf("unexpected GetSession calls: %v", adapter.getCalls)
+	}
+}
+
+func TestIndexSessionsClassifiesOutcomes(t *testing.T) {
+	cache := newTestCache(t)
+
+	dir := t.TempDir()
+	var sessions []adapters.Session
+	for _, id := range []string{"done", "dropped"} {
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte("dummy"), 0o644); err != nil {
+			t.Fatalf("failed to create session file: %v", err)
+		}
+		sessions = append(sessions, adapters.Session{ID: id, Source: "stub", Timestamp: time.Now(), FilePath: path})
+	}
+	adapter := newStubAdapter(sessions, map[string][]adapters.Message{
+		"done": {
+			{Role: "user", Content: "Rename the config flag"},
+			{Role: "assistant", Content: "Renamed it successfully."},
+		},
+		"dropped": {
+			{Role: "user", Content: "Rename the config flag"},
+			{Role: "assistant", Content: "Which one?"},
+			{Role: "user", Content: "The timeout one"},
+		},
+	})
+	if err := indexSessions(map[string]adapters.SessionAdapter{"stub": adapter}, cache, "", ""); err != nil {
+		t.Fatalf("indexSessions returned error: %v", err)
+	}
+
+	outcomes := outcomesForSessions(cache, sessions)
+	if outcomes["done"].Outcome != adapters.OutcomeCompleted || outcomes["dropped"].Outcome != adapters.OutcomeAbandoned {
+		t.Fatalf("unexpected outcomes: %+v", outcomes)
+	}
+	if len(outcomes["dropped"].Signals) == 0 {
+		t.Fatalf("expected the signals to be stored, got %+v", outcomes["dropped"])
+	}
+	unindexed := adapters.Session{ID: "new", Source: "stub"}
+	kept := filterByOutcome(append(sessions, unindexed), outcomes, adapters.OutcomeAbandoned)
+	if len(kept) != 1 || kept[0].ID != "dropped" {
+		t.Fatalf("expected only the abandoned session, got %+v", kept)
+	}
+}
+
+func TestParseServerFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		noCache    bool
+		allowWrite bool
+		httpAddr   string
+		isServer   bool
+	}{
+		{args: nil, isServer: true},
+		{args: []string{"--no-cache"}, noCache: true, isServer: true},
+		{args: []string{"--in-memory"}, noCache: true, isServer: true},
+		{args: []string{"--allow-write", "--no-cache"}, noCache: true, allowWrite: true, isServer: true},
+		{args: []string{"upload", "file.jsonl"}, isServer: false},
+		{args: []string{"--no-cache", "version"}, isServer: false},
+		{args: []string{"--http", ":8080", "--no-cache"}, noCache: true, httpAddr: ":8080", isServer: true},
+		{args: []string{"--http=127.0.0.1:9000"}, httpAddr: "127.0.0.1:9000", isServer: true},
+		{args: []string{"--http"}, isServer: false},
+	}
+
+	for _, tt := range tests {
+		flags, isServer := parseServerFlags(tt.args)
+		if isServer != tt.isServer || flags.noCache != tt.noCache || flags.allowWrite != tt.allowWrite || flags.httpAddr != tt.httpAddr {
+			t.Errorf("parseServerFlags(%v) = %+v, %v; want noCache=%v, allowWrite=%v, httpAddr=%q, %v", tt.args, flags, isServer, tt.noCache, tt.allowWrite, tt.httpAddr, tt.isServer)
+		}
+	}
+}
+
+func TestOpenSearchCacheBackends(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dsn := filepath.Join(t.TempDir(), "index.db")
+	store, err := openSearchCache(serverFlags{}, CacheConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("openSearchCache returned error: %v", err)
+	}
+	status, err := store.Status()
+	store.Close()
+	if err != nil || status.Path != dsn {
+		t.Fatalf("expected the configured sqlite file, got %+v (%v)", status, err)
+	}
+
+	// --no-cache wins over the configured backend
+	store, err = openSearchCache(serverFlags{noCache: true}, CacheConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("openSearchCache returned error: %v", err)
+	}
+	status, err = store.Status()
+	store.Close()
+	if err != nil || !status.InMemory {
+		t.Fatalf("expected an in-memory index with --no-cache, got %+v (%v)", status, err)
+	}
+
+	if _, err := openSearchCache(serverFlags{}, CacheConfig{Backend: "bbolt"}); err == nil {
+		t.Fatal("expected an unknown backend to fail")
+	}
+	if path, err := (CacheConfig{}).sqlitePath(); err != nil || !strings.HasSuffix(path, filepath.Join(".cache", "ai-sessions", "search.db")) {
+		t.Fatalf("expected the default sqlite path, got %q (%v)", path, err)
+	}
+	if _, err := (CacheConfig{Backend: "memory"}).sqlitePath(); err == nil {
+		t.Fatal("expected sqlitePath to fail for a non-sqlite backend")
+	}
+}
+
+func TestSummarizeProjects(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	sessions := []adapters.Session{
+		{ID: "a", Source: "claude", ProjectPath: "/work/api", Timestamp: day},
+		{ID: "b", Source: "codex", ProjectPath: "/work/api", Timestamp: day.Add(48 * time.Hour)},
+		{ID: "agent-1", Source: "claude", ProjectPath: "/work/api", ParentSessionID: "a", Timestamp: day},
+		{ID: "c", Source: "gemini", ProjectPath: "/work/web", Timestamp: day.Add(24 * time.Hour)},
+		{ID: "d", Source: "copilot", Timestamp: day},
+	}
+
+	projects, unknown := summarizeProjects(sessions)
+	if unknown != 1 {
+		t.Fatalf("expected 1 session without a project, got %d", unknown)
+	}
+	if len(projects) != 2 || projects[0].ProjectPath != "/work/api" || projects[1].ProjectPath != "/work/web" {
+		t.Fatalf("expected projects ordered by last activity, got %+v", projects)
+	}
+
+	api := projects[0]
+	if api.SessionCount != 2 || api.Sources["claude"] != 1 || api.Sources["codex"] != 1 {
+		t.Fatalf("unexpected counts for /work/api: %+v", api)
+	}
+	if !api.FirstActivity.Equal(day) || !api.LastActivity.Equal(day.Add(48*time.Hour)) {
+		t.Fatalf("unexpected activity range for /work/api: %v - %v", api.FirstActivity, api.LastActivity)
+	}
+}
+
+func TestLastPageMatchesGetSessionPages(t *testing.T) {
+	messages := make([]adapters.Message, 45)
+	for i := range messages {
+		messages[i] = adapters.Message{Role: "user", Content: fmt.Sprintf("message %d", i)}
+	}
+	adapter := newStubAdapter(nil, map[string][]adapters.Message{"sess-1": messages})
+
+	tail, total, page, err := lastPage(adapter, "sess-1", 20)
+	if err != nil {
+		t.Fatalf("lastPage failed: %v", err)
+	}
+	if total != 45 || page != 2 || len(tail) != 5 || tail[0].Content != "message 40" {
+		t.Fatalf("expected page 2 with messages 40-44 of 45, got page %d with %d of %d starting %q", page, len(tail), total, tail[0].Content)
+	}
+
+	empty := newStubAdapter(nil, map[string][]adapters.Message{"sess-2": {}})
+	if tail, _, page, err := lastPage(empty, "sess-2", 20); err != nil || page != 0 || len(tail) != 0 {
+		t.Fatalf("expected an empty page 0 for an empty session, got page %d with %d messages (%v)", page, len(tail), err)
+	}
+}
+
+func TestPreviewSession(t *testing.T) {
+	session := adapters.Session{ID: "s1", FirstMessage: strings.Repeat("né ", 200), Summary: "Short summary"}
+
+	got := previewSession(session, 0)
+	if want := adapters.TruncateText(session.FirstMessage, adapters.DefaultPreviewLength); got.FirstMessage != want {
+		t.Fatalf("expected the default preview length, got %q", got.FirstMessage)
+	}
+	if got.Summary != "Short summary" {
+		t.Fatalf("expected a short summary to be kept, got %q", got.Summary)
+	}
+
+	if got := previewSession(session, 5); got.FirstMessage != "né né..." {
+		t.Fatalf("expected a 5-character preview, got %q", got.FirstMessage)
+	}
+	if session.FirstMessage != strings.Repeat("né ", 200) {
+		t.Fatal("previewSession should not modify its argument")
+	}
+}
+
+func TestGetMessagesReturnsRange(t *testing.T) {
+	messages := make([]adapters.Message, 30)
+	for i := range messages {
+		messages[i] = adapters.Message{Role: "user", Content: fmt.Sprintf("message %d", i)}
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, map[string][]adapters.Message{"sess-1": messages})}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetMessagesTool(server, adaptersMap, nil, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	call := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "get_messages", Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool: %v", err)
+		}
+		return result
+	}
+
+	result := call(map[string]interface{}{"session_id": "sess-1", "source": "stub", "start_index": 25, "end_index": 40})
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var got struct {
+		EndIndex int                `json:"end_index"`
+		HasMore  bool               `json:"has_more"`
+		Messages []adapters.Message `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if got.EndIndex != 29 || got.HasMore || len(got.Messages) != 5 || got.Messages[0].Content != "message 25" {
+		t.Fatalf("expected messages 25-29, got end %d with %d messages", got.EndIndex, len(got.Messages))
+	}
+
+	if result := call(map[string]interface{}{"session_id": "sess-1", "source": "stub", "start_index": 30}); !result.IsError {
+		t.Fatal("expected an error for a start_index past the end")
+	}
+}
+
+func TestFullContentToolsRecordAccess(t *testing.T) {
+	session := adapters.Session{ID: "s1", Source: "stub", ProjectPath: "/p", Timestamp: time.Now()}
+	messages := []adapters.Message{
+		{Role: "user", Content: "print hello"},
+		{Role: "assistant", Content: "```go\nfmt.Println(\"hello\")\n```"},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter([]adapters.Session{session}, map[string][]adapters.Message{"s1": messages})}
+	cache := newTestCache(t)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetFirstAndLastExchangeTool(server, adaptersMap, cache, nil)
+	addGetToolCallsTool(server, adaptersMap, cache, nil)
+	addExtractCodeBlocksTool(server, adaptersMap, cache, nil)
+	addExtractShellCommandsTool(server, adaptersMap, cache, nil)
+	addExportSessionTool(server, adaptersMap, cache, nil, false)
+	addExportReviewChecklistTool(server, adaptersMap, cache, nil, false)
+	addGenerateResumeContextTool(server, adaptersMap, cache, nil)
+	clientSession := newTestClient(t, server)
+
+	// Eviction keeps recently read sessions, so every read of content counts
+	tools := []string{"get_first_and_last_exchange", "get_tool_calls", "extract_code_blocks", "extract_shell_commands", "export_session", "export_review_checklist", "generate_resume_context"}
+	for _, name := range tools {
+		result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: map[string]interface{}{"session_id": "s1", "source": "stub"}})
+		if err != nil || result.IsError {
+			t.Fatalf("CallTool(%s) = %+v, %v", name, result, err)
+		}
+	}
+
+	entries, err := cache.AccessLog("s1", "stub", 0)
+	if err != nil {
+		t.Fatalf("AccessLog: %v", err)
+	}
+	if len(entries) != len(tools) {
+		t.Fatalf("expected a read recorded for each of %d tools, got %d", len(tools), len(entries))
+	}
+	for _, entry := range entries {
+		if entry.MessageCount != len(messages) {
+			t.Fatalf("expected each read to count the session's messages, got %+v", entry)
+		}
+	}
+}
+
+func TestGetCostReportUsesIndexedCosts(t *testing.T) {
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	usage := func(input, cacheRead float64) map[string]interface{} {
+		return map[string]interface{}{
+			"model": "claude-sonnet-4",
+			"usage": map[string]interface{}{"input_tokens": input, "output_tokens": 1000.0, "cache_read_input_tokens": cacheRead},
+		}
+	}
+	stub := newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", ProjectPath: "/app", Timestamp: time.Now(), FilePath: sessionFile}},
+		map[string][]adapters.Message{"sess-1": {
+			{Role: "user", Content: "add caching"},
+			{Role: "assistant", Content: "done", Metadata: usage(1000, 3000)},
+		}},
+	)
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": stub}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetCostReportTool(server, adaptersMap, newTestCache(t), nil)
+	clientSession := newTestClient(t, server)
+
+	for call := 0; call < 2; call++ {
+		var report struct {
+			Total    adapters.CostReport `json:"total"`
+			Projects []projectCost       `json:"projects"`
+		}
+		callJSONTool(t, clientSession, "get_cost_report", map[string]interface{}{"source": "stub"}, &report)
+		if report.Total.SessionCount != 1 || report.Total.Tokens.Input != 1000 || report.Total.CacheHitRatio != 0.75 {
+			t.Fatalf("unexpected total on call %d: %+v", call, report.Total)
+		}
+		if len(report.Projects) != 1 || report.Projects[0].ProjectPath != "/app" || report.Projects[0].CostUSD == 0 {
+			t.Fatalf("unexpected projects on call %d: %+v", call, report.Projects)
+		}
+	}
+	// The session is read once, to index it, rather than on every call
+	if calls := stub.getCalls["sess-1"]; calls != 1 {
+		t.Fatalf("expected the session to be read once, got %d reads", calls)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// staleSessionGrace is how long a session's file may be missing before
+// maintenance removes it from the index.
+const staleSessionGrace = 7 * 24 * time.Hour
+
+// minMaintenanceInterval keeps an interval schedule from running maintenance
+// (which vacuums the whole index) back to back.
+const minMaintenanceInterval = time.Hour
+
+// maintenanceSchedule is when maintenance runs: daily at a local time of day,
+// or at a fixed interval.
+type maintenanceSchedule struct {
+	hour, minute int
+	every        time.Duration
+}
+
+// maintenanceSchedule parses the configured maintenance_schedule: a local time
+// of day such as "03:00", or a Go duration such as "12h". ok is false when
+// maintenance is off.
+func (c *ServerConfig) maintenanceSchedule() (schedule maintenanceSchedule, ok bool, err error) {
+	value := c.MaintenanceSchedule
+	if value == "" || value == "off" {
+		return maintenanceSchedule{}, false, nil
+	}
+	if t, err := time.Parse("15:04", value); err == nil {
+		return maintenanceSchedule{hour: t.Hour(), minute: t.Minute()}, true, nil
+	}
+	every, err := time.ParseDuration(value)
+	if err != nil {
+		return maintenanceSchedule{}, false, fmt.Errorf("invalid maintenance_schedule %q (expected a time of day like 03:00 or a duration like 12h)", value)
+	}
+	if every < minMaintenanceInterval {
+		return maintenanceSchedule{}, false, fmt.Errorf("maintenance_schedule must be at least %s", minMaintenanceInterval)
+	}
+	return maintenanceSchedule{every: every}, true, nil
+}
+
+// next returns the first scheduled run after t.
+func (s maintenanceSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	run := time.Date(t.Year(), t.Month(), t.Day(), s.hour, s.minute, 0, 0, t.Location())
+	if !run.After(t) {
+		run = run.AddDate(0, 0, 1)
+	}
+	return run
+}
+
+// String describes the schedule as it is configured.
+func (s maintenanceSchedule) String() string {
+	if s.every > 0 {
+		return "every " + s.every.String()
+	}
+	return fmt.Sprintf("daily at %02d:%02d", s.hour, s.minute)
+}
+
+// maintenanceReport is the outcome of one maintenance run.
+type maintenanceReport struct {
+	StartedAt       time.Time          `json:"started_at"`
+	FinishedAt      time.Time          `json:"finished_at"`
+	SessionsChecked int                `json:"sessions_checked"`
+	StaleSessions   search.StaleReview `json:"stale_sessions"`
+	Evicted         search.Eviction    `json:"evicted"`
+	BytesBefore     int64              `json:"bytes_before"`
+	BytesAfter      int64              `json:"bytes_after"`
+	Errors          []string           `json:"errors,omitempty"`
+}
+
+// runMaintenance brings the index up to date, reviews sessions whose files
+// have gone missing (removing those missing past the grace period), evicts
+// the least recently used sessions beyond the retention caps, and vacuums the
+// database. A failed step is recorded and the rest still run.
+func runMaintenance(ctx context.Context, adaptersMap map[string]adapters.SessionAdapter, cache search.Store, retention search.Retention, now time.Time) maintenanceReport {
+	report := maintenanceReport{StartedAt: now}
+	fail := func(step string, err error) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	opts := indexOptions{progress: func(_, total int) { report.SessionsChecked = total }}
+	if err := indexSessionsContext(ctx, adaptersMap, cache, "", "", opts); err != nil {
+		fail("index", err)
+	}
+	if ctx.Err() == nil {
+		review, err := cache.ReviewStaleSessions(now, staleSessionGrace)
+		if err != nil {
+			fail("stale sessions", err)
+		}
+		report.StaleSessions = review
+	}
+	if ctx.Err() == nil {
+		eviction, err := cache.Evict(now, retention)
+		if err != nil {
+			fail("eviction", err)
+		}
+		report.Evicted = eviction
+	}
+	if ctx.Err() == nil {
+		before, after, err := cache.Vacuum()
+		if err != nil {
+			fail("vacuum", err)
+		}
+		report.BytesBefore, report.BytesAfter = before, after
+	}
+
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// maintainer runs maintenance on its schedule and remembers the last run for
+// get_diagnostics.
+type maintainer struct {
+	schedule  maintenanceSchedule
+	retention search.Retention
+
+	mu      sync.Mutex
+	nextRun time.Time
+	running bool
+	last    *maintenanceReport
+}
+
+// maintenanceStatus is what get_diagnostics shows about scheduled maintenance.
+type maintenanceStatus struct {
+	Schedule string             `json:"schedule"`
+	NextRun  time.Time          `json:"next_run"`
+	Running  bool               `json:"running"`
+	LastRun  *maintenanceReport `json:"last_run,omitempty"`
+}
+
+// status returns a snapshot of the maintainer's state.
+func (m *maintainer) status() maintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maintenanceStatus{Schedule: m.schedule.String(), NextRun: m.nextRun, Running: m.running, LastRun: m.last}
+}
+
+// run waits for each scheduled time and runs maintenance, until ctx is cancelled.
+func (m *maintainer) run(ctx context.Context, adaptersMap map[string]adapters.SessionAdapter, cache search.Store) {
+	for {
+		m.mu.Lock()
+		m.nextRun = m.schedule.next(time.Now())
+		wait := time.Until(m.nextRun)
+		m.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if lowPower.Load() {
+			continue // skipped in low-power mode; the next scheduled run catches up
+		}
+
+		m.mu.Lock()
+		m.running = true
+		m.mu.Unlock()
+
+		report := runMaintenance(ctx, adaptersMap, cache, m.retention, time.Now())
+		for _, msg := range report.Errors {
+			log.Printf("Warning: maintenance %s", msg)
+		}
+
+		m.mu.Lock()
+		m.running = false
+		m.last = &report
+		m.mu.Unlock()
+	}
+}
+
+// Tool 37: get_diagnostics
+type getDiagnosticsArgs struct{}
+
+// addGetDiagnosticsTool reports the server's state. maint is nil when
+// scheduled maintenance is off.
+func addGetDiagnosticsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, config *ServerConfig, maint *maintainer, indexer *backgroundIndexer) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_diagnostics",
+		Description: "Report the server's state: available sources, the size and freshness of the search index, sessions quarantined because their files are missing or evicted by the retention caps, background indexing, and the schedule and results of the last maintenance run",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getDiagnosticsArgs) (*mcp.CallToolResult, any, error) {
+		sources := make([]string, 0, len(adaptersMap))
+		for name := range adaptersMap {
+			sources = append(sources, name)
+		}
+		sort.Strings(sources)
+
+		info, err := searchCache.Info()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := map[string]interface{}{
+			"sources":         sources,
+			"index":           info,
+			"aggregates_only": config.AggregatesOnly,
+			"indexer":         indexer.currentStatus(),
+			"power":           powerStatus(),
+		}
+		if config.IndexPollInterval != "" {
+			result["index_poll_interval"] = config.IndexPollInterval
+		}
+		if maint != nil {
+			result["maintenance"] = maint.status()
+		} else {
+			result["maintenance"] = map[string]string{"schedule": "off"}
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
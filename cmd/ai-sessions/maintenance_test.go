@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestMaintenanceSchedule(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    string
+		ok      bool
+		wantErr bool
+	}{
+		{"", "", false, false},
+		{"off", "", false, false},
+		{"03:00", "daily at 03:00", true, false},
+		{"12h", "every 12h0m0s", true, false},
+		{"5m", "", false, true},
+		{"nightly", "", false, true},
+	} {
+		config := &ServerConfig{MaintenanceSchedule: tt.value}
+		got, ok, err := config.maintenanceSchedule()
+		if (err != nil) != tt.wantErr || ok != tt.ok || (ok && got.String() != tt.want) {
+			t.Errorf("maintenanceSchedule(%q) = %v, %v, %v; want %q, %v (error: %v)", tt.value, got, ok, err, tt.want, tt.ok, tt.wantErr)
+		}
+	}
+}
+
+func TestMaintenanceScheduleNext(t *testing.T) {
+	daily := maintenanceSchedule{hour: 3}
+	before := time.Date(2025, 3, 10, 1, 0, 0, 0, time.Local)
+	if got, want := daily.next(before), time.Date(2025, 3, 10, 3, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("next(%v) = %v, want %v", before, got, want)
+	}
+	at := time.Date(2025, 3, 10, 3, 0, 0, 0, time.Local)
+	if got, want := daily.next(at), time.Date(2025, 3, 11, 3, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("next(%v) = %v, want %v", at, got, want)
+	}
+	interval := maintenanceSchedule{every: 6 * time.Hour}
+	if got, want := interval.next(at), at.Add(6*time.Hour); !got.Equal(want) {
+		t.Errorf("next(%v) = %v, want %v", at, got, want)
+	}
+}
+
+func TestRunMaintenance(t *testing.T) {
+	cache := newTestCache(t)
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+	adapter := newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", FilePath: sessionFile, Timestamp: time.Now()}},
+		map[string][]adapters.Message{"sess-1": {{Role: "user", Content: "maintained keyword"}}},
+	)
+
+	report := runMaintenance(context.Background(), map[string]adapters.SessionAdapter{"stub": adapter}, cache, search.Retention{}, time.Now())
+	if len(report.Errors) != 0 {
+		t.Fatalf("unexpected maintenance errors: %v", report.Errors)
+	}
+	if report.SessionsChecked != 1 || report.BytesAfter == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if results, err := cache.Search("maintained keyword", "", "", 10); err != nil || len(results) != 1 {
+		t.Fatalf("expected maintenance to index the session, got %d results (%v)", len(results), err)
+	}
+
+	if err := os.Remove(sessionFile); err != nil {
+		t.Fatalf("failed to remove session file: %v", err)
+	}
+	adapter.sessions = nil
+	report = runMaintenance(context.Background(), map[string]adapters.SessionAdapter{"stub": adapter}, cache, search.Retention{}, time.Now())
+	if report.StaleSessions.Quarantined != 1 {
+		t.Fatalf("expected the missing session to be quarantined, got %+v", report.StaleSessions)
+	}
+}
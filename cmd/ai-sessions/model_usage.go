@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// collectModelUsage aggregates model usage over each source's most recent
+// sessions, up to limit per source, skipping sessions last active before since
+// when it is set. filter decides which listed sessions may be read and returns
+// the projects it withheld.
+func collectModelUsage(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, since time.Time, limit int, filter func([]adapters.Session) ([]adapters.Session, []string)) ([]adapters.ModelUsage, int, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, 0, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	tracker := adapters.NewModelUsageTracker()
+	sessionCount := 0
+	var withheld []string
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, limit)
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		for _, session := range listed {
+			if !since.IsZero() && session.Timestamp.Before(since) {
+				continue
+			}
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+			tracker.AddSession(name, messages)
+			sessionCount++
+		}
+	}
+	sort.Strings(withheld)
+	return tracker.Usage(), sessionCount, withheld, nil
+}
+
+// Tool 38: get_model_usage
+type getModelUsageArgs struct {
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include sessions from this project. Leave empty for all projects."`
+	Since       string `json:"since,omitempty" jsonschema:"Only include sessions active since this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h)"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions to include per source (default: 100)"`
+}
+
+func addGetModelUsageTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_model_usage",
+		Description: "Report which models were used across recent sessions: per model, the sources and number of sessions it appeared in, the number of responses, tokens, estimated cost, and when it was first and last used",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getModelUsageArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 100
+		}
+
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = parseSince(args.Since, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		models, sessionCount, withheld, err := collectModelUsage(adaptersMap, args.Source, args.ProjectPath, since, args.Limit, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := map[string]interface{}{
+			"session_count": sessionCount,
+			"models":        models,
+		}
+		if !since.IsZero() {
+			result["since"] = since.Format(time.RFC3339)
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// notesForSessions returns the notes on sessions, keyed by session ID, for
+// list results. A failed lookup is logged and leaves the notes out.
+func notesForSessions(searchCache search.Store, sessions []adapters.Session) map[string][]search.Note {
+	if searchCache == nil {
+		return nil
+	}
+	notes, err := searchCache.NotesForSessions(sessions)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return notes
+}
+
+// notesForSession returns the notes on one session, for get results.
+func notesForSession(searchCache search.Store, sessionID, source string) []search.Note {
+	if searchCache == nil {
+		return nil
+	}
+	notes, err := searchCache.Notes(sessionID, source)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return notes
+}
+
+// Tool 31: annotate_session
+type annotateSessionArgs struct {
+	SessionID    string `json:"session_id" jsonschema:"The session to annotate"`
+	Source       string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Text         string `json:"text" jsonschema:"The note, such as \"this is where the migration strategy was decided\""`
+	MessageIndex *int   `json:"message_index,omitempty" jsonschema:"Anchor the note to this message, counting from 0. Leave empty for a note on the whole session."`
+}
+
+func addAnnotateSessionTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "annotate_session",
+		Description: "Leave a note on a session, optionally anchored to one message, as a breadcrumb for later. Notes are stored locally and returned with the session by list_sessions, search_sessions, get_session, get_messages, and get_last_session.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args annotateSessionArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		args.Text = strings.TrimSpace(args.Text)
+		if args.Text == "" {
+			return nil, nil, fmt.Errorf("text is required")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		// Reading the session confirms it exists and bounds the anchor
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if args.MessageIndex != nil && (*args.MessageIndex < 0 || *args.MessageIndex >= len(messages)) {
+			return nil, nil, fmt.Errorf("message_index %d is out of range (session has %d messages)", *args.MessageIndex, len(messages))
+		}
+
+		note, err := searchCache.AddNote(search.Note{
+			SessionID:    args.SessionID,
+			Source:       args.Source,
+			MessageIndex: args.MessageIndex,
+			Text:         args.Text,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := map[string]interface{}{
+			"note":  note,
+			"notes": notesForSession(searchCache, args.SessionID, args.Source),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
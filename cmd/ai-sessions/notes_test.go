@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestAnnotateSessionNotesAppearInListResults(t *testing.T) {
+	sessions := []adapters.Session{
+		{ID: "sess-1", Source: "stub", FirstMessage: "Plan the migration", Timestamp: time.Now()},
+		{ID: "sess-2", Source: "stub", FirstMessage: "Fix a typo", Timestamp: time.Now().Add(-time.Hour)},
+	}
+	messages := map[string][]adapters.Message{
+		"sess-1": {{Role: "user", Content: "Plan the migration"}, {Role: "assistant", Content: "Use expand/contract."}},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, messages)}
+	cache := newTestCache(t)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addAnnotateSessionTool(server, adaptersMap, cache, nil)
+	addListSessionsTool(server, adaptersMap, cache, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	call := func(name string, args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool: %v", err)
+		}
+		return result
+	}
+
+	result := call("annotate_session", map[string]interface{}{
+		"session_id": "sess-1", "source": "stub", "text": "migration strategy decided here", "message_index": 1,
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if result := call("annotate_session", map[string]interface{}{
+		"session_id": "sess-1", "source": "stub", "text": "out of range", "message_index": 2,
+	}); !result.IsError {
+		t.Fatal("expected an error for a message_index past the end")
+	}
+
+	result = call("list_sessions", map[string]interface{}{"source": "stub"})
+	var got struct {
+		Notes map[string][]search.Note `json:"notes"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	notes := got.Notes["sess-1"]
+	if len(got.Notes) != 1 || len(notes) != 1 || notes[0].Text != "migration strategy decided here" || notes[0].MessageIndex == nil || *notes[0].MessageIndex != 1 {
+		t.Fatalf("expected the anchored note on sess-1, got %+v", got.Notes)
+	}
+}
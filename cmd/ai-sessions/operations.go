@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Operation states reported by get_operation_status.
+const (
+	operationRunning   = "running"
+	operationSucceeded = "succeeded"
+	operationFailed    = "failed"
+	operationCancelled = "cancelled"
+)
+
+// operationStatus is a snapshot of a background operation.
+type operationStatus struct {
+	ID         string      `json:"operation_id"`
+	Kind       string      `json:"kind"`
+	Status     string      `json:"status"`
+	Done       int         `json:"done"`
+	Total      int         `json:"total"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+}
+
+// operationFunc does the work of a background operation. It should return
+// promptly once ctx is cancelled and call progress as it goes.
+type operationFunc func(ctx context.Context, progress func(done, total int)) (interface{}, error)
+
+type operation struct {
+	status operationStatus
+	cancel context.CancelFunc
+}
+
+// operationManager runs heavy tool work (full reindex, exports) in the background
+// so a tool call can return an operation ID immediately.
+type operationManager struct {
+	mu     sync.Mutex
+	ops    map[string]*operation
+	nextID int
+}
+
+func newOperationManager() *operationManager {
+	return &operationManager{ops: make(map[string]*operation)}
+}
+
+// start launches fn in the background and returns its initial status.
+func (m *operationManager) start(kind string, fn operationFunc) operationStatus {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	m.nextID++
+	op := &operation{
+		status: operationStatus{
+			ID:        fmt.Sprintf("op-%d", m.nextID),
+			Kind:      kind,
+			Status:    operationRunning,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+	m.ops[op.status.ID] = op
+	snapshot := op.status
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, func(done, total int) {
+			m.mu.Lock()
+			op.status.Done = done
+			op.status.Total = total
+			m.mu.Unlock()
+		})
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		finished := time.Now()
+		op.status.FinishedAt = &finished
+		switch {
+		case errors.Is(err, context.Canceled):
+			op.status.Status = operationCancelled
+		case err != nil:
+			op.status.Status = operationFailed
+			op.status.Error = err.Error()
+		default:
+			op.status.Status = operationSucceeded
+			op.status.Result = result
+		}
+	}()
+
+	return snapshot
+}
+
+// get returns the current status of an operation.
+func (m *operationManager) get(id string) (operationStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, ok := m.ops[id]
+	if !ok {
+		return operationStatus{}, fmt.Errorf("unknown operation: %s", id)
+	}
+	return op.status, nil
+}
+
+// cancel requests cancellation of a running operation. Cancelling a finished
+// operation is not an error; its final status is returned unchanged.
+func (m *operationManager) cancel(id string) (operationStatus, error) {
+	m.mu.Lock()
+	op, ok := m.ops[id]
+	m.mu.Unlock()
+	if !ok {
+		return operationStatus{}, fmt.Errorf("unknown operation: %s", id)
+	}
+
+	op.cancel()
+	return m.get(id)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForOperation polls until the operation leaves the running state.
+func waitForOperation(t *testing.T, m *operationManager, id string) operationStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := m.get(id)
+		if err != nil {
+			t.Fatalf("get(%s): %v", id, err)
+		}
+		if status.Status != operationRunning {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("operation %s did not finish", id)
+	return operationStatus{}
+}
+
+func TestOperationManagerSucceedsAndFails(t *testing.T) {
+	m := newOperationManager()
+
+	ok := m.start("test", func(ctx context.Context, progress func(done, total int)) (interface{}, error) {
+		progress(2, 2)
+		return "done", nil
+	})
+	if ok.Status != operationRunning || ok.ID == "" {
+		t.Fatalf("unexpected initial status: %+v", ok)
+	}
+	status := waitForOperation(t, m, ok.ID)
+	if status.Status != operationSucceeded || status.Result != "done" || status.Done != 2 || status.FinishedAt == nil {
+		t.Fatalf("unexpected final status: %+v", status)
+	}
+
+	failed := m.start("test", func(ctx context.Context, progress func(done, total int)) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	status = waitForOperation(t, m, failed.ID)
+	if status.Status != operationFailed || status.Error != "boom" {
+		t.Fatalf("unexpected failed status: %+v", status)
+	}
+
+	if _, err := m.get("op-missing"); err == nil {
+		t.Fatalf("expected unknown operation error")
+	}
+}
+
+func TestOperationManagerCancel(t *testing.T) {
+	m := newOperationManager()
+	started := make(chan struct{})
+
+	op := m.start("test", func(ctx context.Context, progress func(done, total int)) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	if _, err := m.cancel(op.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	status := waitForOperation(t, m, op.ID)
+	if status.Status != operationCancelled {
+		t.Fatalf("expected cancelled status, got %+v", status)
+	}
+}
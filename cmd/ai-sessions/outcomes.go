@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// outcomesForSessions returns the indexed outcome of each of sessions that
+// has one, keyed by session ID, for list results. A failed lookup is logged
+// and leaves the outcomes out.
+func outcomesForSessions(searchCache search.Store, sessions []adapters.Session) map[string]adapters.SessionOutcome {
+	if searchCache == nil {
+		return nil
+	}
+	outcomes, err := searchCache.SessionOutcomes(sessions)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return outcomes
+}
+
+// filterByOutcome keeps the sessions whose indexed outcome is outcome.
+// Sessions that haven't been classified are dropped.
+func filterByOutcome(sessions []adapters.Session, outcomes map[string]adapters.SessionOutcome, outcome string) []adapters.Session {
+	kept := make([]adapters.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if o, ok := outcomes[session.ID]; ok && o.Outcome == outcome {
+			kept = append(kept, session)
+		}
+	}
+	return kept
+}
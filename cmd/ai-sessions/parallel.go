@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentSources caps how many adapters list sessions at once. Listing
+// is mostly directory walks and file reads, so a few at a time keeps a slow
+// source from holding up the rest without flooding the disk. Low-power mode
+// lists one at a time.
+const maxConcurrentSources = 4
+
+// sourceSessions is what one adapter listed.
+type sourceSessions struct {
+	name     string
+	adapter  adapters.SessionAdapter
+	sessions []adapters.Session
+}
+
+// listSessionsConcurrently lists sessions from every adapter concurrently.
+// A source that fails to list is logged and left out, like a source with no
+// sessions, so one broken source doesn't fail the rest. Results are in source
+// name order. It returns ctx's error if ctx is cancelled before all sources
+// are listed.
+func listSessionsConcurrently(ctx context.Context, adaptersToQuery map[string]adapters.SessionAdapter, projectPath string, limit int) ([]sourceSessions, error) {
+	names := make([]string, 0, len(adaptersToQuery))
+	for name := range adaptersToQuery {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]sourceSessions, len(names))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(sourceConcurrency())
+	for i, name := range names {
+		adapter := adaptersToQuery[name]
+		results[i] = sourceSessions{name: name, adapter: adapter}
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			sessions, err := adapter.ListSessions(projectPath, limit)
+			if err != nil {
+				log.Printf("Error listing sessions for %s: %v", adapter.Name(), err)
+				return nil
+			}
+			results[i].sessions = sessions
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// flattenSessions flattens listed sessions into one slice, in source name order.
+func flattenSessions(listed []sourceSessions) []adapters.Session {
+	var sessions []adapters.Session
+	for _, l := range listed {
+		sessions = append(sessions, l.sessions...)
+	}
+	return sessions
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// blockingAdapter is a stubAdapter whose listing waits until release is closed.
+type blockingAdapter struct {
+	*stubAdapter
+	release chan struct{}
+}
+
+func (b *blockingAdapter) ListSessions(projectPath string, limit int) ([]adapters.Session, error) {
+	<-b.release
+	return b.stubAdapter.ListSessions(projectPath, limit)
+}
+
+// releasingAdapter is a stubAdapter that releases a blockingAdapter when listed.
+type releasingAdapter struct {
+	*stubAdapter
+	release chan struct{}
+}
+
+func (r *releasingAdapter) ListSessions(projectPath string, limit int) ([]adapters.Session, error) {
+	close(r.release)
+	return r.stubAdapter.ListSessions(projectPath, limit)
+}
+
+func TestListSessionsConcurrently(t *testing.T) {
+	release := make(chan struct{})
+	broken := newStubAdapter(nil, nil)
+	broken.listErr = errors.New("unreadable")
+	adaptersMap := map[string]adapters.SessionAdapter{
+		// Listed first by name, so a serial loop would wait forever on it
+		"a-slow":   &blockingAdapter{stubAdapter: newStubAdapter([]adapters.Session{{ID: "slow-1"}}, nil), release: release},
+		"b-broken": broken,
+		"c-fast":   &releasingAdapter{stubAdapter: newStubAdapter([]adapters.Session{{ID: "fast-1"}, {ID: "fast-2"}}, nil), release: release},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	listed, err := listSessionsConcurrently(ctx, adaptersMap, "", 0)
+	if err != nil {
+		t.Fatalf("listSessionsConcurrently returned error: %v", err)
+	}
+	if len(listed) != 3 || listed[0].name != "a-slow" || listed[1].name != "b-broken" || listed[2].name != "c-fast" {
+		t.Fatalf("expected results in source order, got %+v", listed)
+	}
+	if len(listed[1].sessions) != 0 {
+		t.Fatalf("expected the broken source to list nothing, got %v", listed[1].sessions)
+	}
+
+	var ids []string
+	for _, s := range flattenSessions(listed) {
+		ids = append(ids, s.ID)
+	}
+	if len(ids) != 3 || ids[0] != "slow-1" || ids[1] != "fast-1" || ids[2] != "fast-2" {
+		t.Fatalf("unexpected flattened sessions: %v", ids)
+	}
+
+	cancelled, stop := context.WithCancel(context.Background())
+	stop()
+	if _, err := listSessionsConcurrently(cancelled, adaptersMap, "", 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled context to stop listing, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// personaCapableAdapter is implemented by adapters that can tell which output
+// styles, agents, or custom instructions shaped a session.
+type personaCapableAdapter interface {
+	DetectPersonas(sessionID string) ([]adapters.Persona, error)
+}
+
+// Tool 51: get_session_personas
+type getSessionPersonasArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to detect personas for"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude, codex, copilot, or opencode)"`
+}
+
+func addGetSessionPersonasTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_session_personas",
+		Description: "Show which configuration shaped how the agent behaved in a session: Claude Code output styles, opencode agents, and custom instructions such as CLAUDE.md, AGENTS.md, or Copilot instructions files. Each persona says whether the session recorded it (with the messages it covered) or it was found in the current settings or project, so behavior differences between sessions can be traced to configuration.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionPersonasArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+		detector, ok := adapter.(personaCapableAdapter)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s does not support persona detection (supported: claude, codex, copilot, opencode)", args.Source)
+		}
+
+		session, found := findSession(adapter, args.SessionID)
+		if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+			return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+		}
+
+		personas, err := detector.DetectPersonas(args.SessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to detect personas: %w", err)
+		}
+
+		resultJSON, err := json.MarshalIndent(map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"personas":   personas,
+			"count":      len(personas),
+			"caveat":     adapters.PersonaCaveat,
+		}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// personaStubAdapter is a stub adapter that detects a fixed persona.
+type personaStubAdapter struct {
+	*stubAdapter
+}
+
+func (s personaStubAdapter) DetectPersonas(sessionID string) ([]adapters.Persona, error) {
+	return []adapters.Persona{{Kind: adapters.PersonaAgent, Name: "plan", Evidence: adapters.EvidenceTranscript, Messages: 1}}, nil
+}
+
+func TestGetSessionPersonas(t *testing.T) {
+	sessions := []adapters.Session{{ID: "sess-1", Source: "stub", ProjectPath: "/app"}}
+	adaptersMap := map[string]adapters.SessionAdapter{
+		"stub":  personaStubAdapter{newStubAdapter(sessions, nil)},
+		"plain": newStubAdapter(sessions, nil),
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetSessionPersonasTool(server, adaptersMap, nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "get_session_personas", Arguments: map[string]interface{}{"session_id": "sess-1", "source": "stub"}})
+	if err != nil || result.IsError {
+		t.Fatalf("get_session_personas failed: %v %+v", err, result)
+	}
+	var got struct {
+		Personas []adapters.Persona `json:"personas"`
+		Caveat   string             `json:"caveat"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if len(got.Personas) != 1 || got.Personas[0].Name != "plan" || got.Caveat == "" {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "get_session_personas", Arguments: map[string]interface{}{"session_id": "sess-1", "source": "plain"}})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "does not support persona detection") {
+		t.Fatalf("expected an unsupported source error, got %+v", result)
+	}
+}
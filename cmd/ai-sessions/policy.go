@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fullContentTools lists tools that return full message content rather than
+// metadata, aggregates, or snippets. They are withheld in aggregates-only mode.
+var fullContentTools = []string{
+	"get_session",
+	"get_last_session",
+	"get_messages",
+	"get_first_and_last_exchange",
+	"get_session_tree",
+	"export_session",
+	"export_review_checklist",
+	"generate_resume_context",
+	"get_tool_calls",
+	"list_tool_failures",
+	"extract_code_blocks",
+	"extract_shell_commands",
+	"export_snapshot",
+}
+
+// applyAggregatesOnly removes full-content tools, and the session resource,
+// from the server so untrusted clients can only see session metadata,
+// aggregates, and search snippets.
+func applyAggregatesOnly(server *mcp.Server, config *ServerConfig) {
+	if !config.AggregatesOnly {
+		return
+	}
+	server.RemoveTools(fullContentTools...)
+	server.RemoveResourceTemplates(sessionResourceTemplate)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// listServerTools connects an in-memory client to server and returns its tool names.
+func listServerTools(t *testing.T, server *mcp.Server) map[string]bool {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.ListTools(ctx, &mcp.ListToolsParams{})
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	names := make(map[string]bool, len(result.Tools))
+	for _, tool := range result.Tools {
+		names[tool.Name] = true
+	}
+	return names
+}
+
+func TestApplyAggregatesOnly(t *testing.T) {
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, nil)}
+
+	for _, aggregatesOnly := range []bool{false, true} {
+		server := mcp.NewServer(&mcp.Implementation{Name: "ai-sessions", Version: "test"}, nil)
+		addListSessionsTool(server, adaptersMap, nil, nil)
+		addGetSessionTool(server, adaptersMap, nil, nil)
+		applyAggregatesOnly(server, &ServerConfig{AggregatesOnly: aggregatesOnly})
+
+		tools := listServerTools(t, server)
+		if !tools["list_sessions"] {
+			t.Errorf("aggregatesOnly=%v: list_sessions should stay available", aggregatesOnly)
+		}
+		if tools["get_session"] == aggregatesOnly {
+			t.Errorf("aggregatesOnly=%v: get_session available = %v", aggregatesOnly, tools["get_session"])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Power modes, as accepted by set_power_mode.
+const (
+	powerNormal = "normal"
+	powerLow    = "low"
+)
+
+// Limits in low-power mode. Sources are listed one at a time, and searches
+// return fewer snippets.
+const (
+	lowPowerSourceConcurrency = 1
+	lowPowerSearchResults     = 5  // search_sessions results
+	lowPowerInSessionMatches  = 10 // search_in_session matches
+)
+
+// lowPower is set while the server runs in low-power mode, which keeps it
+// lightweight on laptops running on battery: background indexing, file
+// watching, and scheduled maintenance pause, and searches index only what
+// they need when they run.
+var lowPower atomic.Bool
+
+// setPowerMode switches between normal and low-power mode. Returning to
+// normal asks the indexer for a full pass, to catch up on what changed
+// while indexing was paused.
+func setPowerMode(mode string, indexer *backgroundIndexer) error {
+	switch mode {
+	case powerNormal, powerLow:
+	default:
+		return fmt.Errorf("invalid mode %q (expected %s or %s)", mode, powerNormal, powerLow)
+	}
+	low := mode == powerLow
+	adapters.SetLowPower(low)
+	if lowPower.Swap(low) && !low && indexer != nil {
+		indexer.requestFullPass()
+	}
+	return nil
+}
+
+// currentPowerMode returns the mode the server runs in.
+func currentPowerMode() string {
+	if lowPower.Load() {
+		return powerLow
+	}
+	return powerNormal
+}
+
+// sourceConcurrency returns how many sources may list sessions at once.
+func sourceConcurrency() int {
+	if lowPower.Load() {
+		return lowPowerSourceConcurrency
+	}
+	return maxConcurrentSources
+}
+
+// powerLimit caps limit at lowPowerMax in low-power mode.
+func powerLimit(limit, lowPowerMax int) int {
+	if lowPower.Load() && limit > lowPowerMax {
+		return lowPowerMax
+	}
+	return limit
+}
+
+// powerStatus describes the current mode for set_power_mode and get_diagnostics.
+func powerStatus() map[string]interface{} {
+	status := map[string]interface{}{
+		"mode":                currentPowerMode(),
+		"background_indexing": !lowPower.Load(),
+		"source_concurrency":  sourceConcurrency(),
+	}
+	if lowPower.Load() {
+		status["max_search_results"] = lowPowerSearchResults
+		status["max_in_session_matches"] = lowPowerInSessionMatches
+	}
+	return status
+}
+
+// Tool 52: set_power_mode
+type setPowerModeArgs struct {
+	Mode string `json:"mode" jsonschema:"normal, or low to keep the server lightweight (for example on battery)"`
+}
+
+func addSetPowerModeTool(server *mcp.Server, indexer *backgroundIndexer) {
+	addTool(server, &mcp.Tool{
+		Name:        "set_power_mode",
+		Description: "Switch the server between normal and low-power mode. Low-power mode pauses background indexing, file watching, and scheduled maintenance (searches index what changed when they run instead), lists one source at a time, returns fewer search snippets, and reads session files with smaller buffers. Returning to normal catches the index up in the background.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setPowerModeArgs) (*mcp.CallToolResult, any, error) {
+		if args.Mode == "" {
+			return nil, nil, fmt.Errorf("mode is required")
+		}
+		if err := setPowerMode(args.Mode, indexer); err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(powerStatus(), "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
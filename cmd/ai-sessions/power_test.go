@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestLowPowerMode(t *testing.T) {
+	t.Cleanup(func() { setPowerMode(powerNormal, nil) })
+	if err := setPowerMode("turbo", nil); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+
+	cache := newTestCache(t)
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+	adapter := newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", FilePath: sessionFile, Timestamp: time.Now()}},
+		map[string][]adapters.Message{"sess-1": {{Role: "user", Content: "battery keyword"}}},
+	)
+	indexer := newBackgroundIndexer(map[string]adapters.SessionAdapter{"stub": adapter}, cache)
+
+	if err := setPowerMode(powerLow, indexer); err != nil {
+		t.Fatalf("setPowerMode returned error: %v", err)
+	}
+	if sourceConcurrency() != lowPowerSourceConcurrency || powerLimit(50, 10) != 10 || powerLimit(3, 10) != 3 {
+		t.Fatalf("unexpected low-power limits: concurrency %d", sourceConcurrency())
+	}
+
+	// Nothing is indexed in the background...
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		indexer.run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+	if adapter.listCalls != 0 {
+		t.Fatalf("expected no background passes in low-power mode, got %d", adapter.listCalls)
+	}
+
+	// ...so searches index what they need themselves
+	if err := indexer.awaitWarm(context.Background()); err != nil {
+		t.Fatalf("awaitWarm returned error: %v", err)
+	}
+	if results, err := cache.Search("battery keyword", "", "", 10); err != nil || len(results) != 1 {
+		t.Fatalf("expected the session to be indexed for the search, got %d results (%v)", len(results), err)
+	}
+
+	// Returning to normal catches up with a pass over every source
+	if err := setPowerMode(powerNormal, indexer); err != nil {
+		t.Fatalf("setPowerMode returned error: %v", err)
+	}
+	if len(indexer.fullPass) != 1 {
+		t.Fatal("expected a full pass to be requested when leaving low-power mode")
+	}
+	if sourceConcurrency() != maxConcurrentSources {
+		t.Fatalf("expected normal concurrency, got %d", sourceConcurrency())
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// addTypedTool registers a tool whose handler returns a typed result. The
+// tool declares an output schema derived from Out, and each result is sent as
+// structured content, so clients can read it without parsing text, and as
+// indented JSON text for clients that only read text.
+func addTypedTool[In, Out any](server *mcp.Server, tool *mcp.Tool, handler func(ctx context.Context, req *mcp.CallToolRequest, args In) (Out, error)) {
+	schema, err := jsonschema.For[Out](nil)
+	if err != nil {
+		panic(fmt.Sprintf("output schema for %s: %v", tool.Name, err))
+	}
+	allowNullCollections(schema, true)
+	tool.OutputSchema = schema
+
+	addTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		out, err := handler(ctx, req, args)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resultJSON, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, out, nil
+	})
+}
+
+// allowNullCollections lets the arrays and objects nested in schema be null,
+// as nil slices and maps marshal. The result itself, at root, stays an object.
+func allowNullCollections(schema *jsonschema.Schema, root bool) {
+	if schema == nil {
+		return
+	}
+	if !root && (schema.Type == "array" || schema.Type == "object") {
+		schema.Types = []string{"null", schema.Type}
+		schema.Type = ""
+	}
+	for _, property := range schema.Properties {
+		allowNullCollections(property, false)
+	}
+	allowNullCollections(schema.Items, false)
+	allowNullCollections(schema.AdditionalProperties, false)
+}
+
+// listSessionsResult is the result of list_sessions.
+type listSessionsResult struct {
+	Sessions      []adapters.Session                 `json:"sessions"`
+	Count         int                                `json:"count"`
+	SourceCounts  map[string]int                     `json:"source_counts,omitempty"` // sessions returned per source
+	Notes         map[string][]search.Note           `json:"notes,omitempty"`
+	Outcomes      map[string]adapters.SessionOutcome `json:"outcomes,omitempty"`
+	OutcomeCaveat string                             `json:"outcome_caveat,omitempty"`
+	gitStateResult
+	WithheldProjects []string `json:"withheld_projects,omitempty"`
+}
+
+// searchSessionsResult is the result of search_sessions.
+type searchSessionsResult struct {
+	Query            string         `json:"query"`
+	Matches          []sessionHit   `json:"matches"`
+	Count            int            `json:"count"`
+	TotalMatches     int            `json:"total_matches"`           // matches across every page
+	Offset           int            `json:"offset,omitempty"`        // matches skipped before this page
+	NextCursor       string         `json:"next_cursor,omitempty"`   // pass as cursor for the next page; absent on the last page
+	SourceCounts     map[string]int `json:"source_counts,omitempty"` // matches returned per source
+	OutcomeCaveat    string         `json:"outcome_caveat,omitempty"`
+	WithheldProjects []string       `json:"withheld_projects,omitempty"`
+	PeerMatches      []peerMatch    `json:"peer_matches,omitempty"`
+	PeerErrors       []peerError    `json:"peer_errors,omitempty"`
+}
+
+// sessionHit is a session matching a search_sessions query.
+type sessionHit struct {
+	Session     adapters.Session         `json:"session"`
+	Score       float64                  `json:"score"`
+	Snippet     string                   `json:"snippet"`
+	ContentHash string                   `json:"content_hash,omitempty"`
+	Notes       []search.Note            `json:"notes,omitempty"`
+	Outcome     *adapters.SessionOutcome `json:"outcome,omitempty"`
+	// MessageIndex and Page locate the best-matching message, for get_session.
+	// They are left out when no single message matched, such as when the terms
+	// were only in the summary.
+	MessageIndex *int `json:"message_index,omitempty"`
+	Page         *int `json:"page,omitempty"`
+	// Fields lists the query terms found in the summary, first message,
+	// and content. Summary and first message hits rank higher.
+	Fields []search.FieldMatch `json:"fields,omitempty"`
+	// KeywordScore and SemanticScore are the scores the hybrid ranker fused
+	// into Score, so clients can see why a session matched.
+	KeywordScore  *float64 `json:"keyword_score,omitempty"`
+	SemanticScore *float64 `json:"semantic_score,omitempty"`
+}
+
+// getSessionResult is the result of get_session. Messages is left out when
+// they were streamed as progress notifications instead.
+type getSessionResult struct {
+	SessionID    string              `json:"session_id"`
+	Source       string              `json:"source"`
+	Page         int                 `json:"page"`
+	ResolvedPage int                 `json:"resolved_page"`
+	PageSize     int                 `json:"page_size"`
+	FromEnd      bool                `json:"from_end"`
+	HasMore      bool                `json:"has_more"`
+	Count        int                 `json:"count"`
+	Streamed     bool                `json:"streamed,omitempty"`
+	Messages     *[]adapters.Message `json:"messages,omitempty"`
+	// TotalMessages and TotalPages are set for sources that count messages,
+	// and count only matching messages when filtered
+	TotalMessages *int `json:"total_messages,omitempty"`
+	TotalPages    *int `json:"total_pages,omitempty"`
+	// FilteredOut is how many messages the roles and include_tool_output filters left out
+	FilteredOut int           `json:"filtered_out,omitempty"`
+	Notes       []search.Note `json:"notes,omitempty"`
+}
+
+// searchInSessionResult is the result of search_in_session.
+type searchInSessionResult struct {
+	SessionID     string       `json:"session_id"`
+	Source        string       `json:"source"`
+	Query         string       `json:"query"`
+	PageSize      int          `json:"page_size"`
+	TotalMessages int          `json:"total_messages"`
+	TotalMatches  int          `json:"total_matches"`
+	Matches       []pagedMatch `json:"matches"`
+	Count         int          `json:"count"`
+}
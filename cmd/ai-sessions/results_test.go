@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// newTestClient connects a client to server over an in-memory transport.
+func newTestClient(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	t.Cleanup(func() { clientSession.Close() })
+	return clientSession
+}
+
+// callTypedTool calls a tool and decodes its structured content into out.
+func callTypedTool(t *testing.T, clientSession *mcp.ClientSession, name string, args map[string]interface{}, out interface{}) {
+	t.Helper()
+	result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s): %v", name, err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error from %s: %v", name, result.Content[0].(*mcp.TextContent).Text)
+	}
+	raw, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("marshal structured content: %v", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatalf("unmarshal %s structured content: %v", name, err)
+	}
+}
+
+func TestToolsReturnStructuredContent(t *testing.T) {
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", FirstMessage: "fix the parser", Timestamp: time.Now()}},
+		map[string][]adapters.Message{"sess-1": {
+			{Role: "user", Content: "fix the parser"},
+			{Role: "assistant", Content: "the parser is fixed"},
+		}},
+	)}
+	cache := newTestCache(t)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListSessionsTool(server, adaptersMap, cache, nil)
+	addGetSessionTool(server, adaptersMap, cache, nil)
+	addSearchInSessionTool(server, adaptersMap, nil)
+
+	ctx := context.Background()
+	clientSession := newTestClient(t, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.OutputSchema == nil {
+			t.Errorf("expected %s to declare an output schema", tool.Name)
+		}
+	}
+
+	call := func(name string, args map[string]interface{}, out interface{}) {
+		t.Helper()
+		callTypedTool(t, clientSession, name, args, out)
+	}
+
+	var listed listSessionsResult
+	call("list_sessions", map[string]interface{}{"source": "stub"}, &listed)
+	if listed.Count != 1 || listed.Sessions[0].ID != "sess-1" {
+		t.Fatalf("unexpected list_sessions result: %+v", listed)
+	}
+
+	var session getSessionResult
+	call("get_session", map[string]interface{}{"session_id": "sess-1", "source": "stub"}, &session)
+	if session.Messages == nil || len(*session.Messages) != 2 || (*session.Messages)[1].Content != "the parser is fixed" {
+		t.Fatalf("unexpected get_session result: %+v", session)
+	}
+
+	var found searchInSessionResult
+	call("search_in_session", map[string]interface{}{"session_id": "sess-1", "source": "stub", "query": "fixed"}, &found)
+	if found.TotalMatches != 1 || found.Matches[0].Index != 1 {
+		t.Fatalf("unexpected search_in_session result: %+v", found)
+	}
+}
+
+func TestGetSessionFilters(t *testing.T) {
+	toolResult := map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"}
+	var messages []adapters.Message
+	for i := 0; i < 5; i++ {
+		messages = append(messages,
+			adapters.Message{Role: "user", Content: fmt.Sprintf("question %d", i)},
+			adapters.Message{Role: "assistant", Content: fmt.Sprintf("answer %d", i)},
+			adapters.Message{Role: "user", Metadata: map[string]interface{}{"raw_content": []interface{}{toolResult}}},
+		)
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, map[string][]adapters.Message{"sess-1": messages})}
+	cache := newTestCache(t)
+	no := false
+
+	// read returns the contents of the page read with args
+	read := func(args getSessionArgs) ([]string, getSessionResult) {
+		t.Helper()
+		args.SessionID, args.Source = "sess-1", sourceFilter{"stub"}
+		result, err := readSessionPage(context.Background(), nil, adaptersMap, cache, nil, args, nil)
+		if err != nil {
+			t.Fatalf("readSessionPage(%+v) returned error: %v", args, err)
+		}
+		var contents []string
+		for _, msg := range *result.Messages {
+			contents = append(contents, msg.Content)
+		}
+		return contents, result
+	}
+
+	// Tool output is dropped before the page is cut, so pages stay full
+	contents, result := read(getSessionArgs{PageSize: 4, IncludeToolOutput: &no})
+	if want := []string{"question 0", "answer 0", "question 1", "answer 1"}; !reflect.DeepEqual(contents, want) {
+		t.Fatalf("expected %v, got %v", want, contents)
+	}
+	if *result.TotalMessages != 10 || *result.TotalPages != 3 || result.FilteredOut != 5 || !result.HasMore {
+		t.Fatalf("unexpected pagination for a filtered page: %+v", result)
+	}
+
+	// Pages counted from the end are cut from the filtered messages too
+	contents, _ = read(getSessionArgs{PageSize: 2, Page: 1, FromEnd: true, Roles: []string{"assistant"}})
+	if want := []string{"answer 2", "answer 3"}; !reflect.DeepEqual(contents, want) {
+		t.Fatalf("expected the second-to-last page of assistant messages %v, got %v", want, contents)
+	}
+
+	if _, err := readSessionPage(context.Background(), nil, adaptersMap, cache, nil, getSessionArgs{SessionID: "sess-1", Source: sourceFilter{"stub"}, Roles: []string{"robot"}}, nil); err == nil {
+		t.Fatal("expected an unknown role to be rejected")
+	}
+}
+
+func TestListSessionsSubstantiveFilters(t *testing.T) {
+	now := time.Now()
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter([]adapters.Session{
+		{ID: "empty", Source: "stub", Timestamp: now},
+		{ID: "one-off", Source: "stub", FirstMessage: "hi", UserMessageCount: 1, Timestamp: now.Add(-time.Minute)},
+		{ID: "real-work", Source: "stub", FirstMessage: "refactor the parser", UserMessageCount: 12, Timestamp: now.Add(-time.Hour)},
+	}, nil)}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListSessionsTool(server, adaptersMap, newTestCache(t), nil)
+	clientSession := newTestClient(t, server)
+
+	// ids lists the sessions list_sessions returns for args
+	ids := func(args map[string]interface{}) []string {
+		t.Helper()
+		args["source"] = "stub"
+		var listed listSessionsResult
+		callTypedTool(t, clientSession, "list_sessions", args, &listed)
+		var ids []string
+		for _, session := range listed.Sessions {
+			ids = append(ids, session.ID)
+		}
+		return ids
+	}
+
+	if got := ids(map[string]interface{}{"exclude_empty": true}); !reflect.DeepEqual(got, []string{"one-off", "real-work"}) {
+		t.Fatalf("expected exclude_empty to drop the empty session, got %v", got)
+	}
+	// Filters apply before the limit, so the limit counts matching sessions
+	if got := ids(map[string]interface{}{"min_user_messages": 2, "limit": 1}); !reflect.DeepEqual(got, []string{"real-work"}) {
+		t.Fatalf("expected min_user_messages to keep only real work, got %v", got)
+	}
+}
+
+func TestSourceFilterArrays(t *testing.T) {
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	now := time.Now()
+	adaptersMap := make(map[string]adapters.SessionAdapter)
+	cache := newTestCache(t)
+	for i, source := range []string{"claude", "codex", "gemini"} {
+		session := adapters.Session{ID: source + "-1", Source: source, FirstMessage: "deploy the api", Timestamp: now.Add(-time.Duration(i) * time.Minute), FilePath: sessionFile}
+		messages := []adapters.Message{{Role: "user", Content: "deploy the api"}}
+		adaptersMap[source] = newStubAdapter([]adapters.Session{session}, map[string][]adapters.Message{session.ID: messages})
+		if err := cache.IndexSession(session, "deploy the api"); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+		if err := cache.IndexMessages(session.ID, messages); err != nil {
+			t.Fatalf("IndexMessages: %v", err)
+		}
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addListSessionsTool(server, adaptersMap, cache, nil)
+	addSearchSessionsTool(server, adaptersMap, cache, nil, nil, newBackgroundIndexer(adaptersMap, cache))
+	addGetSessionTool(server, adaptersMap, cache, nil)
+	clientSession := newTestClient(t, server)
+
+	var listed listSessionsResult
+	callTypedTool(t, clientSession, "list_sessions", map[string]interface{}{"source": []string{"claude", "codex"}}, &listed)
+	if want := map[string]int{"claude": 1, "codex": 1}; listed.Count != 2 || !reflect.DeepEqual(listed.SourceCounts, want) {
+		t.Fatalf("expected one session from each of two sources, got %+v", listed)
+	}
+	callTypedTool(t, clientSession, "list_sessions", map[string]interface{}{"source": "gemini"}, &listed)
+	if listed.Count != 1 || listed.Sessions[0].Source != "gemini" {
+		t.Fatalf("expected a single source as a string to still work, got %+v", listed)
+	}
+
+	var found searchSessionsResult
+	callTypedTool(t, clientSession, "search_sessions", map[string]interface{}{"query": "deploy", "source": []string{"gemini", "codex"}, "limit": 5}, &found)
+	if want := map[string]int{"codex": 1, "gemini": 1}; found.Count != 2 || !reflect.DeepEqual(found.SourceCounts, want) {
+		t.Fatalf("expected matches from the two sources, got %+v", found)
+	}
+	callTypedTool(t, clientSession, "search_sessions", map[string]interface{}{"query": "deploy", "source": []string{"claude", "gemini"}, "limit": 1}, &found)
+	if found.Count != 1 || (found.Matches[0].Session.Source != "claude" && found.Matches[0].Session.Source != "gemini") {
+		t.Fatalf("expected the limit to apply after filtering by source, got %+v", found)
+	}
+
+	// get_session finds the session in whichever of the sources has it
+	var session getSessionResult
+	callTypedTool(t, clientSession, "get_session", map[string]interface{}{"session_id": "codex-1", "source": []string{"claude", "codex"}}, &session)
+	if session.Source != "codex" || session.Count != 1 {
+		t.Fatalf("expected the session to be found in codex, got %+v", session)
+	}
+
+	result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{Name: "list_sessions", Arguments: map[string]interface{}{"source": []string{"claude", "missing"}}})
+	if err != nil || !result.IsError {
+		t.Fatalf("expected an unknown source in the array to fail, got %+v (%v)", result, err)
+	}
+}
+
+func TestSearchSessionsPagination(t *testing.T) {
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	now := time.Now()
+	cache := newTestCache(t)
+	var sessions []adapters.Session
+	for i := 0; i < 5; i++ {
+		session := adapters.Session{ID: fmt.Sprintf("s%d", i), Source: "claude", Timestamp: now.Add(-time.Duration(i) * time.Minute), FilePath: sessionFile}
+		sessions = append(sessions, session)
+		content := "deploy the api " + strings.Repeat("deploy ", i)
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession: %v", err)
+		}
+		if err := cache.IndexMessages(session.ID, []adapters.Message{{Role: "user", Content: content}}); err != nil {
+			t.Fatalf("IndexMessages: %v", err)
+		}
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"claude": newStubAdapter(sessions, nil)}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addSearchSessionsTool(server, adaptersMap, cache, nil, nil, newBackgroundIndexer(adaptersMap, cache))
+	clientSession := newTestClient(t, server)
+
+	// Following cursors visits every match once, in rank order: shorter
+	// sessions rank higher
+	args := map[string]interface{}{"query": "deploy", "limit": 2}
+	var seen []string
+	for page := 0; ; page++ {
+		var found searchSessionsResult
+		callTypedTool(t, clientSession, "search_sessions", args, &found)
+		if found.TotalMatches != 5 {
+			t.Fatalf("expected 5 total matches on page %d, got %d", page, found.TotalMatches)
+		}
+		for _, hit := range found.Matches {
+			seen = append(seen, hit.Session.ID)
+		}
+		if found.NextCursor == "" {
+			break
+		}
+		args = map[string]interface{}{"query": "deploy", "limit": 2, "cursor": found.NextCursor}
+	}
+	if want := []string{"s0", "s1", "s2", "s3", "s4"}; !reflect.DeepEqual(seen, want) {
+		t.Fatalf("expected pages to cover %v, got %v", want, seen)
+	}
+
+	var found searchSessionsResult
+	callTypedTool(t, clientSession, "search_sessions", map[string]interface{}{"query": "deploy", "limit": 2, "offset": 4}, &found)
+	if found.Count != 1 || found.Offset != 4 || found.NextCursor != "" || found.Matches[0].Session.ID != "s4" {
+		t.Fatalf("expected the last match alone at offset 4, got %+v", found)
+	}
+	// An offset and limit whose sum overflows are past the end rather than wrapping
+	callTypedTool(t, clientSession, "search_sessions", map[string]interface{}{"query": "deploy", "offset": 1 << 62, "limit": 1 << 62}, &found)
+	if found.Count != 0 || found.TotalMatches != 5 || found.NextCursor != "" {
+		t.Fatalf("expected no matches past the end, got %+v", found)
+	}
+
+	cursor := searchCursor(searchSessionsArgs{Query: "deploy"}, 2)
+	for _, bad := range []map[string]interface{}{
+		{"query": "api", "cursor": cursor},
+		{"query": "deploy", "cursor": cursor, "offset": 2},
+		{"query": "deploy", "cursor": "not a cursor"},
+	} {
+		result, err := clientSession.CallTool(context.Background(), &mcp.CallToolParams{Name: "search_sessions", Arguments: bad})
+		if err != nil || !result.IsError {
+			t.Errorf("expected %v to fail, got %+v (%v)", bad, result, err)
+		}
+	}
+}
+
+func TestSearchFiltersCoverSearchArgs(t *testing.T) {
+	described := make(map[string]bool)
+	for _, filter := range searchFilters(nil, nil) {
+		described[filter.Name] = true
+	}
+
+	argsType := reflect.TypeOf(searchSessionsArgs{})
+	for i := 0; i < argsType.NumField(); i++ {
+		name, _, _ := strings.Cut(argsType.Field(i).Tag.Get("json"), ",")
+		if name == "query" {
+			continue
+		}
+		if !described[name] {
+			t.Errorf("get_search_syntax doesn't describe the %s argument", name)
+		}
+		delete(described, name)
+	}
+	for name := range described {
+		t.Errorf("get_search_syntax describes %s, which search_sessions doesn't take", name)
+	}
+}
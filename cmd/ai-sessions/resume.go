@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// resumeExchange is a user prompt and the assistant's last reply to it.
+type resumeExchange struct {
+	index     int
+	prompt    string
+	reply     string
+	timestamp time.Time
+}
+
+// isPrompt reports whether msg is something the user typed, as opposed to a
+// user-role message that only carries tool results.
+func isPrompt(msg adapters.Message) bool {
+	return msg.Role == "user" && strings.TrimSpace(msg.Content) != "" && len(adapters.ExtractToolResults(msg)) == 0
+}
+
+// lastExchanges splits a session into exchanges at each user prompt and
+// returns the last n of them.
+func lastExchanges(messages []adapters.Message, n int) []resumeExchange {
+	var exchanges []resumeExchange
+	for i, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		switch {
+		case isPrompt(msg):
+			exchanges = append(exchanges, resumeExchange{index: i, prompt: content, timestamp: msg.Timestamp})
+		case msg.Role == "assistant" && content != "" && len(exchanges) > 0:
+			exchanges[len(exchanges)-1].reply = content
+		}
+	}
+	if len(exchanges) > n {
+		exchanges = exchanges[len(exchanges)-n:]
+	}
+	return exchanges
+}
+
+// renderResumeContext formats a session as a Markdown "resume packet" meant to
+// be pasted into a new agent session: where the work stands, the last few
+// exchanges, the tasks still open, and the files involved. Each message is
+// truncated to maxLength bytes.
+func renderResumeContext(session adapters.Session, messages []adapters.Message, exchanges, maxLength int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Resuming: %s\n\n", exportTitle(session))
+	fmt.Fprintf(&b, "This continues a previous %s session (`%s`)", getAgentDisplayName(session.Source), session.ID)
+	if session.ProjectPath != "" {
+		fmt.Fprintf(&b, " in `%s`", session.ProjectPath)
+	}
+	b.WriteString(". Pick up where it left off.\n")
+
+	b.WriteString("\n## Final state\n\n")
+	stats := adapters.ComputeSessionStats(messages)
+	fmt.Fprintf(&b, "- %d messages", stats.MessageCount)
+	if stats.EndTime != nil {
+		fmt.Fprintf(&b, ", last active %s", stats.EndTime.UTC().Format(time.RFC3339))
+	}
+	b.WriteString("\n")
+	invocations := adapters.ExtractToolInvocations(messages)
+	if len(invocations) > 0 {
+		last := invocations[len(invocations)-1]
+		outcome := "no result recorded"
+		if last.Success != nil && *last.Success {
+			outcome = "succeeded"
+		} else if last.Success != nil {
+			outcome = "failed"
+		}
+		fmt.Fprintf(&b, "- Last tool call: `%s` %s (%s)\n", last.Name, toolCallPreview(last.ToolCall), outcome)
+	}
+	var lastReply string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && strings.TrimSpace(messages[i].Content) != "" {
+			lastReply = strings.TrimSpace(messages[i].Content)
+			break
+		}
+	}
+	if lastReply != "" {
+		b.WriteString("- Last assistant message:\n\n")
+		b.WriteString(indent(truncateString(lastReply, maxLength), "  > ") + "\n")
+	}
+
+	recent := lastExchanges(messages, exchanges)
+	if len(recent) > 0 {
+		b.WriteString("\n## Recent exchanges\n")
+		for _, ex := range recent {
+			fmt.Fprintf(&b, "\n### Message %d", ex.index)
+			if !ex.timestamp.IsZero() {
+				fmt.Fprintf(&b, " · %s", ex.timestamp.UTC().Format(time.RFC3339))
+			}
+			b.WriteString("\n\n**User:**\n\n")
+			b.WriteString(truncateString(ex.prompt, maxLength) + "\n")
+			if ex.reply != "" {
+				b.WriteString("\n**Assistant:**\n\n")
+				b.WriteString(truncateString(ex.reply, maxLength) + "\n")
+			}
+		}
+	}
+
+	if todos := adapters.ExtractOpenTodos(messages); len(todos) > 0 {
+		b.WriteString("\n## Open tasks\n\n")
+		for _, todo := range todos {
+			fmt.Fprintf(&b, "- [ ] %s", todo.Text)
+			if todo.Status != "" && todo.Status != "pending" {
+				fmt.Fprintf(&b, " (%s)", strings.ReplaceAll(todo.Status, "_", " "))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	var modified, read []string
+	for _, f := range resolveFilePaths(adapters.ComputeFilesTouched(messages), session.ProjectPath) {
+		if f.Writes+f.Edits > 0 {
+			modified = append(modified, f.Path)
+		} else if f.Reads > 0 {
+			read = append(read, f.Path)
+		}
+	}
+	if len(modified)+len(read) > 0 {
+		b.WriteString("\n## Files\n")
+		for _, group := range []struct {
+			label string
+			paths []string
+		}{{"Modified", modified}, {"Read", read}} {
+			if len(group.paths) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "\n%s:\n", group.label)
+			for _, path := range group.paths {
+				fmt.Fprintf(&b, "- `%s`\n", path)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// Tool 26: generate_resume_context
+type generateResumeContextArgs struct {
+	SessionID        string `json:"session_id" jsonschema:"The session to resume"`
+	Source           string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	Exchanges        int    `json:"exchanges,omitempty" jsonschema:"How many of the last user/assistant exchanges to include (default: 3)"`
+	MaxMessageLength int    `json:"max_message_length,omitempty" jsonschema:"Truncate each included message to this many characters (default: 1500)"`
+}
+
+func addGenerateResumeContextTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "generate_resume_context",
+		Description: "Generate a compact Markdown \"resume packet\" for a session: its final state, the last few user/assistant exchanges, open TODOs, and the files it read and modified. Paste it into a new agent session to continue the work.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args generateResumeContextArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.Exchanges == 0 {
+			args.Exchanges = 3
+		}
+		if args.MaxMessageLength == 0 {
+			args.MaxMessageLength = 1500
+		}
+		if args.Exchanges < 0 || args.MaxMessageLength < 0 {
+			return nil, nil, fmt.Errorf("exchanges and max_message_length must be positive")
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		recordAccess(searchCache, req.Session, search.AccessEntry{
+			SessionID: args.SessionID, Source: args.Source, MessageCount: len(messages),
+		})
+
+		packet := renderResumeContext(lookupSession(adapter, args.SessionID), messages, args.Exchanges, args.MaxMessageLength)
+
+		// The packet is returned as plain Markdown so it can be pasted as-is
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: packet},
+			},
+		}, nil, nil
+	})
+}
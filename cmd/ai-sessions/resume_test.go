@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestRenderResumeContext(t *testing.T) {
+	session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/src/app", FirstMessage: "Add retries"}
+	messages := []adapters.Message{
+		{Role: "user", Content: "Add retries to the client"},
+		{Role: "assistant", Content: "Done with the first pass."},
+		{Role: "user", Content: "Now cover it with tests"},
+		{Role: "assistant", Content: "Editing.", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Edit", "input": map[string]interface{}{"file_path": "client_test.go"}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "ok"},
+			},
+		}},
+		{Role: "assistant", Content: "Tests added.\nTODO: cover the timeout path"},
+	}
+
+	packet := renderResumeContext(session, messages, 1, 1500)
+	for _, want := range []string{
+		"# Resuming: Add retries\n",
+		"in `/src/app`",
+		"- Last tool call: `Edit` client_test.go (succeeded)\n",
+		"### Message 2\n\n**User:**\n\nNow cover it with tests\n\n**Assistant:**\n\nTests added.",
+		"- [ ] cover the timeout path\n",
+		"Modified:\n- `/src/app/client_test.go`\n",
+	} {
+		if !strings.Contains(packet, want) {
+			t.Errorf("expected resume packet to contain %q, got:\n%s", want, packet)
+		}
+	}
+	if strings.Contains(packet, "Add retries to the client") {
+		t.Errorf("expected only the last exchange, got:\n%s", packet)
+	}
+}
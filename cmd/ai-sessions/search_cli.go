@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+	"golang.org/x/term"
+)
+
+// searchPickerItem is one search result in the interactive picker.
+type searchPickerItem struct {
+	Row     string // session row, as in the upload picker
+	Snippet string // where the query matched, shown for the highlighted result
+}
+
+// matchedPage returns the page of the first message matching query in scope,
+// so the show view opens where the search hit. It returns 0 if no single
+// message matches.
+func matchedPage(messages []adapters.Message, query, scope string, pageSize int) int {
+	matches := search.SearchMessagesInScope(messages, query, scope, 0)
+	if len(matches) == 0 {
+		return 0
+	}
+	return matches[0].Index / pageSize
+}
+
+// pickSearchResult lets the user choose a result, filtering as they type.
+func pickSearchResult(query string, results []search.SearchResult) (search.SearchResult, error) {
+	termWidth := getTerminalWidth()
+	items := make([]searchPickerItem, len(results))
+	for i, r := range results {
+		items[i] = searchPickerItem{
+			Row:     formatSessionRow(r.Session, termWidth),
+			Snippet: truncateString(strings.Join(strings.Fields(r.Snippet), " "), max(termWidth-4, 40)),
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d sessions match %q\n", len(results), query)
+	fmt.Println("Type to filter, use the arrow keys to navigate, and press enter to open")
+	fmt.Println()
+	fmt.Println("\033[2m" + formatTableHeader() + "\033[0m") // Dim color for header
+
+	prompt := promptui.Select{
+		Label: " ",
+		Items: items,
+		Templates: &promptui.SelectTemplates{
+			Label:    `{{ "" }}`,
+			Active:   "\033[36m{{ .Row }}\033[0m", // Cyan for active
+			Inactive: "{{ .Row }}",
+			Selected: "\033[32m{{ .Row }}\033[0m", // Green for selected
+			Details:  "\033[2m{{ .Snippet }}\033[0m",
+		},
+		Size:              15,
+		HideHelp:          true,
+		StartInSearchMode: true,
+		Searcher: func(input string, index int) bool {
+			session := results[index].Session
+			haystack := strings.ToLower(strings.Join([]string{
+				session.FirstMessage, session.Summary, session.ProjectPath, getAgentDisplayName(session.Source), results[index].Snippet,
+			}, " "))
+			for _, word := range strings.Fields(strings.ToLower(input)) {
+				if !strings.Contains(haystack, word) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return search.SearchResult{}, fmt.Errorf("selection cancelled: %w", err)
+	}
+	return results[idx], nil
+}
+
+// printSearchResults lists results for scripts and pipes, one per line:
+// source, session ID, score, and snippet, separated by tabs.
+func printSearchResults(results []search.SearchResult) {
+	for _, r := range results {
+		snippet := strings.Join(strings.Fields(r.Snippet), " ")
+		fmt.Printf("%s\t%s\t%.3f\t%s\n", r.Session.Source, r.Session.ID, r.Score, snippet)
+	}
+}
+
+// handleSearchCommand processes: aisessions search <query> [--source <source>] [--project <path>] [--scope <scope>] [--limit <n>] [--include-tool-output] [show options]
+func handleSearchCommand() {
+	var queryParts []string
+	var source, projectPath, scope string
+	var includeToolOutput bool
+	limit := 50
+	flags := defaultShowFlags()
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		used, err := flags.parse(args, i)
+		if err != nil {
+			exitWithError(err)
+		}
+		if used > 0 {
+			i += used - 1
+			continue
+		}
+
+		switch args[i] {
+		case "--source", "--project", "--scope", "--limit":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			switch args[i] {
+			case "--source":
+				source = args[i+1]
+			case "--project":
+				projectPath = args[i+1]
+			case "--scope":
+				scope = args[i+1]
+			default:
+				if limit, err = strconv.Atoi(args[i+1]); err != nil || limit <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid --limit: %s\n", args[i+1])
+					os.Exit(1)
+				}
+			}
+			i++
+		case "--include-tool-output":
+			includeToolOutput = true
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				os.Exit(1)
+			}
+			queryParts = append(queryParts, args[i])
+		}
+	}
+
+	query := strings.Join(queryParts, " ")
+	if query == "" {
+		fmt.Fprintf(os.Stderr, "Usage: aisessions search <query> [--source <source>] [--project <path>] [--scope <scope>] [--limit <n>] [--include-tool-output] [--expand] [--page-size <n>] [--no-pager] [--no-color]\n")
+		os.Exit(1)
+	}
+	if err := search.ValidateScope(scope); err != nil {
+		exitWithError(err)
+	}
+
+	adaptersMap, _ := adapters.NewRegistered()
+	if serverConfig, err := loadServerConfig(); err == nil {
+		addConfiguredAdapters(adaptersMap, serverConfig)
+	}
+
+	cache, err := openConfiguredCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open search index: %v\n", err)
+		os.Exit(1)
+	}
+	defer cache.Close()
+	useFileCaches(adaptersMap, cache)
+
+	if err := indexSessions(adaptersMap, cache, source, projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: indexing error: %v\n", err)
+	}
+
+	if !includeToolOutput {
+		scope = search.WithoutToolOutput(scope)
+	}
+	results, err := cache.SearchInScope(query, scope, source, projectPath, limit, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: search failed: %v\n", err)
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No sessions match %q\n", query)
+		os.Exit(1)
+	}
+
+	// Only offer the picker when someone is at the terminal
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		printSearchResults(results)
+		return
+	}
+
+	selected, err := pickSearchResult(query, results)
+	if err != nil {
+		return
+	}
+
+	adapter, ok := adaptersMap[selected.Session.Source]
+	if !ok {
+		exitWithError(adapters.SourceUnavailableError(selected.Session.Source))
+	}
+	messages, err := adapter.GetSession(selected.Session.ID, 0, 100000) // Get all messages
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to get session: %w", err))
+	}
+
+	page := matchedPage(messages, query, scope, flags.pageSize)
+	if err := showTranscript(selected.Session, messages, page, flags); err != nil {
+		exitWithError(err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+const serverConfigFile = "server.json"
+
+// ServerConfig holds optional settings for the MCP server, read from ~/.aisessions/server.json.
+// A missing file is equivalent to an empty config.
+type ServerConfig struct {
+	// GenericJSONL declares additional JSONL-backed sources that need no Go code
+	GenericJSONL []adapters.GenericJSONLConfig `json:"generic_jsonl,omitempty"`
+
+	// GenericSQLite declares additional SQLite-backed sources driven by SELECT queries
+	GenericSQLite []adapters.GenericSQLiteConfig `json:"generic_sqlite,omitempty"`
+
+	// AggregatesOnly withholds tools that return full session content; list and
+	// search still return metadata and snippets
+	AggregatesOnly bool `json:"aggregates_only,omitempty"`
+
+	// RequireProjectConsent withholds sessions from a project until the user approves it
+	RequireProjectConsent bool `json:"require_project_consent,omitempty"`
+
+	// AllowedProjects are project paths approved up front when consent is required
+	AllowedProjects []string `json:"allowed_projects,omitempty"`
+
+	// DefaultRanker names the search ranker used when a query doesn't pick one
+	DefaultRanker string `json:"default_ranker,omitempty"`
+
+	// IndexPollInterval, if set, reindexes changed sessions in the background at
+	// this interval (a Go duration such as "1m") instead of only when searching
+	IndexPollInterval string `json:"index_poll_interval,omitempty"`
+
+	// WatchSessions indexes new and modified session files as they are
+	// written, using filesystem notifications on each source's storage
+	WatchSessions bool `json:"watch_sessions,omitempty"`
+
+	// LowPower starts the server in low-power mode, which set_power_mode
+	// switches at runtime
+	LowPower bool `json:"low_power,omitempty"`
+
+	// Limits bound how much of any one session file is read, so a huge
+	// session can't exhaust memory or stall a tool call
+	Limits adapters.Limits `json:"limits,omitempty"`
+
+	// MaintenanceSchedule, if set, runs index maintenance (incremental index,
+	// missing-file review, vacuum) daily at a local time such as "03:00", or at
+	// an interval such as "12h"
+	MaintenanceSchedule string `json:"maintenance_schedule,omitempty"`
+
+	// ClaudeContainers locates Claude Code sessions written inside containers
+	// and maps their container project paths to local ones
+	ClaudeContainers adapters.ClaudeContainerConfig `json:"claude_containers,omitempty"`
+
+	// FederationPeers are other MCP memory servers search_sessions can forward
+	// queries to when asked with include_peers
+	FederationPeers []PeerConfig `json:"federation_peers,omitempty"`
+
+	// ClientLogLevel is the least severe level of server log lines, such as
+	// source warnings, forwarded to clients as MCP logging notifications:
+	// debug, info (the default), notice, warning, error, or "off"
+	ClientLogLevel string `json:"client_log_level,omitempty"`
+
+	// Cache selects where the search index is stored
+	Cache CacheConfig `json:"cache,omitempty"`
+}
+
+// CacheConfig selects the search index's storage backend, how it tokenizes
+// text, and how large it may grow.
+type CacheConfig struct {
+	// Backend is "sqlite" (the default), "memory", or a backend registered by
+	// an extension built into the binary
+	Backend string `json:"backend,omitempty"`
+
+	// DSN says where the backend keeps the index. For sqlite it is the
+	// database file, ~/.cache/ai-sessions/search.db by default
+	DSN string `json:"dsn,omitempty"`
+
+	// Analyzer turns on stemming and stopwords. Changing it rebuilds the
+	// index the next time sessions are indexed
+	Analyzer search.Analyzer `json:"analyzer,omitempty"`
+
+	// Retention caps the index's size, session count, and age. Maintenance
+	// and "cache vacuum" evict the least recently used sessions beyond them
+	Retention search.Retention `json:"retention,omitempty"`
+}
+
+// backend returns the configured backend name, or the default.
+func (c CacheConfig) backend() string {
+	if c.Backend == "" {
+		return search.BackendSQLite
+	}
+	return c.Backend
+}
+
+// dsn returns the configured location, defaulting the sqlite database path.
+func (c CacheConfig) dsn() (string, error) {
+	if c.DSN == "" && c.backend() == search.BackendSQLite {
+		return defaultCachePath()
+	}
+	return c.DSN, nil
+}
+
+// sqlitePath returns the database file of a sqlite index, for commands that
+// work on the file itself.
+func (c CacheConfig) sqlitePath() (string, error) {
+	if c.backend() != search.BackendSQLite {
+		return "", fmt.Errorf("the search index uses the %s backend, not a sqlite file", c.backend())
+	}
+	return c.dsn()
+}
+
+// open opens the configured index with the configured analyzer.
+func (c CacheConfig) open() (search.Store, error) {
+	dsn, err := c.dsn()
+	if err != nil {
+		return nil, err
+	}
+	store, err := search.Open(c.backend(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.SetAnalyzer(c.Analyzer); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// getServerConfigPath returns the path to the server config file
+func getServerConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, configDir, serverConfigFile), nil
+}
+
+// loadServerConfig loads the server configuration from disk.
+// It returns an empty config if the file does not exist.
+func loadServerConfig() (*ServerConfig, error) {
+	configPath, err := getServerConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &ServerConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server config: %w", err)
+	}
+
+	var config ServerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid server config file %s: %w", configPath, err)
+	}
+
+	return &config, nil
+}
+
+// addConfiguredAdapters registers config-driven adapters, skipping any whose name
+// collides with an adapter that is already registered, and points the Claude
+// adapter at sessions written inside containers.
+func addConfiguredAdapters(adaptersMap map[string]adapters.SessionAdapter, config *ServerConfig) []error {
+	var errs []error
+	for _, cfg := range config.GenericJSONL {
+		if _, exists := adaptersMap[cfg.Name]; exists {
+			errs = append(errs, fmt.Errorf("generic JSONL adapter %q conflicts with an existing source", cfg.Name))
+			continue
+		}
+		adapter, err := adapters.NewGenericJSONLAdapter(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		adaptersMap[cfg.Name] = adapter
+	}
+	for _, cfg := range config.GenericSQLite {
+		if _, exists := adaptersMap[cfg.Name]; exists {
+			errs = append(errs, fmt.Errorf("generic SQLite adapter %q conflicts with an existing source", cfg.Name))
+			continue
+		}
+		adapter, err := adapters.NewGenericSQLiteAdapter(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		adaptersMap[cfg.Name] = adapter
+	}
+	if claude, ok := adaptersMap["claude"].(*adapters.ClaudeAdapter); ok {
+		containers := config.ClaudeContainers
+		// A devcontainer in the current directory is picked up without configuration
+		if cwd, err := os.Getwd(); err == nil {
+			if _, err := adapters.DetectDevcontainer(cwd); err == nil {
+				containers.Devcontainers = append(containers.Devcontainers, cwd)
+			}
+		}
+		errs = append(errs, claude.ConfigureContainers(containers)...)
+	}
+	return errs
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"golang.org/x/term"
+)
+
+// showOptions controls how a transcript is rendered in the terminal.
+type showOptions struct {
+	expand bool // show tool call inputs and tool results in full instead of one line each
+	color  bool
+	width  int
+}
+
+// ANSI styles for transcript rendering
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiDim     = "\033[2m"
+	ansiCyan    = "\033[36m"
+	ansiMagenta = "\033[35m"
+	ansiYellow  = "\033[33m"
+	ansiRed     = "\033[31m"
+)
+
+// foldedPreviewLength is how much of a tool call's input is shown when folded.
+const foldedPreviewLength = 100
+
+// transcriptRenderer renders a session for reading in the terminal: colored role
+// headers, message text rendered as Markdown, and tool calls folded to one line.
+type transcriptRenderer struct {
+	opts     showOptions
+	markdown *glamour.TermRenderer
+}
+
+func newTranscriptRenderer(opts showOptions) *transcriptRenderer {
+	style := styles.NoTTYStyle
+	if opts.color {
+		style = styles.DarkStyle
+		if bg := os.Getenv("COLORFGBG"); strings.HasSuffix(bg, ";15") || strings.HasSuffix(bg, ";7") {
+			style = styles.LightStyle
+		}
+	}
+	r := &transcriptRenderer{opts: opts}
+	// A failed renderer just means message text is printed as-is
+	r.markdown, _ = glamour.NewTermRenderer(glamour.WithStandardStyle(style), glamour.WithWordWrap(opts.width))
+	return r
+}
+
+// style wraps text in an ANSI style when color is enabled.
+func (r *transcriptRenderer) style(code, text string) string {
+	if !r.opts.color {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// render returns the transcript of messages, numbering them from firstIndex.
+func (r *transcriptRenderer) render(session adapters.Session, messages []adapters.Message, firstIndex, total int) string {
+	var b strings.Builder
+
+	b.WriteString(r.style(ansiBold, exportTitle(session)) + "\n")
+	details := []string{getAgentDisplayName(session.Source), session.ID}
+	if session.ProjectPath != "" {
+		details = append(details, session.ProjectPath)
+	}
+	if !session.Timestamp.IsZero() {
+		details = append(details, session.Timestamp.Local().Format("2006-01-02 15:04"))
+	}
+	b.WriteString(r.style(ansiDim, strings.Join(details, " · ")) + "\n")
+	if len(messages) < total {
+		fmt.Fprintf(&b, "%s\n", r.style(ansiDim, fmt.Sprintf("messages %d-%d of %d", firstIndex, firstIndex+len(messages)-1, total)))
+	}
+
+	for i, msg := range messages {
+		r.renderMessage(&b, firstIndex+i, msg)
+	}
+	return b.String()
+}
+
+// renderMessage writes one message, skipping messages with nothing to show.
+func (r *transcriptRenderer) renderMessage(b *strings.Builder, index int, msg adapters.Message) {
+	content := strings.TrimSpace(msg.Content)
+	calls := adapters.ExtractToolCalls(msg)
+	results := adapters.ExtractToolResults(msg)
+	images := adapters.ExtractImages(msg)
+	if content == "" && len(calls) == 0 && len(results) == 0 && len(images) == 0 {
+		return
+	}
+
+	header := r.style(roleColor(msg.Role)+ansiBold, roleHeading(msg.Role))
+	header += r.style(ansiDim, fmt.Sprintf(" #%d", index))
+	if !msg.Timestamp.IsZero() {
+		header += r.style(ansiDim, " · "+msg.Timestamp.Local().Format("15:04:05"))
+	}
+	b.WriteString("\n" + header + "\n")
+
+	if content != "" {
+		b.WriteString(r.renderMarkdown(content) + "\n")
+	}
+	for _, img := range images {
+		b.WriteString(r.style(ansiDim, fmt.Sprintf("  [%s image, %.1f KB]", img.MediaType, float64(len(img.Data))/1024)) + "\n")
+	}
+
+	for _, call := range calls {
+		if !r.opts.expand {
+			b.WriteString(r.style(ansiYellow, "  ▸ "+call.Name) + " " + r.style(ansiDim, toolCallPreview(call)) + "\n")
+			continue
+		}
+		b.WriteString(r.style(ansiYellow, "  ▾ "+call.Name) + "\n")
+		if len(call.Input) > 0 {
+			if input, err := json.MarshalIndent(call.Input, "", "  "); err == nil {
+				b.WriteString(indent(string(input), "    ") + "\n")
+			}
+		}
+	}
+
+	for _, result := range results {
+		label, color := "result", ansiDim
+		if result.IsError {
+			label, color = "error", ansiRed
+		}
+		lines := strings.Count(strings.TrimRight(result.Content, "\n"), "\n") + 1
+		if !r.opts.expand {
+			b.WriteString(r.style(color, fmt.Sprintf("  ▸ %s (%d lines)", label, lines)) + "\n")
+			continue
+		}
+		b.WriteString(r.style(color, "  ▾ "+label) + "\n")
+		b.WriteString(indent(strings.TrimRight(result.Content, "\n"), "    ") + "\n")
+	}
+}
+
+// renderMarkdown renders message text, falling back to the raw text if rendering fails.
+func (r *transcriptRenderer) renderMarkdown(text string) string {
+	if r.markdown == nil {
+		return indent(text, "  ")
+	}
+	out, err := r.markdown.Render(text)
+	if err != nil {
+		return indent(text, "  ")
+	}
+	return strings.Trim(out, "\n")
+}
+
+// roleColor returns the ANSI color for a message role.
+func roleColor(role string) string {
+	switch role {
+	case "user":
+		return ansiCyan
+	case "assistant":
+		return ansiMagenta
+	}
+	return ansiYellow
+}
+
+// toolCallPreview summarizes a tool call's input on one line, preferring the
+// argument that usually identifies what the call did.
+func toolCallPreview(call adapters.ToolCall) string {
+	for _, key := range []string{"command", "cmd", "file_path", "filePath", "path", "pattern", "query", "url", "description"} {
+		if value, ok := call.Input[key]; ok {
+			if s, ok := value.(string); ok && s != "" {
+				return truncateString(strings.Join(strings.Fields(s), " "), foldedPreviewLength)
+			}
+		}
+	}
+	if len(call.Input) == 0 {
+		return ""
+	}
+	input, err := json.Marshal(call.Input)
+	if err != nil {
+		return ""
+	}
+	return truncateString(string(input), foldedPreviewLength)
+}
+
+// indent prefixes every line of text.
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+// pageOutput shows text through $PAGER (less by default), or prints it directly
+// if no pager can be started.
+func pageOutput(text string) {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-R"}
+	}
+
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if _, set := os.LookupEnv("LESS"); !set && pager[0] == "less" {
+		// Quit immediately if it fits on one screen, and keep colors
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Print(text)
+		return
+	}
+	_ = cmd.Wait()
+}
+
+// showFlags are the display flags shared by the show and search commands.
+type showFlags struct {
+	expand   bool
+	noPager  bool
+	noColor  bool
+	pageSize int
+}
+
+func defaultShowFlags() showFlags {
+	return showFlags{pageSize: 20, noColor: os.Getenv("NO_COLOR") != ""}
+}
+
+// parse consumes the display flag at args[i], if it is one, and returns how
+// many arguments it used.
+func (f *showFlags) parse(args []string, i int) (int, error) {
+	switch args[i] {
+	case "--expand":
+		f.expand = true
+	case "--no-pager":
+		f.noPager = true
+	case "--no-color":
+		f.noColor = true
+	case "--page-size":
+		if i+1 >= len(args) {
+			return 0, fmt.Errorf("--page-size requires a value")
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid --page-size: %s", args[i+1])
+		}
+		f.pageSize = n
+		return 2, nil
+	default:
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// showTranscript renders a session for the terminal, starting at page (or the
+// whole session if page is negative), through the pager when appropriate.
+func showTranscript(session adapters.Session, messages []adapters.Message, page int, flags showFlags) error {
+	total, first := len(messages), 0
+	if page >= 0 {
+		first = page * flags.pageSize
+		if first >= total {
+			return fmt.Errorf("page %d is past the end of the session (%d messages)", page, total)
+		}
+		messages = messages[first:min(first+flags.pageSize, total)]
+	}
+
+	isTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	renderer := newTranscriptRenderer(showOptions{
+		expand: flags.expand,
+		color:  isTerminal && !flags.noColor,
+		width:  min(getTerminalWidth(), 120),
+	})
+	output := renderer.render(session, messages, first, total)
+	if !flags.expand && strings.Contains(output, "▸") {
+		output += "\n" + renderer.style(ansiDim, "Tool calls are folded; use --expand to show inputs and results.") + "\n"
+	}
+
+	if isTerminal && !flags.noPager {
+		pageOutput(output)
+		return nil
+	}
+	fmt.Print(output)
+	return nil
+}
+
+// handleShowCommand processes: aisessions show <session-id> --source <source> [--expand] [--page <n>] [--page-size <n>] [--no-pager] [--no-color]
+func handleShowCommand() {
+	var sessionID, source string
+	flags := defaultShowFlags()
+	page := -1
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		used, err := flags.parse(args, i)
+		if err != nil {
+			exitWithError(err)
+		}
+		if used > 0 {
+			i += used - 1
+			continue
+		}
+
+		switch args[i] {
+		case "--source", "--page":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			if args[i] == "--source" {
+				source = args[i+1]
+			} else if page, err = strconv.Atoi(args[i+1]); err != nil || page < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --page: %s\n", args[i+1])
+				os.Exit(1)
+			}
+			i++
+		default:
+			if sessionID != "" || strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown argument: %s\n", args[i])
+				os.Exit(1)
+			}
+			sessionID = args[i]
+		}
+	}
+
+	if sessionID == "" || source == "" {
+		fmt.Fprintf(os.Stderr, "Usage: aisessions show <session-id> --source <source> [--expand] [--page <n>] [--page-size <n>] [--no-pager] [--no-color]\n")
+		os.Exit(1)
+	}
+
+	adaptersMap, _ := adapters.NewRegistered()
+	if serverConfig, err := loadServerConfig(); err == nil {
+		addConfiguredAdapters(adaptersMap, serverConfig)
+	}
+
+	adapter, ok := adaptersMap[source]
+	if !ok {
+		exitWithError(adapters.SourceUnavailableError(source))
+	}
+
+	messages, err := adapter.GetSession(sessionID, 0, 100000) // Get all messages
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to get session: %w", err))
+	}
+
+	if err := showTranscript(lookupSession(adapter, sessionID), messages, page, flags); err != nil {
+		exitWithError(err)
+	}
+}
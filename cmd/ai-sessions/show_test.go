@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+func TestTranscriptRendererFoldsToolCalls(t *testing.T) {
+	session := adapters.Session{ID: "s1", Source: "claude", FirstMessage: "Fix the build"}
+	messages := []adapters.Message{
+		{Role: "user", Content: "Fix the **build**", Timestamp: time.Now()},
+		{Role: "assistant", Content: "Checking.", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Bash", "input": map[string]interface{}{"command": "go build ./...", "timeout": 60000}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "main.go:3: undefined: foo\nexit status 1", "is_error": true},
+			},
+		}},
+	}
+
+	folded := newTranscriptRenderer(showOptions{width: 80}).render(session, messages, 10, 40)
+	for _, want := range []string{"Fix the build\n", "messages 10-12 of 40", "User #10", "Assistant #11", "  ▸ Bash go build ./...\n", "  ▸ error (2 lines)\n"} {
+		if !strings.Contains(folded, want) {
+			t.Errorf("expected folded transcript to contain %q, got:\n%s", want, folded)
+		}
+	}
+	if strings.Contains(folded, "undefined: foo") || strings.Contains(folded, "\"timeout\"") {
+		t.Errorf("expected tool details to be folded, got:\n%s", folded)
+	}
+	if strings.Contains(folded, "\033[") {
+		t.Errorf("expected no ANSI codes without color, got:\n%q", folded)
+	}
+
+	expanded := newTranscriptRenderer(showOptions{width: 80, expand: true}).render(session, messages, 0, 3)
+	for _, want := range []string{"  ▾ Bash\n", "\"timeout\": 60000", "  ▾ error\n    main.go:3: undefined: foo\n    exit status 1\n"} {
+		if !strings.Contains(expanded, want) {
+			t.Errorf("expected expanded transcript to contain %q, got:\n%s", want, expanded)
+		}
+	}
+
+	colored := newTranscriptRenderer(showOptions{width: 80, color: true}).render(session, messages, 0, 3)
+	if !strings.Contains(colored, ansiCyan+ansiBold+"User"+ansiReset) {
+		t.Errorf("expected a colored user header, got:\n%q", colored)
+	}
+}
+
+func TestMatchedPage(t *testing.T) {
+	messages := make([]adapters.Message, 45)
+	for i := range messages {
+		messages[i] = adapters.Message{Role: "user", Content: "nothing to see"}
+	}
+	messages[27].Content = "the OAuth refresh token expired"
+	messages[41].Content = "oauth again"
+
+	if page := matchedPage(messages, "oauth", search.ScopeAll, 20); page != 1 {
+		t.Errorf("expected the first match's page 1, got %d", page)
+	}
+	if page := matchedPage(messages, "oauth", search.ScopeAll, 10); page != 2 {
+		t.Errorf("expected page 2 with 10 messages per page, got %d", page)
+	}
+	if page := matchedPage(messages, "kubernetes", search.ScopeAll, 20); page != 0 {
+		t.Errorf("expected page 0 without a message match, got %d", page)
+	}
+}
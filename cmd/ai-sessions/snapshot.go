@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// handleCacheCommand processes: aisessions cache export|import <file>, and
+// aisessions cache vacuum
+func handleCacheCommand() {
+	if len(os.Args) == 3 && os.Args[2] == "vacuum" {
+		handleCacheVacuum()
+		return
+	}
+	if len(os.Args) != 4 || (os.Args[2] != "export" && os.Args[2] != "import") {
+		fmt.Fprintf(os.Stderr, "Usage: aisessions cache export|import <file>\n       aisessions cache vacuum\n")
+		os.Exit(1)
+	}
+	action, file := os.Args[2], os.Args[3]
+
+	var cachePath string
+	serverConfig, err := loadServerConfig()
+	if err == nil {
+		cachePath, err = serverConfig.Cache.sqlitePath()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if action == "import" {
+		info, err := search.RestoreSnapshot(file, cachePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored %d indexed sessions into %s\n", info.Sessions, cachePath)
+		fmt.Println("Restart any running MCP servers to pick up the restored index.")
+		return
+	}
+
+	cache, err := search.NewCache(cachePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open search cache: %v\n", err)
+		os.Exit(1)
+	}
+	defer cache.Close()
+
+	if err := cache.Snapshot(file); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Saved search index snapshot to %s\n", file)
+}
+
+// handleCacheVacuum evicts the least recently used sessions beyond the
+// configured retention caps, then vacuums the index to reclaim their space.
+func handleCacheVacuum() {
+	serverConfig, err := loadServerConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cache, err := serverConfig.Cache.open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open search cache: %v\n", err)
+		os.Exit(1)
+	}
+	defer cache.Close()
+
+	eviction, err := cache.Evict(time.Now(), serverConfig.Cache.Retention)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if eviction.Total() > 0 {
+		fmt.Printf("Evicted %d sessions (%d by age, %d by count, %d by size)\n",
+			eviction.Total(), eviction.ByAge, eviction.ByCount, eviction.BySize)
+	}
+	before, after, err := cache.Vacuum()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Vacuumed search index: %s -> %s\n", formatBytes(before), formatBytes(after))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// snapshotCapableAdapter is implemented by adapters whose agent snapshots
+// workspace files during a session (Claude Code file history, Gemini CLI
+// checkpointing).
+type snapshotCapableAdapter interface {
+	ListSnapshots(sessionID string) ([]adapters.WorkspaceSnapshot, error)
+	ReadSnapshot(sessionID, snapshotID string, read adapters.SnapshotReader) error
+}
+
+// externalSnapshotDir holds exported files that were outside the session's
+// project, under their absolute path.
+const externalSnapshotDir = "_external"
+
+// snapshotExportPath returns where a snapshot file is written under outputDir:
+// at its path relative to the project, or under externalSnapshotDir when it is
+// outside the project (or the project is unknown).
+func snapshotExportPath(outputDir, projectPath, path string) (string, error) {
+	rel := ""
+	if projectPath != "" {
+		if r, err := filepath.Rel(projectPath, path); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = r
+		}
+	}
+	if rel == "" {
+		rel = filepath.Join(externalSnapshotDir, strings.TrimPrefix(filepath.Clean(path), filepath.VolumeName(path)))
+	}
+	target := filepath.Join(outputDir, rel)
+	if target != outputDir && !strings.HasPrefix(target, outputDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("snapshot file %s would be written outside %s", path, outputDir)
+	}
+	return target, nil
+}
+
+// exportSnapshot writes a snapshot's files under outputDir and returns the
+// paths written, relative to outputDir. Unless overwrite is set, outputDir
+// must be empty or not exist yet.
+func exportSnapshot(adapter snapshotCapableAdapter, session adapters.Session, snapshotID, outputDir string, overwrite bool) ([]string, error) {
+	if entries, err := os.ReadDir(outputDir); err == nil && len(entries) > 0 && !overwrite {
+		return nil, fmt.Errorf("output_dir %s is not empty (set overwrite to write into it anyway)", outputDir)
+	}
+
+	written := []string{}
+	err := adapter.ReadSnapshot(session.ID, snapshotID, func(path string, content io.Reader) error {
+		target, err := snapshotExportPath(outputDir, session.ProjectPath, path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		if _, err := io.Copy(f, content); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		rel, _ := filepath.Rel(outputDir, target)
+		written = append(written, rel)
+		return nil
+	})
+	return written, err
+}
+
+// snapshotAdapter returns the adapter for source if its agent records
+// workspace snapshots.
+func snapshotAdapter(adaptersMap map[string]adapters.SessionAdapter, source string) (adapters.SessionAdapter, snapshotCapableAdapter, error) {
+	adapter, ok := adaptersMap[source]
+	if !ok {
+		return nil, nil, adapters.SourceUnavailableError(source)
+	}
+	snapshotter, ok := adapter.(snapshotCapableAdapter)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s does not record workspace snapshots (supported: claude, gemini)", source)
+	}
+	return adapter, snapshotter, nil
+}
+
+// Tool 48: list_snapshots
+type listSnapshotsArgs struct {
+	SessionID string `json:"session_id" jsonschema:"The session ID to list snapshots for"`
+	Source    string `json:"source" jsonschema:"The source that created this session (claude or gemini)"`
+}
+
+func addListSnapshotsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "list_snapshots",
+		Description: "List the workspace snapshots an agent took during a session, oldest first: Claude Code file-history backups (the files as they were before each prompt's edits) and Gemini CLI checkpoints (the project before each file-changing tool call). Each lists the files it holds; export one with export_snapshot to recover the pre-agent state.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listSnapshotsArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		adapter, snapshotter, err := snapshotAdapter(adaptersMap, args.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, found := findSession(adapter, args.SessionID)
+		if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+			return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+		}
+
+		snapshots, err := snapshotter.ListSnapshots(args.SessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+
+		resultJSON, err := json.MarshalIndent(map[string]interface{}{
+			"session_id": args.SessionID,
+			"source":     args.Source,
+			"snapshots":  snapshots,
+			"count":      len(snapshots),
+		}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// Tool 49: export_snapshot
+type exportSnapshotArgs struct {
+	SessionID  string `json:"session_id" jsonschema:"The session ID the snapshot belongs to"`
+	Source     string `json:"source" jsonschema:"The source that created this session (claude or gemini)"`
+	SnapshotID string `json:"snapshot_id" jsonschema:"The snapshot ID, as returned by list_snapshots"`
+	OutputDir  string `json:"output_dir" jsonschema:"Absolute directory to write the snapshot's files to, laid out relative to the session's project. Must be empty unless overwrite is set."`
+	Overwrite  bool   `json:"overwrite,omitempty" jsonschema:"Write into output_dir even if it already has files, replacing any with the same path"`
+}
+
+// addExportSnapshotTool is only registered when the server runs with
+// --allow-write.
+func addExportSnapshotTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "export_snapshot",
+		Description: "Write a workspace snapshot's files to a directory, laid out relative to the session's project (files outside it go under _external/), so a pre-agent state can be inspected or copied back. Never writes into the project itself unless output_dir points there.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportSnapshotArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.SnapshotID == "" {
+			return nil, nil, fmt.Errorf("snapshot_id is required")
+		}
+		if !filepath.IsAbs(args.OutputDir) {
+			return nil, nil, fmt.Errorf("output_dir must be absolute")
+		}
+		adapter, snapshotter, err := snapshotAdapter(adaptersMap, args.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, found := findSession(adapter, args.SessionID)
+		if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+			return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+		}
+
+		outputDir := filepath.Clean(args.OutputDir)
+		written, err := exportSnapshot(snapshotter, session, args.SnapshotID, outputDir, args.Overwrite)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The snapshot's files come from the session's tool calls, not its messages
+		recordAccess(searchCache, req.Session, search.AccessEntry{SessionID: args.SessionID, Source: args.Source})
+
+		// Files created later in the session have no content to export;
+		// restoring the snapshot means deleting them.
+		notExisting := []string{}
+		if snapshots, err := snapshotter.ListSnapshots(args.SessionID); err == nil {
+			for _, snapshot := range snapshots {
+				if snapshot.ID != args.SnapshotID {
+					continue
+				}
+				for _, f := range snapshot.Files {
+					if !f.Existed {
+						notExisting = append(notExisting, f.Path)
+					}
+				}
+			}
+		}
+
+		resultJSON, err := json.MarshalIndent(map[string]interface{}{
+			"session_id":    args.SessionID,
+			"source":        args.Source,
+			"snapshot_id":   args.SnapshotID,
+			"output_dir":    outputDir,
+			"files_written": written,
+			"count":         len(written),
+			"not_existing":  notExisting,
+		}, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
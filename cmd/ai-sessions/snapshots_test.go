@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// stubSnapshotter serves one snapshot with fixed file contents.
+type stubSnapshotter struct {
+	files map[string]string
+}
+
+func (s stubSnapshotter) ListSnapshots(sessionID string) ([]adapters.WorkspaceSnapshot, error) {
+	return nil, nil
+}
+
+func (s stubSnapshotter) ReadSnapshot(sessionID, snapshotID string, read adapters.SnapshotReader) error {
+	for path, content := range s.files {
+		if err := read(path, strings.NewReader(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSnapshotExportPath(t *testing.T) {
+	tests := []struct {
+		project, path, want string
+	}{
+		{"/work/app", "/work/app/cmd/main.go", "/out/cmd/main.go"},
+		{"/work/app", "/work/application/x.go", "/out/_external/work/application/x.go"},
+		{"/work/app", "/etc/hosts", "/out/_external/etc/hosts"},
+		{"", "/work/app/main.go", "/out/_external/work/app/main.go"},
+	}
+	for _, tt := range tests {
+		got, err := snapshotExportPath("/out", tt.project, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("snapshotExportPath(%q, %q) = %q, %v; want %q", tt.project, tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestExportSnapshot(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "restore")
+	snapshotter := stubSnapshotter{files: map[string]string{
+		"/work/app/main.go": "package main",
+		"/tmp/notes.txt":    "notes",
+	}}
+	session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/work/app"}
+
+	written, err := exportSnapshot(snapshotter, session, "m1", out, false)
+	if err != nil {
+		t.Fatalf("exportSnapshot returned error: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("expected two files written, got %v", written)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "main.go"))
+	if err != nil || string(data) != "package main" {
+		t.Fatalf("unexpected main.go: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "_external", "tmp", "notes.txt")); err != nil {
+		t.Fatalf("expected external file under _external: %v", err)
+	}
+
+	if _, err := exportSnapshot(snapshotter, session, "m1", out, false); err == nil {
+		t.Fatal("expected an error exporting into a non-empty directory")
+	}
+	if _, err := exportSnapshot(snapshotter, session, "m1", out, true); err != nil {
+		t.Fatalf("exportSnapshot with overwrite returned error: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// sourceFilter is a source argument that takes one source name or an array
+// of them, such as "claude" or ["claude", "codex"]. Empty means every source.
+type sourceFilter []string
+
+func (f *sourceFilter) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*f = nil
+		if one != "" {
+			*f = sourceFilter{one}
+		}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("source must be a string or an array of strings")
+	}
+	*f = nil
+	for _, source := range many {
+		if source != "" && !slices.Contains(*f, source) {
+			*f = append(*f, source)
+		}
+	}
+	return nil
+}
+
+// argTypeSchemas overrides the input schemas inferred for argument types
+// whose JSON form differs from their Go type.
+var argTypeSchemas = map[reflect.Type]*jsonschema.Schema{
+	reflect.TypeFor[sourceFilter](): {
+		Types: []string{"string", "array"},
+		Items: &jsonschema.Schema{Type: "string"},
+	},
+}
+
+// single returns the one source filtered on, or "" when the filter is empty
+// or names several sources.
+func (f sourceFilter) single() string {
+	if len(f) == 1 {
+		return f[0]
+	}
+	return ""
+}
+
+// includes reports whether sessions from source pass the filter.
+func (f sourceFilter) includes(source string) bool {
+	return len(f) == 0 || slices.Contains(f, source)
+}
+
+// adapters returns the adapters the filter selects, or an
+// ErrSourceUnavailable error naming the first unknown source.
+func (f sourceFilter) adapters(adaptersMap map[string]adapters.SessionAdapter) (map[string]adapters.SessionAdapter, error) {
+	if len(f) == 0 {
+		return adaptersMap, nil
+	}
+	selected := make(map[string]adapters.SessionAdapter, len(f))
+	for _, source := range f {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, adapters.SourceUnavailableError(source)
+		}
+		selected[source] = adapter
+	}
+	return selected, nil
+}
+
+// indexSources lazily indexes the sessions of each source the filter selects.
+func indexSources(adaptersMap map[string]adapters.SessionAdapter, cache search.Store, sources sourceFilter, projectPath string) error {
+	if len(sources) == 0 {
+		return indexSessions(adaptersMap, cache, "", projectPath)
+	}
+	var errs []error
+	for _, source := range sources {
+		if err := indexSessions(adaptersMap, cache, source, projectPath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveSessionSource returns the source holding sessionID: the only source
+// given, or the first of several that has the session.
+func resolveSessionSource(adaptersMap map[string]adapters.SessionAdapter, sources sourceFilter, sessionID string) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("source is required")
+	}
+	if _, err := sources.adapters(adaptersMap); err != nil {
+		return "", err
+	}
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+	for _, source := range sources {
+		if _, err := adaptersMap[source].GetSession(sessionID, 0, 1); err == nil {
+			return source, nil
+		}
+	}
+	return "", adapters.SessionNotFoundError(sessionID)
+}
+
+// sourceCounts counts sessions per source.
+func sourceCounts(sessions []adapters.Session) map[string]int {
+	if len(sessions) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, session := range sessions {
+		counts[session.Source]++
+	}
+	return counts
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// storageCapableAdapter is implemented by adapters that keep a session's data
+// in more than its FilePath.
+type storageCapableAdapter interface {
+	SessionStorage(session adapters.Session) []string
+}
+
+// storageGroup is the disk usage of one source, project, or month.
+type storageGroup struct {
+	Name         string `json:"name"`
+	Bytes        int64  `json:"bytes"`
+	SessionCount int    `json:"session_count"`
+}
+
+// storageMonth is the disk usage of sessions started in one month, and of all
+// sessions started up to the end of it.
+type storageMonth struct {
+	storageGroup
+	CumulativeBytes int64 `json:"cumulative_bytes"`
+}
+
+// sessionStorage is the disk usage of one session.
+type sessionStorage struct {
+	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
+	ProjectPath  string    `json:"project_path,omitempty"`
+	FirstMessage string    `json:"first_message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	Bytes        int64     `json:"bytes"`
+	Paths        []string  `json:"paths"`
+}
+
+// sharedStorage is a file holding many sessions, such as a SQLite database.
+type sharedStorage struct {
+	Path         string `json:"path"`
+	Source       string `json:"source"`
+	Bytes        int64  `json:"bytes"`
+	SessionCount int    `json:"session_count"`
+}
+
+// storageReport is the disk usage of session history. Files shared by several
+// sessions count toward their source's total but can't be split by session,
+// project, or month, so they are listed on their own.
+type storageReport struct {
+	TotalBytes   int64            `json:"total_bytes"`
+	SessionCount int              `json:"session_count"`
+	BySource     []storageGroup   `json:"by_source"`
+	ByProject    []storageGroup   `json:"by_project"`
+	ByMonth      []storageMonth   `json:"by_month"`
+	Largest      []sessionStorage `json:"largest_sessions"`
+	SharedFiles  []sharedStorage  `json:"shared_files,omitempty"`
+}
+
+// storageGroups accumulates disk usage per group name.
+type storageGroups map[string]*storageGroup
+
+func (g storageGroups) add(name string, bytes int64, sessions int) {
+	group, ok := g[name]
+	if !ok {
+		group = &storageGroup{Name: name}
+		g[name] = group
+	}
+	group.Bytes += bytes
+	group.SessionCount += sessions
+}
+
+// largestFirst returns the groups by size, largest first.
+func (g storageGroups) largestFirst() []storageGroup {
+	groups := make([]storageGroup, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Bytes != groups[j].Bytes {
+			return groups[i].Bytes > groups[j].Bytes
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// collectStorage measures the disk usage of every listed session. filter
+// decides which sessions may be reported and returns the projects it withheld.
+// limit caps the largest sessions returned (0 means no limit).
+func collectStorage(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, limit int, filter func([]adapters.Session) ([]adapters.Session, []string)) (storageReport, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return storageReport{}, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	report := storageReport{Largest: []sessionStorage{}}
+	bySource, byProject, byMonth := storageGroups{}, storageGroups{}, storageGroups{}
+	shared := make(map[string]*sharedStorage)
+	var withheld []string
+
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, 0) // Get all sessions
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		sessionsPerFile := make(map[string]int)
+		for _, s := range listed {
+			sessionsPerFile[s.FilePath]++
+		}
+
+		for _, session := range listed {
+			report.SessionCount++
+			if sessionsPerFile[session.FilePath] > 1 {
+				file, ok := shared[session.FilePath]
+				if !ok {
+					file = &sharedStorage{Path: session.FilePath, Source: session.Source, Bytes: adapters.DiskUsage(session.FilePath)}
+					shared[session.FilePath] = file
+					report.TotalBytes += file.Bytes
+					bySource.add(session.Source, file.Bytes, 0)
+				}
+				file.SessionCount++
+				bySource.add(session.Source, 0, 1)
+				continue
+			}
+
+			paths := []string{session.FilePath}
+			if storer, ok := adapter.(storageCapableAdapter); ok {
+				paths = storer.SessionStorage(session)
+			}
+			var bytes int64
+			for _, path := range paths {
+				bytes += adapters.DiskUsage(path)
+			}
+
+			report.TotalBytes += bytes
+			bySource.add(session.Source, bytes, 1)
+			byProject.add(session.ProjectPath, bytes, 1)
+			if !session.Timestamp.IsZero() {
+				byMonth.add(session.Timestamp.Local().Format("2006-01"), bytes, 1)
+			}
+			report.Largest = append(report.Largest, sessionStorage{
+				SessionID:    session.ID,
+				Source:       session.Source,
+				ProjectPath:  session.ProjectPath,
+				FirstMessage: truncateString(session.FirstMessage, 200),
+				Timestamp:    session.Timestamp,
+				Bytes:        bytes,
+				Paths:        paths,
+			})
+		}
+	}
+
+	report.BySource = bySource.largestFirst()
+	report.ByProject = byProject.largestFirst()
+
+	months := byMonth.largestFirst()
+	sort.Slice(months, func(i, j int) bool { return months[i].Name < months[j].Name })
+	var cumulative int64
+	for _, month := range months {
+		cumulative += month.Bytes
+		report.ByMonth = append(report.ByMonth, storageMonth{storageGroup: month, CumulativeBytes: cumulative})
+	}
+
+	sort.Slice(report.Largest, func(i, j int) bool {
+		if report.Largest[i].Bytes != report.Largest[j].Bytes {
+			return report.Largest[i].Bytes > report.Largest[j].Bytes
+		}
+		return report.Largest[i].Timestamp.After(report.Largest[j].Timestamp)
+	})
+	if limit > 0 && len(report.Largest) > limit {
+		report.Largest = report.Largest[:limit]
+	}
+
+	for _, file := range shared {
+		report.SharedFiles = append(report.SharedFiles, *file)
+	}
+	sort.Slice(report.SharedFiles, func(i, j int) bool {
+		return report.SharedFiles[i].Bytes > report.SharedFiles[j].Bytes
+	})
+	sort.Strings(withheld)
+
+	return report, withheld, nil
+}
+
+// formatBytes formats a size with a binary unit, e.g. "1.5 MiB".
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// Tool 36: storage_report
+type storageReportArgs struct {
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for all projects."`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of largest sessions to return (default: 20)"`
+}
+
+func addStorageReportTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "storage_report",
+		Description: "Report the disk space session history takes per source, per project, and per month (with the running total, to show growth), and list the largest sessions. Useful for deciding what to prune or archive.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args storageReportArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 20
+		}
+
+		report, withheld, err := collectStorage(adaptersMap, args.Source, args.ProjectPath, args.Limit, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := map[string]interface{}{
+			"total_bytes":      report.TotalBytes,
+			"session_count":    report.SessionCount,
+			"by_source":        report.BySource,
+			"by_project":       report.ByProject,
+			"by_month":         report.ByMonth,
+			"largest_sessions": report.Largest,
+		}
+		if len(report.SharedFiles) > 0 {
+			result["shared_files"] = report.SharedFiles
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
+
+// handleStorageCommand processes: aisessions storage [--source <source>] [--project <path>] [--limit <n>]
+func handleStorageCommand() {
+	var source, projectPath string
+	limit := 10
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--source", "--project", "--limit":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			switch args[i] {
+			case "--source":
+				source = args[i+1]
+			case "--project":
+				projectPath = args[i+1]
+			default:
+				var err error
+				if limit, err = strconv.Atoi(args[i+1]); err != nil || limit <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid --limit: %s\n", args[i+1])
+					os.Exit(1)
+				}
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: aisessions storage [--source <source>] [--project <path>] [--limit <n>]\n")
+			os.Exit(1)
+		}
+	}
+
+	adaptersMap, _ := adapters.NewRegistered()
+	if serverConfig, err := loadServerConfig(); err == nil {
+		addConfiguredAdapters(adaptersMap, serverConfig)
+	}
+
+	report, _, err := collectStorage(adaptersMap, source, projectPath, limit, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+		return sessions, nil
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+	printStorageReport(report)
+}
+
+// printStorageReport prints a storage report as plain-text tables.
+func printStorageReport(report storageReport) {
+	fmt.Printf("%s in %d sessions\n", formatBytes(report.TotalBytes), report.SessionCount)
+
+	printGroups := func(title string, groups []storageGroup) {
+		if len(groups) == 0 {
+			return
+		}
+		fmt.Printf("\n%s\n", title)
+		for _, g := range groups {
+			name := g.Name
+			if name == "" {
+				name = "(no project)"
+			}
+			fmt.Printf("  %10s  %5d sessions  %s\n", formatBytes(g.Bytes), g.SessionCount, name)
+		}
+	}
+	printGroups("By source", report.BySource)
+	printGroups("By project", report.ByProject)
+
+	if len(report.ByMonth) > 0 {
+		fmt.Printf("\nBy month started (running total)\n")
+		for _, m := range report.ByMonth {
+			fmt.Printf("  %s  %10s  %5d sessions  %10s\n", m.Name, formatBytes(m.Bytes), m.SessionCount, formatBytes(m.CumulativeBytes))
+		}
+	}
+
+	if len(report.Largest) > 0 {
+		fmt.Printf("\nLargest sessions\n")
+		for _, s := range report.Largest {
+			title := strings.Join(strings.Fields(s.FirstMessage), " ")
+			fmt.Printf("  %10s  %-8s %s  %s\n", formatBytes(s.Bytes), s.Source, s.SessionID, truncateString(title, 60))
+		}
+	}
+
+	if len(report.SharedFiles) > 0 {
+		fmt.Printf("\nShared files (not split by session)\n")
+		for _, f := range report.SharedFiles {
+			fmt.Printf("  %10s  %5d sessions  %s\n", formatBytes(f.Bytes), f.SessionCount, f.Path)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectStorage(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	db := write("shared.db", 500)
+
+	march := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	april := march.AddDate(0, 1, 0)
+	sessions := []adapters.Session{
+		{ID: "big", Source: "stub", ProjectPath: "/src/app", Timestamp: march, FilePath: write("big.jsonl", 300)},
+		{ID: "small", Source: "stub", ProjectPath: "/src/lib", Timestamp: april, FilePath: write("small.jsonl", 100)},
+		{ID: "db-1", Source: "stub", ProjectPath: "/src/app", Timestamp: april, FilePath: db},
+		{ID: "db-2", Source: "stub", ProjectPath: "/src/lib", Timestamp: april, FilePath: db},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, nil)}
+	allowAll := func(s []adapters.Session) ([]adapters.Session, []string) { return s, nil }
+
+	report, _, err := collectStorage(adaptersMap, "", "", 1, allowAll)
+	if err != nil {
+		t.Fatalf("collectStorage failed: %v", err)
+	}
+
+	if report.TotalBytes != 900 || report.SessionCount != 4 {
+		t.Fatalf("expected 900 bytes over 4 sessions with the shared file counted once, got %d over %d", report.TotalBytes, report.SessionCount)
+	}
+	if len(report.BySource) != 1 || report.BySource[0].Bytes != 900 || report.BySource[0].SessionCount != 4 {
+		t.Fatalf("expected the source to include the shared file, got %+v", report.BySource)
+	}
+	if len(report.ByProject) != 2 || report.ByProject[0] != (storageGroup{Name: "/src/app", Bytes: 300, SessionCount: 1}) {
+		t.Fatalf("expected projects to leave out the shared file, got %+v", report.ByProject)
+	}
+	if len(report.ByMonth) != 2 || report.ByMonth[0].Name != "2025-03" || report.ByMonth[1].CumulativeBytes != 400 {
+		t.Fatalf("expected two months with a running total, got %+v", report.ByMonth)
+	}
+	if len(report.Largest) != 1 || report.Largest[0].SessionID != "big" {
+		t.Fatalf("expected the limit to keep the largest session, got %+v", report.Largest)
+	}
+	if len(report.SharedFiles) != 1 || report.SharedFiles[0] != (sharedStorage{Path: db, Source: "stub", Bytes: 500, SessionCount: 2}) {
+		t.Fatalf("expected the shared file to be listed once, got %+v", report.SharedFiles)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		512:             "512 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	} {
+		if got := formatBytes(bytes); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// streamChunkSize is how many messages each streamed progress notification carries.
+const streamChunkSize = 50
+
+// messageStream sends messages to the client in chunks, as progress
+// notifications for the request's progress token, so a client can show a
+// large page as it arrives. Each notification's _meta holds "offset", the
+// index of its first message in the page, and "messages". Messages are added
+// as the adapter parses them, so a chunk goes out as soon as it fills and the
+// page is never held or encoded as a single document.
+type messageStream struct {
+	ctx     context.Context
+	session *mcp.ServerSession
+	token   any
+	sent    int // messages sent so far
+	chunk   []adapters.Message
+}
+
+func newMessageStream(ctx context.Context, session *mcp.ServerSession, token any) *messageStream {
+	return &messageStream{ctx: ctx, session: session, token: token}
+}
+
+// add queues a message, sending the chunk once it is full.
+func (s *messageStream) add(msg adapters.Message) error {
+	prepareMessage(&msg)
+	s.chunk = append(s.chunk, msg)
+	if len(s.chunk) < streamChunkSize {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush sends any queued messages.
+func (s *messageStream) flush() error {
+	if len(s.chunk) == 0 {
+		return nil
+	}
+	end := s.sent + len(s.chunk)
+	err := s.session.NotifyProgress(s.ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: s.token,
+		Message:       fmt.Sprintf("messages %d-%d", s.sent+1, end),
+		Progress:      float64(end),
+		Meta:          mcp.Meta{"offset": s.sent, "messages": s.chunk},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stream messages: %w", err)
+	}
+	s.sent = end
+	// A fresh slice, since the sent one may still be referenced while encoding
+	s.chunk = nil
+	return nil
+}
+
+// prepareMessage fills in the fields get_session always returns.
+func prepareMessage(msg *adapters.Message) {
+	if msg.PartTypes == nil {
+		msg.PartTypes = map[string]int{}
+	}
+	msg.ContentHash = adapters.HashMessage(*msg)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestGetSessionStreamsMessages(t *testing.T) {
+	messages := make([]adapters.Message, 120)
+	for i := range messages {
+		messages[i] = adapters.Message{Role: "user", Content: fmt.Sprintf("message %d", i)}
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(nil, map[string][]adapters.Message{"sess-1": messages})}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetSessionTool(server, adaptersMap, newTestCache(t), nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	var (
+		mu       sync.Mutex
+		streamed = make(map[int]string)
+	)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			var chunk struct {
+				Offset   int                `json:"offset"`
+				Messages []adapters.Message `json:"messages"`
+			}
+			data, _ := json.Marshal(req.Params.Meta)
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				t.Errorf("unmarshal chunk: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for i, msg := range chunk.Messages {
+				streamed[chunk.Offset+i] = msg.Content
+			}
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	args := map[string]interface{}{"session_id": "sess-1", "source": "stub", "page_size": 120, "stream": true}
+	params := &mcp.CallToolParams{Name: "get_session", Arguments: args}
+	result, err := clientSession.CallTool(ctx, params)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected streaming without a progress token to fail")
+	}
+
+	// SetProgressToken can't add a token to params without _meta
+	params.Meta = mcp.Meta{"progressToken": "page-1"}
+	result, err = clientSession.CallTool(ctx, params)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if got["streamed"] != true || got["count"] != float64(120) || got["messages"] != nil {
+		t.Fatalf("expected a result without messages, got %v", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(streamed)
+		mu.Unlock()
+		if n == len(messages) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(streamed) != len(messages) {
+		t.Fatalf("expected %d streamed messages, got %d", len(messages), len(streamed))
+	}
+	for i := range messages {
+		if want := fmt.Sprintf("message %d", i); streamed[i] != want {
+			t.Fatalf("streamed message %d = %q, want %q", i, streamed[i], want)
+		}
+	}
+}
+
+// pageStreamingStub streams pages, holding back the rest of the page until the
+// client has received the first chunk.
+type pageStreamingStub struct {
+	*stubAdapter
+	firstChunk chan struct{}
+}
+
+func (s *pageStreamingStub) StreamSessionPage(sessionID string, page, pageSize int, fromEnd bool, yield func(adapters.Message) error) (int, int, bool, error) {
+	messages, total, resolved, hasMore := adapters.PaginateMessages(s.messages[sessionID], page, pageSize, fromEnd)
+	for i, msg := range messages {
+		if i == streamChunkSize {
+			select {
+			case <-s.firstChunk:
+			case <-time.After(5 * time.Second):
+				return 0, 0, false, fmt.Errorf("first chunk wasn't sent while reading")
+			}
+		}
+		if err := yield(msg); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	return total, resolved, hasMore, nil
+}
+
+func TestGetSessionStreamsMessagesAsTheyAreRead(t *testing.T) {
+	messages := make([]adapters.Message, 120)
+	for i := range messages {
+		messages[i] = adapters.Message{Role: "user", Content: fmt.Sprintf("message %d", i)}
+	}
+	stub := &pageStreamingStub{
+		stubAdapter: newStubAdapter(nil, map[string][]adapters.Message{"sess-1": messages}),
+		firstChunk:  make(chan struct{}),
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": stub}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	addGetSessionTool(server, adaptersMap, newTestCache(t), nil)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	var once sync.Once
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			if offset, _ := req.Params.Meta["offset"].(float64); offset == 0 {
+				once.Do(func() { close(stub.firstChunk) })
+			}
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	params := &mcp.CallToolParams{
+		Name:      "get_session",
+		Arguments: map[string]interface{}{"session_id": "sess-1", "source": "stub", "page_size": 120, "stream": true},
+		Meta:      mcp.Meta{"progressToken": "page-1"},
+	}
+	result, err := clientSession.CallTool(ctx, params)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if got["count"] != float64(120) || got["total_messages"] != float64(120) || got["messages"] != nil {
+		t.Fatalf("expected a counted result without messages, got %v", got)
+	}
+}
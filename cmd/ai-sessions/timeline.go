@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// timelineEvent is one line of a session timeline: a message's text or one of
+// its tool calls.
+type timelineEvent struct {
+	Index     int        `json:"index"` // message index, as used by get_session pages and search_in_session
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Role      string     `json:"role"`
+	Type      string     `json:"type"` // "message" or "tool_call"
+	Tool      string     `json:"tool,omitempty"`
+	Preview   string     `json:"preview"`
+	Failed    bool       `json:"failed,omitempty"` // the tool call's result was an error
+}
+
+// buildTimeline condenses a session to one event per message with text and one
+// per tool call, each with a preview of at most previewLength characters.
+// Messages that only carry tool results are folded into their calls.
+func buildTimeline(messages []adapters.Message, previewLength int) []timelineEvent {
+	failed := make(map[string]bool)
+	for _, inv := range adapters.ExtractToolInvocations(messages) {
+		if inv.ID != "" && inv.Success != nil && !*inv.Success {
+			failed[inv.ID] = true
+		}
+	}
+
+	events := []timelineEvent{}
+	for i, msg := range messages {
+		var ts *time.Time
+		if !msg.Timestamp.IsZero() {
+			t := msg.Timestamp
+			ts = &t
+		}
+		if content := strings.Join(strings.Fields(msg.Content), " "); content != "" {
+			events = append(events, timelineEvent{
+				Index:     i,
+				Timestamp: ts,
+				Role:      msg.Role,
+				Type:      "message",
+				Preview:   adapters.TruncateText(content, previewLength),
+			})
+		}
+		for _, call := range adapters.ExtractToolCalls(msg) {
+			events = append(events, timelineEvent{
+				Index:     i,
+				Timestamp: ts,
+				Role:      msg.Role,
+				Type:      "tool_call",
+				Tool:      call.Name,
+				Preview:   adapters.TruncateText(toolCallPreview(call), previewLength),
+				Failed:    call.ID != "" && failed[call.ID],
+			})
+		}
+	}
+	return events
+}
+
+// Tool 28: get_session_timeline
+type getSessionTimelineArgs struct {
+	SessionID     string `json:"session_id" jsonschema:"The session ID to summarize"`
+	Source        string `json:"source" jsonschema:"The source that created this session (claude, gemini, codex, opencode, mistral, copilot)"`
+	PreviewLength int    `json:"preview_length,omitempty" jsonschema:"Truncate each event's preview to this many characters (default: 100)"`
+}
+
+func addGetSessionTimelineTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_session_timeline",
+		Description: "Get a condensed timeline of a session: one line per message and tool call with timestamp, role, and a short preview. Use it to see the shape of a long session before reading specific message ranges.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSessionTimelineArgs) (*mcp.CallToolResult, any, error) {
+		if args.SessionID == "" {
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+		if args.Source == "" {
+			return nil, nil, fmt.Errorf("source is required")
+		}
+		if args.PreviewLength <= 0 {
+			args.PreviewLength = 100
+		}
+
+		adapter, ok := adaptersMap[args.Source]
+		if !ok {
+			return nil, nil, adapters.SourceUnavailableError(args.Source)
+		}
+
+		if consent != nil {
+			if projectPath, found := findSessionProject(adapter, args.SessionID); !consent.allowedSession(ctx, req.Session, projectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, projectPath, found)
+			}
+		}
+
+		messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+
+		events := buildTimeline(messages, args.PreviewLength)
+		result := map[string]interface{}{
+			"session_id":     args.SessionID,
+			"source":         args.Source,
+			"total_messages": len(messages),
+			"events":         events,
+			"count":          len(events),
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
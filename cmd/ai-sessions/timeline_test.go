@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	messages := []adapters.Message{
+		{Role: "user", Content: "Run the\n  tests please", Timestamp: start},
+		{Role: "assistant", Content: "Running them.", Timestamp: start.Add(time.Second), Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "t1", "name": "Bash", "input": map[string]interface{}{"command": "go test ./..."}},
+			},
+		}},
+		{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "t1", "content": "FAIL", "is_error": true},
+			},
+		}},
+		{Role: "assistant", Content: "One test fails because the fixture is missing"},
+	}
+
+	events := buildTimeline(messages, 20)
+	want := []timelineEvent{
+		{Index: 0, Role: "user", Type: "message", Preview: "Run the tests please"},
+		{Index: 1, Role: "assistant", Type: "message", Preview: "Running them."},
+		{Index: 1, Role: "assistant", Type: "tool_call", Tool: "Bash", Preview: "go test ./...", Failed: true},
+		{Index: 3, Role: "assistant", Type: "message", Preview: "One test fails becau..."},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		got := events[i]
+		got.Timestamp = nil
+		if got != w {
+			t.Errorf("event %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if events[1].Timestamp == nil || !events[1].Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("expected the message timestamp on its events, got %v", events[1].Timestamp)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// toolFailure is one failed tool execution and what led up to it.
+type toolFailure struct {
+	SessionID    string                 `json:"session_id"`
+	Source       string                 `json:"source"`
+	ProjectPath  string                 `json:"project_path,omitempty"`
+	MessageIndex int                    `json:"message_index"`
+	Timestamp    *time.Time             `json:"timestamp,omitempty"`
+	Tool         string                 `json:"tool"`
+	Input        map[string]interface{} `json:"input,omitempty"`
+	Error        string                 `json:"error"`
+	// Prompt is the user prompt the agent was working on when the call failed
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// toolFailureCount is how often one tool failed out of the calls that recorded a result.
+type toolFailureCount struct {
+	Tool        string  `json:"tool"`
+	Failures    int     `json:"failures"`
+	Calls       int     `json:"calls"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// toolFailureReport lists failed tool executions, most recent first, with
+// failure counts per tool.
+type toolFailureReport struct {
+	Failures     []toolFailure      `json:"failures"`
+	ByTool       []toolFailureCount `json:"by_tool"`
+	SessionCount int                `json:"session_count"`
+}
+
+// sessionToolFailures returns the failed tool executions in one session, with
+// errors and prompts truncated to maxLength characters unless it is negative.
+func sessionToolFailures(session adapters.Session, messages []adapters.Message, maxLength int) []toolFailure {
+	clip := func(s string) string {
+		s = strings.TrimSpace(s)
+		if maxLength < 0 {
+			return s
+		}
+		return truncateString(s, maxLength)
+	}
+
+	var failures []toolFailure
+	for _, invocation := range adapters.ExtractToolInvocations(messages) {
+		if invocation.Success == nil || *invocation.Success {
+			continue
+		}
+		failure := toolFailure{
+			SessionID:    session.ID,
+			Source:       session.Source,
+			ProjectPath:  session.ProjectPath,
+			MessageIndex: invocation.MessageIndex,
+			Timestamp:    invocation.Timestamp,
+			Tool:         invocation.Name,
+			Input:        invocation.Input,
+			Error:        clip(*invocation.Result),
+		}
+		for i := invocation.MessageIndex; i >= 0; i-- {
+			if isPrompt(messages[i]) {
+				failure.Prompt = clip(messages[i].Content)
+				break
+			}
+		}
+		failures = append(failures, failure)
+	}
+	return failures
+}
+
+// collectToolFailures scans each source's most recent sessions, up to limit
+// per source, for failed tool executions. tool, when set, keeps only calls to
+// that tool. filter decides which listed sessions may be read and returns the
+// projects it withheld.
+func collectToolFailures(adaptersMap map[string]adapters.SessionAdapter, source, projectPath, tool string, since time.Time, limit, maxLength int, filter func([]adapters.Session) ([]adapters.Session, []string)) (toolFailureReport, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return toolFailureReport{}, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	report := toolFailureReport{Failures: []toolFailure{}}
+	counts := make(map[string]*toolFailureCount)
+	var withheld []string
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, limit)
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		for _, session := range listed {
+			if !since.IsZero() && session.Timestamp.Before(since) {
+				continue
+			}
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+			report.SessionCount++
+
+			for _, invocation := range adapters.ExtractToolInvocations(messages) {
+				if invocation.Success == nil || (tool != "" && !strings.EqualFold(invocation.Name, tool)) {
+					continue
+				}
+				count, ok := counts[invocation.Name]
+				if !ok {
+					count = &toolFailureCount{Tool: invocation.Name}
+					counts[invocation.Name] = count
+				}
+				count.Calls++
+				if !*invocation.Success {
+					count.Failures++
+				}
+			}
+			for _, failure := range sessionToolFailures(session, messages, maxLength) {
+				if tool == "" || strings.EqualFold(failure.Tool, tool) {
+					report.Failures = append(report.Failures, failure)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(report.Failures, func(i, j int) bool {
+		a, b := report.Failures[i].Timestamp, report.Failures[j].Timestamp
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	report.ByTool = []toolFailureCount{}
+	for _, count := range counts {
+		if count.Failures == 0 {
+			continue
+		}
+		count.FailureRate = float64(count.Failures) / float64(count.Calls)
+		report.ByTool = append(report.ByTool, *count)
+	}
+	sort.Slice(report.ByTool, func(i, j int) bool {
+		if report.ByTool[i].Failures != report.ByTool[j].Failures {
+			return report.ByTool[i].Failures > report.ByTool[j].Failures
+		}
+		return report.ByTool[i].Tool < report.ByTool[j].Tool
+	})
+	sort.Strings(withheld)
+	return report, withheld, nil
+}
+
+// Tool 39: list_tool_failures
+type listToolFailuresArgs struct {
+	Source          string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath     string `json:"project_path,omitempty" jsonschema:"Only include sessions from this project. Leave empty for all projects."`
+	Tool            string `json:"tool,omitempty" jsonschema:"Only include failures of this tool (case-insensitive)"`
+	Since           string `json:"since,omitempty" jsonschema:"Only include sessions active since this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h)"`
+	Limit           int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions to scan per source (default: 50)"`
+	MaxFailures     int    `json:"max_failures,omitempty" jsonschema:"Maximum number of failures to return, most recent first (default: 100)"`
+	MaxResultLength int    `json:"max_result_length,omitempty" jsonschema:"Truncate each error and prompt to this many characters (default: 500, -1 for no limit)"`
+}
+
+// recordFailureReads logs a read of each session failures were returned from,
+// counting the failed calls returned from it as its messages.
+func recordFailureReads(searchCache search.Store, session *mcp.ServerSession, failures []toolFailure) {
+	type sessionKey struct{ source, id string }
+	counts := make(map[sessionKey]int)
+	var order []sessionKey
+	for _, failure := range failures {
+		key := sessionKey{failure.Source, failure.SessionID}
+		if counts[key] == 0 {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+	for _, key := range order {
+		recordAccess(searchCache, session, search.AccessEntry{SessionID: key.id, Source: key.source, MessageCount: counts[key]})
+	}
+}
+
+func addListToolFailuresTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "list_tool_failures",
+		Description: "Find failed tool executions across recent sessions (Claude tool errors, Copilot and opencode failures, Mistral is_error results), each with its arguments, error output, and the user prompt it was working on, plus failure counts and rates per tool for spotting recurring failure patterns",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listToolFailuresArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+		if args.MaxFailures == 0 {
+			args.MaxFailures = 100
+		}
+		if args.MaxResultLength == 0 {
+			args.MaxResultLength = 500
+		}
+
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = parseSince(args.Since, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		report, withheld, err := collectToolFailures(adaptersMap, args.Source, args.ProjectPath, args.Tool, since, args.Limit, args.MaxResultLength, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+			return consent.filterSessions(ctx, req.Session, sessions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		failureCount := len(report.Failures)
+		if len(report.Failures) > args.MaxFailures {
+			report.Failures = report.Failures[:args.MaxFailures]
+		}
+		recordFailureReads(searchCache, req.Session, report.Failures)
+
+		result := map[string]interface{}{
+			"failures":      report.Failures,
+			"failure_count": failureCount,
+			"by_tool":       report.ByTool,
+			"session_count": report.SessionCount,
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
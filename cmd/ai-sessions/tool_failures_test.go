@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectToolFailures(t *testing.T) {
+	now := time.Now()
+	toolUse := func(id, name string) adapters.Message {
+		return adapters.Message{Role: "assistant", Timestamp: now, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_use", "id": id, "name": name, "input": map[string]interface{}{"command": "go test"}}},
+		}}
+	}
+	toolResult := func(id, content string, isError bool) adapters.Message {
+		return adapters.Message{Role: "user", Metadata: map[string]interface{}{
+			"raw_content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": content, "is_error": isError}},
+		}}
+	}
+
+	sessions := []adapters.Session{
+		{ID: "s1", Source: "stub", ProjectPath: "/src/app", Timestamp: now},
+		{ID: "s2", Source: "stub", ProjectPath: "/src/lib", Timestamp: now.Add(-30 * 24 * time.Hour)},
+	}
+	messages := map[string][]adapters.Message{
+		"s1": {
+			{Role: "user", Content: "Fix the failing tests"},
+			toolUse("t1", "Bash"),
+			toolResult("t1", "exit status 1: FAIL", true),
+			toolUse("t2", "Bash"),
+			toolResult("t2", "ok", false),
+			toolUse("t3", "Read"),
+			toolResult("t3", "file not found", true),
+		},
+		"s2": {
+			toolUse("t1", "Bash"),
+			toolResult("t1", "old failure", true),
+		},
+	}
+	adaptersMap := map[string]adapters.SessionAdapter{"stub": newStubAdapter(sessions, messages)}
+	allowAll := func(s []adapters.Session) ([]adapters.Session, []string) { return s, nil }
+
+	report, _, err := collectToolFailures(adaptersMap, "", "", "", now.Add(-24*time.Hour), 50, 10, allowAll)
+	if err != nil {
+		t.Fatalf("collectToolFailures failed: %v", err)
+	}
+	if report.SessionCount != 1 || len(report.Failures) != 2 {
+		t.Fatalf("expected two failures from the recent session, got %+v", report)
+	}
+	failure := report.Failures[0]
+	if failure.Tool != "Bash" || failure.SessionID != "s1" || failure.MessageIndex != 1 || failure.Error != "exit st..." || failure.Prompt != "Fix the..." {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+	if len(report.ByTool) != 2 || report.ByTool[0] != (toolFailureCount{Tool: "Bash", Failures: 1, Calls: 2, FailureRate: 0.5}) {
+		t.Fatalf("unexpected counts %+v", report.ByTool)
+	}
+
+	report, _, err = collectToolFailures(adaptersMap, "", "", "read", time.Time{}, 50, -1, allowAll)
+	if err != nil {
+		t.Fatalf("collectToolFailures failed: %v", err)
+	}
+	if report.SessionCount != 2 || len(report.Failures) != 1 || report.Failures[0].Error != "file not found" {
+		t.Fatalf("expected only the Read failure, got %+v", report.Failures)
+	}
+
+	if _, _, err := collectToolFailures(adaptersMap, "missing", "", "", time.Time{}, 50, -1, allowAll); err == nil {
+		t.Fatal("expected an error for an unknown source")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// collectToolTimings times tool calls over each source's most recent
+// sessions, up to limit per source, skipping sessions last active before since
+// when it is set, and keeping the maxSlowest slowest calls. filter decides
+// which listed sessions may be read and returns the projects it withheld.
+func collectToolTimings(adaptersMap map[string]adapters.SessionAdapter, source, projectPath string, since time.Time, limit, maxSlowest int, filter func([]adapters.Session) ([]adapters.Session, []string)) (*adapters.ToolTimingTracker, int, []string, error) {
+	adaptersToQuery := adaptersMap
+	if source != "" {
+		adapter, ok := adaptersMap[source]
+		if !ok {
+			return nil, 0, nil, adapters.SourceUnavailableError(source)
+		}
+		adaptersToQuery = map[string]adapters.SessionAdapter{source: adapter}
+	}
+
+	tracker := adapters.NewToolTimingTracker(maxSlowest)
+	sessionCount := 0
+	var withheld []string
+	for name, adapter := range adaptersToQuery {
+		listed, err := adapter.ListSessions(projectPath, limit)
+		if err != nil {
+			log.Printf("Error listing sessions for %s: %v", name, err)
+			continue
+		}
+		listed, skipped := filter(listed)
+		for _, project := range skipped {
+			withheld = appendUnique(withheld, project)
+		}
+
+		for _, session := range listed {
+			if !since.IsZero() && session.Timestamp.Before(since) {
+				continue
+			}
+			messages, err := adapter.GetSession(session.ID, 0, 100000) // Get all messages
+			if err != nil {
+				log.Printf("Error getting session %s: %v", session.ID, err)
+				continue
+			}
+			tracker.AddSession(session.ID, name, messages)
+			sessionCount++
+		}
+	}
+	sort.Strings(withheld)
+	return tracker, sessionCount, withheld, nil
+}
+
+// Tool 44: get_tool_timings
+type getToolTimingsArgs struct {
+	SessionID   string `json:"session_id,omitempty" jsonschema:"Time the tool calls of this session only (requires source). Leave empty to aggregate over recent sessions."`
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Only include sessions from this project. Leave empty for all projects."`
+	Since       string `json:"since,omitempty" jsonschema:"Only include sessions active since this time: an RFC 3339 time, a date (2025-03-01), or a duration back from now (168h)"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of recent sessions to include per source (default: 50)"`
+	MaxSlowest  int    `json:"max_slowest,omitempty" jsonschema:"Maximum number of slowest calls to list (default: 10)"`
+}
+
+func addGetToolTimingsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "get_tool_timings",
+		Description: "Report how long tool calls took, for one session or across recent sessions: per tool, the number of calls and the total, average, median, and longest duration, plus the slowest individual calls. Durations come from the times Copilot and opencode record, or else from the gap between a call's message and its result's, so calls without timestamps are counted but not timed.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getToolTimingsArgs) (*mcp.CallToolResult, any, error) {
+		if args.Limit == 0 {
+			args.Limit = 50
+		}
+		if args.MaxSlowest == 0 {
+			args.MaxSlowest = 10
+		}
+
+		var tracker *adapters.ToolTimingTracker
+		var sessionCount int
+		var withheld []string
+		if args.SessionID != "" {
+			if args.Source == "" {
+				return nil, nil, fmt.Errorf("source is required with session_id")
+			}
+			adapter, ok := adaptersMap[args.Source]
+			if !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+			session, found := findSession(adapter, args.SessionID)
+			if !consent.allowedSession(ctx, req.Session, session.ProjectPath, found) {
+				return nil, nil, sessionConsentError(args.SessionID, session.ProjectPath, found)
+			}
+			messages, err := adapter.GetSession(args.SessionID, 0, 100000) // Get all messages
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get session: %w", err)
+			}
+			tracker = adapters.NewToolTimingTracker(args.MaxSlowest)
+			tracker.AddSession(args.SessionID, args.Source, messages)
+			sessionCount = 1
+		} else {
+			var since time.Time
+			if args.Since != "" {
+				var err error
+				if since, err = parseSince(args.Since, time.Now()); err != nil {
+					return nil, nil, err
+				}
+			}
+			var err error
+			tracker, sessionCount, withheld, err = collectToolTimings(adaptersMap, args.Source, args.ProjectPath, since, args.Limit, args.MaxSlowest, func(sessions []adapters.Session) ([]adapters.Session, []string) {
+				return consent.filterSessions(ctx, req.Session, sessions)
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		result := map[string]interface{}{
+			"session_count": sessionCount,
+			"tools":         tracker.Timings(),
+			"slowest_calls": tracker.Slowest(),
+		}
+		if args.SessionID != "" {
+			result["session_id"] = args.SessionID
+			result["source"] = args.Source
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestCollectToolTimings(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	toolCall := func(id, command string, at time.Time) adapters.Message {
+		return adapters.Message{Role: "assistant", Timestamp: at, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": id, "name": "Bash", "input": map[string]interface{}{"command": command}},
+			},
+		}}
+	}
+	toolResult := func(id string, at time.Time) adapters.Message {
+		return adapters.Message{Role: "user", Timestamp: at, Metadata: map[string]interface{}{
+			"raw_content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": "ok"},
+			},
+		}}
+	}
+
+	stub := newStubAdapter([]adapters.Session{
+		{ID: "recent", Source: "claude", ProjectPath: "/work/app", Timestamp: ts},
+		{ID: "old", Source: "claude", ProjectPath: "/work/app", Timestamp: ts.AddDate(0, 0, -30)},
+		{ID: "private", Source: "claude", ProjectPath: "/work/secret", Timestamp: ts},
+	}, map[string][]adapters.Message{
+		"recent":  {toolCall("t1", "go test ./...", ts), toolResult("t1", ts.Add(40*time.Second))},
+		"old":     {toolCall("t2", "make", ts), toolResult("t2", ts.Add(time.Hour))},
+		"private": {toolCall("t3", "make", ts), toolResult("t3", ts.Add(time.Hour))},
+	})
+	filter := func(sessions []adapters.Session) ([]adapters.Session, []string) {
+		var kept []adapters.Session
+		var withheld []string
+		for _, s := range sessions {
+			if s.ProjectPath == "/work/secret" {
+				withheld = appendUnique(withheld, s.ProjectPath)
+				continue
+			}
+			kept = append(kept, s)
+		}
+		return kept, withheld
+	}
+
+	tracker, sessionCount, withheld, err := collectToolTimings(map[string]adapters.SessionAdapter{"claude": stub}, "", "", ts.AddDate(0, 0, -7), 10, 5, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessionCount != 1 || len(withheld) != 1 || withheld[0] != "/work/secret" {
+		t.Fatalf("expected 1 session and the secret project withheld, got %d and %v", sessionCount, withheld)
+	}
+	timings := tracker.Timings()
+	if len(timings) != 1 || timings[0].Tool != "Bash" || timings[0].TotalMS != 40000 {
+		t.Fatalf("expected 40s of Bash, got %+v", timings)
+	}
+	if slowest := tracker.Slowest(); len(slowest) != 1 || slowest[0].SessionID != "recent" {
+		t.Fatalf("expected the recent session's call, got %+v", slowest)
+	}
+
+	if _, _, _, err := collectToolTimings(map[string]adapters.SessionAdapter{"claude": stub}, "gemini", "", time.Time{}, 10, 5, filter); err == nil {
+		t.Fatal("expected an error for an unavailable source")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// usagePeriods maps each usage_stats period to how far back it reaches.
+// "all" has no limit.
+var usagePeriods = map[string]time.Duration{
+	"week":    7 * 24 * time.Hour,
+	"month":   30 * 24 * time.Hour,
+	"quarter": 90 * 24 * time.Hour,
+	"year":    365 * 24 * time.Hour,
+	"all":     0,
+}
+
+// usageGroup is the activity within one day, week, month, source, or project.
+type usageGroup struct {
+	Name            string `json:"name,omitempty"`
+	SessionsStarted int    `json:"sessions_started"`
+	ActiveSessions  int    `json:"active_sessions"`
+	ActiveProjects  int    `json:"active_projects"`
+	Messages        int    `json:"messages"`
+	Prompts         int    `json:"prompts"`
+	ToolCalls       int    `json:"tool_calls"`
+}
+
+// summarizeUsage merges per-project usage rows into one group per row group,
+// leaving out projects allowed rejects. It returns the groups in row order and
+// the projects left out.
+func summarizeUsage(rows []search.UsageRow, allowed func(projectPath string) bool) ([]usageGroup, []string) {
+	groups := []usageGroup{}
+	verdicts := make(map[string]bool)
+	var withheld []string
+	for _, row := range rows {
+		ok, seen := verdicts[row.ProjectPath]
+		if !seen {
+			ok = allowed(row.ProjectPath)
+			verdicts[row.ProjectPath] = ok
+			if !ok {
+				withheld = append(withheld, row.ProjectPath)
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].Name != row.Group {
+			groups = append(groups, usageGroup{Name: row.Group})
+		}
+		group := &groups[len(groups)-1]
+		group.SessionsStarted += row.SessionsStarted
+		group.ActiveSessions += row.ActiveSessions
+		group.Messages += row.Messages
+		group.Prompts += row.Prompts
+		group.ToolCalls += row.ToolCalls
+		if row.ActiveSessions > 0 {
+			group.ActiveProjects++
+		}
+	}
+	sort.Strings(withheld)
+	return groups, withheld
+}
+
+// Tool 35: usage_stats
+type usageStatsArgs struct {
+	Period      string `json:"period,omitempty" jsonschema:"How far back to look: week, month, quarter, year, or all (default: month, the last 30 days)"`
+	GroupBy     string `json:"group_by,omitempty" jsonschema:"How to break down the totals: day, week, month, source, or project (default: day)"`
+	Source      string `json:"source,omitempty" jsonschema:"Filter by source name (claude, gemini, codex, opencode, mistral, copilot). Leave empty for all sources."`
+	ProjectPath string `json:"project_path,omitempty" jsonschema:"Filter by project directory path. Leave empty for all projects."`
+}
+
+func addUsageStatsTool(server *mcp.Server, adaptersMap map[string]adapters.SessionAdapter, searchCache search.Store, consent *projectConsent) {
+	addTool(server, &mcp.Tool{
+		Name:        "usage_stats",
+		Description: "Personal usage analytics across all sources: sessions started, active sessions and projects, messages, user prompts, and tool calls, broken down by day, week, month, source, or project. Computed from the search index, so repeated calls are fast.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args usageStatsArgs) (*mcp.CallToolResult, any, error) {
+		if args.Period == "" {
+			args.Period = "month"
+		}
+		if args.GroupBy == "" {
+			args.GroupBy = search.UsageByDay
+		}
+		window, ok := usagePeriods[args.Period]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown period %q (expected one of: week, month, quarter, year, all)", args.Period)
+		}
+		if err := search.ValidateUsageGrouping(args.GroupBy); err != nil {
+			return nil, nil, err
+		}
+		if args.Source != "" {
+			if _, ok := adaptersMap[args.Source]; !ok {
+				return nil, nil, adapters.SourceUnavailableError(args.Source)
+			}
+		}
+		var since time.Time
+		if window > 0 {
+			since = time.Now().Add(-window)
+		}
+
+		// Bring the index up to date; only changed sessions are read
+		if err := indexSessions(adaptersMap, searchCache, args.Source, args.ProjectPath); err != nil {
+			log.Printf("Warning: indexing error: %v", err)
+		}
+
+		rows, err := searchCache.Usage(args.GroupBy, since, args.Source, args.ProjectPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalRows, err := searchCache.Usage("", since, args.Source, args.ProjectPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		allowed := func(projectPath string) bool {
+			return consent.allowed(ctx, req.Session, projectPath)
+		}
+		groups, withheld := summarizeUsage(rows, allowed)
+		totals, _ := summarizeUsage(totalRows, allowed)
+		total := usageGroup{}
+		if len(totals) > 0 {
+			total = totals[0]
+		}
+
+		result := map[string]interface{}{
+			"period":   args.Period,
+			"group_by": args.GroupBy,
+			"total":    total,
+			"groups":   groups,
+		}
+		if !since.IsZero() {
+			result["since"] = since.Format(time.RFC3339)
+		}
+		if len(withheld) > 0 {
+			result["withheld_projects"] = withheld
+		}
+
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+	"github.com/yoavf/ai-sessions-mcp/search"
+)
+
+// minIndexPollInterval keeps polling from turning into a busy loop over
+// directories that may live on slow network storage.
+const minIndexPollInterval = 5 * time.Second
+
+// indexPollInterval parses the configured index_poll_interval. Zero means
+// polling is off and sessions are only indexed when a search needs them.
+func (c *ServerConfig) indexPollInterval() (time.Duration, error) {
+	if c.IndexPollInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(c.IndexPollInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index_poll_interval %q: %w", c.IndexPollInterval, err)
+	}
+	if interval < minIndexPollInterval {
+		return 0, fmt.Errorf("index_poll_interval must be at least %s", minIndexPollInterval)
+	}
+	return interval, nil
+}
+
+// backgroundIndexer keeps the search index current from a goroutine started
+// with the server, so searches don't pay for indexing. It makes a full pass
+// at startup, then again whenever a search asks for a refresh and, when
+// polling is configured, every poll interval. Refreshes skip sources a
+// sessionWatcher keeps current. Passes are skipped in low-power mode.
+type backgroundIndexer struct {
+	adaptersMap map[string]adapters.SessionAdapter
+	cache       search.Store
+
+	ready     chan struct{} // closed when the first pass finishes
+	readyOnce sync.Once
+	refresh   chan struct{} // a pending refresh request; buffered so requests coalesce
+	fullPass  chan struct{} // a pending request to pass over every source, buffered likewise
+	watched   map[string]bool
+
+	mu     sync.Mutex
+	status indexerStatus
+}
+
+// indexerStatus is what get_diagnostics shows about background indexing.
+type indexerStatus struct {
+	State           string     `json:"state"` // "warming" until the first pass finishes, then "ready"
+	Running         bool       `json:"running"`
+	Passes          int        `json:"passes"`
+	SessionsChecked int        `json:"sessions_checked"`
+	LastPassAt      *time.Time `json:"last_pass_at,omitempty"`
+	LastPassMS      int64      `json:"last_pass_ms"`
+	WatchedSources  []string   `json:"watched_sources,omitempty"` // kept current by filesystem notifications
+}
+
+func newBackgroundIndexer(adaptersMap map[string]adapters.SessionAdapter, cache search.Store) *backgroundIndexer {
+	return &backgroundIndexer{
+		adaptersMap: adaptersMap,
+		cache:       cache,
+		ready:       make(chan struct{}),
+		refresh:     make(chan struct{}, 1),
+		fullPass:    make(chan struct{}, 1),
+		watched:     make(map[string]bool),
+		status:      indexerStatus{State: "warming"},
+	}
+}
+
+// run indexes every source until ctx is cancelled: once at startup, then on
+// each refresh request, and every interval when it is non-zero. Polling
+// relies on file modification times rather than filesystem notifications, so
+// it also works for session directories on network storage (NFS, SMB), where
+// change events are unreliable. Unchanged sessions are skipped, so each pass
+// after the first only lists sessions and stats their files.
+func (b *backgroundIndexer) run(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	sources := b.adaptersMap
+	for {
+		if !lowPower.Load() {
+			b.pass(ctx, sources)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			sources = b.adaptersMap
+		case <-b.fullPass:
+			sources = b.adaptersMap
+		case <-b.refresh:
+			sources = b.unwatched()
+		}
+	}
+}
+
+// pass indexes new and changed sessions from the given sources.
+func (b *backgroundIndexer) pass(ctx context.Context, sources map[string]adapters.SessionAdapter) {
+	b.mu.Lock()
+	b.status.Running = true
+	b.mu.Unlock()
+
+	start := time.Now()
+	checked := 0
+	opts := indexOptions{progress: func(_, total int) { checked = total }}
+	if err := indexSessionsContext(ctx, sources, b.cache, "", "", opts); err != nil && ctx.Err() == nil {
+		log.Printf("Warning: indexing error: %v", err)
+	}
+
+	b.mu.Lock()
+	b.status.Running = false
+	if ctx.Err() == nil {
+		finished := time.Now()
+		b.status.State = "ready"
+		b.status.Passes++
+		b.status.SessionsChecked = checked
+		b.status.LastPassAt = &finished
+		b.status.LastPassMS = finished.Sub(start).Milliseconds()
+	}
+	b.mu.Unlock()
+	if ctx.Err() == nil {
+		b.readyOnce.Do(func() { close(b.ready) })
+	}
+}
+
+// setWatched records the sources a sessionWatcher keeps current, so
+// refreshes no longer list them.
+func (b *backgroundIndexer) setWatched(sources []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, source := range sources {
+		b.watched[source] = true
+	}
+	b.status.WatchedSources = append([]string(nil), sources...)
+}
+
+// unwatched returns the sources no sessionWatcher keeps current.
+func (b *backgroundIndexer) unwatched() map[string]adapters.SessionAdapter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sources := make(map[string]adapters.SessionAdapter)
+	for name, adapter := range b.adaptersMap {
+		if !b.watched[name] {
+			sources[name] = adapter
+		}
+	}
+	return sources
+}
+
+// requestRefresh asks for another pass without waiting for it. It does
+// nothing when every source is watched.
+func (b *backgroundIndexer) requestRefresh() {
+	if len(b.unwatched()) == 0 {
+		return
+	}
+	select {
+	case b.refresh <- struct{}{}:
+	default: // a refresh is already pending
+	}
+}
+
+// requestFullPass asks for a pass over every source, watched or not, without
+// waiting for it.
+func (b *backgroundIndexer) requestFullPass() {
+	select {
+	case b.fullPass <- struct{}{}:
+	default: // a full pass is already pending
+	}
+}
+
+// awaitWarm prepares the index for a search. While the index is cold (the
+// first pass hasn't finished and nothing was indexed before), it waits for the
+// first pass so the search has something to find. Otherwise it returns at
+// once, searching what is already indexed, and asks for a refresh so sessions
+// changed since the last pass show up in later searches. In low-power mode
+// nothing indexes in the background, so it indexes changed sessions itself.
+func (b *backgroundIndexer) awaitWarm(ctx context.Context) error {
+	if lowPower.Load() {
+		return indexSessionsShared(ctx, b.adaptersMap, b.cache, "", "")
+	}
+	select {
+	case <-b.ready:
+		b.requestRefresh()
+		return nil
+	default:
+	}
+
+	info, err := b.cache.Info()
+	if err == nil && info.Sessions > 0 {
+		b.requestRefresh()
+		return nil
+	}
+	select {
+	case <-b.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// currentStatus returns a snapshot of the indexer's state.
+func (b *backgroundIndexer) currentStatus() indexerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+func TestIndexPollInterval(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"2m", 2 * time.Minute, false},
+		{"1s", 0, true},
+		{"often", 0, true},
+	} {
+		config := &ServerConfig{IndexPollInterval: tt.value}
+		got, err := config.indexPollInterval()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("indexPollInterval(%q) = %v, %v; want %v (error: %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBackgroundIndexerPollsUntilCancelled(t *testing.T) {
+	cache := newTestCache(t)
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+	adapter := newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", FilePath: sessionFile, Timestamp: time.Now()}},
+		map[string][]adapters.Message{"sess-1": {{Role: "user", Content: "polled keyword"}}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		newBackgroundIndexer(map[string]adapters.SessionAdapter{"stub": adapter}, cache).run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("indexer did not stop after cancellation")
+	}
+
+	if adapter.listCalls < 2 {
+		t.Fatalf("expected several polls, got %d", adapter.listCalls)
+	}
+	if adapter.getCalls["sess-1"] != 1 {
+		t.Fatalf("expected the unchanged session to be indexed once, got %d", adapter.getCalls["sess-1"])
+	}
+	results, err := cache.Search("polled keyword", "", "", 10)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected the polled session to be searchable, got %v (%v)", results, err)
+	}
+}
+
+func TestBackgroundIndexerAwaitWarm(t *testing.T) {
+	cache := newTestCache(t)
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+	adapter := newStubAdapter(
+		[]adapters.Session{{ID: "sess-1", Source: "stub", FilePath: sessionFile, Timestamp: time.Now()}},
+		map[string][]adapters.Message{"sess-1": {{Role: "user", Content: "warm keyword"}}},
+	)
+	indexer := newBackgroundIndexer(map[string]adapters.SessionAdapter{"stub": adapter}, cache)
+
+	// A cold index with no indexer running makes searches wait
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := indexer.awaitWarm(short); err == nil {
+		t.Fatal("expected awaitWarm to wait while the index is cold")
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go indexer.run(ctx, 0)
+	if err := indexer.awaitWarm(ctx); err != nil {
+		t.Fatalf("awaitWarm returned error: %v", err)
+	}
+	results, err := cache.Search("warm keyword", "", "", 10)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected the session to be indexed before the search, got %v (%v)", results, err)
+	}
+	if status := indexer.currentStatus(); status.State != "ready" || status.Passes < 1 || status.SessionsChecked != 1 {
+		t.Fatalf("unexpected indexer status: %+v", status)
+	}
+
+	// Once the index has sessions, a new indexer doesn't hold up searches
+	warm := newBackgroundIndexer(map[string]adapters.SessionAdapter{"stub": adapter}, cache)
+	if err := warm.awaitWarm(short); err != nil {
+		t.Fatalf("expected a warm index not to wait, got %v", err)
+	}
+}
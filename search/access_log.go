@@ -0,0 +1,96 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccessEntry records one read of a session page or message range by an MCP client.
+type AccessEntry struct {
+	SessionID     string    `json:"session_id"`
+	Source        string    `json:"source"`
+	ClientName    string    `json:"client_name"`
+	ClientVersion string    `json:"client_version,omitempty"`
+	Page          int       `json:"page"`
+	PageSize      int       `json:"page_size"`   // 0 for reads of a message range rather than a page
+	StartIndex    int       `json:"start_index"` // index of the first message read
+	MessageCount  int       `json:"message_count"`
+	AccessedAt    time.Time `json:"accessed_at"`
+}
+
+// RecordAccess appends an entry to the access log. For page reads StartIndex
+// defaults to the page's first message.
+func (c *Cache) RecordAccess(entry AccessEntry) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if entry.AccessedAt.IsZero() {
+		entry.AccessedAt = time.Now()
+	}
+	if entry.StartIndex == 0 {
+		entry.StartIndex = entry.Page * entry.PageSize
+	}
+
+	_, err := c.db.Exec(`
+		INSERT INTO access_log (session_id, source, client_name, client_version, page, page_size, start_index, message_count, accessed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.SessionID, entry.Source, entry.ClientName, entry.ClientVersion,
+		entry.Page, entry.PageSize, entry.StartIndex, entry.MessageCount, entry.AccessedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to record access: %w", err)
+	}
+	return nil
+}
+
+// AccessLog returns access log entries, newest first.
+// Empty sessionID or source means no filter on that field. A limit of 0 means no limit.
+func (c *Cache) AccessLog(sessionID, source string, limit int) ([]AccessEntry, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if sessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, sessionID)
+	}
+	if source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, source)
+	}
+
+	query := `
+		SELECT session_id, source, client_name, COALESCE(client_version, ''), page, page_size,
+			COALESCE(start_index, page * page_size), message_count, accessed_at
+		FROM access_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY accessed_at DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AccessEntry
+	for rows.Next() {
+		var (
+			entry      AccessEntry
+			accessedAt int64
+		)
+		if err := rows.Scan(&entry.SessionID, &entry.Source, &entry.ClientName, &entry.ClientVersion,
+			&entry.Page, &entry.PageSize, &entry.StartIndex, &entry.MessageCount, &accessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		entry.AccessedAt = time.UnixMilli(accessedAt)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
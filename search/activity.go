@@ -0,0 +1,212 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Usage groupings accepted by Usage. The time groupings use local time.
+const (
+	UsageByDay     = "day"
+	UsageByWeek    = "week"
+	UsageByMonth   = "month"
+	UsageBySource  = "source"
+	UsageByProject = "project"
+)
+
+// UsageGroupings lists the groupings Usage accepts.
+func UsageGroupings() []string {
+	return []string{UsageByDay, UsageByWeek, UsageByMonth, UsageBySource, UsageByProject}
+}
+
+// ValidateUsageGrouping returns an error if groupBy isn't one of UsageGroupings.
+func ValidateUsageGrouping(groupBy string) error {
+	if groupBy == "" {
+		return fmt.Errorf("group_by is required")
+	}
+	_, err := usageKey(groupBy, "")
+	return err
+}
+
+// usageKey returns the SQL expression naming the group a row belongs to, with
+// column holding the row's time in Unix seconds. An empty groupBy puts every
+// row in one group.
+func usageKey(groupBy, column string) (string, error) {
+	switch groupBy {
+	case "":
+		return "''", nil
+	case UsageByDay:
+		return "strftime('%Y-%m-%d', " + column + ", 'unixepoch', 'localtime')", nil
+	case UsageByWeek:
+		return "strftime('%Y-W%W', " + column + ", 'unixepoch', 'localtime')", nil
+	case UsageByMonth:
+		return "strftime('%Y-%m', " + column + ", 'unixepoch', 'localtime')", nil
+	case UsageBySource:
+		return "s.source", nil
+	case UsageByProject:
+		return "s.project_path", nil
+	}
+	return "", fmt.Errorf("unknown group_by %q (expected one of: %s)", groupBy, strings.Join(UsageGroupings(), ", "))
+}
+
+// IndexSessionActivity records how many messages, user prompts, and tool calls
+// a session had in each hour, replacing any previously recorded for it.
+// Messages without a timestamp count toward the hour the session started.
+// The session must already be indexed with IndexSession.
+func (c *Cache) IndexSessionActivity(session adapters.Session, messages []adapters.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	type hourActivity struct{ messages, prompts, toolCalls int }
+	hours := make(map[int64]*hourActivity)
+	for _, msg := range messages {
+		ts := msg.Timestamp
+		if ts.IsZero() {
+			ts = session.Timestamp
+		}
+		hour := ts.Truncate(time.Hour).Unix()
+		activity, ok := hours[hour]
+		if !ok {
+			activity = &hourActivity{}
+			hours[hour] = activity
+		}
+		activity.messages++
+		activity.toolCalls += len(adapters.ExtractToolCalls(msg))
+		if msg.Role == "user" && strings.TrimSpace(msg.Content) != "" && len(adapters.ExtractToolResults(msg)) == 0 {
+			activity.prompts++
+		}
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM session_activity WHERE session_id = ?", session.ID); err != nil {
+		return fmt.Errorf("failed to delete old session activity: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO session_activity (session_id, hour, messages, prompts, tool_calls) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for hour, activity := range hours {
+		if _, err := stmt.Exec(session.ID, hour, activity.messages, activity.prompts, activity.toolCalls); err != nil {
+			return fmt.Errorf("failed to insert session activity: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UsageRow is the activity of one project within one group.
+type UsageRow struct {
+	Group           string
+	ProjectPath     string
+	SessionsStarted int // sessions that started in the group
+	ActiveSessions  int // sessions with any message in the group
+	Messages        int
+	Prompts         int
+	ToolCalls       int
+}
+
+// Usage aggregates indexed session activity since a time, grouped by groupBy
+// and split by project so callers can leave projects out. An empty groupBy
+// returns one group covering the whole range. Empty source or projectPath means
+// no filter on that field. Rows are ordered by group, then project.
+func (c *Cache) Usage(groupBy string, since time.Time, source, projectPath string) ([]UsageRow, error) {
+	activityKey, err := usageKey(groupBy, "a.hour")
+	if err != nil {
+		return nil, err
+	}
+	startKey, _ := usageKey(groupBy, "s.timestamp")
+
+	filters := ""
+	var filterArgs []interface{}
+	if source != "" {
+		filters += " AND s.source = ?"
+		filterArgs = append(filterArgs, source)
+	}
+	if projectPath != "" {
+		filters += " AND project_matches(?, s.project_path)"
+		filterArgs = append(filterArgs, projectPath)
+	}
+	// The last hour starting before since still overlaps the range
+	sinceHour := since.Truncate(time.Hour).Unix()
+
+	rowsByKey := make(map[[2]string]*UsageRow)
+	var ordered []*UsageRow
+	row := func(group, project string) *UsageRow {
+		key := [2]string{group, project}
+		r, ok := rowsByKey[key]
+		if !ok {
+			r = &UsageRow{Group: group, ProjectPath: project}
+			rowsByKey[key] = r
+			ordered = append(ordered, r)
+		}
+		return r
+	}
+
+	activity, err := c.db.Query(`
+		SELECT `+activityKey+`, s.project_path, COUNT(DISTINCT a.session_id),
+		       SUM(a.messages), SUM(a.prompts), SUM(a.tool_calls)
+		FROM session_activity a
+		JOIN sessions s ON s.id = a.session_id
+		WHERE a.hour >= ?`+filters+`
+		GROUP BY 1, 2`, append([]interface{}{sinceHour}, filterArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session activity: %w", err)
+	}
+	defer activity.Close()
+	for activity.Next() {
+		var group, project string
+		var active, messages, prompts, toolCalls int
+		if err := activity.Scan(&group, &project, &active, &messages, &prompts, &toolCalls); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		r := row(group, project)
+		r.ActiveSessions, r.Messages, r.Prompts, r.ToolCalls = active, messages, prompts, toolCalls
+	}
+	if err := activity.Err(); err != nil {
+		return nil, err
+	}
+
+	started, err := c.db.Query(`
+		SELECT `+startKey+`, s.project_path, COUNT(*)
+		FROM sessions s
+		WHERE s.timestamp >= ?`+filters+`
+		GROUP BY 1, 2`, append([]interface{}{since.Unix()}, filterArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session starts: %w", err)
+	}
+	defer started.Close()
+	for started.Next() {
+		var group, project string
+		var count int
+		if err := started.Scan(&group, &project, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row(group, project).SessionsStarted = count
+	}
+	if err := started.Err(); err != nil {
+		return nil, err
+	}
+
+	rows := make([]UsageRow, len(ordered))
+	for i, r := range ordered {
+		rows[i] = *r
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Group != rows[j].Group {
+			return rows[i].Group < rows[j].Group
+		}
+		return rows[i].ProjectPath < rows[j].ProjectPath
+	})
+	return rows, nil
+}
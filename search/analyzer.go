@@ -0,0 +1,158 @@
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// indexVersion numbers the way Tokenize turns text into tokens. Bump it when
+// tokenization changes, so existing indexes are rebuilt.
+const indexVersion = 1
+
+// Analyzer configures how text is turned into tokens, at index and query
+// time alike. The zero Analyzer neither stems nor drops stopwords.
+type Analyzer struct {
+	// Stemming reduces English words to their stems, so "connections" and
+	// "connecting" match "connect"
+	Stemming bool `json:"stemming,omitempty"`
+
+	// EnglishStopwords leaves common English words, such as "the" and
+	// "with", out of the index and queries
+	EnglishStopwords bool `json:"english_stopwords,omitempty"`
+
+	// Stopwords are more words to leave out, matched case-insensitively
+	Stopwords []string `json:"stopwords,omitempty"`
+}
+
+// englishStopwords are the words EnglishStopwords leaves out. Words of one
+// letter are always left out.
+var englishStopwords = []string{
+	"about", "after", "all", "also", "am", "an", "and", "any", "are", "as", "at",
+	"be", "been", "but", "by", "can", "could", "did", "do", "does", "for", "from",
+	"had", "has", "have", "he", "her", "his", "how", "if", "in", "into", "is",
+	"it", "its", "just", "me", "my", "no", "not", "of", "on", "or", "our", "she",
+	"so", "than", "that", "the", "their", "them", "then", "there", "these",
+	"they", "this", "to", "was", "we", "were", "what", "when", "where", "which",
+	"while", "who", "will", "with", "would", "you", "your",
+}
+
+// activeAnalyzer is the analyzer in use. Tokenization is shared by the whole
+// package, since messages are also matched outside any index.
+var activeAnalyzer atomic.Pointer[analyzerState]
+
+// analyzerState is an Analyzer ready to apply.
+type analyzerState struct {
+	stemming  bool
+	stopwords map[string]bool
+}
+
+// compile normalizes the analyzer for use.
+func (a Analyzer) compile() *analyzerState {
+	state := &analyzerState{stemming: a.Stemming, stopwords: make(map[string]bool)}
+	if a.EnglishStopwords {
+		for _, word := range englishStopwords {
+			state.stopwords[word] = true
+		}
+	}
+	for _, word := range a.Stopwords {
+		state.stopwords[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return state
+}
+
+// version identifies the tokens the analyzer produces, along with
+// indexVersion, so an index built with other settings is noticed.
+func (a Analyzer) version() float64 {
+	state := a.compile()
+	words := make([]string, 0, len(state.stopwords))
+	for word := range state.stopwords {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d|%t|%s", indexVersion, state.stemming, strings.Join(words, ","))
+	return float64(h.Sum32())
+}
+
+// normalize applies the active analyzer to a lower-cased token, returning ""
+// for a stopword.
+func normalize(token string) string {
+	state := activeAnalyzer.Load()
+	if state == nil {
+		return token
+	}
+	if state.stopwords[token] {
+		return ""
+	}
+	if state.stemming {
+		return stem(token)
+	}
+	return token
+}
+
+// describeAnalyzer explains the active analyzer for DescribeSyntax, so clients
+// know why other forms of a word match and why a query of stopwords has no
+// terms.
+func describeAnalyzer() SyntaxRule {
+	state := activeAnalyzer.Load()
+	if state == nil || (!state.stemming && len(state.stopwords) == 0) {
+		return SyntaxRule{
+			Name:        "analyzer",
+			Description: "Keywords match only as written: words aren't reduced to their stems, and no stopwords are left out.",
+		}
+	}
+
+	var parts []string
+	if state.stemming {
+		parts = append(parts, "English words are reduced to their stems in the index and in queries alike, so other forms of a word match it, including inside phrases.")
+	}
+	if len(state.stopwords) > 0 {
+		words := make([]string, 0, len(state.stopwords))
+		for word := range state.stopwords {
+			words = append(words, word)
+		}
+		slices.Sort(words)
+		parts = append(parts, fmt.Sprintf("These stopwords are left out of the index and queries, so a query made only of them fails with \"no valid search terms\": %s.", strings.Join(words, ", ")))
+	}
+	rule := SyntaxRule{Name: "analyzer", Description: strings.Join(parts, " ")}
+	if state.stemming {
+		rule.Example = "connection refusing"
+	}
+	return rule
+}
+
+// SetAnalyzer switches tokenization to the analyzer's settings. When the
+// index was built with different settings, or by a different version of the
+// tokenizer, every session is marked for reindexing, so the next indexing
+// run rebuilds the index with the new tokens.
+func (c *Cache) SetAnalyzer(a Analyzer) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	activeAnalyzer.Store(a.compile())
+
+	version := a.version()
+	var indexed float64
+	err := c.db.QueryRow("SELECT value FROM search_stats WHERE key = 'analyzer_version'").Scan(&indexed)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Indexes from before analyzers were configurable used the defaults
+		indexed = Analyzer{}.version()
+	} else if err != nil {
+		return fmt.Errorf("failed to check analyzer version: %w", err)
+	}
+	if indexed == version {
+		return nil
+	}
+
+	if _, err := c.db.Exec("UPDATE sessions SET file_mtime = 0"); err != nil {
+		return fmt.Errorf("failed to mark sessions for reindexing: %w", err)
+	}
+	if _, err := c.db.Exec("INSERT OR REPLACE INTO search_stats (key, value) VALUES ('analyzer_version', ?)", version); err != nil {
+		return fmt.Errorf("failed to record analyzer version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Store is everything the server keeps in its index: searchable session
+// content, per-session metadata (files, outcomes, scopes), what adapters cache
+// about session files, and user data such as notes, bookmarks, and the access
+// log. Cache implements it on SQLite; other backends can be added with
+// RegisterBackend.
+type Store interface {
+	Close() error
+
+	// Indexing
+	NeedsReindex(sessionID string, filePath string) (bool, error)
+	IndexSession(session adapters.Session, content string) error
+	IndexMessageHashes(sessionID string, messages []adapters.Message) error
+	IndexSessionActivity(session adapters.Session, messages []adapters.Message) error
+	IndexSessionFiles(sessionID string, files []adapters.FileTouch) error
+	IndexFileChanges(session adapters.Session, changes []adapters.FileChange) error
+	IndexSessionOutcome(sessionID string, outcome adapters.SessionOutcome) error
+	IndexSessionModels(sessionID string, models []string) error
+	IndexSessionCosts(sessionID string, entries []adapters.CostEntry) error
+	IndexScopes(sessionID string, messages []adapters.Message) error
+	IndexMessages(sessionID string, messages []adapters.Message) error
+	ResetIndex(source string) (int, error)
+
+	// What adapters cache about session files, so they only parse changed
+	// files, read pages by seeking, and find sessions without a search
+	adapters.MetadataCache
+	adapters.OffsetCache
+	adapters.PathCache
+
+	// Search
+	SetDefaultRanker(name string) error
+	SetAnalyzer(a Analyzer) error
+	DefaultRanker() string
+	Search(query string, source string, projectPath string, limit int) ([]SearchResult, error)
+	SearchWithRanker(query string, source string, projectPath string, limit int, rankerName string) ([]SearchResult, error)
+	SearchInScope(query string, scope string, source string, projectPath string, limit int, rankerName string) ([]SearchResult, error)
+	LookupContentHash(hash string) ([]ContentRef, error)
+	FindSessionsByFile(path string, prefix bool, source string, projectPath string, limit int) ([]FileMatch, error)
+	FileHistory(path string, prefix bool, source string, projectPath string, since time.Time, limit int) ([]FileHistoryEntry, error)
+	RelatedSessions(sessionID string, limit int) ([]RelatedSession, error)
+	SharedKeywords(contentA, contentB string, limit int) ([]Keyword, error)
+	SessionOutcomes(sessions []adapters.Session) (map[string]adapters.SessionOutcome, error)
+	SessionCosts(sessions []adapters.Session) (map[string][]adapters.CostEntry, error)
+	Usage(groupBy string, since time.Time, source, projectPath string) ([]UsageRow, error)
+
+	// User data
+	AddNote(note Note) (Note, error)
+	Notes(sessionID, source string) ([]Note, error)
+	NotesForSessions(sessions []adapters.Session) (map[string][]Note, error)
+	AddBookmark(bookmark Bookmark) (Bookmark, error)
+	Bookmarks(sessionID, source, projectPath string, limit int) ([]Bookmark, error)
+	RecordAccess(entry AccessEntry) error
+	AccessLog(sessionID, source string, limit int) ([]AccessEntry, error)
+	ChangeCheckpoint(clientName string) (checkedAt time.Time, ok bool, err error)
+	SetChangeCheckpoint(clientName string, checkedAt time.Time) error
+
+	// Forgetting and maintenance
+	ForgetSession(sessionID, source string, now time.Time) (removed bool, err error)
+	UnforgetSession(sessionID, source string) (bool, error)
+	IsForgotten(sessionID, source string) (bool, error)
+	ForgottenSessions() ([]ForgottenSession, error)
+	ReviewStaleSessions(now time.Time, grace time.Duration) (StaleReview, error)
+	Evict(now time.Time, r Retention) (Eviction, error)
+	Vacuum() (before, after int64, err error)
+	Info() (IndexInfo, error)
+	Status() (IndexStatus, error)
+	Snapshot(path string) error
+}
+
+var _ Store = (*Cache)(nil)
+
+// Default backend names.
+const (
+	BackendSQLite = "sqlite" // a SQLite database file; the default
+	BackendMemory = "memory" // an in-memory SQLite database, discarded on Close
+)
+
+// BackendOpener opens a store. dsn says where the store lives, in whatever
+// form the backend needs: a file path, a connection string, or nothing.
+type BackendOpener func(dsn string) (Store, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]BackendOpener)
+)
+
+func init() {
+	RegisterBackend(BackendSQLite, func(dsn string) (Store, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("the %s backend needs a database path", BackendSQLite)
+		}
+		return NewCache(dsn)
+	})
+	RegisterBackend(BackendMemory, func(string) (Store, error) {
+		return NewMemoryCache()
+	})
+}
+
+// RegisterBackend makes a storage backend available under the given name.
+// Like adapters.Register, it is intended to be called from init functions,
+// so forks and files behind build tags can add backends (a shared database
+// for team use, say) without touching the server.
+// RegisterBackend panics if name is empty, open is nil, or name is already registered.
+func RegisterBackend(name string, open BackendOpener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if name == "" {
+		panic("search: RegisterBackend called with empty name")
+	}
+	if open == nil {
+		panic("search: RegisterBackend opener is nil for " + name)
+	}
+	if _, dup := backends[name]; dup {
+		panic(fmt.Sprintf("search: RegisterBackend called twice for %q", name))
+	}
+	backends[name] = open
+}
+
+// Backends returns the sorted names of all registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens a store with the named backend.
+func Open(backend, dsn string) (Store, error) {
+	backendsMu.RLock()
+	open, ok := backends[backend]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown cache backend %q (available: %s)", backend, strings.Join(Backends(), ", "))
+	}
+	return open(dsn)
+}
@@ -57,39 +57,121 @@ func (s *BM25Scorer) Score(queryTerms []string, termFreqs map[string]int, docLen
 	return score
 }
 
-// Tokenize converts text to normalized tokens for indexing/searching
+// Tokenize converts text to normalized tokens for indexing. Text is split
+// into words on anything but letters, digits, underscores, and dots, so code
+// identifiers and dotted paths stay whole. Each word is indexed whole and then
+// by its parts: the segments of a dotted path, the pieces of a snake_case
+// name, and the words of a camelCase one. "getSessionPage" is findable as
+// itself and as "session", and "os.path.join" as itself and as "path".
 func Tokenize(text string) []string {
-	text = strings.ToLower(text)
+	var tokens []string
+	for _, word := range splitWords(text) {
+		tokens = appendParts(tokens, word)
+	}
+	return tokens
+}
 
+// queryTokens converts query text to tokens, one per word. Words aren't split
+// into their parts, so "getSessionPage" finds that identifier rather than
+// every session mentioning "page". Tokens are taken whole from text this way
+// wherever they need to line up one after another, as in phrases.
+func queryTokens(text string) []string {
 	var tokens []string
-	var current strings.Builder
-
-	for _, r := range text {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) {
-			current.WriteRune(r)
-		} else {
-			if current.Len() > 0 {
-				token := current.String()
-				// Skip very short tokens (stopwords handled implicitly)
-				if len(token) > 1 {
-					tokens = append(tokens, token)
-				}
-				current.Reset()
+	for _, word := range splitWords(text) {
+		tokens = appendToken(tokens, word)
+	}
+	return tokens
+}
+
+// splitWords splits text into words of letters, digits, underscores, and
+// dots, trimming the dots and underscores around them, such as a sentence's
+// final period.
+func splitWords(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if word := strings.Trim(field, "._"); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// appendParts appends word and then its parts, recursively: dotted segments,
+// then snake_case pieces, then camelCase words.
+func appendParts(tokens []string, word string) []string {
+	tokens = appendToken(tokens, word)
+	var parts []string
+	switch {
+	case strings.Contains(word, "."):
+		parts = strings.Split(word, ".")
+	case strings.Contains(word, "_"):
+		parts = strings.Split(word, "_")
+	default:
+		camel := splitCamelCase(word)
+		if len(camel) == 1 {
+			return tokens
+		}
+		for _, part := range camel {
+			tokens = appendToken(tokens, part)
+		}
+		// An acronym may run into the next word, as in "SQLite", which
+		// splits into SQ and Lite; keep the two together as well
+		for i := 0; i+1 < len(camel); i++ {
+			if isUpper(camel[i]) && len(camel[i]) > 1 && !isUpper(camel[i+1]) && camel[i]+camel[i+1] != word {
+				tokens = appendToken(tokens, camel[i]+camel[i+1])
 			}
 		}
+		return tokens
 	}
+	for _, part := range parts {
+		if part = strings.Trim(part, "._"); part != "" {
+			tokens = appendParts(tokens, part)
+		}
+	}
+	return tokens
+}
 
-	// Don't forget last token
-	if current.Len() > 0 {
-		token := current.String()
-		if len(token) > 1 {
+// appendToken appends word, lowercased and normalized by the active
+// Analyzer, unless it is a single character or a stopword.
+func appendToken(tokens []string, word string) []string {
+	// Skip very short tokens (stopwords handled implicitly)
+	if token := strings.ToLower(word); len(token) > 1 {
+		if token = normalize(token); token != "" {
 			tokens = append(tokens, token)
 		}
 	}
-
 	return tokens
 }
 
+// isUpper reports whether s has no lower-case letters.
+func isUpper(s string) bool {
+	return strings.ToUpper(s) == s
+}
+
+// splitCamelCase splits a word before each upper-case letter that starts a
+// new word: "getSessionPage" into get, Session, Page, and "HTTPServer" into
+// HTTP, Server. Digits stay with the letters before them, as in "utf8Decode".
+func splitCamelCase(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
 // TermFrequency counts occurrences of each term in tokens
 func TermFrequency(tokens []string) map[string]int {
 	freqs := make(map[string]int)
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bookmark marks one message so it can be jumped back to without searching again.
+type Bookmark struct {
+	ID           int64     `json:"id"`
+	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
+	ProjectPath  string    `json:"project_path"`
+	MessageIndex int       `json:"message_index"`
+	Label        string    `json:"label"`
+	Preview      string    `json:"preview"` // start of the message when it was bookmarked
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AddBookmark stores a bookmark, replacing any earlier bookmark on the same
+// message, and returns it with its ID and creation time set.
+func (c *Cache) AddBookmark(bookmark Bookmark) (Bookmark, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if bookmark.CreatedAt.IsZero() {
+		bookmark.CreatedAt = time.Now()
+	}
+
+	err := c.db.QueryRow(`
+		INSERT INTO bookmarks (session_id, source, project_path, message_index, label, preview, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session_id, source, message_index) DO UPDATE SET
+			project_path = excluded.project_path,
+			label = excluded.label,
+			preview = excluded.preview,
+			created_at = excluded.created_at
+		RETURNING id
+	`, bookmark.SessionID, bookmark.Source, bookmark.ProjectPath, bookmark.MessageIndex,
+		bookmark.Label, bookmark.Preview, bookmark.CreatedAt.UnixMilli()).Scan(&bookmark.ID)
+	if err != nil {
+		return Bookmark{}, fmt.Errorf("failed to add bookmark: %w", err)
+	}
+	return bookmark, nil
+}
+
+// Bookmarks returns bookmarks, newest first. Empty sessionID, source, or
+// projectPath means no filter on that field. A limit of 0 means no limit.
+func (c *Cache) Bookmarks(sessionID, source, projectPath string, limit int) ([]Bookmark, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if sessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, sessionID)
+	}
+	if source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, source)
+	}
+	if projectPath != "" {
+		conditions = append(conditions, "project_matches(?, project_path)")
+		args = append(args, projectPath)
+	}
+
+	query := `
+		SELECT id, session_id, source, project_path, message_index, label, preview, created_at
+		FROM bookmarks`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var (
+			b         Bookmark
+			createdAt int64
+		)
+		if err := rows.Scan(&b.ID, &b.SessionID, &b.Source, &b.ProjectPath, &b.MessageIndex, &b.Label, &b.Preview, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		b.CreatedAt = time.UnixMilli(createdAt)
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
@@ -1,15 +1,22 @@
 package search
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/rivo/uniseg"
 	"github.com/yoavf/ai-sessions-mcp/adapters"
 	_ "modernc.org/sqlite"
 )
@@ -19,9 +26,20 @@ var schemaSQL string
 
 // Cache manages the search index and session cache
 type Cache struct {
-	db *sql.DB
+	db        *sql.DB
+	path      string    // database file; empty for in-memory caches
+	ranker    Ranker    // default ranker when a search doesn't name one
+	keepAlive *sql.Conn // holds an in-memory database open; nil for on-disk caches
+
+	// writeMu serializes writes. SQLite allows one writer at a time, and a
+	// transaction that reads before writing fails with SQLITE_BUSY, rather
+	// than waiting out busy_timeout, when another connection wrote first.
+	writeMu sync.Mutex
 }
 
+// memoryCacheSeq gives each in-memory cache its own database name.
+var memoryCacheSeq atomic.Int64
+
 // NewCache creates a new search cache with SQLite backend
 func NewCache(dbPath string) (*Cache, error) {
 	// Ensure directory exists
@@ -45,22 +63,317 @@ func NewCache(dbPath string) (*Cache, error) {
 		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
 	}
 
-	// Initialize schema
-	if _, err := db.Exec(schemaSQL); err != nil {
+	if err := initSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Cache{db: db, path: dbPath}, nil
+}
+
+// NewMemoryCache creates a search cache held entirely in memory, for CI or
+// one-shot use where writing to disk is undesirable. The index starts empty,
+// is filled lazily like the on-disk cache, and is discarded on Close.
+func NewMemoryCache() (*Cache, error) {
+	// A shared-cache memory database is visible to every pooled connection,
+	// which Search needs since it issues queries while iterating rows
+	dsn := fmt.Sprintf("file:aisessions-memory-%d?mode=memory&cache=shared", memoryCacheSeq.Add(1))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// The database only lives while at least one connection is open
+	keepAlive, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := initSchema(db); err != nil {
+		keepAlive.Close()
 		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		return nil, err
+	}
+
+	return &Cache{db: db, keepAlive: keepAlive}, nil
+}
+
+// schemaVersion numbers the layout of the cache database, recorded in its
+// user_version. Bump it whenever schema.sql or migrateSchema changes, so
+// databases from older versions are migrated when they are opened.
+const schemaVersion = 2
+
+// indexTables hold what is derived from session files, and are dropped when
+// the index has to be rebuilt. Notes, bookmarks, the access log, the file
+// change ledger, and forgotten and evicted sessions are kept.
+var indexTables = slices.Concat(sessionTables, []string{"sessions", "session_metadata", "message_offsets", "session_paths"})
+
+// initSchema creates tables and brings databases created by other versions up
+// to date. A database from a newer version, or one that fails to migrate, has
+// its index dropped and rebuilt from the session files, rather than failing
+// or being misread.
+func initSchema(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version == schemaVersion {
+		if _, err := db.Exec(schemaSQL); err != nil {
+			return fmt.Errorf("failed to initialize schema: %w", err)
+		}
+		return nil
+	}
+
+	if version > schemaVersion {
+		// A newer version may have indexed sessions in ways this one misreads
+		if err := dropIndex(db); err != nil {
+			return err
+		}
+	}
+	if err := upgradeSchema(db); err != nil {
+		if err := dropIndex(db); err != nil {
+			return err
+		}
+		if err := upgradeSchema(db); err != nil {
+			return err
+		}
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// upgradeSchema creates missing tables and runs the migrations.
+func upgradeSchema(db *sql.DB) error {
+	reshaped, err := dropReshapedTables(db)
+	if err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	if _, err := db.Exec(schemaSQL); err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
+	if err := migrateSchema(db); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	if reshaped {
+		// Refill the recreated tables
+		if _, err := db.Exec("UPDATE sessions SET file_mtime = 0"); err != nil {
+			return fmt.Errorf("failed to mark sessions for reindexing: %w", err)
+		}
+	}
+	return nil
+}
+
+// reshapedTables are index tables whose layout changed in ways ensureColumn
+// can't migrate, each with a column only its old layout has.
+var reshapedTables = map[string]string{
+	// Messages were indexed, and their text copied, once per scope
+	"message_documents":  "scope",
+	"message_term_index": "scope",
+}
 
-	return &Cache{db: db}, nil
+// dropReshapedTables drops the reshapedTables still in their old layout, so
+// schema.sql recreates them. It reports whether any were dropped.
+func dropReshapedTables(db *sql.DB) (bool, error) {
+	dropped := false
+	for table, oldColumn := range reshapedTables {
+		old, err := hasColumn(db, table, oldColumn)
+		if err != nil {
+			return false, err
+		}
+		if !old {
+			continue
+		}
+		if _, err := db.Exec("DROP TABLE " + table); err != nil {
+			return false, fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+		dropped = true
+	}
+	return dropped, nil
+}
+
+// dropIndex drops the tables in indexTables, so upgradeSchema recreates them
+// empty and the next indexing run rebuilds them.
+func dropIndex(db *sql.DB) error {
+	for _, table := range indexTables {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// migrateSchema adds columns introduced after a cache database was first created.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so new columns
+// (and indexes on them) must be added here.
+func migrateSchema(db *sql.DB) error {
+	if err := ensureColumn(db, "sessions", "content_hash", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_sessions_content_hash ON sessions(content_hash)"); err != nil {
+		return fmt.Errorf("failed to create content hash index: %w", err)
+	}
+	if err := ensureColumn(db, "access_log", "start_index", "INTEGER"); err != nil {
+		return err
+	}
+	for _, column := range []string{"outcome", "outcome_confidence", "outcome_signals"} {
+		if err := ensureColumn(db, "sessions", column, "TEXT"); err != nil {
+			return err
+		}
+	}
+	if err := reindexOnce(db, "session_files_backfilled"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "session_activity_backfilled"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "file_changes_backfilled"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "outcomes_backfilled"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "sessions", "models", "TEXT"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "models_backfilled"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "session_costs_backfilled"); err != nil {
+		return err
+	}
+	if err := reindexOnce(db, "message_index_backfilled"); err != nil {
+		return err
+	}
+	// Tokens from before Tokenize split code identifiers into their parts
+	if err := reindexOnce(db, "code_tokens_backfilled"); err != nil {
+		return err
+	}
+	for _, scope := range indexedScopes {
+		if err := reindexOnce(db, scope+"_scope_backfilled"); err != nil {
+			return err
+		}
+	}
+	// ScopeConversation is selected from the message index instead
+	if err := execOnce(db, "conversation_scope_dropped",
+		"DELETE FROM scoped_documents WHERE scope = 'conversation'; DELETE FROM scoped_term_index WHERE scope = 'conversation'",
+		"drop the conversation scope index"); err != nil {
+		return err
+	}
+	// Role scopes are selected from the message index instead
+	if err := execOnce(db, "role_scopes_dropped",
+		"DELETE FROM scoped_documents WHERE scope LIKE 'role:%'; DELETE FROM scoped_term_index WHERE scope LIKE 'role:%'",
+		"drop the role scope index"); err != nil {
+		return err
+	}
+	// Cached listing metadata from before sessions recorded git state lacks it
+	if err := execOnce(db, "git_metadata_backfilled", "DELETE FROM session_metadata", "clear cached session metadata"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reindexOnce marks every indexed session as stale the first time it runs
+// with key, so the next indexing run fills in data that older versions
+// didn't record. key names the backfill in search_stats.
+func reindexOnce(db *sql.DB, key string) error {
+	return execOnce(db, key, "UPDATE sessions SET file_mtime = 0", "mark sessions for reindexing")
+}
+
+// execOnce runs statement the first time it runs with key, which names the
+// migration in search_stats. what describes the statement in errors.
+func execOnce(db *sql.DB, key, statement, what string) error {
+	if _, err := db.Exec("INSERT OR IGNORE INTO search_stats (key, value) VALUES (?, 0)", key); err != nil {
+		return fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	var done float64
+	if err := db.QueryRow("SELECT value FROM search_stats WHERE key = ?", key).Scan(&done); err != nil {
+		return fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	if done != 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(statement); err != nil {
+		return fmt.Errorf("failed to %s: %w", what, err)
+	}
+	if _, err := db.Exec("UPDATE search_stats SET value = 1 WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to record %s: %w", key, err)
+	}
+	return nil
+}
+
+// ensureColumn adds a column to a table if it doesn't exist yet.
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	exists, err := hasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// hasColumn reports whether a table has a column. A missing table has none.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// SetDefaultRanker selects the ranker used by searches that don't name one.
+func (c *Cache) SetDefaultRanker(name string) error {
+	r, err := GetRanker(name)
+	if err != nil {
+		return err
+	}
+	c.ranker = r
+	return nil
+}
+
+// DefaultRanker returns the name of the ranker used by searches that don't name one.
+func (c *Cache) DefaultRanker() string {
+	if c.ranker == nil {
+		return DefaultRankerName
+	}
+	return c.ranker.Name()
 }
 
 // Close closes the database connection
 func (c *Cache) Close() error {
+	if c.keepAlive != nil {
+		c.keepAlive.Close()
+	}
 	return c.db.Close()
 }
 
 // IndexSession indexes a session for searching
 func (c *Cache) IndexSession(session adapters.Session, content string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	tx, err := c.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -90,6 +403,9 @@ func (c *Cache) IndexSession(session adapters.Session, content string) error {
 	if err != nil {
 		return fmt.Errorf("failed to insert session: %w", err)
 	}
+	if _, err = tx.Exec("DELETE FROM evicted_sessions WHERE session_id = ?", session.ID); err != nil {
+		return fmt.Errorf("failed to clear evicted session: %w", err)
+	}
 
 	// Delete old term index entries for this session
 	if _, err = tx.Exec("DELETE FROM term_index WHERE session_id = ?", session.ID); err != nil {
@@ -117,13 +433,116 @@ func (c *Cache) IndexSession(session adapters.Session, content string) error {
 	return tx.Commit()
 }
 
+// IndexSessionModels records the models a session used, for model:
+// qualifiers. The session must already be indexed with IndexSession.
+func (c *Cache) IndexSessionModels(sessionID string, models []string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.db.Exec("UPDATE sessions SET models = ? WHERE id = ?", strings.Join(models, "\n"), sessionID); err != nil {
+		return fmt.Errorf("failed to record models: %w", err)
+	}
+	return nil
+}
+
+// IndexMessageHashes records the content address of each message in a session,
+// along with the session's own content address derived from them.
+// The session must already be indexed with IndexSession.
+func (c *Cache) IndexMessageHashes(sessionID string, messages []adapters.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM message_hashes WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old message hashes: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO message_hashes (session_id, message_index, role, hash) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	hashes := make([]string, len(messages))
+	for i, msg := range messages {
+		hashes[i] = adapters.HashMessage(msg)
+		if _, err := stmt.Exec(sessionID, i, msg.Role, hashes[i]); err != nil {
+			return fmt.Errorf("failed to insert message hash: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE sessions SET content_hash = ? WHERE id = ?", adapters.HashSession(hashes), sessionID); err != nil {
+		return fmt.Errorf("failed to update session hash: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ContentRef locates content in the cache by its content address.
+type ContentRef struct {
+	SessionID    string `json:"session_id"`
+	Source       string `json:"source"`
+	ProjectPath  string `json:"project_path"`
+	MessageIndex int    `json:"message_index"` // -1 when the hash addresses a whole session
+	Role         string `json:"role,omitempty"`
+}
+
+// LookupContentHash returns every indexed message or session with the given content hash.
+func (c *Cache) LookupContentHash(hash string) ([]ContentRef, error) {
+	var refs []ContentRef
+
+	rows, err := c.db.Query(`
+		SELECT s.id, s.source, s.project_path
+		FROM sessions s
+		WHERE s.content_hash = ?
+	`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session hash: %w", err)
+	}
+	for rows.Next() {
+		ref := ContentRef{MessageIndex: -1}
+		if err := rows.Scan(&ref.SessionID, &ref.Source, &ref.ProjectPath); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+
+	rows, err = c.db.Query(`
+		SELECT mh.session_id, s.source, s.project_path, mh.message_index, mh.role
+		FROM message_hashes mh
+		JOIN sessions s ON s.id = mh.session_id
+		WHERE mh.hash = ?
+		ORDER BY mh.session_id, mh.message_index
+	`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up message hash: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ref ContentRef
+		if err := rows.Scan(&ref.SessionID, &ref.Source, &ref.ProjectPath, &ref.MessageIndex, &ref.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, rows.Err()
+}
+
 // NeedsReindex checks if a session needs to be reindexed based on file modification time
 func (c *Cache) NeedsReindex(sessionID string, filePath string) (bool, error) {
 	var cachedMtime int64
 	err := c.db.QueryRow("SELECT file_mtime FROM sessions WHERE id = ?", sessionID).Scan(&cachedMtime)
 
 	if err == sql.ErrNoRows {
-		return true, nil // Not indexed yet
+		// Not indexed yet, unless it was evicted and hasn't changed since
+		evicted, err := c.evictedUnchanged(sessionID, filePath)
+		return !evicted, err
 	}
 	if err != nil {
 		return false, fmt.Errorf("failed to check cache: %w", err)
@@ -139,28 +558,99 @@ func (c *Cache) NeedsReindex(sessionID string, filePath string) (bool, error) {
 
 // SearchResult represents a search result with score and matching snippet
 type SearchResult struct {
-	Session adapters.Session
-	Score   float64
-	Snippet string // Contextual snippet showing where the match occurred
+	Session     adapters.Session
+	Score       float64
+	Snippet     string // Contextual snippet showing where the match occurred
+	ContentHash string // Content address of the session, if known
+	// MessageIndex is the position of the best-matching message in the
+	// session, counting from 0. It is nil when no message matched on its
+	// own, such as when the terms were only in the summary.
+	MessageIndex *int
+	// KeywordScore and SemanticScore are the scores whose rankings a
+	// FusionRanker fused into Score. They are nil for other rankers.
+	KeywordScore  *float64
+	SemanticScore *float64
+	// Fields lists the query terms found in the session's summary, first
+	// message, and content. Terms in the summary or first message raise the
+	// score.
+	Fields []FieldMatch
 }
 
-// Search performs BM25-ranked search across indexed sessions
+// Search performs ranked search across indexed sessions using the default ranker
 func (c *Cache) Search(query string, source string, projectPath string, limit int) ([]SearchResult, error) {
-	queryTerms := Tokenize(query)
+	return c.SearchWithRanker(query, source, projectPath, limit, "")
+}
+
+// SearchWithRanker performs search using the named ranker.
+// An empty ranker name uses the cache's default ranker (BM25 unless changed).
+func (c *Cache) SearchWithRanker(query string, source string, projectPath string, limit int, rankerName string) ([]SearchResult, error) {
+	return c.SearchInScope(query, ScopeAll, source, projectPath, limit, rankerName)
+}
+
+// SearchInScope performs search using the named ranker, matching only the
+// session text in scope. Sessions are matched as a whole, but ranked by their
+// best-matching message against the other messages' text in the same scope,
+// and the snippet is taken from that message. The query may
+// use phrases, boolean operators, and field qualifiers, as QueryGrammar
+// describes; a query that doesn't parse returns an error wrapping
+// ErrQuerySyntax. A role: qualifier matches the query in that role's
+// messages, so it only combines with ScopeAll or ScopeConversation.
+func (c *Cache) SearchInScope(query string, scope string, source string, projectPath string, limit int, rankerName string) ([]SearchResult, error) {
+	if scope != ScopeConversation {
+		if err := ValidateScope(scope); err != nil {
+			return nil, err
+		}
+	}
+	if scope == "" {
+		scope = ScopeAll
+	}
+
+	parsed, err := parseQuery(query, c.expandPrefix)
+	if err != nil {
+		return nil, err
+	}
+	// Candidates contain a term that isn't excluded; the rest of the query
+	// is checked against each one
+	queryTerms := parsed.positive
 	if len(queryTerms) == 0 {
 		return nil, fmt.Errorf("no valid search terms")
 	}
+	if parsed.role != "" {
+		if scope != ScopeAll && scope != ScopeConversation {
+			return nil, fmt.Errorf("%w: role: can't be combined with the %s scope", ErrQuerySyntax, scope)
+		}
+		scope = roleScope(parsed.role)
+	}
+
+	ranker := c.ranker
+	if rankerName != "" || ranker == nil {
+		r, err := GetRanker(rankerName)
+		if err != nil {
+			return nil, err
+		}
+		ranker = r
+	}
+	fusion, hybrid := ranker.(FusionRanker)
 
 	// Get global stats for BM25
-	stats, err := c.getStats()
+	stats, err := c.getStats(scope)
 	if err != nil {
 		return nil, err
 	}
 
-	scorer := NewBM25Scorer(stats.avgDocLength, stats.totalDocs)
-
 	// Get document frequencies for query terms
-	docFreqs, err := c.getDocumentFrequencies(queryTerms)
+	docFreqs, err := c.getDocumentFrequencies(scope, queryTerms)
+	if err != nil {
+		return nil, err
+	}
+
+	corpus := Corpus{
+		TotalDocs:    stats.totalDocs,
+		AvgDocLength: stats.avgDocLength,
+		DocFreqs:     docFreqs,
+		Now:          time.Now(),
+	}
+	messageCorpus, err := c.messageCorpus(scope, queryTerms, corpus.Now)
 	if err != nil {
 		return nil, err
 	}
@@ -168,12 +658,36 @@ func (c *Cache) Search(query string, source string, projectPath string, limit in
 	// Build SQL query with filters - include content for snippet extraction
 	sqlQuery := `
 		SELECT DISTINCT s.id, s.source, s.project_path, s.file_path,
-		       s.first_message, s.summary, s.timestamp, s.doc_length, s.content
+		       s.first_message, s.summary, s.timestamp, s.doc_length, s.content,
+		       s.content_hash, s.models, NULL
 		FROM sessions s
 		JOIN term_index ti ON s.id = ti.session_id
 		WHERE ti.term IN (`
-
 	args := make([]interface{}, 0)
+	switch {
+	case derivedScope(scope):
+		ms := messageScopeOf(scope)
+		sqlQuery = `
+		SELECT DISTINCT s.id, s.source, s.project_path, s.file_path,
+		       s.first_message, s.summary, s.timestamp, 0, '',
+		       s.content_hash, s.models, s.content
+		FROM sessions s
+		JOIN message_term_index ti ON s.id = ti.session_id AND ti.` + ms.frequency + ` > 0
+		JOIN message_documents d ON d.session_id = ti.session_id AND d.message_index = ti.message_index AND ` + ms.where + `
+		WHERE ti.term IN (`
+		args = append(args, ms.args...)
+	case scope != ScopeAll:
+		sqlQuery = `
+		SELECT DISTINCT s.id, s.source, s.project_path, s.file_path,
+		       s.first_message, s.summary, s.timestamp, d.doc_length, d.content,
+		       s.content_hash, s.models, s.content
+		FROM sessions s
+		JOIN scoped_documents d ON s.id = d.session_id AND d.scope = ?
+		JOIN scoped_term_index ti ON s.id = ti.session_id AND ti.scope = d.scope
+		WHERE ti.term IN (`
+		args = append(args, scope)
+	}
+
 	for i, term := range queryTerms {
 		if i > 0 {
 			sqlQuery += ", "
@@ -189,7 +703,7 @@ func (c *Cache) Search(query string, source string, projectPath string, limit in
 		args = append(args, source)
 	}
 	if projectPath != "" {
-		sqlQuery += " AND s.project_path = ?"
+		sqlQuery += " AND project_matches(?, s.project_path)"
 		args = append(args, projectPath)
 	}
 
@@ -200,40 +714,114 @@ func (c *Cache) Search(query string, source string, projectPath string, limit in
 	defer rows.Close()
 
 	var results []SearchResult
+	var titleScores []float64 // by result, for boostTitleMatches
 
 	for rows.Next() {
 		var session adapters.Session
 		var timestampUnix int64
 		var docLength int
 		var content string
+		var contentHash, models, sessionContent sql.NullString
 
 		err := rows.Scan(&session.ID, &session.Source, &session.ProjectPath,
 			&session.FilePath, &session.FirstMessage, &session.Summary,
-			&timestampUnix, &docLength, &content)
+			&timestampUnix, &docLength, &content, &contentHash, &models, &sessionContent)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		// The full content holds each message's text; in ScopeAll it's content
+		if !sessionContent.Valid {
+			sessionContent.String = content
+		}
+		if derivedScope(scope) {
+			docLength, content, err = c.derivedDocument(session.ID, sessionContent.String, scope)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		session.Timestamp = time.Unix(timestampUnix, 0)
 
 		// Get term frequencies for this document
-		termFreqs, err := c.getTermFrequencies(session.ID, queryTerms)
+		termFreqs, err := c.getTermFrequencies(scope, session.ID, parsed.all)
 		if err != nil {
 			return nil, err
 		}
-
-		// Calculate BM25 score
-		score := scorer.Score(queryTerms, termFreqs, docLength, docFreqs)
-
-		// Extract snippet from cached content
-		snippet := GetSnippet(content, queryTerms, 300)
-
-		results = append(results, SearchResult{
-			Session: session,
-			Score:   score,
-			Snippet: snippet,
+		var docTokens []string // tokenized once, and only for phrases
+		var fieldErr error
+		matched := parsed.matches(queryDoc{
+			termFreqs: termFreqs,
+			tokens: func() []string {
+				if docTokens == nil {
+					docTokens = queryTokens(content)
+				}
+				return docTokens
+			},
+			field: func(field, value string) bool {
+				ok, err := c.matchField(session, models.String, field, value)
+				if err != nil && fieldErr == nil {
+					fieldErr = err
+				}
+				return ok
+			},
 		})
+		if fieldErr != nil {
+			return nil, fieldErr
+		}
+		if !matched {
+			continue
+		}
+
+		result := SearchResult{Session: session, ContentHash: contentHash.String}
+		whole := Document{
+			Session:   session,
+			TermFreqs: termFreqs,
+			DocLength: docLength,
+			Content:   content,
+		}
+		hit, ok, err := c.bestMessage(ranker, session, sessionContent.String, scope, queryTerms, messageCorpus)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.Score = hit.score
+			result.Snippet = GetSnippet(hit.content, queryTerms, 300)
+			result.MessageIndex = &hit.index
+		} else {
+			// Only the summary or first message matched, or the session's
+			// messages aren't indexed yet
+			result.Score = ranker.Score(queryTerms, whole, corpus)
+			result.Snippet = GetSnippet(content, queryTerms, 300)
+		}
+		if hybrid {
+			// The semantic ranker picks its own best message
+			keyword, semantic := result.Score, 0.0
+			hit, ok, err := c.bestMessage(fusion.Semantic, session, sessionContent.String, scope, queryTerms, messageCorpus)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				semantic = hit.score
+			} else {
+				semantic = fusion.Semantic.Score(queryTerms, whole, corpus)
+			}
+			result.KeywordScore, result.SemanticScore = &keyword, &semantic
+		}
+		result.Fields = fieldMatches(session, queryTerms, termFreqs)
+		titleScores = append(titleScores, titleScore(result.Fields, corpus))
+		results = append(results, result)
+	}
+	if hybrid {
+		keyword := make([]float64, len(results))
+		semantic := make([]float64, len(results))
+		for i, result := range results {
+			keyword[i], semantic[i] = *result.KeywordScore, *result.SemanticScore
+		}
+		for i, score := range fusion.Fuse(keyword, semantic) {
+			results[i].Score = score
+		}
 	}
+	boostTitleMatches(results, titleScores)
 
 	// Sort by score (descending)
 	sort.Slice(results, func(i, j int) bool {
@@ -248,23 +836,56 @@ func (c *Cache) Search(query string, source string, projectPath string, limit in
 	return results, nil
 }
 
-// GetSnippet extracts a contextual snippet from content around the first occurrence of query terms
+// matchField reports whether an indexed session matches a field qualifier.
+// models holds the session's models, one per line.
+func (c *Cache) matchField(session adapters.Session, models, field, value string) (bool, error) {
+	switch field {
+	case FieldSource:
+		return strings.EqualFold(session.Source, value), nil
+	case FieldModel:
+		for _, model := range strings.Split(models, "\n") {
+			if model != "" && strings.Contains(strings.ToLower(model), strings.ToLower(value)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case FieldTitle:
+		words := queryTokens(value)
+		return containsSequence(queryTokens(session.Summary), words) || containsSequence(queryTokens(session.FirstMessage), words), nil
+	case FieldFile:
+		value = filepath.ToSlash(filepath.Clean(value))
+		suffix := "/" + strings.TrimPrefix(value, "/")
+		var found int
+		err := c.db.QueryRow(`
+			SELECT COUNT(*) FROM session_files
+			WHERE session_id = ? AND (path = ? OR substr(path, -?) = ?)
+		`, session.ID, value, len([]rune(suffix)), suffix).Scan(&found)
+		if err != nil {
+			return false, fmt.Errorf("failed to match file: %w", err)
+		}
+		return found > 0, nil
+	}
+	return false, fmt.Errorf("%w: unknown field %s:", ErrQuerySyntax, field)
+}
+
+// GetSnippet extracts a contextual snippet from content around the first occurrence of query terms.
+// maxLength is in bytes; the snippet is widened to whole grapheme clusters so
+// multi-byte characters and emoji are never split.
 func GetSnippet(content string, queryTerms []string, maxLength int) string {
 	if maxLength == 0 {
 		maxLength = 300
 	}
 
-	contentLower := strings.ToLower(content)
-
-	// Find the earliest position of any query term
+	// Find the earliest position of any query term. Matching is done on the
+	// original content, since lower-casing can change byte offsets.
 	firstPos := len(content)
-	matchedTerm := ""
+	matchedLength := 0
 
 	for _, term := range queryTerms {
-		pos := strings.Index(contentLower, term)
-		if pos != -1 && pos < firstPos {
-			firstPos = pos
-			matchedTerm = term
+		loc := regexp.MustCompile("(?i)" + regexp.QuoteMeta(term)).FindStringIndex(content)
+		if loc != nil && loc[0] < firstPos {
+			firstPos = loc[0]
+			matchedLength = loc[1] - loc[0]
 		}
 	}
 
@@ -273,13 +894,14 @@ func GetSnippet(content string, queryTerms []string, maxLength int) string {
 		if len(content) <= maxLength {
 			return content
 		}
-		return content[:maxLength] + "..."
+		_, end := graphemeBounds(content, 0, maxLength)
+		return content[:end] + "..."
 	}
 
 	// Calculate snippet boundaries
 	halfLength := maxLength / 2
 	start := firstPos - halfLength
-	end := firstPos + len(matchedTerm) + halfLength
+	end := firstPos + matchedLength + halfLength
 
 	// Adjust boundaries
 	if start < 0 {
@@ -310,6 +932,7 @@ func GetSnippet(content string, queryTerms []string, maxLength int) string {
 		}
 	}
 
+	start, end = graphemeBounds(content, start, end)
 	snippet := content[start:end]
 
 	// Add ellipsis if truncated
@@ -323,16 +946,56 @@ func GetSnippet(content string, queryTerms []string, maxLength int) string {
 	return snippet
 }
 
+// graphemeBounds widens the byte range [start, end) of s to the nearest
+// grapheme cluster boundaries.
+func graphemeBounds(s string, start, end int) (int, int) {
+	newStart, newEnd := start, end
+	pos := 0
+	state := -1
+	rest := s
+	for len(rest) > 0 && pos < end {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if pos <= start && start < pos+len(cluster) {
+			newStart = pos
+		}
+		pos += len(cluster)
+		if pos >= end {
+			newEnd = pos
+		}
+	}
+	return newStart, newEnd
+}
+
 // getStats retrieves global search statistics
 type searchStats struct {
 	totalDocs    int
 	avgDocLength float64
 }
 
-func (c *Cache) getStats() (*searchStats, error) {
+func (c *Cache) getStats(scope string) (*searchStats, error) {
 	var totalDocs int
 	var avgDocLength float64
 
+	if derivedScope(scope) {
+		ms := messageScopeOf(scope)
+		err := c.db.QueryRow(`
+			SELECT COUNT(*), COALESCE(AVG(doc_length), 0)
+			FROM (SELECT SUM(d.`+ms.length+`) AS doc_length FROM message_documents d WHERE `+ms.where+` GROUP BY d.session_id)`,
+			ms.args...).Scan(&totalDocs, &avgDocLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s stats: %w", scope, err)
+		}
+		return &searchStats{totalDocs: totalDocs, avgDocLength: avgDocLength}, nil
+	}
+	if scope != ScopeAll {
+		err := c.db.QueryRow("SELECT COUNT(*), COALESCE(AVG(doc_length), 0) FROM scoped_documents WHERE scope = ?", scope).Scan(&totalDocs, &avgDocLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s stats: %w", scope, err)
+		}
+		return &searchStats{totalDocs: totalDocs, avgDocLength: avgDocLength}, nil
+	}
+
 	err := c.db.QueryRow("SELECT value FROM search_stats WHERE key = 'total_docs'").Scan(&totalDocs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total_docs: %w", err)
@@ -375,18 +1038,58 @@ func (c *Cache) updateStats(tx *sql.Tx) error {
 	return nil
 }
 
-// getDocumentFrequencies returns the number of documents containing each term
-func (c *Cache) getDocumentFrequencies(terms []string) (map[string]int, error) {
+// expandPrefix returns the indexed keywords starting with prefix, those in
+// the most sessions first, up to maxPrefixTerms.
+func (c *Cache) expandPrefix(prefix string) ([]string, error) {
+	// A range on term uses the index, where LIKE would scan it
+	rows, err := c.db.Query(`
+		SELECT term FROM term_index
+		WHERE term >= ? AND term < ?
+		GROUP BY term
+		ORDER BY COUNT(*) DESC, term
+		LIMIT ?
+	`, prefix, prefix+string(utf8.MaxRune), maxPrefixTerms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand prefix %s*: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	var terms []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+// getDocumentFrequencies returns the number of documents in scope containing each term
+func (c *Cache) getDocumentFrequencies(scope string, terms []string) (map[string]int, error) {
 	freqs := make(map[string]int)
 
 	query := "SELECT term, COUNT(DISTINCT session_id) FROM term_index WHERE term IN ("
-	args := make([]interface{}, len(terms))
+	var args []interface{}
+	switch {
+	case derivedScope(scope):
+		ms := messageScopeOf(scope)
+		query = `
+			SELECT t.term, COUNT(DISTINCT t.session_id)
+			FROM message_term_index t
+			JOIN message_documents d ON d.session_id = t.session_id AND d.message_index = t.message_index
+			WHERE ` + ms.where + ` AND t.` + ms.frequency + ` > 0 AND t.term IN (`
+		args = append(args, ms.args...)
+	case scope != ScopeAll:
+		query = "SELECT term, COUNT(DISTINCT session_id) FROM scoped_term_index WHERE scope = ? AND term IN ("
+		args = append(args, scope)
+	}
 	for i, term := range terms {
 		if i > 0 {
 			query += ", "
 		}
 		query += "?"
-		args[i] = term
+		args = append(args, term)
 	}
 	query += ") GROUP BY term"
 
@@ -408,12 +1111,28 @@ func (c *Cache) getDocumentFrequencies(terms []string) (map[string]int, error) {
 	return freqs, nil
 }
 
-// getTermFrequencies returns term frequencies for a specific document
-func (c *Cache) getTermFrequencies(sessionID string, terms []string) (map[string]int, error) {
+// getTermFrequencies returns term frequencies for a specific document in scope
+func (c *Cache) getTermFrequencies(scope string, sessionID string, terms []string) (map[string]int, error) {
 	freqs := make(map[string]int)
 
 	query := "SELECT term, term_frequency FROM term_index WHERE session_id = ? AND term IN ("
 	args := []interface{}{sessionID}
+	groupBy := ""
+	switch {
+	case derivedScope(scope):
+		// Summed over the session's messages in scope
+		ms := messageScopeOf(scope)
+		query = `
+			SELECT t.term, SUM(t.` + ms.frequency + `)
+			FROM message_term_index t
+			JOIN message_documents d ON d.session_id = t.session_id AND d.message_index = t.message_index
+			WHERE ` + ms.where + ` AND t.session_id = ? AND t.` + ms.frequency + ` > 0 AND t.term IN (`
+		args = append(slices.Clone(ms.args), sessionID)
+		groupBy = " GROUP BY t.term"
+	case scope != ScopeAll:
+		query = "SELECT term, term_frequency FROM scoped_term_index WHERE scope = ? AND session_id = ? AND term IN ("
+		args = []interface{}{scope, sessionID}
+	}
 	for i, term := range terms {
 		if i > 0 {
 			query += ", "
@@ -421,7 +1140,7 @@ func (c *Cache) getTermFrequencies(sessionID string, terms []string) (map[string
 		query += "?"
 		args = append(args, term)
 	}
-	query += ")"
+	query += ")" + groupBy
 
 	rows, err := c.db.Query(query, args...)
 	if err != nil {
@@ -1,12 +1,19 @@
 package search
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/yoavf/ai-sessions-mcp/adapters"
 )
@@ -45,6 +52,234 @@ func TestTokenizeAndTermFrequency(t *testing.T) {
 	}
 }
 
+func TestTokenizeCodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "getSessionPageFromSQLite", want: []string{"getsessionpagefromsqlite", "get", "session", "page", "from", "sq", "lite", "sqlite"}},
+		{text: "HTTPServer utf8Decode", want: []string{"httpserver", "http", "server", "utf8decode", "utf8", "decode"}},
+		{text: "MAX_PAGE_SIZE", want: []string{"max_page_size", "max", "page", "size"}},
+		{text: "call os.path.join.", want: []string{"call", "os.path.join", "os", "path", "join"}},
+		{text: "api.getSession(id)", want: []string{"api.getsession", "api", "getsession", "get", "session", "id"}},
+		{text: "ENOENT: no such file", want: []string{"enoent", "no", "such", "file"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	// Queries keep identifiers whole
+	if got, want := queryTokens("getSessionPage main.go"), []string{"getsessionpage", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queryTokens = %v, want %v", got, want)
+	}
+
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	session := adapters.Session{ID: "code", Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+	if err := cache.IndexSession(session, "the bug is in getSessionPageFromSQLite when the file is missing"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	for _, query := range []string{"getSessionPageFromSQLite", "sqlite", "session page", `"getSessionPageFromSQLite when"`} {
+		results, err := cache.Search(query, "", "", 10)
+		if err != nil || len(results) != 1 {
+			t.Errorf("Search(%q) = %v (%v), want the session", query, results, err)
+		}
+	}
+	if results, _ := cache.Search("getSessionPageFromFile", "", "", 10); len(results) != 0 {
+		t.Errorf("expected a different identifier not to match, got %v", results)
+	}
+}
+
+func TestStem(t *testing.T) {
+	for word, want := range map[string]string{
+		"caresses": "caress", "ponies": "poni", "cats": "cat", "feed": "feed",
+		"agreed": "agre", "plastered": "plaster", "motoring": "motor", "sing": "sing",
+		"hopping": "hop", "filing": "file", "happy": "happi", "relational": "relat",
+		"conditional": "condit", "connections": "connect", "connecting": "connect",
+		"generalization": "gener", "controll": "control", "utf8": "utf8", "go": "go",
+	} {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestAnalyzerSettings(t *testing.T) {
+	cache := newTempCache(t)
+	t.Cleanup(func() { activeAnalyzer.Store(nil) })
+	if err := cache.SetAnalyzer(Analyzer{Stemming: true, EnglishStopwords: true, Stopwords: []string{"Please"}}); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if got, want := Tokenize("Please fix the refused connections"), []string{"fix", "refus", "connect"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tokenize = %v, want %v", got, want)
+	}
+	// analyzerRule is the analyzer rule get_search_syntax describes
+	analyzerRule := func() string {
+		t.Helper()
+		for _, rule := range DescribeSyntax().Rules {
+			if rule.Name == "analyzer" {
+				return rule.Description
+			}
+		}
+		t.Fatal("expected DescribeSyntax to describe the analyzer")
+		return ""
+	}
+	if rule := analyzerRule(); !strings.Contains(rule, "stems") || !strings.Contains(rule, "please") || !strings.Contains(rule, "no valid search terms") {
+		t.Fatalf("expected the analyzer rule to describe stemming and stopwords, got %q", rule)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	session := adapters.Session{ID: "stemmed", Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+	if err := cache.IndexSession(session, "the connections were refused"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	if results, err := cache.Search(`"connection refusing"`, "", "", 10); err != nil || len(results) != 1 {
+		t.Fatalf("expected other forms of the words to match, got %v (%v)", results, err)
+	}
+	if results, err := cache.Search("the", "", "", 10); err == nil {
+		t.Fatalf("expected a query of stopwords to have no terms, got %v", results)
+	}
+
+	// The same settings keep the index; different ones rebuild it
+	if err := cache.SetAnalyzer(Analyzer{Stopwords: []string{"please"}, EnglishStopwords: true, Stemming: true}); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if stale, err := cache.NeedsReindex(session.ID, filePath); err != nil || stale {
+		t.Fatalf("expected unchanged settings to keep the index (stale %v, %v)", stale, err)
+	}
+	if err := cache.SetAnalyzer(Analyzer{}); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if rule := analyzerRule(); strings.Contains(rule, "please") {
+		t.Fatalf("expected the analyzer rule to follow the new settings, got %q", rule)
+	}
+	if stale, err := cache.NeedsReindex(session.ID, filePath); err != nil || !stale {
+		t.Fatalf("expected new settings to mark the session for reindexing (stale %v, %v)", stale, err)
+	}
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	cache := newTempCache(t)
+	now := time.Now()
+	dir := t.TempDir()
+	for id, age := range map[string]int{"ancient": 400, "s1": 30, "s2": 20, "s3": 10} {
+		filePath := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+		started := now.AddDate(0, 0, -age)
+		if err := os.Chtimes(filePath, started, started); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: started, FilePath: filePath}
+		if err := cache.IndexSession(session, "retained content"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+	// Reading the oldest of the rest makes it the most recently used
+	if err := cache.RecordAccess(AccessEntry{SessionID: "s1", Source: "claude", ClientName: "test", AccessedAt: now}); err != nil {
+		t.Fatalf("RecordAccess failed: %v", err)
+	}
+
+	eviction, err := cache.Evict(now, Retention{MaxAgeDays: 365, MaxSessions: 2, MaxSizeMB: 1024})
+	if err != nil {
+		t.Fatalf("Evict failed: %v", err)
+	}
+	if eviction != (Eviction{ByAge: 1, ByCount: 1}) {
+		t.Fatalf("unexpected eviction %+v", eviction)
+	}
+	results, err := cache.Search("retained", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var kept []string
+	for _, r := range results {
+		kept = append(kept, r.Session.ID)
+	}
+	sort.Strings(kept)
+	if !reflect.DeepEqual(kept, []string{"s1", "s3"}) {
+		t.Fatalf("expected s1 and s3 to be kept, got %v", kept)
+	}
+	if info, err := cache.Info(); err != nil || info.Sessions != 2 || info.Evicted != 2 {
+		t.Fatalf("unexpected index info %+v (%v)", info, err)
+	}
+
+	// Evicted sessions stay out until their file is modified
+	s2 := filepath.Join(dir, "s2.jsonl")
+	if stale, err := cache.NeedsReindex("s2", s2); err != nil || stale {
+		t.Fatalf("expected an evicted session to be left out (stale %v, %v)", stale, err)
+	}
+	if err := os.Chtimes(s2, now.Add(time.Minute), now.Add(time.Minute)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if stale, err := cache.NeedsReindex("s2", s2); err != nil || !stale {
+		t.Fatalf("expected a modified evicted session to be indexed again (stale %v, %v)", stale, err)
+	}
+}
+
+func TestEvictToSizeCap(t *testing.T) {
+	cache := newTempCache(t)
+	now := time.Now()
+	dir := t.TempDir()
+	const total = 40
+	for i := 0; i < total; i++ {
+		id := fmt.Sprintf("s%02d", i)
+		filePath := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+		started := now.Add(time.Duration(i-total) * time.Hour)
+		if err := os.Chtimes(filePath, started, started); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+		// Mostly distinct terms, so the term index, whose pages every
+		// session shares, is most of the database
+		words := make([]string, 2000)
+		for j := range words {
+			words[j] = fmt.Sprintf("term%dx%d", j, i%4)
+		}
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: started, FilePath: filePath}
+		if err := cache.IndexSession(session, strings.Join(words, " ")); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	tx, err := cache.db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	used, err := usedBytes(tx)
+	tx.Rollback()
+	if err != nil {
+		t.Fatalf("usedBytes: %v", err)
+	}
+	// A cap of about half the index keeps about half the sessions
+	limitMB := used >> 20 / 2
+	if limitMB == 0 {
+		t.Fatalf("expected an index of several MB, got %d bytes", used)
+	}
+	eviction, err := cache.Evict(now, Retention{MaxSizeMB: limitMB})
+	if err != nil {
+		t.Fatalf("Evict failed: %v", err)
+	}
+	kept := total - eviction.BySize
+	fits := int(float64(total) * float64(limitMB<<20) / float64(used))
+	if kept < fits-1 || kept > fits {
+		t.Fatalf("expected about %d of %d sessions to fit in %d MB of %d bytes, kept %d", fits, total, limitMB, used, kept)
+	}
+	if info, err := cache.Info(); err != nil || info.Sessions != kept {
+		t.Fatalf("unexpected index info %+v (%v)", info, err)
+	}
+}
+
 func TestBM25Score(t *testing.T) {
 	scorer := NewBM25Scorer(100, 10)
 	termFreqs := map[string]int{"gopher": 2}
@@ -75,6 +310,23 @@ func TestGetSnippet(t *testing.T) {
 	}
 }
 
+func TestGetSnippetMultiByte(t *testing.T) {
+	content := strings.Repeat("🙂 ", 30) + "İstanbul keyword " + strings.Repeat("日本語のテキスト ", 10)
+	snippet := GetSnippet(content, []string{"keyword"}, 41)
+	if !utf8.ValidString(snippet) {
+		t.Fatalf("snippet split a multi-byte character: %q", snippet)
+	}
+	if !strings.Contains(snippet, "keyword") {
+		t.Fatalf("snippet missing keyword: %q", snippet)
+	}
+
+	// Lower-casing "İ" changes its length, which used to shift the match offset
+	snippet = GetSnippet("İİİİ "+strings.Repeat("x", 100)+" needle", []string{"needle"}, 10)
+	if !strings.Contains(snippet, "needle") {
+		t.Fatalf("snippet missing match after case-changing characters: %q", snippet)
+	}
+}
+
 func TestCacheIndexSearchAndNeedsReindex(t *testing.T) {
 	cache := newTempCache(t)
 	tempDir := t.TempDir()
@@ -140,3 +392,1788 @@ func TestCacheIndexSearchAndNeedsReindex(t *testing.T) {
 		t.Fatal("expected NeedsReindex to return true after file mtime change")
 	}
 }
+
+func TestSearchByProjectPattern(t *testing.T) {
+	cache := newTempCache(t)
+	root := t.TempDir()
+	filePath := filepath.Join(root, "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	monorepo := filepath.Join(root, "monorepo")
+	link := filepath.Join(root, "link")
+	if err := os.Mkdir(monorepo, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Symlink(monorepo, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	for id, project := range map[string]string{
+		"root":     monorepo,
+		"api":      filepath.Join(monorepo, "services", "api"),
+		"worktree": filepath.Join(link, "feature-branch"),
+		"other":    filepath.Join(root, "other"),
+	} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: project, Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, "deploy script"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	// found returns the IDs of the sessions matching deploy in projectPath
+	found := func(projectPath string) []string {
+		t.Helper()
+		results, err := cache.Search("deploy", "", projectPath, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", projectPath, err)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.Session.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	if got := found(monorepo); !reflect.DeepEqual(got, []string{"root"}) {
+		t.Fatalf("expected a plain path to match exactly, got %v", got)
+	}
+	if got := found(link); !reflect.DeepEqual(got, []string{"root"}) {
+		t.Fatalf("expected a symlinked path to match its target, got %v", got)
+	}
+	if got := found(monorepo + "/**"); !reflect.DeepEqual(got, []string{"api", "root", "worktree"}) {
+		t.Fatalf("expected the glob to match the monorepo and everything under it, got %v", got)
+	}
+	if got := found(monorepo + "/*/api"); !reflect.DeepEqual(got, []string{"api"}) {
+		t.Fatalf("expected a single-segment wildcard, got %v", got)
+	}
+	if _, err := cache.Search("deploy", "", monorepo+"/[", 10); err == nil {
+		t.Fatal("expected an invalid pattern to fail the search")
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	cache := newTempCache(t)
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(sessionFile, []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("failed to create session file: %v", err)
+	}
+
+	// Writers on separate connections would otherwise fail with SQLITE_BUSY
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session := adapters.Session{ID: fmt.Sprintf("sess-%d", i), Source: "claude", Timestamp: time.Now(), FilePath: sessionFile}
+			messages := []adapters.Message{{Role: "user", Content: "parallel write"}}
+			if err := cache.IndexSession(session, "parallel write"); err != nil {
+				errs <- err
+			}
+			if err := cache.IndexScopes(session.ID, messages); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent write failed: %v", err)
+	}
+
+	results, err := cache.Search("parallel", "", "", 50)
+	if err != nil || len(results) != 20 {
+		t.Fatalf("expected 20 indexed sessions, got %d (%v)", len(results), err)
+	}
+}
+
+func TestIndexMessageHashesAndLookup(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	session := adapters.Session{ID: "sess-hash", Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+	messages := []adapters.Message{
+		{Role: "user", Content: "hello"},
+		{Role: "assistant", Content: "hi there"},
+	}
+	if err := cache.IndexSession(session, "hello hi there"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	if err := cache.IndexMessageHashes(session.ID, messages); err != nil {
+		t.Fatalf("IndexMessageHashes failed: %v", err)
+	}
+
+	refs, err := cache.LookupContentHash(adapters.HashMessage(messages[1]))
+	if err != nil {
+		t.Fatalf("LookupContentHash failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0].SessionID != "sess-hash" || refs[0].MessageIndex != 1 || refs[0].Role != "assistant" {
+		t.Fatalf("unexpected message refs: %+v", refs)
+	}
+
+	sessionHash := adapters.HashSession([]string{adapters.HashMessage(messages[0]), adapters.HashMessage(messages[1])})
+	refs, err = cache.LookupContentHash(sessionHash)
+	if err != nil {
+		t.Fatalf("LookupContentHash (session) failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0].MessageIndex != -1 {
+		t.Fatalf("unexpected session refs: %+v", refs)
+	}
+
+	results, err := cache.Search("hello", "", "", 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ContentHash != sessionHash {
+		t.Fatalf("search result missing content hash: %+v", results)
+	}
+}
+
+func TestNewCacheMigratesOldSchema(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", cachePath)
+	if err != nil {
+		t.Fatalf("open old db: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE sessions (
+		id TEXT PRIMARY KEY, source TEXT NOT NULL, project_path TEXT NOT NULL, file_path TEXT NOT NULL,
+		first_message TEXT, summary TEXT, timestamp INTEGER NOT NULL, last_indexed INTEGER NOT NULL,
+		file_mtime INTEGER NOT NULL, doc_length INTEGER DEFAULT 0, content TEXT)`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	db.Close()
+
+	cache, err := NewCache(cachePath)
+	if err != nil {
+		t.Fatalf("NewCache on old schema failed: %v", err)
+	}
+	defer cache.Close()
+
+	if _, err := cache.LookupContentHash("abc"); err != nil {
+		t.Fatalf("content_hash column missing after migration: %v", err)
+	}
+}
+
+func TestNewCacheRebuildsIncompatibleIndex(t *testing.T) {
+	t.Run("newer version", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "newer.db")
+		cache, err := NewCache(cachePath)
+		if err != nil {
+			t.Fatalf("NewCache failed: %v", err)
+		}
+		filePath := filepath.Join(t.TempDir(), "session.jsonl")
+		if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+		session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, "indexed by a newer version"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if _, err := cache.AddNote(Note{SessionID: "s1", Source: "claude", Text: "keep me"}); err != nil {
+			t.Fatalf("AddNote failed: %v", err)
+		}
+		if _, err := cache.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion+1)); err != nil {
+			t.Fatalf("set user_version: %v", err)
+		}
+		cache.Close()
+
+		cache, err = NewCache(cachePath)
+		if err != nil {
+			t.Fatalf("NewCache on a newer index failed: %v", err)
+		}
+		defer cache.Close()
+		status, err := cache.Status()
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		if status.Sessions != 0 || status.SchemaVersion != schemaVersion {
+			t.Fatalf("expected the index to be dropped and versioned %d, got %+v", schemaVersion, status)
+		}
+		if stale, err := cache.NeedsReindex("s1", filePath); err != nil || !stale {
+			t.Fatalf("expected the session to be reindexed (stale %v, %v)", stale, err)
+		}
+		if notes, err := cache.Notes("s1", "claude"); err != nil || len(notes) != 1 {
+			t.Fatalf("expected notes to be kept, got %v (%v)", notes, err)
+		}
+	})
+
+	t.Run("reshaped tables", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "reshaped.db")
+		cache, err := NewCache(cachePath)
+		if err != nil {
+			t.Fatalf("NewCache failed: %v", err)
+		}
+		filePath := filepath.Join(t.TempDir(), "session.jsonl")
+		if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+		session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, "indexed before messages were located"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		// Message tables as version 1 laid them out, with a copy per scope
+		for _, statement := range []string{
+			"DROP TABLE message_documents",
+			"DROP TABLE message_term_index",
+			"CREATE TABLE message_documents (session_id TEXT, scope TEXT, message_index INTEGER, doc_length INTEGER, content TEXT)",
+			"CREATE TABLE message_term_index (scope TEXT, term TEXT, session_id TEXT, message_index INTEGER, term_frequency INTEGER)",
+			"PRAGMA user_version = 1",
+		} {
+			if _, err := cache.db.Exec(statement); err != nil {
+				t.Fatalf("%s: %v", statement, err)
+			}
+		}
+		cache.Close()
+
+		cache, err = NewCache(cachePath)
+		if err != nil {
+			t.Fatalf("NewCache on reshaped tables failed: %v", err)
+		}
+		defer cache.Close()
+		for table := range reshapedTables {
+			if old, err := hasColumn(cache.db, table, "scope"); err != nil || old {
+				t.Fatalf("expected %s to be recreated (old layout %v, %v)", table, old, err)
+			}
+		}
+		if status, err := cache.Status(); err != nil || status.Sessions != 1 {
+			t.Fatalf("expected the session to stay indexed, got %+v (%v)", status, err)
+		}
+		if stale, err := cache.NeedsReindex("s1", filePath); err != nil || !stale {
+			t.Fatalf("expected the session to be reindexed (stale %v, %v)", stale, err)
+		}
+	})
+
+	t.Run("failed migration", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "broken.db")
+		db, err := sql.Open("sqlite", cachePath)
+		if err != nil {
+			t.Fatalf("open old db: %v", err)
+		}
+		// Too old to migrate: schema.sql indexes columns this table lacks
+		if _, err := db.Exec("CREATE TABLE sessions (id TEXT PRIMARY KEY, source TEXT NOT NULL)"); err != nil {
+			t.Fatalf("create old schema: %v", err)
+		}
+		db.Close()
+
+		cache, err := NewCache(cachePath)
+		if err != nil {
+			t.Fatalf("NewCache on an unmigratable index failed: %v", err)
+		}
+		defer cache.Close()
+		if status, err := cache.Status(); err != nil || status.SchemaVersion != schemaVersion {
+			t.Fatalf("expected the index to be rebuilt at version %d, got %+v (%v)", schemaVersion, status, err)
+		}
+	})
+}
+
+func TestRecordAndQueryAccessLog(t *testing.T) {
+	cache := newTempCache(t)
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []AccessEntry{
+		{SessionID: "s1", Source: "claude", ClientName: "codex", Page: 0, PageSize: 20, MessageCount: 20, AccessedAt: base},
+		{SessionID: "s1", Source: "claude", ClientName: "codex", ClientVersion: "1.2", Page: 1, PageSize: 20, MessageCount: 5, AccessedAt: base.Add(time.Minute)},
+		{SessionID: "s2", Source: "gemini", ClientName: "claude-code", Page: 0, PageSize: 10, MessageCount: 3, AccessedAt: base.Add(2 * time.Minute)},
+	}
+	for _, entry := range entries {
+		if err := cache.RecordAccess(entry); err != nil {
+			t.Fatalf("RecordAccess failed: %v", err)
+		}
+	}
+
+	all, err := cache.AccessLog("", "", 0)
+	if err != nil {
+		t.Fatalf("AccessLog failed: %v", err)
+	}
+	if len(all) != 3 || all[0].SessionID != "s2" {
+		t.Fatalf("expected 3 entries newest first, got %+v", all)
+	}
+
+	s1, err := cache.AccessLog("s1", "claude", 1)
+	if err != nil {
+		t.Fatalf("AccessLog failed: %v", err)
+	}
+	if len(s1) != 1 || s1[0].Page != 1 || s1[0].StartIndex != 20 || s1[0].ClientVersion != "1.2" || !s1[0].AccessedAt.Equal(base.Add(time.Minute)) {
+		t.Fatalf("unexpected filtered entries: %+v", s1)
+	}
+
+	// Range reads record where they started instead of a page
+	if err := cache.RecordAccess(AccessEntry{SessionID: "s3", Source: "codex", ClientName: "codex", StartIndex: 120, MessageCount: 10}); err != nil {
+		t.Fatalf("RecordAccess failed: %v", err)
+	}
+	s3, err := cache.AccessLog("s3", "", 0)
+	if err != nil {
+		t.Fatalf("AccessLog failed: %v", err)
+	}
+	if len(s3) != 1 || s3[0].StartIndex != 120 || s3[0].PageSize != 0 {
+		t.Fatalf("unexpected range entry: %+v", s3)
+	}
+}
+
+func TestSearchWithRecencyRanker(t *testing.T) {
+	cache := newTempCache(t)
+	now := time.Now()
+
+	dir := t.TempDir()
+
+	// Same content, so BM25 ties and recency decides the order
+	sessions := []adapters.Session{
+		{ID: "old", Timestamp: now.Add(-365 * 24 * time.Hour)},
+		{ID: "new", Timestamp: now.Add(-time.Hour)},
+		{ID: "filler1", Timestamp: now},
+		{ID: "filler2", Timestamp: now},
+		{ID: "filler3", Timestamp: now},
+	}
+	for _, s := range sessions {
+		s.Source = "claude"
+		s.ProjectPath = "/p"
+		s.FilePath = filepath.Join(dir, s.ID+".jsonl")
+		if err := os.WriteFile(s.FilePath, []byte("{}"), 0o600); err != nil {
+			t.Fatalf("failed to write session file: %v", err)
+		}
+		content := "database migration rollback"
+		if strings.HasPrefix(s.ID, "filler") {
+			content = "unrelated notes"
+		}
+		if err := cache.IndexSession(s, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	bm25, err := cache.SearchWithRanker("migration", "", "", 10, "bm25")
+	if err != nil {
+		t.Fatalf("bm25 search failed: %v", err)
+	}
+	recency, err := cache.SearchWithRanker("migration", "", "", 10, "bm25_recency")
+	if err != nil {
+		t.Fatalf("recency search failed: %v", err)
+	}
+	if len(bm25) != 2 || len(recency) != 2 {
+		t.Fatalf("expected 2 results from each ranker, got %d and %d", len(bm25), len(recency))
+	}
+	if recency[0].Session.ID != "new" || recency[0].Score <= recency[1].Score {
+		t.Fatalf("expected newer session to rank first with recency, got %+v", recency)
+	}
+	if math.Abs(bm25[0].Score-bm25[1].Score) > 1e-9 {
+		t.Fatalf("expected BM25 scores to tie, got %v and %v", bm25[0].Score, bm25[1].Score)
+	}
+
+	if _, err := cache.SearchWithRanker("migration", "", "", 10, "nope"); err == nil {
+		t.Fatalf("expected unknown ranker to fail")
+	}
+	if err := cache.SetDefaultRanker("bm25_recency"); err != nil {
+		t.Fatalf("SetDefaultRanker failed: %v", err)
+	}
+	results, err := cache.Search("migration", "", "", 10)
+	if err != nil || len(results) == 0 || results[0].Session.ID != "new" {
+		t.Fatalf("expected default ranker to apply, got %+v (err %v)", results, err)
+	}
+}
+
+func TestSearchWithVectorRanker(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	for id, content := range map[string]string{
+		"focused": "database migration",
+		"diluted": "database migration among notes on lunch, parking, standups, and the offsite agenda",
+		"other":   "unrelated notes",
+	} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexMessages(id, []adapters.Message{{Role: "user", Content: content}}); err != nil {
+			t.Fatalf("IndexMessages failed: %v", err)
+		}
+	}
+
+	results, err := cache.SearchWithRanker("database migration", "", "", 10, "vector")
+	if err != nil {
+		t.Fatalf("vector search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Session.ID != "focused" || results[1].Session.ID != "diluted" {
+		t.Fatalf("expected the focused session to rank first, got %+v", results)
+	}
+	// A message that is exactly the query points the same way as it
+	if math.Abs(results[0].Score-1) > 1e-9 || results[1].Score <= 0 || results[1].Score >= 1 {
+		t.Fatalf("expected cosine similarities of 1 and between 0 and 1, got %v and %v", results[0].Score, results[1].Score)
+	}
+}
+
+func TestSearchWithHybridRanker(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	for id, content := range map[string]string{
+		"focused":  "database migration",
+		"repeated": strings.Repeat("database migration rollback plan with many other words ", 4),
+		"other1":   "unrelated notes",
+		"other2":   "lunch plans",
+		"other3":   "standup agenda",
+	} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexMessages(id, []adapters.Message{{Role: "user", Content: content}}); err != nil {
+			t.Fatalf("IndexMessages failed: %v", err)
+		}
+	}
+
+	results, err := cache.SearchWithRanker("database migration", "", "", 10, "hybrid")
+	if err != nil {
+		t.Fatalf("hybrid search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	keyword := make([]float64, len(results))
+	semantic := make([]float64, len(results))
+	for i, result := range results {
+		if result.KeywordScore == nil || result.SemanticScore == nil || *result.KeywordScore <= 0 || *result.SemanticScore <= 0 {
+			t.Fatalf("expected keyword and semantic scores on %s, got %v and %v", result.Session.ID, result.KeywordScore, result.SemanticScore)
+		}
+		keyword[i], semantic[i] = *result.KeywordScore, *result.SemanticScore
+	}
+	for i, want := range (FusionRanker{K: 60}).Fuse(keyword, semantic) {
+		if math.Abs(results[i].Score-want) > 1e-12 {
+			t.Fatalf("expected %s to score %v by rank fusion, got %v", results[i].Session.ID, want, results[i].Score)
+		}
+	}
+
+	results, err = cache.SearchWithRanker("database migration", "", "", 10, "bm25")
+	if err != nil || len(results) != 2 || results[0].KeywordScore != nil || results[0].SemanticScore != nil {
+		t.Fatalf("expected other rankers to leave the component scores out, got %+v (%v)", results, err)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	r := FusionRanker{K: 60}
+	// Opposite rankings fuse to a tie; ties within a ranking share a rank
+	fused := r.Fuse([]float64{3, 2, 2}, []float64{1, 2, 3})
+	if math.Abs(fused[0]-(1.0/61+1.0/63)) > 1e-12 || math.Abs(fused[1]-(1.0/62+1.0/62)) > 1e-12 || math.Abs(fused[2]-(1.0/62+1.0/61)) > 1e-12 {
+		t.Fatalf("unexpected fused scores %v", fused)
+	}
+	// A zero score leaves a result out of that ranking
+	fused = r.Fuse([]float64{1, 0}, []float64{0, 0})
+	if fused[0] != 1.0/61 || fused[1] != 0 {
+		t.Fatalf("unexpected fused scores %v", fused)
+	}
+}
+
+func TestBooleanAndPhraseQueries(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	for id, content := range map[string]string{
+		"refused":  "the postgres connection refused every request",
+		"reversed": "refused: the connection was reset by postgres",
+		"sqlite":   "sqlite migration rolled back",
+		"both":     "postgres and sqlite migration notes",
+	} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "postgres sqlite", want: []string{"both", "refused", "reversed", "sqlite"}},
+		{query: `"connection refused"`, want: []string{"refused"}},
+		{query: "postgres AND sqlite", want: []string{"both"}},
+		{query: "postgres OR sqlite", want: []string{"both", "refused", "reversed", "sqlite"}},
+		{query: "postgres -sqlite", want: []string{"refused", "reversed"}},
+		{query: "migration NOT postgres", want: []string{"sqlite"}},
+		{query: `postgres -"connection refused"`, want: []string{"both", "reversed"}},
+		{query: "(postgres OR sqlite) AND migration", want: []string{"both", "sqlite"}},
+		{query: "sqlite OR postgres AND refused", want: []string{"both", "refused", "reversed", "sqlite"}},
+		// Operators only count in upper case
+		{query: "postgres and", want: []string{"both", "refused", "reversed"}},
+	}
+	for _, tt := range tests {
+		results, err := cache.Search(tt.query, "", "", 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Session.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{`"unclosed`, "(postgres OR sqlite", "postgres)", "postgres AND", "OR sqlite", "-postgres", "NOT", `""`} {
+		if _, err := cache.Search(query, "", "", 10); !errors.Is(err, ErrQuerySyntax) {
+			t.Errorf("Search(%q) returned %v, want a query syntax error", query, err)
+		}
+	}
+}
+
+func TestPrefixQueries(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	for id, content := range map[string]string{
+		"deploy":     "deploy the api",
+		"deployment": "the deployment failed",
+		"rollout":    "staged rollout of the api",
+	} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "deploy*", want: []string{"deploy", "deployment"}},
+		{query: "DEPLOYM*", want: []string{"deployment"}},
+		{query: "deploy* -failed", want: []string{"deploy"}},
+		{query: "deploy* AND api", want: []string{"deploy"}},
+		{query: "roll*", want: []string{"rollout"}},
+		// A prefix no keyword starts with matches nothing
+		{query: "kube*", want: nil},
+		// Single letters are too short to expand, and are ignored like any
+		// single-letter keyword
+		{query: "d* rollout", want: []string{"rollout"}},
+	}
+	for _, tt := range tests {
+		results, err := cache.Search(tt.query, "", "", 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Session.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFieldQualifiers(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	sessions := []struct {
+		session  adapters.Session
+		messages []adapters.Message
+		models   []string
+		files    []string
+	}{
+		{
+			session: adapters.Session{ID: "asked", Source: "claude", Summary: "Fix the login flow"},
+			messages: []adapters.Message{
+				{Role: "user", Content: "why does the token expire"},
+				{Role: "assistant", Content: "the session cache is stale"},
+			},
+			models: []string{"claude-sonnet-4"},
+			files:  []string{"/repo/cmd/main.go"},
+		},
+		{
+			session: adapters.Session{ID: "answered", Source: "codex", FirstMessage: "login flow cleanup"},
+			messages: []adapters.Message{
+				{Role: "user", Content: "clean up the cache"},
+				{Role: "assistant", Content: "the token expire check moved"},
+			},
+			models: []string{"gpt-5-codex"},
+			files:  []string{"/repo/internal/main.go"},
+		},
+	}
+	for _, s := range sessions {
+		s.session.ProjectPath, s.session.FilePath, s.session.Timestamp = "/repo", filePath, time.Now()
+		var parts []string
+		for _, msg := range s.messages {
+			parts = append(parts, msg.Content)
+		}
+		if err := cache.IndexSession(s.session, strings.Join(parts, " ")); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexScopes(s.session.ID, s.messages); err != nil {
+			t.Fatalf("IndexScopes failed: %v", err)
+		}
+		if err := cache.IndexMessages(s.session.ID, s.messages); err != nil {
+			t.Fatalf("IndexMessages failed: %v", err)
+		}
+		if err := cache.IndexSessionModels(s.session.ID, s.models); err != nil {
+			t.Fatalf("IndexSessionModels failed: %v", err)
+		}
+		var touches []adapters.FileTouch
+		for _, file := range s.files {
+			touches = append(touches, adapters.FileTouch{Path: file, Reads: 1})
+		}
+		if err := cache.IndexSessionFiles(s.session.ID, touches); err != nil {
+			t.Fatalf("IndexSessionFiles failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "token source:claude", want: []string{"asked"}},
+		{query: "token -source:claude", want: []string{"answered"}},
+		{query: "token model:GPT-5", want: []string{"answered"}},
+		{query: "token file:main.go", want: []string{"answered", "asked"}},
+		{query: "token file:cmd/main.go", want: []string{"asked"}},
+		{query: "token file:d/main.go", want: nil},
+		{query: `token title:"login flow"`, want: []string{"answered", "asked"}},
+		{query: `token title:"flow login"`, want: nil},
+		{query: "token role:user", want: []string{"asked"}},
+		{query: "expire role:assistant", want: []string{"answered"}},
+		{query: "token (source:claude OR model:sonnet)", want: []string{"asked"}},
+		{query: "(cache AND source:codex) OR (token AND model:sonnet)", want: []string{"answered", "asked"}},
+		// Only known fields are qualifiers
+		{query: "http://token", want: []string{"answered", "asked"}},
+	}
+	var copies int
+	if err := cache.db.QueryRow("SELECT COUNT(*) FROM scoped_term_index WHERE scope LIKE 'role:%'").Scan(&copies); err != nil || copies != 0 {
+		t.Fatalf("expected role text to be selected from the message index, found %d rows of its own (%v)", copies, err)
+	}
+	for _, tt := range tests {
+		results, err := cache.Search(tt.query, "", "", 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Session.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"source:claude", "token role:system", "token -role:user", "token role:user role:assistant", "token model:", `token title:"unclosed`} {
+		if _, err := cache.Search(query, "", "", 10); !errors.Is(err, ErrQuerySyntax) {
+			t.Errorf("Search(%q) returned %v, want a query syntax error", query, err)
+		}
+	}
+	if _, err := cache.SearchInScope("token role:user", ScopeCode, "", "", 10, ""); !errors.Is(err, ErrQuerySyntax) {
+		t.Errorf("expected role: with the code scope to be a query syntax error, got %v", err)
+	}
+}
+
+func TestSearchBoostsTitleMatches(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	now := time.Now()
+	sessions := []struct {
+		session adapters.Session
+		body    string
+	}{
+		{
+			adapters.Session{ID: "body", Source: "claude", Timestamp: now, FilePath: filePath, FirstMessage: "look at the scheduler"},
+			"the scheduler hit a deadlock, another deadlock, and a third deadlock under load",
+		},
+		{
+			adapters.Session{ID: "title", Source: "claude", Timestamp: now, FilePath: filePath, FirstMessage: "look at the scheduler", Summary: "Scheduler deadlock"},
+			"the scheduler stalled under load once with a deadlock and then recovered",
+		},
+		{
+			// Matched only in the body, many times over
+			adapters.Session{ID: "heavy", Source: "claude", Timestamp: now, FilePath: filePath},
+			strings.Repeat("deadlock ", 20),
+		},
+	}
+	// With deadlock in half the sessions, its BM25 IDF and so every body score
+	// for it is zero, which leaves only the title to rank by
+	for i := 0; i < 3; i++ {
+		session := adapters.Session{ID: fmt.Sprintf("other%d", i), Source: "claude", Timestamp: now, FilePath: filePath}
+		if err := cache.IndexSession(session, "planning the team lunch"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+	for _, s := range sessions {
+		content := strings.Join([]string{s.session.FirstMessage, s.session.Summary, s.body}, " ")
+		if err := cache.IndexSession(s.session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+
+	results, err := cache.Search("deadlock", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Session.ID != "title" {
+		t.Fatalf("expected the summary match to rank first, got %+v", results)
+	}
+	wantTitle := []FieldMatch{{Field: MatchSummary, Terms: []string{"deadlock"}}, {Field: MatchContent, Terms: []string{"deadlock"}}}
+	if !reflect.DeepEqual(results[0].Fields, wantTitle) {
+		t.Fatalf("unexpected fields %+v, want %+v", results[0].Fields, wantTitle)
+	}
+	wantBody := []FieldMatch{{Field: MatchContent, Terms: []string{"deadlock"}}}
+	if !reflect.DeepEqual(results[1].Fields, wantBody) {
+		t.Fatalf("unexpected fields %+v, want %+v", results[1].Fields, wantBody)
+	}
+
+	results, err = cache.Search("scheduler deadlock", "", "", 10)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Search failed: %v (%v)", results, err)
+	}
+	// A first message match still outranks the strongest body-only match
+	if got := results[1].Fields[0]; results[1].Session.ID != "body" || got.Field != MatchFirstMessage || !reflect.DeepEqual(got.Terms, []string{"scheduler"}) {
+		t.Fatalf("expected the first message match to rank second, got %+v", results[1])
+	}
+}
+
+func TestSearchRanksByBestMessage(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	filler := strings.Repeat("unrelated chatter about lunch plans ", 40)
+
+	// A giant session with a short message about the deadlock, and a small
+	// one that mentions it in passing in a long message
+	var giant []adapters.Message
+	for i := 0; i < 200; i++ {
+		giant = append(giant, adapters.Message{Role: "assistant", Content: filler})
+	}
+	giant[57] = adapters.Message{Role: "assistant", Content: "the deadlock is in the worker pool"}
+	small := []adapters.Message{
+		{Role: "user", Content: "tidy up"},
+		{Role: "assistant", Content: filler + "deadlock " + filler},
+	}
+	for id, messages := range map[string][]adapters.Message{"giant": giant, "small": small} {
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath, Summary: "session " + id}
+		parts := []string{session.Summary}
+		for _, msg := range messages {
+			parts = append(parts, msg.Content)
+		}
+		if err := cache.IndexSession(session, strings.Join(parts, " ")); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexMessages(id, messages); err != nil {
+			t.Fatalf("IndexMessages failed: %v", err)
+		}
+	}
+
+	results, err := cache.Search("deadlock", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Session.ID != "giant" {
+		t.Fatalf("expected the giant session's focused message to rank first, got %+v", results)
+	}
+	if results[0].MessageIndex == nil || *results[0].MessageIndex != 57 || results[0].Snippet != giant[57].Content {
+		t.Fatalf("expected the match to point at message 57, got index %v and snippet %q", results[0].MessageIndex, results[0].Snippet)
+	}
+	if results[1].MessageIndex == nil || *results[1].MessageIndex != 1 {
+		t.Fatalf("expected the small session's match at message 1, got %v", results[1].MessageIndex)
+	}
+
+	// Terms found only outside messages still match, without a location
+	results, err = cache.Search("giant", "", "", 10)
+	if err != nil || len(results) != 1 || results[0].MessageIndex != nil {
+		t.Fatalf("expected a summary match without a message index, got %+v (%v)", results, err)
+	}
+}
+
+func TestSearchWithoutToolOutput(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	for id, messages := range map[string][]adapters.Message{
+		"dumped": {
+			{Role: "user", Content: "show me the config"},
+			{Role: "tool", Content: "retries: 3\ntimeout: 30s\nretries_backoff: exponential"},
+		},
+		"discussed": {
+			{Role: "user", Content: "how many retries should we allow"},
+			{Role: "assistant", Content: "Three retries with backoff is plenty."},
+		},
+	} {
+		var parts []string
+		for _, msg := range messages {
+			parts = append(parts, msg.Content)
+		}
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: "/p", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, strings.Join(parts, " ")); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexScopes(id, messages); err != nil {
+			t.Fatalf("IndexScopes failed: %v", err)
+		}
+		if err := cache.IndexMessages(id, messages); err != nil {
+			t.Fatalf("IndexMessages failed: %v", err)
+		}
+	}
+
+	var copies int
+	if err := cache.db.QueryRow("SELECT COUNT(*) FROM scoped_term_index WHERE scope = ?", ScopeConversation).Scan(&copies); err != nil || copies != 0 {
+		t.Fatalf("expected conversation text to be selected from the message index, found %d rows of its own (%v)", copies, err)
+	}
+
+	for scope, want := range map[string][]string{
+		ScopeAll:                     {"discussed", "dumped"},
+		WithoutToolOutput(ScopeAll):  {"discussed"},
+		WithoutToolOutput(ScopeCode): nil,
+	} {
+		results, err := cache.SearchInScope("retries", scope, "", "", 10, "")
+		if err != nil {
+			t.Fatalf("SearchInScope(%s) failed: %v", scope, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Session.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchInScope(%s) = %v, want %v", scope, got, want)
+		}
+	}
+	if WithoutToolOutput(ScopeProse) != ScopeProse {
+		t.Fatal("expected scopes without tool output to be left alone")
+	}
+}
+
+func TestDescribeSyntaxListsRankers(t *testing.T) {
+	syntax := DescribeSyntax()
+	if len(syntax.Rules) == 0 {
+		t.Fatalf("expected syntax rules")
+	}
+	if syntax.Default != DefaultRankerName {
+		t.Fatalf("expected default ranker %q, got %q", DefaultRankerName, syntax.Default)
+	}
+	found := false
+	for _, name := range syntax.Rankers {
+		if name == "bm25_recency" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected registered rankers to be listed, got %v", syntax.Rankers)
+	}
+}
+
+func TestMemoryCacheIndexesAndSearches(t *testing.T) {
+	cache, err := NewMemoryCache()
+	if err != nil {
+		t.Fatalf("NewMemoryCache failed: %v", err)
+	}
+	defer cache.Close()
+
+	other, err := NewMemoryCache()
+	if err != nil {
+		t.Fatalf("NewMemoryCache failed: %v", err)
+	}
+	defer other.Close()
+
+	filePath := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/p", FilePath: filePath, Timestamp: time.Now()}
+	if err := cache.IndexSession(session, "kubernetes deployment rollout"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+
+	results, err := cache.Search("rollout", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Session.ID != "s1" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	// Separate in-memory caches must not share data
+	results, err = other.Search("rollout", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected isolated memory caches, got %+v", results)
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	messages := []adapters.Message{
+		{Role: "user", Content: "Why does the login fail?"},
+		{Role: "assistant", Content: "Let me check the config."},
+		{Role: "assistant", Content: "The login token expired; refresh the Token and retry."},
+	}
+
+	matches := SearchMessages(messages, "token login login", 0)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	if matches[0].Index != 0 || strings.Join(matches[0].MatchedTerms, ",") != "login" {
+		t.Fatalf("unexpected first match: %+v", matches[0])
+	}
+	if matches[1].Index != 2 || strings.Join(matches[1].MatchedTerms, ",") != "token,login" {
+		t.Fatalf("unexpected second match: %+v", matches[1])
+	}
+	if !strings.Contains(matches[1].Snippet, "token") {
+		t.Fatalf("expected snippet around the match, got %q", matches[1].Snippet)
+	}
+
+	if matches := SearchMessages(messages, "?!", 0); matches != nil {
+		t.Fatalf("expected no matches for a query without terms, got %+v", matches)
+	}
+}
+
+func TestSearchInProseScope(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	explained := []adapters.Message{
+		{Role: "user", Content: "How does oauth refresh work here?"},
+		{Role: "assistant", Content: "The oauth refresh token is exchanged before expiry.\n```go\nrefresh()\n```"},
+	}
+	coded := []adapters.Message{
+		{Role: "user", Content: "Add oauth refresh"},
+		{Role: "assistant", Content: "Done.\n```go\nfunc oauthRefresh() { refresh() }\n```"},
+	}
+	for id, messages := range map[string][]adapters.Message{"explained": explained, "coded": coded} {
+		session := adapters.Session{ID: id, Source: "claude", Timestamp: time.Now(), FilePath: filePath}
+		if err := cache.IndexSession(session, messages[0].Content+" "+messages[1].Content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexScopes(id, messages); err != nil {
+			t.Fatalf("IndexScopes failed: %v", err)
+		}
+	}
+
+	all, err := cache.Search("refresh", "", "", 10)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected both sessions without a scope, got %+v (%v)", all, err)
+	}
+
+	prose, err := cache.SearchInScope("refresh", ScopeProse, "", "", 10, "")
+	if err != nil {
+		t.Fatalf("SearchInScope failed: %v", err)
+	}
+	if len(prose) != 1 || prose[0].Session.ID != "explained" {
+		t.Fatalf("expected only the session that explained it, got %+v", prose)
+	}
+	if strings.Contains(prose[0].Snippet, "```") || strings.Contains(prose[0].Snippet, "How does") {
+		t.Fatalf("expected a snippet from the assistant's prose, got %q", prose[0].Snippet)
+	}
+
+	if _, err := cache.SearchInScope("refresh", "comments", "", "", 10, ""); err == nil {
+		t.Fatal("expected an error for an unknown scope")
+	}
+
+	matches := SearchMessagesInScope(explained, "refresh", ScopeProse, 0)
+	if len(matches) != 1 || matches[0].Index != 1 {
+		t.Fatalf("expected the assistant message only, got %+v", matches)
+	}
+
+	code, err := cache.SearchInScope("oauthrefresh", ScopeCode, "", "", 10, "")
+	if err != nil {
+		t.Fatalf("SearchInScope failed: %v", err)
+	}
+	if len(code) != 1 || code[0].Session.ID != "coded" {
+		t.Fatalf("expected only the session that wrote the function, got %+v", code)
+	}
+	if prose, _ := cache.SearchInScope("oauthrefresh", ScopeProse, "", "", 10, ""); len(prose) != 0 {
+		t.Fatalf("expected no prose matches for code, got %+v", prose)
+	}
+}
+
+func TestScopeTextCode(t *testing.T) {
+	msg := adapters.Message{
+		Role:    "assistant",
+		Content: "Writing the config:\n```yaml\nretries: 3\n```",
+		Metadata: map[string]interface{}{"raw_content": []interface{}{
+			map[string]interface{}{"type": "tool_use", "name": "Write", "input": map[string]interface{}{"file_path": "a.go", "content": "package a"}},
+			map[string]interface{}{"type": "tool_use", "name": "Bash", "input": map[string]interface{}{"command": "go test"}},
+		}},
+	}
+	if got, want := ScopeText(msg, ScopeCode), "retries: 3\npackage a"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got := ScopeText(adapters.Message{Role: "user", Content: "```\npasted\n```"}, ScopeCode); got != "" {
+		t.Fatalf("expected code the user pasted to be left out, got %q", got)
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	cache := newTempCache(t)
+
+	filePath := filepath.Join(t.TempDir(), "s.jsonl")
+	if err := os.WriteFile(filePath, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	session := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/p", FilePath: filePath, Timestamp: time.Now()}
+	if err := cache.IndexSession(session, "terraform state migration"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := cache.Snapshot(snapshotPath); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := cache.Snapshot(snapshotPath); err == nil {
+		t.Fatal("expected an error when the snapshot file already exists")
+	}
+
+	restoredPath := filepath.Join(t.TempDir(), "restored", "search.db")
+	info, err := RestoreSnapshot(snapshotPath, restoredPath)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if info.Sessions != 1 {
+		t.Fatalf("expected 1 session in snapshot, got %d", info.Sessions)
+	}
+
+	restored, err := NewCache(restoredPath)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	defer restored.Close()
+
+	results, err := restored.Search("terraform", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Session.ID != "s1" {
+		t.Fatalf("unexpected results after restore: %+v", results)
+	}
+	if needs, err := restored.NeedsReindex("s1", filePath); err != nil || needs {
+		t.Fatalf("expected restored session to be up to date, got needs=%v err=%v", needs, err)
+	}
+}
+
+func TestRestoreSnapshotRejectsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	bogus := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(bogus, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RestoreSnapshot(bogus, filepath.Join(dir, "search.db")); err == nil {
+		t.Fatal("expected restoring a non-cache file to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "search.db")); !os.IsNotExist(err) {
+		t.Fatalf("expected no cache to be written, got err=%v", err)
+	}
+}
+
+func TestGroupByTask(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	sessions := []adapters.Session{
+		{ID: "c1", Source: "claude", FirstMessage: "Add pagination to the users API endpoint", Timestamp: day},
+		{ID: "x1", Source: "codex", FirstMessage: "add pagination to the users API endpoint please", Timestamp: day.Add(time.Hour)},
+		{ID: "g1", Source: "gemini", FirstMessage: "Write a migration for the orders table", Timestamp: day.Add(2 * time.Hour)},
+		{ID: "c2", Source: "claude", FirstMessage: "continue", Timestamp: day.Add(3 * time.Hour)},
+		{ID: "c3", Source: "claude", FirstMessage: "continue", Timestamp: day.Add(4 * time.Hour)},
+	}
+
+	groups := GroupByTask(sessions, 0)
+	if len(groups) != 1 {
+		t.Fatalf("expected one group, got %+v", groups)
+	}
+	if len(groups[0]) != 2 || groups[0][0].ID != "c1" || groups[0][1].ID != "x1" {
+		t.Fatalf("expected c1 then x1, got %+v", groups[0])
+	}
+
+	if sim := Jaccard([]string{"a", "b"}, []string{"b", "c"}); math.Abs(sim-1.0/3) > 1e-9 {
+		t.Fatalf("expected Jaccard 1/3, got %v", sim)
+	}
+}
+
+func TestFindSessionsByFile(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	now := time.Now()
+	older := adapters.Session{ID: "older", Source: "claude", ProjectPath: "/src/app", Timestamp: now.Add(-time.Hour), FilePath: filePath}
+	newer := adapters.Session{ID: "newer", Source: "codex", ProjectPath: "/src/app", Timestamp: now, FilePath: filePath}
+	for _, s := range []adapters.Session{older, newer} {
+		if err := cache.IndexSession(s, "session content"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+	if err := cache.IndexSessionFiles("older", []adapters.FileTouch{
+		{Path: "/src/app/main.go", Reads: 2},
+		{Path: "/src/app/main.go", Edits: 1},
+		{Path: "/src/app2/other.go", Writes: 1},
+	}); err != nil {
+		t.Fatalf("IndexSessionFiles failed: %v", err)
+	}
+	if err := cache.IndexSessionFiles("newer", []adapters.FileTouch{{Path: "/src/app/util/helpers.go", Edits: 3}}); err != nil {
+		t.Fatalf("IndexSessionFiles failed: %v", err)
+	}
+
+	matches, err := cache.FindSessionsByFile("/src/app/main.go", false, "", "", 0)
+	if err != nil {
+		t.Fatalf("FindSessionsByFile failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Session.ID != "older" {
+		t.Fatalf("expected only the older session for an exact path, got %+v", matches)
+	}
+	if want := (adapters.FileTouch{Path: "/src/app/main.go", Reads: 2, Edits: 1}); len(matches[0].Files) != 1 || matches[0].Files[0] != want {
+		t.Fatalf("expected duplicate paths to be merged into %+v, got %+v", want, matches[0].Files)
+	}
+
+	matches, err = cache.FindSessionsByFile("/src/app/", true, "", "", 0)
+	if err != nil {
+		t.Fatalf("FindSessionsByFile failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Session.ID != "newer" || matches[1].Session.ID != "older" {
+		t.Fatalf("expected both sessions, most recent first, got %+v", matches)
+	}
+	if len(matches[1].Files) != 1 {
+		t.Fatalf("expected /src/app2 to be excluded by the trailing slash, got %+v", matches[1].Files)
+	}
+
+	if matches, err = cache.FindSessionsByFile("/src/app/", true, "claude", "", 0); err != nil || len(matches) != 1 {
+		t.Fatalf("expected source filter to leave one session, got %+v (%v)", matches, err)
+	}
+	if matches, err = cache.FindSessionsByFile("/src/app/", true, "", "", 1); err != nil || len(matches) != 1 || matches[0].Session.ID != "newer" {
+		t.Fatalf("expected limit to keep the most recent session, got %+v (%v)", matches, err)
+	}
+}
+
+func TestSessionFilesBackfillReindexesOnce(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "cache.db")
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	cache, err := NewCache(dbPath)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	if err := cache.IndexSession(adapters.Session{ID: "s1", Source: "claude", Timestamp: time.Now(), FilePath: filePath}, "content"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	// Simulate a cache written before session files were tracked
+	if _, err := cache.db.Exec("UPDATE search_stats SET value = 0 WHERE key = 'session_files_backfilled'"); err != nil {
+		t.Fatalf("reset backfill marker: %v", err)
+	}
+	if _, err := cache.db.Exec("PRAGMA user_version = 0"); err != nil {
+		t.Fatalf("reset schema version: %v", err)
+	}
+	cache.Close()
+
+	for i, want := range []bool{true, false} {
+		cache, err := NewCache(dbPath)
+		if err != nil {
+			t.Fatalf("NewCache failed: %v", err)
+		}
+		needs, err := cache.NeedsReindex("s1", filePath)
+		if err != nil || needs != want {
+			t.Fatalf("open %d: NeedsReindex = %v, %v; want %v", i, needs, err, want)
+		}
+		if err := cache.IndexSession(adapters.Session{ID: "s1", Source: "claude", Timestamp: time.Now(), FilePath: filePath}, "content"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		cache.Close()
+	}
+}
+
+func TestRelatedSessions(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	now := time.Now()
+	index := func(id, project, content string, files ...string) {
+		t.Helper()
+		session := adapters.Session{ID: id, Source: "claude", ProjectPath: project, Timestamp: now, FilePath: filePath}
+		if err := cache.IndexSession(session, content); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		touches := make([]adapters.FileTouch, len(files))
+		for i, f := range files {
+			touches[i] = adapters.FileTouch{Path: f, Edits: 1}
+		}
+		if err := cache.IndexSessionFiles(id, touches); err != nil {
+			t.Fatalf("IndexSessionFiles failed: %v", err)
+		}
+	}
+
+	index("target", "/src/app", "migrate the billing webhook handler to stripe events and retry failed invoices", "/src/app/billing.go", "/src/app/webhook.go")
+	index("text", "/src/other", "stripe webhook retries for failed invoices in billing")
+	index("files", "/src/app", "rename a helper", "/src/app/webhook.go")
+	for i, content := range []string{"update readme", "fix flaky login test", "bump dependencies", "add dark mode toggle"} {
+		index(fmt.Sprintf("unrelated-%d", i), "/src/misc", content)
+	}
+
+	related, err := cache.RelatedSessions("target", 0)
+	if err != nil {
+		t.Fatalf("RelatedSessions failed: %v", err)
+	}
+	if len(related) != 2 {
+		t.Fatalf("expected the text and file matches only, got %+v", related)
+	}
+
+	byID := map[string]RelatedSession{}
+	for _, r := range related {
+		byID[r.Session.ID] = r
+	}
+	if r := byID["text"]; r.TextScore != 1 || r.SameProject || len(r.SharedFiles) != 0 {
+		t.Errorf("unexpected text match: %+v", r)
+	}
+	if r := byID["files"]; !r.SameProject || len(r.SharedFiles) != 1 || r.SharedFiles[0] != "/src/app/webhook.go" {
+		t.Errorf("unexpected file match: %+v", r)
+	}
+	if want := relatedFilesWeight*0.5 + relatedProjectWeight; math.Abs(byID["files"].Score-want) > 0.001 {
+		t.Errorf("expected file match score %.3f, got %.3f", want, byID["files"].Score)
+	}
+
+	if _, err := cache.RelatedSessions("missing", 0); err == nil {
+		t.Error("expected an error for a session that isn't indexed")
+	}
+}
+
+func TestChangeCheckpoints(t *testing.T) {
+	cache := newTempCache(t)
+
+	if _, ok, err := cache.ChangeCheckpoint("codex"); err != nil || ok {
+		t.Fatalf("expected no checkpoint yet, got ok=%v err=%v", ok, err)
+	}
+
+	first := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{first, first.Add(time.Hour)} {
+		if err := cache.SetChangeCheckpoint("codex", at); err != nil {
+			t.Fatalf("SetChangeCheckpoint failed: %v", err)
+		}
+	}
+
+	got, ok, err := cache.ChangeCheckpoint("codex")
+	if err != nil || !ok || !got.Equal(first.Add(time.Hour)) {
+		t.Fatalf("expected the latest checkpoint, got %v ok=%v err=%v", got, ok, err)
+	}
+	if _, ok, _ := cache.ChangeCheckpoint("claude-code"); ok {
+		t.Fatal("checkpoints should be per client")
+	}
+}
+
+func TestSessionNotes(t *testing.T) {
+	cache := newTempCache(t)
+
+	anchor := 12
+	for _, note := range []Note{
+		{SessionID: "s1", Source: "claude", MessageIndex: &anchor, Text: "migration strategy decided here"},
+		{SessionID: "s1", Source: "claude", Text: "spike on the new queue"},
+		{SessionID: "s1", Source: "codex", Text: "same ID, other source"},
+		{SessionID: "s2", Source: "claude", Text: "abandoned"},
+	} {
+		if _, err := cache.AddNote(note); err != nil {
+			t.Fatalf("AddNote failed: %v", err)
+		}
+	}
+
+	notes, err := cache.Notes("s1", "claude")
+	if err != nil {
+		t.Fatalf("Notes failed: %v", err)
+	}
+	if len(notes) != 2 || notes[0].MessageIndex != nil || notes[1].MessageIndex == nil || *notes[1].MessageIndex != 12 {
+		t.Fatalf("expected the session note before the anchored one, got %+v", notes)
+	}
+
+	bySession, err := cache.NotesForSessions([]adapters.Session{{ID: "s1", Source: "claude"}, {ID: "s3", Source: "claude"}})
+	if err != nil {
+		t.Fatalf("NotesForSessions failed: %v", err)
+	}
+	if len(bySession) != 1 || len(bySession["s1"]) != 2 {
+		t.Fatalf("expected the two claude notes on s1, got %+v", bySession)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	cache := newTempCache(t)
+
+	first, err := cache.AddBookmark(Bookmark{SessionID: "s1", Source: "claude", ProjectPath: "/app", MessageIndex: 4, Label: "schema", Preview: "Let's add a column", CreatedAt: time.UnixMilli(1000)})
+	if err != nil {
+		t.Fatalf("AddBookmark failed: %v", err)
+	}
+	if _, err := cache.AddBookmark(Bookmark{SessionID: "s2", Source: "codex", ProjectPath: "/other", MessageIndex: 0, Label: "start", CreatedAt: time.UnixMilli(2000)}); err != nil {
+		t.Fatalf("AddBookmark failed: %v", err)
+	}
+	// Bookmarking the same message again relabels it
+	again, err := cache.AddBookmark(Bookmark{SessionID: "s1", Source: "claude", ProjectPath: "/app", MessageIndex: 4, Label: "schema decision", CreatedAt: time.UnixMilli(3000)})
+	if err != nil {
+		t.Fatalf("AddBookmark failed: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Fatalf("expected the bookmark to be updated in place, got IDs %d and %d", first.ID, again.ID)
+	}
+
+	all, err := cache.Bookmarks("", "", "", 0)
+	if err != nil {
+		t.Fatalf("Bookmarks failed: %v", err)
+	}
+	if len(all) != 2 || all[0].Label != "schema decision" || all[1].SessionID != "s2" {
+		t.Fatalf("expected two bookmarks, newest first, got %+v", all)
+	}
+
+	filtered, err := cache.Bookmarks("", "", "/other", 0)
+	if err != nil || len(filtered) != 1 || filtered[0].SessionID != "s2" {
+		t.Fatalf("expected the /other bookmark only, got %+v (%v)", filtered, err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	cache := newTempCache(t)
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	toolUse := map[string]interface{}{"raw_content": []interface{}{
+		map[string]interface{}{"type": "tool_use", "name": "Bash", "input": map[string]interface{}{"command": "ls"}},
+	}}
+	sessions := []struct {
+		session  adapters.Session
+		messages []adapters.Message
+	}{
+		{adapters.Session{ID: "long", Source: "claude", ProjectPath: "/src/app", Timestamp: day1, FilePath: filePath}, []adapters.Message{
+			{Role: "user", Content: "start", Timestamp: day1},
+			{Role: "assistant", Content: "running", Timestamp: day1, Metadata: toolUse},
+			{Role: "user", Content: "continue", Timestamp: day2},
+			{Role: "assistant", Content: "done"}, // no timestamp: counts toward the session start
+		}},
+		{adapters.Session{ID: "other", Source: "codex", ProjectPath: "/src/lib", Timestamp: day2, FilePath: filePath}, []adapters.Message{
+			{Role: "user", Content: "hello", Timestamp: day2},
+		}},
+	}
+	for _, s := range sessions {
+		if err := cache.IndexSession(s.session, "content"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+		if err := cache.IndexSessionActivity(s.session, s.messages); err != nil {
+			t.Fatalf("IndexSessionActivity failed: %v", err)
+		}
+	}
+
+	rows, err := cache.Usage(UsageByDay, day1.Add(-time.Hour), "", "")
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	want := []UsageRow{
+		{Group: "2025-03-01", ProjectPath: "/src/app", SessionsStarted: 1, ActiveSessions: 1, Messages: 3, Prompts: 1, ToolCalls: 1},
+		{Group: "2025-03-02", ProjectPath: "/src/app", ActiveSessions: 1, Messages: 1, Prompts: 1},
+		{Group: "2025-03-02", ProjectPath: "/src/lib", SessionsStarted: 1, ActiveSessions: 1, Messages: 1, Prompts: 1},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("unexpected daily usage:\n got %+v\nwant %+v", rows, want)
+	}
+
+	rows, err = cache.Usage("", day2, "claude", "")
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Messages != 1 || rows[0].SessionsStarted != 0 || rows[0].ActiveSessions != 1 {
+		t.Fatalf("expected one claude row for the second day only, got %+v", rows)
+	}
+
+	if _, err := cache.Usage("hour", day1, "", ""); err == nil {
+		t.Fatal("expected an error for an unknown grouping")
+	}
+}
+
+func TestReviewStaleSessions(t *testing.T) {
+	cache := newTempCache(t)
+	dir := t.TempDir()
+	keptFile := filepath.Join(dir, "kept.jsonl")
+	goneFile := filepath.Join(dir, "gone.jsonl")
+	for _, path := range []string{keptFile, goneFile} {
+		if err := os.WriteFile(path, []byte("test"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+	}
+	for id, path := range map[string]string{"kept": keptFile, "gone": goneFile} {
+		if err := cache.IndexSession(adapters.Session{ID: id, Source: "claude", Timestamp: time.Now(), FilePath: path}, "stale keyword"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+	if err := os.Remove(goneFile); err != nil {
+		t.Fatalf("remove session file: %v", err)
+	}
+
+	now := time.Now()
+	grace := 24 * time.Hour
+	review, err := cache.ReviewStaleSessions(now, grace)
+	if err != nil {
+		t.Fatalf("ReviewStaleSessions failed: %v", err)
+	}
+	if review != (StaleReview{Quarantined: 1, Pending: 1}) {
+		t.Fatalf("expected the missing session to be quarantined, got %+v", review)
+	}
+	if results, err := cache.Search("stale keyword", "", "", 10); err != nil || len(results) != 2 {
+		t.Fatalf("expected a quarantined session to stay searchable, got %d results (%v)", len(results), err)
+	}
+
+	review, err = cache.ReviewStaleSessions(now.Add(grace+time.Hour), grace)
+	if err != nil {
+		t.Fatalf("ReviewStaleSessions failed: %v", err)
+	}
+	if review != (StaleReview{Removed: 1}) {
+		t.Fatalf("expected the session to be removed after the grace period, got %+v", review)
+	}
+	results, err := cache.Search("stale keyword", "", "", 10)
+	if err != nil || len(results) != 1 || results[0].Session.ID != "kept" {
+		t.Fatalf("expected only the kept session to remain, got %+v (%v)", results, err)
+	}
+
+	info, err := cache.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Sessions != 1 || info.Quarantined != 0 || info.Bytes == 0 || info.LastIndexed == nil {
+		t.Fatalf("unexpected index info %+v", info)
+	}
+	if _, after, err := cache.Vacuum(); err != nil || after == 0 {
+		t.Fatalf("Vacuum = %d, %v", after, err)
+	}
+}
+
+func TestReviewStaleSessionsRestoresReturningFiles(t *testing.T) {
+	cache := newTempCache(t)
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	if err := cache.IndexSession(adapters.Session{ID: "s1", Source: "claude", Timestamp: time.Now(), FilePath: path}, "content"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	if err := os.Rename(path, path+".moved"); err != nil {
+		t.Fatalf("move session file: %v", err)
+	}
+	if _, err := cache.ReviewStaleSessions(time.Now(), time.Hour); err != nil {
+		t.Fatalf("ReviewStaleSessions failed: %v", err)
+	}
+	if err := os.Rename(path+".moved", path); err != nil {
+		t.Fatalf("restore session file: %v", err)
+	}
+
+	review, err := cache.ReviewStaleSessions(time.Now().Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("ReviewStaleSessions failed: %v", err)
+	}
+	if review != (StaleReview{Restored: 1}) {
+		t.Fatalf("expected the session to be restored, got %+v", review)
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	cache := newTempCache(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		ts := base.Add(d)
+		return &ts
+	}
+
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	older := adapters.Session{ID: "s1", Source: "claude", ProjectPath: "/app", Timestamp: base, FirstMessage: "Add login", FilePath: filePath}
+	if err := cache.IndexSession(older, "content"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	err := cache.IndexFileChanges(older, []adapters.FileChange{
+		{Path: "/app/auth.go", Kind: adapters.FileWrite, Tool: "Write", MessageIndex: 2, Timestamp: at(0), Preview: "package app"},
+		{Path: "/app/main.go", Kind: adapters.FileEdit, Tool: "Edit", MessageIndex: 4},
+	})
+	if err != nil {
+		t.Fatalf("IndexFileChanges failed: %v", err)
+	}
+	// Never indexed as a session, as if its file had been deleted since
+	newer := adapters.Session{ID: "s2", Source: "codex", ProjectPath: "/app", Timestamp: base.Add(time.Hour)}
+	if err := cache.IndexFileChanges(newer, []adapters.FileChange{
+		{Path: "/app/auth.go", Kind: adapters.FileEdit, Tool: "apply_patch", MessageIndex: 1, Timestamp: at(2 * time.Hour)},
+	}); err != nil {
+		t.Fatalf("IndexFileChanges failed: %v", err)
+	}
+
+	history, err := cache.FileHistory("/app/auth.go", false, "", "", time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("FileHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].SessionID != "s2" || history[0].FirstMessage != "" {
+		t.Fatalf("expected the newer change first, from a session no longer indexed, got %+v", history)
+	}
+	if got := history[1]; got.SessionID != "s1" || got.FirstMessage != "Add login" || got.MessageIndex != 2 || got.Preview != "package app" {
+		t.Fatalf("unexpected older change %+v", got)
+	}
+
+	history, err = cache.FileHistory("/app/", true, "claude", "/app", time.Time{}, 0)
+	if err != nil || len(history) != 2 || history[0].Path != "/app/main.go" || !history[0].Timestamp.Equal(base) {
+		t.Fatalf("expected both claude changes, undated ones at the session's time, got %+v (%v)", history, err)
+	}
+	history, err = cache.FileHistory("/app/auth.go", false, "", "", base.Add(time.Hour), 0)
+	if err != nil || len(history) != 1 || history[0].SessionID != "s2" {
+		t.Fatalf("expected since to drop the older change, got %+v (%v)", history, err)
+	}
+
+	// Reindexing a session replaces its changes
+	if err := cache.IndexFileChanges(older, nil); err != nil {
+		t.Fatalf("IndexFileChanges failed: %v", err)
+	}
+	if history, err := cache.FileHistory("/app/", true, "", "", time.Time{}, 0); err != nil || len(history) != 1 {
+		t.Fatalf("expected only the codex change to remain, got %+v (%v)", history, err)
+	}
+}
+
+func TestForgetSession(t *testing.T) {
+	cache := newTempCache(t)
+	path := filepath.Join(t.TempDir(), "secret.jsonl")
+	if err := os.WriteFile(path, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	session := adapters.Session{ID: "secret", Source: "claude", ProjectPath: "/work/app", Timestamp: time.Now(), FilePath: path}
+	if err := cache.IndexSession(session, "api key sk-live"); err != nil {
+		t.Fatalf("IndexSession failed: %v", err)
+	}
+	if err := cache.IndexFileChanges(session, []adapters.FileChange{{Path: "/work/app/.env", Kind: "write", Tool: "Write"}}); err != nil {
+		t.Fatalf("IndexFileChanges failed: %v", err)
+	}
+
+	// A session of the same ID from another source isn't the indexed one
+	now := time.Now()
+	if removed, err := cache.ForgetSession("secret", "codex", now); err != nil || removed {
+		t.Fatalf("expected nothing removed for another source, got %v (%v)", removed, err)
+	}
+	removed, err := cache.ForgetSession("secret", "claude", now.Add(time.Second))
+	if err != nil || !removed {
+		t.Fatalf("expected the session to be removed, got %v (%v)", removed, err)
+	}
+	if results, err := cache.Search("sk-live", "", "", 10); err != nil || len(results) != 0 {
+		t.Fatalf("expected no results for a forgotten session, got %+v (%v)", results, err)
+	}
+	if history, err := cache.FileHistory("/work/app/.env", false, "", "", time.Time{}, 10); err != nil || len(history) != 0 {
+		t.Fatalf("expected the session's file changes to be forgotten, got %+v (%v)", history, err)
+	}
+	if forgotten, err := cache.IsForgotten("secret", "claude"); err != nil || !forgotten {
+		t.Fatalf("expected a tombstone, got %v (%v)", forgotten, err)
+	}
+	list, err := cache.ForgottenSessions()
+	if err != nil || len(list) != 2 || list[0].Source != "claude" {
+		t.Fatalf("expected both tombstones, latest first, got %+v (%v)", list, err)
+	}
+
+	if restored, err := cache.UnforgetSession("secret", "claude"); err != nil || !restored {
+		t.Fatalf("expected the tombstone to be removed, got %v (%v)", restored, err)
+	}
+	if forgotten, err := cache.IsForgotten("secret", "claude"); err != nil || forgotten {
+		t.Fatalf("expected no tombstone after unforgetting, got %v (%v)", forgotten, err)
+	}
+}
+
+func TestStatusAndResetIndex(t *testing.T) {
+	cache := newTempCache(t)
+	dir := t.TempDir()
+	for i, source := range []string{"claude", "claude", "codex"} {
+		path := filepath.Join(dir, fmt.Sprintf("s%d.jsonl", i))
+		if err := os.WriteFile(path, []byte("test"), 0o644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+		session := adapters.Session{ID: fmt.Sprintf("s%d", i), Source: source, Timestamp: time.Now(), FilePath: path}
+		if err := cache.IndexSession(session, "rebuild keyword"); err != nil {
+			t.Fatalf("IndexSession failed: %v", err)
+		}
+	}
+	if _, err := cache.ForgetSession("gone", "claude", time.Now()); err != nil {
+		t.Fatalf("ForgetSession failed: %v", err)
+	}
+
+	status, err := cache.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Sessions != 3 || status.Forgotten != 1 || status.InMemory || status.DiskBytes == 0 || status.Path == "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if len(status.Sources) != 2 || status.Sources[0].Source != "claude" || status.Sources[0].Sessions != 2 || status.Sources[0].LastIndexed == nil {
+		t.Fatalf("expected claude then codex, got %+v", status.Sources)
+	}
+
+	removed, err := cache.ResetIndex("codex")
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 codex session removed, got %d (%v)", removed, err)
+	}
+	if results, err := cache.Search("rebuild keyword", "", "", 10); err != nil || len(results) != 2 {
+		t.Fatalf("expected the claude sessions to remain, got %d (%v)", len(results), err)
+	}
+
+	removed, err = cache.ResetIndex("")
+	if err != nil || removed != 2 {
+		t.Fatalf("expected the 2 remaining sessions removed, got %d (%v)", removed, err)
+	}
+	if status, err = cache.Status(); err != nil || status.Sessions != 0 || len(status.Sources) != 0 || status.Forgotten != 1 {
+		t.Fatalf("expected an empty index that still remembers forgotten sessions, got %+v (%v)", status, err)
+	}
+
+	memory, err := NewMemoryCache()
+	if err != nil {
+		t.Fatalf("NewMemoryCache failed: %v", err)
+	}
+	defer memory.Close()
+	if status, err := memory.Status(); err != nil || !status.InMemory || status.Path != "" {
+		t.Fatalf("expected an in-memory status, got %+v (%v)", status, err)
+	}
+}
+
+func TestOpenBackends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	store, err := Open(BackendSQLite, path)
+	if err != nil {
+		t.Fatalf("Open(sqlite) returned error: %v", err)
+	}
+	store.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the sqlite backend to create %s: %v", path, err)
+	}
+
+	store, err = Open(BackendMemory, "")
+	if err != nil {
+		t.Fatalf("Open(memory) returned error: %v", err)
+	}
+	if status, err := store.Status(); err != nil || !status.InMemory {
+		t.Fatalf("expected an in-memory store, got %+v (%v)", status, err)
+	}
+	store.Close()
+
+	if _, err := Open("postgres", "postgres://localhost/index"); err == nil || !strings.Contains(err.Error(), "memory, sqlite") {
+		t.Fatalf("expected an unknown backend error listing the backends, got %v", err)
+	}
+	if _, err := Open(BackendSQLite, ""); err == nil {
+		t.Fatal("expected the sqlite backend to require a path")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a backend twice to panic")
+		}
+	}()
+	RegisterBackend(BackendSQLite, func(string) (Store, error) { return nil, nil })
+}
+
+func TestSessionMetadataCache(t *testing.T) {
+	cache := newTempCache(t)
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	session := adapters.Session{
+		ID:               "s1",
+		Source:           "codex",
+		ProjectPath:      "/p",
+		FirstMessage:     "fix the build",
+		Timestamp:        time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC),
+		UserMessageCount: 3,
+		FilePath:         "/sessions/s1.jsonl",
+	}
+
+	if _, ok := cache.LoadSessionMetadata(session.FilePath, modTime, 100); ok {
+		t.Fatal("expected a miss before anything is stored")
+	}
+	cache.StoreSessionMetadata(session.FilePath, modTime, 100, session)
+	got, ok := cache.LoadSessionMetadata(session.FilePath, modTime, 100)
+	if !ok || !reflect.DeepEqual(got, session) {
+		t.Fatalf("expected the stored session back, got %+v (%v)", got, ok)
+	}
+	if _, ok := cache.LoadSessionMetadata(session.FilePath, modTime.Add(time.Nanosecond), 100); ok {
+		t.Fatal("expected a miss after the file's modification time changed")
+	}
+	if _, ok := cache.LoadSessionMetadata(session.FilePath, modTime, 101); ok {
+		t.Fatal("expected a miss after the file's size changed")
+	}
+
+	if _, err := cache.ResetIndex("claude"); err != nil {
+		t.Fatalf("ResetIndex returned error: %v", err)
+	}
+	if _, ok := cache.LoadSessionMetadata(session.FilePath, modTime, 100); !ok {
+		t.Fatal("resetting another source should keep the metadata")
+	}
+	if _, err := cache.ResetIndex("codex"); err != nil {
+		t.Fatalf("ResetIndex returned error: %v", err)
+	}
+	if _, ok := cache.LoadSessionMetadata(session.FilePath, modTime, 100); ok {
+		t.Fatal("resetting the source should clear its metadata")
+	}
+}
+
+func TestMessageOffsetsCache(t *testing.T) {
+	cache := newTempCache(t)
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	offsets := []adapters.MessageOffset{{Offset: 0}, {Offset: 120, State: []byte(`{"model":"gpt-5"}`)}}
+
+	cache.StoreMessageOffsets("codex", "/sessions/a.jsonl", modTime, 300, offsets)
+	got, ok := cache.LoadMessageOffsets("/sessions/a.jsonl", modTime, 300)
+	if !ok || !reflect.DeepEqual(got, offsets) {
+		t.Fatalf("expected the stored offsets back, got %+v (%v)", got, ok)
+	}
+	if _, ok := cache.LoadMessageOffsets("/sessions/a.jsonl", modTime, 301); ok {
+		t.Fatal("expected a miss after the file's size changed")
+	}
+
+	if _, err := cache.ResetIndex(""); err != nil {
+		t.Fatalf("ResetIndex returned error: %v", err)
+	}
+	if _, ok := cache.LoadMessageOffsets("/sessions/a.jsonl", modTime, 300); ok {
+		t.Fatal("a full reset should clear cached offsets")
+	}
+}
+
+func TestSessionPathCache(t *testing.T) {
+	cache := newTempCache(t)
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 42, time.UTC)
+
+	if _, _, ok := cache.LookupSessionPath("mistral", "s1"); ok {
+		t.Fatal("expected a miss before anything is stored")
+	}
+	cache.StoreSessionPath("mistral", "s1", "/logs/session_1.json", modTime)
+	cache.StoreSessionPath("mistral", "s1", "/logs/session_2.json", modTime.Add(time.Second))
+	path, got, ok := cache.LookupSessionPath("mistral", "s1")
+	if !ok || path != "/logs/session_2.json" || !got.Equal(modTime.Add(time.Second)) {
+		t.Fatalf("expected the latest path, got %q at %v (%v)", path, got, ok)
+	}
+	if _, _, ok := cache.LookupSessionPath("gemini", "s1"); ok {
+		t.Fatal("paths should be kept per source")
+	}
+
+	if _, err := cache.ResetIndex("mistral"); err != nil {
+		t.Fatalf("ResetIndex returned error: %v", err)
+	}
+	if _, _, ok := cache.LookupSessionPath("mistral", "s1"); ok {
+		t.Fatal("resetting the source should clear its paths")
+	}
+}
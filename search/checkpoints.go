@@ -0,0 +1,35 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ChangeCheckpoint returns when a client last asked what changed. ok is false
+// if it never has.
+func (c *Cache) ChangeCheckpoint(clientName string) (checkedAt time.Time, ok bool, err error) {
+	var millis int64
+	err = c.db.QueryRow("SELECT checked_at FROM change_checkpoints WHERE client_name = ?", clientName).Scan(&millis)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read change checkpoint: %w", err)
+	}
+	return time.UnixMilli(millis), true, nil
+}
+
+// SetChangeCheckpoint records when a client asked what changed.
+func (c *Cache) SetChangeCheckpoint(clientName string, checkedAt time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.db.Exec(`
+		INSERT INTO change_checkpoints (client_name, checked_at) VALUES (?, ?)
+		ON CONFLICT (client_name) DO UPDATE SET checked_at = excluded.checked_at
+	`, clientName, checkedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to record change checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// IndexSessionCosts records a session's token usage and cost per model, so
+// cost reports don't have to read every session again. Entries are summed
+// per model, keeping priced and unpriced entries apart.
+func (c *Cache) IndexSessionCosts(sessionID string, entries []adapters.CostEntry) error {
+	type modelKey struct {
+		model    string
+		unpriced bool
+	}
+	var order []modelKey
+	byModel := make(map[modelKey]*adapters.CostEntry)
+	for _, entry := range entries {
+		key := modelKey{entry.Model, entry.Unpriced}
+		sum, ok := byModel[key]
+		if !ok {
+			sum = &adapters.CostEntry{Model: entry.Model, Unpriced: entry.Unpriced}
+			byModel[key] = sum
+			order = append(order, key)
+		}
+		sum.Tokens.Input += entry.Tokens.Input
+		sum.Tokens.Output += entry.Tokens.Output
+		sum.Tokens.Reasoning += entry.Tokens.Reasoning
+		sum.Tokens.CacheRead += entry.Tokens.CacheRead
+		sum.Tokens.CacheWrite += entry.Tokens.CacheWrite
+		sum.Tokens.Total += entry.Tokens.Total
+		sum.CostUSD += entry.CostUSD
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM session_costs WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old session costs: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO session_costs (session_id, model, unpriced, input_tokens, output_tokens, reasoning_tokens,
+		                           cache_read_tokens, cache_write_tokens, total_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, key := range order {
+		sum := byModel[key]
+		if _, err := stmt.Exec(sessionID, sum.Model, sum.Unpriced, sum.Tokens.Input, sum.Tokens.Output, sum.Tokens.Reasoning,
+			sum.Tokens.CacheRead, sum.Tokens.CacheWrite, sum.Tokens.Total, sum.CostUSD); err != nil {
+			return fmt.Errorf("failed to insert session cost: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SessionCosts returns the recorded cost entries of each of sessions that is
+// indexed, keyed by session ID, with one entry per model. An indexed session
+// without usage has no entries. Sessions missing from the result aren't
+// indexed, and have to be read to find their cost.
+func (c *Cache) SessionCosts(sessions []adapters.Session) (map[string][]adapters.CostEntry, error) {
+	costs := make(map[string][]adapters.CostEntry)
+	if len(sessions) == 0 {
+		return costs, nil
+	}
+
+	sources := make(map[string]string, len(sessions))
+	args := make([]interface{}, len(sessions))
+	for i, s := range sessions {
+		sources[s.ID] = s.Source
+		args[i] = s.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(sessions)), ", ")
+
+	// Sessions marked for reindexing may have been indexed before costs
+	// were recorded, so they count as not indexed
+	rows, err := c.db.Query(`
+		SELECT s.id, s.source, c.session_id IS NULL, COALESCE(c.model, ''), COALESCE(c.unpriced, 0),
+		       COALESCE(c.input_tokens, 0), COALESCE(c.output_tokens, 0), COALESCE(c.reasoning_tokens, 0),
+		       COALESCE(c.cache_read_tokens, 0), COALESCE(c.cache_write_tokens, 0), COALESCE(c.total_tokens, 0),
+		       COALESCE(c.cost_usd, 0)
+		FROM sessions s
+		LEFT JOIN session_costs c ON c.session_id = s.id
+		WHERE s.file_mtime != 0 AND s.id IN (`+placeholders+`)
+		ORDER BY s.id, c.model`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session costs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, source string
+		var noUsage bool
+		var entry adapters.CostEntry
+		t := &entry.Tokens
+		if err := rows.Scan(&id, &source, &noUsage, &entry.Model, &entry.Unpriced,
+			&t.Input, &t.Output, &t.Reasoning, &t.CacheRead, &t.CacheWrite, &t.Total, &entry.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if sources[id] != source {
+			continue
+		}
+		if noUsage {
+			costs[id] = []adapters.CostEntry{}
+			continue
+		}
+		costs[id] = append(costs[id], entry)
+	}
+	return costs, rows.Err()
+}
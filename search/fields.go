@@ -0,0 +1,106 @@
+package search
+
+import (
+	"math"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Fields of a session that query terms are matched in, as reported by
+// SearchResult.Fields.
+const (
+	MatchSummary      = "summary"
+	MatchFirstMessage = "first_message"
+	MatchContent      = "content" // the indexed text of the searched scope
+)
+
+// Weights of the title fields in titleScore. The summary describes the whole
+// session, so its terms count for more than the first message's.
+const (
+	summaryWeight      = 2.0
+	firstMessageWeight = 1.0
+)
+
+// FieldMatch lists the query terms found in one field of a session.
+type FieldMatch struct {
+	Field string   `json:"field"`
+	Terms []string `json:"terms"`
+}
+
+// fieldMatches returns the query terms found in a session's summary, its
+// first message, and its indexed content, whose term frequencies are
+// termFreqs. Fields without any are left out.
+func fieldMatches(session adapters.Session, queryTerms []string, termFreqs map[string]int) []FieldMatch {
+	summary := make(map[string]bool)
+	for _, token := range Tokenize(session.Summary) {
+		summary[token] = true
+	}
+	firstMessage := make(map[string]bool)
+	for _, token := range Tokenize(session.FirstMessage) {
+		firstMessage[token] = true
+	}
+
+	matches := []FieldMatch{{Field: MatchSummary}, {Field: MatchFirstMessage}, {Field: MatchContent}}
+	seen := make(map[string]bool)
+	for _, term := range queryTerms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		for i, found := range []bool{summary[term], firstMessage[term], termFreqs[term] > 0} {
+			if found {
+				matches[i].Terms = append(matches[i].Terms, term)
+			}
+		}
+	}
+
+	found := matches[:0]
+	for _, match := range matches {
+		if len(match.Terms) > 0 {
+			found = append(found, match)
+		}
+	}
+	return found
+}
+
+// titleScore is the BM25 score of the query terms in a session's title fields,
+// each field weighted and each term counted once, since titles are too short
+// for repeats to mean much. IDF never drops below zero here, so a term common
+// to most sessions still counts in a title.
+func titleScore(matches []FieldMatch, corpus Corpus) float64 {
+	score := 0.0
+	for _, match := range matches {
+		weight := 0.0
+		switch match.Field {
+		case MatchSummary:
+			weight = summaryWeight
+		case MatchFirstMessage:
+			weight = firstMessageWeight
+		}
+		for _, term := range match.Terms {
+			df := float64(corpus.DocFreqs[term])
+			score += weight * math.Log(1+(float64(corpus.TotalDocs)-df+0.5)/(df+0.5))
+		}
+	}
+	return score
+}
+
+// boostTitleMatches adds each result's title score, from titleScores, to its
+// score. Results with one are also raised by the spread of all the scores,
+// which puts each above every result matched only in its body, however the
+// ranker scored them: even a body score of zero or below gets the raise.
+func boostTitleMatches(results []SearchResult, titleScores []float64) {
+	if len(results) == 0 {
+		return
+	}
+	lowest, highest := results[0].Score, results[0].Score
+	for _, result := range results[1:] {
+		lowest = min(lowest, result.Score)
+		highest = max(highest, result.Score)
+	}
+	for i := range results {
+		if titleScores[i] > 0 {
+			results[i].Score += highest - lowest + titleScores[i]
+		}
+	}
+}
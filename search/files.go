@@ -0,0 +1,229 @@
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// IndexSessionFiles records the files a session's tool calls referenced, replacing
+// any previously recorded for it. Counts for duplicate paths are summed.
+// The session must already be indexed with IndexSession.
+func (c *Cache) IndexSessionFiles(sessionID string, files []adapters.FileTouch) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM session_files WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old session files: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO session_files (session_id, path, reads, writes, edits, other)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session_id, path) DO UPDATE SET
+			reads = reads + excluded.reads,
+			writes = writes + excluded.writes,
+			edits = edits + excluded.edits,
+			other = other + excluded.other
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range files {
+		if _, err := stmt.Exec(sessionID, f.Path, f.Reads, f.Writes, f.Edits, f.Other); err != nil {
+			return fmt.Errorf("failed to insert session file: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FileMatch is a session that referenced a file, with the matching files and
+// how the session accessed each.
+type FileMatch struct {
+	Session adapters.Session
+	Files   []adapters.FileTouch
+}
+
+// FindSessionsByFile returns indexed sessions whose tool calls referenced path,
+// most recent first. With prefix set, any file whose path starts with path
+// matches, so a directory finds every session that touched a file inside it.
+func (c *Cache) FindSessionsByFile(path string, prefix bool, source string, projectPath string, limit int) ([]FileMatch, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	sqlQuery := `
+		SELECT s.id, s.source, s.project_path, s.file_path, s.first_message, s.summary, s.timestamp,
+		       f.path, f.reads, f.writes, f.edits, f.other
+		FROM session_files f
+		JOIN sessions s ON s.id = f.session_id`
+	args := []interface{}{}
+	if prefix {
+		sqlQuery += " WHERE substr(f.path, 1, ?) = ?"
+		args = append(args, len([]rune(path)), path)
+	} else {
+		sqlQuery += " WHERE f.path = ?"
+		args = append(args, path)
+	}
+	if source != "" {
+		sqlQuery += " AND s.source = ?"
+		args = append(args, source)
+	}
+	if projectPath != "" {
+		sqlQuery += " AND project_matches(?, s.project_path)"
+		args = append(args, projectPath)
+	}
+	sqlQuery += " ORDER BY s.timestamp DESC, s.id, f.path"
+
+	rows, err := c.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions by file: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []FileMatch
+	for rows.Next() {
+		var session adapters.Session
+		var timestampUnix int64
+		var file adapters.FileTouch
+		err := rows.Scan(&session.ID, &session.Source, &session.ProjectPath, &session.FilePath,
+			&session.FirstMessage, &session.Summary, &timestampUnix,
+			&file.Path, &file.Reads, &file.Writes, &file.Edits, &file.Other)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if n := len(matches); n > 0 && matches[n-1].Session.ID == session.ID {
+			matches[n-1].Files = append(matches[n-1].Files, file)
+			continue
+		}
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+		session.Timestamp = time.Unix(timestampUnix, 0)
+		matches = append(matches, FileMatch{Session: session, Files: []adapters.FileTouch{file}})
+	}
+
+	return matches, rows.Err()
+}
+
+// IndexFileChanges records the file writes and edits a session made in the
+// file change ledger, replacing any previously recorded for it. Changes
+// without a timestamp are recorded at the session's time.
+func (c *Cache) IndexFileChanges(session adapters.Session, changes []adapters.FileChange) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM file_changes WHERE session_id = ? AND source = ?", session.ID, session.Source); err != nil {
+		return fmt.Errorf("failed to delete old file changes: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO file_changes (session_id, source, project_path, seq, message_index, path, kind, tool, timestamp, preview)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for seq, change := range changes {
+		ts := session.Timestamp
+		if change.Timestamp != nil {
+			ts = *change.Timestamp
+		}
+		_, err := stmt.Exec(session.ID, session.Source, session.ProjectPath, seq, change.MessageIndex,
+			change.Path, change.Kind, change.Tool, ts.UnixMilli(), change.Preview)
+		if err != nil {
+			return fmt.Errorf("failed to insert file change: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FileHistoryEntry is one change in the file change ledger and the session
+// that made it.
+type FileHistoryEntry struct {
+	SessionID   string `json:"session_id"`
+	Source      string `json:"source"`
+	ProjectPath string `json:"project_path"`
+	// FirstMessage is empty when the session is no longer indexed
+	FirstMessage string `json:"first_message,omitempty"`
+	adapters.FileChange
+}
+
+// FileHistory returns the ledger's changes to path, most recent first. With
+// prefix set, any file whose path starts with path matches. since, when not
+// zero, drops older changes.
+func (c *Cache) FileHistory(path string, prefix bool, source string, projectPath string, since time.Time, limit int) ([]FileHistoryEntry, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	sqlQuery := `
+		SELECT f.session_id, f.source, f.project_path, COALESCE(s.first_message, ''),
+		       f.path, f.kind, f.tool, f.message_index, f.timestamp, f.preview
+		FROM file_changes f
+		LEFT JOIN sessions s ON s.id = f.session_id AND s.source = f.source`
+	args := []interface{}{}
+	if prefix {
+		sqlQuery += " WHERE substr(f.path, 1, ?) = ?"
+		args = append(args, len([]rune(path)), path)
+	} else {
+		sqlQuery += " WHERE f.path = ?"
+		args = append(args, path)
+	}
+	if source != "" {
+		sqlQuery += " AND f.source = ?"
+		args = append(args, source)
+	}
+	if projectPath != "" {
+		sqlQuery += " AND project_matches(?, f.project_path)"
+		args = append(args, projectPath)
+	}
+	if !since.IsZero() {
+		sqlQuery += " AND f.timestamp >= ?"
+		args = append(args, since.UnixMilli())
+	}
+	sqlQuery += " ORDER BY f.timestamp DESC, f.session_id, f.seq DESC"
+	if limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := c.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []FileHistoryEntry{}
+	for rows.Next() {
+		var entry FileHistoryEntry
+		var timestampMillis int64
+		err := rows.Scan(&entry.SessionID, &entry.Source, &entry.ProjectPath, &entry.FirstMessage,
+			&entry.Path, &entry.Kind, &entry.Tool, &entry.MessageIndex, &timestampMillis, &entry.Preview)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ts := time.UnixMilli(timestampMillis)
+		entry.Timestamp = &ts
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ForgottenSession is a session that was removed from the index and is kept
+// out of it.
+type ForgottenSession struct {
+	SessionID   string    `json:"session_id"`
+	Source      string    `json:"source"`
+	ForgottenAt time.Time `json:"forgotten_at"`
+}
+
+// ForgetSession removes a session from the index, along with its entries in
+// the file change ledger, and records a tombstone so indexing won't add it
+// back. Notes and bookmarks are the user's own and are kept. removed reports
+// whether the session was indexed.
+func (c *Cache) ForgetSession(sessionID, source string, now time.Time) (removed bool, err error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var indexedSource string
+	err = tx.QueryRow("SELECT source FROM sessions WHERE id = ?", sessionID).Scan(&indexedSource)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if removed = err == nil && indexedSource == source; removed {
+		if err := c.removeSessions(tx, []string{sessionID}); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM file_changes WHERE session_id = ? AND source = ?", sessionID, source); err != nil {
+		return false, fmt.Errorf("failed to delete file changes: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO forgotten_sessions (session_id, source, forgotten_at)
+		VALUES (?, ?, ?)
+	`, sessionID, source, now.UnixMilli()); err != nil {
+		return false, fmt.Errorf("failed to record forgotten session: %w", err)
+	}
+	return removed, tx.Commit()
+}
+
+// UnforgetSession deletes a session's tombstone so the next indexing run adds
+// it back. It reports whether the session had been forgotten.
+func (c *Cache) UnforgetSession(sessionID, source string) (bool, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	res, err := c.db.Exec("DELETE FROM forgotten_sessions WHERE session_id = ? AND source = ?", sessionID, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to unforget session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to unforget session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// IsForgotten reports whether a session has been forgotten.
+func (c *Cache) IsForgotten(sessionID, source string) (bool, error) {
+	var forgotten int
+	err := c.db.QueryRow("SELECT 1 FROM forgotten_sessions WHERE session_id = ? AND source = ?", sessionID, source).Scan(&forgotten)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check forgotten sessions: %w", err)
+	}
+	return true, nil
+}
+
+// ForgottenSessions lists the forgotten sessions, most recently forgotten first.
+func (c *Cache) ForgottenSessions() ([]ForgottenSession, error) {
+	rows, err := c.db.Query("SELECT session_id, source, forgotten_at FROM forgotten_sessions ORDER BY forgotten_at DESC, session_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forgotten sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []ForgottenSession{}
+	for rows.Next() {
+		var s ForgottenSession
+		var forgottenAt int64
+		if err := rows.Scan(&s.SessionID, &s.Source, &forgottenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		s.ForgottenAt = time.UnixMilli(forgottenAt)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
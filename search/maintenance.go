@@ -0,0 +1,189 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// sessionTables hold rows indexed for a session, besides the sessions table.
+// Notes and bookmarks are the user's own, and the file change ledger
+// outlives sessions, so they are kept.
+var sessionTables = []string{
+	"term_index",
+	"message_hashes",
+	"session_files",
+	"scoped_documents",
+	"scoped_term_index",
+	"message_documents",
+	"message_term_index",
+	"session_activity",
+	"session_costs",
+	"stale_sessions",
+}
+
+// removeSessions deletes sessions from the index along with everything indexed
+// for them, and updates the search statistics.
+func (c *Cache) removeSessions(tx *sql.Tx, ids []string) error {
+	for _, id := range ids {
+		for _, table := range sessionTables {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE session_id = ?", id); err != nil {
+				return fmt.Errorf("failed to delete from %s: %w", table, err)
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM sessions WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+	return c.updateStats(tx)
+}
+
+// StaleReview is the outcome of ReviewStaleSessions.
+type StaleReview struct {
+	Quarantined int `json:"quarantined"` // sessions whose file went missing since the last review
+	Restored    int `json:"restored"`    // quarantined sessions whose file is back
+	Removed     int `json:"removed"`     // sessions missing for longer than the grace period
+	Pending     int `json:"pending"`     // sessions still quarantined
+}
+
+// ReviewStaleSessions checks that every indexed session's file still exists.
+// A session whose file is missing is quarantined rather than dropped, since
+// its directory may only be unmounted for now. It stays searchable and is
+// removed from the index once it has been missing for longer than grace.
+func (c *Cache) ReviewStaleSessions(now time.Time, grace time.Duration) (StaleReview, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	rows, err := c.db.Query(`
+		SELECT s.id, s.file_path, st.missing_since
+		FROM sessions s
+		LEFT JOIN stale_sessions st ON st.session_id = s.id`)
+	if err != nil {
+		return StaleReview{}, fmt.Errorf("failed to list indexed sessions: %w", err)
+	}
+
+	var missing, restored, expired []string
+	exists := make(map[string]bool)
+	for rows.Next() {
+		var id, filePath string
+		var missingSince sql.NullInt64
+		if err := rows.Scan(&id, &filePath, &missingSince); err != nil {
+			rows.Close()
+			return StaleReview{}, fmt.Errorf("failed to scan row: %w", err)
+		}
+		found, checked := exists[filePath]
+		if !checked {
+			_, err := os.Stat(filePath)
+			found = !os.IsNotExist(err)
+			exists[filePath] = found
+		}
+		switch {
+		case found && missingSince.Valid:
+			restored = append(restored, id)
+		case !found && !missingSince.Valid:
+			missing = append(missing, id)
+		case !found && now.Sub(time.UnixMilli(missingSince.Int64)) > grace:
+			expired = append(expired, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return StaleReview{}, err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return StaleReview{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range missing {
+		if _, err := tx.Exec("INSERT INTO stale_sessions (session_id, missing_since) VALUES (?, ?)", id, now.UnixMilli()); err != nil {
+			return StaleReview{}, fmt.Errorf("failed to quarantine session: %w", err)
+		}
+	}
+	for _, id := range restored {
+		if _, err := tx.Exec("DELETE FROM stale_sessions WHERE session_id = ?", id); err != nil {
+			return StaleReview{}, fmt.Errorf("failed to restore session: %w", err)
+		}
+	}
+	if len(expired) > 0 {
+		if err := c.removeSessions(tx, expired); err != nil {
+			return StaleReview{}, err
+		}
+	}
+
+	review := StaleReview{Quarantined: len(missing), Restored: len(restored), Removed: len(expired)}
+	if err := tx.QueryRow("SELECT COUNT(*) FROM stale_sessions").Scan(&review.Pending); err != nil {
+		return StaleReview{}, fmt.Errorf("failed to count quarantined sessions: %w", err)
+	}
+	return review, tx.Commit()
+}
+
+// Vacuum rebuilds the database file to reclaim space left by deleted rows and
+// returns its size in bytes before and after.
+func (c *Cache) Vacuum() (before, after int64, err error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.vacuum()
+}
+
+// vacuum is Vacuum for callers already holding writeMu.
+func (c *Cache) vacuum() (before, after int64, err error) {
+	if before, err = c.size(); err != nil {
+		return 0, 0, err
+	}
+	if _, err := c.db.Exec("VACUUM"); err != nil {
+		return 0, 0, fmt.Errorf("failed to vacuum search index: %w", err)
+	}
+	if after, err = c.size(); err != nil {
+		return 0, 0, err
+	}
+	return before, after, nil
+}
+
+// size returns the size of the database in bytes.
+func (c *Cache) size() (int64, error) {
+	var pages, pageSize int64
+	if err := c.db.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := c.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
+
+// IndexInfo describes the state of the search index.
+type IndexInfo struct {
+	Sessions    int        `json:"sessions"`
+	Quarantined int        `json:"quarantined"` // sessions whose file is missing, see ReviewStaleSessions
+	Evicted     int        `json:"evicted"`     // sessions left out to stay within retention caps, see Evict
+	Bytes       int64      `json:"bytes"`
+	LastIndexed *time.Time `json:"last_indexed,omitempty"`
+}
+
+// Info reports how many sessions are indexed, how large the index is, and
+// when a session was last indexed.
+func (c *Cache) Info() (IndexInfo, error) {
+	var info IndexInfo
+	var lastIndexed sql.NullInt64
+	err := c.db.QueryRow("SELECT COUNT(*), MAX(last_indexed) FROM sessions").Scan(&info.Sessions, &lastIndexed)
+	if err != nil {
+		return IndexInfo{}, fmt.Errorf("failed to read index info: %w", err)
+	}
+	if lastIndexed.Valid {
+		t := time.Unix(lastIndexed.Int64, 0)
+		info.LastIndexed = &t
+	}
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM stale_sessions").Scan(&info.Quarantined); err != nil {
+		return IndexInfo{}, fmt.Errorf("failed to count quarantined sessions: %w", err)
+	}
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM evicted_sessions").Scan(&info.Evicted); err != nil {
+		return IndexInfo{}, fmt.Errorf("failed to count evicted sessions: %w", err)
+	}
+	if info.Bytes, err = c.size(); err != nil {
+		return IndexInfo{}, err
+	}
+	return info, nil
+}
@@ -0,0 +1,320 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// MessageMatch is a message within one session that matched a query.
+type MessageMatch struct {
+	Index        int       `json:"index"` // position in the session, counting from 0
+	Role         string    `json:"role"`
+	Timestamp    time.Time `json:"timestamp,omitempty"`
+	MatchedTerms []string  `json:"matched_terms"`
+	Snippet      string    `json:"snippet"`
+}
+
+// SearchMessages finds the messages containing any query term, in session order.
+// Queries are tokenized the same way as Search.
+func SearchMessages(messages []adapters.Message, query string, snippetLength int) []MessageMatch {
+	return SearchMessagesInScope(messages, query, ScopeAll, snippetLength)
+}
+
+// SearchMessagesInScope is SearchMessages matching only each message's text in
+// scope (see ScopeText). An invalid scope matches nothing; check it with
+// ValidateScope first.
+func SearchMessagesInScope(messages []adapters.Message, query string, scope string, snippetLength int) []MessageMatch {
+	queryTerms := uniqueTerms(queryTokens(query))
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	var matches []MessageMatch
+	for i, msg := range messages {
+		text := ScopeText(msg, scope)
+		freqs := TermFrequency(Tokenize(text))
+
+		var matched []string
+		for _, term := range queryTerms {
+			if freqs[term] > 0 {
+				matched = append(matched, term)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		matches = append(matches, MessageMatch{
+			Index:        i,
+			Role:         msg.Role,
+			Timestamp:    msg.Timestamp,
+			MatchedTerms: matched,
+			Snippet:      GetSnippet(text, matched, snippetLength),
+		})
+	}
+	return matches
+}
+
+// uniqueTerms drops repeated terms, keeping first occurrences in order.
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// messageScope describes where a scope's text is in the message index: the
+// message_documents column with its length in tokens, the message_term_index
+// column with its term frequencies, and a condition selecting the scope's
+// messages from message_documents (as d).
+type messageScope struct {
+	length    string
+	frequency string
+	where     string
+	args      []interface{}
+}
+
+// messageScopeOf returns where scope's text is in the message index. Scopes
+// that are a subset of messages are selected by their role and kind, so each
+// message is indexed once.
+func messageScopeOf(scope string) messageScope {
+	switch scope {
+	case ScopeProse:
+		return messageScope{length: "prose_length", frequency: "prose_frequency", where: "d.prose_length > 0"}
+	case ScopeCode:
+		return messageScope{length: "code_length", frequency: "code_frequency", where: "d.code_length > 0"}
+	case ScopeConversation:
+		return messageScope{length: "doc_length", frequency: "term_frequency", where: "d.doc_length > 0 AND d.is_tool_output = 0"}
+	}
+	if role, ok := strings.CutPrefix(scope, "role:"); ok {
+		return messageScope{
+			length:    "doc_length",
+			frequency: "term_frequency",
+			where:     "d.doc_length > 0 AND d.is_tool_output = 0 AND d.role = ?",
+			args:      []interface{}{role},
+		}
+	}
+	return messageScope{length: "doc_length", frequency: "term_frequency", where: "d.doc_length > 0"}
+}
+
+// IndexMessages indexes the text of each message in a session on its own,
+// replacing what was indexed for it before. Searches rank a session by its
+// best-matching message, so a giant session doesn't skew length
+// normalization, and report where that message is. The session must already
+// be indexed with IndexSession, since messages are located in its content
+// rather than stored again.
+func (c *Cache) IndexMessages(sessionID string, messages []adapters.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var content string
+	if err := tx.QueryRow("SELECT COALESCE(content, '') FROM sessions WHERE id = ?", sessionID).Scan(&content); err != nil {
+		return fmt.Errorf("failed to get session content: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM message_documents WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old message documents: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM message_term_index WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old message term index: %w", err)
+	}
+
+	docStmt, err := tx.Prepare(`
+		INSERT INTO message_documents
+		(session_id, message_index, role, is_tool_output, doc_length, prose_length, code_length, content_start, content_end)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer docStmt.Close()
+	termStmt, err := tx.Prepare(`
+		INSERT INTO message_term_index (term, session_id, message_index, term_frequency, prose_frequency, code_frequency)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer termStmt.Close()
+
+	spans := messageSpans(content, messages)
+	for i, msg := range messages {
+		tokens := Tokenize(msg.Content)
+		prose := Tokenize(ScopeText(msg, ScopeProse))
+		code := Tokenize(ScopeText(msg, ScopeCode))
+		if len(tokens)+len(prose)+len(code) == 0 {
+			continue
+		}
+		if _, err := docStmt.Exec(sessionID, i, msg.Role, adapters.IsToolOutput(msg),
+			len(tokens), len(prose), len(code), spans[i][0], spans[i][1]); err != nil {
+			return fmt.Errorf("failed to insert message document: %w", err)
+		}
+
+		freqs, proseFreqs, codeFreqs := TermFrequency(tokens), TermFrequency(prose), TermFrequency(code)
+		terms := make(map[string]bool, len(freqs))
+		for _, scopeFreqs := range []map[string]int{freqs, proseFreqs, codeFreqs} {
+			for term := range scopeFreqs {
+				terms[term] = true
+			}
+		}
+		for term := range terms {
+			if _, err := termStmt.Exec(term, sessionID, i, freqs[term], proseFreqs[term], codeFreqs[term]); err != nil {
+				return fmt.Errorf("failed to insert message term: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// messageSpans locates each message's text in content, the session text
+// IndexSession stored, which ends with the messages' text in order. Spans are
+// byte offsets; a message whose text isn't found gets [-1, -1].
+func messageSpans(content string, messages []adapters.Message) [][2]int {
+	spans := make([][2]int, len(messages))
+	end := len(content)
+	for i := len(messages) - 1; i >= 0; i-- {
+		text := messages[i].Content
+		start := -1
+		if text != "" {
+			start = strings.LastIndex(content[:end], text)
+		}
+		if start < 0 {
+			spans[i] = [2]int{-1, -1}
+			continue
+		}
+		spans[i] = [2]int{start, start + len(text)}
+		end = start
+	}
+	return spans
+}
+
+// messageText returns the text of a message at the byte offsets
+// IndexMessages recorded in the session's content, or "" if it wasn't found.
+func messageText(content string, start, end int) string {
+	if start < 0 || start > end || end > len(content) {
+		return ""
+	}
+	return content[start:end]
+}
+
+// messageCorpus returns the statistics of the messages indexed in scope, for
+// ranking messages rather than whole sessions.
+func (c *Cache) messageCorpus(scope string, terms []string, now time.Time) (Corpus, error) {
+	corpus := Corpus{DocFreqs: make(map[string]int), Now: now}
+	ms := messageScopeOf(scope)
+	err := c.db.QueryRow("SELECT COUNT(*), COALESCE(AVG(d."+ms.length+"), 0) FROM message_documents d WHERE "+ms.where, ms.args...).
+		Scan(&corpus.TotalDocs, &corpus.AvgDocLength)
+	if err != nil {
+		return Corpus{}, fmt.Errorf("failed to get message stats: %w", err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(terms)), ", ")
+	query := `
+		SELECT t.term, COUNT(*)
+		FROM message_term_index t
+		JOIN message_documents d ON d.session_id = t.session_id AND d.message_index = t.message_index
+		WHERE ` + ms.where + ` AND t.` + ms.frequency + ` > 0 AND t.term IN (` + placeholders + `)
+		GROUP BY t.term`
+	args := slices.Clone(ms.args)
+	for _, term := range terms {
+		args = append(args, term)
+	}
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return Corpus{}, fmt.Errorf("failed to get message frequencies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var term string
+		var count int
+		if err := rows.Scan(&term, &count); err != nil {
+			return Corpus{}, err
+		}
+		corpus.DocFreqs[term] = count
+	}
+	return corpus, rows.Err()
+}
+
+// messageHit is the best-matching message of a session.
+type messageHit struct {
+	index   int
+	score   float64
+	content string
+}
+
+// bestMessage scores each of a session's messages in scope that contains a
+// query term, and returns the best one. content is the session's indexed
+// content, which holds the messages' text. ok is false when no message
+// matches, such as when only the summary matched.
+func (c *Cache) bestMessage(ranker Ranker, session adapters.Session, content string, scope string, queryTerms []string, corpus Corpus) (hit messageHit, ok bool, err error) {
+	ms := messageScopeOf(scope)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(queryTerms)), ", ")
+	query := `
+		SELECT t.message_index, t.term, t.` + ms.frequency + `, d.` + ms.length + `, d.content_start, d.content_end
+		FROM message_term_index t
+		JOIN message_documents d ON d.session_id = t.session_id AND d.message_index = t.message_index
+		WHERE ` + ms.where + ` AND t.session_id = ? AND t.` + ms.frequency + ` > 0 AND t.term IN (` + placeholders + `)
+		ORDER BY t.message_index`
+	args := append(slices.Clone(ms.args), session.ID)
+	for _, term := range queryTerms {
+		args = append(args, term)
+	}
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return messageHit{}, false, fmt.Errorf("failed to get message term frequencies: %w", err)
+	}
+	defer rows.Close()
+
+	type message struct {
+		termFreqs map[string]int
+		docLength int
+		content   string
+	}
+	var order []int
+	messages := make(map[int]*message)
+	for rows.Next() {
+		var index, freq, docLength, start, end int
+		var term string
+		if err := rows.Scan(&index, &term, &freq, &docLength, &start, &end); err != nil {
+			return messageHit{}, false, err
+		}
+		msg, seen := messages[index]
+		if !seen {
+			msg = &message{termFreqs: make(map[string]int), docLength: docLength, content: messageText(content, start, end)}
+			messages[index] = msg
+			order = append(order, index)
+		}
+		msg.termFreqs[term] = freq
+	}
+	if err := rows.Err(); err != nil {
+		return messageHit{}, false, err
+	}
+
+	// The earliest message wins ties
+	for _, index := range order {
+		msg := messages[index]
+		score := ranker.Score(queryTerms, Document{
+			Session:   session,
+			TermFreqs: msg.termFreqs,
+			DocLength: msg.docLength,
+			Content:   msg.content,
+		}, corpus)
+		if !ok || score > hit.score {
+			hit, ok = messageHit{index: index, score: score, content: msg.content}, true
+		}
+	}
+	return hit, ok, nil
+}
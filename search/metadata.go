@@ -0,0 +1,133 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+var (
+	_ adapters.MetadataCache = (*Cache)(nil)
+	_ adapters.OffsetCache   = (*Cache)(nil)
+	_ adapters.PathCache     = (*Cache)(nil)
+)
+
+// LoadSessionMetadata returns the session metadata stored for path, if it was
+// stored for a file with the same modification time and size. Read errors are
+// treated as a miss, so the adapter parses the file instead.
+func (c *Cache) LoadSessionMetadata(path string, modTime time.Time, size int64) (adapters.Session, bool) {
+	var data string
+	err := c.db.QueryRow(
+		"SELECT session FROM session_metadata WHERE path = ? AND mod_time = ? AND size = ?",
+		path, modTime.UnixNano(), size,
+	).Scan(&data)
+	if err != nil {
+		return adapters.Session{}, false
+	}
+	var session adapters.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return adapters.Session{}, false
+	}
+	return session, true
+}
+
+// StoreSessionMetadata records the session metadata extracted from path,
+// replacing what was stored for an earlier version of the file. Failing to
+// store only costs a reparse next time, so errors are ignored.
+func (c *Cache) StoreSessionMetadata(path string, modTime time.Time, size int64, session adapters.Session) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+	c.db.Exec(`
+		INSERT INTO session_metadata (path, source, mod_time, size, session) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			source = excluded.source, mod_time = excluded.mod_time,
+			size = excluded.size, session = excluded.session
+	`, path, session.Source, modTime.UnixNano(), size, string(data))
+}
+
+// LoadMessageOffsets returns the message offsets stored for path, if they were
+// stored for a file with the same modification time and size. Like metadata,
+// read errors are treated as a miss.
+func (c *Cache) LoadMessageOffsets(path string, modTime time.Time, size int64) ([]adapters.MessageOffset, bool) {
+	var data string
+	err := c.db.QueryRow(
+		"SELECT offsets FROM message_offsets WHERE path = ? AND mod_time = ? AND size = ?",
+		path, modTime.UnixNano(), size,
+	).Scan(&data)
+	if err != nil {
+		return nil, false
+	}
+	var offsets []adapters.MessageOffset
+	if err := json.Unmarshal([]byte(data), &offsets); err != nil {
+		return nil, false
+	}
+	return offsets, true
+}
+
+// StoreMessageOffsets records where each message in path starts, replacing
+// the offsets of an earlier version of the file.
+func (c *Cache) StoreMessageOffsets(source, path string, modTime time.Time, size int64, offsets []adapters.MessageOffset) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if offsets == nil {
+		offsets = []adapters.MessageOffset{}
+	}
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return
+	}
+	c.db.Exec(`
+		INSERT INTO message_offsets (path, source, mod_time, size, offsets) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			source = excluded.source, mod_time = excluded.mod_time,
+			size = excluded.size, offsets = excluded.offsets
+	`, path, source, modTime.UnixNano(), size, string(data))
+}
+
+// LookupSessionPath returns the file recorded for a session and its
+// modification time when it was recorded. Read errors are treated as a miss.
+func (c *Cache) LookupSessionPath(source, sessionID string) (string, time.Time, bool) {
+	var path string
+	var modTime int64
+	err := c.db.QueryRow(
+		"SELECT path, mod_time FROM session_paths WHERE source = ? AND session_id = ?",
+		source, sessionID,
+	).Scan(&path, &modTime)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return path, time.Unix(0, modTime), true
+}
+
+// StoreSessionPath records the file that holds a session.
+func (c *Cache) StoreSessionPath(source, sessionID, path string, modTime time.Time) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.db.Exec(`
+		INSERT INTO session_paths (source, session_id, path, mod_time) VALUES (?, ?, ?, ?)
+		ON CONFLICT (source, session_id) DO UPDATE SET path = excluded.path, mod_time = excluded.mod_time
+	`, source, sessionID, path, modTime.UnixNano())
+}
+
+// clearFileCaches removes cached metadata, message offsets, and session paths
+// for source, or for every source when it is empty.
+func clearFileCaches(tx *sql.Tx, source string) error {
+	for _, table := range []string{"session_metadata", "message_offsets", "session_paths"} {
+		query, args := "DELETE FROM "+table, []interface{}{}
+		if source != "" {
+			query += " WHERE source = ?"
+			args = append(args, source)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	return nil
+}
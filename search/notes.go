@@ -0,0 +1,113 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Note is a free-text note left on a session, such as "this is where the
+// migration strategy was decided".
+type Note struct {
+	ID           int64     `json:"id"`
+	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
+	MessageIndex *int      `json:"message_index,omitempty"` // the message it refers to, if any
+	Text         string    `json:"text"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AddNote stores a note and returns it with its ID and creation time set.
+func (c *Cache) AddNote(note Note) (Note, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+
+	var messageIndex sql.NullInt64
+	if note.MessageIndex != nil {
+		messageIndex = sql.NullInt64{Int64: int64(*note.MessageIndex), Valid: true}
+	}
+	res, err := c.db.Exec(`
+		INSERT INTO session_notes (session_id, source, message_index, text, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, note.SessionID, note.Source, messageIndex, note.Text, note.CreatedAt.UnixMilli())
+	if err != nil {
+		return Note{}, fmt.Errorf("failed to add note: %w", err)
+	}
+	if note.ID, err = res.LastInsertId(); err != nil {
+		return Note{}, fmt.Errorf("failed to add note: %w", err)
+	}
+	return note, nil
+}
+
+// Notes returns the notes on one session: notes on the whole session first,
+// then anchored notes in message order.
+func (c *Cache) Notes(sessionID, source string) ([]Note, error) {
+	return c.queryNotes("WHERE session_id = ? AND source = ?", sessionID, source)
+}
+
+// NotesForSessions returns the notes on each of sessions that has any, keyed
+// by session ID.
+func (c *Cache) NotesForSessions(sessions []adapters.Session) (map[string][]Note, error) {
+	notes := make(map[string][]Note)
+	if len(sessions) == 0 {
+		return notes, nil
+	}
+
+	sources := make(map[string]string, len(sessions))
+	args := make([]interface{}, len(sessions))
+	for i, s := range sessions {
+		sources[s.ID] = s.Source
+		args[i] = s.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(sessions)), ", ")
+
+	all, err := c.queryNotes("WHERE session_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range all {
+		if sources[note.SessionID] == note.Source {
+			notes[note.SessionID] = append(notes[note.SessionID], note)
+		}
+	}
+	return notes, nil
+}
+
+// queryNotes returns the notes matching a WHERE clause, ordered for display.
+func (c *Cache) queryNotes(where string, args ...interface{}) ([]Note, error) {
+	rows, err := c.db.Query(`
+		SELECT id, session_id, source, message_index, text, created_at
+		FROM session_notes
+		`+where+`
+		ORDER BY message_index, created_at, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		var (
+			note         Note
+			messageIndex sql.NullInt64
+			createdAt    int64
+		)
+		if err := rows.Scan(&note.ID, &note.SessionID, &note.Source, &messageIndex, &note.Text, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		if messageIndex.Valid {
+			index := int(messageIndex.Int64)
+			note.MessageIndex = &index
+		}
+		note.CreatedAt = time.UnixMilli(createdAt)
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// IndexSessionOutcome records how a session probably ended, for filtering
+// sessions by outcome. The session must already be indexed with IndexSession.
+func (c *Cache) IndexSessionOutcome(sessionID string, outcome adapters.SessionOutcome) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	signals, err := json.Marshal(outcome.Signals)
+	if err != nil {
+		return fmt.Errorf("failed to encode outcome signals: %w", err)
+	}
+	if _, err := c.db.Exec(`
+		UPDATE sessions SET outcome = ?, outcome_confidence = ?, outcome_signals = ?
+		WHERE id = ?
+	`, outcome.Outcome, outcome.Confidence, string(signals), sessionID); err != nil {
+		return fmt.Errorf("failed to record outcome: %w", err)
+	}
+	return nil
+}
+
+// SessionOutcomes returns the recorded outcome of each of sessions that is
+// indexed with one, keyed by session ID.
+func (c *Cache) SessionOutcomes(sessions []adapters.Session) (map[string]adapters.SessionOutcome, error) {
+	outcomes := make(map[string]adapters.SessionOutcome)
+	if len(sessions) == 0 {
+		return outcomes, nil
+	}
+
+	sources := make(map[string]string, len(sessions))
+	args := make([]interface{}, len(sessions))
+	for i, s := range sessions {
+		sources[s.ID] = s.Source
+		args[i] = s.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(sessions)), ", ")
+
+	rows, err := c.db.Query(`
+		SELECT id, source, outcome, outcome_confidence, outcome_signals
+		FROM sessions
+		WHERE outcome IS NOT NULL AND id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, source, outcome string
+		var confidence, signals sql.NullString
+		if err := rows.Scan(&id, &source, &outcome, &confidence, &signals); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if sources[id] != source {
+			continue
+		}
+		entry := adapters.SessionOutcome{Outcome: outcome, Confidence: confidence.String, Signals: []string{}}
+		if signals.Valid {
+			if err := json.Unmarshal([]byte(signals.String), &entry.Signals); err != nil {
+				return nil, fmt.Errorf("failed to decode outcome signals: %w", err)
+			}
+		}
+		outcomes[id] = entry
+	}
+	return outcomes, rows.Err()
+}
@@ -0,0 +1,575 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrQuerySyntax means a search query couldn't be parsed. Errors wrapping it
+// say what was wrong.
+var ErrQuerySyntax = errors.New("query syntax error")
+
+// QueryGrammar is the search query grammar, in EBNF. Terms side by side
+// match a session containing any of them and none of the excluded ones, as
+// plain keyword queries always have; field qualifiers beside them narrow them
+// down, as exclusions do. AND binds tighter than terms side by
+// side, which bind tighter than OR. Operators are only recognized in upper
+// case, so "and" and "or" are searched for like any other word. A field
+// qualifier matches session metadata rather than text; see QueryFields.
+const QueryGrammar = `query     = or ;
+or        = terms , { "OR" , terms } ;
+terms     = and , { and } ;
+and       = unary , { "AND" , unary } ;
+unary     = ( "NOT" | "-" ) , unary | atom ;
+atom      = word | prefix | '"' , phrase , '"' | qualifier | "(" , or , ")" ;
+prefix    = word , "*" ;
+qualifier = field , ":" , ( word | '"' , phrase , '"' ) ;`
+
+// Field qualifiers, written field:value in a query.
+const (
+	// FieldRole matches the query's text only in messages by that role, user
+	// or assistant, leaving out tool output. It applies to the whole query
+	// wherever it appears, and can't be excluded.
+	FieldRole = "role"
+	// FieldSource matches sessions from that source.
+	FieldSource = "source"
+	// FieldModel matches sessions using a model whose name contains the value.
+	FieldModel = "model"
+	// FieldFile matches sessions whose tool calls referenced the file: its
+	// full path, or a path ending in the value, such as "main.go" or
+	// "cmd/main.go".
+	FieldFile = "file"
+	// FieldTitle matches sessions whose summary or first message contains the
+	// value's words in order.
+	FieldTitle = "title"
+)
+
+// maxPrefixTerms caps how many indexed keywords a prefix term expands to.
+// The most common ones are kept.
+const maxPrefixTerms = 50
+
+// QueryFields returns the field qualifiers a query can use. Other words with
+// a colon, such as URLs, are searched for as plain text.
+func QueryFields() []string {
+	return []string{FieldRole, FieldSource, FieldModel, FieldFile, FieldTitle}
+}
+
+// messageRoles are the values FieldRole accepts.
+var messageRoles = []string{"user", "assistant"}
+
+// queryKind is the kind of a queryNode.
+type queryKind int
+
+const (
+	nodeTerm   queryKind = iota // any of terms
+	nodePhrase                  // terms, consecutively
+	nodeAnd                     // every child
+	nodeOr                      // any child
+	nodeNot                     // not the only child
+	nodeField                   // a field qualifier other than role
+)
+
+// queryNode is a node of a parsed search query.
+type queryNode struct {
+	kind     queryKind
+	terms    []string // the tokens of a word or phrase
+	children []*queryNode
+	field    string // the field of a qualifier
+	value    string // the value of a qualifier
+}
+
+// parsedQuery is a search query ready to run.
+type parsedQuery struct {
+	root *queryNode
+	// positive are the tokens that aren't excluded. Candidates contain at
+	// least one, and they alone are ranked and highlighted.
+	positive []string
+	// all is every token in the query, excluded ones included.
+	all []string
+	// phrases is whether matching needs the document's token sequence.
+	phrases bool
+	// role is the message role the query's text must appear in, or "" for
+	// any text.
+	role string
+}
+
+// prefixExpander returns the indexed keywords starting with prefix.
+type prefixExpander func(prefix string) ([]string, error)
+
+// parseQuery parses a search query, expanding prefix terms such as "deploy*"
+// to the keywords expand finds for them. It returns a nil root when the query
+// has no searchable tokens, such as a query of single letters.
+func parseQuery(query string, expand prefixExpander) (*parsedQuery, error) {
+	items, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return &parsedQuery{}, nil
+	}
+	p := &queryParser{items: items, expand: expand}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.items) {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrQuerySyntax, p.items[p.pos])
+	}
+
+	parsed := &parsedQuery{root: root, role: p.role}
+	parsed.collect(root, false)
+	if root != nil && len(parsed.positive) == 0 {
+		return nil, fmt.Errorf("%w: a query needs at least one term that isn't excluded", ErrQuerySyntax)
+	}
+	return parsed, nil
+}
+
+// collect gathers the tokens under node, noting whether they are excluded.
+func (q *parsedQuery) collect(node *queryNode, excluded bool) {
+	if node == nil {
+		return
+	}
+	if node.kind == nodePhrase {
+		q.phrases = true
+	}
+	for _, term := range node.terms {
+		q.all = appendTerm(q.all, term)
+		if !excluded {
+			q.positive = appendTerm(q.positive, term)
+		}
+	}
+	for _, child := range node.children {
+		q.collect(child, excluded != (node.kind == nodeNot))
+	}
+}
+
+func appendTerm(terms []string, term string) []string {
+	for _, t := range terms {
+		if t == term {
+			return terms
+		}
+	}
+	return append(terms, term)
+}
+
+// queryDoc is what a query is matched against: the frequencies of the
+// query's tokens in a document, its tokens, for phrases, and its metadata,
+// for field qualifiers.
+type queryDoc struct {
+	termFreqs map[string]int
+	tokens    func() []string
+	field     func(field, value string) bool
+}
+
+// matches reports whether a document satisfies the query.
+func (q *parsedQuery) matches(doc queryDoc) bool {
+	return q.root.matches(doc)
+}
+
+func (n *queryNode) matches(doc queryDoc) bool {
+	switch n.kind {
+	case nodeTerm:
+		for _, term := range n.terms {
+			if doc.termFreqs[term] > 0 {
+				return true
+			}
+		}
+		return false
+	case nodePhrase:
+		return containsSequence(doc.tokens(), n.terms)
+	case nodeAnd:
+		for _, child := range n.children {
+			if !child.matches(doc) {
+				return false
+			}
+		}
+		return true
+	case nodeOr:
+		for _, child := range n.children {
+			if child.matches(doc) {
+				return true
+			}
+		}
+		return false
+	case nodeNot:
+		return !n.children[0].matches(doc)
+	case nodeField:
+		return doc.field(n.field, n.value)
+	}
+	return false
+}
+
+// containsSequence reports whether tokens contains seq as a consecutive run.
+func containsSequence(tokens, seq []string) bool {
+	for i := 0; i+len(seq) <= len(tokens); i++ {
+		match := true
+		for j, token := range seq {
+			if tokens[i+j] != token {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// queryItemKind is the kind of a lexed queryItem.
+type queryItemKind int
+
+const (
+	itemWord queryItemKind = iota
+	itemPhrase
+	itemAnd
+	itemOr
+	itemNot // NOT or a leading "-"
+	itemOpen
+	itemClose
+	itemQualifier // field:value, with the field in field and the value in text
+)
+
+// queryItem is a lexed piece of a search query.
+type queryItem struct {
+	kind  queryItemKind
+	text  string
+	field string
+}
+
+func (i queryItem) String() string {
+	if i.kind == itemQualifier {
+		return strconv.Quote(i.field + ":" + i.text)
+	}
+	return strconv.Quote(i.text)
+}
+
+// lexQuery splits a query into words, phrases, qualifiers, operators, and
+// parentheses. A "-" only excludes at the start of a word, so "foo-bar" is
+// one word.
+func lexQuery(query string) ([]queryItem, error) {
+	var items []queryItem
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			phrase, end, err := lexPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, queryItem{kind: itemPhrase, text: phrase})
+			i = end
+		case r == '(':
+			items = append(items, queryItem{kind: itemOpen, text: "("})
+			i++
+		case r == ')':
+			items = append(items, queryItem{kind: itemClose, text: ")"})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			items = append(items, queryItem{kind: itemNot, text: "-"})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`"()`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			if field, value, ok := strings.Cut(word, ":"); ok && slices.Contains(QueryFields(), field) {
+				if value == "" && end < len(runes) && runes[end] == '"' {
+					phrase, phraseEnd, err := lexPhrase(runes, end)
+					if err != nil {
+						return nil, err
+					}
+					value, end = phrase, phraseEnd
+				}
+				if strings.TrimSpace(value) == "" {
+					return nil, fmt.Errorf("%w: %s: needs a value", ErrQuerySyntax, field)
+				}
+				items = append(items, queryItem{kind: itemQualifier, field: field, text: value})
+				i = end
+				continue
+			}
+			switch word {
+			case "AND":
+				items = append(items, queryItem{kind: itemAnd, text: word})
+			case "OR":
+				items = append(items, queryItem{kind: itemOr, text: word})
+			case "NOT":
+				items = append(items, queryItem{kind: itemNot, text: word})
+			default:
+				items = append(items, queryItem{kind: itemWord, text: word})
+			}
+			i = end
+		}
+	}
+	return items, nil
+}
+
+// lexPhrase returns the text of the quoted phrase starting at runes[start],
+// and the position after its closing quote.
+func lexPhrase(runes []rune, start int) (string, int, error) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end == len(runes) {
+		return "", 0, fmt.Errorf("%w: unclosed quote", ErrQuerySyntax)
+	}
+	return string(runes[start+1 : end]), end + 1, nil
+}
+
+// queryParser parses lexed query items by recursive descent, following
+// QueryGrammar. Words without searchable tokens, and role qualifiers, which
+// are kept in role, parse to nil and drop out of the nodes around them.
+type queryParser struct {
+	items   []queryItem
+	pos     int
+	negated int // how many NOTs enclose the item being parsed
+	role    string
+	expand  prefixExpander
+}
+
+func (p *queryParser) peek() (queryItem, bool) {
+	if p.pos < len(p.items) {
+		return p.items[p.pos], true
+	}
+	return queryItem{}, false
+}
+
+// startsOperand reports whether item can begin an operand.
+func startsOperand(item queryItem) bool {
+	switch item.kind {
+	case itemWord, itemPhrase, itemQualifier, itemNot, itemOpen:
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (*queryNode, error) {
+	first, err := p.parseTerms()
+	if err != nil {
+		return nil, err
+	}
+	children := []*queryNode{first}
+	for {
+		item, ok := p.peek()
+		if !ok || item.kind != itemOr {
+			break
+		}
+		p.pos++
+		if next, ok := p.peek(); !ok || !startsOperand(next) {
+			return nil, fmt.Errorf("%w: OR needs a term after it", ErrQuerySyntax)
+		}
+		next, err := p.parseTerms()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	return combine(nodeOr, children), nil
+}
+
+// parseTerms parses terms side by side: any of the included ones, and none
+// of the excluded ones, in sessions matching every filter.
+func (p *queryParser) parseTerms() (*queryNode, error) {
+	var included, filters []*queryNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if node != nil && node.isFilter() {
+			filters = append(filters, node)
+		} else {
+			included = append(included, node)
+		}
+		if item, ok := p.peek(); !ok || !startsOperand(item) {
+			break
+		}
+	}
+	return combine(nodeAnd, append([]*queryNode{combine(nodeOr, included)}, filters...)), nil
+}
+
+// isFilter reports whether a node only narrows down the sessions other terms
+// match: an exclusion or a field qualifier, or a group of them.
+func (n *queryNode) isFilter() bool {
+	switch n.kind {
+	case nodeNot, nodeField:
+		return true
+	case nodeAnd, nodeOr:
+		for _, child := range n.children {
+			if !child.isFilter() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseAnd() (*queryNode, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []*queryNode{first}
+	for {
+		item, ok := p.peek()
+		if !ok || item.kind != itemAnd {
+			break
+		}
+		p.pos++
+		if next, ok := p.peek(); !ok || !startsOperand(next) {
+			return nil, fmt.Errorf("%w: AND needs a term after it", ErrQuerySyntax)
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	return combine(nodeAnd, children), nil
+}
+
+func (p *queryParser) parseUnary() (*queryNode, error) {
+	item, ok := p.peek()
+	if ok && item.kind == itemNot {
+		p.pos++
+		if next, ok := p.peek(); !ok || !startsOperand(next) {
+			return nil, fmt.Errorf("%w: %s needs a term after it", ErrQuerySyntax, item.text)
+		}
+		p.negated++
+		operand, err := p.parseUnary()
+		p.negated--
+		if err != nil || operand == nil {
+			return nil, err
+		}
+		return &queryNode{kind: nodeNot, children: []*queryNode{operand}}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *queryParser) parseAtom() (*queryNode, error) {
+	item, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: the query ends where a term was expected", ErrQuerySyntax)
+	}
+	switch item.kind {
+	case itemWord:
+		p.pos++
+		if prefix, ok := termPrefix(item.text); ok && p.expand != nil {
+			return p.parsePrefix(prefix)
+		}
+		if terms := queryTokens(item.text); len(terms) > 0 {
+			return &queryNode{kind: nodeTerm, terms: terms}, nil
+		}
+		return nil, nil
+	case itemPhrase:
+		p.pos++
+		terms := queryTokens(item.text)
+		switch len(terms) {
+		case 0:
+			return nil, fmt.Errorf("%w: empty phrase", ErrQuerySyntax)
+		case 1:
+			return &queryNode{kind: nodeTerm, terms: terms}, nil
+		}
+		return &queryNode{kind: nodePhrase, terms: terms}, nil
+	case itemQualifier:
+		p.pos++
+		return p.parseQualifier(item)
+	case itemOpen:
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != itemClose {
+			return nil, fmt.Errorf("%w: unclosed parenthesis", ErrQuerySyntax)
+		}
+		p.pos++
+		return node, nil
+	case itemAnd, itemOr:
+		return nil, fmt.Errorf("%w: %s needs a term before it", ErrQuerySyntax, item.text)
+	}
+	return nil, fmt.Errorf("%w: unexpected %s", ErrQuerySyntax, item)
+}
+
+// termPrefix returns the prefix of a prefix term: a single word of at least
+// two characters followed by "*".
+func termPrefix(word string) (string, bool) {
+	prefix, ok := strings.CutSuffix(word, "*")
+	if !ok {
+		return "", false
+	}
+	prefix = strings.ToLower(prefix)
+	if words := splitWords(prefix); len(words) != 1 || words[0] != prefix || len([]rune(prefix)) < 2 {
+		return "", false
+	}
+	return prefix, true
+}
+
+// parsePrefix expands a prefix term to the keywords starting with it, which
+// match like keywords side by side. A prefix no keyword starts with is kept
+// as its own term, so it matches nothing rather than leaving the query
+// without terms.
+func (p *queryParser) parsePrefix(prefix string) (*queryNode, error) {
+	terms, err := p.expand(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		terms = []string{prefix}
+	}
+	return &queryNode{kind: nodeTerm, terms: terms}, nil
+}
+
+// parseQualifier checks a field qualifier's value. Role qualifiers are kept
+// aside, since they choose the text the whole query is matched against.
+func (p *queryParser) parseQualifier(item queryItem) (*queryNode, error) {
+	value := strings.TrimSpace(item.text)
+	switch item.field {
+	case FieldRole:
+		role := strings.ToLower(value)
+		if !slices.Contains(messageRoles, role) {
+			return nil, fmt.Errorf("%w: role: must be one of %s, not %q", ErrQuerySyntax, strings.Join(messageRoles, ", "), value)
+		}
+		if p.negated > 0 {
+			return nil, fmt.Errorf("%w: role: can't be excluded", ErrQuerySyntax)
+		}
+		if p.role != "" && p.role != role {
+			return nil, fmt.Errorf("%w: a query can only use one role:", ErrQuerySyntax)
+		}
+		p.role = role
+		return nil, nil
+	case FieldTitle:
+		if len(queryTokens(value)) == 0 {
+			return nil, fmt.Errorf("%w: title: needs a searchable word", ErrQuerySyntax)
+		}
+	}
+	return &queryNode{kind: nodeField, field: item.field, value: value}, nil
+}
+
+// combine joins children under an AND or OR node, dropping nil children and
+// skipping the node when only one child is left.
+func combine(kind queryKind, children []*queryNode) *queryNode {
+	var kept []*queryNode
+	for _, child := range children {
+		if child != nil {
+			kept = append(kept, child)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return nil
+	case 1:
+		return kept[0]
+	}
+	return &queryNode{kind: kind, children: kept}
+}
@@ -0,0 +1,228 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// DefaultRankerName is the ranker used when none is configured or requested.
+const DefaultRankerName = "bm25"
+
+// Document is what a Ranker sees of one candidate session.
+type Document struct {
+	Session   adapters.Session
+	TermFreqs map[string]int // frequency of each query term in the document
+	DocLength int            // total number of terms in the document
+	Content   string         // full indexed content
+}
+
+// Corpus holds collection-wide statistics shared by all documents in a search.
+type Corpus struct {
+	TotalDocs    int
+	AvgDocLength float64
+	DocFreqs     map[string]int // number of documents containing each query term
+	Now          time.Time
+}
+
+// Ranker scores a candidate document for a query. Higher scores rank first.
+type Ranker interface {
+	Name() string
+	Score(queryTerms []string, doc Document, corpus Corpus) float64
+}
+
+var (
+	rankersMu sync.RWMutex
+	rankers   = make(map[string]Ranker)
+)
+
+func init() {
+	RegisterRanker(BM25Ranker{})
+	RegisterRanker(RecencyRanker{Base: BM25Ranker{}, HalfLife: 30 * 24 * time.Hour})
+	RegisterRanker(VectorRanker{})
+	RegisterRanker(FusionRanker{Keyword: BM25Ranker{}, Semantic: VectorRanker{}, K: 60})
+}
+
+// RegisterRanker makes a ranker selectable by name, replacing any ranker with the same name.
+func RegisterRanker(r Ranker) {
+	rankersMu.Lock()
+	defer rankersMu.Unlock()
+	rankers[r.Name()] = r
+}
+
+// GetRanker returns the ranker registered under name. An empty name selects the default.
+func GetRanker(name string) (Ranker, error) {
+	if name == "" {
+		name = DefaultRankerName
+	}
+
+	rankersMu.RLock()
+	defer rankersMu.RUnlock()
+	r, ok := rankers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ranker: %s", name)
+	}
+	return r, nil
+}
+
+// RankerNames returns the sorted names of all registered rankers.
+func RankerNames() []string {
+	rankersMu.RLock()
+	defer rankersMu.RUnlock()
+
+	names := make([]string, 0, len(rankers))
+	for name := range rankers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BM25Ranker ranks purely by BM25 relevance.
+type BM25Ranker struct{}
+
+// Name returns the ranker name.
+func (BM25Ranker) Name() string { return "bm25" }
+
+// Score returns the BM25 score of the document.
+func (BM25Ranker) Score(queryTerms []string, doc Document, corpus Corpus) float64 {
+	scorer := NewBM25Scorer(corpus.AvgDocLength, corpus.TotalDocs)
+	return scorer.Score(queryTerms, doc.TermFreqs, doc.DocLength, corpus.DocFreqs)
+}
+
+// RecencyRanker boosts newer sessions: the base score is multiplied by a factor
+// that decays from 2 (brand new) toward 1 (very old) with the given half-life.
+type RecencyRanker struct {
+	Base     Ranker
+	HalfLife time.Duration
+}
+
+// Name returns the ranker name, derived from the base ranker.
+func (r RecencyRanker) Name() string { return r.Base.Name() + "_recency" }
+
+// Score returns the base score weighted by session age.
+func (r RecencyRanker) Score(queryTerms []string, doc Document, corpus Corpus) float64 {
+	score := r.Base.Score(queryTerms, doc, corpus)
+	if score <= 0 || doc.Session.Timestamp.IsZero() || r.HalfLife <= 0 {
+		return score
+	}
+
+	age := corpus.Now.Sub(doc.Session.Timestamp)
+	if age < 0 {
+		age = 0
+	}
+	decay := math.Pow(0.5, float64(age)/float64(r.HalfLife))
+	return score * (1 + decay)
+}
+
+// VectorRanker ranks by the cosine similarity of the query and document term
+// vectors, in the vector space model. Document terms are weighted by their log
+// frequency and query terms by log frequency times inverse document frequency
+// (SMART lnc.ltc). Unlike BM25, a repeated term's weight doesn't saturate, and
+// every distinct term of the document counts toward its length.
+type VectorRanker struct{}
+
+// Name returns the ranker name.
+func (VectorRanker) Name() string { return "vector" }
+
+// Score returns the cosine similarity of the query and the document, from 0
+// to 1.
+func (VectorRanker) Score(queryTerms []string, doc Document, corpus Corpus) float64 {
+	var dot, queryNorm float64
+	for term, freq := range TermFrequency(queryTerms) {
+		df := corpus.DocFreqs[term]
+		if df == 0 {
+			continue
+		}
+		weight := logWeight(freq) * math.Log(1+float64(corpus.TotalDocs)/float64(df))
+		queryNorm += weight * weight
+		if tf := doc.TermFreqs[term]; tf > 0 {
+			dot += weight * logWeight(tf)
+		}
+	}
+	docNorm := vectorLength(doc)
+	if dot == 0 || docNorm == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(queryNorm) * docNorm)
+}
+
+// logWeight is the weight of a term that occurs freq times.
+func logWeight(freq int) float64 {
+	return 1 + math.Log(float64(freq))
+}
+
+// vectorLength returns the length of a document's term vector, weighted by
+// logWeight. Without its content, each token is taken to be a distinct term.
+func vectorLength(doc Document) float64 {
+	if doc.Content == "" {
+		return math.Sqrt(float64(doc.DocLength))
+	}
+	var sum float64
+	for _, freq := range TermFrequency(Tokenize(doc.Content)) {
+		weight := logWeight(freq)
+		sum += weight * weight
+	}
+	return math.Sqrt(sum)
+}
+
+// FusionRanker ranks search results by reciprocal rank fusion (RRF) of a
+// keyword ranker and a semantic ranker: searches score each session with
+// both, and it scores 1/(K + rank) in each ranking it appears in. Score
+// alone, for a single document, is the keyword score.
+type FusionRanker struct {
+	Keyword  Ranker
+	Semantic Ranker
+	K        float64 // damps the lead of the top ranks; 60 is the usual choice
+}
+
+// Name returns the ranker name.
+func (FusionRanker) Name() string { return "hybrid" }
+
+// Score returns the keyword ranker's score.
+func (r FusionRanker) Score(queryTerms []string, doc Document, corpus Corpus) float64 {
+	return r.Keyword.Score(queryTerms, doc, corpus)
+}
+
+// Fuse returns the fused score of each result from its keyword and semantic
+// scores. A score of 0 leaves the result out of that ranking.
+func (r FusionRanker) Fuse(keyword, semantic []float64) []float64 {
+	fused := make([]float64, len(keyword))
+	for _, scores := range [][]float64{keyword, semantic} {
+		for i, rank := range ranks(scores) {
+			if rank > 0 {
+				fused[i] += 1 / (r.K + float64(rank))
+			}
+		}
+	}
+	return fused
+}
+
+// ranks returns the rank of each score, from 1 for the highest. Equal scores
+// share a rank, and scores of 0 or less are unranked (0).
+func ranks(scores []float64) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	ranked := make([]int, len(scores))
+	for pos, i := range order {
+		if scores[i] <= 0 {
+			break
+		}
+		if pos > 0 && scores[i] == scores[order[pos-1]] {
+			ranked[i] = ranked[order[pos-1]]
+		} else {
+			ranked[i] = pos + 1
+		}
+	}
+	return ranked
+}
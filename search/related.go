@@ -0,0 +1,216 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Weights of the signals combined by RelatedSessions. They sum to 1, so a
+// session with identical text, the same files, and the same project scores 1.
+const (
+	relatedTextWeight    = 0.5
+	relatedFilesWeight   = 0.3
+	relatedProjectWeight = 0.2
+)
+
+// relatedQueryTerms is how many of a session's most distinctive terms are used
+// to find sessions with similar text.
+const relatedQueryTerms = 20
+
+// RelatedSession is an indexed session ranked by its similarity to another.
+type RelatedSession struct {
+	Session     adapters.Session
+	Score       float64  // combined similarity, from 0 to 1
+	TextScore   float64  // BM25 score of the session's text, relative to the best match
+	SharedFiles []string // files both sessions' tool calls referenced
+	SameProject bool
+}
+
+// RelatedSessions ranks indexed sessions by similarity to sessionID, combining
+// BM25 over the session's most distinctive terms, the files both sessions
+// touched, and whether they share a project. The session must be indexed.
+func (c *Cache) RelatedSessions(sessionID string, limit int) ([]RelatedSession, error) {
+	target, err := c.indexedSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string]*RelatedSession)
+
+	terms, err := c.distinctiveTerms(sessionID, relatedQueryTerms)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) > 0 {
+		results, err := c.SearchWithRanker(strings.Join(terms, " "), "", "", 0, "bm25")
+		if err != nil {
+			return nil, err
+		}
+		best := 0.0
+		for _, r := range results {
+			if r.Session.ID != sessionID {
+				best = math.Max(best, r.Score)
+			}
+		}
+		for _, r := range results {
+			if r.Session.ID == sessionID || r.Score <= 0 || best <= 0 {
+				continue
+			}
+			candidates[r.Session.ID] = &RelatedSession{Session: r.Session, TextScore: r.Score / best}
+		}
+	}
+
+	targetFiles, shared, err := c.sharedFiles(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for id, files := range shared {
+		candidate, ok := candidates[id]
+		if !ok {
+			session, err := c.indexedSession(id)
+			if err != nil {
+				return nil, err
+			}
+			candidate = &RelatedSession{Session: session}
+			candidates[id] = candidate
+		}
+		candidate.SharedFiles = files
+	}
+
+	related := make([]RelatedSession, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate.SameProject = target.ProjectPath != "" && candidate.Session.ProjectPath == target.ProjectPath
+		score := relatedTextWeight * candidate.TextScore
+		if targetFiles > 0 {
+			score += relatedFilesWeight * float64(len(candidate.SharedFiles)) / float64(targetFiles)
+		}
+		if candidate.SameProject {
+			score += relatedProjectWeight
+		}
+		candidate.Score = math.Round(score*1000) / 1000
+		candidate.TextScore = math.Round(candidate.TextScore*1000) / 1000
+		related = append(related, *candidate)
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].Session.Timestamp.After(related[j].Session.Timestamp)
+	})
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+// indexedSession returns the cached metadata of an indexed session.
+func (c *Cache) indexedSession(sessionID string) (adapters.Session, error) {
+	var session adapters.Session
+	var timestampUnix int64
+	err := c.db.QueryRow(`
+		SELECT id, source, project_path, file_path, first_message, summary, timestamp
+		FROM sessions WHERE id = ?
+	`, sessionID).Scan(&session.ID, &session.Source, &session.ProjectPath, &session.FilePath,
+		&session.FirstMessage, &session.Summary, &timestampUnix)
+	if err == sql.ErrNoRows {
+		return adapters.Session{}, fmt.Errorf("session not indexed: %s", sessionID)
+	}
+	if err != nil {
+		return adapters.Session{}, fmt.Errorf("failed to read session: %w", err)
+	}
+	session.Timestamp = time.Unix(timestampUnix, 0)
+	return session, nil
+}
+
+// distinctiveTerms returns up to n of a session's terms ranked by tf-idf, so
+// words that appear in most sessions don't drive the similarity search.
+func (c *Cache) distinctiveTerms(sessionID string, n int) ([]string, error) {
+	rows, err := c.db.Query(`
+		SELECT ti.term, ti.term_frequency, COUNT(other.session_id)
+		FROM term_index ti
+		JOIN term_index other ON other.term = ti.term
+		WHERE ti.session_id = ?
+		GROUP BY ti.term
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session terms: %w", err)
+	}
+	defer rows.Close()
+
+	stats, err := c.getStats(ScopeAll)
+	if err != nil {
+		return nil, err
+	}
+
+	type weighted struct {
+		term   string
+		weight float64
+	}
+	var terms []weighted
+	for rows.Next() {
+		var term string
+		var tf, df int
+		if err := rows.Scan(&term, &tf, &df); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		// Terms unique to this session can't match anything else
+		if isNumber(term) || df < 2 {
+			continue
+		}
+		idf := math.Log(1 + (float64(stats.totalDocs)-float64(df)+0.5)/(float64(df)+0.5))
+		terms = append(terms, weighted{term: term, weight: math.Log(1+float64(tf)) * idf})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].weight != terms[j].weight {
+			return terms[i].weight > terms[j].weight
+		}
+		return terms[i].term < terms[j].term
+	})
+	result := make([]string, 0, min(n, len(terms)))
+	for _, t := range terms[:min(n, len(terms))] {
+		result = append(result, t.term)
+	}
+	return result, nil
+}
+
+// sharedFiles returns how many files a session referenced and, for every other
+// session that referenced some of the same files, which ones.
+func (c *Cache) sharedFiles(sessionID string) (int, map[string][]string, error) {
+	var count int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM session_files WHERE session_id = ?", sessionID).Scan(&count); err != nil {
+		return 0, nil, fmt.Errorf("failed to count session files: %w", err)
+	}
+
+	rows, err := c.db.Query(`
+		SELECT other.session_id, other.path
+		FROM session_files f
+		JOIN session_files other ON other.path = f.path AND other.session_id != f.session_id
+		WHERE f.session_id = ?
+		ORDER BY other.session_id, other.path
+	`, sessionID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to find shared files: %w", err)
+	}
+	defer rows.Close()
+
+	shared := make(map[string][]string)
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		shared[id] = append(shared[id], path)
+	}
+	return count, shared, rows.Err()
+}
@@ -0,0 +1,181 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Retention caps how large the index may grow. A zero field is no cap.
+type Retention struct {
+	MaxSizeMB   int64 `json:"max_size_mb,omitempty"`  // size of the database, not counting free pages
+	MaxSessions int   `json:"max_sessions,omitempty"` // number of indexed sessions
+	MaxAgeDays  int   `json:"max_age_days,omitempty"` // days since a session was last used
+}
+
+// IsZero reports whether no cap is set.
+func (r Retention) IsZero() bool {
+	return r == Retention{}
+}
+
+// Eviction counts the sessions Evict removed, by the cap that removed them.
+type Eviction struct {
+	ByAge   int `json:"by_age"`
+	ByCount int `json:"by_count"`
+	BySize  int `json:"by_size"`
+}
+
+// Total returns the number of sessions evicted.
+func (e Eviction) Total() int {
+	return e.ByAge + e.ByCount + e.BySize
+}
+
+// Evict removes the least recently used sessions from the index until it is
+// within the retention caps. A session was last used when it started, when
+// its file was last written, or when a client last read it, whichever is
+// latest. Evicted sessions are recorded so indexing leaves them out until
+// their file is modified again. The database file only shrinks once it is
+// vacuumed.
+func (c *Cache) Evict(now time.Time, r Retention) (Eviction, error) {
+	var eviction Eviction
+	if r.IsZero() {
+		return eviction, nil
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return Eviction{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Sessions from least to most recently used, with the bytes of their
+	// content and term rows. Term rows are counted twice, since each is also
+	// stored in an index.
+	rows, err := tx.Query(`
+		SELECT s.id, s.source, MAX(s.timestamp * 1000, s.file_mtime * 1000, COALESCE(a.accessed_at, 0)) AS used,
+		       LENGTH(s.content) + 2 * COALESCE(t.bytes, 0) + 2 * COALESCE(m.bytes, 0)
+		FROM sessions s
+		LEFT JOIN (SELECT session_id, MAX(accessed_at) AS accessed_at FROM access_log GROUP BY session_id) a
+			ON a.session_id = s.id
+		LEFT JOIN (SELECT session_id, SUM(LENGTH(term) + LENGTH(session_id) + 8) AS bytes FROM term_index GROUP BY session_id) t
+			ON t.session_id = s.id
+		LEFT JOIN (SELECT session_id, SUM(LENGTH(term) + LENGTH(session_id) + 16) AS bytes FROM message_term_index GROUP BY session_id) m
+			ON m.session_id = s.id
+		ORDER BY used, s.id`)
+	if err != nil {
+		return Eviction{}, fmt.Errorf("failed to list indexed sessions: %w", err)
+	}
+	type lruSession struct {
+		id, source string
+		used       int64
+		bytes      int64
+	}
+	var sessions []lruSession
+	for rows.Next() {
+		var s lruSession
+		if err := rows.Scan(&s.id, &s.source, &s.used, &s.bytes); err != nil {
+			rows.Close()
+			return Eviction{}, fmt.Errorf("failed to scan row: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Eviction{}, fmt.Errorf("failed to list indexed sessions: %w", err)
+	}
+
+	evict := func(n int) error {
+		for _, s := range sessions[:n] {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO evicted_sessions (session_id, source, evicted_at) VALUES (?, ?, ?)",
+				s.id, s.source, now.UnixMilli()); err != nil {
+				return fmt.Errorf("failed to record evicted session: %w", err)
+			}
+			if err := c.removeSessions(tx, []string{s.id}); err != nil {
+				return err
+			}
+		}
+		sessions = sessions[n:]
+		return nil
+	}
+
+	if r.MaxAgeDays > 0 {
+		cutoff := now.AddDate(0, 0, -r.MaxAgeDays).UnixMilli()
+		n := 0
+		for n < len(sessions) && sessions[n].used < cutoff {
+			n++
+		}
+		if err := evict(n); err != nil {
+			return Eviction{}, err
+		}
+		eviction.ByAge = n
+	}
+	if r.MaxSessions > 0 && len(sessions) > r.MaxSessions {
+		n := len(sessions) - r.MaxSessions
+		if err := evict(n); err != nil {
+			return Eviction{}, err
+		}
+		eviction.ByCount = n
+	}
+	if r.MaxSizeMB > 0 {
+		// Deleting rows leaves most pages partly used rather than free, so
+		// the database barely shrinks as sessions are evicted. Measure it
+		// once, and share that out among the sessions by their bytes to find
+		// how many to evict.
+		used, err := usedBytes(tx)
+		if err != nil {
+			return Eviction{}, err
+		}
+		var estimated int64
+		for _, s := range sessions {
+			estimated += s.bytes
+		}
+		if excess := used - r.MaxSizeMB<<20; excess > 0 && estimated > 0 {
+			scale := float64(used) / float64(estimated)
+			n := 0
+			for freed := 0.0; n < len(sessions) && freed < float64(excess); n++ {
+				freed += float64(sessions[n].bytes) * scale
+			}
+			if err := evict(n); err != nil {
+				return Eviction{}, err
+			}
+			eviction.BySize = n
+		}
+	}
+	return eviction, tx.Commit()
+}
+
+// usedBytes returns the bytes of the database in use, leaving out pages freed
+// by deletes that a vacuum would reclaim.
+func usedBytes(tx *sql.Tx) (int64, error) {
+	var pages, free, pageSize int64
+	if err := tx.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := tx.QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+		return 0, fmt.Errorf("failed to read free page count: %w", err)
+	}
+	if err := tx.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return (pages - free) * pageSize, nil
+}
+
+// evictedUnchanged reports whether a session was evicted and its file hasn't
+// been modified since, so indexing should leave it out.
+func (c *Cache) evictedUnchanged(sessionID, filePath string) (bool, error) {
+	var evictedAt int64
+	err := c.db.QueryRow("SELECT evicted_at FROM evicted_sessions WHERE session_id = ?", sessionID).Scan(&evictedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check evicted sessions: %w", err)
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return !fileInfo.ModTime().After(time.UnixMilli(evictedAt)), nil
+}
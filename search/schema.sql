@@ -10,7 +10,12 @@ CREATE TABLE IF NOT EXISTS sessions (
     last_indexed INTEGER NOT NULL,
     file_mtime INTEGER NOT NULL,  -- Track file modification time
     doc_length INTEGER DEFAULT 0,  -- Total tokens for BM25
-    content TEXT                    -- Full session content for snippet extraction
+    content TEXT,                   -- Full session content for snippet extraction
+    content_hash TEXT,              -- Content address derived from message hashes
+    outcome TEXT,                   -- completed, abandoned, or failed, as guessed by adapters.ClassifyOutcome
+    outcome_confidence TEXT,
+    outcome_signals TEXT,           -- JSON array of the signals behind the outcome
+    models TEXT                     -- Models the session used, one per line
 );
 
 CREATE INDEX IF NOT EXISTS idx_sessions_source ON sessions(source);
@@ -37,3 +42,240 @@ CREATE TABLE IF NOT EXISTS search_stats (
 -- Insert default stats
 INSERT OR IGNORE INTO search_stats (key, value) VALUES ('total_docs', 0);
 INSERT OR IGNORE INTO search_stats (key, value) VALUES ('avg_doc_length', 0);
+
+-- Content addresses for indexed messages, independent of file location or paging
+CREATE TABLE IF NOT EXISTS message_hashes (
+    session_id TEXT NOT NULL,
+    message_index INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    PRIMARY KEY (session_id, message_index),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_hashes_hash ON message_hashes(hash);
+
+-- Files referenced by tool call arguments, with how often each session accessed them
+CREATE TABLE IF NOT EXISTS session_files (
+    session_id TEXT NOT NULL,
+    path TEXT NOT NULL,
+    reads INTEGER NOT NULL DEFAULT 0,
+    writes INTEGER NOT NULL DEFAULT 0,
+    edits INTEGER NOT NULL DEFAULT 0,
+    other INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, path),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_files_path ON session_files(path);
+
+-- Read receipts: which client read which page of which session, and when
+CREATE TABLE IF NOT EXISTS access_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    client_name TEXT NOT NULL,
+    client_version TEXT,
+    page INTEGER NOT NULL,
+    page_size INTEGER NOT NULL,
+    start_index INTEGER,              -- First message read; NULL in entries recorded before it was tracked
+    message_count INTEGER NOT NULL,
+    accessed_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_log_session ON access_log(session_id, accessed_at DESC);
+
+-- When each MCP client last asked what changed, for changes_since
+CREATE TABLE IF NOT EXISTS change_checkpoints (
+    client_name TEXT PRIMARY KEY,
+    checked_at INTEGER NOT NULL       -- Unix milliseconds
+);
+
+-- Session text restricted to one search scope (see scopes.go), one row per
+-- session and scope. The full text lives in sessions.content.
+CREATE TABLE IF NOT EXISTS scoped_documents (
+    session_id TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    doc_length INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (session_id, scope),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Inverted index over scoped_documents
+CREATE TABLE IF NOT EXISTS scoped_term_index (
+    scope TEXT NOT NULL,
+    term TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    term_frequency INTEGER NOT NULL,
+    PRIMARY KEY (scope, term, session_id),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scoped_term_index_session ON scoped_term_index(session_id);
+
+-- Each message of a session, so searches rank sessions by their best message
+-- and report where it is. The message text isn't copied: content_start and
+-- content_end are its byte offsets in sessions.content. Lengths are in tokens,
+-- for the whole message and for its text in ScopeProse and ScopeCode.
+CREATE TABLE IF NOT EXISTS message_documents (
+    session_id TEXT NOT NULL,
+    message_index INTEGER NOT NULL,  -- position in the session, counting from 0
+    role TEXT NOT NULL,
+    is_tool_output INTEGER NOT NULL,
+    doc_length INTEGER NOT NULL,
+    prose_length INTEGER NOT NULL,
+    code_length INTEGER NOT NULL,
+    content_start INTEGER NOT NULL,  -- -1 when the text isn't in sessions.content
+    content_end INTEGER NOT NULL,
+    PRIMARY KEY (session_id, message_index),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Inverted index over message_documents, one row per term and message with
+-- its frequency in the whole message and in each scope
+CREATE TABLE IF NOT EXISTS message_term_index (
+    term TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    message_index INTEGER NOT NULL,
+    term_frequency INTEGER NOT NULL,
+    prose_frequency INTEGER NOT NULL,
+    code_frequency INTEGER NOT NULL,
+    PRIMARY KEY (term, session_id, message_index),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_term_index_session ON message_term_index(session_id);
+
+-- Notes users leave on sessions, optionally anchored to a message. Notes are
+-- not tied to the indexed sessions table, so reindexing never drops them.
+CREATE TABLE IF NOT EXISTS session_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    message_index INTEGER,            -- NULL for notes on the whole session
+    text TEXT NOT NULL,
+    created_at INTEGER NOT NULL       -- Unix milliseconds
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_notes_session ON session_notes(session_id, source);
+
+-- Messages bookmarked to jump back to later, at most one bookmark per message
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    message_index INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    preview TEXT NOT NULL,            -- Start of the message, as it was when bookmarked
+    created_at INTEGER NOT NULL,      -- Unix milliseconds
+    UNIQUE (session_id, source, message_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_created ON bookmarks(created_at DESC);
+
+-- Messages, user prompts, and tool calls per session per hour, for usage_stats
+CREATE TABLE IF NOT EXISTS session_activity (
+    session_id TEXT NOT NULL,
+    hour INTEGER NOT NULL,            -- Unix seconds at the start of the hour
+    messages INTEGER NOT NULL,
+    prompts INTEGER NOT NULL,
+    tool_calls INTEGER NOT NULL,
+    PRIMARY KEY (session_id, hour),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Token usage and cost per session per model, for get_cost_report
+CREATE TABLE IF NOT EXISTS session_costs (
+    session_id TEXT NOT NULL,
+    model TEXT NOT NULL,
+    unpriced INTEGER NOT NULL,        -- 1 when the cost wasn't recorded and the model's price is unknown
+    input_tokens INTEGER NOT NULL,
+    output_tokens INTEGER NOT NULL,
+    reasoning_tokens INTEGER NOT NULL,
+    cache_read_tokens INTEGER NOT NULL,
+    cache_write_tokens INTEGER NOT NULL,
+    total_tokens INTEGER NOT NULL,
+    cost_usd REAL NOT NULL,
+    PRIMARY KEY (session_id, model, unpriced),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Indexed sessions whose file has gone missing. They stay searchable until
+-- they have been missing for a grace period, in case the directory is only
+-- unmounted, and are then removed by maintenance.
+CREATE TABLE IF NOT EXISTS stale_sessions (
+    session_id TEXT PRIMARY KEY,
+    missing_since INTEGER NOT NULL    -- Unix milliseconds
+);
+
+-- Every file write and edit made by a session's tool calls, the per-project
+-- changelog behind file_history. Like notes, the ledger is not tied to the
+-- indexed sessions table, so it outlives sessions whose files are deleted.
+CREATE TABLE IF NOT EXISTS file_changes (
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    seq INTEGER NOT NULL,             -- Order of the change within the session
+    message_index INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    kind TEXT NOT NULL,               -- write or edit
+    tool TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,       -- Unix milliseconds; the session's time when the message has none
+    preview TEXT NOT NULL,
+    PRIMARY KEY (session_id, source, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_changes_path ON file_changes(path);
+
+-- Sessions users asked to forget. They are removed from the index and skipped
+-- by indexing, so their content isn't surfaced again; the session files
+-- themselves are left alone.
+CREATE TABLE IF NOT EXISTS forgotten_sessions (
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    forgotten_at INTEGER NOT NULL,    -- Unix milliseconds
+    PRIMARY KEY (session_id, source)
+);
+
+-- Sessions evicted to keep the index within its retention limits. Indexing
+-- skips them until their file is modified again.
+CREATE TABLE IF NOT EXISTS evicted_sessions (
+    session_id TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    evicted_at INTEGER NOT NULL       -- Unix milliseconds
+);
+
+-- Session metadata adapters extracted from session files, so listing only
+-- parses files that changed. An entry is used while the file's modification
+-- time and size match; any other change to the file replaces it.
+CREATE TABLE IF NOT EXISTS session_metadata (
+    path TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    mod_time INTEGER NOT NULL,        -- Unix nanoseconds
+    size INTEGER NOT NULL,
+    session TEXT NOT NULL             -- JSON-encoded adapters.Session
+);
+
+-- Where each message starts in JSONL session files, so a page of a large
+-- session is read by seeking to it. Valid while the file's modification time
+-- and size match, like session_metadata.
+CREATE TABLE IF NOT EXISTS message_offsets (
+    path TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    mod_time INTEGER NOT NULL,        -- Unix nanoseconds
+    size INTEGER NOT NULL,
+    offsets TEXT NOT NULL             -- JSON-encoded []adapters.MessageOffset
+);
+
+-- Which file holds each session, for sources whose files aren't named after
+-- the session ID. An entry is trusted while the file's modification time is
+-- unchanged, and checked against the file once it changes.
+CREATE TABLE IF NOT EXISTS session_paths (
+    source TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    path TEXT NOT NULL,
+    mod_time INTEGER NOT NULL,        -- Unix nanoseconds
+    PRIMARY KEY (source, session_id)
+);
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// Search scopes restrict matching to one kind of session text.
+const (
+	ScopeAll   = "all"   // everything: prompts, replies, and tool output
+	ScopeProse = "prose" // the assistant's explanations, without code blocks or tool output
+	ScopeCode  = "code"  // the assistant's code blocks and the text its tools wrote to files
+)
+
+// ScopeConversation is ScopeAll without tool output: prompts, replies, and
+// tool calls, but not the file dumps and command output tools returned, which
+// would otherwise dominate ranking. It isn't offered as a search scope;
+// WithoutToolOutput picks it. It has no index of its own: its text is the
+// messages that aren't tool output, selected from the message index.
+const ScopeConversation = "conversation"
+
+// indexedScopes are the scopes with their own index alongside the full text.
+var indexedScopes = []string{ScopeProse, ScopeCode}
+
+// roleScope names the internal scope holding the text of role's messages,
+// for role: qualifiers. Like ScopeConversation, it has no index of its own:
+// its text is the role's messages, selected from the message index.
+func roleScope(role string) string {
+	return "role:" + role
+}
+
+// derivedScope reports whether scope's text is assembled from the messages
+// it selects in the message index (see messageScopeOf), rather than indexed
+// on its own.
+func derivedScope(scope string) bool {
+	return scope == ScopeConversation || strings.HasPrefix(scope, "role:")
+}
+
+// ScopeNames returns the names of the search scopes.
+func ScopeNames() []string {
+	return append([]string{ScopeAll}, indexedScopes...)
+}
+
+// ValidateScope checks that scope names a search scope. An empty scope means ScopeAll.
+func ValidateScope(scope string) error {
+	if scope == "" || slices.Contains(ScopeNames(), scope) {
+		return nil
+	}
+	return fmt.Errorf("unknown search scope %q (expected one of: %s)", scope, strings.Join(ScopeNames(), ", "))
+}
+
+// WithoutToolOutput returns the scope to search to match the text in scope
+// but not tool output. Only ScopeAll includes tool output, so other scopes
+// are returned as they are.
+func WithoutToolOutput(scope string) string {
+	if scope == "" || scope == ScopeAll {
+		return ScopeConversation
+	}
+	return scope
+}
+
+// ScopeText returns the part of a message's text that belongs to scope, or ""
+// for an unknown scope.
+func ScopeText(msg adapters.Message, scope string) string {
+	switch scope {
+	case "", ScopeAll:
+		return msg.Content
+	case ScopeConversation:
+		if adapters.IsToolOutput(msg) {
+			return ""
+		}
+		return msg.Content
+	case ScopeProse:
+		if msg.Role != "assistant" || len(adapters.ExtractToolResults(msg)) > 0 {
+			return ""
+		}
+		return strings.TrimSpace(adapters.StripCodeBlocks(msg.Content))
+	case ScopeCode:
+		var parts []string
+		if msg.Role == "assistant" {
+			for _, block := range adapters.ExtractCodeBlocks(msg.Content) {
+				parts = append(parts, block.Code)
+			}
+		}
+		for _, call := range adapters.ExtractToolCalls(msg) {
+			parts = append(parts, adapters.WrittenText(call)...)
+		}
+		return strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	if role, ok := strings.CutPrefix(scope, "role:"); ok {
+		if msg.Role != role || adapters.IsToolOutput(msg) {
+			return ""
+		}
+		return msg.Content
+	}
+	return ""
+}
+
+// IndexScopes indexes a session's text in each scope that has its own index,
+// replacing what was indexed for it before. The session must already be
+// indexed with IndexSession.
+func (c *Cache) IndexScopes(sessionID string, messages []adapters.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM scoped_documents WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old scoped documents: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM scoped_term_index WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete old scoped term index: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO scoped_term_index (scope, term, session_id, term_frequency) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, scope := range indexedScopes {
+		var parts []string
+		for _, msg := range messages {
+			if text := ScopeText(msg, scope); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		content := strings.Join(parts, " ")
+		tokens := Tokenize(content)
+
+		if _, err := tx.Exec("INSERT INTO scoped_documents (session_id, scope, doc_length, content) VALUES (?, ?, ?, ?)",
+			sessionID, scope, len(tokens), content); err != nil {
+			return fmt.Errorf("failed to insert scoped document: %w", err)
+		}
+		for term, freq := range TermFrequency(tokens) {
+			if _, err := stmt.Exec(scope, term, sessionID, freq); err != nil {
+				return fmt.Errorf("failed to insert scoped term: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// derivedDocument assembles a session's text in a derived scope from the
+// messages the scope selects, returning its length in tokens and the text.
+// content is the session's indexed content, which holds the messages' text.
+func (c *Cache) derivedDocument(sessionID, content, scope string) (int, string, error) {
+	ms := messageScopeOf(scope)
+	rows, err := c.db.Query(`
+		SELECT d.`+ms.length+`, d.content_start, d.content_end
+		FROM message_documents d
+		WHERE `+ms.where+` AND d.session_id = ?
+		ORDER BY d.message_index`, append(slices.Clone(ms.args), sessionID)...)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get %s messages: %w", scope, err)
+	}
+	defer rows.Close()
+
+	docLength := 0
+	var parts []string
+	for rows.Next() {
+		var length, start, end int
+		if err := rows.Scan(&length, &start, &end); err != nil {
+			return 0, "", fmt.Errorf("failed to scan row: %w", err)
+		}
+		docLength += length
+		if text := messageText(content, start, end); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return docLength, strings.Join(parts, " "), rows.Err()
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yoavf/ai-sessions-mcp/adapters"
+)
+
+// DefaultTaskSimilarity is the minimum Jaccard similarity of two first messages
+// for their sessions to count as attempts at the same task.
+const DefaultTaskSimilarity = 0.6
+
+// minTaskTerms keeps short openers ("continue", "hi") from grouping unrelated sessions.
+const minTaskTerms = 3
+
+// Jaccard returns the Jaccard similarity of two term sets.
+func Jaccard(a, b []string) float64 {
+	setA := make(map[string]bool, len(a))
+	for _, term := range a {
+		setA[term] = true
+	}
+	setB := make(map[string]bool, len(b))
+	for _, term := range b {
+		setB[term] = true
+	}
+	if len(setA) == 0 && len(setB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for term := range setA {
+		if setB[term] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// GroupByTask clusters sessions whose first user messages are near-duplicates,
+// such as the same task retried across tools or models. Sessions are linked when
+// their similarity is at least threshold, and linked sessions form one group
+// (single linkage). Only groups with more than one session are returned, each
+// ordered oldest first; groups are ordered by their latest attempt, newest first.
+func GroupByTask(sessions []adapters.Session, threshold float64) [][]adapters.Session {
+	if threshold <= 0 {
+		threshold = DefaultTaskSimilarity
+	}
+
+	terms := make([][]string, len(sessions))
+	for i, s := range sessions {
+		terms[i] = uniqueTerms(Tokenize(s.FirstMessage))
+	}
+
+	parent := make([]int, len(sessions))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range sessions {
+		if len(terms[i]) < minTaskTerms {
+			continue
+		}
+		for j := i + 1; j < len(sessions); j++ {
+			if len(terms[j]) < minTaskTerms {
+				continue
+			}
+			if Jaccard(terms[i], terms[j]) >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	byRoot := make(map[int][]adapters.Session)
+	for i, s := range sessions {
+		root := find(i)
+		byRoot[root] = append(byRoot[root], s)
+	}
+
+	var groups [][]adapters.Session
+	for _, group := range byRoot {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Timestamp.Before(group[j].Timestamp) })
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][len(groups[i])-1].Timestamp.After(groups[j][len(groups[j])-1].Timestamp)
+	})
+	return groups
+}
+
+// Keyword is a term two sessions share, weighted by how often both use it and
+// how rare it is across the index.
+type Keyword struct {
+	Term   string  `json:"term"`
+	Weight float64 `json:"weight"`
+}
+
+// docFreqBatch bounds the number of terms per document-frequency query.
+const docFreqBatch = 500
+
+// SharedKeywords returns up to limit terms that contentA and contentB have in
+// common, most distinctive first. Terms found in most indexed sessions, such as
+// common words, get little weight; numbers are skipped.
+func (c *Cache) SharedKeywords(contentA, contentB string, limit int) ([]Keyword, error) {
+	freqsA := TermFrequency(Tokenize(contentA))
+	freqsB := TermFrequency(Tokenize(contentB))
+
+	var shared []string
+	for term := range freqsA {
+		if freqsB[term] > 0 && !isNumber(term) {
+			shared = append(shared, term)
+		}
+	}
+
+	stats, err := c.getStats(ScopeAll)
+	if err != nil {
+		return nil, err
+	}
+	docFreqs := make(map[string]int, len(shared))
+	for start := 0; start < len(shared); start += docFreqBatch {
+		batch, err := c.getDocumentFrequencies(ScopeAll, shared[start:min(start+docFreqBatch, len(shared))])
+		if err != nil {
+			return nil, err
+		}
+		for term, df := range batch {
+			docFreqs[term] = df
+		}
+	}
+
+	keywords := make([]Keyword, 0, len(shared))
+	for _, term := range shared {
+		idf := 1.0
+		if stats.totalDocs > 0 {
+			df := float64(docFreqs[term])
+			idf = math.Log(1 + (float64(stats.totalDocs)-df+0.5)/(df+0.5))
+		}
+		tf := float64(min(freqsA[term], freqsB[term]))
+		keywords = append(keywords, Keyword{Term: term, Weight: math.Round(idf*math.Log(1+tf)*1000) / 1000})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Weight != keywords[j].Weight {
+			return keywords[i].Weight > keywords[j].Weight
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+
+	if limit > 0 && len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+	return keywords, nil
+}
+
+// isNumber reports whether a token is all digits.
+func isNumber(term string) bool {
+	return strings.IndexFunc(term, func(r rune) bool { return !unicode.IsDigit(r) }) == -1
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot writes a consistent copy of the whole cache to a single file at path,
+// which must not already exist. The snapshot can be restored with RestoreSnapshot.
+func (c *Cache) Snapshot(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot file already exists: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	if _, err := c.db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// SnapshotInfo summarizes a cache snapshot.
+type SnapshotInfo struct {
+	Sessions int `json:"sessions"`
+}
+
+// RestoreSnapshot replaces the cache at dbPath with the snapshot at snapshotPath.
+// The snapshot is validated and migrated to the current schema before it replaces
+// the cache, so a bad file leaves the existing cache untouched.
+func RestoreSnapshot(snapshotPath, dbPath string) (SnapshotInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Stage the copy next to the cache so the final rename is atomic
+	staged, err := os.CreateTemp(filepath.Dir(dbPath), filepath.Base(dbPath)+".restore-*")
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	stagedPath := staged.Name()
+	defer os.Remove(stagedPath)
+
+	if err := copySnapshot(snapshotPath, staged); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	info, err := prepareSnapshot(stagedPath)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	// Drop WAL files belonging to the old cache so they aren't replayed onto the snapshot
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return SnapshotInfo{}, fmt.Errorf("failed to remove %s: %w", dbPath+suffix, err)
+		}
+	}
+	if err := os.Rename(stagedPath, dbPath); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to replace cache: %w", err)
+	}
+	return info, nil
+}
+
+// copySnapshot copies the snapshot file into dst and closes it.
+func copySnapshot(snapshotPath string, dst *os.File) error {
+	src, err := os.Open(snapshotPath)
+	if err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	return nil
+}
+
+// prepareSnapshot checks that path holds a search cache and brings it up to the current schema.
+func prepareSnapshot(path string) (SnapshotInfo, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer db.Close()
+
+	var tables int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('sessions', 'term_index')").Scan(&tables)
+	if err != nil || tables != 2 {
+		return SnapshotInfo{}, fmt.Errorf("not a search cache snapshot: %s", path)
+	}
+
+	if err := initSchema(db); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	var info SnapshotInfo
+	if err := db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&info.Sessions); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return info, nil
+}
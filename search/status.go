@@ -0,0 +1,140 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SourceIndexStatus describes the sessions indexed from one source.
+type SourceIndexStatus struct {
+	Source      string     `json:"source"`
+	Sessions    int        `json:"sessions"`
+	LastIndexed *time.Time `json:"last_indexed,omitempty"`
+}
+
+// IndexStatus describes the search index in more detail than IndexInfo: where
+// it is stored, its size on disk, and a breakdown per source.
+type IndexStatus struct {
+	IndexInfo
+	Path          string              `json:"path,omitempty"` // empty for an in-memory index
+	InMemory      bool                `json:"in_memory"`
+	DiskBytes     int64               `json:"disk_bytes"` // the database file and its write-ahead log
+	Forgotten     int                 `json:"forgotten"`  // sessions kept out by ForgetSession
+	SchemaVersion int                 `json:"schema_version"`
+	Sources       []SourceIndexStatus `json:"sources"`
+}
+
+// Status reports the index's Info along with where it is stored, its size on
+// disk, and how many sessions each source has indexed, most first.
+func (c *Cache) Status() (IndexStatus, error) {
+	info, err := c.Info()
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	status := IndexStatus{IndexInfo: info, Path: c.path, InMemory: c.path == "", Sources: []SourceIndexStatus{}}
+
+	if c.path != "" {
+		for _, file := range []string{c.path, c.path + "-wal"} {
+			if fi, err := os.Stat(file); err == nil {
+				status.DiskBytes += fi.Size()
+			}
+		}
+	}
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM forgotten_sessions").Scan(&status.Forgotten); err != nil {
+		return IndexStatus{}, fmt.Errorf("failed to count forgotten sessions: %w", err)
+	}
+	if err := c.db.QueryRow("PRAGMA user_version").Scan(&status.SchemaVersion); err != nil {
+		return IndexStatus{}, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	rows, err := c.db.Query(`
+		SELECT source, COUNT(*), MAX(last_indexed)
+		FROM sessions
+		GROUP BY source
+		ORDER BY COUNT(*) DESC, source`)
+	if err != nil {
+		return IndexStatus{}, fmt.Errorf("failed to read index status: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s SourceIndexStatus
+		var lastIndexed sql.NullInt64
+		if err := rows.Scan(&s.Source, &s.Sessions, &lastIndexed); err != nil {
+			return IndexStatus{}, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if lastIndexed.Valid {
+			t := time.Unix(lastIndexed.Int64, 0)
+			s.LastIndexed = &t
+		}
+		status.Sources = append(status.Sources, s)
+	}
+	return status, rows.Err()
+}
+
+// ResetIndex removes every indexed session, or only those from source when it
+// is set, along with what is cached about their files, so the next indexing run
+// rebuilds them from scratch. Notes, bookmarks, the access log, the file change
+// ledger, and forgotten sessions are kept. A full reset also vacuums the
+// database. It returns the number of sessions removed.
+func (c *Cache) ResetIndex(source string) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	query, args := "SELECT id FROM sessions", []interface{}{}
+	if source != "" {
+		query += " WHERE source = ?"
+		args = append(args, source)
+	}
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list indexed sessions: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list indexed sessions: %w", err)
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := clearFileCaches(tx, source); err != nil {
+		return 0, err
+	}
+	if source != "" {
+		if err := c.removeSessions(tx, ids); err != nil {
+			return 0, err
+		}
+	} else {
+		// Emptying every table at once is much faster than removing sessions one by one
+		for _, table := range append(sessionTables, "sessions") {
+			if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+				return 0, fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+		if err := c.updateStats(tx); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to reset index: %w", err)
+	}
+
+	if source == "" {
+		if _, _, err := c.vacuum(); err != nil {
+			return len(ids), err
+		}
+	}
+	return len(ids), nil
+}
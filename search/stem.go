@@ -0,0 +1,221 @@
+package search
+
+import "strings"
+
+// stem reduces an English word to its stem with the Porter stemming
+// algorithm, so "connections", "connected", and "connecting" all become
+// "connect". Only lower-case ASCII words are stemmed; identifiers with digits
+// and words in other scripts are returned as they are.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	w := []byte(word)
+	w = stemStep1a(w)
+	w = stemStep1b(w)
+	w = stemStep1c(w)
+	w = replaceSuffix(w, 0, step2Suffixes)
+	w = replaceSuffix(w, 0, step3Suffixes)
+	w = stemStep4(w)
+	w = stemStep5(w)
+	return string(w)
+}
+
+// isConsonant reports whether w[i] is a consonant: a letter other than a, e,
+// i, o, and u, and other than a y after a consonant.
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w, Porter's m.
+func measure(w []byte) int {
+	m := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel reports whether w contains a vowel.
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant reports whether w ends with two of the same consonant.
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant, where the last
+// consonant isn't w, x, or y, as in "hop" but not "snow".
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func stemStep1a(w []byte) []byte {
+	s := string(w)
+	switch {
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(s, "ss"):
+		return w
+	case strings.HasSuffix(s, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func stemStep1b(w []byte) []byte {
+	s := string(w)
+	if strings.HasSuffix(s, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+	var trimmed []byte
+	switch {
+	case strings.HasSuffix(s, "ed") && hasVowel(w[:len(w)-2]):
+		trimmed = w[:len(w)-2]
+	case strings.HasSuffix(s, "ing") && hasVowel(w[:len(w)-3]):
+		trimmed = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	t := string(trimmed)
+	switch {
+	case strings.HasSuffix(t, "at"), strings.HasSuffix(t, "bl"), strings.HasSuffix(t, "iz"):
+		return append(trimmed, 'e')
+	case endsDoubleConsonant(trimmed):
+		switch trimmed[len(trimmed)-1] {
+		case 'l', 's', 'z':
+			return trimmed
+		}
+		return trimmed[:len(trimmed)-1]
+	case measure(trimmed) == 1 && endsCVC(trimmed):
+		return append(trimmed, 'e')
+	}
+	return trimmed
+}
+
+func stemStep1c(w []byte) []byte {
+	if n := len(w); w[n-1] == 'y' && hasVowel(w[:n-1]) {
+		w[n-1] = 'i'
+	}
+	return w
+}
+
+// suffixRule replaces suffix with replacement.
+type suffixRule struct{ suffix, replacement string }
+
+var step2Suffixes = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3Suffixes = []suffixRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+// replaceSuffix applies the rule for the longest of rules' suffixes that w
+// ends with, if the rest of w has a measure above minMeasure.
+func replaceSuffix(w []byte, minMeasure int, rules []suffixRule) []byte {
+	best := -1
+	for i, rule := range rules {
+		if strings.HasSuffix(string(w), rule.suffix) && (best < 0 || len(rule.suffix) > len(rules[best].suffix)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return w
+	}
+	rest := w[:len(w)-len(rules[best].suffix)]
+	if measure(rest) <= minMeasure {
+		return w
+	}
+	return append(rest, rules[best].replacement...)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func stemStep4(w []byte) []byte {
+	best := ""
+	for _, suffix := range step4Suffixes {
+		if strings.HasSuffix(string(w), suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return w
+	}
+	rest := w[:len(w)-len(best)]
+	if measure(rest) <= 1 {
+		return w
+	}
+	if best == "ion" {
+		if last := rest[len(rest)-1]; last != 's' && last != 't' {
+			return w
+		}
+	}
+	return rest
+}
+
+func stemStep5(w []byte) []byte {
+	if n := len(w); w[n-1] == 'e' {
+		m := measure(w[:n-1])
+		if m > 1 || (m == 1 && !endsCVC(w[:n-1])) {
+			w = w[:n-1]
+		}
+	}
+	if n := len(w); w[n-1] == 'l' && endsDoubleConsonant(w) && measure(w) > 1 {
+		w = w[:n-1]
+	}
+	return w
+}
@@ -0,0 +1,93 @@
+package search
+
+import "fmt"
+
+// SyntaxRule describes one element of the supported query syntax.
+type SyntaxRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Example     string `json:"example,omitempty"`
+}
+
+// QuerySyntax describes how search queries are interpreted, so callers can
+// discover capabilities at runtime instead of guessing.
+type QuerySyntax struct {
+	Grammar string       `json:"grammar"`
+	Rules   []SyntaxRule `json:"rules"`
+	Rankers []string     `json:"rankers"`
+	Default string       `json:"default_ranker"`
+}
+
+// DescribeSyntax returns the query syntax understood by Tokenize and Search.
+// Keep it in sync when the tokenizer, QueryGrammar, or query handling changes.
+func DescribeSyntax() QuerySyntax {
+	return QuerySyntax{
+		Grammar: QueryGrammar,
+		Rules: []SyntaxRule{
+			{
+				Name:        "keywords",
+				Description: "Words are split into keywords on any character that is not a letter, digit, underscore, or dot. Keywords side by side match a session containing any of them; sessions containing more (and rarer) keywords rank higher.",
+				Example:     "authentication bug",
+			},
+			{
+				Name:        "identifiers",
+				Description: "Code identifiers and dotted paths are indexed whole and by their parts: camelCase words, snake_case pieces, and dotted segments. A whole identifier in a query matches only that identifier; one of its parts matches wherever the part appears.",
+				Example:     "getSessionPage OR sqlite",
+			},
+			{
+				Name:        "phrases",
+				Description: "Words in double quotes match only in that order, next to each other.",
+				Example:     `"connection refused"`,
+			},
+			{
+				Name:        "and",
+				Description: "AND matches sessions containing both sides.",
+				Example:     "oauth AND refresh",
+			},
+			{
+				Name:        "or",
+				Description: "OR matches sessions matching either side. AND binds tighter than terms side by side, which bind tighter than OR.",
+				Example:     "postgres OR sqlite",
+			},
+			{
+				Name:        "not",
+				Description: "NOT, or a - at the start of a word or phrase, leaves out sessions matching what follows, from the terms beside it. A query needs at least one term that isn't excluded.",
+				Example:     "migration -rollback",
+			},
+			{
+				Name:        "qualifiers",
+				Description: "field:value matches session metadata instead of text. Beside other terms, qualifiers narrow down the sessions those terms match, as exclusions do, and they work with operators and parentheses like any term. source: matches the source, model: a model whose name contains the value, file: a file the session's tools referenced by full path or trailing path segments, and title: words in order in the summary or first message. role:user or role:assistant matches the query's text only in that role's messages, leaving out tool output; it applies to the whole query, can't be excluded, and can't be combined with another scope. Quote values with spaces. A query needs at least one term besides qualifiers. Other words with a colon are searched for as text.",
+				Example:     `role:user source:claude file:main.go "race condition"`,
+			},
+			{
+				Name:        "grouping",
+				Description: "Parentheses group terms.",
+				Example:     `(postgres OR sqlite) AND "schema migration"`,
+			},
+			{
+				Name:        "operators_case",
+				Description: "AND, OR, and NOT are operators only in upper case; in lower case they are searched for as words.",
+			},
+			{
+				Name:        "case",
+				Description: "Matching is case-insensitive.",
+			},
+			{
+				Name:        "short_tokens",
+				Description: "Single-character keywords are ignored.",
+			},
+			{
+				Name:        "errors",
+				Description: "A query that doesn't parse, such as one with an unclosed quote or parenthesis, fails with the error code query_syntax_error instead of being searched as plain text.",
+			},
+			{
+				Name:        "prefix",
+				Description: fmt.Sprintf("A word ending in * matches keywords starting with it, such as deploy, deployed, and deployment for deploy*. The prefix is matched against indexed keywords, which are stems when stemming is on, and expands to at most the %d most common of them. A * anywhere else is treated as plain text.", maxPrefixTerms),
+				Example:     "deploy*",
+			},
+			describeAnalyzer(),
+		},
+		Rankers: RankerNames(),
+		Default: DefaultRankerName,
+	}
+}